@@ -0,0 +1,52 @@
+package muxter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// StatusClientClosedRequest is the non-standard status logged for requests abandoned by their clients, following the
+// convention of nginx. It is never sent, since there is no one to send it to.
+const StatusClientClosedRequest = 499
+
+// IsClientAbort reports whether the error was caused by the client going away rather than by the server: a broken
+// pipe or a connection reset when writing the response, a closed connection, or the cancellation of the request's
+// context. Such errors are expected on busy servers and are not server errors.
+func IsClientAbort(err error) bool {
+	return errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, context.Canceled)
+}
+
+// ClientAborted reports whether the client of the request went away, either because the request's context was
+// canceled or because writing the response failed. It returns false if the request is not served by Mux.ServeHTTP.
+func (c Context) ClientAborted() bool {
+	c.checkOwner()
+	return c.lifecycle != nil && (c.lifecycle.aborted() || c.lifecycle.r.Context().Err() == context.Canceled)
+}
+
+// isClientAbortPanic reports whether a recovered panic value is a client abort error.
+func isClientAbortPanic(recovered interface{}) bool {
+	err, ok := recovered.(error)
+	return ok && IsClientAbort(err)
+}
+
+// serveObserved serves the request and then calls report with whether the client went away. Handlers panicking with
+// client abort errors are reported as aborted before the panic resumes, such that observers like Logger and
+// RouteStats account for them. Other panics are not reported.
+func serveObserved(h Handler, w http.ResponseWriter, r *http.Request, c Context, report func(aborted bool)) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if isClientAbortPanic(recovered) {
+				report(true)
+			}
+			panic(recovered)
+		}
+	}()
+	h.ServeHTTPx(w, r, c)
+	report(c.ClientAborted())
+}
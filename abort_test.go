@@ -0,0 +1,189 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// brokenPipeWriter fails writes like the connection of a client that went away.
+type brokenPipeWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (brokenPipeWriter) Write([]byte) (int, error) {
+	return 0, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+}
+
+func TestIsClientAbort(t *testing.T) {
+	testcases := []struct {
+		Err      error
+		Expected bool
+	}{
+		{Err: &net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)}, Expected: true},
+		{Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, Expected: true},
+		{Err: fmt.Errorf("copying body: %w", net.ErrClosed), Expected: true},
+		{Err: context.Canceled, Expected: true},
+		{Err: context.DeadlineExceeded},
+		{Err: http.ErrAbortHandler},
+		{Err: io.ErrUnexpectedEOF},
+		{Err: errors.New("database is down")},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Err.Error(), func(t *testing.T) {
+			if actual := IsClientAbort(tc.Err); actual != tc.Expected {
+				t.Errorf("expected %v but got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestRecoverClientAbort(t *testing.T) {
+	testcases := []struct {
+		Name          string
+		Panic         interface{}
+		ExpectedPanic interface{}
+		ExpectedCode  int
+	}{
+		{Name: "abort handler", Panic: http.ErrAbortHandler, ExpectedPanic: http.ErrAbortHandler, ExpectedCode: 200},
+		{Name: "broken pipe", Panic: fmt.Errorf("flushing: %w", syscall.EPIPE), ExpectedPanic: http.ErrAbortHandler, ExpectedCode: 200},
+		{Name: "server error", Panic: errors.New("boom"), ExpectedCode: 500},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var recovered bool
+			handler := Recover(func(interface{}, http.ResponseWriter, *http.Request, Context) {
+				recovered = true
+			})(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				panic(tc.Panic)
+			}))
+
+			var actual interface{}
+			func() {
+				defer func() { actual = recover() }()
+				handler.ServeHTTPx(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), Context{})
+			}()
+
+			if actual != tc.ExpectedPanic {
+				t.Errorf("expected panic %v but got %v", tc.ExpectedPanic, actual)
+			}
+			if recovered != (tc.ExpectedCode == 500) {
+				t.Errorf("expected recover handler to be called: %v", tc.ExpectedCode == 500)
+			}
+		})
+	}
+}
+
+func TestObservedClientAbort(t *testing.T) {
+	testcases := []struct {
+		Name            string
+		Handler         HandlerFunc
+		Writer          func(*httptest.ResponseRecorder) http.ResponseWriter
+		Context         context.Context
+		ExpectedLog     string
+		ExpectedErrors  uint64
+		ExpectedAborted uint64
+	}{
+		{
+			Name:        "served",
+			Handler:     func(w http.ResponseWriter, r *http.Request, c Context) { w.Write([]byte("ok")) },
+			ExpectedLog: "200 false\n",
+		},
+		{
+			Name: "server error",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.WriteHeader(500)
+			},
+			ExpectedLog:    "500 false\n",
+			ExpectedErrors: 1,
+		},
+		{
+			Name: "broken pipe",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				if _, err := w.Write([]byte("ok")); err != nil {
+					w.WriteHeader(500)
+				}
+			},
+			Writer:          func(rec *httptest.ResponseRecorder) http.ResponseWriter { return brokenPipeWriter{rec} },
+			ExpectedLog:     "499 true\n",
+			ExpectedAborted: 1,
+		},
+		{
+			Name: "canceled",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				http.Error(w, r.Context().Err().Error(), 500)
+			},
+			Context:         canceledContext(),
+			ExpectedLog:     "499 true\n",
+			ExpectedAborted: 1,
+		},
+		{
+			Name: "client abort panic",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				panic(fmt.Errorf("streaming: %w", syscall.ECONNRESET))
+			},
+			ExpectedLog:     "499 true\n",
+			ExpectedAborted: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var logs bytes.Buffer
+			stats := NewRouteStats(RouteStatsOptions{})
+
+			mux := New()
+			mux.Use(
+				Recover(nil),
+				stats.Middleware,
+				Logger(&logs, func(overview RespOverview) string {
+					return fmt.Sprintf("%d %v", overview.Code, overview.ClientAborted)
+				}),
+			)
+			mux.Handle("/", tc.Handler)
+
+			rec := httptest.NewRecorder()
+			var w http.ResponseWriter = rec
+			if tc.Writer != nil {
+				w = tc.Writer(rec)
+			}
+			r := httptest.NewRequest("GET", "/", nil)
+			if tc.Context != nil {
+				r = r.WithContext(tc.Context)
+			}
+
+			func() {
+				defer func() {
+					if recovered := recover(); recovered != nil && recovered != http.ErrAbortHandler {
+						t.Fatalf("unexpected panic %v", recovered)
+					}
+				}()
+				mux.ServeHTTP(w, r)
+			}()
+
+			if logs.String() != tc.ExpectedLog {
+				t.Errorf("expected log %q but got %q", tc.ExpectedLog, logs.String())
+			}
+			routes := stats.Routes()
+			if len(routes) != 1 || routes[0].Errors != tc.ExpectedErrors || routes[0].Aborted != tc.ExpectedAborted {
+				t.Errorf("expected %d errors and %d aborted requests but got %+v", tc.ExpectedErrors, tc.ExpectedAborted, routes)
+			}
+		})
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,197 @@
+package muxter
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+)
+
+// AdminOptions configures Mux.AdminHandler.
+type AdminOptions struct {
+	// Authorize reports whether the request may use the admin API. Unauthorized requests are answered with 403
+	// Forbidden. It is required.
+	Authorize func(r *http.Request, c Context) bool
+	// Limiters are the rate limiters whose settings are exposed, by name.
+	Limiters map[string]*RateLimiter
+	// Caches are the caches that can be purged, by name.
+	Caches map[string]*Cache
+	// NotFound are the statistics of the requests served as not found, if they are collected.
+	NotFound *NotFoundStats
+}
+
+// AdminHandler returns a handler exposing the runtime controls of the mux as a JSON API, to be mounted under a
+// subtree with StripDepth:
+//
+//	GET  /routes                the route table, as returned by Mux.Routes
+//	POST /routes/disable        disables the route of {"pattern":"/books/:id","status":503}, served as 404 by default
+//	POST /routes/enable         enables the route of {"pattern":"/books/:id"}
+//	GET  /maintenance           the maintenance mode, as {"enabled":true,"retryAfter":60}
+//	PUT  /maintenance           sets the maintenance mode, with retryAfter in seconds
+//	GET  /limiters              the rate and burst of the limiters, as {"limiters":{"api":{"rate":10,"burst":20}}}
+//	PUT  /limiters/:name        sets the rate and burst of the limiter with the name
+//	POST /caches/:name/purge    purges the cache with the name, as with Cache.PurgeHandler
+//	GET  /notfound              the not found requests by nearest pattern and referrer, as with NotFoundStats.Handler
+//	DELETE /notfound            resets the not found statistics
+//
+// The mount is best registered as MaintenanceExempt, so that maintenance mode can be turned off through the API.
+// AdminHandler panics if opts.Authorize is nil.
+//
+//	mux.Handle("/admin/", muxter.StripDepth(1, mux.AdminHandler(muxter.AdminOptions{
+//		Authorize: isAdmin,
+//		Limiters:  map[string]*muxter.RateLimiter{"api": limiter},
+//	})), muxter.MaintenanceExempt())
+func (m *Mux) AdminHandler(opts AdminOptions) Handler {
+	if opts.Authorize == nil {
+		panic("muxter: admin handler requires an Authorize function")
+	}
+
+	admin := New(JSONErrors(true))
+
+	admin.GetFunc("/routes", func(w http.ResponseWriter, r *http.Request, c Context) {
+		routes := m.Routes()
+		if routes == nil {
+			routes = []RouteInfo{}
+		}
+		writeAdminJSON(w, struct {
+			Routes []RouteInfo `json:"routes"`
+		}{routes})
+	})
+
+	admin.PostFunc("/routes/:action", func(w http.ResponseWriter, r *http.Request, c Context) {
+		var body struct {
+			Pattern string `json:"pattern"`
+			Status  int    `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+
+		var status int32
+		switch c.Param("action") {
+		case "enable":
+		case "disable":
+			switch body.Status {
+			case 0, http.StatusNotFound:
+				status = http.StatusNotFound
+			case http.StatusServiceUnavailable:
+				status = http.StatusServiceUnavailable
+			default:
+				writeAdminError(w, http.StatusBadRequest, "status must be 404 or 503")
+				return
+			}
+		default:
+			writeStatus(w, c, http.StatusNotFound)
+			return
+		}
+
+		v := m.lookupPattern(body.Pattern)
+		if v == nil {
+			writeAdminError(w, http.StatusNotFound, "route not found: "+body.Pattern)
+			return
+		}
+		m.toggle(v, status)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	type maintenanceBody struct {
+		Enabled    bool    `json:"enabled"`
+		RetryAfter float64 `json:"retryAfter"`
+	}
+
+	getMaintenance := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		enabled, retryAfter := m.Maintenance()
+		writeAdminJSON(w, maintenanceBody{Enabled: enabled, RetryAfter: retryAfter.Seconds()})
+	})
+
+	putMaintenance := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		var body maintenanceBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+		if body.RetryAfter < 0 || math.IsInf(body.RetryAfter, 0) {
+			writeAdminError(w, http.StatusBadRequest, "retryAfter must not be negative")
+			return
+		}
+		m.SetMaintenance(body.Enabled, time.Duration(body.RetryAfter*float64(time.Second)))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	admin.Handle("/maintenance", MethodHandler{GET: getMaintenance, PUT: putMaintenance})
+
+	type limitBody struct {
+		Rate  float64 `json:"rate"`
+		Burst int     `json:"burst"`
+	}
+
+	admin.GetFunc("/limiters", func(w http.ResponseWriter, r *http.Request, c Context) {
+		limiters := make(map[string]limitBody, len(opts.Limiters))
+		for name, limiter := range opts.Limiters {
+			rate, burst := limiter.Limit()
+			limiters[name] = limitBody{Rate: rate, Burst: burst}
+		}
+		writeAdminJSON(w, struct {
+			Limiters map[string]limitBody `json:"limiters"`
+		}{limiters})
+	})
+
+	admin.PutFunc("/limiters/:name", func(w http.ResponseWriter, r *http.Request, c Context) {
+		limiter, ok := opts.Limiters[c.Param("name")]
+		if !ok {
+			writeAdminError(w, http.StatusNotFound, "limiter not found: "+c.Param("name"))
+			return
+		}
+		var body limitBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+		if body.Rate <= 0 {
+			writeAdminError(w, http.StatusBadRequest, "rate must be positive")
+			return
+		}
+		limiter.SetLimit(body.Rate, body.Burst)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	admin.PostFunc("/caches/:name/purge", func(w http.ResponseWriter, r *http.Request, c Context) {
+		cache, ok := opts.Caches[c.Param("name")]
+		if !ok {
+			writeAdminError(w, http.StatusNotFound, "cache not found: "+c.Param("name"))
+			return
+		}
+		cache.PurgeHandler().ServeHTTPx(w, r, c)
+	})
+
+	if opts.NotFound != nil {
+		admin.Handle("/notfound", MethodHandler{
+			GET: opts.NotFound.Handler(),
+			DELETE: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				opts.NotFound.Reset()
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		})
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if !opts.Authorize(r, c) {
+			c.jsonErrors = true
+			writeStatus(w, c, http.StatusForbidden)
+			return
+		}
+		admin.ServeHTTPx(w, r, c)
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Error: msg, Status: status})
+}
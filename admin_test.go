@@ -0,0 +1,141 @@
+package muxter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 10, Burst: 20})
+	cache := NewCache(CacheOptions{HeuristicFreshness: true})
+
+	mux := New()
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte("book"))
+	}, cache.Middleware)
+	mux.Handle("/admin/", StripDepth(1, mux.AdminHandler(AdminOptions{
+		Authorize: func(r *http.Request, c Context) bool { return r.Header.Get("Authorization") == "admin" },
+		Limiters:  map[string]*RateLimiter{"api": limiter},
+		Caches:    map[string]*Cache{"pages": cache},
+	})), MaintenanceExempt())
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if strings.HasPrefix(target, "/admin/") {
+			r.Header.Set("Authorization", "admin")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/admin/routes", nil))
+		if w.Code != 403 {
+			t.Errorf("expected 403 but got %d", w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("expected a JSON error but got %q", contentType)
+		}
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		w := serve("GET", "/admin/routes", "")
+		var body struct{ Routes []RouteInfo }
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Routes) != 2 || body.Routes[1].Pattern != "/books/:id" {
+			t.Errorf("unexpected routes: %+v", body.Routes)
+		}
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		if w := serve("POST", "/admin/routes/disable", `{"pattern":"/books/:id","status":503}`); w.Code != 204 {
+			t.Fatalf("expected 204 but got %d: %s", w.Code, w.Body)
+		}
+		if w := serve("GET", "/books/1", ""); w.Code != 503 {
+			t.Errorf("expected disabled route to be unavailable but got %d", w.Code)
+		}
+		if w := serve("POST", "/admin/routes/enable", `{"pattern":"/books/:id"}`); w.Code != 204 {
+			t.Fatalf("expected 204 but got %d: %s", w.Code, w.Body)
+		}
+		if w := serve("GET", "/books/1", ""); w.Code != 200 {
+			t.Errorf("expected enabled route to be served but got %d", w.Code)
+		}
+		if w := serve("POST", "/admin/routes/disable", `{"pattern":"/missing"}`); w.Code != 404 {
+			t.Errorf("expected 404 for unknown pattern but got %d", w.Code)
+		}
+		if w := serve("POST", "/admin/routes/disable", `{"pattern":"/books/:id","status":500}`); w.Code != 400 {
+			t.Errorf("expected 400 for invalid status but got %d", w.Code)
+		}
+	})
+
+	t.Run("maintenance", func(t *testing.T) {
+		if w := serve("PUT", "/admin/maintenance", `{"enabled":true,"retryAfter":60}`); w.Code != 204 {
+			t.Fatalf("expected 204 but got %d: %s", w.Code, w.Body)
+		}
+		w := serve("GET", "/books/1", "")
+		if w.Code != 503 || w.Header().Get("Retry-After") != "60" {
+			t.Errorf("expected 503 with Retry-After 60 in maintenance but got %d and %q", w.Code, w.Header().Get("Retry-After"))
+		}
+		if w := serve("GET", "/admin/maintenance", ""); strings.TrimSpace(w.Body.String()) != `{"enabled":true,"retryAfter":60}` {
+			t.Errorf("unexpected maintenance mode: %s", w.Body)
+		}
+		if w := serve("PUT", "/admin/maintenance", `{"enabled":false}`); w.Code != 204 {
+			t.Fatalf("expected exempt admin API to be served in maintenance but got %d", w.Code)
+		}
+		if w := serve("GET", "/books/1", ""); w.Code != 200 {
+			t.Errorf("expected route to be served after maintenance but got %d", w.Code)
+		}
+	})
+
+	t.Run("limiters", func(t *testing.T) {
+		if w := serve("PUT", "/admin/limiters/api", `{"rate":5}`); w.Code != 204 {
+			t.Fatalf("expected 204 but got %d: %s", w.Code, w.Body)
+		}
+		if w := serve("GET", "/admin/limiters", ""); strings.TrimSpace(w.Body.String()) != `{"limiters":{"api":{"rate":5,"burst":5}}}` {
+			t.Errorf("unexpected limiters: %s", w.Body)
+		}
+		if w := serve("PUT", "/admin/limiters/api", `{"rate":0}`); w.Code != 400 {
+			t.Errorf("expected 400 for invalid rate but got %d", w.Code)
+		}
+		if w := serve("PUT", "/admin/limiters/other", `{"rate":1}`); w.Code != 404 {
+			t.Errorf("expected 404 for unknown limiter but got %d", w.Code)
+		}
+	})
+
+	t.Run("cache purge", func(t *testing.T) {
+		serve("GET", "/books/1", "")
+		if w := serve("POST", "/admin/caches/pages/purge?path=/books/1", ""); strings.TrimSpace(w.Body.String()) != `{"purged":1}` {
+			t.Errorf("unexpected purge response: %s", w.Body)
+		}
+	})
+}
+
+func TestMaintenance(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request, c Context) {}, MaintenanceExempt())
+
+	mux.SetMaintenance(true, 0)
+
+	for path, expected := range map[string]int{"/": 503, "/healthz": 200} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != expected {
+			t.Errorf("expected %s to be answered with %d in maintenance but got %d", path, expected, w.Code)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "" {
+			t.Errorf("expected no Retry-After but got %q", retryAfter)
+		}
+	}
+
+	if enabled, _ := mux.Maintenance(); !enabled {
+		t.Errorf("expected maintenance mode to be enabled")
+	}
+}
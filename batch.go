@@ -0,0 +1,314 @@
+package muxter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// BatchOptions configures the handler returned by Mux.Batch.
+type BatchOptions struct {
+	// MaxRequests is the maximum number of requests in a batch. It defaults to 20.
+	MaxRequests int
+	// MaxBodyBytes is the maximum size of the batch request body. It defaults to 1MB.
+	MaxBodyBytes int64
+	// Concurrency is the number of requests of a batch served concurrently. It defaults to 1, serving the requests
+	// one after the other in order.
+	Concurrency int
+}
+
+type batchKey struct{}
+
+func batchContext(parent *http.Request) context.Context {
+	return context.WithValue(parent.Context(), batchKey{}, true)
+}
+
+type batchRequest struct {
+	contentID string
+	request   *http.Request
+}
+
+type batchJSONRequest struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type batchJSONResponse struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Batch returns a handler that serves batches of requests through the mux in-process, saving clients round trips.
+// Requests of a batch inherit the headers of the batch request, except for its content headers, and their own
+// headers take precedence. Two formats are supported depending on the batch request's Content-Type:
+//
+// With multipart/mixed every part is an application/http message containing a request, and the response is a
+// multipart/mixed message of application/http parts containing the responses. A part's Content-ID is returned as
+// "response-" followed by the Content-ID.
+//
+// With application/json the body is of the form:
+//
+//	{"requests": [{"id": "1", "method": "GET", "path": "/users/1", "headers": {"Accept": "application/json"}}]}
+//
+// and the response of the form {"responses": [{"id": "1", "status": 200, "headers": {...}, "body": ...}]} where
+// request and response bodies are JSON values, and non JSON response bodies are JSON strings.
+//
+// Responses are in the order of the requests. Batches cannot be nested.
+func (m *Mux) Batch(opts BatchOptions) Handler {
+	if opts.MaxRequests <= 0 {
+		opts.MaxRequests = 20
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if r.Context().Value(batchKey{}) != nil {
+			http.Error(w, "muxter: nested batch requests are not supported", http.StatusBadRequest)
+			return
+		}
+
+		mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeStatus(w, c, http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var requests []batchRequest
+		var jsonRequests []batchJSONRequest
+
+		switch mediaType {
+		case "multipart/mixed":
+			requests, err = parseMultipartBatch(r, body, params["boundary"])
+		case "application/json":
+			requests, jsonRequests, err = parseJSONBatch(r, body)
+		default:
+			writeStatus(w, c, http.StatusUnsupportedMediaType)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("muxter: invalid batch request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(requests) > opts.MaxRequests {
+			http.Error(w, fmt.Sprintf("muxter: batch exceeds the maximum of %d requests", opts.MaxRequests), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		responses := m.serveBatch(requests, opts.Concurrency)
+
+		if mediaType == "application/json" {
+			writeJSONBatch(w, jsonRequests, responses)
+			return
+		}
+		writeMultipartBatch(w, requests, responses)
+	})
+}
+
+func (m *Mux) serveBatch(requests []batchRequest, concurrency int) []*http.Response {
+	responses := make([]*http.Response, len(requests))
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			resp, err := m.dispatch(requests[i].request)
+			if err != nil {
+				rec := &dispatchRecorder{header: http.Header{}}
+				rec.WriteHeader(http.StatusInternalServerError)
+				resp = rec.result(requests[i].request)
+			}
+			responses[i] = resp
+		}(i)
+	}
+	wg.Wait()
+
+	return responses
+}
+
+// newSubRequest creates a request served on behalf of the parent request, such as a request of a batch, that
+// inherits the parent's headers, except for its content headers, and its connection details.
+func newSubRequest(ctx context.Context, parent *http.Request, method, target string, header http.Header, body []byte) (*http.Request, error) {
+	if !strings.HasPrefix(target, "/") {
+		return nil, fmt.Errorf("path must begin with a forward-slash but got: %s", target)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header = parent.Header.Clone()
+	for _, key := range []string{"Content-Type", "Content-Length", "Content-Encoding", "Content-Id", "Content-Transfer-Encoding"} {
+		r.Header.Del(key)
+	}
+	for key, values := range header {
+		r.Header[key] = values
+	}
+
+	r.Host = parent.Host
+	r.RemoteAddr = parent.RemoteAddr
+	r.TLS = parent.TLS
+	r.RequestURI = target
+
+	return r, nil
+}
+
+func parseMultipartBatch(parent *http.Request, body []byte, boundary string) ([]batchRequest, error) {
+	if boundary == "" {
+		return nil, errors.New("missing multipart boundary")
+	}
+
+	var requests []batchRequest
+
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return requests, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); mediaType != "application/http" {
+			return nil, fmt.Errorf("part %d must be of type application/http", len(requests)+1)
+		}
+
+		embedded, err := http.ReadRequest(bufio.NewReader(part))
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", len(requests)+1, err)
+		}
+		embeddedBody, err := io.ReadAll(embedded.Body)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", len(requests)+1, err)
+		}
+
+		r, err := newSubRequest(batchContext(parent), parent, embedded.Method, embedded.RequestURI, embedded.Header, embeddedBody)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", len(requests)+1, err)
+		}
+
+		requests = append(requests, batchRequest{contentID: part.Header.Get("Content-ID"), request: r})
+	}
+}
+
+func parseJSONBatch(parent *http.Request, body []byte) ([]batchRequest, []batchJSONRequest, error) {
+	var batch struct {
+		Requests []batchJSONRequest `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, nil, err
+	}
+
+	requests := make([]batchRequest, len(batch.Requests))
+	for i, jr := range batch.Requests {
+		header := http.Header{}
+		for key, value := range jr.Headers {
+			header.Set(key, value)
+		}
+		if len(jr.Body) > 0 && header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/json")
+		}
+
+		method := jr.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		r, err := newSubRequest(batchContext(parent), parent, strings.ToUpper(method), jr.Path, header, jr.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+		requests[i] = batchRequest{request: r}
+	}
+
+	return requests, batch.Requests, nil
+}
+
+func writeJSONBatch(w http.ResponseWriter, requests []batchJSONRequest, responses []*http.Response) {
+	result := struct {
+		Responses []batchJSONResponse `json:"responses"`
+	}{
+		Responses: make([]batchJSONResponse, len(responses)),
+	}
+
+	for i, resp := range responses {
+		body, _ := io.ReadAll(resp.Body)
+
+		headers := make(map[string]string, len(resp.Header))
+		for key := range resp.Header {
+			headers[key] = resp.Header.Get(key)
+		}
+
+		raw := json.RawMessage(body)
+		if len(body) > 0 && !json.Valid(body) {
+			raw, _ = json.Marshal(string(body))
+		}
+
+		result.Responses[i] = batchJSONResponse{
+			ID:      requests[i].ID,
+			Status:  resp.StatusCode,
+			Headers: headers,
+			Body:    raw,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeMultipartBatch(w http.ResponseWriter, requests []batchRequest, responses []*http.Response) {
+	mw := multipart.NewWriter(w)
+
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+
+	for i, resp := range responses {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", "application/http")
+		if id := requests[i].contentID; id != "" {
+			header.Set("Content-ID", "response-"+id)
+		}
+
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return
+		}
+		if err := resp.Write(part); err != nil {
+			return
+		}
+	}
+
+	mw.Close()
+}
@@ -0,0 +1,175 @@
+package muxter
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newBatchTestMux() *Mux {
+	mux := New()
+	mux.GetFunc("/users/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"`+c.Param("id")+`","auth":"`+r.Header.Get("Authorization")+`"}`)
+	})
+	mux.PostFunc("/echo", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		io.Copy(w, r.Body)
+	})
+	mux.GetFunc("/panic", func(w http.ResponseWriter, r *http.Request, c Context) {
+		panic("boom")
+	})
+	mux.Handle("/batch", mux.Batch(BatchOptions{MaxRequests: 3, Concurrency: 2}))
+	return mux
+}
+
+func TestBatchJSON(t *testing.T) {
+	mux := newBatchTestMux()
+
+	testcases := []struct {
+		Name         string
+		Body         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name: "requests",
+			Body: `{"requests":[
+				{"id":"a","method":"GET","path":"/users/1"},
+				{"id":"b","method":"POST","path":"/echo","body":{"hello":"world"}},
+				{"id":"c","path":"/missing"}
+			]}`,
+			ExpectedCode: 200,
+			ExpectedBody: `{"responses":[` +
+				`{"id":"a","status":200,"headers":{"Content-Type":"application/json"},"body":{"id":"1","auth":"token"}},` +
+				`{"id":"b","status":200,"headers":{"Content-Type":"application/json"},"body":{"hello":"world"}},` +
+				`{"id":"c","status":404,"headers":{"Content-Type":"text/plain; charset=utf-8","X-Content-Type-Options":"nosniff"},"body":"Not Found\n"}` +
+				`]}` + "\n",
+		},
+		{
+			Name:         "panic",
+			Body:         `{"requests":[{"path":"/panic"}]}`,
+			ExpectedCode: 200,
+			ExpectedBody: `{"responses":[{"status":500}]}` + "\n",
+		},
+		{
+			Name:         "too many requests",
+			Body:         `{"requests":[{"path":"/users/1"},{"path":"/users/2"},{"path":"/users/3"},{"path":"/users/4"}]}`,
+			ExpectedCode: 413,
+			ExpectedBody: "muxter: batch exceeds the maximum of 3 requests\n",
+		},
+		{
+			Name:         "invalid path",
+			Body:         `{"requests":[{"path":"users"}]}`,
+			ExpectedCode: 400,
+			ExpectedBody: "muxter: invalid batch request: request 1: path must begin with a forward-slash but got: users\n",
+		},
+		{
+			Name:         "nested batch",
+			Body:         `{"requests":[{"method":"POST","path":"/batch","body":{"requests":[]}}]}`,
+			ExpectedCode: 200,
+			ExpectedBody: `{"responses":[{"status":400,"headers":{"Content-Type":"text/plain; charset=utf-8","X-Content-Type-Options":"nosniff"},"body":"muxter: nested batch requests are not supported\n"}]}` + "\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/batch", strings.NewReader(tc.Body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "token")
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body:\n%s\nbut got:\n%s", tc.ExpectedBody, body)
+			}
+		})
+	}
+}
+
+func TestBatchMultipart(t *testing.T) {
+	mux := newBatchTestMux()
+
+	body := strings.Join([]string{
+		"--batch",
+		"Content-Type: application/http",
+		"Content-ID: 1",
+		"",
+		"GET /users/7 HTTP/1.1",
+		"Host: example.com",
+		"",
+		"",
+		"--batch",
+		"Content-Type: application/http",
+		"Content-ID: 2",
+		"",
+		"POST /echo HTTP/1.1",
+		"Host: example.com",
+		"Content-Type: text/plain",
+		"Content-Length: 5",
+		"",
+		"hello",
+		"--batch--",
+		"",
+	}, "\r\n")
+
+	w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/batch", strings.NewReader(body))
+	r.Header.Set("Content-Type", "multipart/mixed; boundary=batch")
+	r.Header.Set("Authorization", "token")
+
+	mux.ServeHTTP(w, r)
+
+	if w.Code != 200 {
+		t.Fatalf("expected code 200 but got %d: %s", w.Code, w.Body.String())
+	}
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+
+	expected := []struct {
+		ContentID string
+		Status    int
+		Body      string
+	}{
+		{ContentID: "response-1", Status: 200, Body: `{"id":"7","auth":"token"}`},
+		{ContentID: "response-2", Status: 200, Body: "hello"},
+	}
+
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for _, exp := range expected {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id := part.Header.Get("Content-ID"); id != exp.ContentID {
+			t.Errorf("expected content id %q but got %q", exp.ContentID, id)
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+
+		if resp.StatusCode != exp.Status {
+			t.Errorf("expected status %d but got %d", exp.Status, resp.StatusCode)
+		}
+		if string(respBody) != exp.Body {
+			t.Errorf("expected body %q but got %q", exp.Body, respBody)
+		}
+	}
+
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected no more parts but got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+// Package benchmark benchmarks route tables against muxter and alternative routers, so that applications can measure
+// the routing cost of their own routes rather than of synthetic ones.
+//
+//	func BenchmarkRoutes(b *testing.B) {
+//		benchmark.Run(b, routes, benchmark.Muxter(), benchmark.ServeMux(), gorillaRouter)
+//	}
+//
+// Other routers, including other versions of muxter imported under a different module path, are compared by
+// adapting them with a Router. Benchmarks of a single router across muxter versions can also be compared by
+// running them with each version and comparing the results with benchstat.
+package benchmark
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/davidmdm/muxter"
+)
+
+// Route is a route of the table to benchmark.
+type Route struct {
+	// Method is the method of the route. It is empty for routes accepting every method.
+	Method string
+	// Pattern is the pattern of the route in muxter syntax, such as /users/:id or /files/*path.
+	Pattern string
+	// Path is the path of the request made for the route. It defaults to the pattern with its params replaced by
+	// their names, and must be set for patterns with regular expression params.
+	Path string
+}
+
+// Router is a router to benchmark.
+type Router struct {
+	// Name is the name of the router, used to name its benchmark.
+	Name string
+	// Build returns the router serving the routes with the handler.
+	Build func(routes []Route, handler http.Handler) (http.Handler, error)
+}
+
+// Result is the result of the benchmark of a router.
+type Result struct {
+	Router string
+	// Err is the error that prevented the router from being benchmarked, such as patterns it does not support or
+	// requests it does not route to their handler.
+	Err error
+	testing.BenchmarkResult
+}
+
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s\terror: %v", r.Router, r.Err)
+	}
+	return fmt.Sprintf("%s\t%s\t%s", r.Router, r.BenchmarkResult.String(), r.MemString())
+}
+
+// Run runs a sub-benchmark of b for every router serving requests for each of the routes in turn. Routers that
+// cannot serve the routes are skipped.
+func Run(b *testing.B, routes []Route, routers ...Router) {
+	for _, router := range routers {
+		b.Run(router.Name, func(b *testing.B) {
+			bench, err := prepare(routes, router)
+			if err != nil {
+				b.Skip(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			bench(b)
+		})
+	}
+}
+
+// Compare benchmarks every router serving requests for each of the routes in turn, outside of go test.
+func Compare(routes []Route, routers ...Router) []Result {
+	results := make([]Result, len(routers))
+	for i, router := range routers {
+		results[i].Router = router.Name
+
+		bench, err := prepare(routes, router)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].BenchmarkResult = testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			bench(b)
+		})
+	}
+	return results
+}
+
+// prepare builds the router and checks that it routes the request of every route to the handler. It returns the
+// benchmark of the router.
+func prepare(routes []Route, router Router) (func(b *testing.B), error) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	h, err := router.Build(routes, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]*http.Request, len(routes))
+	for i, route := range routes {
+		path, err := requestPath(route)
+		if err != nil {
+			return nil, err
+		}
+		method := route.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		requests[i] = httptest.NewRequest(method, path, nil)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, requests[i])
+		if w.Code != http.StatusNoContent {
+			return nil, fmt.Errorf("%s %s is not routed to its handler: status %d", method, path, w.Code)
+		}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no routes")
+	}
+
+	return func(b *testing.B) {
+		w := &discardWriter{header: http.Header{}}
+		for i := 0; i < b.N; i++ {
+			h.ServeHTTP(w, requests[i%len(requests)])
+		}
+	}, nil
+}
+
+var paramPattern = regexp.MustCompile(`[:*]([^/]*)`)
+
+func requestPath(route Route) (string, error) {
+	if route.Path != "" {
+		return route.Path, nil
+	}
+	if strings.Contains(route.Pattern, "#") {
+		return "", fmt.Errorf("route %s has a regular expression param and requires a path", route.Pattern)
+	}
+	return paramPattern.ReplaceAllString(route.Pattern, "$1"), nil
+}
+
+// Muxter returns the Router of a muxter.Mux. Patterns declared with several methods are registered with a
+// muxter.MethodHandler.
+func Muxter() Router {
+	return Router{
+		Name: "muxter",
+		Build: func(routes []Route, handler http.Handler) (http.Handler, error) {
+			methods := map[string]map[string]bool{}
+			var patterns []string
+			for _, route := range routes {
+				if methods[route.Pattern] == nil {
+					methods[route.Pattern] = map[string]bool{}
+					patterns = append(patterns, route.Pattern)
+				}
+				methods[route.Pattern][route.Method] = true
+			}
+
+			mux := muxter.New()
+			h := muxter.Adaptor(handler, muxter.NoContext)
+
+			err := func() (err error) {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						err = fmt.Errorf("%v", recovered)
+					}
+				}()
+
+				for _, pattern := range patterns {
+					if len(methods[pattern]) == 1 {
+						for method := range methods[pattern] {
+							if method == "" {
+								mux.Handle(pattern, h)
+							} else {
+								mux.Handle(pattern, h, mux.Method(method))
+							}
+						}
+						continue
+					}
+
+					var mh muxter.MethodHandler
+					for method := range methods[pattern] {
+						switch method {
+						case http.MethodGet:
+							mh.GET = h
+						case http.MethodHead:
+							mh.HEAD = h
+						case http.MethodPost:
+							mh.POST = h
+						case http.MethodPut:
+							mh.PUT = h
+						case http.MethodPatch:
+							mh.PATCH = h
+						case http.MethodDelete:
+							mh.DELETE = h
+						default:
+							return fmt.Errorf("pattern %s is declared with method %s and other methods", pattern, method)
+						}
+					}
+					mux.Handle(pattern, mh)
+				}
+				return nil
+			}()
+
+			return mux, err
+		},
+	}
+}
+
+// ServeMux returns the Router of a net/http ServeMux. It only supports route tables without params, and routes
+// requests regardless of their method.
+func ServeMux() Router {
+	return Router{
+		Name: "net/http",
+		Build: func(routes []Route, handler http.Handler) (http.Handler, error) {
+			mux := http.NewServeMux()
+			seen := map[string]bool{}
+			for _, route := range routes {
+				if strings.ContainsAny(route.Pattern, ":*#") {
+					return nil, fmt.Errorf("net/http ServeMux does not support the params of %s", route.Pattern)
+				}
+				if !seen[route.Pattern] {
+					seen[route.Pattern] = true
+					mux.Handle(route.Pattern, handler)
+				}
+			}
+			return mux, nil
+		},
+	}
+}
+
+// discardWriter is a response writer discarding the response, reused across requests.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
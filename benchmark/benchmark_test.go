@@ -0,0 +1,98 @@
+package benchmark
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var routes = []Route{
+	{Method: "GET", Pattern: "/users"},
+	{Method: "POST", Pattern: "/users"},
+	{Method: "GET", Pattern: "/users/:id"},
+	{Method: "DELETE", Pattern: "/users/:id"},
+	{Method: "GET", Pattern: "/users/:id/posts/:post"},
+	{Pattern: "/files/*path", Path: "/files/a/b.txt"},
+	{Method: "GET", Pattern: `/orders/#id:\d+`, Path: "/orders/42"},
+}
+
+var staticRoutes = []Route{
+	{Pattern: "/"},
+	{Pattern: "/about"},
+	{Pattern: "/static/"},
+}
+
+func TestCompare(t *testing.T) {
+	broken := Router{
+		Name: "broken",
+		Build: func(routes []Route, handler http.Handler) (http.Handler, error) {
+			return http.NotFoundHandler(), nil
+		},
+	}
+	failing := Router{
+		Name: "failing",
+		Build: func(routes []Route, handler http.Handler) (http.Handler, error) {
+			return nil, errors.New("unsupported")
+		},
+	}
+
+	results := Compare(routes, Muxter(), ServeMux(), broken, failing)
+
+	expected := []struct {
+		Router string
+		Err    string
+	}{
+		{Router: "muxter"},
+		{Router: "net/http", Err: "net/http ServeMux does not support the params of /users/:id"},
+		{Router: "broken", Err: "GET /users is not routed to its handler: status 404"},
+		{Router: "failing", Err: "unsupported"},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results but got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if result.Router != expected[i].Router {
+			t.Errorf("expected router %s but got %s", expected[i].Router, result.Router)
+		}
+		if err := result.Err; (err == nil) != (expected[i].Err == "") || (err != nil && err.Error() != expected[i].Err) {
+			t.Errorf("%s: expected error %q but got %v", result.Router, expected[i].Err, err)
+		}
+		if result.Err == nil && (result.N == 0 || !strings.HasPrefix(result.String(), "muxter\t")) {
+			t.Errorf("%s: expected benchmark to run but got %s", result.Router, result)
+		}
+	}
+}
+
+func TestRequestPath(t *testing.T) {
+	testcases := []struct {
+		Route    Route
+		Expected string
+		Err      string
+	}{
+		{Route: Route{Pattern: "/users/:id/posts/:post"}, Expected: "/users/id/posts/post"},
+		{Route: Route{Pattern: "/files/*path"}, Expected: "/files/path"},
+		{Route: Route{Pattern: "/static/"}, Expected: "/static/"},
+		{Route: Route{Pattern: "/users/:id", Path: "/users/7"}, Expected: "/users/7"},
+		{Route: Route{Pattern: `/orders/#id:\d+`}, Err: `route /orders/#id:\d+ has a regular expression param and requires a path`},
+	}
+
+	for _, tc := range testcases {
+		path, err := requestPath(tc.Route)
+		if path != tc.Expected {
+			t.Errorf("%s: expected path %q but got %q", tc.Route.Pattern, tc.Expected, path)
+		}
+		if (err == nil && tc.Err != "") || (err != nil && err.Error() != tc.Err) {
+			t.Errorf("%s: expected error %q but got %v", tc.Route.Pattern, tc.Err, err)
+		}
+	}
+}
+
+func BenchmarkRoutes(b *testing.B) {
+	Run(b, routes, Muxter(), ServeMux())
+}
+
+func BenchmarkStaticRoutes(b *testing.B) {
+	Run(b, staticRoutes, Muxter(), ServeMux())
+}
@@ -0,0 +1,419 @@
+package muxter
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindError is returned when request values cannot be bound into a struct. Status is the HTTP status code the
+// error should be reported with: 400 for missing or malformed values and 422 for values failing validation.
+type BindError struct {
+	Status int
+	// Source is where the value comes from, such as "query".
+	Source string
+	// Key is the name of the value, such as the query parameter's name. It is empty for validation errors.
+	Key string
+	Err error
+}
+
+func (err *BindError) Error() string {
+	if err.Key == "" {
+		return fmt.Sprintf("%s: %v", err.Source, err.Err)
+	}
+	return fmt.Sprintf("%s %q: %v", err.Source, err.Key, err.Err)
+}
+
+func (err *BindError) Unwrap() error { return err.Err }
+
+// BindQuery binds the query parameters of the request into the struct dst points to. Fields are bound from the
+// parameter named by their query tag, and fields without the tag are ignored except for embedded structs whose
+// fields are bound recursively:
+//
+//	var params struct {
+//		Page   int        `query:"page" default:"1"`
+//		Search string     `query:"q,required"`
+//		Tags   []string   `query:"tag"`
+//		Since  *time.Time `query:"since"`
+//	}
+//	err := muxter.BindQuery(r, &params)
+//
+// Supported field types are strings, booleans, integers, floats, time.Duration, time.Time in RFC 3339 format,
+// types implementing encoding.TextUnmarshaler, and pointers to and slices of those. Slices receive every value of
+// repeated parameters and other fields the first value. Missing parameters take the value of the default tag if
+// any, fields with the required option must be present, and pointer fields are left nil when absent. Once bound, dst
+// is validated if it implements interface{ Validate() error }. Failures are reported as a *BindError.
+func BindQuery(r *http.Request, dst interface{}) error {
+	query := r.URL.Query()
+	return bind(dst, queryBinder(query))
+}
+
+// BindHeader binds the headers of the request into the struct dst points to, following the same rules as BindQuery
+// with header tags naming the headers. Header names are case insensitive. Slice fields receive the elements of
+// comma separated header lists, and times may also be in the HTTP date format:
+//
+//	var headers struct {
+//		RequestID   string     `header:"X-Request-Id,required"`
+//		Version     int        `header:"Api-Version" default:"1"`
+//		ModifiedAt  *time.Time `header:"If-Modified-Since"`
+//		Preferences []string   `header:"Prefer"`
+//	}
+//	err := muxter.BindHeader(r, &headers)
+func BindHeader(r *http.Request, dst interface{}) error {
+	return bind(dst, headerBinder(r.Header))
+}
+
+func queryBinder(query url.Values) binder {
+	return binder{source: "query", lookup: func(key string, list bool) []string { return query[key] }}
+}
+
+func headerBinder(header http.Header) binder {
+	return binder{source: "header", lookup: func(key string, list bool) []string {
+		values := header.Values(key)
+		if !list {
+			return values
+		}
+		var elems []string
+		for _, value := range values {
+			for _, elem := range strings.Split(value, ",") {
+				if elem = strings.TrimSpace(elem); elem != "" {
+					elems = append(elems, elem)
+				}
+			}
+		}
+		return elems
+	}}
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// binder binds the values of a request source, such as its query, into the fields tagged with the source's name.
+// Fields are bound from the values returned by lookup for their key, or with decode for sources like the body that
+// bind a whole field at once.
+type binder struct {
+	source string
+	lookup func(key string, list bool) []string
+	decode func(field reflect.Value) error
+}
+
+// bind binds dst with the binders and validates it.
+func bind(dst interface{}, binders ...binder) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("muxter: binding requires a non-nil pointer to a struct but got %T", dst)
+	}
+
+	sources := make([]string, len(binders))
+	for i, b := range binders {
+		sources[i] = b.source
+	}
+
+	err := bindFields(rv.Elem().Type(), sources, func(field reflect.StructField, index []int, source, key, options string) error {
+		b := binders[0]
+		for _, candidate := range binders {
+			if candidate.source == source {
+				b = candidate
+			}
+		}
+
+		if b.decode != nil {
+			if err := b.decode(rv.Elem().FieldByIndex(index)); err != nil {
+				return &BindError{Status: http.StatusBadRequest, Source: source, Err: err}
+			}
+			return nil
+		}
+
+		values := b.lookup(key, isList(field.Type))
+		if len(values) == 0 {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				values = []string{def}
+			} else if options == "required" {
+				return &BindError{Status: http.StatusBadRequest, Source: source, Key: key, Err: errors.New("is required")}
+			} else {
+				return nil
+			}
+		}
+
+		if err := setField(rv.Elem().FieldByIndex(index), values); err != nil {
+			return &BindError{Status: http.StatusBadRequest, Source: source, Key: key, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if validator, ok := dst.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			source := "request"
+			if len(binders) == 1 {
+				source = binders[0].source
+			}
+			return &BindError{Status: http.StatusUnprocessableEntity, Source: source, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// bindFields calls fn for the exported fields of the struct type tagged with one of the sources, including the
+// fields of embedded structs, with the index of the field and its parsed tag.
+func bindFields(rt reflect.Type, sources []string, fn func(field reflect.StructField, index []int, source, key, options string) error) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		found := false
+		for _, source := range sources {
+			tag, ok := field.Tag.Lookup(source)
+			if !ok {
+				continue
+			}
+			found = true
+			if !field.IsExported() || tag == "-" {
+				break
+			}
+
+			key, options, _ := strings.Cut(tag, ",")
+			if key == "" {
+				key = field.Name
+			}
+			if err := fn(field, field.Index, source, key, options); err != nil {
+				return err
+			}
+			break
+		}
+
+		if !found && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			err := bindFields(field.Type, sources, func(embedded reflect.StructField, index []int, source, key, options string) error {
+				return fn(embedded, append([]int{i}, index...), source, key, options)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isList reports whether fields of the type are bound to every value rather than the first.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(field reflect.Value, values []string) error {
+	if isList(field.Type()) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = http.ParseTime(value); err != nil {
+				return fmt.Errorf("invalid time %q", value)
+			}
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// BindParams binds the params of the matched route into the struct dst points to, following the same rules as
+// BindQuery with param tags naming the params:
+//
+//	var params struct {
+//		ID int `param:"id"`
+//	}
+//	err := c.BindParams(&params)
+func (c Context) BindParams(dst interface{}) error {
+	return bind(dst, c.paramBinder())
+}
+
+// Bind binds the params, query parameters, headers and body of the request into the struct dst points to in a
+// single pass, with fields declaring their source with param, query, header or body tags. The field tagged
+// `body:"json"` receives the JSON decoded request body. Other fields follow the rules of BindQuery, and dst is
+// validated once every field is bound.
+//
+//	var req struct {
+//		ID      int    `param:"id"`
+//		DryRun  bool   `query:"dryRun"`
+//		IfMatch string `header:"If-Match,required"`
+//		Book    Book   `body:"json"`
+//	}
+//	err := c.Bind(r, &req)
+func (c Context) Bind(r *http.Request, dst interface{}) error {
+	return bind(dst, c.paramBinder(), queryBinder(r.URL.Query()), headerBinder(r.Header), bodyBinder(r))
+}
+
+func (c Context) paramBinder() binder {
+	return binder{source: "param", lookup: func(key string, list bool) []string {
+		if value, ok := c.lookupParam(key); ok {
+			return []string{value}
+		}
+		return nil
+	}}
+}
+
+func bodyBinder(r *http.Request) binder {
+	return binder{source: "body", decode: func(field reflect.Value) error {
+		if r.Body == nil || r.Body == http.NoBody {
+			return errors.New("is required")
+		}
+		if err := json.NewDecoder(r.Body).Decode(field.Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid json: %w", err)
+		}
+		return nil
+	}}
+}
+
+// ParameterInfo describes a request value bound by a route, as declared with the Binds registration option.
+type ParameterInfo struct {
+	// Name is the name of the param, query parameter or header.
+	Name string `json:"name"`
+	// In is where the value comes from: "path", "query", "header" or "body", as in OpenAPI parameter objects.
+	In string `json:"in"`
+	// Type is the JSON schema type of the value: "string", "integer", "number", "boolean", "array" or "object".
+	Type string `json:"type"`
+	// Required reports whether the value must be present.
+	Required bool `json:"required,omitempty"`
+	// Default is the value used when it is absent.
+	Default string `json:"default,omitempty"`
+}
+
+// Binds is a registration option that declares the struct a route binds its request into with Context.Bind, such
+// that the route's parameters are recorded in its RouteInfo for documentation and OpenAPI generation. It panics if
+// a param tag names a param that is not part of the route's pattern.
+//
+//	mux.HandleFunc("/books/:id", updateBook, muxter.Binds(UpdateBookRequest{}))
+func Binds(v interface{}) Middleware {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("muxter: Binds requires a struct but got %T", v))
+	}
+
+	return registrationOption(func(ri *RouteInfo) {
+		params := patternParams(ri.Pattern)
+
+		var parameters []ParameterInfo
+		bindFields(rt, []string{"param", "query", "header", "body"}, func(field reflect.StructField, index []int, source, key, options string) error {
+			info := ParameterInfo{Name: key, In: source, Type: schemaType(field.Type), Required: options == "required"}
+			info.Default = field.Tag.Get("default")
+
+			switch source {
+			case "param":
+				if !params[key] {
+					panic(fmt.Sprintf("muxter: route %s binds param %q that is not part of its pattern", ri.Pattern, key))
+				}
+				info.In, info.Required = "path", true
+			case "body":
+				info.Name, info.Required = field.Name, true
+			}
+
+			parameters = append(parameters, info)
+			return nil
+		})
+
+		ri.Parameters = append(ri.Parameters, parameters...)
+	})
+}
+
+// schemaType returns the JSON schema type of values bound into fields of the type.
+func schemaType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType || t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "string"
+	}
+}
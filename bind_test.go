@@ -0,0 +1,293 @@
+package muxter
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type queryParams struct {
+	Page    int            `query:"page" default:"1"`
+	Search  string         `query:"q,required"`
+	Tags    []string       `query:"tag"`
+	Since   *time.Time     `query:"since"`
+	Timeout time.Duration  `query:"timeout" default:"5s"`
+	Exact   bool           `query:"exact"`
+	Score   float64        `query:"score"`
+	IP      net.IP         `query:"ip"`
+	Ignored string         `query:"-"`
+	Limit   *uint8         `query:"limit"`
+	Other   map[string]int `json:"other"`
+	embeddedParams
+}
+
+type embeddedParams struct {
+	Sort string `query:"sort" default:"asc"`
+}
+
+type validatedParams struct {
+	Page int `query:"page"`
+}
+
+func (params validatedParams) Validate() error {
+	if params.Page > 10 {
+		return errors.New("page must be at most 10")
+	}
+	return nil
+}
+
+func TestBindQuery(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	limit := uint8(50)
+
+	testcases := []struct {
+		Name          string
+		Query         string
+		Expected      queryParams
+		ExpectedError string
+	}{
+		{
+			Name:     "defaults",
+			Query:    "q=go",
+			Expected: queryParams{Page: 1, Search: "go", Timeout: 5 * time.Second, embeddedParams: embeddedParams{Sort: "asc"}},
+		},
+		{
+			Name:  "values",
+			Query: "q=go&page=3&tag=a&tag=b&since=2024-01-02T03:04:05Z&timeout=1m&exact=true&score=0.5&ip=10.0.0.1&limit=50&sort=desc&Ignored=x",
+			Expected: queryParams{
+				Page:           3,
+				Search:         "go",
+				Tags:           []string{"a", "b"},
+				Since:          &since,
+				Timeout:        time.Minute,
+				Exact:          true,
+				Score:          0.5,
+				IP:             net.ParseIP("10.0.0.1"),
+				Limit:          &limit,
+				embeddedParams: embeddedParams{Sort: "desc"},
+			},
+		},
+		{
+			Name:          "missing required",
+			Query:         "page=2",
+			ExpectedError: `query "q": is required`,
+		},
+		{
+			Name:          "malformed integer",
+			Query:         "q=go&page=two",
+			ExpectedError: `query "page": invalid integer "two"`,
+		},
+		{
+			Name:          "overflow",
+			Query:         "q=go&limit=300",
+			ExpectedError: `query "limit": invalid unsigned integer "300"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var params queryParams
+			err := BindQuery(httptest.NewRequest("GET", "/search?"+tc.Query, nil), &params)
+
+			if tc.ExpectedError != "" {
+				var bindErr *BindError
+				if !errors.As(err, &bindErr) || bindErr.Status != 400 || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected bind error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(params, tc.Expected) {
+				t.Errorf("expected %+v but got %+v", tc.Expected, params)
+			}
+		})
+	}
+}
+
+func TestBindQueryValidation(t *testing.T) {
+	var params validatedParams
+	err := BindQuery(httptest.NewRequest("GET", "/?page=11", nil), &params)
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || bindErr.Status != 422 || err.Error() != "query: page must be at most 10" {
+		t.Fatalf("expected validation error but got %v", err)
+	}
+
+	if err := BindQuery(httptest.NewRequest("GET", "/", nil), params); err == nil {
+		t.Error("expected binding into a non-pointer to fail")
+	}
+}
+
+type headerParams struct {
+	RequestID   string     `header:"x-request-id,required"`
+	Version     int        `header:"Api-Version" default:"1"`
+	ModifiedAt  *time.Time `header:"If-Modified-Since"`
+	UserAgent   string     `header:"User-Agent"`
+	Preferences []string   `header:"Prefer"`
+}
+
+func TestBindHeader(t *testing.T) {
+	modifiedAt := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	testcases := []struct {
+		Name          string
+		Header        map[string][]string
+		Expected      headerParams
+		ExpectedError string
+	}{
+		{
+			Name:     "defaults",
+			Header:   map[string][]string{"X-Request-Id": {"abc"}},
+			Expected: headerParams{RequestID: "abc", Version: 1},
+		},
+		{
+			Name: "values",
+			Header: map[string][]string{
+				"X-Request-Id":      {"abc"},
+				"Api-Version":       {"3"},
+				"If-Modified-Since": {"Wed, 21 Oct 2015 07:28:00 GMT"},
+				"User-Agent":        {"curl/8.0 (x86_64, linux)"},
+				"Prefer":            {"return=minimal, respond-async", "wait=10"},
+			},
+			Expected: headerParams{
+				RequestID:   "abc",
+				Version:     3,
+				ModifiedAt:  &modifiedAt,
+				UserAgent:   "curl/8.0 (x86_64, linux)",
+				Preferences: []string{"return=minimal", "respond-async", "wait=10"},
+			},
+		},
+		{
+			Name:          "missing required",
+			Header:        map[string][]string{},
+			ExpectedError: `header "x-request-id": is required`,
+		},
+		{
+			Name:          "malformed time",
+			Header:        map[string][]string{"X-Request-Id": {"abc"}, "If-Modified-Since": {"yesterday"}},
+			ExpectedError: `header "If-Modified-Since": invalid time "yesterday"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for key, values := range tc.Header {
+				for _, value := range values {
+					r.Header.Add(key, value)
+				}
+			}
+
+			var params headerParams
+			err := BindHeader(r, &params)
+
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(params, tc.Expected) {
+				t.Errorf("expected %+v but got %+v", tc.Expected, params)
+			}
+		})
+	}
+}
+
+type updateBookRequest struct {
+	ID      int    `param:"id"`
+	DryRun  bool   `query:"dryRun"`
+	IfMatch string `header:"If-Match,required"`
+	Book    struct {
+		Title string `json:"title"`
+	} `body:"json"`
+}
+
+func TestBindParams(t *testing.T) {
+	type params struct {
+		ID    int    `param:"id"`
+		Shelf string `param:"shelf"`
+	}
+
+	var bound params
+	var err error
+
+	mux := New()
+	mux.HandleFunc("/shelves/:shelf/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		err = c.BindParams(&bound)
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/shelves/scifi/books/42", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (params{ID: 42, Shelf: "scifi"}); bound != expected {
+		t.Errorf("expected %+v but got %+v", expected, bound)
+	}
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/shelves/scifi/books/forty-two", nil))
+	if err == nil || err.Error() != `param "id": invalid integer "forty-two"` {
+		t.Errorf("expected invalid param error but got %v", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	var bound updateBookRequest
+	var err error
+
+	mux := New()
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		bound = updateBookRequest{}
+		err = c.Bind(r, &bound)
+	}, Binds(updateBookRequest{}))
+
+	r := httptest.NewRequest("PUT", "/books/7?dryRun=true", strings.NewReader(`{"title":"Dune"}`))
+	r.Header.Set("If-Match", `"v1"`)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bound.ID != 7 || !bound.DryRun || bound.IfMatch != `"v1"` || bound.Book.Title != "Dune" {
+		t.Errorf("unexpected bound request: %+v", bound)
+	}
+
+	r = httptest.NewRequest("PUT", "/books/7", strings.NewReader(`{"title":`))
+	r.Header.Set("If-Match", `"v1"`)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if err == nil || !strings.HasPrefix(err.Error(), "body: invalid json") {
+		t.Errorf("expected invalid body error but got %v", err)
+	}
+
+	expected := []ParameterInfo{
+		{Name: "id", In: "path", Type: "integer", Required: true},
+		{Name: "dryRun", In: "query", Type: "boolean"},
+		{Name: "If-Match", In: "header", Type: "string", Required: true},
+		{Name: "Book", In: "body", Type: "object", Required: true},
+	}
+	if parameters := mux.Routes()[0].Parameters; !reflect.DeepEqual(parameters, expected) {
+		t.Errorf("expected route parameters %+v but got %+v", expected, parameters)
+	}
+}
+
+func TestBindsUnknownParam(t *testing.T) {
+	defer func() {
+		expected := `muxter: route /books/:bookId binds param "id" that is not part of its pattern`
+		if recovered := recover(); recovered != expected {
+			t.Errorf("expected panic %q but got %v", expected, recovered)
+		}
+	}()
+
+	New().HandleFunc("/books/:bookId", func(w http.ResponseWriter, r *http.Request, c Context) {}, Binds(&updateBookRequest{}))
+}
@@ -0,0 +1,332 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// BlobStore is an object storage, such as S3 or GCS, receiving the files of multipart uploads served by
+// UploadToBlobStore.
+type BlobStore interface {
+	// CreateUpload starts the upload of an object in parts.
+	CreateUpload(ctx context.Context, key, contentType string) (BlobUpload, error)
+}
+
+// BlobUpload is the upload of an object in parts. Parts are uploaded in order, one at a time, and the object only
+// exists in the store once the upload is completed.
+type BlobUpload interface {
+	// UploadPart stores a part of the object. The data is only valid until UploadPart returns.
+	UploadPart(ctx context.Context, part BlobPart) error
+	// Complete assembles the uploaded parts into the object.
+	Complete(ctx context.Context) error
+	// Abort discards the uploaded parts.
+	Abort(ctx context.Context) error
+}
+
+// BlobPart is a part of an object uploaded to a BlobStore.
+type BlobPart struct {
+	// Number is the number of the part, starting at 1.
+	Number int
+	Data   []byte
+	// SHA256 is the SHA-256 checksum of Data, for stores verifying the integrity of parts such as S3 with its
+	// x-amz-checksum-sha256 header.
+	SHA256 []byte
+}
+
+// UploadedBlob is a file of a multipart request stored by UploadToBlobStore.
+type UploadedBlob struct {
+	// Field is the name of the form field of the file.
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	// Key is the key of the object in the store.
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	// SHA256 is the hex encoded SHA-256 checksum of the file.
+	SHA256 string `json:"sha256"`
+}
+
+// BlobUploadOptions configures UploadToBlobStore.
+type BlobUploadOptions struct {
+	// Store receives the files. It is required.
+	Store BlobStore
+	// PartSize is the size in bytes of the parts uploaded to the store, and the memory used to buffer the upload of
+	// a request. It defaults to 8MiB, S3 requiring parts of at least 5MiB but the last.
+	PartSize int
+	// MaxFileSize is the size in bytes of the largest file accepted. Requests with larger files are rejected with
+	// 413 Request Entity Too Large. Zero means no limit.
+	MaxFileSize int64
+	// MaxFieldsSize is the total size in bytes of the non-file form fields. It defaults to 1MiB.
+	MaxFieldsSize int64
+	// Key returns the key of the object storing a file. It defaults to a random hex identifier followed by the
+	// extension of the filename.
+	Key func(r *http.Request, c Context, field, filename string) string
+	// OnComplete responds once every file has been stored, with the non-file form fields of the request. It defaults
+	// to responding 201 Created with the uploaded blobs as a JSON array.
+	OnComplete func(w http.ResponseWriter, r *http.Request, c Context, blobs []UploadedBlob, fields url.Values)
+}
+
+// UploadProgress is the progress of the upload of a request served by UploadToBlobStore.
+type UploadProgress struct {
+	// Files is the number of files whose upload started.
+	Files int `json:"files"`
+	// Received is the number of bytes of files read from the request.
+	Received int64 `json:"received"`
+	// Stored is the number of bytes of files uploaded to the store.
+	Stored int64 `json:"stored"`
+}
+
+type uploadCounters struct {
+	files    atomic.Int64
+	received atomic.Int64
+	stored   atomic.Int64
+}
+
+// UploadProgress returns the progress of the upload of the request when it is served by UploadToBlobStore, such
+// that a middleware or a goroutine started by one can report it while the upload is ongoing. It returns the zero
+// value for requests not served by Mux.ServeHTTP.
+func (c Context) UploadProgress() UploadProgress {
+	c.checkOwner()
+	if c.lifecycle == nil {
+		return UploadProgress{}
+	}
+	ext := c.lifecycle.extension.Load()
+	if ext == nil {
+		return UploadProgress{}
+	}
+	counters := &ext.upload
+	return UploadProgress{
+		Files:    int(counters.files.Load()),
+		Received: counters.received.Load(),
+		Stored:   counters.stored.Load(),
+	}
+}
+
+var (
+	errBlobTooLarge       = errors.New("file too large")
+	errBlobFieldsTooLarge = errors.New("form fields too large")
+	errBlobChecksum       = errors.New("checksum mismatch")
+)
+
+// UploadToBlobStore returns a handler streaming the files of multipart/form-data requests to a blob store in parts,
+// without temporary files on the router's host: the memory used by a request is a single part. Files are only
+// completed in the store once the whole request has been read, and the uploads of the request are aborted if it
+// fails, such that a request stores all of its files or none unless the store fails while completing them.
+//
+// Files whose part has a Content-Digest header with a sha-256 digest, as defined by RFC 9530, are verified against
+// it, and requests with mismatching files are rejected with 400 Bad Request. Requests that are not multipart are
+// rejected with 415 Unsupported Media Type, and failures of the store are answered with 502 Bad Gateway. It panics
+// if the store is nil.
+//
+//	mux.Handle("/uploads", muxter.UploadToBlobStore(muxter.BlobUploadOptions{Store: s3Store, MaxFileSize: 5 << 30}))
+func UploadToBlobStore(opts BlobUploadOptions) Handler {
+	if opts.Store == nil {
+		panic("muxter: UploadToBlobStore requires a store")
+	}
+	if opts.PartSize <= 0 {
+		opts.PartSize = 8 << 20
+	}
+	if opts.MaxFieldsSize <= 0 {
+		opts.MaxFieldsSize = 1 << 20
+	}
+	if opts.Key == nil {
+		opts.Key = randomBlobKey
+	}
+	if opts.OnComplete == nil {
+		opts.OnComplete = func(w http.ResponseWriter, r *http.Request, c Context, blobs []UploadedBlob, fields url.Values) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(blobs)
+		}
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			writeStatus(w, c, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		u := &blobUploader{opts: opts, r: r, c: c, buf: make([]byte, opts.PartSize), fields: url.Values{}}
+		if c.lifecycle != nil {
+			u.progress = &c.lifecycle.extended().upload
+		} else {
+			u.progress = new(uploadCounters)
+		}
+
+		if err := u.readAll(reader); err != nil {
+			u.abort()
+			switch {
+			case errors.Is(err, errBlobTooLarge), errors.Is(err, errBlobFieldsTooLarge):
+				writeStatus(w, c, http.StatusRequestEntityTooLarge)
+			case errors.Is(err, errBlobChecksum), errors.As(err, new(blobReadError)):
+				writeStatus(w, c, http.StatusBadRequest)
+			default:
+				writeStatus(w, c, http.StatusBadGateway)
+			}
+			return
+		}
+
+		for i, upload := range u.uploads {
+			if err := upload.Complete(r.Context()); err != nil {
+				u.uploads = u.uploads[i:]
+				u.abort()
+				writeStatus(w, c, http.StatusBadGateway)
+				return
+			}
+		}
+
+		opts.OnComplete(w, r, c, u.blobs, u.fields)
+	})
+}
+
+// blobReadError is an error reading the request, as opposed to an error of the store.
+type blobReadError struct{ error }
+
+func (err blobReadError) Unwrap() error { return err.error }
+
+type blobUploader struct {
+	opts     BlobUploadOptions
+	r        *http.Request
+	c        Context
+	buf      []byte
+	progress *uploadCounters
+
+	uploads    []BlobUpload
+	blobs      []UploadedBlob
+	fields     url.Values
+	fieldsSize int64
+}
+
+func (u *blobUploader) readAll(reader *multipart.Reader) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return blobReadError{err}
+		}
+
+		if part.FileName() == "" {
+			err = u.readField(part)
+		} else {
+			err = u.upload(part)
+		}
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (u *blobUploader) readField(part *multipart.Part) error {
+	value, err := io.ReadAll(io.LimitReader(part, u.opts.MaxFieldsSize-u.fieldsSize+1))
+	if err != nil {
+		return blobReadError{err}
+	}
+	if u.fieldsSize += int64(len(value)); u.fieldsSize > u.opts.MaxFieldsSize {
+		return errBlobFieldsTooLarge
+	}
+	u.fields.Add(part.FormName(), string(value))
+	return nil
+}
+
+func (u *blobUploader) upload(part *multipart.Part) error {
+	ctx := u.r.Context()
+
+	blob := UploadedBlob{
+		Field:       part.FormName(),
+		Filename:    part.FileName(),
+		ContentType: part.Header.Get("Content-Type"),
+	}
+	if blob.ContentType == "" {
+		blob.ContentType = "application/octet-stream"
+	}
+	blob.Key = u.opts.Key(u.r, u.c, blob.Field, blob.Filename)
+
+	upload, err := u.opts.Store.CreateUpload(ctx, blob.Key, blob.ContentType)
+	if err != nil {
+		return fmt.Errorf("creating upload of %s: %w", blob.Key, err)
+	}
+	u.uploads = append(u.uploads, upload)
+	u.progress.files.Add(1)
+
+	checksum := sha256.New()
+	for number := 1; ; number++ {
+		n, err := io.ReadFull(part, u.buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return blobReadError{err}
+		}
+		if n == 0 && number > 1 {
+			break
+		}
+
+		blob.Size += int64(n)
+		u.progress.received.Add(int64(n))
+		if u.opts.MaxFileSize > 0 && blob.Size > u.opts.MaxFileSize {
+			return errBlobTooLarge
+		}
+
+		data := u.buf[:n]
+		checksum.Write(data)
+		sum := sha256.Sum256(data)
+		if err := upload.UploadPart(ctx, BlobPart{Number: number, Data: data, SHA256: sum[:]}); err != nil {
+			return fmt.Errorf("uploading part %d of %s: %w", number, blob.Key, err)
+		}
+		u.progress.stored.Add(int64(n))
+
+		if n < len(u.buf) {
+			break
+		}
+	}
+
+	sum := checksum.Sum(nil)
+	digests, err := parseDigestHeader(part.Header.Get("Content-Digest"), true)
+	if err != nil {
+		return blobReadError{err}
+	}
+	if expected, ok := digests["sha-256"]; ok && !bytes.Equal(expected, sum) {
+		return errBlobChecksum
+	}
+	blob.SHA256 = hex.EncodeToString(sum)
+
+	u.blobs = append(u.blobs, blob)
+	return nil
+}
+
+// abort aborts the uploads of the request. The request's context may be canceled already, as when the client went
+// away, so the uploads are aborted with a context that is not.
+func (u *blobUploader) abort() {
+	ctx := detachedContext{u.r.Context()}
+	for _, upload := range u.uploads {
+		upload.Abort(ctx)
+	}
+}
+
+func randomBlobKey(r *http.Request, c Context, field, filename string) string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	// The extension is kept only if it is plain, since filenames are chosen by clients.
+	ext := path.Ext(filename)
+	if len(ext) > 16 || strings.IndexFunc(ext[min(len(ext), 1):], func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) != -1 {
+		ext = ""
+	}
+	return hex.EncodeToString(id) + ext
+}
@@ -0,0 +1,247 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memoryBlobStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	parts   map[string][]int
+	aborted []string
+	fail    string
+}
+
+func (s *memoryBlobStore) CreateUpload(ctx context.Context, key, contentType string) (BlobUpload, error) {
+	if s.fail == key {
+		return nil, errors.New("store unavailable")
+	}
+	return &memoryBlobUpload{store: s, key: key}, nil
+}
+
+type memoryBlobUpload struct {
+	store *memoryBlobStore
+	key   string
+	data  []byte
+	parts []int
+}
+
+func (u *memoryBlobUpload) UploadPart(ctx context.Context, part BlobPart) error {
+	if sum := sha256.Sum256(part.Data); !bytes.Equal(sum[:], part.SHA256) || part.Number != len(u.parts)+1 {
+		return errors.New("corrupted part")
+	}
+	u.data = append(u.data, part.Data...)
+	u.parts = append(u.parts, len(part.Data))
+	return nil
+}
+
+func (u *memoryBlobUpload) Complete(ctx context.Context) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	u.store.objects[u.key] = u.data
+	u.store.parts[u.key] = u.parts
+	return nil
+}
+
+func (u *memoryBlobUpload) Abort(ctx context.Context) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	u.store.aborted = append(u.store.aborted, u.key)
+	return nil
+}
+
+type uploadFile struct {
+	Field, Filename, Content, Digest string
+}
+
+func multipartBody(t *testing.T, fields map[string]string, files ...uploadFile) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	for _, file := range files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="`+file.Field+`"; filename="`+file.Filename+`"`)
+		header.Set("Content-Type", "text/plain")
+		if file.Digest != "" {
+			header.Set("Content-Digest", file.Digest)
+		}
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(file.Content))
+	}
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func sha256Digest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+func TestUploadToBlobStore(t *testing.T) {
+	testcases := []struct {
+		Name            string
+		Files           []uploadFile
+		Fields          map[string]string
+		ContentType     string
+		Fail            string
+		ExpectedCode    int
+		ExpectedObjects map[string]string
+		ExpectedParts   map[string][]int
+		ExpectedAborted []string
+		ExpectedFields  url.Values
+	}{
+		{
+			Name: "files in parts",
+			Files: []uploadFile{
+				{Field: "doc", Filename: "a.txt", Content: "hello world", Digest: sha256Digest("hello world")},
+				{Field: "doc", Filename: "b.txt", Content: "12345678"},
+			},
+			Fields:          map[string]string{"album": "holidays"},
+			ExpectedCode:    201,
+			ExpectedObjects: map[string]string{"doc/a.txt": "hello world", "doc/b.txt": "12345678"},
+			ExpectedParts:   map[string][]int{"doc/a.txt": {4, 4, 3}, "doc/b.txt": {4, 4}},
+			ExpectedFields:  url.Values{"album": {"holidays"}},
+		},
+		{
+			Name:            "checksum mismatch",
+			Files:           []uploadFile{{Field: "doc", Filename: "a.txt", Content: "hello world", Digest: sha256Digest("hello")}},
+			ExpectedCode:    400,
+			ExpectedAborted: []string{"doc/a.txt"},
+		},
+		{
+			Name: "too large",
+			Files: []uploadFile{
+				{Field: "doc", Filename: "a.txt", Content: "small"},
+				{Field: "doc", Filename: "b.txt", Content: strings.Repeat("x", 17)},
+			},
+			ExpectedCode:    413,
+			ExpectedAborted: []string{"doc/a.txt", "doc/b.txt"},
+		},
+		{
+			Name: "store failure",
+			Files: []uploadFile{
+				{Field: "doc", Filename: "a.txt", Content: "small"},
+				{Field: "doc", Filename: "b.txt", Content: "small"},
+			},
+			Fail:            "doc/b.txt",
+			ExpectedCode:    502,
+			ExpectedAborted: []string{"doc/a.txt"},
+		},
+		{
+			Name:         "not multipart",
+			ContentType:  "application/json",
+			ExpectedCode: 415,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			store := &memoryBlobStore{objects: map[string][]byte{}, parts: map[string][]int{}, fail: tc.Fail}
+
+			var fields url.Values
+			var progress UploadProgress
+
+			mux := New()
+			mux.Handle("/uploads", UploadToBlobStore(BlobUploadOptions{
+				Store:       store,
+				PartSize:    4,
+				MaxFileSize: 16,
+				Key: func(r *http.Request, c Context, field, filename string) string {
+					return field + "/" + filename
+				},
+				OnComplete: func(w http.ResponseWriter, r *http.Request, c Context, blobs []UploadedBlob, values url.Values) {
+					fields, progress = values, c.UploadProgress()
+					w.WriteHeader(http.StatusCreated)
+					json.NewEncoder(w).Encode(blobs)
+				},
+			}))
+
+			body, contentType := multipartBody(t, tc.Fields, tc.Files...)
+			if tc.ContentType != "" {
+				contentType = tc.ContentType
+			}
+			r := httptest.NewRequest("POST", "/uploads", body)
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("expected code %d but got %d: %s", tc.ExpectedCode, w.Code, w.Body.String())
+			}
+			if !reflect.DeepEqual(store.aborted, tc.ExpectedAborted) {
+				t.Errorf("expected aborted uploads %q but got %q", tc.ExpectedAborted, store.aborted)
+			}
+			if tc.ExpectedCode != 201 {
+				if len(store.objects) != 0 {
+					t.Errorf("expected no objects but got %d", len(store.objects))
+				}
+				return
+			}
+
+			objects := map[string]string{}
+			for key, data := range store.objects {
+				objects[key] = string(data)
+			}
+			if !reflect.DeepEqual(objects, tc.ExpectedObjects) {
+				t.Errorf("expected objects %q but got %q", tc.ExpectedObjects, objects)
+			}
+			if !reflect.DeepEqual(store.parts, tc.ExpectedParts) {
+				t.Errorf("expected parts %v but got %v", tc.ExpectedParts, store.parts)
+			}
+			if !reflect.DeepEqual(fields, tc.ExpectedFields) {
+				t.Errorf("expected fields %v but got %v", tc.ExpectedFields, fields)
+			}
+
+			var blobs []UploadedBlob
+			json.Unmarshal(w.Body.Bytes(), &blobs)
+			sum := sha256.Sum256([]byte("hello world"))
+			if len(blobs) != 2 || blobs[0].Key != "doc/a.txt" || blobs[0].Size != 11 || blobs[0].SHA256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("unexpected blobs %+v", blobs)
+			}
+			if expected := (UploadProgress{Files: 2, Received: 19, Stored: 19}); progress != expected {
+				t.Errorf("expected progress %+v but got %+v", expected, progress)
+			}
+		})
+	}
+}
+
+func TestRandomBlobKey(t *testing.T) {
+	testcases := []struct {
+		Filename    string
+		ExpectedExt string
+	}{
+		{Filename: "photo.jpeg", ExpectedExt: ".jpeg"},
+		{Filename: "archive", ExpectedExt: ""},
+		{Filename: "evil.<script>", ExpectedExt: ""},
+		{Filename: "../../etc/passwd.txt", ExpectedExt: ".txt"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Filename, func(t *testing.T) {
+			key := randomBlobKey(nil, Context{}, "file", tc.Filename)
+			if len(key) != 32+len(tc.ExpectedExt) || !strings.HasSuffix(key, tc.ExpectedExt) || strings.Contains(key, "/") {
+				t.Errorf("expected a random key with extension %q but got %q", tc.ExpectedExt, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,600 @@
+package muxter
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheOptions configures a Cache.
+type CacheOptions struct {
+	// TTL is how long responses are cached when they are marked public but do not set a lifetime with the max-age or
+	// s-maxage directives of their Cache-Control header, and responses without freshness directives when
+	// HeuristicFreshness is set. It defaults to one minute.
+	TTL time.Duration
+	// HeuristicFreshness caches the responses without the max-age, s-maxage or public directives in their
+	// Cache-Control header for TTL. By default only responses marked cacheable by their handler are cached, such that
+	// handlers serving per-user content without saying so are not cached for everyone.
+	HeuristicFreshness bool
+	// MaxEntries is the maximum number of responses cached, evicting the least recently used. It defaults to 1024.
+	MaxEntries int
+	// MaxBodySize is the size in bytes of the largest response body cached. It defaults to 1MiB.
+	MaxBodySize int
+	// AuthorizePurge enables the PURGE and BAN methods on the routes the cache is used on, serving them if it returns
+	// true and responding with 403 Forbidden otherwise. PURGE invalidates the responses cached for the request's path
+	// and BAN the responses cached for paths starting with it, unless the request has a Surrogate-Key header in which
+	// case the responses tagged with its space separated keys are invalidated. When it is nil these requests are
+	// passed to the handler.
+	AuthorizePurge func(*http.Request) bool
+	// EarlyRevalidation weighs how early popular responses are revalidated before they expire. It defaults to 1, and
+	// a negative value disables early revalidation.
+	EarlyRevalidation float64
+	// NotFoundSubtrees are the rooted subtrees, such as "/wp-admin/", under which 404 Not Found responses are cached to
+	// absorb the traffic of scanners cheaply. Requests not matching any route are only served through the cache when
+	// it is used with Mux.UseGlobal.
+	NotFoundSubtrees []string
+	// NotFoundTTL is how long not found responses are cached. It defaults to 10 seconds.
+	NotFoundTTL time.Duration
+	// Key builds the keys of cached responses. By default the keys are made of the host, path and sorted query
+	// parameters of requests.
+	Key CacheKey
+	// Clock expires cached responses. It defaults to the system clock.
+	Clock Clock
+	// Rand draws the early revalidations of responses. It defaults to the global source of math/rand.
+	Rand Rand
+}
+
+// CacheStats are the counters of a Cache.
+type CacheStats struct {
+	// Hits is the number of requests served from the cache.
+	Hits uint64
+	// Misses is the number of GET requests that could have been served from the cache but were served by the handler.
+	Misses uint64
+	// NotFoundHits is the number of requests absorbed by cached not found responses. It is included in Hits.
+	NotFoundHits uint64
+}
+
+// Cache is an in-memory HTTP cache for responses served through a mux. It is shared by the routes it is used on so
+// that requests with unsafe methods invalidate the responses cached for the URLs they modify.
+type Cache struct {
+	opts CacheOptions
+
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	notFoundHits atomic.Uint64
+
+	mu      sync.Mutex
+	flights map[string]chan struct{}
+	entries map[string]*list.Element
+	order   *list.List
+	paths   map[string]map[string]struct{}
+	keys    map[string]map[string]struct{}
+}
+
+// NewCache returns a cache configured by opts. Its Middleware method caches the responses of the routes it is used on.
+//
+//	cache := muxter.NewCache(muxter.CacheOptions{TTL: 30 * time.Second})
+//	mux.Use(cache.Middleware)
+func NewCache(opts CacheOptions) *Cache {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1024
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 1 << 20
+	}
+	if opts.EarlyRevalidation == 0 {
+		opts.EarlyRevalidation = 1
+	}
+	if opts.NotFoundTTL <= 0 {
+		opts.NotFoundTTL = 10 * time.Second
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	if opts.Rand == nil {
+		opts.Rand = systemRand{}
+	}
+	return &Cache{
+		opts:    opts,
+		flights: map[string]chan struct{}{},
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		paths:   map[string]map[string]struct{}{},
+		keys:    map[string]map[string]struct{}{},
+	}
+}
+
+type cacheEntry struct {
+	key     string
+	path    string
+	pattern string
+	keys    []string
+	code    int
+	header  http.Header
+	body    []byte
+	vary    map[string]string
+	delta   time.Duration
+	stored  time.Time
+	expires time.Time
+}
+
+// Middleware is a middleware caching the responses of the handler. Successful responses to GET requests are cached,
+// keyed by host, path and query as configured by the Key option, and serve later GET and HEAD requests until they
+// expire, as are not found responses under the NotFoundSubtrees option. Responses are only cached if their
+// Cache-Control header has the max-age, s-maxage or public directives, unless the HeuristicFreshness option is set,
+// and never if they set cookies, if their Cache-Control header has the no-store, no-cache or private directives, or if
+// they vary on every request header. Requests with an Authorization or a Cookie header, or with the no-store or no-cache
+// directives, bypass the cache. Responses served from the cache have an Age header.
+//
+// To protect handlers from stampedes, concurrent requests for a response that is not cached wait for the first one
+// to be served rather than all calling the handler. Responses are also revalidated before they expire with a
+// probability growing as they near expiry and with the time their handler took, such that a single request
+// revalidates a popular response while the others are still served from the cache.
+//
+// Requests with other methods are never served from the cache, and PURGE and BAN requests are served by the cache
+// when the AuthorizePurge option is set. When they succeed, with a status below 400, the
+// responses cached for their path and for the paths of their Location and Content-Location headers are invalidated,
+// across query strings. Responses to GET requests are buffered so that they can be cached.
+func (cache *Cache) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		target := c.requestURL(r)
+
+		if (r.Method == "PURGE" || r.Method == "BAN") && cache.opts.AuthorizePurge != nil {
+			if !cache.opts.AuthorizePurge(r) {
+				writeStatus(w, c, http.StatusForbidden)
+				return
+			}
+			purged := 0
+			switch keys := strings.Fields(r.Header.Get("Surrogate-Key")); {
+			case len(keys) > 0:
+				purged = cache.InvalidateKey(keys...)
+			case r.Method == "PURGE":
+				purged = cache.Invalidate(target.Path)
+			default:
+				purged = cache.InvalidatePrefix(target.Path)
+			}
+			writePurged(w, purged)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			proxy := &responseProxy{ResponseWriter: w}
+			h.ServeHTTPx(proxy, r, c)
+			if r.Method != http.MethodOptions && r.Method != http.MethodTrace && proxy.Code() < 400 {
+				cache.invalidateModified(r, target.Path, proxy.Header())
+			}
+			return
+		}
+
+		directives := cacheDirectives(r.Header.Get("Cache-Control"))
+		_, noStore := directives["no-store"]
+		_, noCache := directives["no-cache"]
+		if noStore || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+			h.ServeHTTPx(w, r, c)
+			return
+		}
+
+		key := cache.opts.Key.Key(r, c)
+		if r.Method == http.MethodHead {
+			if !noCache {
+				if entry, ok := cache.lookup(key, r); ok {
+					cache.serve(w, r, entry)
+					return
+				}
+			}
+			h.ServeHTTPx(w, r, c)
+			return
+		}
+
+		if !noCache {
+			entry, fill, wait := cache.acquire(key, r)
+			if wait != nil {
+				select {
+				case <-wait:
+					entry, _ = cache.lookup(key, r)
+				case <-r.Context().Done():
+				}
+			}
+			if entry != nil {
+				cache.serve(w, r, entry)
+				return
+			}
+			if fill != nil {
+				defer fill()
+			}
+		}
+
+		cache.misses.Add(1)
+		start := cache.opts.Clock.Now()
+
+		rec := &dispatchRecorder{header: w.Header()}
+		h.ServeHTTPx(rec, r, c)
+		rec.WriteHeader(http.StatusOK)
+
+		if ttl, ok := cache.ttl(rec, target.Path); ok {
+			now := cache.opts.Clock.Now()
+			cache.store(&cacheEntry{
+				key:     key,
+				path:    target.Path,
+				pattern: c.Pattern(),
+				keys:    surrogateKeys(rec.sent, c),
+				code:    rec.code,
+				header:  rec.sent,
+				body:    append([]byte(nil), rec.body.Bytes()...),
+				vary:    varyValues(rec.sent, r),
+				delta:   now.Sub(start),
+				stored:  now,
+				expires: now.Add(ttl),
+			})
+		}
+
+		w.WriteHeader(rec.code)
+		w.Write(rec.body.Bytes())
+	})
+}
+
+// Invalidate removes the responses cached for the path, across hosts and query strings, and returns their number.
+func (cache *Cache) Invalidate(path string) int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var n int
+	for key := range cache.paths[path] {
+		cache.remove(cache.entries[key])
+		n++
+	}
+	return n
+}
+
+// InvalidateKey removes the responses tagged with any of the surrogate keys and returns their number.
+func (cache *Cache) InvalidateKey(keys ...string) int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var n int
+	for _, key := range keys {
+		for entryKey := range cache.keys[key] {
+			cache.remove(cache.entries[entryKey])
+			n++
+		}
+	}
+	return n
+}
+
+// InvalidatePrefix removes the responses cached for the paths starting with prefix and returns their number.
+func (cache *Cache) InvalidatePrefix(prefix string) int {
+	return cache.invalidateFunc(func(entry *cacheEntry) bool { return strings.HasPrefix(entry.path, prefix) })
+}
+
+// InvalidatePattern removes the responses cached for the routes registered with the pattern, such as /books/:id, and
+// returns their number.
+func (cache *Cache) InvalidatePattern(pattern string) int {
+	return cache.invalidateFunc(func(entry *cacheEntry) bool { return entry.pattern == pattern })
+}
+
+func (cache *Cache) invalidateFunc(match func(*cacheEntry) bool) int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var n int
+	for elem := cache.order.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*cacheEntry)) {
+			cache.remove(elem)
+			n++
+		}
+		elem = next
+	}
+	return n
+}
+
+// PurgeHandler returns a handler for an administrative endpoint invalidating cached responses, selected by the
+// query parameters path, prefix, pattern and key as with the Invalidate, InvalidatePrefix, InvalidatePattern and
+// InvalidateKey methods.
+// It responds with the number of responses invalidated, such as {"purged":3}. The handler must be guarded by the
+// application's authorization.
+//
+//	mux.Handle("/admin/cache", cache.PurgeHandler(), mux.Method("POST"), requireAdmin)
+func (cache *Cache) PurgeHandler() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		query := r.URL.Query()
+
+		var purged int
+		for _, path := range query["path"] {
+			purged += cache.Invalidate(path)
+		}
+		for _, prefix := range query["prefix"] {
+			purged += cache.InvalidatePrefix(prefix)
+		}
+		for _, pattern := range query["pattern"] {
+			purged += cache.InvalidatePattern(pattern)
+		}
+		purged += cache.InvalidateKey(query["key"]...)
+		writePurged(w, purged)
+	})
+}
+
+func writePurged(w http.ResponseWriter, purged int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"purged":` + strconv.Itoa(purged) + "}\n"))
+}
+
+// invalidateModified invalidates the responses for the path of a request modifying a resource, and for the paths of
+// the resources its response links to on the same host.
+func (cache *Cache) invalidateModified(r *http.Request, path string, header http.Header) {
+	cache.Invalidate(path)
+	for _, location := range []string{header.Get("Location"), header.Get("Content-Location")} {
+		if location == "" {
+			continue
+		}
+		u, err := url.Parse(location)
+		if err != nil || (u.Host != "" && !strings.EqualFold(u.Host, r.Host)) {
+			continue
+		}
+		if u.Path != "" && u.Path != path {
+			cache.Invalidate(u.Path)
+		}
+	}
+}
+
+// ttl returns how long the recorded response for the path can be cached, and false if it cannot be cached.
+func (cache *Cache) ttl(rec *dispatchRecorder, path string) (time.Duration, bool) {
+	notFound := rec.code == http.StatusNotFound && cache.negative(path)
+	if (rec.code != http.StatusOK && !notFound) || rec.body.Len() > cache.opts.MaxBodySize {
+		return 0, false
+	}
+	if len(rec.sent["Set-Cookie"]) > 0 || varyAll(rec.sent) {
+		return 0, false
+	}
+
+	directives := cacheDirectives(rec.sent.Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[directive]; ok {
+			return 0, false
+		}
+	}
+	if notFound {
+		return cache.opts.NotFoundTTL, true
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[directive]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if _, public := directives["public"]; public || cache.opts.HeuristicFreshness {
+		return cache.opts.TTL, true
+	}
+	return 0, false
+}
+
+// varyAll reports whether the response varies on every request header, with a Vary header listing *.
+func varyAll(header http.Header) bool {
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.TrimSpace(field) == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// negative reports whether not found responses for the path are cached.
+func (cache *Cache) negative(path string) bool {
+	for _, subtree := range cache.opts.NotFoundSubtrees {
+		if strings.HasPrefix(path, subtree) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the counters of the cache.
+func (cache *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:         cache.hits.Load(),
+		Misses:       cache.misses.Load(),
+		NotFoundHits: cache.notFoundHits.Load(),
+	}
+}
+
+func (cache *Cache) lookup(key string, r *http.Request) (*cacheEntry, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.fresh(key, r)
+	return entry, entry != nil
+}
+
+// acquire returns the entry serving the request. If there is none, or if the entry should be revalidated early, it
+// returns a function to call once the response is stored to make the request the one filling the entry, or a channel
+// closed when the request filling the entry is done if there is one.
+func (cache *Cache) acquire(key string, r *http.Request) (entry *cacheEntry, fill func(), wait <-chan struct{}) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry = cache.fresh(key, r)
+	flight, inFlight := cache.flights[key]
+
+	if entry != nil && (inFlight || !cache.revalidate(entry)) {
+		return entry, nil, nil
+	}
+	if inFlight {
+		return nil, nil, flight
+	}
+
+	flight = make(chan struct{})
+	cache.flights[key] = flight
+
+	return nil, func() {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		delete(cache.flights, key)
+		close(flight)
+	}, nil
+}
+
+// fresh returns the unexpired entry for the key matching the request. The cache's lock must be held.
+func (cache *Cache) fresh(key string, r *http.Request) *cacheEntry {
+	elem, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if cache.opts.Clock.Now().After(entry.expires) {
+		cache.remove(elem)
+		return nil
+	}
+	for name, value := range entry.vary {
+		if r.Header.Get(name) != value {
+			return nil
+		}
+	}
+	cache.order.MoveToFront(elem)
+	return entry
+}
+
+// revalidate reports whether the entry should be revalidated before it expires, using the probabilistic early
+// expiration of Vattani et al., "Optimal Probabilistic Cache Stampede Prevention".
+func (cache *Cache) revalidate(entry *cacheEntry) bool {
+	beta := cache.opts.EarlyRevalidation
+	if beta < 0 {
+		return false
+	}
+	early := -entry.delta.Seconds() * beta * math.Log(cache.opts.Rand.Float64())
+	return early >= entry.expires.Sub(cache.opts.Clock.Now()).Seconds()
+}
+
+func (cache *Cache) store(entry *cacheEntry) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.entries[entry.key]; ok {
+		cache.remove(elem)
+	}
+
+	cache.entries[entry.key] = cache.order.PushFront(entry)
+	if cache.paths[entry.path] == nil {
+		cache.paths[entry.path] = map[string]struct{}{}
+	}
+	cache.paths[entry.path][entry.key] = struct{}{}
+	for _, key := range entry.keys {
+		if cache.keys[key] == nil {
+			cache.keys[key] = map[string]struct{}{}
+		}
+		cache.keys[key][entry.key] = struct{}{}
+	}
+
+	if cache.order.Len() > cache.opts.MaxEntries {
+		cache.remove(cache.order.Back())
+	}
+}
+
+// remove removes the element from the cache. The cache's lock must be held.
+func (cache *Cache) remove(elem *list.Element) {
+	entry := cache.order.Remove(elem).(*cacheEntry)
+	delete(cache.entries, entry.key)
+	delete(cache.paths[entry.path], entry.key)
+	if len(cache.paths[entry.path]) == 0 {
+		delete(cache.paths, entry.path)
+	}
+	for _, key := range entry.keys {
+		delete(cache.keys[key], entry.key)
+		if len(cache.keys[key]) == 0 {
+			delete(cache.keys, key)
+		}
+	}
+}
+
+func (cache *Cache) serve(w http.ResponseWriter, r *http.Request, entry *cacheEntry) {
+	cache.hits.Add(1)
+	if entry.code == http.StatusNotFound {
+		cache.notFoundHits.Add(1)
+	}
+
+	header := w.Header()
+	for key, values := range entry.header {
+		header[key] = append([]string(nil), values...)
+	}
+	header.Set("Age", strconv.Itoa(int(cache.opts.Clock.Now().Sub(entry.stored)/time.Second)))
+	header.Set("Content-Length", strconv.Itoa(len(entry.body)))
+
+	w.WriteHeader(entry.code)
+	if r.Method != http.MethodHead {
+		w.Write(entry.body)
+	}
+}
+
+// SurrogateKeys tags the response with surrogate keys, such as "book-1" and "author-7" for a page showing a book
+// and its author, so that responses cached by a Cache can be invalidated together with Cache.InvalidateKey when the
+// content they are built from changes. Responses can also be tagged with a Surrogate-Key header of space separated
+// keys, as understood by CDNs. SurrogateKeys has no effect if the request is not served by Mux.ServeHTTP.
+func (c Context) SurrogateKeys(keys ...string) {
+	c.checkOwner()
+	if c.lifecycle == nil {
+		return
+	}
+	ext := c.lifecycle.extended()
+	ext.surrogate = append(ext.surrogate, keys...)
+}
+
+// surrogateKeys returns the surrogate keys of the response, given with Context.SurrogateKeys or its Surrogate-Key
+// header.
+func surrogateKeys(header http.Header, c Context) []string {
+	var keys []string
+	if c.lifecycle != nil {
+		if ext := c.lifecycle.extension.Load(); ext != nil {
+			keys = append(keys, ext.surrogate...)
+		}
+	}
+	for _, value := range header.Values("Surrogate-Key") {
+		keys = append(keys, strings.Fields(value)...)
+	}
+	return keys
+}
+
+// varyValues returns the values of the request headers the response varies on.
+func varyValues(header http.Header, r *http.Request) map[string]string {
+	var values map[string]string
+	for _, vary := range header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if values == nil {
+				values = map[string]string{}
+			}
+			values[name] = r.Header.Get(name)
+		}
+	}
+	return values
+}
+
+// cacheDirectives parses the directives of a Cache-Control header into a map of their lowercase names to their
+// unquoted values.
+func cacheDirectives(header string) map[string]string {
+	directives := map[string]string{}
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(directive, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return directives
+}
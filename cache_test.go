@@ -0,0 +1,567 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var calls atomic.Int32
+
+	cache := NewCache(CacheOptions{})
+
+	mux := New()
+	mux.Use(cache.Middleware)
+
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		switch r.Method {
+		case "GET", "HEAD":
+			n := calls.Add(1)
+			w.Header().Set("Vary", "Accept-Language")
+			switch r.URL.Query().Get("cache") {
+			case "private":
+				w.Header().Set("Cache-Control", "private")
+			case "cookie":
+				w.Header().Set("Cache-Control", "max-age=60")
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			case "vary-all":
+				w.Header().Set("Cache-Control", "max-age=60")
+				w.Header().Add("Vary", "*")
+			case "unmarked":
+			default:
+				w.Header().Set("Cache-Control", "max-age=60")
+			}
+			w.Write([]byte(c.Param("id") + " v" + strconv.Itoa(int(n)) + " " + r.Header.Get("Accept-Language")))
+		case "PUT":
+			w.WriteHeader(http.StatusNoContent)
+		case "DELETE":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Location", "/books/3")
+		w.WriteHeader(http.StatusCreated)
+	}, mux.Method("POST"))
+
+	type step struct {
+		Method       string
+		Target       string
+		Header       map[string]string
+		ExpectedCode int
+		ExpectedBody string
+		ExpectedHit  bool
+	}
+
+	testcases := []struct {
+		Name  string
+		Steps []step
+	}{
+		{
+			Name: "hit",
+			Steps: []step{
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v1 "},
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v1 ", ExpectedHit: true},
+				{Method: "HEAD", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "", ExpectedHit: true},
+				{Method: "GET", Target: "/books/1?page=2", ExpectedCode: 200, ExpectedBody: "1 v2 "},
+			},
+		},
+		{
+			Name: "vary",
+			Steps: []step{
+				{Method: "GET", Target: "/books/1", Header: map[string]string{"Accept-Language": "fr"}, ExpectedCode: 200, ExpectedBody: "1 v1 fr"},
+				{Method: "GET", Target: "/books/1", Header: map[string]string{"Accept-Language": "fr"}, ExpectedCode: 200, ExpectedBody: "1 v1 fr", ExpectedHit: true},
+				{Method: "GET", Target: "/books/1", Header: map[string]string{"Accept-Language": "en"}, ExpectedCode: 200, ExpectedBody: "1 v2 en"},
+			},
+		},
+		{
+			Name: "bypass",
+			Steps: []step{
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v1 "},
+				{Method: "GET", Target: "/books/1", Header: map[string]string{"Authorization": "Bearer token"}, ExpectedCode: 200, ExpectedBody: "1 v2 "},
+				{Method: "GET", Target: "/books/1", Header: map[string]string{"Cookie": "session=1"}, ExpectedCode: 200, ExpectedBody: "1 v3 "},
+				{Method: "GET", Target: "/books/1", Header: map[string]string{"Cache-Control": "no-cache"}, ExpectedCode: 200, ExpectedBody: "1 v4 "},
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v4 ", ExpectedHit: true},
+			},
+		},
+		{
+			Name: "uncacheable responses",
+			Steps: []step{
+				{Method: "GET", Target: "/books/1?cache=private", ExpectedCode: 200, ExpectedBody: "1 v1 "},
+				{Method: "GET", Target: "/books/1?cache=private", ExpectedCode: 200, ExpectedBody: "1 v2 "},
+				{Method: "GET", Target: "/books/1?cache=cookie", ExpectedCode: 200, ExpectedBody: "1 v3 "},
+				{Method: "GET", Target: "/books/1?cache=cookie", ExpectedCode: 200, ExpectedBody: "1 v4 "},
+				{Method: "GET", Target: "/books/1?cache=vary-all", ExpectedCode: 200, ExpectedBody: "1 v5 "},
+				{Method: "GET", Target: "/books/1?cache=vary-all", ExpectedCode: 200, ExpectedBody: "1 v6 "},
+				{Method: "GET", Target: "/books/1?cache=unmarked", ExpectedCode: 200, ExpectedBody: "1 v7 "},
+				{Method: "GET", Target: "/books/1?cache=unmarked", ExpectedCode: 200, ExpectedBody: "1 v8 "},
+			},
+		},
+		{
+			Name: "head miss is not cached",
+			Steps: []step{
+				{Method: "HEAD", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v1 "},
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v2 "},
+			},
+		},
+		{
+			Name: "invalidated by successful unsafe methods",
+			Steps: []step{
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v1 "},
+				{Method: "GET", Target: "/books/1?page=2", ExpectedCode: 200, ExpectedBody: "1 v2 "},
+				{Method: "GET", Target: "/books/2", ExpectedCode: 200, ExpectedBody: "2 v3 "},
+				{Method: "DELETE", Target: "/books/1", ExpectedCode: 403},
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v1 ", ExpectedHit: true},
+				{Method: "PUT", Target: "/books/1", ExpectedCode: 204},
+				{Method: "GET", Target: "/books/1", ExpectedCode: 200, ExpectedBody: "1 v4 "},
+				{Method: "GET", Target: "/books/1?page=2", ExpectedCode: 200, ExpectedBody: "1 v5 "},
+				{Method: "GET", Target: "/books/2", ExpectedCode: 200, ExpectedBody: "2 v3 ", ExpectedHit: true},
+			},
+		},
+		{
+			Name: "invalidated by location",
+			Steps: []step{
+				{Method: "GET", Target: "/books/3", ExpectedCode: 200, ExpectedBody: "3 v1 "},
+				{Method: "POST", Target: "/books", ExpectedCode: 201},
+				{Method: "GET", Target: "/books/3", ExpectedCode: 200, ExpectedBody: "3 v2 "},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			calls.Store(0)
+			for _, path := range []string{"/books/1", "/books/2", "/books/3"} {
+				cache.Invalidate(path)
+			}
+
+			for i, step := range tc.Steps {
+				r := httptest.NewRequest(step.Method, step.Target, nil)
+				for key, value := range step.Header {
+					r.Header.Set(key, value)
+				}
+
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, r)
+
+				if w.Code != step.ExpectedCode {
+					t.Errorf("step %d: expected status %d but got %d", i, step.ExpectedCode, w.Code)
+				}
+				if w.Body.String() != step.ExpectedBody {
+					t.Errorf("step %d: expected body %q but got %q", i, step.ExpectedBody, w.Body.String())
+				}
+				if hit := w.Header().Get("Age") != ""; hit != step.ExpectedHit {
+					t.Errorf("step %d: expected cache hit to be %v", i, step.ExpectedHit)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheLifetime(t *testing.T) {
+	var calls int
+
+	cache := NewCache(CacheOptions{MaxEntries: 1})
+	handler := cache.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		w.Header().Set("Cache-Control", r.URL.Query().Get("cc"))
+		w.Write([]byte("ok"))
+	}))
+
+	get := func(target string) {
+		handler.ServeHTTPx(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil), Context{})
+	}
+
+	get("/a?cc=max-age=0")
+	get("/a?cc=max-age=0")
+	if calls != 2 {
+		t.Errorf("expected response with max-age=0 not to be cached but handler was called %d times", calls)
+	}
+
+	get("/a?cc=s-maxage=60,max-age=0")
+	get("/a?cc=s-maxage=60,max-age=0")
+	if calls != 3 {
+		t.Errorf("expected s-maxage to take precedence but handler was called %d times", calls)
+	}
+
+	get("/b?cc=public")
+	get("/a?cc=s-maxage=60,max-age=0")
+	if calls != 5 {
+		t.Errorf("expected least recently used entry to be evicted but handler was called %d times", calls)
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	cache := NewCache(CacheOptions{
+		HeuristicFreshness: true,
+		AuthorizePurge:     func(r *http.Request) bool { return r.Header.Get("X-Purge-Token") == "secret" },
+	})
+
+	mux := New()
+	mux.Use(cache.Middleware)
+
+	var calls int
+	respond := func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		w.Write([]byte(strconv.Itoa(calls)))
+	}
+	mux.HandleFunc("/books/:id", respond)
+	mux.HandleFunc("/authors/:id", respond)
+	mux.Handle("/admin/cache", cache.PurgeHandler(), mux.Method("POST"))
+
+	serve := func(method, target, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			r.Header.Set("X-Purge-Token", token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	fill := func() {
+		for _, target := range []string{"/books/1", "/books/1?page=2", "/books/2", "/authors/1"} {
+			serve("GET", target, "")
+		}
+	}
+
+	testcases := []struct {
+		Name         string
+		Method       string
+		Target       string
+		Token        string
+		ExpectedCode int
+		ExpectedBody string
+		Remaining    int
+	}{
+		{
+			Name:         "purge",
+			Method:       "PURGE",
+			Target:       "/books/1",
+			Token:        "secret",
+			ExpectedCode: 200,
+			ExpectedBody: "{\"purged\":2}\n",
+			Remaining:    2,
+		},
+		{
+			Name:         "ban",
+			Method:       "BAN",
+			Target:       "/books/",
+			Token:        "secret",
+			ExpectedCode: 404,
+			ExpectedBody: "Not Found\n",
+			Remaining:    4,
+		},
+		{
+			Name:         "ban path",
+			Method:       "BAN",
+			Target:       "/books/2",
+			Token:        "secret",
+			ExpectedCode: 200,
+			ExpectedBody: "{\"purged\":1}\n",
+			Remaining:    3,
+		},
+		{
+			Name:         "unauthorized",
+			Method:       "PURGE",
+			Target:       "/books/1",
+			Token:        "guess",
+			ExpectedCode: 403,
+			ExpectedBody: "Forbidden\n",
+			Remaining:    4,
+		},
+		{
+			Name:         "admin by pattern",
+			Method:       "POST",
+			Target:       "/admin/cache?pattern=/books/:id",
+			ExpectedCode: 200,
+			ExpectedBody: "{\"purged\":3}\n",
+			Remaining:    1,
+		},
+		{
+			Name:         "admin by prefix and path",
+			Method:       "POST",
+			Target:       "/admin/cache?prefix=/books/&path=/authors/1",
+			ExpectedCode: 200,
+			ExpectedBody: "{\"purged\":4}\n",
+			Remaining:    0,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cache.InvalidatePrefix("/")
+			fill()
+
+			w := serve(tc.Method, tc.Target, tc.Token)
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+
+			before := calls
+			fill()
+			if remaining := 4 - (calls - before); remaining != tc.Remaining {
+				t.Errorf("expected %d responses to remain cached but got %d", tc.Remaining, remaining)
+			}
+		})
+	}
+}
+
+func TestCacheSurrogateKeys(t *testing.T) {
+	cache := NewCache(CacheOptions{HeuristicFreshness: true, AuthorizePurge: func(r *http.Request) bool { return true }})
+
+	mux := New()
+	mux.Use(cache.Middleware)
+
+	var calls int
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		c.SurrogateKeys("book-"+c.Param("id"), "author-"+r.URL.Query().Get("author"))
+		w.Write([]byte("book"))
+	})
+	mux.HandleFunc("/authors/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		w.Header().Set("Surrogate-Key", "author-"+c.Param("id")+" authors")
+		w.Write([]byte("author"))
+	})
+	mux.Handle("/admin/cache", cache.PurgeHandler())
+
+	serve := func(method, target string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		for key, value := range header {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	targets := []string{"/books/1?author=7", "/books/2?author=7", "/books/3?author=8", "/authors/7", "/authors/8"}
+	fill := func() int {
+		before := calls
+		for _, target := range targets {
+			serve("GET", target, nil)
+		}
+		return calls - before
+	}
+
+	testcases := []struct {
+		Name         string
+		Method       string
+		Target       string
+		Header       map[string]string
+		ExpectedBody string
+	}{
+		{
+			Name:         "single key",
+			Method:       "POST",
+			Target:       "/admin/cache?key=book-1",
+			ExpectedBody: "{\"purged\":1}\n",
+		},
+		{
+			Name:         "shared key",
+			Method:       "POST",
+			Target:       "/admin/cache?key=author-7",
+			ExpectedBody: "{\"purged\":3}\n",
+		},
+		{
+			Name:         "several keys",
+			Method:       "POST",
+			Target:       "/admin/cache?key=author-7&key=authors",
+			ExpectedBody: "{\"purged\":4}\n",
+		},
+		{
+			Name:         "purge request",
+			Method:       "PURGE",
+			Target:       "/books/1",
+			Header:       map[string]string{"Surrogate-Key": "author-8 book-2"},
+			ExpectedBody: "{\"purged\":3}\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cache.InvalidatePrefix("/")
+			fill()
+
+			if w := serve(tc.Method, tc.Target, tc.Header); w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+
+	cache.InvalidatePrefix("/")
+	fill()
+	if n := fill(); n != 0 {
+		t.Errorf("expected all responses to be cached but handler was called %d times", n)
+	}
+	cache.InvalidatePrefix("/books/")
+	if n := cache.InvalidateKey("book-1", "author-7", "authors"); n != 2 {
+		t.Errorf("expected keys of invalidated responses to be removed but invalidated %d responses", n)
+	}
+}
+
+func TestCacheStampede(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	cache := NewCache(CacheOptions{HeuristicFreshness: true})
+	handler := cache.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte("popular"))
+	}))
+
+	const n = 20
+
+	var started, done sync.WaitGroup
+	bodies := make(chan string, n)
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTPx(w, httptest.NewRequest("GET", "/popular", nil), Context{})
+			bodies <- w.Body.String()
+		}()
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(bodies)
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected concurrent requests to call the handler once but got %d calls", n)
+	}
+	for body := range bodies {
+		if body != "popular" {
+			t.Errorf("expected every request to be served the response but got %q", body)
+		}
+	}
+}
+
+func TestCacheEarlyRevalidation(t *testing.T) {
+	testcases := []struct {
+		Name              string
+		EarlyRevalidation float64
+		ExpectedCalls     int
+	}{
+		{
+			Name:              "revalidated early",
+			EarlyRevalidation: 1e9,
+			ExpectedCalls:     2,
+		},
+		{
+			Name:              "disabled",
+			EarlyRevalidation: -1,
+			ExpectedCalls:     1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var calls int
+			cache := NewCache(CacheOptions{TTL: time.Hour, HeuristicFreshness: true, EarlyRevalidation: tc.EarlyRevalidation})
+			handler := cache.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				calls++
+				time.Sleep(time.Millisecond)
+				w.Write([]byte("slow"))
+			}))
+
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				handler.ServeHTTPx(w, httptest.NewRequest("GET", "/slow", nil), Context{})
+				if w.Body.String() != "slow" {
+					t.Errorf("expected body slow but got %q", w.Body.String())
+				}
+			}
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("expected %d calls but got %d", tc.ExpectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestCacheNotFound(t *testing.T) {
+	cache := NewCache(CacheOptions{HeuristicFreshness: true, NotFoundSubtrees: []string{"/wp-admin/", "/api/"}})
+
+	var calls int
+	mux := New()
+	mux.UseGlobal(cache.Middleware)
+	mux.SetNotFoundHandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/api/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		if c.Param("id") != "1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("book"))
+	})
+
+	testcases := []struct {
+		Name          string
+		Path          string
+		ExpectedCode  int
+		ExpectedCalls int
+	}{
+		{
+			Name:          "unmatched path",
+			Path:          "/wp-admin/setup.php",
+			ExpectedCode:  404,
+			ExpectedCalls: 1,
+		},
+		{
+			Name:          "missing resource",
+			Path:          "/api/books/2",
+			ExpectedCode:  404,
+			ExpectedCalls: 1,
+		},
+		{
+			Name:          "found",
+			Path:          "/api/books/1",
+			ExpectedCode:  200,
+			ExpectedCalls: 1,
+		},
+		{
+			Name:          "outside of subtrees",
+			Path:          "/.env",
+			ExpectedCode:  404,
+			ExpectedCalls: 3,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			calls = 0
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+				if w.Code != tc.ExpectedCode {
+					t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+				}
+			}
+			if calls != tc.ExpectedCalls {
+				t.Errorf("expected %d calls but got %d", tc.ExpectedCalls, calls)
+			}
+		})
+	}
+
+	expected := CacheStats{Hits: 6, Misses: 6, NotFoundHits: 4}
+	if stats := cache.Stats(); stats != expected {
+		t.Errorf("expected stats %+v but got %+v", expected, stats)
+	}
+}
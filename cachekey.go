@@ -0,0 +1,55 @@
+package muxter
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CacheKey builds the canonical keys identifying the responses cached by a Cache and the requests it coalesces, such
+// that requests differing only in the order of their query parameters or in parameters ignored by the application
+// share a key, raising the hit rate.
+type CacheKey struct {
+	// IgnoreParams are the query parameters left out of keys, such as tracking parameters. A trailing * matches names
+	// by prefix, as in "utm_*".
+	IgnoreParams []string
+}
+
+// Key returns the key of the request: its host, path and query parameters sorted by name. The values of a repeated
+// parameter keep their order, which can be significant to handlers. The path and query are those of the request as
+// received by the mux.
+func (k CacheKey) Key(r *http.Request, c Context) string {
+	target := c.requestURL(r)
+	if target.RawQuery == "" {
+		return r.Host + " " + target.EscapedPath()
+	}
+
+	query, err := url.ParseQuery(target.RawQuery)
+	if err != nil {
+		return r.Host + " " + target.RequestURI()
+	}
+	for name := range query {
+		if matchesParam(name, k.IgnoreParams) {
+			delete(query, name)
+		}
+	}
+	if len(query) == 0 {
+		return r.Host + " " + target.EscapedPath()
+	}
+	return r.Host + " " + target.EscapedPath() + "?" + query.Encode()
+}
+
+func matchesParam(name string, params []string) bool {
+	for _, param := range params {
+		if prefix := strings.TrimSuffix(param, "*"); prefix != param {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if name == param {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheKey(t *testing.T) {
+	key := CacheKey{IgnoreParams: []string{"utm_*", "fbclid"}}
+
+	testcases := []struct {
+		Name        string
+		Target      string
+		ExpectedKey string
+	}{
+		{
+			Name:        "no query",
+			Target:      "http://example.com/books",
+			ExpectedKey: "example.com /books",
+		},
+		{
+			Name:        "sorted params",
+			Target:      "http://example.com/books?sort=title&page=2",
+			ExpectedKey: "example.com /books?page=2&sort=title",
+		},
+		{
+			Name:        "repeated params keep their order",
+			Target:      "http://example.com/books?tag=b&page=2&tag=a",
+			ExpectedKey: "example.com /books?page=2&tag=b&tag=a",
+		},
+		{
+			Name:        "ignored params",
+			Target:      "http://example.com/books?utm_source=mail&page=2&fbclid=abc&utm_medium=email",
+			ExpectedKey: "example.com /books?page=2",
+		},
+		{
+			Name:        "only ignored params",
+			Target:      "http://example.com/books?utm_source=mail",
+			ExpectedKey: "example.com /books",
+		},
+		{
+			Name:        "escaping",
+			Target:      "http://example.com/books%20new?q=a+b&author=%C3%A9",
+			ExpectedKey: "example.com /books%20new?author=%C3%A9&q=a+b",
+		},
+		{
+			Name:        "invalid query",
+			Target:      "http://example.com/books?q=%zz&page=2",
+			ExpectedKey: "example.com /books?q=%zz&page=2",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if k := key.Key(httptest.NewRequest("GET", tc.Target, nil), Context{}); k != tc.ExpectedKey {
+				t.Errorf("expected key %q but got %q", tc.ExpectedKey, k)
+			}
+		})
+	}
+}
+
+func TestCacheKeyOption(t *testing.T) {
+	var calls int
+	cache := NewCache(CacheOptions{HeuristicFreshness: true, Key: CacheKey{IgnoreParams: []string{"utm_*"}}})
+
+	mux := New()
+	mux.Use(cache.Middleware)
+	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		w.Write([]byte("books"))
+	})
+
+	for _, target := range []string{"/books?page=2&sort=title", "/books?sort=title&page=2", "/books?sort=title&utm_source=mail&page=2"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	if calls != 1 {
+		t.Errorf("expected equivalent requests to share a cached response but handler was called %d times", calls)
+	}
+}
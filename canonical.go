@@ -0,0 +1,126 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CanonicalPolicy describes how the canonical URL of a route is computed from the requests it serves.
+type CanonicalPolicy struct {
+	// Template is the pattern of the canonical URL, filled in with the params of the request, such as "/books/:id"
+	// for a route also registered as "/b/:id". Its params must be params of the route. It defaults to the pattern
+	// of the route.
+	Template string `json:"template,omitempty"`
+	// Origin is the scheme and host of canonical URLs, such as "https://www.example.com". It defaults to the origin
+	// of the request as seen by the client.
+	Origin string `json:"origin,omitempty"`
+	// Query are the query parameters kept in canonical URLs. Other parameters, such as tracking parameters, are
+	// dropped.
+	Query []string `json:"query,omitempty"`
+	// Lowercase lowercases the path of canonical URLs.
+	Lowercase bool `json:"lowercase,omitempty"`
+	// Redirect redirects GET and HEAD requests whose URL is not canonical to the canonical URL with 301 Moved
+	// Permanently, rather than serving them with a canonical link.
+	Redirect bool `json:"redirect,omitempty"`
+}
+
+// Canonical is a registration option declaring the canonical URL of a route, for sites whose pages can be reached
+// under several URLs, such as several patterns or with tracking parameters. Responses carry a
+// `Link: <url>; rel="canonical"` header for search engines, and the policy is listed in the route's metadata.
+//
+//	mux.HandleFunc("/books/:id", book, muxter.Canonical(muxter.CanonicalPolicy{Origin: "https://www.example.com"}))
+//	mux.HandleFunc("/b/:id", book, muxter.Canonical(muxter.CanonicalPolicy{Template: "/books/:id", Redirect: true}))
+func Canonical(policy CanonicalPolicy) Middleware {
+	policy.Origin = strings.TrimSuffix(policy.Origin, "/")
+
+	return func(h Handler) Handler {
+		return routeOption{
+			Handler: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				canonical, ok := policy.url(r, c)
+				if !ok {
+					h.ServeHTTPx(w, r, c)
+					return
+				}
+
+				if policy.Redirect && (r.Method == http.MethodGet || r.Method == http.MethodHead) && !policy.matches(canonical, r, c) {
+					http.Redirect(w, r, canonical.String(), http.StatusMovedPermanently)
+					return
+				}
+
+				w.Header().Add("Link", "<"+canonical.String()+`>; rel="canonical"`)
+				h.ServeHTTPx(w, r, c)
+			}),
+			apply: func(ri *RouteInfo) {
+				template := policy.Template
+				if template == "" {
+					template = ri.Pattern
+				}
+				params := patternParams(ri.Pattern)
+				for param := range patternParams(template) {
+					if !params[param] {
+						panic(fmt.Sprintf("muxter: canonical template %s has param %q which is not a param of route %s", template, param, ri.Pattern))
+					}
+				}
+				p := policy
+				ri.Canonical = &p
+			},
+		}
+	}
+}
+
+// url returns the canonical URL of the request, and false if it cannot be computed such as for routes with host
+// patterns without template.
+func (p CanonicalPolicy) url(r *http.Request, c Context) (*url.URL, bool) {
+	template := p.Template
+	if template == "" {
+		template = c.Pattern()
+	}
+	if isHostPattern(template) {
+		return nil, false
+	}
+
+	path, err := expandPattern(template, c.lookupParam)
+	if err != nil {
+		return nil, false
+	}
+	if p.Template == "" && strings.HasSuffix(template, "/") {
+		// Rooted subtrees serve many paths, each its own canonical path.
+		path = c.requestURL(r).Path
+	}
+	if p.Lowercase {
+		path = strings.ToLower(path)
+	}
+
+	origin := p.Origin
+	if origin == "" {
+		origin = externalOrigin(r)
+	}
+	canonical, err := url.Parse(origin)
+	if err != nil {
+		return nil, false
+	}
+	canonical.Path = path
+
+	if len(p.Query) > 0 {
+		query, kept := c.requestURL(r).Query(), url.Values{}
+		for _, key := range p.Query {
+			if values, ok := query[key]; ok {
+				kept[key] = values
+			}
+		}
+		canonical.RawQuery = kept.Encode()
+	}
+
+	return canonical, true
+}
+
+// matches reports whether the request was made to the canonical URL.
+func (p CanonicalPolicy) matches(canonical *url.URL, r *http.Request, c Context) bool {
+	requested := c.requestURL(r)
+	if requested.Path != canonical.Path || requested.RawQuery != canonical.RawQuery {
+		return false
+	}
+	return p.Origin == "" || strings.EqualFold(externalOrigin(r), p.Origin)
+}
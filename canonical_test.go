@@ -0,0 +1,100 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	book := func(w http.ResponseWriter, r *http.Request, c Context) { w.Write([]byte("book " + c.Param("id"))) }
+
+	mux := New()
+	mux.HandleFunc("/books/:id", book, Canonical(CanonicalPolicy{Origin: "https://www.example.com/", Query: []string{"page"}}))
+	mux.HandleFunc("/b/:id", book, Canonical(CanonicalPolicy{Template: "/books/:id", Origin: "https://www.example.com", Redirect: true}))
+	mux.HandleFunc("/Authors/:name", book, Canonical(CanonicalPolicy{Lowercase: true}))
+	mux.HandleFunc("/docs/", book, Canonical(CanonicalPolicy{Redirect: true}))
+
+	cases := []struct {
+		Name             string
+		Method           string
+		Target           string
+		ExpectedCode     int
+		ExpectedLink     string
+		ExpectedLocation string
+	}{
+		{
+			Name:         "pattern",
+			Target:       "/books/1?utm_source=mail&page=2",
+			ExpectedCode: 200,
+			ExpectedLink: `<https://www.example.com/books/1?page=2>; rel="canonical"`,
+		},
+		{
+			Name:             "redirect to template",
+			Target:           "/b/1?utm_source=mail",
+			ExpectedCode:     301,
+			ExpectedLocation: "https://www.example.com/books/1",
+		},
+		{
+			Name:         "no redirect for other methods",
+			Method:       "POST",
+			Target:       "/b/1",
+			ExpectedCode: 200,
+			ExpectedLink: `<https://www.example.com/books/1>; rel="canonical"`,
+		},
+		{
+			Name:         "lowercase with request origin",
+			Target:       "/Authors/Ursula",
+			ExpectedCode: 200,
+			ExpectedLink: `<http://example.com/authors/ursula>; rel="canonical"`,
+		},
+		{
+			Name:         "subtree",
+			Target:       "/docs/intro",
+			ExpectedCode: 200,
+			ExpectedLink: `<http://example.com/docs/intro>; rel="canonical"`,
+		},
+		{
+			Name:             "subtree query",
+			Target:           "/docs/intro?ref=home",
+			ExpectedCode:     301,
+			ExpectedLocation: "http://example.com/docs/intro",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			method := tc.Method
+			if method == "" {
+				method = "GET"
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(method, tc.Target, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if link := w.Header().Get("Link"); link != tc.ExpectedLink {
+				t.Errorf("expected link %q but got %q", tc.ExpectedLink, link)
+			}
+			if location := w.Header().Get("Location"); location != tc.ExpectedLocation {
+				t.Errorf("expected location %q but got %q", tc.ExpectedLocation, location)
+			}
+		})
+	}
+
+	routes := mux.Routes()
+	if policy := routes[1].Canonical; routes[1].Pattern != "/b/:id" || policy == nil || policy.Template != "/books/:id" {
+		t.Errorf("expected canonical policy in route metadata but got %+v", routes[1])
+	}
+}
+
+func TestCanonicalTemplateParams(t *testing.T) {
+	defer func() {
+		expected := `muxter: canonical template /books/:isbn has param "isbn" which is not a param of route /b/:id`
+		if recovered := recover(); recovered != expected {
+			t.Errorf("expected panic %q but got %v", expected, recovered)
+		}
+	}()
+	New().HandleFunc("/b/:id", func(w http.ResponseWriter, r *http.Request, c Context) {}, Canonical(CanonicalPolicy{Template: "/books/:isbn"}))
+}
@@ -0,0 +1,95 @@
+package muxter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Catalog provides the messages used for the bodies of built-in responses, such as not found and method not
+// allowed, by locale and status code.
+type Catalog interface {
+	// Message returns the message for the status in the locale, and false if the catalog has no such message.
+	// The locale is the one negotiated by the AcceptLanguage middleware and is empty when it is not in use.
+	Message(locale string, status int) (string, bool)
+}
+
+// MessageCatalog is a Catalog of messages by locale and status code. Lookups fall back from a locale such as
+// "fr-CA" to its language "fr", and then to the messages of the empty locale.
+//
+//	muxter.MessageCatalog{
+//		"fr": {404: "Page introuvable", 405: "Méthode non autorisée"},
+//		"":   {404: "Not found"},
+//	}
+type MessageCatalog map[string]map[int]string
+
+func (mc MessageCatalog) Message(locale string, status int) (string, bool) {
+	for {
+		if msg, ok := mc[locale][status]; ok {
+			return msg, true
+		}
+		if locale == "" {
+			return "", false
+		}
+		if idx := strings.LastIndexByte(locale, '-'); idx != -1 {
+			locale = locale[:idx]
+		} else {
+			locale = ""
+		}
+	}
+}
+
+// Messages sets the catalog used for the bodies of the mux's built-in responses. Messages missing from the
+// catalog default to the standard status text. Nested muxes use the catalog of their parent unless they set their own.
+func Messages(catalog Catalog) MuxOption {
+	return func(m *Mux) {
+		m.catalog = catalog
+	}
+}
+
+// statusText returns the body of a built-in response for the status.
+func (c Context) statusText(status int) string {
+	if c.extra().catalog != nil {
+		if msg, ok := c.extra().catalog.Message(c.locale, status); ok {
+			return msg
+		}
+	}
+	return http.StatusText(status)
+}
+
+// writeStatus writes a built-in response for the status, as JSON if the mux is configured with JSONErrors.
+func writeStatus(w http.ResponseWriter, c Context, status int) {
+	if c.extra().catalog != nil && c.locale != "" {
+		w.Header().Set("Content-Language", c.locale)
+		w.Header().Add("Vary", "Accept-Language")
+	}
+
+	msg := c.statusText(status)
+	if c.jsonErrors && msg == http.StatusText(status) {
+		msg = strings.ToLower(msg)
+	}
+	writeMessage(w, c, status, msg)
+}
+
+// writeMessage writes a built-in response for the status with the message as its body, as JSON if the mux is
+// configured with JSONErrors.
+func writeMessage(w http.ResponseWriter, c Context, status int, msg string) {
+	if !c.jsonErrors {
+		http.Error(w, msg, status)
+		return
+	}
+
+	body, _ := json.Marshal(errorBody{Error: msg, Status: status})
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// errorBody is the body of built-in responses when the mux is configured with JSONErrors.
+type errorBody struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
@@ -0,0 +1,189 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMessageCatalog(t *testing.T) {
+	catalog := MessageCatalog{
+		"fr":    {404: "Page introuvable", 405: "Méthode non autorisée"},
+		"fr-CA": {404: "Page pas trouvée"},
+		"":      {404: "Nothing here"},
+	}
+
+	parent := New(Messages(catalog))
+	parent.UseGlobal(AcceptLanguage("en", "fr", "fr-CA"))
+	parent.GetFunc("/resource", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	child := New()
+	child.PostFunc("/resource", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	parent.Handle("/child/", StripDepth(1, child))
+
+	testcases := []struct {
+		Name                    string
+		Method                  string
+		Path                    string
+		AcceptLanguage          string
+		ExpectedCode            int
+		ExpectedBody            string
+		ExpectedContentLanguage string
+	}{
+		{
+			Name:                    "not found in language",
+			Method:                  "GET",
+			Path:                    "/missing",
+			AcceptLanguage:          "fr",
+			ExpectedCode:            404,
+			ExpectedBody:            "Page introuvable\n",
+			ExpectedContentLanguage: "fr",
+		},
+		{
+			Name:                    "not found in region",
+			Method:                  "GET",
+			Path:                    "/missing",
+			AcceptLanguage:          "fr-CA",
+			ExpectedCode:            404,
+			ExpectedBody:            "Page pas trouvée\n",
+			ExpectedContentLanguage: "fr-CA",
+		},
+		{
+			Name:                    "region falls back to language",
+			Method:                  "POST",
+			Path:                    "/resource",
+			AcceptLanguage:          "fr-CA",
+			ExpectedCode:            405,
+			ExpectedBody:            "Méthode non autorisée\n",
+			ExpectedContentLanguage: "fr-CA",
+		},
+		{
+			Name:                    "falls back to empty locale",
+			Method:                  "GET",
+			Path:                    "/missing",
+			AcceptLanguage:          "en",
+			ExpectedCode:            404,
+			ExpectedBody:            "Nothing here\n",
+			ExpectedContentLanguage: "en",
+		},
+		{
+			Name:                    "falls back to status text",
+			Method:                  "POST",
+			Path:                    "/resource",
+			AcceptLanguage:          "en",
+			ExpectedCode:            405,
+			ExpectedBody:            "Method Not Allowed\n",
+			ExpectedContentLanguage: "en",
+		},
+		{
+			Name:                    "nested mux uses parent catalog",
+			Method:                  "GET",
+			Path:                    "/child/resource",
+			AcceptLanguage:          "fr",
+			ExpectedCode:            405,
+			ExpectedBody:            "Méthode non autorisée\n",
+			ExpectedContentLanguage: "fr",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil)
+			r.Header.Set("Accept-Language", tc.AcceptLanguage)
+
+			parent.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+			if lang := w.Header().Get("Content-Language"); lang != tc.ExpectedContentLanguage {
+				t.Errorf("expected content language %q but got %q", tc.ExpectedContentLanguage, lang)
+			}
+		})
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	parent := New(JSONErrors(true), Messages(MessageCatalog{"fr": {404: "Page introuvable"}}))
+	parent.UseGlobal(AcceptLanguage("en", "fr"))
+	parent.Use(Recover(nil))
+	parent.GetFunc("/resource", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	parent.GetFunc("/panic", func(w http.ResponseWriter, r *http.Request, c Context) { panic("boom") })
+
+	text := New(JSONErrors(false))
+	parent.Handle("/text/", StripDepth(1, text))
+
+	testcases := []struct {
+		Name                string
+		Method              string
+		Path                string
+		AcceptLanguage      string
+		ExpectedCode        int
+		ExpectedBody        string
+		ExpectedContentType string
+	}{
+		{
+			Name:                "not found",
+			Method:              "GET",
+			Path:                "/missing",
+			ExpectedCode:        404,
+			ExpectedBody:        `{"error":"not found","status":404}` + "\n",
+			ExpectedContentType: "application/json",
+		},
+		{
+			Name:                "method not allowed",
+			Method:              "POST",
+			Path:                "/resource",
+			ExpectedCode:        405,
+			ExpectedBody:        `{"error":"method not allowed","status":405}` + "\n",
+			ExpectedContentType: "application/json",
+		},
+		{
+			Name:                "recovered panic",
+			Method:              "GET",
+			Path:                "/panic",
+			ExpectedCode:        500,
+			ExpectedBody:        `{"error":"internal server error","status":500}` + "\n",
+			ExpectedContentType: "application/json",
+		},
+		{
+			Name:                "catalog message",
+			Method:              "GET",
+			Path:                "/missing",
+			AcceptLanguage:      "fr",
+			ExpectedCode:        404,
+			ExpectedBody:        `{"error":"Page introuvable","status":404}` + "\n",
+			ExpectedContentType: "application/json",
+		},
+		{
+			Name:                "nested mux opts out",
+			Method:              "GET",
+			Path:                "/text/missing",
+			ExpectedCode:        404,
+			ExpectedBody:        "Not Found\n",
+			ExpectedContentType: "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil)
+			r.Header.Set("Accept-Language", tc.AcceptLanguage)
+
+			parent.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.ExpectedContentType {
+				t.Errorf("expected content type %q but got %q", tc.ExpectedContentType, contentType)
+			}
+		})
+	}
+}
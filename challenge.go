@@ -0,0 +1,136 @@
+package muxter
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Challenger verifies that clients are not automated, such as with a CAPTCHA, a proof of work or a token issued by
+// a verification flow.
+type Challenger interface {
+	// Verified reports whether the request carries the solution of a challenge.
+	Verified(r *http.Request) bool
+	// Challenge answers the request with a challenge, such as a redirect to a verification flow.
+	Challenge(w http.ResponseWriter, r *http.Request, c Context)
+}
+
+// ChallengeSignal reports whether a request looks automated and must be challenged. The Signal methods of
+// RateLimiter and Honeypot are signals.
+type ChallengeSignal func(r *http.Request, c Context) bool
+
+// Challenge is a middleware challenging the requests that any of the signals report as suspicious, unless they carry
+// the solution of a challenge. Requests without signals are served as usual, such that legitimate clients are only
+// challenged once defense components grow suspicious of them.
+//
+//	mux.Handle("/signup", signup, muxter.Challenge(captcha, limiter.Signal, honeypot.Signal))
+func Challenge(challenger Challenger, signals ...ChallengeSignal) Middleware {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			for _, signal := range signals {
+				if signal(r, c) {
+					if challenger.Verified(r) {
+						break
+					}
+					challenger.Challenge(w, r, c)
+					return
+				}
+			}
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+}
+
+// RedirectChallenger is a Challenger redirecting suspicious requests to a verification flow, which sets a cookie once
+// the client passes it. Requests that cannot be redirected, as they are not GET or HEAD requests, are answered with
+// 403 Forbidden.
+type RedirectChallenger struct {
+	// URL is the URL of the verification flow. The URL of the challenged request is added as the next query param.
+	URL string
+	// Cookie is the cookie set by the verification flow.
+	Cookie string
+	// Verify reports whether the value of the cookie is valid, such as a signed token that has not expired.
+	Verify func(value string) bool
+}
+
+func (rc RedirectChallenger) Verified(r *http.Request) bool {
+	cookie, err := r.Cookie(rc.Cookie)
+	return err == nil && rc.Verify(cookie.Value)
+}
+
+func (rc RedirectChallenger) Challenge(w http.ResponseWriter, r *http.Request, c Context) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeStatus(w, c, http.StatusForbidden)
+		return
+	}
+	target, err := url.Parse(rc.URL)
+	if err != nil {
+		writeStatus(w, c, http.StatusForbidden)
+		return
+	}
+	query := target.Query()
+	query.Set("next", c.requestURL(r).RequestURI())
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// Signal is a ChallengeSignal reporting the requests of clients that exceeded their budget, such that they are
+// challenged instead of being answered with 429 Too Many Requests. It takes a token from the client's bucket.
+func (l *RateLimiter) Signal(r *http.Request, c Context) bool {
+	return l.Take(l.opts.Key(r, c)) > 0
+}
+
+// Honeypot flags the clients requesting trap routes, which legitimate clients never request, such as links hidden
+// from users or paths probed by vulnerability scanners. Its Signal method reports flagged clients to the Challenge
+// middleware.
+type Honeypot struct {
+	ttl time.Duration
+	key func(r *http.Request, c Context) string
+
+	mu      sync.Mutex
+	flagged map[string]time.Time
+}
+
+// NewHoneypot returns a Honeypot flagging clients for the ttl. Clients are keyed by the IP address of their remote
+// address if key is nil.
+func NewHoneypot(ttl time.Duration, key func(r *http.Request, c Context) string) *Honeypot {
+	if key == nil {
+		key = remoteIP
+	}
+	return &Honeypot{ttl: ttl, key: key, flagged: map[string]time.Time{}}
+}
+
+// Trap returns a handler flagging the clients it serves and answering them as not found.
+//
+//	mux.Handle("/wp-login.php", honeypot.Trap())
+func (hp *Honeypot) Trap() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		hp.Flag(hp.key(r, c))
+		writeStatus(w, c, http.StatusNotFound)
+	})
+}
+
+// Flag flags the client with the key.
+func (hp *Honeypot) Flag(key string) {
+	now := time.Now()
+
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	for k, expires := range hp.flagged {
+		if now.After(expires) {
+			delete(hp.flagged, k)
+		}
+	}
+	hp.flagged[key] = now.Add(hp.ttl)
+}
+
+// Signal is a ChallengeSignal reporting the requests of flagged clients.
+func (hp *Honeypot) Signal(r *http.Request, c Context) bool {
+	hp.mu.Lock()
+	expires, ok := hp.flagged[hp.key(r, c)]
+	hp.mu.Unlock()
+
+	return ok && time.Now().Before(expires)
+}
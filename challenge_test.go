@@ -0,0 +1,64 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChallenge(t *testing.T) {
+	honeypot := NewHoneypot(time.Minute, nil)
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 0.001, Burst: 2})
+	challenger := RedirectChallenger{
+		URL:    "/verify?flow=captcha",
+		Cookie: "verified",
+		Verify: func(value string) bool { return value == "ok" },
+	}
+
+	mux := New()
+	mux.Handle("/wp-login.php", honeypot.Trap())
+	mux.HandleFunc("/signup", func(w http.ResponseWriter, r *http.Request, c Context) {}, Challenge(challenger, honeypot.Signal))
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request, c Context) {}, Challenge(challenger, limiter.Signal))
+
+	request := func(method, target, remoteAddr, cookie string) *httptest.ResponseRecorder {
+		w, r := httptest.NewRecorder(), httptest.NewRequest(method, target, nil)
+		r.RemoteAddr = remoteAddr
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "verified", Value: cookie})
+		}
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request("GET", "/signup", "1.1.1.1:1000", ""); w.Code != 200 {
+		t.Errorf("expected unflagged client to be served but got %d", w.Code)
+	}
+
+	if w := request("GET", "/wp-login.php", "1.1.1.1:1000", ""); w.Code != 404 {
+		t.Errorf("expected trap to answer not found but got %d", w.Code)
+	}
+
+	w := request("GET", "/signup?plan=pro", "1.1.1.1:2000", "")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/verify?flow=captcha&next=%2Fsignup%3Fplan%3Dpro" {
+		t.Errorf("expected flagged client to be redirected to the challenge but got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := request("POST", "/signup", "1.1.1.1:2000", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected flagged POST to be forbidden but got %d", w.Code)
+	}
+	if w := request("GET", "/signup", "1.1.1.1:2000", "forged"); w.Code != http.StatusFound {
+		t.Errorf("expected invalid solution to be challenged but got %d", w.Code)
+	}
+	if w := request("GET", "/signup", "1.1.1.1:2000", "ok"); w.Code != 200 {
+		t.Errorf("expected verified client to be served but got %d", w.Code)
+	}
+	if w := request("GET", "/signup", "2.2.2.2:1000", ""); w.Code != 200 {
+		t.Errorf("expected other client to be served but got %d", w.Code)
+	}
+
+	for i, expected := range []int{200, 200, http.StatusFound} {
+		if w := request("GET", "/search", "3.3.3.3:1000", ""); w.Code != expected {
+			t.Errorf("expected search request %d to be answered with %d but got %d", i, expected, w.Code)
+		}
+	}
+}
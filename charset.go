@@ -0,0 +1,258 @@
+package muxter
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Charset converts text between a character set and UTF-8. Character sets of golang.org/x/text can be used as:
+//
+//	muxter.Charset{
+//		Decoder: func(r io.Reader) io.Reader { return transform.NewReader(r, charmap.Windows1252.NewDecoder()) },
+//		Encoder: func(w io.Writer) io.WriteCloser { return transform.NewWriter(w, charmap.Windows1252.NewEncoder()) },
+//	}
+type Charset struct {
+	// Decoder returns a reader of the text of r converted to UTF-8.
+	Decoder func(r io.Reader) io.Reader
+	// Encoder returns a writer converting UTF-8 text to the character set and writing it to w. It is closed once the
+	// response is written.
+	Encoder func(w io.Writer) io.WriteCloser
+}
+
+// Latin1 is the ISO-8859-1 character set. Characters it cannot represent are encoded as '?'.
+var Latin1 = Charset{
+	Decoder: func(r io.Reader) io.Reader { return &latin1Reader{r: r} },
+	Encoder: func(w io.Writer) io.WriteCloser { return &latin1Writer{w: w} },
+}
+
+// TranscodeOptions configures the Transcode middleware.
+type TranscodeOptions struct {
+	// Charsets are the supported character sets by lowercase name. They default to ISO-8859-1 as iso-8859-1 and
+	// latin1. UTF-8 and US-ASCII are always supported.
+	Charsets map[string]Charset
+	// Responses converts text responses written in UTF-8 to the character set of the request body, or else to the
+	// first supported character set of the request's Accept-Charset header.
+	Responses bool
+}
+
+// Transcode converts request bodies declared in other character sets than UTF-8 by the charset parameter of their
+// Content-Type to UTF-8, such that handlers only deal with UTF-8. The Content-Type of converted requests is updated
+// and their Content-Length removed. Requests declaring an unsupported character set are answered with 415
+// Unsupported Media Type.
+func Transcode(opts TranscodeOptions) Middleware {
+	if opts.Charsets == nil {
+		opts.Charsets = map[string]Charset{"iso-8859-1": Latin1, "latin1": Latin1}
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			var target string
+
+			mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if name := strings.ToLower(params["charset"]); err == nil && !isUTF8(name) {
+				charset, ok := opts.Charsets[name]
+				if !ok {
+					writeStatus(w, c, http.StatusUnsupportedMediaType)
+					return
+				}
+				target = name
+
+				if r.Body != nil && r.Body != http.NoBody {
+					r.Body = readCloser{charset.Decoder(r.Body), r.Body}
+				}
+				params["charset"] = "utf-8"
+				r.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+				r.Header.Del("Content-Length")
+				r.ContentLength = -1
+			}
+
+			if !opts.Responses {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+			if target == "" {
+				target = acceptedCharset(r.Header.Get("Accept-Charset"), opts.Charsets)
+			}
+			if target == "" {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			tw := &transcodeWriter{ResponseWriter: w, name: target, charset: opts.Charsets[target]}
+			defer tw.close()
+
+			h.ServeHTTPx(tw, r, c)
+		})
+	}
+}
+
+func isUTF8(charset string) bool {
+	return charset == "" || charset == "utf-8" || charset == "utf8" || charset == "us-ascii"
+}
+
+// acceptedCharset returns the supported character set the Accept-Charset header prefers, or the empty string if it
+// prefers UTF-8 or accepts none of the supported character sets.
+func acceptedCharset(header string, charsets map[string]Charset) string {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(params[len("q="):], 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: strings.ToLower(name), q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, cr := range ranges {
+		if cr.tag == "*" || isUTF8(cr.tag) {
+			return ""
+		}
+		if _, ok := charsets[cr.tag]; ok {
+			return cr.tag
+		}
+	}
+	return ""
+}
+
+// transcodeWriter converts text responses written in UTF-8 to a character set.
+type transcodeWriter struct {
+	http.ResponseWriter
+	name    string
+	charset Charset
+	encoder io.WriteCloser
+	started bool
+}
+
+func (w *transcodeWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *transcodeWriter) WriteHeader(code int) {
+	if !w.started && code >= 200 {
+		w.started = true
+		header := w.Header()
+		mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+		if err == nil && isUTF8(strings.ToLower(params["charset"])) && isText(mediaType, params) {
+			params["charset"] = w.name
+			header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+			header.Del("Content-Length")
+			w.encoder = w.charset.Encoder(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *transcodeWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.encoder.Write(p)
+}
+
+func (w *transcodeWriter) close() {
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+// isText reports whether a response of the media type is text in a character set.
+func isText(mediaType string, params map[string]string) bool {
+	return strings.HasPrefix(mediaType, "text/") || params["charset"] != ""
+}
+
+// latin1Reader converts ISO-8859-1 text to UTF-8, where every byte is the code point of its character.
+type latin1Reader struct {
+	r       io.Reader
+	buf     []byte
+	pending []byte
+}
+
+func (r *latin1Reader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		size := len(p) / 2
+		if size == 0 {
+			size = 1
+		}
+		if cap(r.buf) < size {
+			r.buf = make([]byte, size)
+		}
+		n, err := r.r.Read(r.buf[:size])
+		if n == 0 {
+			return 0, err
+		}
+
+		out := r.pending[:0]
+		for _, b := range r.buf[:n] {
+			out = utf8.AppendRune(out, rune(b))
+		}
+		r.pending = out
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// latin1Writer converts UTF-8 text to ISO-8859-1, holding incomplete characters until the next write.
+type latin1Writer struct {
+	w       io.Writer
+	partial []byte
+	buf     []byte
+}
+
+func (w *latin1Writer) Write(p []byte) (int, error) {
+	text := p
+	if len(w.partial) > 0 {
+		text = append(w.partial, p...)
+	}
+
+	out := w.buf[:0]
+	for len(text) > 0 {
+		if !utf8.FullRune(text) {
+			break
+		}
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if r > 0xFF {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	w.partial = append(w.partial[:0], text...)
+	w.buf = out
+
+	if _, err := w.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *latin1Writer) Close() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	w.partial = nil
+	_, err := w.w.Write([]byte{'?'})
+	return err
+}
@@ -0,0 +1,125 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTranscode(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, r.Header.Get("Content-Type")+": "+string(body))
+	}, Transcode(TranscodeOptions{Responses: true}))
+
+	testcases := []struct {
+		Name                string
+		Body                string
+		ContentType         string
+		AcceptCharset       string
+		ExpectedCode        int
+		ExpectedContentType string
+		ExpectedBody        string
+	}{
+		{
+			Name:                "utf-8",
+			Body:                "café",
+			ContentType:         "text/plain; charset=utf-8",
+			ExpectedCode:        200,
+			ExpectedContentType: "text/plain; charset=utf-8",
+			ExpectedBody:        "text/plain; charset=utf-8: café",
+		},
+		{
+			Name:                "latin1 request",
+			Body:                "caf\xe9",
+			ContentType:         "text/plain; charset=ISO-8859-1",
+			ExpectedCode:        200,
+			ExpectedContentType: "text/plain; charset=iso-8859-1",
+			ExpectedBody:        "text/plain; charset=utf-8: caf\xe9",
+		},
+		{
+			Name:                "accept charset",
+			Body:                "café €",
+			ContentType:         "text/plain",
+			AcceptCharset:       "utf-8;q=0.5, latin1",
+			ExpectedCode:        200,
+			ExpectedContentType: "text/plain; charset=latin1",
+			ExpectedBody:        "text/plain: caf\xe9 ?",
+		},
+		{
+			Name:                "accept utf-8",
+			Body:                "café",
+			ContentType:         "text/plain",
+			AcceptCharset:       "utf-8, latin1;q=0.5",
+			ExpectedCode:        200,
+			ExpectedContentType: "text/plain; charset=utf-8",
+			ExpectedBody:        "text/plain: café",
+		},
+		{
+			Name:         "unsupported charset",
+			Body:         "caf\xe9",
+			ContentType:  "text/plain; charset=koi8-r",
+			ExpectedCode: 415,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(tc.Body))
+			r.Header.Set("Content-Type", tc.ContentType)
+			if tc.AcceptCharset != "" {
+				r.Header.Set("Accept-Charset", tc.AcceptCharset)
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedCode != 200 {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.ExpectedContentType {
+				t.Errorf("expected content type %q but got %q", tc.ExpectedContentType, contentType)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+		})
+	}
+}
+
+func TestLatin1(t *testing.T) {
+	decoded, err := io.ReadAll(Latin1.Decoder(strings.NewReader("\xe9t\xe9")))
+	if err != nil || string(decoded) != "été" {
+		t.Fatalf("expected to decode %q but got %q (%v)", "été", decoded, err)
+	}
+
+	// Reading and writing one byte at a time splits characters across calls.
+	var oneByte strings.Builder
+	for r := Latin1.Decoder(strings.NewReader("\xe9t\xe9")); ; {
+		var p [1]byte
+		n, err := r.Read(p[:])
+		oneByte.Write(p[:n])
+		if err != nil {
+			break
+		}
+	}
+	if oneByte.String() != "été" {
+		t.Errorf("expected to decode %q one byte at a time but got %q", "été", oneByte.String())
+	}
+
+	var encoded strings.Builder
+	encoder := Latin1.Encoder(&encoded)
+	for _, b := range []byte("été") {
+		encoder.Write([]byte{b})
+	}
+	encoder.Close()
+	if encoded.String() != "\xe9t\xe9" {
+		t.Errorf("expected to encode %q but got %q", "\xe9t\xe9", encoded.String())
+	}
+}
@@ -0,0 +1,67 @@
+package muxter
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the middlewares depending on it, such as the RateLimiter and the Cache. Middlewares default
+// to the system clock, and tests can use a ManualClock to expire cached responses or refill rate limits without
+// sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// Rand is a source of randomness for the middlewares that sample requests or randomize their behavior, such as the
+// early revalidation of the Cache. Tests can use a seeded *rand.Rand, or a source returning fixed numbers, to make
+// these middlewares deterministic. Implementations must be safe for concurrent use, which *rand.Rand is not unless
+// its source is.
+type Rand interface {
+	// Float64 returns a number in [0.0, 1.0).
+	Float64() float64
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+type systemRand struct{}
+
+func (systemRand) Float64() float64 { return rand.Float64() }
+
+// ManualClock is a Clock whose time only changes when it is set or advanced, for deterministic tests.
+//
+//	clock := muxter.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	limiter := muxter.NewRateLimiter(muxter.RateLimitOptions{Rate: 1, Clock: clock})
+//	// ... exhaust the budget of a client
+//	clock.Advance(time.Second)
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a clock stopped at now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the time of the clock.
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the time of the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,194 @@
+package muxter
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fixedRand float64
+
+func (r fixedRand) Float64() float64 { return float64(r) }
+
+func TestRateLimiterClock(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 1, Burst: 2, Clock: clock})
+
+	for i, expected := range []time.Duration{0, 0, time.Second} {
+		if wait := limiter.Take("client"); wait != expected {
+			t.Errorf("take %d: expected to wait %v but got %v", i, expected, wait)
+		}
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if wait := limiter.Take("client"); wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms for a token but got %v", wait)
+	}
+
+	clock.Advance(2 * time.Second)
+	if wait := limiter.Take("client"); wait != 0 {
+		t.Errorf("expected the bucket to be refilled but got a wait of %v", wait)
+	}
+}
+
+func TestMemoryLimiterStoreClock(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 1, Burst: 1, Clock: clock})
+	store := limiter.opts.Store.(*MemoryLimiterStore)
+
+	limiter.Take("client")
+	if _, ok, _ := store.Get("client"); !ok {
+		t.Fatal("expected the bucket of the client to be stored")
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, ok, _ := store.Get("client"); ok {
+		t.Error("expected the full bucket of the client to expire with the clock of the limiter")
+	}
+}
+
+func TestCacheClock(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewCache(CacheOptions{TTL: time.Minute, HeuristicFreshness: true, EarlyRevalidation: -1, Clock: clock})
+
+	var calls int
+	handler := cache.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		w.Write([]byte("body"))
+	}))
+
+	testcases := []struct {
+		Name          string
+		Advance       time.Duration
+		ExpectedCalls int
+		ExpectedAge   string
+	}{
+		{Name: "stored", ExpectedCalls: 1},
+		{Name: "fresh", Advance: 30 * time.Second, ExpectedCalls: 1, ExpectedAge: "30"},
+		{Name: "expired", Advance: 31 * time.Second, ExpectedCalls: 2},
+		{Name: "refreshed", Advance: 59 * time.Second, ExpectedCalls: 2, ExpectedAge: "59"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			clock.Advance(tc.Advance)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTPx(w, httptest.NewRequest("GET", "/", nil), Context{})
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("expected %d calls but got %d", tc.ExpectedCalls, calls)
+			}
+			if age := w.Header().Get("Age"); age != tc.ExpectedAge {
+				t.Errorf("expected age %q but got %q", tc.ExpectedAge, age)
+			}
+		})
+	}
+}
+
+func TestCacheRand(t *testing.T) {
+	testcases := []struct {
+		Name          string
+		Rand          fixedRand
+		ExpectedCalls int
+	}{
+		{Name: "unlucky draw", Rand: 1e-300, ExpectedCalls: 2},
+		{Name: "lucky draw", Rand: 0.5, ExpectedCalls: 1},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			cache := NewCache(CacheOptions{TTL: time.Minute, HeuristicFreshness: true, Clock: clock, Rand: tc.Rand})
+
+			var calls int
+			handler := cache.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				calls++
+				clock.Advance(time.Second)
+				w.Write([]byte("slow"))
+			}))
+
+			for i := 0; i < 2; i++ {
+				handler.ServeHTTPx(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil), Context{})
+			}
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("expected %d calls but got %d", tc.ExpectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestFragmentCacheClock(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fragments := NewFragmentCache(FragmentCacheOptions{TTL: time.Minute, Clock: clock})
+
+	var renders int
+	render := func() {
+		fragments.Render(io.Discard, Context{}, "sidebar", func(w io.Writer) error {
+			renders++
+			return nil
+		})
+	}
+
+	render()
+	clock.Advance(59 * time.Second)
+	render()
+	if renders != 1 {
+		t.Errorf("expected the fragment to be cached but it was rendered %d times", renders)
+	}
+
+	clock.Advance(time.Second)
+	render()
+	if renders != 2 {
+		t.Errorf("expected the fragment to expire but it was rendered %d times", renders)
+	}
+}
+
+func TestSchemaRecorderRand(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Rand     fixedRand
+		Recorded bool
+	}{
+		{Name: "sampled", Rand: 0.04, Recorded: true},
+		{Name: "not sampled", Rand: 0.05},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder := NewSchemaRecorder(SchemaOptions{SampleRate: 0.05, Rand: tc.Rand})
+
+			mux := New()
+			mux.Use(recorder.Middleware)
+			mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+			r := httptest.NewRequest("POST", "/books", io.NopCloser(bytes.NewReader([]byte(`{"title":"Dune"}`))))
+			r.Header.Set("Content-Type", "application/json")
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			_, recorded := recorder.Schemas()["POST /books"]
+			if recorded != tc.Recorded {
+				t.Errorf("expected recorded to be %v but got %v", tc.Recorded, recorded)
+			}
+		})
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	clock.Advance(time.Hour)
+	if now := clock.Now(); !now.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected %v but got %v", start.Add(time.Hour), now)
+	}
+
+	clock.Set(start)
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("expected %v but got %v", start, now)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type route struct {
+	Pattern string `json:"pattern"`
+	Name    string `json:"name"`
+}
+
+type segment struct {
+	static   string
+	param    string
+	catchall bool
+	// host is set for the params of the labels of host patterns, which are not path escaped.
+	host bool
+}
+
+// parsePattern splits a muxter pattern into static text and param segments. Host patterns of the form "//host/path"
+// produce scheme relative URLs, whose host labels starting with : or # are params as in the mux.
+func parsePattern(pattern string) ([]segment, error) {
+	if !strings.HasPrefix(pattern, "//") {
+		return parsePath(pattern)
+	}
+
+	host, path := pattern[2:], "/"
+	if idx := strings.IndexByte(host, '/'); idx != -1 {
+		host, path = host[:idx], host[idx:]
+	}
+
+	segments := []segment{{static: "//"}}
+	for i, label := range strings.Split(host, ".") {
+		if i > 0 {
+			segments = appendStatic(segments, ".")
+		}
+		switch {
+		case strings.HasPrefix(label, ":"):
+			segments = append(segments, segment{param: label[1:], host: true})
+		case strings.HasPrefix(label, "#"):
+			key, _, ok := strings.Cut(label[1:], ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid regexp param in pattern %s", pattern)
+			}
+			segments = append(segments, segment{param: key, host: true})
+		default:
+			segments = appendStatic(segments, label)
+		}
+	}
+
+	pathSegments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range pathSegments {
+		if seg.param == "" && !seg.catchall {
+			segments = appendStatic(segments, seg.static)
+		} else {
+			segments = append(segments, seg)
+		}
+	}
+	return segments, nil
+}
+
+// appendStatic appends static text to the segments, merging it with the last segment if it is static as well.
+func appendStatic(segments []segment, static string) []segment {
+	if n := len(segments); n > 0 && segments[n-1].param == "" && !segments[n-1].catchall {
+		segments[n-1].static += static
+		return segments
+	}
+	return append(segments, segment{static: static})
+}
+
+// parsePath splits the path of a muxter pattern into static text and param segments.
+func parsePath(pattern string) ([]segment, error) {
+	var segments []segment
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ':', '#':
+			end := i + 1
+			for end < len(pattern) && pattern[end] != '/' {
+				if pattern[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > len(pattern) {
+				end = len(pattern)
+			}
+			key := pattern[i+1 : end]
+			if pattern[i] == '#' {
+				idx := strings.IndexByte(key, ':')
+				if idx == -1 {
+					return nil, fmt.Errorf("invalid regexp param in pattern %s", pattern)
+				}
+				key = key[:idx]
+			}
+			segments = append(segments, segment{param: key})
+			i = end
+		case '*':
+			segments = append(segments, segment{param: pattern[i+1:], catchall: true})
+			i = len(pattern)
+		default:
+			var static strings.Builder
+			for ; i < len(pattern) && !strings.ContainsRune(":#*", rune(pattern[i])); i++ {
+				if pattern[i] == '\\' && i+1 < len(pattern) && strings.ContainsRune(":#*\\", rune(pattern[i+1])) {
+					i++
+				}
+				static.WriteByte(pattern[i])
+			}
+			segments = append(segments, segment{static: static.String()})
+		}
+	}
+	return segments, nil
+}
+
+// identifier converts a route name such as "user.show" or "user-show" into an exported Go identifier: UserShow.
+func identifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	id := b.String()
+	if id != "" && unicode.IsDigit(rune(id[0])) {
+		id = "R" + id
+	}
+	return id
+}
+
+// paramIdentifier converts a param key into an unexported Go identifier safe to use as a function argument.
+func paramIdentifier(key string) string {
+	id := identifier(key)
+	if id == "" {
+		return "param"
+	}
+	id = strings.ToLower(id[:1]) + id[1:]
+	switch id {
+	case "break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func",
+		"go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch",
+		"type", "var", "url", "strings":
+		return id + "Param"
+	}
+	return id
+}
+
+func generate(pkg string, routes []route) ([]byte, error) {
+	var named []route
+	for _, r := range routes {
+		if r.Name != "" {
+			named = append(named, r)
+		}
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Name < named[j].Name })
+
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, "// Code generated by muxter-gen. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+
+	var (
+		consts   bytes.Buffer
+		builders bytes.Buffer
+		usesURL  bool
+		usesStr  bool
+		seen     = map[string]string{}
+	)
+
+	for _, r := range named {
+		id := identifier(r.Name)
+		if id == "" {
+			return nil, fmt.Errorf("route name %q does not produce a valid identifier", r.Name)
+		}
+		if other, ok := seen[id]; ok {
+			return nil, fmt.Errorf("route names %q and %q produce the same identifier %s", other, r.Name, id)
+		}
+		seen[id] = r.Name
+
+		segments, err := parsePattern(r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&consts, "\tRoute%s = %q\n", id, r.Pattern)
+
+		var (
+			args  []string
+			parts []string
+		)
+		for _, seg := range segments {
+			switch {
+			case seg.param == "" && !seg.catchall:
+				parts = append(parts, fmt.Sprintf("%q", seg.static))
+			case seg.host:
+				arg := paramIdentifier(seg.param)
+				args = append(args, arg)
+				parts = append(parts, arg)
+			case seg.catchall:
+				arg := paramIdentifier(seg.param)
+				args = append(args, arg)
+				parts = append(parts, fmt.Sprintf("strings.ReplaceAll(url.PathEscape(%s), \"%%2F\", \"/\")", arg))
+				usesURL, usesStr = true, true
+			default:
+				arg := paramIdentifier(seg.param)
+				args = append(args, arg)
+				parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", arg))
+				usesURL = true
+			}
+		}
+
+		signature := ""
+		if len(args) > 0 {
+			signature = strings.Join(args, ", ") + " string"
+		}
+
+		fmt.Fprintf(&builders, "// URLFor%s returns the path of route %q: %s\n", id, r.Name, r.Pattern)
+		fmt.Fprintf(&builders, "func URLFor%s(%s) string {\n\treturn %s\n}\n\n", id, signature, strings.Join(parts, " + "))
+	}
+
+	if usesURL || usesStr {
+		fmt.Fprintln(&buf, "import (")
+		if usesURL {
+			fmt.Fprintln(&buf, "\t\"net/url\"")
+		}
+		if usesStr {
+			fmt.Fprintln(&buf, "\t\"strings\"")
+		}
+		fmt.Fprintln(&buf, ")")
+		fmt.Fprintln(&buf)
+	}
+
+	if consts.Len() > 0 {
+		fmt.Fprintf(&buf, "const (\n%s)\n\n", consts.String())
+	}
+	buf.Write(builders.Bytes())
+
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	routes := []route{
+		{Pattern: "/users/:id", Name: "user.show"},
+		{Pattern: "/files/*path", Name: "file-download"},
+		{Pattern: `/assets/#dir:folder-\d+/:type`, Name: "asset"},
+		{Pattern: "/about", Name: "about"},
+		{Pattern: "/unnamed/:id"},
+	}
+
+	src, err := generate("routes", routes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by muxter-gen. DO NOT EDIT.
+
+package routes
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	RouteAbout        = "/about"
+	RouteAsset        = "/assets/#dir:folder-\\d+/:type"
+	RouteFileDownload = "/files/*path"
+	RouteUserShow     = "/users/:id"
+)
+
+// URLForAbout returns the path of route "about": /about
+func URLForAbout() string {
+	return "/about"
+}
+
+// URLForAsset returns the path of route "asset": /assets/#dir:folder-\d+/:type
+func URLForAsset(dir, typeParam string) string {
+	return "/assets/" + url.PathEscape(dir) + "/" + url.PathEscape(typeParam)
+}
+
+// URLForFileDownload returns the path of route "file-download": /files/*path
+func URLForFileDownload(path string) string {
+	return "/files/" + strings.ReplaceAll(url.PathEscape(path), "%2F", "/")
+}
+
+// URLForUserShow returns the path of route "user.show": /users/:id
+func URLForUserShow(id string) string {
+	return "/users/" + url.PathEscape(id)
+}
+`
+
+	if string(src) != expected {
+		t.Errorf("unexpected output:\n%s", src)
+	}
+}
+
+func TestGenerateConflictingNames(t *testing.T) {
+	_, err := generate("routes", []route{
+		{Pattern: "/a", Name: "user.show"},
+		{Pattern: "/b", Name: "user-show"},
+	})
+
+	expected := `route names "user-show" and "user.show" produce the same identifier UserShow`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q but got %v", expected, err)
+	}
+}
+
+func TestParsePatternEscapes(t *testing.T) {
+	segments, err := parsePattern(`/meetings/12\:30/:room`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(segments) != 2 || segments[0].static != "/meetings/12:30/" || segments[1].param != "room" {
+		t.Errorf("expected escaped colon to be static text but got %+v", segments)
+	}
+}
+
+func TestParsePatternHost(t *testing.T) {
+	segments, err := parsePattern(`//:tenant.#region:eu|us.example.com/users/:id`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []segment{
+		{static: "//"},
+		{param: "tenant", host: true},
+		{static: "."},
+		{param: "region", host: true},
+		{static: ".example.com/users/"},
+		{param: "id"},
+	}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("expected segments %+v but got %+v", expected, segments)
+	}
+
+	src, err := generate("routes", []route{{Pattern: "//:tenant.example.com/", Name: "tenant.home"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(src), `return "//" + tenant + ".example.com/"`) {
+		t.Errorf("expected a scheme relative url with the tenant as host label but got:\n%s", src)
+	}
+}
@@ -0,0 +1,70 @@
+// Command muxter-gen generates typed route constants and URL builders from a muxter route manifest.
+//
+// A manifest is a JSON array of routes as returned by (*muxter.Mux).Routes:
+//
+//	[{"pattern": "/users/:id", "name": "user.show"}]
+//
+// For every named route muxter-gen emits a constant holding its pattern and a function building its path:
+//
+//	const RouteUserShow = "/users/:id"
+//
+//	func URLForUserShow(id string) string
+//
+// It is intended to be invoked via go:generate:
+//
+//	//go:generate go run github.com/davidmdm/muxter/cmd/muxter-gen -manifest routes.json -package routes -out routes_gen.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidmdm/muxter"
+)
+
+func main() {
+	var (
+		manifest = flag.String("manifest", "routes.json", "path to the route manifest")
+		pkg      = flag.String("package", "routes", "package name of the generated file")
+		out      = flag.String("out", "", "output file, defaults to stdout")
+	)
+	flag.Parse()
+
+	if err := run(*manifest, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "muxter-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(manifest, pkg, out string) error {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return err
+	}
+
+	var routes []route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return fmt.Errorf("invalid manifest %s: %w", manifest, err)
+	}
+
+	for i, r := range routes {
+		pattern, err := muxter.NormalizePattern(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid manifest %s: %w", manifest, err)
+		}
+		routes[i].Pattern = pattern
+	}
+
+	src, err := generate(pkg, routes)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
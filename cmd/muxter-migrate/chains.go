@@ -0,0 +1,194 @@
+package main
+
+import (
+	"strings"
+)
+
+// chiMethods are the methods of chi routers registering a route for a method, by name.
+var chiMethods = map[string]string{
+	"Connect": "CONNECT",
+	"Delete":  "DELETE",
+	"Get":     "GET",
+	"Head":    "HEAD",
+	"Options": "OPTIONS",
+	"Patch":   "PATCH",
+	"Post":    "POST",
+	"Put":     "PUT",
+	"Trace":   "TRACE",
+}
+
+func chiChain(s *scanner, prefix string, calls []call) (string, bool) {
+	router := true
+	for _, c := range calls {
+		router = false
+		switch c.Name {
+		case "With":
+			s.problem(c.Expr, "the middlewares given to With are not migrated")
+			router = true
+		case "Use":
+			s.problem(c.Expr, "the middlewares given to Use are not migrated")
+		case "Group":
+			if len(c.Args) == 1 {
+				s.subrouter(c.Args[0], prefix)
+			}
+			router = true
+		case "Route":
+			pattern, ok := s.literal(c, 0)
+			if !ok || len(c.Args) != 2 {
+				return "", false
+			}
+			prefix = s.dialect.Join(prefix, pattern)
+			s.subrouter(c.Args[1], prefix)
+			router = true
+		case "Mount":
+			if pattern, ok := s.literal(c, 0); ok && len(c.Args) == 2 {
+				s.problem(c.Expr, "the handler mounted at %s is registered on a rooted subtree, routers mounted this way are migrated on their own", pattern)
+				s.add(c.Expr, prefix, strings.TrimSuffix(pattern, "/")+"/", c.Args[1], route{Subtree: true})
+			}
+		case "Handle", "HandleFunc":
+			if pattern, ok := s.literal(c, 0); ok && len(c.Args) == 2 {
+				s.add(c.Expr, prefix, pattern, c.Args[1], route{Func: c.Name == "HandleFunc"})
+			}
+		case "Method", "MethodFunc":
+			method, ok := s.literal(c, 0)
+			if !ok {
+				break
+			}
+			if pattern, ok := s.literal(c, 1); ok && len(c.Args) == 3 {
+				s.add(c.Expr, prefix, pattern, c.Args[2], route{Methods: []string{strings.ToUpper(method)}, Func: c.Name == "MethodFunc"})
+			}
+		case "NotFound", "MethodNotAllowed":
+			s.problem(c.Expr, "the %s handler is not migrated, set it with the mux's Set%sHandler", c.Name, c.Name)
+		default:
+			if method, ok := chiMethods[c.Name]; ok {
+				if pattern, ok := s.literal(c, 0); ok && len(c.Args) == 2 {
+					s.add(c.Expr, prefix, pattern, c.Args[1], route{Methods: []string{method}, Func: true})
+				}
+			}
+		}
+	}
+	return prefix, router
+}
+
+// gorillaMatchers are the matchers of gorilla routes that have no muxter equivalent.
+var gorillaMatchers = []string{"Host", "Queries", "Headers", "HeadersRegexp", "Schemes", "MatcherFunc", "BuildVarsFunc"}
+
+// gorillaChain interprets the chains of calls building gorilla routes, such as
+// r.PathPrefix("/api").Subrouter() and r.HandleFunc("/users", h).Methods("GET").Name("users").
+func gorillaChain(s *scanner, prefix string, calls []call) (string, bool) {
+	var (
+		router   = true
+		path     string
+		isPrefix bool
+		methods  []string
+		name     string
+		handler  call
+		found    bool
+	)
+	for _, c := range calls {
+		switch c.Name {
+		case "HandleFunc", "Handle":
+			pattern, ok := s.literal(c, 0)
+			if !ok || len(c.Args) != 2 {
+				return "", false
+			}
+			path, handler, found, router = pattern, c, true, false
+		case "Path", "PathPrefix":
+			pattern, ok := s.literal(c, 0)
+			if !ok {
+				return "", false
+			}
+			path, isPrefix, router = pattern, c.Name == "PathPrefix", false
+		case "Handler", "HandlerFunc":
+			if len(c.Args) == 1 {
+				handler, found = c, true
+			}
+		case "Methods":
+			for i := range c.Args {
+				if method, ok := s.literal(c, i); ok {
+					methods = append(methods, strings.ToUpper(method))
+				}
+			}
+		case "Name":
+			name, _ = s.literal(c, 0)
+		case "Subrouter":
+			if len(methods) > 0 {
+				s.problem(c.Expr, "the methods of the subrouter are not migrated")
+			}
+			prefix = s.dialect.Join(prefix, path)
+			path, isPrefix, methods, router = "", false, nil, true
+		case "Use":
+			s.problem(c.Expr, "the middlewares given to Use are not migrated")
+		case "StrictSlash", "SkipClean", "UseEncodedPath":
+			s.problem(c.Expr, "the %s option is not migrated", c.Name)
+		case "NewRoute":
+			router = false
+		default:
+			if contains(gorillaMatchers, c.Name) {
+				s.problem(c.Expr, "the %s matcher is not migrated, muxter routes match on the path", c.Name)
+			}
+		}
+	}
+
+	if found {
+		if isPrefix && !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+		s.add(handler.Expr, prefix, path, handler.Args[len(handler.Args)-1], route{
+			Methods: methods,
+			Func:    handler.Name == "HandleFunc" || handler.Name == "HandlerFunc",
+			Name:    name,
+			Subtree: isPrefix,
+		})
+	}
+	return prefix, router
+}
+
+// ginMethods are the methods of gin routers registering a route for a method.
+var ginMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+func ginChain(s *scanner, prefix string, calls []call) (string, bool) {
+	for _, c := range calls {
+		switch c.Name {
+		case "Group":
+			pattern, ok := s.literal(c, 0)
+			if !ok {
+				return "", false
+			}
+			if len(c.Args) > 1 {
+				s.problem(c.Expr, "the middlewares of the group %s are not migrated", pattern)
+			}
+			prefix = s.dialect.Join(prefix, pattern)
+		case "Use":
+			s.problem(c.Expr, "the middlewares given to Use are not migrated")
+		case "Any":
+			ginRoute(s, c, nil, prefix, 0)
+		case "Handle":
+			if method, ok := s.literal(c, 0); ok {
+				ginRoute(s, c, []string{strings.ToUpper(method)}, prefix, 1)
+			}
+		case "Static", "StaticFS", "StaticFile", "StaticFileFS":
+			s.problem(c.Expr, "the static files served with %s are not migrated", c.Name)
+		case "NoRoute", "NoMethod", "Match":
+			s.problem(c.Expr, "%s is not migrated", c.Name)
+		default:
+			if contains(ginMethods, c.Name) {
+				ginRoute(s, c, []string{c.Name}, prefix, 0)
+			}
+		}
+	}
+	return prefix, true
+}
+
+// ginRoute records the route of the call whose pattern is its i-th argument, followed by its handlers. gin handlers
+// are chains whose last function is the handler, the others being middlewares.
+func ginRoute(s *scanner, c call, methods []string, prefix string, i int) {
+	pattern, ok := s.literal(c, i)
+	if !ok || len(c.Args) < i+2 {
+		return
+	}
+	if len(c.Args) > i+2 {
+		s.problem(c.Expr, "the middlewares of %s are not migrated", pattern)
+	}
+	s.add(c.Expr, prefix, pattern, c.Args[len(c.Args)-1], route{Methods: methods, Func: true})
+}
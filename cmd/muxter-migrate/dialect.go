@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dialect describes the routers of a package migrated from.
+type dialect struct {
+	Name string
+	// ImportPath is the import path of the router's package, or its prefix for major versions.
+	ImportPath string
+	// Constructors are the functions of the package returning a router.
+	Constructors []string
+	// Types are the router types of the package, identifying routers received as parameters.
+	Types []string
+	// Adapter is the compat function wrapping the handlers, empty if the handlers are not net/http handlers.
+	Adapter string
+	// Convert converts a pattern of the router to a muxter pattern, returning notes on behaviours that differ.
+	Convert func(pattern string) (string, []string, error)
+	// Join joins a pattern to the prefix of a group of routes.
+	Join func(prefix, pattern string) string
+	// Chain interprets a chain of calls on a router.
+	Chain func(s *scanner, prefix string, calls []call) (string, bool)
+}
+
+var dialects = []*dialect{
+	{
+		Name:         "chi",
+		ImportPath:   "github.com/go-chi/chi",
+		Constructors: []string{"NewRouter", "NewMux"},
+		Types:        []string{"Router", "Mux", "Routes"},
+		Adapter:      "compat.Chi",
+		Convert:      convertBraces,
+		Join:         joinChi,
+		Chain:        chiChain,
+	},
+	{
+		Name:         "gorilla",
+		ImportPath:   "github.com/gorilla/mux",
+		Constructors: []string{"NewRouter"},
+		Types:        []string{"Router"},
+		Adapter:      "compat.Gorilla",
+		Convert:      convertBraces,
+		Join:         joinPath,
+		Chain:        gorillaChain,
+	},
+	{
+		Name:         "gin",
+		ImportPath:   "github.com/gin-gonic/gin",
+		Constructors: []string{"New", "Default"},
+		Types:        []string{"Engine", "RouterGroup", "IRouter", "IRoutes"},
+		Convert:      convertGin,
+		Join:         joinPath,
+		Chain:        ginChain,
+	},
+}
+
+func lookupDialect(name string) (*dialect, error) {
+	for _, d := range dialects {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown router %q, expected chi, gorilla or gin", name)
+}
+
+// trailingSlashNote is the note of patterns ending with a slash, which match a single path in other routers.
+const trailingSlashNote = "the pattern ends with a slash, which muxter treats as a rooted subtree"
+
+// convertBraces converts the patterns of chi and gorilla, where params are written {name} or {name:regexp}. A
+// trailing {name:.*} or chi's trailing * match the rest of the path, like a muxter catchall segment.
+func convertBraces(pattern string) (string, []string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return "", nil, errors.New("the pattern is not rooted")
+	}
+
+	segments := splitBraces(pattern[1:])
+	for i, seg := range segments {
+		last := i == len(segments)-1
+
+		if seg == "*" {
+			if !last {
+				return "", nil, errors.New("the * wildcard is not at the end of the pattern")
+			}
+			segments[i] = "*rest"
+			continue
+		}
+		if !strings.ContainsAny(seg, "{}") {
+			if strings.ContainsAny(seg, ":*#") {
+				return "", nil, fmt.Errorf("the segment %q would be read as a muxter param", seg)
+			}
+			continue
+		}
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") || strings.Count(seg, "{") != 1 {
+			return "", nil, fmt.Errorf("the segment %q has a param inside it, muxter params span whole segments", seg)
+		}
+
+		name, expr, hasExpr := strings.Cut(seg[1:len(seg)-1], ":")
+		switch {
+		case !hasExpr:
+			segments[i] = ":" + name
+		case last && (expr == ".*" || expr == ".+"):
+			segments[i] = "*" + name
+		case strings.Contains(expr, "/"):
+			return "", nil, fmt.Errorf("the param %q has an expression matching slashes", name)
+		default:
+			segments[i] = "#" + name + ":" + expr
+		}
+	}
+
+	converted := "/" + strings.Join(segments, "/")
+	var notes []string
+	if len(pattern) > 1 && strings.HasSuffix(pattern, "/") {
+		notes = append(notes, trailingSlashNote)
+	}
+	return converted, notes, nil
+}
+
+// splitBraces splits the pattern into its segments, ignoring the slashes within braces.
+func splitBraces(pattern string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				segments = append(segments, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, pattern[start:])
+}
+
+// convertGin converts the patterns of gin, whose :name and *name params have the syntax of muxter. The values of
+// gin's catchall params start with a slash, unlike muxter's.
+func convertGin(pattern string) (string, []string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return "", nil, errors.New("the pattern is not rooted")
+	}
+
+	var notes []string
+	segments := strings.Split(pattern[1:], "/")
+	for i, seg := range segments {
+		if j := strings.IndexAny(seg, ":*"); j > 0 {
+			return "", nil, fmt.Errorf("the segment %q has a param inside it, muxter params span whole segments", seg)
+		}
+		if strings.HasPrefix(seg, "#") {
+			return "", nil, fmt.Errorf("the segment %q would be read as a muxter expression", seg)
+		}
+		if strings.HasPrefix(seg, "*") {
+			if i != len(segments)-1 {
+				return "", nil, errors.New("the catchall param is not at the end of the pattern")
+			}
+			notes = append(notes, "the value of the catchall param "+seg[1:]+" no longer starts with a slash")
+		}
+	}
+	if len(pattern) > 1 && strings.HasSuffix(pattern, "/") {
+		notes = append(notes, trailingSlashNote)
+	}
+	return pattern, notes, nil
+}
+
+// joinPath joins a pattern to a prefix, keeping the trailing slash of the pattern.
+func joinPath(prefix, pattern string) string {
+	if prefix == "" {
+		return pattern
+	}
+	if pattern == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(pattern, "/")
+}
+
+// joinChi joins a pattern to the prefix of a chi sub-router, where the "/" route matches the prefix itself.
+func joinChi(prefix, pattern string) string {
+	if prefix != "" && pattern == "/" {
+		return strings.TrimSuffix(prefix, "/")
+	}
+	return joinPath(prefix, pattern)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertPattern(t *testing.T) {
+	testcases := []struct {
+		Dialect       string
+		Pattern       string
+		Expected      string
+		ExpectedNotes []string
+		ExpectedError string
+	}{
+		{Dialect: "chi", Pattern: "/users/{id}", Expected: "/users/:id"},
+		{Dialect: "chi", Pattern: `/users/{id:\d+}/posts`, Expected: `/users/#id:\d+/posts`},
+		{Dialect: "chi", Pattern: "/files/*", Expected: "/files/*rest"},
+		{Dialect: "chi", Pattern: "/users/", Expected: "/users/", ExpectedNotes: []string{trailingSlashNote}},
+		{Dialect: "chi", Pattern: "/files/{name}.{ext}", ExpectedError: `the segment "{name}.{ext}" has a param inside it, muxter params span whole segments`},
+		{Dialect: "chi", Pattern: "/*/edit", ExpectedError: "the * wildcard is not at the end of the pattern"},
+		{Dialect: "gorilla", Pattern: "/static/{path:.*}", Expected: "/static/*path"},
+		{Dialect: "gorilla", Pattern: "/{a:[a-z/]+}/b", ExpectedError: `the param "a" has an expression matching slashes`},
+		{Dialect: "gorilla", Pattern: "/labels/:name", ExpectedError: `the segment ":name" would be read as a muxter param`},
+		{Dialect: "gin", Pattern: "/users/:id", Expected: "/users/:id"},
+		{Dialect: "gin", Pattern: "/assets/*filepath", Expected: "/assets/*filepath", ExpectedNotes: []string{"the value of the catchall param filepath no longer starts with a slash"}},
+		{Dialect: "gin", Pattern: "/v:version/users", ExpectedError: `the segment "v:version" has a param inside it, muxter params span whole segments`},
+		{Dialect: "gin", Pattern: "users", ExpectedError: "the pattern is not rooted"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Dialect+tc.Pattern, func(t *testing.T) {
+			d, err := lookupDialect(tc.Dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, notes, err := d.Convert(tc.Pattern)
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("expected %q but got %q", tc.Expected, actual)
+			}
+			if !reflect.DeepEqual(notes, tc.ExpectedNotes) {
+				t.Errorf("expected notes %q but got %q", tc.ExpectedNotes, notes)
+			}
+		})
+	}
+}
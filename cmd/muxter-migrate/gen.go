@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// methodFields are the methods with a field in muxter.MethodHandler, in the order they are generated.
+var methodFields = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// registration is the registration of the routes of a pattern on the mux.
+type registration struct {
+	Pattern string
+	// Handlers are the routes of the pattern by method, where the empty method handles every method.
+	Handlers map[string]route
+	Name     string
+}
+
+// registrations groups the routes by pattern. Routes of a pattern that cannot be registered together are reported
+// as problems and skipped.
+func (res *result) registrations() []registration {
+	byPattern := map[string]*registration{}
+	var patterns []string
+
+	for _, r := range res.Routes {
+		reg, ok := byPattern[r.Pattern]
+		if !ok {
+			reg = &registration{Pattern: r.Pattern, Handlers: map[string]route{}}
+			byPattern[r.Pattern] = reg
+			patterns = append(patterns, r.Pattern)
+		}
+
+		methods := r.Methods
+		if len(methods) == 0 {
+			methods = []string{""}
+		}
+		for _, method := range methods {
+			if other, ok := reg.Handlers[method]; ok {
+				res.Problems = append(res.Problems, problem{Pos: r.Pos, Message: fmt.Sprintf("%s %s is already registered at %s, the route is skipped", method, r.Pattern, other.Pos)})
+				continue
+			}
+			reg.Handlers[method] = r
+		}
+		if r.Name != "" {
+			if reg.Name != "" && reg.Name != r.Name {
+				res.Problems = append(res.Problems, problem{Pos: r.Pos, Message: fmt.Sprintf("%s is already named %s, the name %s is skipped", r.Pattern, reg.Name, r.Name)})
+				continue
+			}
+			reg.Name = r.Name
+		}
+	}
+	sort.Strings(patterns)
+
+	var regs []registration
+	for _, pattern := range patterns {
+		reg := byPattern[pattern]
+		if len(reg.Handlers) > 1 {
+			var skipped []string
+			for method, r := range reg.Handlers {
+				if !contains(methodFields, method) {
+					what := method
+					if method == "" {
+						what = "every method"
+					}
+					res.Problems = append(res.Problems, problem{Pos: r.Pos, Message: fmt.Sprintf("%s cannot share the pattern %s with other methods, the route is skipped", what, pattern)})
+					skipped = append(skipped, method)
+				}
+			}
+			for _, method := range skipped {
+				delete(reg.Handlers, method)
+			}
+		}
+		if len(reg.Handlers) > 0 {
+			regs = append(regs, *reg)
+		}
+	}
+	return regs
+}
+
+func generate(pkg, name string, res *result) ([]byte, error) {
+	regs := res.registrations()
+	adapter := res.Dialect.Adapter
+
+	var (
+		body       bytes.Buffer
+		usesHTTP   bool
+		usesCompat bool
+	)
+	for _, p := range res.Problems {
+		fmt.Fprintf(&body, "\t// muxter-migrate: %s: %s\n", p.Pos, p.Message)
+	}
+	if len(res.Problems) > 0 {
+		fmt.Fprintln(&body)
+	}
+
+	handler := func(r route) string {
+		if adapter == "" {
+			return fmt.Sprintf("muxter.HandlerFunc(%s)", r.Handler)
+		}
+		usesCompat = true
+		if r.Func {
+			usesHTTP = true
+			return fmt.Sprintf("%s(http.HandlerFunc(%s))", adapter, r.Handler)
+		}
+		return fmt.Sprintf("%s(%s)", adapter, r.Handler)
+	}
+
+	for _, reg := range regs {
+		var options []string
+		if reg.Name != "" {
+			options = append(options, fmt.Sprintf("muxter.Name(%q)", reg.Name))
+		}
+
+		if len(reg.Handlers) == 1 {
+			for method, r := range reg.Handlers {
+				if method != "" {
+					options = append([]string{fmt.Sprintf("mux.Method(%q)", method)}, options...)
+				}
+				fmt.Fprintf(&body, "\tmux.Handle(%q, %s%s)\n", reg.Pattern, handler(r), join(options))
+			}
+			continue
+		}
+
+		fmt.Fprintf(&body, "\tmux.Handle(%q, muxter.MethodHandler{\n", reg.Pattern)
+		for _, method := range methodFields {
+			if r, ok := reg.Handlers[method]; ok {
+				fmt.Fprintf(&body, "\t\t%s: %s,\n", method, handler(r))
+			}
+		}
+		fmt.Fprintf(&body, "\t}%s)\n", join(options))
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by muxter-migrate from the %s routes of the package. Review it, and the handlers it\n", res.Dialect.Name)
+	fmt.Fprintln(&buf, "// registers, before removing the original routes.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintln(&buf, "import (")
+	if usesHTTP {
+		fmt.Fprintln(&buf, "\t\"net/http\"")
+		fmt.Fprintln(&buf)
+	}
+	fmt.Fprintln(&buf, "\t\"github.com/davidmdm/muxter\"")
+	if usesCompat {
+		fmt.Fprintln(&buf, "\t\"github.com/davidmdm/muxter/compat\"")
+	}
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "// %s registers the routes migrated from %s on the mux.\n", name, res.Dialect.Name)
+	fmt.Fprintf(&buf, "func %s(mux *muxter.Mux) {\n%s}\n", name, body.String())
+
+	return format.Source(buf.Bytes())
+}
+
+func join(options []string) string {
+	if len(options) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(options, ", ")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func migrate(t *testing.T, src string) string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "routes.go", src, 0)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	res, err := scan(fset, []*ast.File{file}, "")
+	if err != nil {
+		t.Fatalf("unexpected scan error: %v", err)
+	}
+	out, err := generate("api", "RegisterRoutes", res)
+	if err != nil {
+		t.Fatalf("unexpected generate error: %v", err)
+	}
+	return string(out)
+}
+
+func TestGenerate(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Source   string
+		Expected string
+	}{
+		{
+			Name: "chi",
+			Source: `package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func routes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(logger)
+	r.Get("/health", health)
+	r.Route("/users", func(r chi.Router) {
+		r.Get("/", listUsers)
+		r.Post("/", createUser)
+		r.With(auth).Get("/{id:\\d+}", getUser)
+		r.Handle("/{id}/avatar", avatars)
+	})
+	mountAdmin(r)
+	return r
+}
+
+func mountAdmin(r chi.Router) {
+	r.Mount("/admin", adminHandler())
+	r.Get("/files/{name}.{ext}", download)
+	http.Get("/not/a/route")
+}
+`,
+			Expected: `// Code generated by muxter-migrate from the chi routes of the package. Review it, and the handlers it
+// registers, before removing the original routes.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+	"github.com/davidmdm/muxter/compat"
+)
+
+// RegisterRoutes registers the routes migrated from chi on the mux.
+func RegisterRoutes(mux *muxter.Mux) {
+	// muxter-migrate: routes.go:11:2: the middlewares given to Use are not migrated
+	// muxter-migrate: routes.go:16:3: the middlewares given to With are not migrated
+	// muxter-migrate: routes.go:24:2: the handler mounted at /admin is registered on a rooted subtree, routers mounted this way are migrated on their own
+	// muxter-migrate: routes.go:25:2: /files/{name}.{ext} is not migrated: the segment "{name}.{ext}" has a param inside it, muxter params span whole segments
+
+	mux.Handle("/admin/", compat.Chi(adminHandler()))
+	mux.Handle("/health", compat.Chi(http.HandlerFunc(health)), mux.Method("GET"))
+	mux.Handle("/users", muxter.MethodHandler{
+		GET:  compat.Chi(http.HandlerFunc(listUsers)),
+		POST: compat.Chi(http.HandlerFunc(createUser)),
+	})
+	mux.Handle("/users/#id:\\d+", compat.Chi(http.HandlerFunc(getUser)), mux.Method("GET"))
+	mux.Handle("/users/:id/avatar", compat.Chi(avatars))
+}
+`,
+		},
+		{
+			Name: "gorilla",
+			Source: `package api
+
+import "github.com/gorilla/mux"
+
+func routes() *mux.Router {
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/users/{id}", getUser).Methods("GET", "PUT").Name("user")
+	api.HandleFunc("/users/{id}", deleteUser).Methods("DELETE")
+	api.HandleFunc("/search", search).Queries("q", "{q}")
+	r.PathPrefix("/static/").Handler(files)
+	r.Methods("POST").Path("/events/{rest:.*}").HandlerFunc(ingest)
+	return r
+}
+`,
+			Expected: `// Code generated by muxter-migrate from the gorilla routes of the package. Review it, and the handlers it
+// registers, before removing the original routes.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+	"github.com/davidmdm/muxter/compat"
+)
+
+// RegisterRoutes registers the routes migrated from gorilla on the mux.
+func RegisterRoutes(mux *muxter.Mux) {
+	// muxter-migrate: routes.go:10:2: the Queries matcher is not migrated, muxter routes match on the path
+
+	mux.Handle("/api/search", compat.Gorilla(http.HandlerFunc(search)))
+	mux.Handle("/api/users/:id", muxter.MethodHandler{
+		GET:    compat.Gorilla(http.HandlerFunc(getUser)),
+		PUT:    compat.Gorilla(http.HandlerFunc(getUser)),
+		DELETE: compat.Gorilla(http.HandlerFunc(deleteUser)),
+	}, muxter.Name("user"))
+	mux.Handle("/events/*rest", compat.Gorilla(http.HandlerFunc(ingest)), mux.Method("POST"))
+	mux.Handle("/static/", compat.Gorilla(files))
+}
+`,
+		},
+		{
+			Name: "gin",
+			Source: `package api
+
+import "github.com/gin-gonic/gin"
+
+func routes() *gin.Engine {
+	r := gin.Default()
+	v1 := r.Group("/v1")
+	{
+		v1.GET("/users/:id", auth, getUser)
+		v1.Any("/users/:id", fallback)
+		v1.Static("/assets", "./assets")
+	}
+	r.GET("/files/*path", download)
+	return r
+}
+`,
+			Expected: `// Code generated by muxter-migrate from the gin routes of the package. Review it, and the handlers it
+// registers, before removing the original routes.
+
+package api
+
+import (
+	"github.com/davidmdm/muxter"
+)
+
+// RegisterRoutes registers the routes migrated from gin on the mux.
+func RegisterRoutes(mux *muxter.Mux) {
+	// muxter-migrate: routes.go:9:3: the middlewares of /users/:id are not migrated
+	// muxter-migrate: routes.go:11:3: the static files served with Static are not migrated
+	// muxter-migrate: routes.go:13:2: /files/*path: the value of the catchall param path no longer starts with a slash
+	// muxter-migrate: routes.go:10:3: every method cannot share the pattern /v1/users/:id with other methods, the route is skipped
+
+	mux.Handle("/files/*path", muxter.HandlerFunc(download), mux.Method("GET"))
+	mux.Handle("/v1/users/:id", muxter.HandlerFunc(getUser), mux.Method("GET"))
+}
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := migrate(t, tc.Source); actual != tc.Expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", tc.Expected, actual)
+			}
+		})
+	}
+}
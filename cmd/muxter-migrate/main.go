@@ -0,0 +1,60 @@
+// Command muxter-migrate generates the muxter registration of the routes of an application written with chi,
+// gorilla/mux or gin, lowering the cost of switching routers for large applications.
+//
+// It scans the Go files of a package for the routes registered on routers, following the prefixes of chi's Route,
+// gorilla's Subrouter and gin's Group, converts their patterns to muxter's syntax, and emits a function registering
+// them on a mux:
+//
+//	func RegisterRoutes(mux *muxter.Mux)
+//
+// chi and gorilla handlers are wrapped with the adapters of the github.com/davidmdm/muxter/compat module, such that
+// chi.URLParam and mux.Vars keep working until the handlers are ported. gin handlers have no adapter: the generated
+// registrations refer to them as muxter handlers, such that the compiler points at each handler left to port.
+//
+// The migration is best-effort. Constructs without a muxter equivalent, such as patterns with params inside a
+// segment, host and header matchers, or middlewares, are reported on stderr and as comments in the generated file.
+//
+//	muxter-migrate -from chi -dir ./internal/api -out routes_muxter.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var (
+		from = flag.String("from", "", "router to migrate from: chi, gorilla or gin; detected from the imports by default")
+		dir  = flag.String("dir", ".", "directory of the package to scan")
+		out  = flag.String("out", "routes_muxter.go", "output file, relative to the package directory")
+		name = flag.String("func", "RegisterRoutes", "name of the generated registration function")
+	)
+	flag.Parse()
+
+	if err := run(*from, *dir, *out, *name); err != nil {
+		fmt.Fprintln(os.Stderr, "muxter-migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(from, dir, out, name string) error {
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+
+	pkg, result, err := scanDir(dir, out, from)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(pkg, name, result)
+	if err != nil {
+		return err
+	}
+	for _, p := range result.Problems {
+		fmt.Fprintf(os.Stderr, "muxter-migrate: %s: %s\n", p.Pos, p.Message)
+	}
+	return os.WriteFile(out, src, 0o644)
+}
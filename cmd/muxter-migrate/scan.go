@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// route is a route registered on a router of the migrated package.
+type route struct {
+	// Methods are the methods of the route, or none if it handles every method.
+	Methods []string
+	// Pattern is the muxter pattern of the route.
+	Pattern string
+	// Handler is the source of the handler expression.
+	Handler string
+	// Func reports whether the handler is a function, as given to chi's Get or gorilla's HandleFunc, rather than a
+	// http.Handler.
+	Func bool
+	Name string
+	// Subtree reports whether the route matches a rooted subtree on purpose, as with chi's Mount or gorilla's
+	// PathPrefix, rather than because its pattern ends with a slash.
+	Subtree bool
+	// Pos is the position of the registration, for problems.
+	Pos string
+}
+
+// problem is a construct that is not migrated, or a behaviour of a migrated route that differs in muxter.
+type problem struct {
+	Pos     string
+	Message string
+}
+
+type result struct {
+	Dialect  *dialect
+	Routes   []route
+	Problems []problem
+}
+
+// scanDir parses the Go files of the package in dir, except for tests and the output file, and returns the package
+// name and the routes registered in them.
+func scanDir(dir, out, from string) (string, *result, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || sameFile(path, out) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return "", nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return "", nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	res, err := scan(fset, files, from)
+	return files[0].Name.Name, res, err
+}
+
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// scan returns the routes registered on the routers of the files. The router is detected from the imports of the
+// files if from is empty.
+func scan(fset *token.FileSet, files []*ast.File, from string) (*result, error) {
+	d, err := detectDialect(files, from)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scanner{
+		fset:    fset,
+		dialect: d,
+		routers: map[*ast.Object]string{},
+		handled: map[*ast.CallExpr]bool{},
+		result:  &result{Dialect: d},
+	}
+	for _, file := range files {
+		s.pkg = importName(file, d)
+		if s.pkg == "" {
+			continue
+		}
+		ast.Inspect(file, s.visit)
+	}
+	return s.result, nil
+}
+
+func detectDialect(files []*ast.File, from string) (*dialect, error) {
+	if from != "" {
+		return lookupDialect(from)
+	}
+
+	var found *dialect
+	for _, d := range dialects {
+		for _, file := range files {
+			if importName(file, d) == "" {
+				continue
+			}
+			if found != nil && found != d {
+				return nil, fmt.Errorf("the package imports both %s and %s, choose one with -from", found.Name, d.Name)
+			}
+			found = d
+		}
+	}
+	if found == nil {
+		return nil, errors.New("the package does not import chi, gorilla/mux or gin")
+	}
+	return found, nil
+}
+
+// importName returns the name of the router's package in the file, or the empty string if it is not imported.
+func importName(file *ast.File, d *dialect) string {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || (path != d.ImportPath && !strings.HasPrefix(path, d.ImportPath+"/v")) {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		name := path[strings.LastIndexByte(path, '/')+1:]
+		if strings.HasPrefix(name, "v") && path != d.ImportPath {
+			name = d.ImportPath[strings.LastIndexByte(d.ImportPath, '/')+1:]
+		}
+		return name
+	}
+	return ""
+}
+
+// call is a method call in a chain of calls on a router, such as HandleFunc and Methods in
+// r.HandleFunc("/users", h).Methods("GET").
+type call struct {
+	Name string
+	Args []ast.Expr
+	Expr *ast.CallExpr
+}
+
+type scanner struct {
+	fset    *token.FileSet
+	dialect *dialect
+	// pkg is the name of the router's package in the file being scanned.
+	pkg string
+	// routers are the variables holding routers, with the prefix of their routes.
+	routers map[*ast.Object]string
+	// handled are the calls interpreted as part of a chain, which must not be interpreted on their own.
+	handled map[*ast.CallExpr]bool
+	result  *result
+}
+
+func (s *scanner) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		s.params(n.Type)
+	case *ast.FuncLit:
+		s.params(n.Type)
+	case *ast.AssignStmt:
+		if len(n.Lhs) == len(n.Rhs) {
+			for i, rhs := range n.Rhs {
+				s.assign(n.Lhs[i], rhs)
+			}
+		}
+	case *ast.ValueSpec:
+		if len(n.Names) == len(n.Values) {
+			for i, value := range n.Values {
+				s.assign(n.Names[i], value)
+			}
+		}
+	case *ast.CallExpr:
+		if !s.handled[n] {
+			s.chain(n)
+		}
+	}
+	return true
+}
+
+// params records the parameters of the function whose type is a router, such as r in func(r chi.Router).
+func (s *scanner) params(ft *ast.FuncType) {
+	for _, field := range ft.Params.List {
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		sel, ok := typ.(*ast.SelectorExpr)
+		if !ok || !s.isPackage(sel.X) || !contains(s.dialect.Types, sel.Sel.Name) {
+			continue
+		}
+		for _, name := range field.Names {
+			if _, ok := s.routers[name.Obj]; !ok && name.Obj != nil {
+				s.routers[name.Obj] = ""
+			}
+		}
+	}
+}
+
+// assign records the variable if it is assigned a router.
+func (s *scanner) assign(lhs, rhs ast.Expr) {
+	ident, ok := lhs.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return
+	}
+	if prefix, ok := s.chain(rhs); ok {
+		s.routers[ident.Obj] = prefix
+	}
+}
+
+// chain interprets the chain of calls of the expression if it starts with a router, and returns the prefix of the
+// routes of the router it evaluates to, if any.
+func (s *scanner) chain(expr ast.Expr) (string, bool) {
+	var calls []call
+	for {
+		ce, ok := expr.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		sel, ok := ce.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return "", false
+		}
+		calls = append([]call{{Name: sel.Sel.Name, Args: ce.Args, Expr: ce}}, calls...)
+		expr = sel.X
+	}
+
+	var prefix string
+	switch {
+	case s.isPackage(expr) && len(calls) > 0 && contains(s.dialect.Constructors, calls[0].Name):
+		s.handled[calls[0].Expr] = true
+		calls = calls[1:]
+	case isIdent(expr):
+		var ok bool
+		if prefix, ok = s.routers[expr.(*ast.Ident).Obj]; !ok {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	for _, c := range calls {
+		s.handled[c.Expr] = true
+	}
+	if len(calls) == 0 {
+		return prefix, true
+	}
+	return s.dialect.Chain(s, prefix, calls)
+}
+
+func (s *scanner) isPackage(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Obj == nil && ident.Name == s.pkg
+}
+
+func isIdent(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Obj != nil
+}
+
+// subrouter records the router parameter of a function given to a call such as chi's Route, with its prefix.
+func (s *scanner) subrouter(fn ast.Expr, prefix string) {
+	lit, ok := fn.(*ast.FuncLit)
+	if !ok || len(lit.Type.Params.List) == 0 || len(lit.Type.Params.List[0].Names) == 0 {
+		return
+	}
+	if name := lit.Type.Params.List[0].Names[0]; name.Obj != nil {
+		s.routers[name.Obj] = prefix
+	}
+}
+
+// add records the route r, whose pattern is joined to the prefix and converted, with its handler. Patterns that
+// cannot be converted are reported as problems, as are the differences of behaviour of those that can.
+func (s *scanner) add(node ast.Node, prefix, pattern string, handler ast.Expr, r route) {
+	original := s.dialect.Join(prefix, pattern)
+	converted, notes, err := s.dialect.Convert(original)
+	if err != nil {
+		s.problem(node, "%s is not migrated: %v", original, err)
+		return
+	}
+	for _, note := range notes {
+		if note != trailingSlashNote || !r.Subtree {
+			s.problem(node, "%s: %s", converted, note)
+		}
+	}
+
+	r.Pattern, r.Handler, r.Pos = converted, s.source(handler), s.position(node)
+	s.result.Routes = append(s.result.Routes, r)
+}
+
+func (s *scanner) problem(node ast.Node, format string, args ...interface{}) {
+	s.result.Problems = append(s.result.Problems, problem{Pos: s.position(node), Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *scanner) position(node ast.Node) string {
+	return s.fset.Position(node.Pos()).String()
+}
+
+func (s *scanner) source(expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, s.fset, expr)
+	return buf.String()
+}
+
+// literal returns the value of the string literal argument of the call, reporting a problem if it is not one.
+func (s *scanner) literal(c call, i int) (string, bool) {
+	if i >= len(c.Args) {
+		return "", false
+	}
+	if lit, ok := c.Args[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if value, err := strconv.Unquote(lit.Value); err == nil {
+			return value, true
+		}
+	}
+	s.problem(c.Expr, "%s is not migrated: its argument %s is not a string literal", c.Name, s.source(c.Args[i]))
+	return "", false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
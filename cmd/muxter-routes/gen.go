@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// methodFields are the methods with a field in muxter.MethodHandler, in the order they are generated.
+var methodFields = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+func generate(pkg, name string, routes []route) ([]byte, error) {
+	byPattern := map[string][]route{}
+	var patterns []string
+	for _, r := range routes {
+		if _, ok := byPattern[r.Pattern]; !ok {
+			patterns = append(patterns, r.Pattern)
+		}
+		byPattern[r.Pattern] = append(byPattern[r.Pattern], r)
+	}
+	sort.Strings(patterns)
+
+	var (
+		body     bytes.Buffer
+		usesHTTP bool
+	)
+	for _, pattern := range patterns {
+		group := byPattern[pattern]
+
+		var options []string
+		for _, r := range group {
+			if r.Name == "" {
+				continue
+			}
+			if len(options) > 0 {
+				return nil, fmt.Errorf("%s: pattern %s is already named", r.Pos, pattern)
+			}
+			options = append(options, fmt.Sprintf("muxter.Name(%q)", r.Name))
+		}
+
+		for _, r := range group {
+			usesHTTP = usesHTTP || r.Standard
+		}
+
+		if len(group) == 1 {
+			r := group[0]
+			if r.Method != "" {
+				options = append([]string{fmt.Sprintf("mux.Method(%q)", r.Method)}, options...)
+			}
+			if r.Standard {
+				fmt.Fprintf(&body, "\tmux.StandardHandle(%q, http.HandlerFunc(%s)%s)\n", pattern, r.Handler, join(options))
+			} else {
+				fmt.Fprintf(&body, "\tmux.HandleFunc(%q, %s%s)\n", pattern, r.Handler, join(options))
+			}
+			continue
+		}
+
+		handlers := map[string]route{}
+		for _, r := range group {
+			if r.Method == "" {
+				return nil, fmt.Errorf("%s: pattern %s is declared for every method and for specific methods", r.Pos, pattern)
+			}
+			if !contains(methodFields, r.Method) {
+				return nil, fmt.Errorf("%s: method %s cannot share pattern %s with other methods", r.Pos, r.Method, pattern)
+			}
+			if other, ok := handlers[r.Method]; ok {
+				return nil, fmt.Errorf("%s: %s %s is already declared by %s", r.Pos, r.Method, pattern, other.Handler)
+			}
+			handlers[r.Method] = r
+		}
+
+		fmt.Fprintf(&body, "\tmux.Handle(%q, muxter.MethodHandler{\n", pattern)
+		for _, method := range methodFields {
+			r, ok := handlers[method]
+			if !ok {
+				continue
+			}
+			if r.Standard {
+				fmt.Fprintf(&body, "\t\t%s: muxter.Adaptor(http.HandlerFunc(%s)),\n", method, r.Handler)
+			} else {
+				fmt.Fprintf(&body, "\t\t%s: muxter.HandlerFunc(%s),\n", method, r.Handler)
+			}
+		}
+		fmt.Fprintf(&body, "\t}%s)\n", join(options))
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, "// Code generated by muxter-routes. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintln(&buf, "import (")
+	if usesHTTP {
+		fmt.Fprintln(&buf, "\t\"net/http\"")
+		fmt.Fprintln(&buf)
+	}
+	fmt.Fprintln(&buf, "\t\"github.com/davidmdm/muxter\"")
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "// %s registers the handlers annotated with muxter:route directives on the mux.\n", name)
+	fmt.Fprintf(&buf, "func %s(mux *muxter.Mux) {\n%s}\n", name, body.String())
+
+	return format.Source(buf.Bytes())
+}
+
+func join(options []string) string {
+	if len(options) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(options, ", ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func parse(t *testing.T, src string) []route {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "handlers.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	routes, err := scan(fset, []*ast.File{file})
+	if err != nil {
+		t.Fatalf("unexpected scan error: %v", err)
+	}
+	return routes
+}
+
+func TestGenerate(t *testing.T) {
+	routes := parse(t, `package api
+
+// getUser returns a user.
+//
+//muxter:route GET /users/:id name=user.show
+func getUser(w http.ResponseWriter, r *http.Request, c muxter.Context) {}
+
+//muxter:route put /users/:id
+func updateUser(w http.ResponseWriter, r *http.Request, c muxter.Context) {}
+
+//muxter:route /health
+//muxter:route /healthz
+func health(w http.ResponseWriter, r *http.Request) {}
+
+//muxter:route POST /users
+func createUser(w http.ResponseWriter, r *http.Request) {}
+
+// notAnnotated is not a route.
+func notAnnotated(w http.ResponseWriter, r *http.Request, c muxter.Context) {}
+`)
+
+	src, err := generate("api", "RegisterRoutes", routes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by muxter-routes. DO NOT EDIT.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+)
+
+// RegisterRoutes registers the handlers annotated with muxter:route directives on the mux.
+func RegisterRoutes(mux *muxter.Mux) {
+	mux.StandardHandle("/health", http.HandlerFunc(health))
+	mux.StandardHandle("/healthz", http.HandlerFunc(health))
+	mux.StandardHandle("/users", http.HandlerFunc(createUser), mux.Method("POST"))
+	mux.Handle("/users/:id", muxter.MethodHandler{
+		GET: muxter.HandlerFunc(getUser),
+		PUT: muxter.HandlerFunc(updateUser),
+	}, muxter.Name("user.show"))
+}
+`
+
+	if string(src) != expected {
+		t.Errorf("unexpected output:\n%s", src)
+	}
+}
+
+func TestGenerateSingleRoute(t *testing.T) {
+	routes := parse(t, `package api
+
+//muxter:route GET /users/:id name=user.show
+func getUser(w http.ResponseWriter, r *http.Request, c muxter.Context) {}
+`)
+
+	src, err := generate("api", "Register", routes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by muxter-routes. DO NOT EDIT.
+
+package api
+
+import (
+	"github.com/davidmdm/muxter"
+)
+
+// Register registers the handlers annotated with muxter:route directives on the mux.
+func Register(mux *muxter.Mux) {
+	mux.HandleFunc("/users/:id", getUser, mux.Method("GET"), muxter.Name("user.show"))
+}
+`
+
+	if string(src) != expected {
+		t.Errorf("unexpected output:\n%s", src)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Src      string
+		Expected string
+	}{
+		{
+			Name: "method",
+			Src: `package api
+
+//muxter:route GET /users
+func (s *server) users(w http.ResponseWriter, r *http.Request) {}
+`,
+			Expected: "handlers.go:3:1: users is a method, only functions can be annotated",
+		},
+		{
+			Name: "missing pattern",
+			Src: `package api
+
+//muxter:route GET users
+func users(w http.ResponseWriter, r *http.Request) {}
+`,
+			Expected: "handlers.go:3:1: route directive must have a pattern starting with a slash: GET users",
+		},
+		{
+			Name: "unknown option",
+			Src: `package api
+
+//muxter:route GET /users cache=true
+func users(w http.ResponseWriter, r *http.Request) {}
+`,
+			Expected: `handlers.go:3:1: unknown route directive option "cache=true"`,
+		},
+		{
+			Name: "not a handler",
+			Src: `package api
+
+//muxter:route GET /users
+func users() {}
+`,
+			Expected: "handlers.go:3:1: users is not a handler function",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "handlers.go", tc.Src, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+
+			_, err = scan(fset, []*ast.File{file})
+			if err == nil || err.Error() != tc.Expected {
+				t.Errorf("expected error %q but got %v", tc.Expected, err)
+			}
+		})
+	}
+}
+
+func TestGenerateConflicts(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Src      string
+		Expected string
+	}{
+		{
+			Name: "duplicate method",
+			Src: `package api
+
+//muxter:route GET /users
+func users(w http.ResponseWriter, r *http.Request) {}
+
+//muxter:route GET /users
+func listUsers(w http.ResponseWriter, r *http.Request) {}
+`,
+			Expected: "handlers.go:6:1: GET /users is already declared by users",
+		},
+		{
+			Name: "any method",
+			Src: `package api
+
+//muxter:route GET /users
+func users(w http.ResponseWriter, r *http.Request) {}
+
+//muxter:route /users
+func allUsers(w http.ResponseWriter, r *http.Request) {}
+`,
+			Expected: "handlers.go:6:1: pattern /users is declared for every method and for specific methods",
+		},
+		{
+			Name: "names",
+			Src: `package api
+
+//muxter:route GET /users name=users
+func users(w http.ResponseWriter, r *http.Request) {}
+
+//muxter:route POST /users name=users.create
+func createUser(w http.ResponseWriter, r *http.Request) {}
+`,
+			Expected: "handlers.go:6:1: pattern /users is already named",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := generate("api", "RegisterRoutes", parse(t, tc.Src))
+			if err == nil || err.Error() != tc.Expected {
+				t.Errorf("expected error %q but got %v", tc.Expected, err)
+			}
+		})
+	}
+}
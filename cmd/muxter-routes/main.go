@@ -0,0 +1,56 @@
+// Command muxter-routes generates the registration of handlers annotated with their routes.
+//
+// Route declarations live beside the handlers, as directives in their doc comments:
+//
+//	//muxter:route GET /users/:id name=user.show
+//	func getUser(w http.ResponseWriter, r *http.Request, c muxter.Context) {}
+//
+// The method is optional, and a handler can have several directives. Handlers are muxter handler functions or
+// net/http handler functions. For the annotated functions of a package muxter-routes emits a function registering
+// them on a mux, such that the route tree is still built statically:
+//
+//	func RegisterRoutes(mux *muxter.Mux)
+//
+// Patterns with handlers for several methods are registered with a muxter.MethodHandler. It is intended to be
+// invoked via go:generate:
+//
+//	//go:generate go run github.com/davidmdm/muxter/cmd/muxter-routes -out routes_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var (
+		dir  = flag.String("dir", ".", "directory of the package to scan")
+		out  = flag.String("out", "routes_gen.go", "output file, relative to the package directory")
+		name = flag.String("func", "RegisterRoutes", "name of the generated registration function")
+	)
+	flag.Parse()
+
+	if err := run(*dir, *out, *name); err != nil {
+		fmt.Fprintln(os.Stderr, "muxter-routes:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, out, name string) error {
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+
+	pkg, routes, err := scanDir(dir, out)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(pkg, name, routes)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
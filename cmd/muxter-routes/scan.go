@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const directive = "//muxter:route "
+
+// route is a route declared by a directive on a handler function.
+type route struct {
+	Method  string
+	Pattern string
+	Name    string
+	Handler string
+	// Standard reports whether the handler is a net/http handler function rather than a muxter handler function.
+	Standard bool
+	// Pos is the position of the directive, for error messages.
+	Pos string
+}
+
+// scanDir parses the Go files of the package in dir, except for tests and the output file, and returns the package
+// name and the routes declared in them.
+func scanDir(dir, out string) (string, []route, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || sameFile(path, out) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return "", nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return "", nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	routes, err := scan(fset, files)
+	return files[0].Name.Name, routes, err
+}
+
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// scan returns the routes declared by the directives in the doc comments of the functions of the files.
+func scan(fset *token.FileSet, files []*ast.File) ([]route, error) {
+	var routes []route
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			for _, comment := range fn.Doc.List {
+				if !strings.HasPrefix(comment.Text, directive) {
+					continue
+				}
+				pos := fset.Position(comment.Pos()).String()
+
+				if fn.Recv != nil {
+					return nil, fmt.Errorf("%s: %s is a method, only functions can be annotated", pos, fn.Name.Name)
+				}
+
+				r, err := parseDirective(strings.TrimPrefix(comment.Text, directive))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", pos, err)
+				}
+				r.Handler = fn.Name.Name
+				r.Pos = pos
+
+				switch fn.Type.Params.NumFields() {
+				case 3:
+				case 2:
+					r.Standard = true
+				default:
+					return nil, fmt.Errorf("%s: %s is not a handler function", pos, fn.Name.Name)
+				}
+
+				routes = append(routes, r)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// parseDirective parses the arguments of a route directive: an optional method, the pattern and options.
+func parseDirective(args string) (route, error) {
+	fields := strings.Fields(args)
+
+	var r route
+	if len(fields) > 0 && !strings.HasPrefix(fields[0], "/") {
+		r.Method, fields = strings.ToUpper(fields[0]), fields[1:]
+	}
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return route{}, fmt.Errorf("route directive must have a pattern starting with a slash: %s", args)
+	}
+	r.Pattern = fields[0]
+
+	for _, option := range fields[1:] {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || key != "name" || value == "" {
+			return route{}, fmt.Errorf("unknown route directive option %q", option)
+		}
+		r.Name = value
+	}
+	return r, nil
+}
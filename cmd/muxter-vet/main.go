@@ -0,0 +1,90 @@
+// Command muxter-vet validates a muxter config, as built into a mux with muxter.FromConfig, before it is deployed.
+//
+// It reports every problem of the config at once rather than the first one FromConfig fails on: invalid and
+// conflicting patterns and their regular expressions, duplicate route names, unknown handlers and middlewares,
+// invalid middleware options, proxies to invalid upstream URLs and static routes without a directory. The config is
+// valid when muxter-vet exits with status 0, in which case it prints the resolved route table:
+//
+//	$ muxter-vet -handlers books.get,books.list -middlewares auth gateway.json
+//	METHODS  PATTERN     NAME        TARGET                    MIDDLEWARES
+//	GET      /books      books.list  handler books.list        muxter.Recover
+//	GET      /books/:id  books.get   handler books.get         muxter.Recover, auth
+//	*        /legacy/    -           proxy http://legacy:8080  muxter.Recover
+//
+// The built-in middlewares of muxter.NewMiddlewareRegistry are known, with the schemas of their options. Handlers
+// are only checked when they are listed with -handlers, and other middlewares must be listed with -middlewares.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	var (
+		handlers    = flag.String("handlers", "", "comma separated names of the handlers of the registry, unchecked if empty")
+		middlewares = flag.String("middlewares", "", "comma separated names of the middlewares of the registry besides the built-in ones")
+		root        = flag.String("root", ".", "directory the static directories of the config are relative to")
+		dial        = flag.Duration("dial", 0, "timeout of connecting to the upstream servers of proxies, not connecting if zero")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: muxter-vet [flags] config.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := options{
+		Handlers:    split(*handlers),
+		Middlewares: split(*middlewares),
+		Root:        *root,
+		Dial:        *dial,
+	}
+	if !run(flag.Arg(0), opts) {
+		os.Exit(1)
+	}
+}
+
+func run(path string, opts options) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "muxter-vet:", err)
+		return false
+	}
+
+	report := vet(data, opts)
+	for _, problem := range report.Problems {
+		fmt.Fprintf(os.Stderr, "muxter-vet: %s: %s\n", path, problem)
+	}
+	if len(report.Problems) > 0 {
+		return false
+	}
+	printTable(os.Stdout, report.Routes)
+	return true
+}
+
+func split(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type options struct {
+	// Handlers are the names of the handlers of the registry, or nil if handlers are not checked.
+	Handlers []string
+	// Middlewares are the names of the middlewares of the registry that are not built-in.
+	Middlewares []string
+	Root        string
+	Dial        time.Duration
+}
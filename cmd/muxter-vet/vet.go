@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/davidmdm/muxter"
+)
+
+// row is a route of the resolved route table.
+type row struct {
+	Methods     []string
+	Pattern     string
+	Name        string
+	Target      string
+	Middlewares []string
+}
+
+type report struct {
+	Routes   []row
+	Problems []string
+}
+
+// vet validates the config and resolves its route table. The routes are registered on a mux with stub handlers and
+// middlewares, such that patterns are validated and conflict as they would when the config is built.
+func vet(data []byte, opts options) report {
+	var rep report
+	problem := func(format string, args ...interface{}) {
+		rep.Problems = append(rep.Problems, fmt.Sprintf(format, args...))
+	}
+
+	cfg, err := muxter.ParseConfig(data)
+	if err != nil {
+		problem("%v", strings.TrimPrefix(err.Error(), "muxter: "))
+		return rep
+	}
+
+	registry := muxter.Registry{
+		Middlewares:        map[string]func(muxter.MiddlewareOptions) (muxter.Middleware, error){},
+		MiddlewareRegistry: muxter.NewMiddlewareRegistry(),
+	}
+	for _, name := range opts.Middlewares {
+		registry.Middlewares[name] = func(muxter.MiddlewareOptions) (muxter.Middleware, error) { return nil, nil }
+	}
+
+	chain := func(scope string, configs []muxter.MiddlewareConfig) []string {
+		names := make([]string, len(configs))
+		for i, config := range configs {
+			names[i] = config.Name
+			if err := registry.ValidateMiddleware(config.Name, config.Options); err != nil {
+				problem("%smiddleware %s: %v", scope, config.Name, err)
+			}
+		}
+		return names
+	}
+
+	if limits := cfg.Limits; limits != nil && (limits.MaxPathLength < 0 || limits.MaxPathSegments < 0 || limits.MaxParamLength < 0) {
+		problem("limits must not be negative")
+	}
+	global := chain("", cfg.Middlewares)
+
+	mux := muxter.New()
+	register := func(scope, pattern string, options ...muxter.Middleware) (registered bool) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				problem("%s%s", scope, strings.TrimPrefix(fmt.Sprint(recovered), "muxter: "))
+			}
+		}()
+		mux.Handle(pattern, muxter.HandlerFunc(func(http.ResponseWriter, *http.Request, muxter.Context) {}), options...)
+		return true
+	}
+
+	for _, route := range cfg.Routes {
+		scope := "route " + route.Pattern + ": "
+		if opts.Handlers != nil && !contains(opts.Handlers, route.Handler) {
+			problem("%sunknown handler %q", scope, route.Handler)
+		}
+		middlewares := chain(scope, route.Middlewares)
+
+		var options []muxter.Middleware
+		if route.Name != "" {
+			options = append(options, muxter.Name(route.Name))
+		}
+		if !register(scope, route.Pattern, options...) {
+			continue
+		}
+		methods := make([]string, len(route.Methods))
+		for i, method := range route.Methods {
+			methods[i] = strings.ToUpper(method)
+		}
+		rep.Routes = append(rep.Routes, row{
+			Methods:     methods,
+			Pattern:     normalize(route.Pattern),
+			Name:        route.Name,
+			Target:      "handler " + route.Handler,
+			Middlewares: append(append([]string(nil), global...), middlewares...),
+		})
+	}
+
+	for _, proxy := range cfg.Proxies {
+		scope := "proxy " + proxy.Pattern + ": "
+		middlewares := chain(scope, proxy.Middlewares)
+		if err := vetUpstream(proxy, opts); err != nil {
+			problem("%s%v", scope, err)
+		}
+		if !register(scope, proxy.Pattern) {
+			continue
+		}
+		rep.Routes = append(rep.Routes, row{
+			Pattern:     normalize(proxy.Pattern),
+			Target:      "proxy " + proxy.Target,
+			Middlewares: append(append([]string(nil), global...), middlewares...),
+		})
+	}
+
+	for _, static := range cfg.Static {
+		scope := "static " + static.Pattern + ": "
+		middlewares := chain(scope, static.Middlewares)
+		if !strings.HasSuffix(static.Pattern, "/") {
+			problem("%spattern must be a rooted subtree ending with a slash", scope)
+			continue
+		}
+		dir := static.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(opts.Root, dir)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			problem("%s%s is not a directory", scope, dir)
+		}
+		if !register(scope, static.Pattern+"*file") {
+			continue
+		}
+		rep.Routes = append(rep.Routes, row{
+			Pattern:     normalize(static.Pattern + "*file"),
+			Target:      "static " + static.Dir,
+			Middlewares: append(append([]string(nil), global...), middlewares...),
+		})
+	}
+
+	sort.SliceStable(rep.Routes, func(i, j int) bool { return rep.Routes[i].Pattern < rep.Routes[j].Pattern })
+	return rep
+}
+
+// vetUpstream validates the target of the proxy and the depth it strips, and connects to the upstream server if a
+// dial timeout is set.
+func vetUpstream(proxy muxter.ProxyConfig, opts options) error {
+	target, err := url.Parse(proxy.Target)
+	if err != nil || target.Host == "" || (target.Scheme != "http" && target.Scheme != "https") {
+		return fmt.Errorf("target must be an absolute http or https url but got: %s", proxy.Target)
+	}
+	if target.RawQuery != "" || target.Fragment != "" {
+		return fmt.Errorf("target must not have a query or fragment but got: %s", proxy.Target)
+	}
+	if segments := staticSegments(proxy.Pattern); proxy.StripDepth < 0 || proxy.StripDepth > segments {
+		return fmt.Errorf("stripDepth must be between 0 and the %d static segments of the pattern but got: %d", segments, proxy.StripDepth)
+	}
+
+	if opts.Dial <= 0 {
+		return nil
+	}
+	address := target.Host
+	if target.Port() == "" {
+		port := "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+		address = net.JoinHostPort(target.Hostname(), port)
+	}
+	conn, err := net.DialTimeout("tcp", address, opts.Dial)
+	if err != nil {
+		return fmt.Errorf("upstream %s is unreachable: %v", address, err)
+	}
+	return conn.Close()
+}
+
+// staticSegments returns the number of leading segments of the pattern that are not params, the segments a proxy
+// can strip.
+func staticSegments(pattern string) int {
+	n := 0
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if segment == "" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") || strings.HasPrefix(segment, "#") {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func normalize(pattern string) string {
+	if normalized, err := muxter.NormalizePattern(pattern); err == nil {
+		return normalized
+	}
+	return pattern
+}
+
+func printTable(w io.Writer, routes []row) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHODS\tPATTERN\tNAME\tTARGET\tMIDDLEWARES")
+	for _, r := range routes {
+		methods, name := strings.Join(r.Methods, ","), r.Name
+		if methods == "" {
+			methods = "*"
+		}
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", methods, r.Pattern, name, r.Target, strings.Join(r.Middlewares, ", "))
+	}
+	tw.Flush()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
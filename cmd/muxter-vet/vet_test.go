@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVet(t *testing.T) {
+	root := t.TempDir()
+
+	testcases := []struct {
+		Name     string
+		Config   string
+		Options  options
+		Expected []string
+	}{
+		{
+			Name: "valid",
+			Config: `{
+				"middlewares": [{"name": "muxter.Recover"}],
+				"routes": [{"pattern": "/books/:id", "handler": "books.get", "middlewares": [{"name": "auth"}]}],
+				"proxies": [{"pattern": "/legacy/", "target": "https://legacy.example.com", "stripDepth": 1}],
+				"static": [{"pattern": "/assets/", "dir": "."}]
+			}`,
+			Options: options{Handlers: []string{"books.get"}, Middlewares: []string{"auth"}},
+		},
+		{
+			Name:     "invalid json",
+			Config:   `{"routes": [{"pattern": "/books", "handle": "books"}]}`,
+			Expected: []string{`invalid config: json: unknown field "handle"`},
+		},
+		{
+			Name: "patterns",
+			Config: `{"routes": [
+				{"pattern": "/books/:id", "handler": "books.get", "name": "book"},
+				{"pattern": "/books/:id", "handler": "books.get"},
+				{"pattern": "/books/:id/cover", "handler": "books.cover", "name": "book"},
+				{"pattern": "/authors/#id:[", "handler": "authors.get"}
+			]}`,
+			Expected: []string{
+				"route /books/:id: failed to register route /books/:id - multiple registrations",
+				`route /books/:id/cover: route name "book" is already registered for /books/:id`,
+				"route /authors/#id:[: failed to register route /authors/#id:[ - error parsing regexp: missing closing ]: `[`",
+			},
+		},
+		{
+			Name: "references",
+			Config: `{
+				"middlewares": [{"name": "muxter.RateLimit", "options": {"burst": 2}}],
+				"routes": [{"pattern": "/books", "handler": "books.list", "middlewares": [{"name": "auth"}]}]
+			}`,
+			Options: options{Handlers: []string{"books.get"}},
+			Expected: []string{
+				"middleware muxter.RateLimit: option rate is required",
+				`route /books: unknown handler "books.list"`,
+				`route /books: middleware auth: unknown middleware "auth"`,
+			},
+		},
+		{
+			Name: "upstreams",
+			Config: `{"proxies": [
+				{"pattern": "/a/", "target": "legacy:8080"},
+				{"pattern": "/b/", "target": "ftp://legacy.example.com"},
+				{"pattern": "/c/:id/", "target": "http://legacy.example.com", "stripDepth": 2}
+			]}`,
+			Expected: []string{
+				"proxy /a/: target must be an absolute http or https url but got: legacy:8080",
+				"proxy /b/: target must be an absolute http or https url but got: ftp://legacy.example.com",
+				"proxy /c/:id/: stripDepth must be between 0 and the 1 static segments of the pattern but got: 2",
+			},
+		},
+		{
+			Name:     "static",
+			Config:   `{"static": [{"pattern": "/assets", "dir": "."}, {"pattern": "/img/", "dir": "missing"}]}`,
+			Expected: []string{"static /assets: pattern must be a rooted subtree ending with a slash", "static /img/: " + root + "/missing is not a directory"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Options.Root = root
+			report := vet([]byte(tc.Config), tc.Options)
+			if !reflect.DeepEqual(report.Problems, tc.Expected) {
+				t.Errorf("expected problems:\n%q\nbut got:\n%q", tc.Expected, report.Problems)
+			}
+		})
+	}
+}
+
+func TestVetDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := `{"proxies": [{"pattern": "/legacy/", "target": "http://` + address + `"}]}`
+	report := vet([]byte(config), options{Dial: time.Second})
+	if len(report.Problems) != 1 {
+		t.Errorf("expected the closed upstream to be unreachable but got %q", report.Problems)
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	config := `{
+		"middlewares": [{"name": "muxter.Recover"}],
+		"routes": [
+			{"pattern": "/books/:id", "handler": "books.get", "methods": ["get"], "name": "books.get"},
+			{"pattern": "/books//", "handler": "books.list", "methods": ["GET", "HEAD"]}
+		],
+		"proxies": [{"pattern": "/legacy/", "target": "http://legacy:8080"}]
+	}`
+	report := vet([]byte(config), options{})
+	if len(report.Problems) > 0 {
+		t.Fatalf("unexpected problems: %q", report.Problems)
+	}
+
+	var buf bytes.Buffer
+	printTable(&buf, report.Routes)
+
+	expected := `METHODS   PATTERN     NAME       TARGET                    MIDDLEWARES
+GET,HEAD  /books/     -          handler books.list        muxter.Recover
+GET       /books/:id  books.get  handler books.get         muxter.Recover
+*         /legacy/    -          proxy http://legacy:8080  muxter.Recover
+`
+	if buf.String() != expected {
+		t.Errorf("expected table:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,177 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/davidmdm/muxter/internal"
+)
+
+// RouteMatch is the route a mux matches for a path.
+type RouteMatch struct {
+	// Pattern is the pattern of the matched route, or empty if no route matches. For redirects it is the subtree
+	// pattern without its trailing slash.
+	Pattern string `json:"pattern,omitempty"`
+	// Params are the path params of the match.
+	Params map[string]string `json:"params,omitempty"`
+	// Redirect reports whether the path is redirected to its rooted subtree, such as /docs to /docs/.
+	Redirect bool `json:"redirect,omitempty"`
+}
+
+func (rm RouteMatch) String() string {
+	switch {
+	case rm.Pattern == "":
+		return "not found"
+	case rm.Redirect:
+		return "redirect to " + rm.Pattern + "/"
+	default:
+		return rm.Pattern
+	}
+}
+
+// RoutingDifference is a sample path matched differently by two muxes.
+type RoutingDifference struct {
+	Path string     `json:"path"`
+	A    RouteMatch `json:"a"`
+	B    RouteMatch `json:"b"`
+}
+
+func (d RoutingDifference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Path, d.A, d.B)
+}
+
+// CompareRouting reports the sample paths that the muxes match to different routes, different params or that only
+// one of them matches. It is a safety net when refactoring a route table or upgrading muxter, to run as a test over
+// the paths of recorded traffic or generated from the routes:
+//
+//	for _, diff := range muxter.CompareRouting(oldMux(), newMux(), samplePaths) {
+//		t.Error(diff)
+//	}
+//
+// Sample paths may be absolute URLs, such that routes with host patterns are matched with their host. Routes are
+// matched as for GET requests without headers, and the routes of nested muxes are compared by their mount points.
+func CompareRouting(a, b *Mux, samplePaths []string) []RoutingDifference {
+	var differences []RoutingDifference
+	for _, path := range samplePaths {
+		matchA, matchB := a.Match(path), b.Match(path)
+		if !reflect.DeepEqual(matchA, matchB) {
+			differences = append(differences, RoutingDifference{Path: path, A: matchA, B: matchB})
+		}
+	}
+	return differences
+}
+
+// Match returns the route the mux matches for the path without serving it. The path may be an absolute URL, such
+// that routes with host patterns are matched with its host.
+func (m *Mux) Match(path string) RouteMatch {
+	r, ok := sampleRequest(path)
+	if !ok {
+		return RouteMatch{}
+	}
+
+	var params []internal.Param
+	value := m.lookup(r, &params, nil)
+	if value != nil {
+		value = value.candidate(r)
+	}
+	if value == nil {
+		return RouteMatch{}
+	}
+
+	return RouteMatch{Pattern: value.pattern, Params: paramMap(params), Redirect: value.isRedirect}
+}
+
+// Candidates returns every route matching the path in precedence order, rather than only the route that serves it
+// like Match, to diagnose which routes shadow others. The first candidate is the route returned by Match, unless it
+// is a redirect. Routes with host patterns come before routes with path patterns, then routes matching the whole path
+// come before the rooted subtrees containing it, the deepest first, and static segments are preferred over regexp
+// params, regexp params over params and params over catchalls.
+func (m *Mux) Candidates(path string) []RouteMatch {
+	r, ok := sampleRequest(path)
+	if !ok {
+		return nil
+	}
+
+	var candidates []RouteMatch
+	m.candidates(r, func(v *value, params []internal.Param) bool {
+		candidates = append(candidates, RouteMatch{Pattern: v.pattern, Params: paramMap(params)})
+		return true
+	})
+	return candidates
+}
+
+// LongestPrefix returns the pattern and handler of the deepest rooted subtree, such as a mounted mux, that contains
+// the path, without serving it. It is meant for tooling that needs to know which part of an application owns a path,
+// for example to page the team on call for it. The path may be an absolute URL, such that rooted subtrees with host
+// patterns are matched with its host.
+//
+//	pattern, _, ok := mux.LongestPrefix("/api/billing/invoices/42") // "/api/billing/", true
+func (m *Mux) LongestPrefix(path string) (pattern string, handler Handler, ok bool) {
+	r, valid := sampleRequest(path)
+	if !valid {
+		return "", nil, false
+	}
+
+	m.candidates(r, func(v *value, params []internal.Param) bool {
+		if !strings.HasSuffix(v.pattern, "/") {
+			return true
+		}
+		pattern, handler, ok = v.pattern, v.handler, true
+		return false
+	})
+	return pattern, handler, ok
+}
+
+// candidates calls fn with the values matching the request in precedence order, as listed by Candidates, until fn
+// returns false.
+func (m *Mux) candidates(r *http.Request, fn func(v *value, params []internal.Param) bool) {
+	done := false
+	visit := func(hostPatterns bool) func(v *value, params []internal.Param) bool {
+		return func(v *value, params []internal.Param) bool {
+			if isHostPattern(v.pattern) != hostPatterns {
+				return true
+			}
+			if v = v.candidate(r); v != nil && !fn(v, params) {
+				done = true
+				return false
+			}
+			return true
+		}
+	}
+
+	matchTrailingSlash := m.matchTrailingSlash != nil && *m.matchTrailingSlash
+	if m.hostRoutes && !strings.HasPrefix(r.URL.Path, hostSegment) {
+		if key, ok := hostKey(r.Host, r.URL.Path); ok {
+			m.root.Candidates(key, matchTrailingSlash, visit(true))
+		}
+	}
+	if !done {
+		m.root.Candidates(r.URL.Path, matchTrailingSlash, visit(false))
+	}
+}
+
+// sampleRequest returns a GET request without headers for the path, which may be an absolute URL.
+func sampleRequest(path string) (*http.Request, bool) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, false
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{}}, true
+}
+
+func paramMap(params []internal.Param) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(params))
+	for _, param := range params {
+		result[param.Key] = param.Value
+	}
+	return result
+}
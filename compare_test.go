@@ -0,0 +1,197 @@
+package muxter
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCompareRouting(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	a := New()
+	a.HandleFunc("/books/:id", noop)
+	a.HandleFunc("/books/latest", noop)
+	a.HandleFunc("/docs/", noop)
+	a.HandleFunc("/authors/:name", noop)
+	a.HandleFunc("//api.example.com/", noop)
+
+	b := New()
+	b.HandleFunc("/books/:id", noop)
+	b.HandleFunc("/docs/*rest", noop)
+	b.HandleFunc("/authors/:author", noop)
+	b.HandleFunc("/", noop)
+
+	samples := []string{
+		"/books/1",
+		"/books/latest",
+		"/docs",
+		"/docs/intro",
+		"/authors/ursula",
+		"/about",
+		"http://api.example.com/books/1",
+	}
+
+	expected := []RoutingDifference{
+		{
+			Path: "/books/latest",
+			A:    RouteMatch{Pattern: "/books/latest"},
+			B:    RouteMatch{Pattern: "/books/:id", Params: map[string]string{"id": "latest"}},
+		},
+		{
+			Path: "/docs",
+			A:    RouteMatch{Pattern: "/docs", Redirect: true},
+			B:    RouteMatch{Pattern: "/"},
+		},
+		{
+			Path: "/docs/intro",
+			A:    RouteMatch{Pattern: "/docs/"},
+			B:    RouteMatch{Pattern: "/docs/*rest", Params: map[string]string{"rest": "intro"}},
+		},
+		{
+			Path: "/authors/ursula",
+			A:    RouteMatch{Pattern: "/authors/:name", Params: map[string]string{"name": "ursula"}},
+			B:    RouteMatch{Pattern: "/authors/:author", Params: map[string]string{"author": "ursula"}},
+		},
+		{
+			Path: "/about",
+			A:    RouteMatch{},
+			B:    RouteMatch{Pattern: "/"},
+		},
+		{
+			Path: "http://api.example.com/books/1",
+			A:    RouteMatch{Pattern: "//api.example.com/"},
+			B:    RouteMatch{Pattern: "/books/:id", Params: map[string]string{"id": "1"}},
+		},
+	}
+
+	differences := CompareRouting(a, b, samples)
+	if !reflect.DeepEqual(differences, expected) {
+		t.Errorf("unexpected differences:\n%v\nexpected:\n%v", differences, expected)
+	}
+
+	if differences := CompareRouting(a, a, samples); len(differences) != 0 {
+		t.Errorf("expected a mux to route like itself but got %v", differences)
+	}
+
+	if diff := expected[4].String(); diff != "/about: not found != /" {
+		t.Errorf("unexpected difference string: %q", diff)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New()
+	mux.HandleFunc("/", noop)
+	mux.HandleFunc("/api/", noop)
+	mux.HandleFunc("/api/*rest", noop)
+	mux.HandleFunc("/api/users/", noop)
+	mux.HandleFunc("/api/users/me", noop)
+	mux.HandleFunc("/api/users/:id", noop)
+	mux.HandleFunc(`/api/users/#id:\d+`, noop)
+	mux.HandleFunc("//:tenant.example.com/api/", noop)
+
+	cases := []struct {
+		Path     string
+		Expected []RouteMatch
+	}{
+		{
+			Path: "/api/users/42",
+			Expected: []RouteMatch{
+				{Pattern: "/api/users/:id", Params: map[string]string{"id": "42"}},
+				{Pattern: `/api/users/#id:\d+`, Params: map[string]string{"id": "42"}},
+				{Pattern: "/api/*rest", Params: map[string]string{"rest": "users/42"}},
+				{Pattern: "/api/users/"},
+				{Pattern: "/api/"},
+				{Pattern: "/"},
+			},
+		},
+		{
+			Path: "/api/users/me",
+			Expected: []RouteMatch{
+				{Pattern: "/api/users/me"},
+				{Pattern: "/api/users/:id", Params: map[string]string{"id": "me"}},
+				{Pattern: "/api/*rest", Params: map[string]string{"rest": "users/me"}},
+				{Pattern: "/api/users/"},
+				{Pattern: "/api/"},
+				{Pattern: "/"},
+			},
+		},
+		{
+			Path: "http://acme.example.com/api/users/",
+			Expected: []RouteMatch{
+				{Pattern: "//:tenant.example.com/api/", Params: map[string]string{"tenant": "acme"}},
+				{Pattern: "/api/users/"},
+				{Pattern: "/api/*rest", Params: map[string]string{"rest": "users/"}},
+				{Pattern: "/api/"},
+				{Pattern: "/"},
+			},
+		},
+		{
+			Path:     "/docs",
+			Expected: []RouteMatch{{Pattern: "/"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			candidates := mux.Candidates(tc.Path)
+			if !reflect.DeepEqual(candidates, tc.Expected) {
+				t.Errorf("expected candidates:\n%+v\nbut got:\n%+v", tc.Expected, candidates)
+			}
+			if match := mux.Match(tc.Path); !reflect.DeepEqual(match, candidates[0]) {
+				t.Errorf("expected first candidate to be the match %+v", match)
+			}
+		})
+	}
+}
+
+func TestLongestPrefix(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	billing := New()
+	billing.Handle("/invoices/:id", noop)
+
+	mux := New()
+	mux.Handle("/", noop)
+	mux.Handle("/api/", noop)
+	mux.Handle("/api/billing/", billing)
+	mux.Handle("/api/billing/health", noop)
+	mux.Handle("/api/users/:id/", noop)
+	mux.Handle("/api/users/:id/avatar", noop)
+	mux.Handle("//admin.example.com/", noop)
+
+	cases := []struct {
+		Path            string
+		ExpectedPattern string
+	}{
+		{Path: "/api/billing/invoices/42", ExpectedPattern: "/api/billing/"},
+		{Path: "/api/billing/", ExpectedPattern: "/api/billing/"},
+		{Path: "/api/billing/health", ExpectedPattern: "/api/billing/"},
+		{Path: "/api/billing", ExpectedPattern: "/api/"},
+		{Path: "/api/users/1/avatar", ExpectedPattern: "/api/users/:id/"},
+		{Path: "/api/orders", ExpectedPattern: "/api/"},
+		{Path: "/about", ExpectedPattern: "/"},
+		{Path: "http://admin.example.com/api/orders", ExpectedPattern: "//admin.example.com/"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			pattern, handler, ok := mux.LongestPrefix(tc.Path)
+			if !ok || pattern != tc.ExpectedPattern || handler == nil {
+				t.Errorf("expected subtree %s but got %q (%v)", tc.ExpectedPattern, pattern, ok)
+			}
+		})
+	}
+
+	if _, handler, _ := mux.LongestPrefix("/api/billing/invoices/42"); handler == nil {
+		t.Fatalf("expected handler")
+	} else if _, ok := handler.(*Mux); !ok {
+		t.Errorf("expected handler of the mounted mux but got %T", handler)
+	}
+
+	if _, _, ok := New().LongestPrefix("/api"); ok {
+		t.Errorf("expected no subtree in empty mux")
+	}
+}
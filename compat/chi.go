@@ -0,0 +1,47 @@
+package compat
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+	"github.com/go-chi/chi/v5"
+)
+
+// Chi adapts a handler written for chi. The params of the request are available with chi.URLParam, the part of the
+// path matched by a catchall segment or a rooted subtree as the "*" param, and chi.RouteContext(ctx).RoutePattern
+// returns the matched pattern in chi's syntax, such as "/users/{id}".
+//
+//	mux.Handle("/users/:id", compat.Chi(http.HandlerFunc(getUser)))
+func Chi(h http.Handler) muxter.Handler {
+	return muxter.HandlerFunc(func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		rctx := chi.NewRouteContext()
+		keys, values := orderedParams(c)
+		for i, key := range keys {
+			rctx.URLParams.Add(key, values[i])
+		}
+		if rest, ok := remainder(c); ok {
+			rctx.URLParams.Add("*", rest)
+		}
+		rctx.RoutePath = r.URL.Path
+		rctx.RoutePatterns = []string{ChiPattern(c.Pattern())}
+		rctx.RouteMethod = r.Method
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+	})
+}
+
+// ChiPattern returns the chi pattern equivalent to a muxter pattern: "/users/:id" is "/users/{id}", "/#id:\d+" is
+// "/{id:\d+}", and catchall segments and rooted subtrees are "*".
+func ChiPattern(pattern string) string {
+	return convertPattern(pattern, func(seg segment) string {
+		switch seg.kind {
+		case catchallSegment:
+			return "*"
+		case expressionSegment:
+			return "{" + seg.key + ":" + seg.expr + "}"
+		default:
+			return "{" + seg.key + "}"
+		}
+	}, "*")
+}
@@ -0,0 +1,127 @@
+// Package compat adapts handlers written for other routers, such as chi, gorilla/mux and echo, to muxter handlers,
+// such that applications can move to muxter one route at a time. The adapters populate the params of the matched
+// muxter route where the handlers expect their router to have stored them, like chi.URLParam and mux.Vars.
+//
+// It is a module of its own so that muxter does not depend on the routers.
+package compat
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/davidmdm/muxter"
+)
+
+// segmentKind is the kind of a dynamic segment of a muxter pattern.
+type segmentKind int
+
+const (
+	wildcardSegment segmentKind = iota
+	expressionSegment
+	catchallSegment
+)
+
+// segment is a dynamic segment of a muxter pattern.
+type segment struct {
+	kind segmentKind
+	key  string
+	expr string
+}
+
+// convertPattern rewrites a muxter pattern with the syntax of another router: the static parts of the pattern are
+// kept, and its dynamic segments and trailing slash of rooted subtrees are rewritten by the functions.
+func convertPattern(pattern string, dynamic func(segment) string, subtree string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ':', '*', '#':
+			seg, end := parseSegment(pattern, i)
+			b.WriteString(dynamic(seg))
+			i = end
+		default:
+			b.WriteByte(pattern[i])
+			i++
+		}
+	}
+	if strings.HasSuffix(pattern, "/") {
+		b.WriteString(subtree)
+	}
+	return b.String()
+}
+
+// parseSegment parses the dynamic segment of the pattern starting at i, and returns the index of its end.
+func parseSegment(pattern string, i int) (segment, int) {
+	switch pattern[i] {
+	case '*':
+		return segment{kind: catchallSegment, key: pattern[i+1:]}, len(pattern)
+	case '#':
+		colon := strings.IndexByte(pattern[i:], ':')
+		if colon == -1 {
+			return segment{kind: expressionSegment, key: pattern[i+1:]}, len(pattern)
+		}
+		colon += i
+		end := colon + 1
+		for end < len(pattern) && !(pattern[end] == '/' && pattern[end-1] != '\\') {
+			end++
+		}
+		return segment{kind: expressionSegment, key: pattern[i+1 : colon], expr: pattern[colon+1 : end]}, end
+	default:
+		end := strings.IndexByte(pattern[i:], '/')
+		if end == -1 {
+			return segment{kind: wildcardSegment, key: pattern[i+1:]}, len(pattern)
+		}
+		return segment{kind: wildcardSegment, key: pattern[i+1 : i+end]}, i + end
+	}
+}
+
+// orderedParams returns the params of the request in the order of the matched pattern, followed by any other params
+// in lexical order.
+func orderedParams(c muxter.Context) (keys, values []string) {
+	params := c.Params()
+	pattern := c.Pattern()
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != ':' && pattern[i] != '*' && pattern[i] != '#' {
+			continue
+		}
+		seg, end := parseSegment(pattern, i)
+		if value, ok := params[seg.key]; ok {
+			keys, values = append(keys, seg.key), append(values, value)
+			delete(params, seg.key)
+		}
+		i = end - 1
+	}
+
+	rest := make([]string, 0, len(params))
+	for key := range params {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		keys, values = append(keys, key), append(values, params[key])
+	}
+	return keys, values
+}
+
+// remainder returns the part of the request path matched by the catchall segment of the pattern, or by the rooted
+// subtree it ends with, which other routers expose as the "*" param. It reports false if the pattern matches a
+// fixed number of segments.
+func remainder(c muxter.Context) (string, bool) {
+	pattern := c.Pattern()
+	if i := strings.LastIndexByte(pattern, '/'); i != -1 && strings.HasPrefix(pattern[i+1:], "*") {
+		return c.Param(pattern[i+2:]), true
+	}
+	if !strings.HasSuffix(pattern, "/") {
+		return "", false
+	}
+
+	slashes := strings.Count(pattern, "/")
+	path := c.OriginalPath()
+	for i := 0; i < slashes; i++ {
+		next := strings.IndexByte(path, '/')
+		if next == -1 {
+			return "", true
+		}
+		path = path[next+1:]
+	}
+	return path, true
+}
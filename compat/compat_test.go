@@ -0,0 +1,112 @@
+package compat
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmdm/muxter"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+)
+
+func TestAdapters(t *testing.T) {
+	chiHandler := Chi(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %s", chi.URLParam(r, "owner"), chi.URLParam(r, "id"), chi.URLParam(r, "*"), chi.RouteContext(r.Context()).RoutePattern())
+	}))
+	gorillaHandler := Gorilla(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		fmt.Fprintf(w, "%s %s %s", vars["owner"], vars["id"], vars["rest"])
+	}))
+	echoHandler := Echo(nil, func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("%s %s %s %s", c.Param("owner"), c.Param("id"), c.Param("*"), c.Path()))
+	})
+
+	testcases := []struct {
+		Name         string
+		Handler      muxter.Handler
+		Pattern      string
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:         "chi",
+			Handler:      chiHandler,
+			Pattern:      `/repos/:owner/#id:\d+`,
+			Path:         "/repos/davidmdm/42",
+			ExpectedCode: 200,
+			ExpectedBody: `davidmdm 42  /repos/{owner}/{id:\d+}`,
+		},
+		{
+			Name:         "chi catchall",
+			Handler:      chiHandler,
+			Pattern:      "/repos/:owner/files/*rest",
+			Path:         "/repos/davidmdm/files/a/b.go",
+			ExpectedCode: 200,
+			ExpectedBody: "davidmdm  a/b.go /repos/{owner}/files/*",
+		},
+		{
+			Name:         "chi subtree",
+			Handler:      chiHandler,
+			Pattern:      "/repos/:owner/",
+			Path:         "/repos/davidmdm/a/b.go",
+			ExpectedCode: 200,
+			ExpectedBody: "davidmdm  a/b.go /repos/{owner}/*",
+		},
+		{
+			Name:         "gorilla",
+			Handler:      gorillaHandler,
+			Pattern:      "/repos/:owner/files/*rest",
+			Path:         "/repos/davidmdm/files/a/b.go",
+			ExpectedCode: 200,
+			ExpectedBody: "davidmdm  a/b.go",
+		},
+		{
+			Name:         "echo",
+			Handler:      echoHandler,
+			Pattern:      `/repos/:owner/#id:\d+`,
+			Path:         "/repos/davidmdm/42",
+			ExpectedCode: 200,
+			ExpectedBody: "davidmdm 42  /repos/:owner/:id",
+		},
+		{
+			Name:         "echo subtree",
+			Handler:      echoHandler,
+			Pattern:      "/repos/:owner/",
+			Path:         "/repos/davidmdm/a/b.go",
+			ExpectedCode: 200,
+			ExpectedBody: "davidmdm  a/b.go /repos/:owner/*",
+		},
+		{
+			Name:         "echo error",
+			Handler:      echoHandler,
+			Pattern:      `/repos/:owner/#id:\d+`,
+			Path:         "/repos/davidmdm/0",
+			ExpectedCode: 404,
+			ExpectedBody: "{\"message\":\"Not Found\"}\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			m := muxter.New()
+			m.Handle(tc.Pattern, tc.Handler)
+
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
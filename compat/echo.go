@@ -0,0 +1,68 @@
+package compat
+
+import (
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+	"github.com/labstack/echo/v4"
+)
+
+// Echo adapts a handler written for echo, which is served with a context of the echo instance such that its binder,
+// renderer and logger are used. The params of the request are available with echo.Context.Param, the part of the
+// path matched by a catchall segment or a rooted subtree as the "*" param, and echo.Context.Path returns the matched
+// pattern in echo's syntax. Errors returned by the handler are handled by the HTTPErrorHandler of the instance. A
+// nil instance defaults to echo.New().
+//
+//	mux.Handle("/users/:id", compat.Echo(e, getUser))
+func Echo(e *echo.Echo, h echo.HandlerFunc) muxter.Handler {
+	if e == nil {
+		e = echo.New()
+	}
+	return muxter.HandlerFunc(func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		ec := &echoContext{Context: e.NewContext(r, w), path: EchoPattern(c.Pattern())}
+		ec.names, ec.values = orderedParams(c)
+		if rest, ok := remainder(c); ok {
+			ec.names, ec.values = append(ec.names, "*"), append(ec.values, rest)
+		}
+
+		if err := h(ec); err != nil {
+			e.HTTPErrorHandler(err, ec)
+		}
+	})
+}
+
+// EchoPattern returns the echo pattern equivalent to a muxter pattern. Wildcard segments have the same syntax,
+// expressions become wildcards since echo has no equivalent, and catchall segments and rooted subtrees are "*".
+func EchoPattern(pattern string) string {
+	return convertPattern(pattern, func(seg segment) string {
+		if seg.kind == catchallSegment {
+			return "*"
+		}
+		return ":" + seg.key
+	}, "*")
+}
+
+// echoContext holds the params of the request itself, since the params of contexts created with Echo.NewContext
+// are limited to the number of params of the routes registered with the instance.
+type echoContext struct {
+	echo.Context
+	path   string
+	names  []string
+	values []string
+}
+
+func (c *echoContext) Path() string               { return c.path }
+func (c *echoContext) SetPath(path string)        { c.path = path }
+func (c *echoContext) ParamNames() []string       { return c.names }
+func (c *echoContext) ParamValues() []string      { return c.values }
+func (c *echoContext) SetParamNames(n ...string)  { c.names = n }
+func (c *echoContext) SetParamValues(v ...string) { c.values = v }
+
+func (c *echoContext) Param(name string) string {
+	for i, key := range c.names {
+		if key == name && i < len(c.values) {
+			return c.values[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package compat
+
+import (
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+	"github.com/gorilla/mux"
+)
+
+// Gorilla adapts a handler written for gorilla/mux. The params of the request are available with mux.Vars, where
+// the part of the path matched by a catchall segment is stored under the key of the segment like gorilla's
+// "{path:.*}". mux.CurrentRoute returns nil, as there is no gorilla route.
+//
+//	mux.Handle("/users/:id", compat.Gorilla(http.HandlerFunc(getUser)))
+func Gorilla(h http.Handler) muxter.Handler {
+	return muxter.HandlerFunc(func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		h.ServeHTTP(w, mux.SetURLVars(r, c.Params()))
+	})
+}
@@ -0,0 +1,326 @@
+package muxter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Config declares a mux as data, such that it can be loaded from a JSON or YAML file while handlers stay in Go. It
+// is built into a mux with FromConfig.
+type Config struct {
+	// Limits are the path and param limits of the mux.
+	Limits *LimitsConfig `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Middlewares are applied to every route, as with Mux.Use.
+	Middlewares []MiddlewareConfig `json:"middlewares,omitempty" yaml:"middlewares,omitempty"`
+	// Routes are served by handlers of the registry.
+	Routes []RouteConfig `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// Proxies are served by reverse proxies to upstream servers.
+	Proxies []ProxyConfig `json:"proxies,omitempty" yaml:"proxies,omitempty"`
+	// Static are served from directories, as with Static.
+	Static []StaticConfig `json:"static,omitempty" yaml:"static,omitempty"`
+}
+
+// LimitsConfig declares the LimitPath and LimitParams options of a mux. Zero values are not limited.
+type LimitsConfig struct {
+	MaxPathLength   int `json:"maxPathLength,omitempty" yaml:"maxPathLength,omitempty"`
+	MaxPathSegments int `json:"maxPathSegments,omitempty" yaml:"maxPathSegments,omitempty"`
+	MaxParamLength  int `json:"maxParamLength,omitempty" yaml:"maxParamLength,omitempty"`
+}
+
+// MiddlewareConfig declares a middleware by the name it is registered with and its options.
+type MiddlewareConfig struct {
+	Name    string            `json:"name" yaml:"name"`
+	Options MiddlewareOptions `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// RouteConfig declares a route served by a handler of the registry.
+type RouteConfig struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Handler is the name of the handler in the registry.
+	Handler string `json:"handler" yaml:"handler"`
+	// Methods are the methods accepted by the route. Routes without methods accept any method.
+	Methods []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	// Name is the name of the route, as with the Name registration option.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Tags are the tags of the route, as with the Tags registration option.
+	Tags        []string           `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Middlewares []MiddlewareConfig `json:"middlewares,omitempty" yaml:"middlewares,omitempty"`
+}
+
+// ProxyConfig declares a reverse proxy forwarding the requests of a route to an upstream server.
+type ProxyConfig struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Target is the URL of the upstream server. Its path prefixes the paths of forwarded requests.
+	Target string `json:"target" yaml:"target"`
+	// StripDepth is the number of segments removed from the paths of forwarded requests, as with StripDepth.
+	StripDepth  int                `json:"stripDepth,omitempty" yaml:"stripDepth,omitempty"`
+	Middlewares []MiddlewareConfig `json:"middlewares,omitempty" yaml:"middlewares,omitempty"`
+}
+
+// StaticConfig declares a subtree serving the files of a directory.
+type StaticConfig struct {
+	// Pattern is the rooted subtree serving the files, such as "/assets/".
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Dir is the directory of the files.
+	Dir string `json:"dir" yaml:"dir"`
+	// CacheSize and MaxFileSize configure the in-memory cache of the files, as in StaticOptions.
+	CacheSize   int64              `json:"cacheSize,omitempty" yaml:"cacheSize,omitempty"`
+	MaxFileSize int64              `json:"maxFileSize,omitempty" yaml:"maxFileSize,omitempty"`
+	Middlewares []MiddlewareConfig `json:"middlewares,omitempty" yaml:"middlewares,omitempty"`
+}
+
+// MiddlewareOptions are the options of a middleware declared in a Config, as decoded from JSON or YAML.
+type MiddlewareOptions map[string]interface{}
+
+// Decode decodes the options into dst, typically a pointer to the options struct of the middleware, as JSON.
+// Unknown options are an error.
+func (o MiddlewareOptions) Decode(dst interface{}) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
+
+// HandlerRegistry resolves the handlers and middlewares a Config refers to by name.
+type HandlerRegistry interface {
+	// Handler returns the handler with the name.
+	Handler(name string) (Handler, error)
+	// Middleware returns the middleware with the name, configured by the options.
+	Middleware(name string, options MiddlewareOptions) (Middleware, error)
+}
+
+// Registry is a HandlerRegistry of handlers and middleware constructors by name.
+type Registry struct {
+	Handlers    map[string]Handler
+	Middlewares map[string]func(options MiddlewareOptions) (Middleware, error)
+	// MiddlewareRegistry resolves the middlewares that are not in Middlewares, validating their options against
+	// their schemas before the mux is built.
+	MiddlewareRegistry *MiddlewareRegistry
+}
+
+func (reg Registry) Handler(name string) (Handler, error) {
+	if handler, ok := reg.Handlers[name]; ok {
+		return handler, nil
+	}
+	return nil, fmt.Errorf("unknown handler %q", name)
+}
+
+func (reg Registry) Middleware(name string, options MiddlewareOptions) (Middleware, error) {
+	if constructor, ok := reg.Middlewares[name]; ok {
+		return constructor(options)
+	}
+	if reg.MiddlewareRegistry != nil {
+		return reg.MiddlewareRegistry.Middleware(name, options)
+	}
+	return nil, fmt.Errorf("unknown middleware %q", name)
+}
+
+// ValidateMiddleware validates the options of the middlewares of the MiddlewareRegistry. Middlewares of Middlewares
+// have no schema, and their options are only checked by their constructors.
+func (reg Registry) ValidateMiddleware(name string, options MiddlewareOptions) error {
+	if _, ok := reg.Middlewares[name]; ok {
+		return nil
+	}
+	if reg.MiddlewareRegistry != nil {
+		return reg.MiddlewareRegistry.ValidateMiddleware(name, options)
+	}
+	return fmt.Errorf("unknown middleware %q", name)
+}
+
+// middlewareValidator is implemented by registries validating middleware options before they are built, such as
+// Registry and MiddlewareRegistry.
+type middlewareValidator interface {
+	ValidateMiddleware(name string, options MiddlewareOptions) error
+}
+
+// validateConfig validates all the middlewares of the config if the registry can, such that the problems of the
+// config are reported at once and before any middleware is built.
+func validateConfig(cfg Config, registry HandlerRegistry) error {
+	validator, ok := registry.(middlewareValidator)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+	check := func(scope string, chain []MiddlewareConfig) {
+		for _, config := range chain {
+			if err := validator.ValidateMiddleware(config.Name, config.Options); err != nil {
+				problems = append(problems, fmt.Sprintf("%smiddleware %s: %v", scope, config.Name, err))
+			}
+		}
+	}
+
+	check("", cfg.Middlewares)
+	for _, route := range cfg.Routes {
+		check("route "+route.Pattern+": ", route.Middlewares)
+	}
+	for _, proxy := range cfg.Proxies {
+		check("proxy "+proxy.Pattern+": ", proxy.Middlewares)
+	}
+	for _, static := range cfg.Static {
+		check("static "+static.Pattern+": ", static.Middlewares)
+	}
+
+	if problems != nil {
+		return &ChainError{Problems: problems}
+	}
+	return nil
+}
+
+// ParseConfig parses a JSON config.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("muxter: invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// FromConfig builds a mux from the config, resolving the handlers and middlewares it refers to with the registry.
+// Unlike registering routes in code, invalid configs, such as conflicting patterns or unknown handlers, are reported
+// as errors rather than panics. When the registry validates middleware options, as Registry does for the middlewares
+// of its MiddlewareRegistry, all the middlewares of the config are validated before any is built.
+//
+//	cfg, err := muxter.ParseConfig(data)
+//	...
+//	mux, err := muxter.FromConfig(cfg, muxter.Registry{
+//		Handlers:    map[string]muxter.Handler{"books.get": getBook},
+//		Middlewares: map[string]func(muxter.MiddlewareOptions) (muxter.Middleware, error){"auth": newAuth},
+//	})
+func FromConfig(cfg Config, registry HandlerRegistry) (mux *Mux, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			mux, err = nil, fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	if err := validateConfig(cfg, registry); err != nil {
+		return nil, fmt.Errorf("muxter: %w", err)
+	}
+
+	var options []MuxOption
+	if limits := cfg.Limits; limits != nil {
+		if limits.MaxPathLength > 0 || limits.MaxPathSegments > 0 {
+			options = append(options, LimitPath(PathLimits{MaxLength: limits.MaxPathLength, MaxSegments: limits.MaxPathSegments}))
+		}
+		if limits.MaxParamLength > 0 {
+			options = append(options, LimitParams(ParamLimits{Default: ParamLimit{MaxLength: limits.MaxParamLength}}))
+		}
+	}
+	mux = New(options...)
+
+	middlewares, err := configMiddlewares(cfg.Middlewares, registry)
+	if err != nil {
+		return nil, fmt.Errorf("muxter: %w", err)
+	}
+	mux.Use(middlewares...)
+
+	for _, route := range cfg.Routes {
+		handler, err := registry.Handler(route.Handler)
+		if err != nil {
+			return nil, fmt.Errorf("muxter: route %s: %w", route.Pattern, err)
+		}
+		middlewares, err := configMiddlewares(route.Middlewares, registry)
+		if err != nil {
+			return nil, fmt.Errorf("muxter: route %s: %w", route.Pattern, err)
+		}
+		if len(route.Methods) > 0 {
+			middlewares = append([]Middleware{mux.methods(route.Methods)}, middlewares...)
+		}
+		if route.Name != "" {
+			middlewares = append(middlewares, Name(route.Name))
+		}
+		if len(route.Tags) > 0 {
+			middlewares = append(middlewares, Tags(route.Tags...))
+		}
+		mux.Handle(route.Pattern, handler, middlewares...)
+	}
+
+	for _, proxy := range cfg.Proxies {
+		target, err := url.Parse(proxy.Target)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("muxter: proxy %s: target must be an absolute url but got: %s", proxy.Pattern, proxy.Target)
+		}
+		middlewares, err := configMiddlewares(proxy.Middlewares, registry)
+		if err != nil {
+			return nil, fmt.Errorf("muxter: proxy %s: %w", proxy.Pattern, err)
+		}
+		handler := Adaptor(httputil.NewSingleHostReverseProxy(target), NoContext)
+		if proxy.StripDepth > 0 {
+			handler = StripDepth(proxy.StripDepth, handler)
+		}
+		mux.Handle(proxy.Pattern, handler, middlewares...)
+	}
+
+	for _, static := range cfg.Static {
+		if !strings.HasSuffix(static.Pattern, "/") {
+			return nil, fmt.Errorf("muxter: static %s: pattern must be a rooted subtree ending with a slash", static.Pattern)
+		}
+		info, err := os.Stat(static.Dir)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("muxter: static %s: %s is not a directory", static.Pattern, static.Dir)
+		}
+		middlewares, err := configMiddlewares(static.Middlewares, registry)
+		if err != nil {
+			return nil, fmt.Errorf("muxter: static %s: %w", static.Pattern, err)
+		}
+		handler := Static(os.DirFS(static.Dir), StaticOptions{
+			Param:       "file",
+			CacheSize:   static.CacheSize,
+			MaxFileSize: static.MaxFileSize,
+		})
+		mux.Handle(static.Pattern+"*file", handler, middlewares...)
+	}
+
+	return mux, nil
+}
+
+func configMiddlewares(configs []MiddlewareConfig, registry HandlerRegistry) ([]Middleware, error) {
+	middlewares := make([]Middleware, 0, len(configs))
+	for _, config := range configs {
+		middleware, err := registry.Middleware(config.Name, config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", config.Name, err)
+		}
+		middlewares = append(middlewares, middleware)
+	}
+	return middlewares, nil
+}
+
+// methods returns the guard of a route accepting the methods, as used by the method registration helpers.
+func (m *Mux) methods(methods []string) Middleware {
+	if len(methods) == 1 {
+		return m.verb(strings.ToUpper(methods[0]))
+	}
+
+	notAllowed := m.methodNotAllowedHandler
+	if notAllowed == nil {
+		notAllowed = defaultMethodNotAllowedHandler
+	}
+
+	allowed := make([]string, len(methods))
+	for i, method := range methods {
+		allowed[i] = strings.ToUpper(method)
+	}
+
+	return func(h Handler) Handler {
+		return allowMethods(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if !containsFold(allowed, r.Method) {
+				methodNotAllowed(w, r, c, notAllowed, allowed)
+				return
+			}
+			h.ServeHTTPx(w, r, c)
+		}), allowed...)
+	}
+}
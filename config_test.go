@@ -0,0 +1,184 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromConfig(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("upstream " + r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ParseConfig([]byte(`{
+		"limits": {"maxPathSegments": 8, "maxParamLength": 10},
+		"middlewares": [{"name": "header", "options": {"name": "X-Global", "value": "1"}}],
+		"routes": [
+			{"pattern": "/books/:id", "handler": "book", "methods": ["GET", "PUT"], "name": "book", "tags": ["books"]},
+			{"pattern": "/authors", "handler": "authors", "methods": ["post"], "middlewares": [{"name": "header", "options": {"name": "X-Route", "value": "authors"}}]}
+		],
+		"proxies": [{"pattern": "/upstream/", "target": "` + upstream.URL + `/base", "stripDepth": 1}],
+		"static": [{"pattern": "/assets/", "dir": "` + filepath.ToSlash(dir) + `", "cacheSize": 1024}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := Registry{
+		Handlers: map[string]Handler{
+			"book": HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Write([]byte("book " + c.Param("id")))
+			}),
+			"authors": HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Write([]byte("authors"))
+			}),
+		},
+		Middlewares: map[string]func(MiddlewareOptions) (Middleware, error){
+			"header": func(options MiddlewareOptions) (Middleware, error) {
+				var opts struct{ Name, Value string }
+				if err := options.Decode(&opts); err != nil {
+					return nil, err
+				}
+				return func(h Handler) Handler {
+					return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+						w.Header().Set(opts.Name, opts.Value)
+						h.ServeHTTPx(w, r, c)
+					})
+				}, nil
+			},
+		},
+	}
+
+	mux, err := FromConfig(cfg, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name           string
+		Method         string
+		Path           string
+		ExpectedCode   int
+		ExpectedBody   string
+		ExpectedHeader map[string]string
+	}{
+		{Name: "route", Method: "GET", Path: "/books/1", ExpectedCode: 200, ExpectedBody: "book 1", ExpectedHeader: map[string]string{"X-Global": "1"}},
+		{Name: "second method", Method: "PUT", Path: "/books/1", ExpectedCode: 200, ExpectedBody: "book 1"},
+		{Name: "method not allowed", Method: "DELETE", Path: "/books/1", ExpectedCode: 405},
+		{Name: "param limit", Method: "GET", Path: "/books/01234567890", ExpectedCode: 404},
+		{Name: "route middleware", Method: "POST", Path: "/authors", ExpectedCode: 200, ExpectedBody: "authors", ExpectedHeader: map[string]string{"X-Global": "1", "X-Route": "authors"}},
+		{Name: "proxy", Method: "GET", Path: "/upstream/a/b", ExpectedCode: 200, ExpectedBody: "upstream /base/a/b"},
+		{Name: "static", Method: "GET", Path: "/assets/app.css", ExpectedCode: 200, ExpectedBody: "body{}"},
+		{Name: "path limit", Method: "GET", Path: "/a/b/c/d/e/f/g/h/i", ExpectedCode: 414},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("expected code %d but got %d: %s", tc.ExpectedCode, w.Code, w.Body)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+			for key, value := range tc.ExpectedHeader {
+				if actual := w.Header().Get(key); actual != value {
+					t.Errorf("expected header %s to be %q but got %q", key, value, actual)
+				}
+			}
+		})
+	}
+
+	if url, err := mux.URL("book", "id", "2"); err != nil || url != "/books/2" {
+		t.Errorf("expected named route url /books/2 but got %q (%v)", url, err)
+	}
+	if routes := mux.RoutesTagged("books"); len(routes) != 1 {
+		t.Errorf("expected 1 tagged route but got %d", len(routes))
+	}
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	registry := Registry{
+		Handlers: map[string]Handler{
+			"ok": HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {}),
+		},
+		Middlewares: map[string]func(MiddlewareOptions) (Middleware, error){
+			"strict": func(options MiddlewareOptions) (Middleware, error) {
+				var opts struct{ Level int }
+				if err := options.Decode(&opts); err != nil {
+					return nil, err
+				}
+				return func(h Handler) Handler { return h }, nil
+			},
+		},
+	}
+
+	cases := []struct {
+		Name          string
+		Config        Config
+		ExpectedError string
+	}{
+		{
+			Name:          "unknown handler",
+			Config:        Config{Routes: []RouteConfig{{Pattern: "/", Handler: "missing"}}},
+			ExpectedError: `muxter: route /: unknown handler "missing"`,
+		},
+		{
+			Name:          "unknown middleware",
+			Config:        Config{Middlewares: []MiddlewareConfig{{Name: "missing"}}},
+			ExpectedError: `muxter: invalid middleware chain: middleware missing: unknown middleware "missing"`,
+		},
+		{
+			Name: "invalid options",
+			Config: Config{Routes: []RouteConfig{{
+				Pattern:     "/",
+				Handler:     "ok",
+				Middlewares: []MiddlewareConfig{{Name: "strict", Options: MiddlewareOptions{"verbose": true}}},
+			}}},
+			ExpectedError: `muxter: route /: middleware strict: json: unknown field "verbose"`,
+		},
+		{
+			Name:          "conflict",
+			Config:        Config{Routes: []RouteConfig{{Pattern: "/a", Handler: "ok"}, {Pattern: "/a", Handler: "ok"}}},
+			ExpectedError: "muxter: failed to register route /a",
+		},
+		{
+			Name:          "proxy target",
+			Config:        Config{Proxies: []ProxyConfig{{Pattern: "/api/", Target: "backend"}}},
+			ExpectedError: "muxter: proxy /api/: target must be an absolute url but got: backend",
+		},
+		{
+			Name:          "static pattern",
+			Config:        Config{Static: []StaticConfig{{Pattern: "/assets", Dir: "."}}},
+			ExpectedError: "muxter: static /assets: pattern must be a rooted subtree ending with a slash",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mux, err := FromConfig(tc.Config, registry)
+			if err == nil || !strings.HasPrefix(err.Error(), tc.ExpectedError) {
+				t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+			}
+			if mux != nil {
+				t.Errorf("expected no mux on error")
+			}
+		})
+	}
+
+	if _, err := ParseConfig([]byte(`{"routs": []}`)); err == nil {
+		t.Errorf("expected unknown config field to be an error")
+	}
+}
@@ -0,0 +1,173 @@
+package muxter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ErrContentLengthMismatch is returned when reading a request body whose length does not match its Content-Length
+// header through the VerifyContentLength middleware.
+var ErrContentLengthMismatch = errors.New("muxter: request body does not match its content length")
+
+// ContentLengthOptions configures the VerifyContentLength middleware.
+type ContentLengthOptions struct {
+	// VerifyResponses verifies that handlers write as many bytes as the Content-Length header they set. It is meant
+	// for development as it reports the mistakes of handlers rather than of clients.
+	VerifyResponses bool
+	// OnMismatch is called with the reason of every mismatch. It defaults to logging response mismatches with the
+	// standard logger.
+	OnMismatch func(r *http.Request, reason string)
+}
+
+// VerifyContentLength verifies that request bodies are as long as their Content-Length header. Reading a body that
+// ends early or runs past its declared length fails with ErrContentLengthMismatch, and the request is answered with
+// 400 Bad Request whatever the handler responds with, as long as the handler has not written its response before
+// reading the body. Bodies are only verified as far as handlers read them.
+func VerifyContentLength(opts ContentLengthOptions) Middleware {
+	onMismatch := opts.OnMismatch
+	if onMismatch == nil {
+		onMismatch = func(r *http.Request, reason string) {
+			log.Printf("muxter: %s %s: %s", r.Method, r.URL.Path, reason)
+		}
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			var rw *responseLengthWriter
+			if opts.VerifyResponses && r.Method != http.MethodHead {
+				rw = &responseLengthWriter{ResponseWriter: w, expected: -1}
+				w = rw
+			}
+
+			if r.Body == nil || r.Body == http.NoBody {
+				h.ServeHTTPx(w, r, c)
+			} else {
+				body := &lengthReader{ReadCloser: r.Body, expected: r.ContentLength}
+				r.Body = body
+				defer func() { r.Body = body.ReadCloser }()
+
+				cw := &contentLengthWriter{ResponseWriter: w, body: body}
+				h.ServeHTTPx(cw, r, c)
+
+				if body.err != nil && !cw.wrote {
+					if opts.OnMismatch != nil {
+						opts.OnMismatch(r, body.err.Error())
+					}
+					writeStatus(w, c, http.StatusBadRequest)
+				}
+			}
+
+			if rw != nil {
+				if reason := rw.mismatch(); reason != "" {
+					onMismatch(r, reason)
+				}
+			}
+		})
+	}
+}
+
+// lengthReader counts the bytes of a request body, failing if they do not match the expected length. A negative
+// expected length, as for chunked bodies, is not verified.
+type lengthReader struct {
+	io.ReadCloser
+	expected int64
+	read     int64
+	err      error
+}
+
+func (r *lengthReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, ErrContentLengthMismatch
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+
+	switch {
+	case r.expected < 0:
+	case r.read > r.expected:
+		r.err = fmt.Errorf("request body exceeds its content length of %d bytes", r.expected)
+	case errors.Is(err, io.ErrUnexpectedEOF), err == io.EOF && r.read < r.expected:
+		r.err = fmt.Errorf("request body ended after %d of %d bytes", r.read, r.expected)
+	}
+	if r.err != nil {
+		return n, ErrContentLengthMismatch
+	}
+	return n, err
+}
+
+// contentLengthWriter discards the response of the handler once the request body is known to be invalid, such that
+// the request is consistently answered with 400 Bad Request.
+type contentLengthWriter struct {
+	http.ResponseWriter
+	body  *lengthReader
+	wrote bool
+}
+
+func (w *contentLengthWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *contentLengthWriter) WriteHeader(code int) {
+	if w.body.err != nil && !w.wrote {
+		return
+	}
+	if code >= 200 {
+		w.wrote = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *contentLengthWriter) Write(p []byte) (int, error) {
+	if w.body.err != nil && !w.wrote {
+		return len(p), nil
+	}
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+// responseLengthWriter counts the bytes of a response against its Content-Length header.
+type responseLengthWriter struct {
+	http.ResponseWriter
+	expected int64
+	written  int64
+	started  bool
+}
+
+func (w *responseLengthWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *responseLengthWriter) WriteHeader(code int) {
+	if !w.started && code >= 200 {
+		w.started = true
+		if code != http.StatusNoContent && code != http.StatusNotModified {
+			if length, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64); err == nil {
+				w.expected = length
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseLengthWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(len(p))
+	return n, err
+}
+
+// mismatch returns why the response does not match its Content-Length header, or the empty string if it does.
+func (w *responseLengthWriter) mismatch() string {
+	switch {
+	case w.expected < 0:
+		return ""
+	case w.written > w.expected:
+		return fmt.Sprintf("handler wrote %d bytes past its content length of %d bytes", w.written-w.expected, w.expected)
+	case w.written < w.expected:
+		return fmt.Sprintf("handler wrote %d of its content length of %d bytes", w.written, w.expected)
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package muxter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyContentLength(t *testing.T) {
+	var mismatches []string
+	var readErr error
+
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		body, err := io.ReadAll(r.Body)
+		if readErr = err; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}, VerifyContentLength(ContentLengthOptions{
+		OnMismatch: func(r *http.Request, reason string) { mismatches = append(mismatches, reason) },
+	}))
+
+	testcases := []struct {
+		Name             string
+		Body             string
+		ContentLength    int64
+		ExpectedCode     int
+		ExpectedMismatch string
+	}{
+		{
+			Name:          "matching length",
+			Body:          "hello",
+			ContentLength: 5,
+			ExpectedCode:  200,
+		},
+		{
+			Name:          "chunked",
+			Body:          "hello",
+			ContentLength: -1,
+			ExpectedCode:  200,
+		},
+		{
+			Name:             "early eof",
+			Body:             "hel",
+			ContentLength:    5,
+			ExpectedCode:     400,
+			ExpectedMismatch: "request body ended after 3 of 5 bytes",
+		},
+		{
+			Name:             "body too long",
+			Body:             "hello world",
+			ContentLength:    5,
+			ExpectedCode:     400,
+			ExpectedMismatch: "request body exceeds its content length of 5 bytes",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mismatches = nil
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(tc.Body))
+			r.ContentLength = tc.ContentLength
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedMismatch == "" {
+				if len(mismatches) != 0 {
+					t.Errorf("expected no mismatches but got %q", mismatches)
+				}
+				if body := w.Body.String(); body != tc.Body {
+					t.Errorf("expected body %q but got %q", tc.Body, body)
+				}
+				return
+			}
+			if len(mismatches) != 1 || mismatches[0] != tc.ExpectedMismatch {
+				t.Errorf("expected mismatch %q but got %q", tc.ExpectedMismatch, mismatches)
+			}
+			if !errors.Is(readErr, ErrContentLengthMismatch) {
+				t.Errorf("expected handler to read ErrContentLengthMismatch but got %v", readErr)
+			}
+			if body := w.Body.String(); body != "Bad Request\n" {
+				t.Errorf("expected the handler's response to be discarded but got %q", body)
+			}
+		})
+	}
+}
+
+func TestVerifyContentLengthResponses(t *testing.T) {
+	var mismatches []string
+
+	mux := New()
+	mux.Use(VerifyContentLength(ContentLengthOptions{
+		VerifyResponses: true,
+		OnMismatch:      func(r *http.Request, reason string) { mismatches = append(mismatches, reason) },
+	}))
+
+	write := func(length, body string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Header().Set("Content-Length", length)
+			io.WriteString(w, body)
+		}
+	}
+	mux.Handle("/ok", write("5", "hello"))
+	mux.Handle("/short", write("5", "hel"))
+	mux.Handle("/long", write("2", "hello"))
+	mux.HandleFunc("/unset", func(w http.ResponseWriter, r *http.Request, c Context) { io.WriteString(w, "hello") })
+
+	testcases := []struct {
+		Method           string
+		Path             string
+		ExpectedMismatch string
+	}{
+		{Method: "GET", Path: "/ok"},
+		{Method: "GET", Path: "/unset"},
+		{Method: "HEAD", Path: "/short"},
+		{Method: "GET", Path: "/short", ExpectedMismatch: "handler wrote 3 of its content length of 5 bytes"},
+		{Method: "GET", Path: "/long", ExpectedMismatch: "handler wrote 3 bytes past its content length of 2 bytes"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Method+" "+tc.Path, func(t *testing.T) {
+			mismatches = nil
+
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if tc.ExpectedMismatch == "" && len(mismatches) != 0 {
+				t.Errorf("expected no mismatches but got %q", mismatches)
+			}
+			if tc.ExpectedMismatch != "" && (len(mismatches) != 1 || mismatches[0] != tc.ExpectedMismatch) {
+				t.Errorf("expected mismatch %q but got %q", tc.ExpectedMismatch, mismatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package muxter
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeOptions configures the VerifyContentType middleware.
+type ContentTypeOptions struct {
+	// OnMismatch is called with the reason of every response whose Content-Type is not one of the media types
+	// declared by its route. It defaults to panicking once the handler returned, such that the tests serving the
+	// route fail.
+	OnMismatch func(r *http.Request, reason string)
+}
+
+// VerifyContentType verifies that the successful responses of the routes declaring the media types they produce,
+// with the Produces registration option, have a Content-Type among them, such that the documentation generated from
+// the route table does not drift from the behaviour of the handlers. It is meant for development and tests, as it
+// reports the mistakes of handlers rather than of clients. Error responses, responses without a body and the routes
+// that declare no media types are not verified.
+//
+//	mux.Use(muxter.VerifyContentType(muxter.ContentTypeOptions{
+//		OnMismatch: func(r *http.Request, reason string) { t.Error(reason) },
+//	}))
+func VerifyContentType(opts ContentTypeOptions) Middleware {
+	onMismatch := opts.OnMismatch
+	if onMismatch == nil {
+		onMismatch = func(r *http.Request, reason string) {
+			panic(fmt.Sprintf("muxter: %s %s: %s", r.Method, r.URL.Path, reason))
+		}
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			route := c.Route()
+			if route == nil || len(route.Produces) == 0 {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			cw := &contentTypeWriter{ResponseWriter: w, produces: route.Produces}
+			h.ServeHTTPx(cw, r, c)
+
+			if cw.mismatch != "" {
+				onMismatch(r, cw.mismatch)
+			}
+		})
+	}
+}
+
+// contentTypeWriter verifies the Content-Type of the response as its headers are written.
+type contentTypeWriter struct {
+	http.ResponseWriter
+	produces []string
+	code     int
+	// contentType is the Content-Type of the response when its headers were written.
+	contentType string
+	checked     bool
+	mismatch    string
+}
+
+func (w *contentTypeWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *contentTypeWriter) WriteHeader(code int) {
+	if w.code == 0 && code >= 200 {
+		w.code = code
+		w.contentType = w.Header().Get("Content-Type")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *contentTypeWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.checked && len(p) > 0 {
+		w.checked = true
+		w.check()
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *contentTypeWriter) check() {
+	if w.code < 200 || w.code >= 300 {
+		return
+	}
+	declared := strings.Join(w.produces, ", ")
+	if w.contentType == "" {
+		w.mismatch = fmt.Sprintf("handler did not set the Content-Type of its response, the route produces %s", declared)
+		return
+	}
+	for _, mediaType := range w.produces {
+		if mediaTypeMatches(mediaType, w.contentType) {
+			return
+		}
+	}
+	w.mismatch = fmt.Sprintf("handler responded with Content-Type %s, the route produces %s", w.contentType, declared)
+}
+
+// mediaTypeMatches reports whether the Content-Type is the declared media type, or within it if it is a media range
+// such as image/*. Parameters such as the charset are ignored.
+func mediaTypeMatches(declared, contentType string) bool {
+	declared, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return false
+	}
+	actual, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case declared == "*/*" || declared == actual:
+		return true
+	case strings.HasSuffix(declared, "/*"):
+		return strings.HasPrefix(actual, declared[:len(declared)-1])
+	default:
+		return false
+	}
+}
@@ -0,0 +1,122 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyContentType(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Produces []string
+		Handler  HandlerFunc
+		Expected string
+	}{
+		{
+			Name:     "declared",
+			Produces: []string{"application/json"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				io.WriteString(w, "{}")
+			},
+		},
+		{
+			Name:     "media range",
+			Produces: []string{"image/*"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Header().Set("Content-Type", "image/png")
+				io.WriteString(w, "png")
+			},
+		},
+		{
+			Name:     "drifted",
+			Produces: []string{"application/json", "text/csv"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Header().Set("Content-Type", "text/html")
+				io.WriteString(w, "<p>")
+			},
+			Expected: "handler responded with Content-Type text/html, the route produces application/json, text/csv",
+		},
+		{
+			Name:     "sniffed",
+			Produces: []string{"application/json"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				io.WriteString(w, "{}")
+			},
+			Expected: "handler did not set the Content-Type of its response, the route produces application/json",
+		},
+		{
+			Name:     "content-type set after headers",
+			Produces: []string{"application/json"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.WriteHeader(http.StatusCreated)
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, "{}")
+			},
+			Expected: "handler did not set the Content-Type of its response, the route produces application/json",
+		},
+		{
+			Name:     "error response",
+			Produces: []string{"application/json"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+		},
+		{
+			Name:     "no body",
+			Produces: []string{"application/json"},
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			Name: "undeclared",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				io.WriteString(w, "anything")
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var reasons []string
+
+			mux := New()
+			mux.Use(VerifyContentType(ContentTypeOptions{
+				OnMismatch: func(r *http.Request, reason string) { reasons = append(reasons, reason) },
+			}))
+			if tc.Produces != nil {
+				mux.Handle("/reports", tc.Handler, Produces(tc.Produces...))
+			} else {
+				mux.Handle("/reports", tc.Handler)
+			}
+
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports", nil))
+
+			if tc.Expected == "" && len(reasons) != 0 {
+				t.Errorf("expected no mismatch but got %q", reasons)
+			}
+			if tc.Expected != "" && (len(reasons) != 1 || reasons[0] != tc.Expected) {
+				t.Errorf("expected mismatch %q but got %q", tc.Expected, reasons)
+			}
+		})
+	}
+}
+
+func TestVerifyContentTypePanics(t *testing.T) {
+	mux := New()
+	mux.Use(VerifyContentType(ContentTypeOptions{}))
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request, c Context) {
+		io.WriteString(w, "<p>")
+	}, Produces("application/json"))
+
+	defer func() {
+		expected := "muxter: GET /reports: handler did not set the Content-Type of its response, the route produces application/json"
+		if recovered := recover(); recovered != expected {
+			t.Errorf("expected panic %q but got %v", expected, recovered)
+		}
+	}()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports", nil))
+}
@@ -0,0 +1,149 @@
+package muxter
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ContractOptions configures Mux.CheckContract.
+type ContractOptions struct {
+	// Params are the values of the path params of the sample requests by key. Params without a value are "1", so
+	// routes whose regexp params do not match "1" need one.
+	Params map[string]string
+	// Authenticate adds credentials to the sample requests of routes requiring scopes such that their successful
+	// responses are verified. Without it only the rejection of anonymous requests is verified for such routes.
+	Authenticate func(r *http.Request, route RouteInfo)
+}
+
+// ContractViolation is a route not honoring the contract declared by its metadata.
+type ContractViolation struct {
+	// Route is the pattern of the route.
+	Route string
+	// Request is the method and target of the sample request that revealed the violation.
+	Request string
+	// Problem describes the violation.
+	Problem string
+}
+
+func (v ContractViolation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Route, v.Request, v.Problem)
+}
+
+// CheckContract serves sample requests through the mux to verify that its routes honor the contract declared by
+// their RouteInfo, and returns the violations found:
+//
+//   - routes with method guards answer other methods with 405 Method Not Allowed,
+//   - routes requiring scopes do not answer anonymous requests successfully,
+//   - routes do not answer GET requests with server errors or panics,
+//   - successful responses have one of the media types the route produces.
+//
+// Only GET requests are expected to reach handlers, such that it is safe to run against a mux backed by real
+// storage. Routes of host patterns are skipped. It is meant to be run as a test:
+//
+//	func TestContract(t *testing.T) {
+//		for _, violation := range newMux().CheckContract(context.Background(), muxter.ContractOptions{}) {
+//			t.Error(violation)
+//		}
+//	}
+func (m *Mux) CheckContract(ctx context.Context, opts ContractOptions) []ContractViolation {
+	var violations []ContractViolation
+
+	for _, route := range m.Routes() {
+		if route.Disabled || isHostPattern(route.Pattern) {
+			continue
+		}
+
+		target, err := expandPattern(route.Pattern, func(key string) (string, bool) {
+			if value, ok := opts.Params[key]; ok {
+				return value, true
+			}
+			return "1", true
+		})
+		if err != nil {
+			violations = append(violations, ContractViolation{Route: route.Pattern, Problem: fmt.Sprintf("cannot build sample request: %v", err)})
+			continue
+		}
+
+		check := func(method string, authenticate bool, verify func(resp *http.Response) string) {
+			request := method + " " + target
+			r, err := http.NewRequestWithContext(ctx, method, target, http.NoBody)
+			if err != nil {
+				violations = append(violations, ContractViolation{Route: route.Pattern, Request: request, Problem: err.Error()})
+				return
+			}
+			r.RequestURI = r.URL.RequestURI()
+			if authenticate && opts.Authenticate != nil {
+				opts.Authenticate(r, route)
+			}
+
+			resp, err := m.dispatch(r)
+			if err != nil {
+				violations = append(violations, ContractViolation{Route: route.Pattern, Request: request, Problem: err.Error()})
+				return
+			}
+			if problem := verify(resp); problem != "" {
+				violations = append(violations, ContractViolation{Route: route.Pattern, Request: request, Problem: problem})
+			}
+		}
+
+		if route.Methods != nil {
+			if method := disallowedMethod(route.Methods); method != "" {
+				check(method, true, func(resp *http.Response) string {
+					if resp.StatusCode != http.StatusMethodNotAllowed {
+						return fmt.Sprintf("expected 405 for a method the route does not accept but got %d", resp.StatusCode)
+					}
+					return ""
+				})
+			}
+		}
+
+		if !acceptsMethod(route.Methods, http.MethodGet) {
+			continue
+		}
+
+		if len(route.Scopes) > 0 {
+			check(http.MethodGet, false, func(resp *http.Response) string {
+				if resp.StatusCode < 300 {
+					return fmt.Sprintf("route requires scopes but answered an anonymous request with %d", resp.StatusCode)
+				}
+				return ""
+			})
+			if opts.Authenticate == nil {
+				continue
+			}
+		}
+
+		check(http.MethodGet, true, func(resp *http.Response) string {
+			if resp.StatusCode >= 500 {
+				return fmt.Sprintf("server error %d", resp.StatusCode)
+			}
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 || len(route.Produces) == 0 || resp.ContentLength == 0 {
+				return ""
+			}
+			mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+			if err != nil || !containsFold(route.Produces, mediaType) {
+				return fmt.Sprintf("content type %q is not one of %q", resp.Header.Get("Content-Type"), route.Produces)
+			}
+			return ""
+		})
+	}
+
+	return violations
+}
+
+// disallowedMethod returns a method the route does not accept, preferring TRACE which handlers are the least likely
+// to act upon.
+func disallowedMethod(methods []string) string {
+	for _, method := range []string{http.MethodTrace, http.MethodPatch, http.MethodDelete} {
+		if !acceptsMethod(methods, method) {
+			return method
+		}
+	}
+	return ""
+}
+
+func acceptsMethod(methods []string, method string) bool {
+	return methods == nil || containsFold(methods, method)
+}
@@ -0,0 +1,65 @@
+package muxter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCheckContract(t *testing.T) {
+	authenticate := func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if r.Header.Get("Authorization") != "" {
+				c.editExtras().identity = &Identity{Subject: "tester", Scopes: []string{"admin"}}
+			}
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+	json := func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{}`)
+	}
+
+	mux := New()
+	mux.Use(authenticate)
+	mux.GetFunc("/books/:id", json, Produces("application/json"))
+	mux.GetFunc("/reports", func(w http.ResponseWriter, r *http.Request, c Context) {
+		io.WriteString(w, "plain text")
+	}, Produces("application/json"))
+	mux.HandleFunc("/admin", json, RequireScopes("admin"), Produces("text/html"))
+	mux.HandleFunc("/unguarded", json, registrationOption(func(ri *RouteInfo) { ri.Methods = []string{"GET"} }))
+	mux.PostFunc("/orders", func(w http.ResponseWriter, r *http.Request, c Context) {
+		t.Errorf("expected POST route not to be served")
+	})
+	mux.GetFunc("/panics", func(w http.ResponseWriter, r *http.Request, c Context) { panic("boom") })
+	mux.GetFunc("/users/#id:[a-z]+", json)
+
+	violations := mux.CheckContract(context.Background(), ContractOptions{
+		Params:       map[string]string{"id": "abc"},
+		Authenticate: func(r *http.Request, route RouteInfo) { r.Header.Set("Authorization", "Bearer test") },
+	})
+
+	expected := []ContractViolation{
+		{Route: "/admin", Request: "GET /admin", Problem: `content type "application/json" is not one of ["text/html"]`},
+		{Route: "/panics", Request: "GET /panics", Problem: "muxter: handler panicked: boom"},
+		{Route: "/reports", Request: "GET /reports", Problem: `content type "text/plain; charset=utf-8" is not one of ["application/json"]`},
+		{Route: "/unguarded", Request: "TRACE /unguarded", Problem: "expected 405 for a method the route does not accept but got 200"},
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("expected violations:\n%v\nbut got:\n%v", expected, violations)
+	}
+
+	mux = New()
+	mux.HandleFunc("/leaky", json, registrationOption(func(ri *RouteInfo) { ri.Scopes = []string{"admin"} }))
+
+	violations = mux.CheckContract(context.Background(), ContractOptions{})
+
+	expected = []ContractViolation{
+		{Route: "/leaky", Request: "GET /leaky", Problem: "route requires scopes but answered an anonymous request with 200"},
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("expected violations:\n%v\nbut got:\n%v", expected, violations)
+	}
+}
@@ -0,0 +1,114 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// RegisterController registers the methods of controller as routes. Routes are declared with route tags on fields of
+// the controller, conventionally blank fields, whose value is the method name followed by the route pattern
+// optionally preceded by an HTTP method. Other tags of the fields, such as json tags, are ignored:
+//
+//	type Users struct {
+//		_ struct{} `route:"GetUserByID /users/:id"`
+//		_ struct{} `route:"ListUsers GET /users"`
+//		_ struct{} `route:"Purge POST /users/purge"`
+//	}
+//
+// When the HTTP method is omitted it is derived from the method name's first word (Get, Head, Post, Put, Patch or
+// Delete), such that GetUser is a GET route but Getaway is not, and if the name starts with no such word the route
+// accepts any method. Methods must either have the signature of a HandlerFunc or of a standard http.HandlerFunc. The
+// middlewares are applied to every route of the controller.
+//
+// RegisterController panics if a route tag is malformed or refers to a method that does not exist or has an
+// unsupported signature.
+func (m *Mux) RegisterController(controller interface{}, middlewares ...Middleware) {
+	value := reflect.ValueOf(controller)
+
+	structType := value.Type()
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("muxter: controller must be a struct or a pointer to a struct but got %T", controller))
+	}
+
+	var routes []controllerRoute
+	for i := 0; i < structType.NumField(); i++ {
+		tag, ok := structType.Field(i).Tag.Lookup("route")
+		if !ok {
+			continue
+		}
+		route, ok := parseControllerRoute(tag)
+		if !ok {
+			panic(fmt.Sprintf("muxter: controller %s has a malformed route tag %q", structType.Name(), tag))
+		}
+		routes = append(routes, route)
+	}
+
+	for _, route := range routes {
+		if !value.MethodByName(route.name).IsValid() {
+			panic(fmt.Sprintf("muxter: controller %s declares a route for unknown method %s", structType.Name(), route.name))
+		}
+	}
+
+	for _, route := range routes {
+		handler := controllerHandler(value.MethodByName(route.name))
+		if handler == nil {
+			panic(fmt.Sprintf("muxter: controller method %s.%s does not have a handler signature", structType.Name(), route.name))
+		}
+
+		if route.method == "" {
+			m.Handle(route.pattern, handler, middlewares...)
+			continue
+		}
+		m.Handle(route.pattern, handler, append([]Middleware{m.verb(route.method)}, middlewares...)...)
+	}
+}
+
+func controllerHandler(method reflect.Value) Handler {
+	switch fn := method.Interface().(type) {
+	case func(http.ResponseWriter, *http.Request, Context):
+		return HandlerFunc(fn)
+	case func(http.ResponseWriter, *http.Request):
+		return Adaptor(http.HandlerFunc(fn))
+	default:
+		return nil
+	}
+}
+
+// controllerRoute is a route declared by the route tag of a controller.
+type controllerRoute struct {
+	name    string
+	method  string
+	pattern string
+}
+
+// parseControllerRoute parses a route tag of the form "Name [METHOD] pattern".
+func parseControllerRoute(tag string) (controllerRoute, bool) {
+	fields := strings.Fields(tag)
+	switch len(fields) {
+	case 2:
+		return controllerRoute{name: fields[0], method: nameVerb(fields[0]), pattern: fields[1]}, true
+	case 3:
+		return controllerRoute{name: fields[0], method: strings.ToUpper(fields[1]), pattern: fields[2]}, true
+	default:
+		return controllerRoute{}, false
+	}
+}
+
+// nameVerb returns the HTTP method named by the first word of the method name, or the empty string if it names none.
+// The verb must be followed by the end of the name or an upper case letter starting the next word.
+func nameVerb(name string) string {
+	for _, verb := range []string{"Get", "Head", "Post", "Put", "Patch", "Delete"} {
+		if !strings.HasPrefix(name, verb) {
+			continue
+		}
+		if rest := name[len(verb):]; rest == "" || rest[0] >= 'A' && rest[0] <= 'Z' {
+			return strings.ToUpper(verb)
+		}
+	}
+	return ""
+}
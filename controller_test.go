@@ -0,0 +1,129 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type booksController struct {
+	Name string   `json:"name"`
+	_    struct{} `route:"GetBook /books/:id"`
+	_    struct{} `route:"ListBooks GET /books"`
+	_    struct{} `route:"Purge POST /books/purge"`
+	_    struct{} `route:"Any /any"`
+	_    struct{} `route:"Std /std/:id"`
+	_    struct{} `route:"Getaway /getaway"`
+	_    struct{} `route:"Deleted /deleted"`
+}
+
+func (booksController) GetBook(w http.ResponseWriter, r *http.Request, c Context) {
+	io.WriteString(w, "book "+c.Param("id"))
+}
+
+func (booksController) ListBooks(w http.ResponseWriter, r *http.Request, c Context) {
+	io.WriteString(w, "books")
+}
+
+func (*booksController) Purge(w http.ResponseWriter, r *http.Request, c Context) {
+	io.WriteString(w, "purged")
+}
+
+func (booksController) Any(w http.ResponseWriter, r *http.Request, c Context) {
+	io.WriteString(w, r.Method)
+}
+
+func (booksController) Std(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "std "+Param(r, "id"))
+}
+
+func (booksController) Getaway(w http.ResponseWriter, r *http.Request, c Context) {
+	io.WriteString(w, "getaway "+r.Method)
+}
+
+func (booksController) Deleted(w http.ResponseWriter, r *http.Request, c Context) {
+	io.WriteString(w, "deleted "+r.Method)
+}
+
+func (booksController) Helper() {}
+
+func TestRegisterController(t *testing.T) {
+	mux := New()
+	mux.RegisterController(&booksController{})
+
+	testcases := []struct {
+		Method       string
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Method: "GET", Path: "/books/1", ExpectedCode: 200, ExpectedBody: "book 1"},
+		{Method: "POST", Path: "/books/1", ExpectedCode: 405, ExpectedBody: "Method Not Allowed\n"},
+		{Method: "GET", Path: "/books", ExpectedCode: 200, ExpectedBody: "books"},
+		{Method: "POST", Path: "/books/purge", ExpectedCode: 200, ExpectedBody: "purged"},
+		{Method: "GET", Path: "/books/purge", ExpectedCode: 405, ExpectedBody: "Method Not Allowed\n"},
+		{Method: "DELETE", Path: "/any", ExpectedCode: 200, ExpectedBody: "DELETE"},
+		{Method: "GET", Path: "/std/2", ExpectedCode: 200, ExpectedBody: "std 2"},
+		{Method: "POST", Path: "/getaway", ExpectedCode: 200, ExpectedBody: "getaway POST"},
+		{Method: "GET", Path: "/deleted", ExpectedCode: 200, ExpectedBody: "deleted GET"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Method+" "+tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+		})
+	}
+
+	t.Run("unknown method", func(t *testing.T) {
+		defer func() {
+			expected := "muxter: controller badController declares a route for unknown method Missing"
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected panic %q but got %q", expected, actual)
+			}
+		}()
+
+		type badController struct {
+			_ struct{} `route:"Missing /missing"`
+		}
+		New().RegisterController(badController{})
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		defer func() {
+			expected := "muxter: controller method helperController.Helper does not have a handler signature"
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected panic %q but got %q", expected, actual)
+			}
+		}()
+
+		type helperController struct {
+			booksController
+			_ struct{} `route:"Helper /helper"`
+		}
+		New().RegisterController(helperController{})
+	})
+
+	t.Run("malformed tag", func(t *testing.T) {
+		defer func() {
+			expected := `muxter: controller malformedController has a malformed route tag "GetBook"`
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected panic %q but got %q", expected, actual)
+			}
+		}()
+
+		type malformedController struct {
+			booksController
+			_ struct{} `route:"GetBook"`
+		}
+		New().RegisterController(malformedController{})
+	})
+}
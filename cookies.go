@@ -0,0 +1,205 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CookieLimits configures the GuardCookies middleware. Zero values disable a limit.
+type CookieLimits struct {
+	// MaxCount is the maximum number of cookies a request may send.
+	MaxCount int
+	// MaxBytes is the maximum total size of the request's Cookie headers.
+	MaxBytes int
+	// OnReject is called with the reason of every rejected request, for example to record a metric.
+	OnReject func(r *http.Request, reason string)
+}
+
+// GuardCookies rejects requests whose cookies exceed the limits with 431 Request Header Fields Too Large, and
+// enforces the rules of cookie name prefixes on the cookies issued by the handler: cookies prefixed with __Secure-
+// are made Secure, and cookies prefixed with __Host- are made Secure with a Path of / and no Domain. Browsers
+// silently ignore prefixed cookies that break these rules.
+func GuardCookies(limits CookieLimits) Middleware {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if reason := checkCookies(r, limits); reason != "" {
+				if limits.OnReject != nil {
+					limits.OnReject(r, reason)
+				}
+				writeStatus(w, c, http.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+
+			hw := &headerWriter{ResponseWriter: w, rewrite: rewriteCookies(enforceCookiePrefix)}
+			h.ServeHTTPx(hw, r, c)
+			hw.rewriteHeader()
+		})
+	}
+}
+
+func checkCookies(r *http.Request, limits CookieLimits) string {
+	if limits.MaxBytes > 0 {
+		var size int
+		for _, value := range r.Header.Values("Cookie") {
+			size += len(value)
+		}
+		if size > limits.MaxBytes {
+			return fmt.Sprintf("cookies exceed %d bytes", limits.MaxBytes)
+		}
+	}
+	if limits.MaxCount > 0 && len(r.Cookies()) > limits.MaxCount {
+		return fmt.Sprintf("more than %d cookies", limits.MaxCount)
+	}
+	return ""
+}
+
+func enforceCookiePrefix(cookie *setCookie) {
+	switch {
+	case strings.HasPrefix(cookie.name(), "__Host-"):
+		cookie.set("Secure", "")
+		cookie.set("Path", "/")
+		cookie.del("Domain")
+	case strings.HasPrefix(cookie.name(), "__Secure-"):
+		cookie.set("Secure", "")
+	}
+}
+
+// rewriteCookies returns a header rewrite applying fn to the Set-Cookie headers.
+func rewriteCookies(fn func(*setCookie)) func(http.Header) {
+	return func(header http.Header) {
+		cookies := header.Values("Set-Cookie")
+		if len(cookies) == 0 {
+			return
+		}
+
+		rewritten := make([]string, len(cookies))
+		for i, line := range cookies {
+			cookie := parseSetCookie(line)
+			fn(&cookie)
+			rewritten[i] = cookie.String()
+		}
+		header["Set-Cookie"] = rewritten
+	}
+}
+
+// setCookie is a Set-Cookie header value split into its name=value pair and attributes, preserving their order.
+type setCookie struct {
+	pair  string
+	attrs []cookieAttr
+}
+
+type cookieAttr struct {
+	key   string
+	value string
+}
+
+func parseSetCookie(line string) setCookie {
+	parts := strings.Split(line, ";")
+	cookie := setCookie{pair: strings.TrimSpace(parts[0])}
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		cookie.attrs = append(cookie.attrs, cookieAttr{key: strings.TrimSpace(key), value: strings.TrimSpace(value)})
+	}
+	return cookie
+}
+
+func (c setCookie) name() string {
+	name, _, _ := strings.Cut(c.pair, "=")
+	return strings.TrimSpace(name)
+}
+
+func (c setCookie) get(key string) (string, bool) {
+	for _, attr := range c.attrs {
+		if strings.EqualFold(attr.key, key) {
+			return attr.value, true
+		}
+	}
+	return "", false
+}
+
+func (c setCookie) has(key string) bool {
+	_, ok := c.get(key)
+	return ok
+}
+
+// set sets the attribute, where an empty value is used for flag attributes such as Secure.
+func (c *setCookie) set(key, value string) {
+	for i, attr := range c.attrs {
+		if strings.EqualFold(attr.key, key) {
+			c.attrs[i].value = value
+			return
+		}
+	}
+	c.attrs = append(c.attrs, cookieAttr{key: key, value: value})
+}
+
+func (c *setCookie) del(key string) {
+	attrs := c.attrs[:0]
+	for _, attr := range c.attrs {
+		if !strings.EqualFold(attr.key, key) {
+			attrs = append(attrs, attr)
+		}
+	}
+	c.attrs = attrs
+}
+
+func (c setCookie) String() string {
+	var b strings.Builder
+	b.WriteString(c.pair)
+	for _, attr := range c.attrs {
+		b.WriteString("; ")
+		b.WriteString(attr.key)
+		if attr.value != "" {
+			b.WriteByte('=')
+			b.WriteString(attr.value)
+		}
+	}
+	return b.String()
+}
+
+// CookiePolicy is the mux-wide policy for cookies applied by the CookieDefaults option.
+type CookiePolicy struct {
+	// Secure adds the Secure attribute to cookies.
+	Secure bool
+	// HttpOnly adds the HttpOnly attribute to cookies.
+	HttpOnly bool
+	// SameSite is the SameSite attribute of cookies that do not set one.
+	SameSite http.SameSite
+}
+
+// CookieDefaults upgrades the attributes of every cookie issued through the mux according to the policy, including
+// cookies issued by third party handlers mounted on the mux. Attributes are only ever added, so a cookie that sets
+// its own SameSite attribute keeps it. Cookies with SameSite=None are always made Secure as browsers require it.
+func CookieDefaults(policy CookiePolicy) MuxOption {
+	var sameSite string
+	switch policy.SameSite {
+	case http.SameSiteLaxMode:
+		sameSite = "Lax"
+	case http.SameSiteStrictMode:
+		sameSite = "Strict"
+	case http.SameSiteNoneMode:
+		sameSite = "None"
+	}
+
+	return func(m *Mux) {
+		m.rewriteHeaders = append(m.rewriteHeaders, rewriteCookies(func(cookie *setCookie) {
+			if policy.Secure {
+				cookie.set("Secure", "")
+			}
+			if policy.HttpOnly {
+				cookie.set("HttpOnly", "")
+			}
+			if sameSite != "" && !cookie.has("SameSite") {
+				cookie.set("SameSite", sameSite)
+			}
+			if value, _ := cookie.get("SameSite"); strings.EqualFold(value, "None") {
+				cookie.set("Secure", "")
+			}
+		}))
+	}
+}
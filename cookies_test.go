@@ -0,0 +1,92 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGuardCookies(t *testing.T) {
+	var rejections []string
+
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Add("Set-Cookie", "__Host-session=abc; Domain=example.com; Path=/app; HttpOnly")
+		w.Header().Add("Set-Cookie", "__Secure-id=1; path=/")
+		w.Header().Add("Set-Cookie", "plain=1")
+		w.Write([]byte("ok"))
+	}, GuardCookies(CookieLimits{
+		MaxCount: 2,
+		MaxBytes: 64,
+		OnReject: func(r *http.Request, reason string) { rejections = append(rejections, reason) },
+	}))
+
+	t.Run("prefixes enforced", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Cookie", "a=1; b=2")
+
+		mux.ServeHTTP(w, r)
+
+		expected := []string{
+			"__Host-session=abc; Path=/; HttpOnly; Secure",
+			"__Secure-id=1; path=/; Secure",
+			"plain=1",
+		}
+		if actual := w.Header().Values("Set-Cookie"); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected cookies %q but got %q", expected, actual)
+		}
+	})
+
+	testcases := []struct {
+		Name           string
+		Cookie         string
+		ExpectedReason string
+	}{
+		{Name: "too many cookies", Cookie: "a=1; b=2; c=3", ExpectedReason: "more than 2 cookies"},
+		{Name: "cookies too large", Cookie: "a=" + strings.Repeat("x", 64), ExpectedReason: "cookies exceed 64 bytes"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rejections = nil
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Cookie", tc.Cookie)
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != 431 {
+				t.Errorf("expected code 431 but got %d", w.Code)
+			}
+			if len(rejections) != 1 || rejections[0] != tc.ExpectedReason {
+				t.Errorf("expected rejection %q but got %q", tc.ExpectedReason, rejections)
+			}
+		})
+	}
+}
+
+func TestCookieDefaults(t *testing.T) {
+	child := New()
+	child.StandardHandle("/third-party", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "tracking", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "strict", Value: "1", SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "cross", Value: "1", SameSite: http.SameSiteNoneMode})
+	}))
+
+	mux := New(CookieDefaults(CookiePolicy{HttpOnly: true, SameSite: http.SameSiteLaxMode}))
+	mux.Handle("/child/", StripDepth(1, child))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/child/third-party", nil))
+
+	expected := []string{
+		"tracking=1; HttpOnly; SameSite=Lax",
+		"strict=1; SameSite=Strict; HttpOnly",
+		"cross=1; SameSite=None; HttpOnly; Secure",
+	}
+	if actual := w.Header().Values("Set-Cookie"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected cookies %q but got %q", expected, actual)
+	}
+}
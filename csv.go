@@ -0,0 +1,146 @@
+package muxter
+
+import (
+	"encoding/csv"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+type csvOptions struct {
+	filename       string
+	bom            bool
+	flushEvery     int
+	escapeFormulas bool
+}
+
+type CSVOption func(*csvOptions)
+
+// CSVFilename is an option for NewCSVWriter that serves the CSV as an attachment with the filename.
+func CSVFilename(name string) CSVOption {
+	return func(co *csvOptions) {
+		co.filename = name
+	}
+}
+
+// CSVFlushEvery is an option for NewCSVWriter that flushes the response to the client every n records.
+// The default is 100.
+func CSVFlushEvery(n int) CSVOption {
+	return func(co *csvOptions) {
+		co.flushEvery = n
+	}
+}
+
+// CSVWithBOM is an option for NewCSVWriter that starts the response with a UTF-8 byte order mark, which Excel
+// requires to decode non ASCII text correctly.
+var CSVWithBOM CSVOption = func(co *csvOptions) {
+	co.bom = true
+}
+
+// CSVEscapeFormulas is an option for NewCSVWriter that prefixes fields starting with =, +, -, @, a tab or a carriage
+// return with a single quote so that spreadsheet applications do not evaluate them as formulas.
+var CSVEscapeFormulas CSVOption = func(co *csvOptions) {
+	co.escapeFormulas = true
+}
+
+// CSVWriter streams CSV records as the response body. Response headers are written with the first record.
+type CSVWriter struct {
+	w       http.ResponseWriter
+	csv     *csv.Writer
+	options csvOptions
+	records int
+	started bool
+}
+
+// NewCSVWriter returns a CSVWriter writing to w.
+//
+//	cw := muxter.NewCSVWriter(w, muxter.CSVFilename("users.csv"), muxter.CSVWithBOM)
+//	for rows.Next() {
+//		record, err := scan(rows)
+//		if err != nil {
+//			cw.Fail(err)
+//			return
+//		}
+//		if err := cw.Write(record); err != nil {
+//			return
+//		}
+//	}
+//	cw.Flush()
+func NewCSVWriter(w http.ResponseWriter, opts ...CSVOption) *CSVWriter {
+	options := csvOptions{flushEvery: 100}
+	for _, apply := range opts {
+		apply(&options)
+	}
+	return &CSVWriter{w: w, csv: csv.NewWriter(w), options: options}
+}
+
+func (cw *CSVWriter) start() {
+	if cw.started {
+		return
+	}
+	cw.started = true
+
+	cw.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if cw.options.filename != "" {
+		cw.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": cw.options.filename}))
+	}
+	cw.w.WriteHeader(http.StatusOK)
+
+	if cw.options.bom {
+		cw.w.Write([]byte("\ufeff"))
+	}
+}
+
+// Write writes a record, flushing the response every CSVFlushEvery records. An error means the client can no
+// longer be written to and the handler should stop producing records.
+func (cw *CSVWriter) Write(record []string) error {
+	cw.start()
+
+	if cw.options.escapeFormulas {
+		escaped := make([]string, len(record))
+		for i, field := range record {
+			if field != "" && strings.IndexByte("=+-@\t\r", field[0]) != -1 {
+				field = "'" + field
+			}
+			escaped[i] = field
+		}
+		record = escaped
+	}
+
+	if err := cw.csv.Write(record); err != nil {
+		return err
+	}
+
+	cw.records++
+	if cw.options.flushEvery > 0 && cw.records%cw.options.flushEvery == 0 {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush writes buffered records to the client. It must be called once all records have been written.
+func (cw *CSVWriter) Flush() error {
+	cw.start()
+	cw.csv.Flush()
+	if err := cw.csv.Error(); err != nil {
+		return err
+	}
+	if flusher, ok := cw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Fail reports an error producing a record, which is logged. If nothing was sent yet an internal server error is
+// served instead of the CSV. Otherwise the status has already been sent, so Fail aborts the response by panicking
+// with http.ErrAbortHandler, which makes the client see a failed download rather than a truncated but seemingly
+// complete file.
+func (cw *CSVWriter) Fail(err error) {
+	log.Printf("muxter: csv response failed after %d records: %v", cw.records, err)
+	if !cw.started {
+		http.Error(cw.w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,71 @@
+package muxter
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	t.Run("stream", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		cw := NewCSVWriter(w, CSVFilename("café.csv"), CSVWithBOM, CSVEscapeFormulas, CSVFlushEvery(1))
+		cw.Write([]string{"name", "formula"})
+		cw.Write([]string{"bob, jr", "=SUM(A1:A2)"})
+		cw.Write([]string{"\t=1+1", "\r=1+1"})
+
+		if !w.Flushed {
+			t.Errorf("expected response to be flushed")
+		}
+		if err := cw.Flush(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if contentType := w.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+			t.Errorf("unexpected content type %q", contentType)
+		}
+		if disposition := w.Header().Get("Content-Disposition"); disposition != "attachment; filename*=utf-8''caf%C3%A9.csv" {
+			t.Errorf("unexpected content disposition %q", disposition)
+		}
+
+		expected := "\ufeffname,formula\n\"bob, jr\",'=SUM(A1:A2)\n'\t=1+1,\"'\r=1+1\"\n"
+		if body := w.Body.String(); body != expected {
+			t.Errorf("expected body %q but got %q", expected, body)
+		}
+	})
+
+	t.Run("fail before first record", func(t *testing.T) {
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		defer log.SetOutput(os.Stderr)
+
+		w := httptest.NewRecorder()
+
+		NewCSVWriter(w).Fail(errors.New("query failed"))
+
+		if w.Code != 500 {
+			t.Errorf("expected code 500 but got %d", w.Code)
+		}
+		if !strings.Contains(logs.String(), "query failed") {
+			t.Errorf("expected the error to be logged but got %q", logs.String())
+		}
+	})
+
+	t.Run("fail after first record", func(t *testing.T) {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("expected response to be aborted but got %v", recovered)
+			}
+		}()
+
+		cw := NewCSVWriter(httptest.NewRecorder())
+		cw.Write([]string{"a"})
+		cw.Fail(errors.New("query failed"))
+	})
+}
@@ -0,0 +1,84 @@
+package muxter
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// Debug enables the development checks of the mux, which trade performance for diagnostics:
+//
+//   - requests handed to handlers by StripDepth are poisoned once they return, as with the PoisonAfterUse option;
+//   - the state of requests is not recycled once they are served, such that responses written after their request
+//     was served, as by a goroutine started by the handler, panic instead of corrupting the response of another
+//     request;
+//   - the default not found and method not allowed responses to browsers are HTML pages listing the routes of the
+//     mux, and the pattern and params the request partially matched;
+//   - the call sites of routes are recorded, as with the RecordCallSites option.
+//
+// Debug is enabled by default in binaries built with the muxterdebug tag, such that development builds get the checks
+// and production builds pay nothing for them:
+//
+//	go run -tags muxterdebug ./cmd/server
+func Debug(value bool) MuxOption {
+	return func(m *Mux) {
+		m.debug = value
+	}
+}
+
+// servedWriter is the response writer of requests that were served in debug mode, which panics when it is used.
+type servedWriter struct {
+	request string
+}
+
+func (w servedWriter) Header() http.Header {
+	panic("muxter: " + w.request + ": response headers used after its request was served")
+}
+
+func (w servedWriter) Write([]byte) (int, error) {
+	panic("muxter: " + w.request + ": response written after its request was served")
+}
+
+func (w servedWriter) WriteHeader(int) {
+	panic("muxter: " + w.request + ": response written after its request was served")
+}
+
+// acceptsHTML reports whether the request is made by a browser navigating to the URL.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+var debugPage = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p><code>{{.Method}} {{.Path}}</code></p>
+{{- if .Partial}}
+<p>Partially matched <code>{{.Partial}}</code>{{range $key, $value := .Params}} <code>{{$key}}={{$value}}</code>{{end}}</p>
+{{- end}}
+<table>
+<tr><th>Methods</th><th>Pattern</th><th>Name</th><th>Description</th></tr>
+{{- range .Routes}}
+<tr><td>{{if .Methods}}{{range $i, $m := .Methods}}{{if $i}}, {{end}}{{$m}}{{end}}{{else}}*{{end}}</td><td><code>{{.Pattern}}</code></td><td>{{.Name}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// writeDebugPage answers the request with the status as an HTML page listing the routes of the mux in debug mode.
+func writeDebugPage(w http.ResponseWriter, r *http.Request, c Context, status int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	debugPage.Execute(w, struct {
+		Status     int
+		StatusText string
+		Method     string
+		Path       string
+		Partial    string
+		Params     map[string]string
+		Routes     []RouteInfo
+	}{status, http.StatusText(status), r.Method, c.requestURL(r).Path, c.partialPattern, c.Params(), c.extra().debug.Routes()})
+}
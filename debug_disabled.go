@@ -0,0 +1,6 @@
+//go:build !muxterdebug
+
+package muxter
+
+// debugBuild enables the debug mode of every mux in builds with the muxterdebug tag.
+const debugBuild = false
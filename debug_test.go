@@ -0,0 +1,82 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	var (
+		retainedWriter  http.ResponseWriter
+		retainedRequest *http.Request
+	)
+
+	api := New()
+	api.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		retainedWriter, retainedRequest = w, r
+		io.WriteString(w, "books")
+	})
+
+	mux := New(Debug(true))
+	mux.HandleFunc("/api/:version/users", func(w http.ResponseWriter, r *http.Request, c Context) {}, Name("users"), Describe("Lists users"))
+	mux.Handle("/v1/", StripDepth(1, api))
+
+	t.Run("html not found page", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/api/v2/<authors>", nil)
+		r.Header.Set("Accept", "text/html,application/xhtml+xml")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != 404 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("expected html not found page but got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		body := w.Body.String()
+		for _, expected := range []string{
+			"<code>GET /api/v2/&lt;authors&gt;</code>",
+			"Partially matched <code>/api/:version/</code> <code>version=v2</code>",
+			"<td><code>/api/:version/users</code></td><td>users</td><td>Lists users</td>",
+		} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("expected page to contain %q but got:\n%s", expected, body)
+			}
+		}
+	})
+
+	t.Run("plain not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/authors", nil))
+
+		if w.Code != 404 || w.Body.String() != "Not Found\n" {
+			t.Errorf("expected plain not found for clients not accepting html but got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("use after served", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/v1/books", nil))
+		if w.Body.String() != "books" {
+			t.Fatalf("expected books but got %q", w.Body.String())
+		}
+
+		if retainedRequest.Method != "MUXTER_POISONED" {
+			t.Errorf("expected request handed by StripDepth to be poisoned but got method %s", retainedRequest.Method)
+		}
+
+		defer func() {
+			expected := "muxter: GET /v1/books: response written after its request was served"
+			if recovered, _ := recover().(string); recovered != expected {
+				t.Errorf("expected panic %q but got %q", expected, recovered)
+			}
+		}()
+		io.WriteString(retainedWriter, "late")
+	})
+
+	t.Run("call sites", func(t *testing.T) {
+		if route := mux.Routes()[0]; !strings.Contains(route.CallSite, "debug_test.go:") {
+			t.Errorf("expected call sites to be recorded but got %q", route.CallSite)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package muxter
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DeltaOptions configures the DeltaEncoding middleware.
+type DeltaOptions struct {
+	// ClientKey identifies the client a representation is cached for. It defaults to the host of the request's
+	// remote address.
+	ClientKey func(*http.Request) string
+	// MaxEntries is the maximum number of representations cached, evicting the least recently used. It defaults to 1024.
+	MaxEntries int
+}
+
+// DeltaEncoding returns a middleware that cuts the bandwidth of polling clients by sending only the changes to a JSON
+// resource since they last fetched it, using RFC 3229 delta encoding with RFC 6902 JSON Patch documents.
+//
+// Successful JSON responses to GET requests are given a strong ETag, and the last representation served to each
+// client for each URL is cached. When a client sends the ETag of that representation in If-None-Match along with
+// the header "A-IM: json-patch", it receives a 226 IM Used response whose body is a JSON Patch from its copy to the
+// current representation, or a 304 Not Modified response if the resource has not changed. The full representation
+// is sent when the cached representation is not the client's or when the patch would not be smaller.
+func DeltaEncoding(opts DeltaOptions) Middleware {
+	if opts.ClientKey == nil {
+		opts.ClientKey = func(r *http.Request) string {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				return r.RemoteAddr
+			}
+			return host
+		}
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1024
+	}
+
+	cache := &deltaCache{max: opts.MaxEntries, entries: map[string]*list.Element{}, order: list.New()}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if r.Method != "GET" {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			rec := &dispatchRecorder{header: w.Header()}
+			h.ServeHTTPx(rec, r, c)
+			rec.WriteHeader(http.StatusOK)
+
+			body := rec.body.Bytes()
+
+			mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			if rec.code != http.StatusOK || !(mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+				w.WriteHeader(rec.code)
+				w.Write(body)
+				return
+			}
+
+			sum := sha256.Sum256(body)
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+			key := opts.ClientKey(r) + " " + r.URL.RequestURI()
+			previous := cache.swap(key, deltaEntry{etag: etag, body: append([]byte(nil), body...)})
+
+			w.Header().Set("ETag", etag)
+			w.Header().Add("Vary", "A-IM")
+
+			inm := r.Header.Get("If-None-Match")
+			if inm == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			if previous.etag != "" && inm == previous.etag && acceptsEncoding(r.Header.Get("A-IM"), "json-patch") {
+				if patch, err := JSONDiff(previous.body, body); err == nil && len(patch) < len(body) {
+					w.Header().Set("Content-Type", JSONPatchContentType)
+					w.Header().Set("IM", "json-patch")
+					w.Header().Set("Delta-Base", previous.etag)
+					w.Header().Set("Content-Length", strconv.Itoa(len(patch)))
+					w.WriteHeader(http.StatusIMUsed)
+					w.Write(patch)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(rec.code)
+			w.Write(body)
+		})
+	}
+}
+
+type deltaEntry struct {
+	key  string
+	etag string
+	body []byte
+}
+
+// deltaCache is a least recently used cache of the representations served by DeltaEncoding.
+type deltaCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// swap stores the entry for the key and returns the entry it replaces, if any.
+func (cache *deltaCache) swap(key string, entry deltaEntry) deltaEntry {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry.key = key
+
+	if elem, ok := cache.entries[key]; ok {
+		previous := elem.Value.(deltaEntry)
+		elem.Value = entry
+		cache.order.MoveToFront(elem)
+		return previous
+	}
+
+	cache.entries[key] = cache.order.PushFront(entry)
+	if cache.order.Len() > cache.max {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(deltaEntry).key)
+	}
+	return deltaEntry{}
+}
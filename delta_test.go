@@ -0,0 +1,81 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeltaEncoding(t *testing.T) {
+	document := `{"status":"pending","progress":10,"log":["queued"],"owner":{"id":"42","name":"Ada Lovelace","email":"ada@example.com","team":"analytical-engines","roles":["admin","operator"]},"spec":{"image":"registry.example.com/jobs/report:1.4.2","command":["generate","--format=pdf","--since=2024-01-01"],"timeout":"30m"}}`
+
+	mux := New()
+	mux.HandleFunc("/jobs/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, document)
+	}, DeltaEncoding(DeltaOptions{}))
+
+	poll := func(remoteAddr, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/jobs/1", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("A-IM", "json-patch")
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	first := poll("10.0.0.1:1234", "")
+	if first.Code != 200 || first.Body.String() != document {
+		t.Fatalf("expected full representation but got %d %s", first.Code, first.Body.String())
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected response to have an etag")
+	}
+
+	if w := poll("10.0.0.1:1234", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("expected unchanged resource to be not modified but got %d", w.Code)
+	}
+
+	document = `{"status":"running","progress":50,"log":["queued","started"],"owner":{"id":"42","name":"Ada Lovelace","email":"ada@example.com","team":"analytical-engines","roles":["admin","operator"]},"spec":{"image":"registry.example.com/jobs/report:1.4.2","command":["generate","--format=pdf","--since=2024-01-01"],"timeout":"30m"}}`
+
+	delta := poll("10.0.0.1:1234", etag)
+	if delta.Code != http.StatusIMUsed {
+		t.Fatalf("expected status 226 but got %d", delta.Code)
+	}
+	if contentType := delta.Header().Get("Content-Type"); contentType != JSONPatchContentType {
+		t.Errorf("expected content type %q but got %q", JSONPatchContentType, contentType)
+	}
+	if base := delta.Header().Get("Delta-Base"); base != etag {
+		t.Errorf("expected delta base %q but got %q", etag, base)
+	}
+
+	patched, err := JSONPatch([]byte(first.Body.String()), delta.Body.Bytes())
+	if err != nil {
+		t.Fatalf("failed to apply delta: %v", err)
+	}
+	if !jsonBytesEqual(t, patched, []byte(document)) {
+		t.Errorf("expected patched document %s but got %s", document, patched)
+	}
+	if delta.Header().Get("ETag") == etag {
+		t.Errorf("expected delta to have a new etag")
+	}
+
+	if w := poll("10.0.0.2:1234", etag); w.Code != 200 || w.Body.String() != document {
+		t.Errorf("expected another client to receive the full representation but got %d %s", w.Code, w.Body.String())
+	}
+
+	r := httptest.NewRequest("GET", "/jobs/1", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("If-None-Match", delta.Header().Get("ETag"))
+	document = `{"status":"done"}`
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != 200 || w.Body.String() != document {
+		t.Errorf("expected client not accepting deltas to receive the full representation but got %d %s", w.Code, w.Body.String())
+	}
+}
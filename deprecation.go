@@ -0,0 +1,109 @@
+package muxter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecation describes the deprecation of a route.
+type Deprecation struct {
+	// Since is when the route was deprecated. It is optional.
+	Since time.Time `json:"since"`
+	// Sunset is when the route stops being served. It is optional.
+	Sunset time.Time `json:"sunset"`
+	// Successor is the path or URL of the route replacing the deprecated route.
+	Successor string `json:"successor,omitempty"`
+	// Link is the URL of documentation about the deprecation, such as a migration guide.
+	Link string `json:"link,omitempty"`
+}
+
+// Deprecated is a registration option marking a route as deprecated. Responses of the route carry the Deprecation
+// and Sunset headers, and Link headers to its successor and documentation, and the route is listed by the
+// DeprecationsHandler of the mux.
+//
+//	mux.HandleFunc("/v1/books", listBooksV1, muxter.Deprecated(muxter.Deprecation{
+//		Sunset:    time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+//		Successor: "/v2/books",
+//	}))
+func Deprecated(d Deprecation) Middleware {
+	return func(h Handler) Handler {
+		return routeOption{
+			Handler: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				header := w.Header()
+				if d.Since.IsZero() {
+					header.Set("Deprecation", "true")
+				} else {
+					header.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+				}
+				if !d.Sunset.IsZero() {
+					header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+				}
+				if d.Successor != "" {
+					header.Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+				}
+				if d.Link != "" {
+					header.Add("Link", "<"+d.Link+`>; rel="deprecation"`)
+				}
+				h.ServeHTTPx(w, r, c)
+			}),
+			apply: func(ri *RouteInfo) {
+				ri.Deprecation = &d
+			},
+		}
+	}
+}
+
+// DeprecatedRoute is an entry of the deprecation registry served by Mux.DeprecationsHandler.
+type DeprecatedRoute struct {
+	Pattern string   `json:"pattern"`
+	Name    string   `json:"name,omitempty"`
+	Methods []string `json:"methods,omitempty"`
+	// Deprecated and Sunset are RFC 3339 timestamps, omitted when the deprecation does not declare them.
+	Deprecated string `json:"deprecated,omitempty"`
+	Sunset     string `json:"sunset,omitempty"`
+	Successor  string `json:"successor,omitempty"`
+	Link       string `json:"link,omitempty"`
+}
+
+// DeprecatedRoutes returns the routes registered with the Deprecated option sorted by pattern.
+func (m *Mux) DeprecatedRoutes() []DeprecatedRoute {
+	var deprecated []DeprecatedRoute
+	for _, route := range m.routes(func(v *value) bool { return v.route.Deprecation != nil }) {
+		d := route.Deprecation
+		entry := DeprecatedRoute{
+			Pattern:   route.Pattern,
+			Name:      route.Name,
+			Methods:   route.Methods,
+			Successor: d.Successor,
+			Link:      d.Link,
+		}
+		if !d.Since.IsZero() {
+			entry.Deprecated = d.Since.UTC().Format(time.RFC3339)
+		}
+		if !d.Sunset.IsZero() {
+			entry.Sunset = d.Sunset.UTC().Format(time.RFC3339)
+		}
+		deprecated = append(deprecated, entry)
+	}
+	return deprecated
+}
+
+// DeprecationsHandler returns a handler serving the deprecated routes of the mux as JSON, such as
+// {"routes":[{"pattern":"/v1/books","sunset":"2025-06-01T00:00:00Z","successor":"/v2/books"}]}, so that client teams
+// can track their migrations automatically.
+//
+//	mux.Handle("/.well-known/deprecations", mux.DeprecationsHandler(), mux.Method("GET"))
+func (m *Mux) DeprecationsHandler() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		routes := m.DeprecatedRoutes()
+		if routes == nil {
+			routes = []DeprecatedRoute{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Routes []DeprecatedRoute `json:"routes"`
+		}{routes})
+	})
+}
@@ -0,0 +1,77 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeprecated(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New()
+	mux.GetFunc("/v1/books", noop, Name("books.v1"), Deprecated(Deprecation{
+		Since:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2/books",
+		Link:      "https://docs.example.com/migrations/books",
+	}))
+	mux.HandleFunc("/v1/authors", noop, Deprecated(Deprecation{}))
+	mux.GetFunc("/v2/books", noop)
+	mux.Handle("/deprecations", mux.DeprecationsHandler())
+
+	testcases := []struct {
+		Path            string
+		ExpectedHeaders http.Header
+	}{
+		{
+			Path: "/v1/books",
+			ExpectedHeaders: http.Header{
+				"Deprecation": {"@1704067200"},
+				"Sunset":      {"Sun, 01 Jun 2025 00:00:00 GMT"},
+				"Link": {
+					`</v2/books>; rel="successor-version"`,
+					`<https://docs.example.com/migrations/books>; rel="deprecation"`,
+				},
+			},
+		},
+		{
+			Path:            "/v1/authors",
+			ExpectedHeaders: http.Header{"Deprecation": {"true"}},
+		},
+		{
+			Path:            "/v2/books",
+			ExpectedHeaders: http.Header{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			for _, key := range []string{"Deprecation", "Sunset", "Link"} {
+				if actual := w.Header()[key]; !reflect.DeepEqual(actual, tc.ExpectedHeaders[key]) {
+					t.Errorf("expected %s to be %q but got %q", key, tc.ExpectedHeaders[key], actual)
+				}
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/deprecations", nil))
+
+	expected := `{"routes":[` +
+		`{"pattern":"/v1/authors"},` +
+		`{"pattern":"/v1/books","name":"books.v1","methods":["GET","HEAD"],"deprecated":"2024-01-01T00:00:00Z",` +
+		`"sunset":"2025-06-01T00:00:00Z","successor":"/v2/books","link":"https://docs.example.com/migrations/books"}` +
+		"]}\n"
+	if body := w.Body.String(); body != expected {
+		t.Errorf("expected registry:\n%s\nbut got:\n%s", expected, body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected content type application/json but got %q", contentType)
+	}
+}
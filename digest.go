@@ -0,0 +1,233 @@
+package muxter
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrDigestMismatch is the error returned by the bodies of requests verified by VerifyBodyDigest once their end is
+// read, if their content does not match their digest headers.
+var ErrDigestMismatch = errors.New("muxter: request body does not match its digest")
+
+// BodyDigestOptions configures the VerifyBodyDigest middleware.
+type BodyDigestOptions struct {
+	// Required rejects requests with a body but without a digest of a supported algorithm.
+	Required bool
+	// OnReject is called with the reason of every rejected request, for example to record a metric.
+	OnReject func(r *http.Request, reason string)
+}
+
+// digestAlgorithms are the supported algorithms by their lowercase name in the Digest, Content-Digest and Repr-Digest
+// headers.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// VerifyBodyDigest is a middleware verifying request bodies against their digests, for integrity sensitive routes
+// such as uploads. The Content-MD5, Digest, Content-Digest and Repr-Digest headers are supported with the md5,
+// sha-256 and sha-512 algorithms; Repr-Digest is only verified for requests without Content-Encoding.
+//
+// Bodies are verified as they are streamed to the handler: reading the end of a mismatching body fails with
+// ErrDigestMismatch instead of io.EOF, and the response is replaced with 400 Bad Request unless the handler had
+// already started writing it. Bodies that handlers do not read to the end are not verified. Requests with malformed
+// digest headers are rejected with 400 Bad Request before reaching the handler.
+//
+//	mux.HandleFunc("/uploads", upload, muxter.VerifyBodyDigest(muxter.BodyDigestOptions{Required: true}))
+func VerifyBodyDigest(opts BodyDigestOptions) Middleware {
+	reject := func(w http.ResponseWriter, r *http.Request, c Context, reason string) {
+		if opts.OnReject != nil {
+			opts.OnReject(r, reason)
+		}
+		writeStatus(w, c, http.StatusBadRequest)
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if r.Body == nil || r.Body == http.NoBody {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			expected, err := requestDigests(r.Header)
+			if err != nil {
+				reject(w, r, c, err.Error())
+				return
+			}
+			if len(expected) == 0 {
+				if opts.Required {
+					reject(w, r, c, "missing digest")
+					return
+				}
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			body := &digestReader{ReadCloser: r.Body, expected: expected, hashes: map[string]hash.Hash{}}
+			for algorithm := range expected {
+				body.hashes[algorithm] = digestAlgorithms[algorithm]()
+			}
+			r.Body = body
+			defer func() { r.Body = body.ReadCloser }()
+
+			dw := &digestWriter{ResponseWriter: w, body: body, reject: func(w http.ResponseWriter) {
+				reject(w, r, c, "digest mismatch")
+			}}
+			h.ServeHTTPx(dw, r, c)
+			if body.mismatch && !dw.wroteHeader {
+				dw.WriteHeader(http.StatusOK)
+			}
+		})
+	}
+}
+
+// requestDigests returns the expected digests of the request body by algorithm.
+func requestDigests(header http.Header) (map[string][]byte, error) {
+	expected := map[string][]byte{}
+	add := func(algorithm string, digest []byte) error {
+		if previous, ok := expected[algorithm]; ok && !bytes.Equal(previous, digest) {
+			return fmt.Errorf("conflicting %s digests", algorithm)
+		}
+		expected[algorithm] = digest
+		return nil
+	}
+
+	if value := header.Get("Content-MD5"); value != "" {
+		digest, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil || len(digest) != md5.Size {
+			return nil, errors.New("malformed Content-MD5 header")
+		}
+		add("md5", digest)
+	}
+
+	names := []string{"Digest", "Content-Digest"}
+	if header.Get("Content-Encoding") == "" {
+		names = append(names, "Repr-Digest")
+	}
+	for _, name := range names {
+		for _, value := range header.Values(name) {
+			digests, err := parseDigestHeader(value, name != "Digest")
+			if err != nil {
+				return nil, fmt.Errorf("malformed %s header", name)
+			}
+			for algorithm, digest := range digests {
+				if err := add(algorithm, digest); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return expected, nil
+}
+
+// parseDigestHeader parses a header of comma separated digests of the form algorithm=base64, as in the Digest header
+// of RFC 3230, or of the form algorithm=:base64: when structured is true, as in the Content-Digest and Repr-Digest
+// headers of RFC 9530. Algorithms are lowercased, and those that are not supported are skipped.
+func parseDigestHeader(value string, structured bool) (map[string][]byte, error) {
+	digests := map[string][]byte{}
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		algorithm, encoded, ok := strings.Cut(member, "=")
+		if !ok {
+			return nil, fmt.Errorf("digest %q has no value", member)
+		}
+		algorithm = strings.ToLower(algorithm)
+		if _, ok := digestAlgorithms[algorithm]; !ok {
+			continue
+		}
+		if structured {
+			if len(encoded) < 2 || encoded[0] != ':' || encoded[len(encoded)-1] != ':' {
+				return nil, fmt.Errorf("digest %q is not a byte sequence", member)
+			}
+			encoded = encoded[1 : len(encoded)-1]
+		}
+		digest, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("digest %q is not base64: %w", member, err)
+		}
+		digests[algorithm] = digest
+	}
+	return digests, nil
+}
+
+// digestReader hashes the body as it is read, and fails the read of its end if it does not match the digests.
+type digestReader struct {
+	io.ReadCloser
+	expected map[string][]byte
+	hashes   map[string]hash.Hash
+	mismatch bool
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	for _, h := range d.hashes {
+		h.Write(p[:n])
+	}
+	if err == io.EOF {
+		for algorithm, h := range d.hashes {
+			if !bytes.Equal(h.Sum(nil), d.expected[algorithm]) {
+				d.mismatch = true
+				return n, ErrDigestMismatch
+			}
+		}
+	}
+	return n, err
+}
+
+// digestWriter replaces the response with the rejection of the request if the body did not match its digests by the
+// time the handler starts writing it.
+type digestWriter struct {
+	http.ResponseWriter
+	body        *digestReader
+	reject      func(w http.ResponseWriter)
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *digestWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *digestWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.body.mismatch {
+		w.discard = true
+		w.reject(w.ResponseWriter)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *digestWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *digestWriter) Flush() {
+	if w.discard {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
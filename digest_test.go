@@ -0,0 +1,147 @@
+package muxter
+
+import (
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyBodyDigest(t *testing.T) {
+	md5Digest := func(content string) string {
+		sum := md5.Sum([]byte(content))
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+	sha512Digest := func(content string) string {
+		sum := sha512.Sum512([]byte(content))
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	testcases := []struct {
+		Name           string
+		Header         http.Header
+		Body           string
+		Required       bool
+		ExpectedCode   int
+		ExpectedBody   string
+		ExpectedReason string
+	}{
+		{
+			Name:         "content-md5",
+			Header:       http.Header{"Content-Md5": {md5Digest("payload")}},
+			Body:         "payload",
+			ExpectedCode: 200,
+			ExpectedBody: "payload",
+		},
+		{
+			Name:           "content-md5 mismatch",
+			Header:         http.Header{"Content-Md5": {md5Digest("other")}},
+			Body:           "payload",
+			ExpectedCode:   400,
+			ExpectedReason: "digest mismatch",
+		},
+		{
+			Name:         "digest",
+			Header:       http.Header{"Digest": {"UNIXsum=30637, SHA-512=" + sha512Digest("payload")}},
+			Body:         "payload",
+			ExpectedCode: 200,
+			ExpectedBody: "payload",
+		},
+		{
+			Name:           "content-digest mismatch",
+			Header:         http.Header{"Content-Digest": {sha256Digest("other")}},
+			Body:           "payload",
+			ExpectedCode:   400,
+			ExpectedReason: "digest mismatch",
+		},
+		{
+			Name:         "repr-digest",
+			Header:       http.Header{"Repr-Digest": {sha256Digest("payload")}},
+			Body:         "payload",
+			ExpectedCode: 200,
+			ExpectedBody: "payload",
+		},
+		{
+			Name:         "repr-digest of encoded body",
+			Header:       http.Header{"Repr-Digest": {sha256Digest("other")}, "Content-Encoding": {"gzip"}},
+			Body:         "payload",
+			ExpectedCode: 200,
+			ExpectedBody: "payload",
+		},
+		{
+			Name:           "malformed",
+			Header:         http.Header{"Content-Digest": {"sha-256=notbytes"}},
+			Body:           "payload",
+			ExpectedCode:   400,
+			ExpectedReason: "malformed Content-Digest header",
+		},
+		{
+			Name:           "conflicting",
+			Header:         http.Header{"Content-Digest": {sha256Digest("payload")}, "Repr-Digest": {sha256Digest("other")}},
+			Body:           "payload",
+			ExpectedCode:   400,
+			ExpectedReason: "conflicting sha-256 digests",
+		},
+		{
+			Name:         "optional",
+			Body:         "payload",
+			ExpectedCode: 200,
+			ExpectedBody: "payload",
+		},
+		{
+			Name:           "required",
+			Body:           "payload",
+			Required:       true,
+			ExpectedCode:   400,
+			ExpectedReason: "missing digest",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var reasons []string
+			var readErr error
+
+			mux := New()
+			mux.HandleFunc("/uploads", func(w http.ResponseWriter, r *http.Request, c Context) {
+				body, err := io.ReadAll(r.Body)
+				if readErr = err; err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.Write(body)
+			}, VerifyBodyDigest(BodyDigestOptions{
+				Required: tc.Required,
+				OnReject: func(r *http.Request, reason string) { reasons = append(reasons, reason) },
+			}))
+
+			r := httptest.NewRequest("PUT", "/uploads", strings.NewReader(tc.Body))
+			for key, values := range tc.Header {
+				r.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedCode == 200 && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+			if tc.ExpectedReason == "" && len(reasons) != 0 {
+				t.Errorf("expected no rejections but got %q", reasons)
+			}
+			if tc.ExpectedReason != "" && (len(reasons) != 1 || reasons[0] != tc.ExpectedReason) {
+				t.Errorf("expected rejection %q but got %q", tc.ExpectedReason, reasons)
+			}
+			if tc.ExpectedReason == "digest mismatch" && !errors.Is(readErr, ErrDigestMismatch) {
+				t.Errorf("expected the handler to read ErrDigestMismatch but got %v", readErr)
+			}
+		})
+	}
+}
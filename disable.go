@@ -0,0 +1,46 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Disable disables the route registered with the pattern such that it is served as not found, without removing it
+// from the mux. It is safe to call while the mux is serving requests, making it suitable for incident response and
+// dark launches. Disable panics if the pattern is not registered on the mux.
+func (m *Mux) Disable(pattern string) {
+	m.setDisabled(pattern, http.StatusNotFound)
+}
+
+// DisableUnavailable disables the route registered with the pattern like Disable, but serves it as
+// 503 Service Unavailable instead of not found.
+func (m *Mux) DisableUnavailable(pattern string) {
+	m.setDisabled(pattern, http.StatusServiceUnavailable)
+}
+
+// Enable enables the route registered with the pattern that was disabled.
+func (m *Mux) Enable(pattern string) {
+	m.setDisabled(pattern, 0)
+}
+
+func (m *Mux) setDisabled(pattern string, status int32) {
+	v := m.lookupPattern(pattern)
+	if v == nil {
+		panic(fmt.Sprintf("muxter: cannot toggle unregistered route %s", pattern))
+	}
+	m.toggle(v, status)
+}
+
+// toggle sets the disabled status of the route and publishes the change.
+func (m *Mux) toggle(v *value, status int32) {
+	if v.disabled.Swap(status) == status {
+		return
+	}
+	route := *v.route
+	route.Disabled = status != 0
+	if status == 0 {
+		m.events.publish(eventRouteEnabled, RouteEnabledEvent{Route: route})
+	} else {
+		m.events.publish(eventRouteDisabled, RouteDisabledEvent{Route: route, Status: int(status)})
+	}
+}
@@ -0,0 +1,53 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisable(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/feature", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	testcases := []struct {
+		Name         string
+		Toggle       func(pattern string)
+		ExpectedCode int
+	}{
+		{Name: "disable", Toggle: mux.Disable, ExpectedCode: 404},
+		{Name: "enable", Toggle: mux.Enable, ExpectedCode: 200},
+		{Name: "unavailable", Toggle: mux.DisableUnavailable, ExpectedCode: 503},
+		{Name: "enable again", Toggle: mux.Enable, ExpectedCode: 200},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Toggle("/feature")
+
+			if code := serve("/feature"); code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, code)
+			}
+			if code := serve("/other"); code != 200 {
+				t.Errorf("expected other route to be unaffected but got %d", code)
+			}
+		})
+	}
+
+	t.Run("unregistered route", func(t *testing.T) {
+		defer func() {
+			expected := "muxter: cannot toggle unregistered route /missing"
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected panic %q but got %q", expected, actual)
+			}
+		}()
+		mux.Disable("/missing")
+	})
+}
@@ -0,0 +1,90 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Dispatch serves a synthetic request through the mux in-process, running the full chain of middlewares and
+// handlers without a network connection. It is useful for batch handlers, server-side includes and tests.
+// The target is a path, optionally with a query, or an absolute URL whose host is used as the request's host.
+// The response body is fully buffered. Dispatch returns an error if the request cannot be created or if the handler
+// panics.
+func (m *Mux) Dispatch(ctx context.Context, method, target string, header http.Header, body io.Reader) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("muxter: failed to create request: %w", err)
+	}
+	if header != nil {
+		r.Header = header.Clone()
+	}
+	r.RequestURI = r.URL.RequestURI()
+
+	return m.dispatch(r)
+}
+
+// dispatch serves the request through the mux in-process and returns the buffered response. A panic in the handler
+// is returned as an error.
+func (m *Mux) dispatch(r *http.Request) (resp *http.Response, err error) {
+	rec := &dispatchRecorder{header: http.Header{}}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			resp, err = nil, fmt.Errorf("muxter: handler panicked: %v", recovered)
+		}
+	}()
+
+	m.ServeHTTP(rec, r)
+
+	return rec.result(r), nil
+}
+
+// dispatchRecorder buffers the response of an in-process request.
+type dispatchRecorder struct {
+	header      http.Header
+	sent        http.Header
+	code        int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *dispatchRecorder) Header() http.Header { return rec.header }
+
+func (rec *dispatchRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.code = code
+	rec.sent = rec.header.Clone()
+}
+
+func (rec *dispatchRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
+
+func (rec *dispatchRecorder) result(r *http.Request) *http.Response {
+	rec.WriteHeader(http.StatusOK)
+
+	header := rec.sent
+	if header.Get("Content-Type") == "" && rec.body.Len() > 0 {
+		header.Set("Content-Type", http.DetectContentType(rec.body.Bytes()))
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(rec.code) + " " + http.StatusText(rec.code),
+		StatusCode:    rec.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+		ContentLength: int64(rec.body.Len()),
+		Request:       r,
+	}
+}
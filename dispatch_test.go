@@ -0,0 +1,76 @@
+package muxter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	mux := New()
+	mux.Use(func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Header().Set("X-Middleware", "true")
+			h.ServeHTTPx(w, r, c)
+		})
+	})
+	mux.PostFunc("/users/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(201)
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Host+" "+c.Param("id")+" "+r.URL.Query().Get("q")+" "+r.Header.Get("X-Test")+" "+string(body))
+	})
+	mux.GetFunc("/panic", func(w http.ResponseWriter, r *http.Request, c Context) {
+		panic("boom")
+	})
+
+	t.Run("response", func(t *testing.T) {
+		resp, err := mux.Dispatch(
+			context.Background(),
+			"POST",
+			"http://example.com/users/1?q=search",
+			http.Header{"X-Test": {"header"}},
+			strings.NewReader("body"),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+
+		if resp.StatusCode != 201 {
+			t.Errorf("expected status 201 but got %d", resp.StatusCode)
+		}
+		if resp.Header.Get("X-Middleware") != "true" {
+			t.Errorf("expected middlewares to be applied")
+		}
+		if expected := "example.com 1 search header body"; string(body) != expected {
+			t.Errorf("expected body %q but got %q", expected, body)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp, err := mux.Dispatch(context.Background(), "GET", "/missing", nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != 404 {
+			t.Errorf("expected status 404 but got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		_, err := mux.Dispatch(context.Background(), "GET", "/panic", nil, nil)
+		if err == nil || err.Error() != "muxter: handler panicked: boom" {
+			t.Errorf("expected panic error but got %v", err)
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		if _, err := mux.Dispatch(context.Background(), "BAD METHOD", "/", nil, nil); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
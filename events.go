@@ -0,0 +1,158 @@
+package muxter
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RouteRegisteredEvent is published when a route is registered on the mux.
+type RouteRegisteredEvent struct {
+	Route RouteInfo
+}
+
+// RouteDisabledEvent is published when a route is disabled with Mux.Disable, Mux.DisableUnavailable or
+// Mux.DisableTag. Status is the status the route is served as while disabled.
+type RouteDisabledEvent struct {
+	Route  RouteInfo
+	Status int
+}
+
+// RouteEnabledEvent is published when a route is enabled again with Mux.Enable or Mux.EnableTag.
+type RouteEnabledEvent struct {
+	Route RouteInfo
+}
+
+// NotFoundEvent is published when the mux serves a request as not found, including requests for disabled routes.
+type NotFoundEvent struct {
+	Request *http.Request
+	// Path is the request path as received by the mux.
+	Path string
+}
+
+// PanicRecoveredEvent is published when the Recover middleware recovers a panic.
+type PanicRecoveredEvent struct {
+	Request   *http.Request
+	Pattern   string
+	Recovered interface{}
+}
+
+// LimiterRejectedEvent is published when a RateLimiter or a LoadShedder rejects a request.
+type LimiterRejectedEvent struct {
+	Request *http.Request
+	Pattern string
+	// Status is 429 Too Many Requests for rate limited requests and 503 Service Unavailable for shed requests.
+	Status int
+	// RetryAfter is the wait advertised to the client with the Retry-After header.
+	RetryAfter time.Duration
+}
+
+// EventBus publishes the lifecycle events of the muxes it is set on with the Events option to its subscribers, so
+// that observability and admin tooling can be built without wrapping every middleware. Subscribers are called
+// synchronously, on the goroutine registering the route or serving the request, and must return quickly.
+type EventBus struct {
+	mu          sync.RWMutex
+	next        uint64
+	subscribers map[string][]eventSubscriber
+}
+
+type eventSubscriber struct {
+	id uint64
+	fn func(event interface{})
+}
+
+// NewEventBus returns an event bus without subscribers.
+//
+//	events := muxter.NewEventBus()
+//	events.OnPanicRecovered(func(e muxter.PanicRecoveredEvent) { alert(e.Pattern, e.Recovered) })
+//	mux := muxter.New(muxter.Events(events))
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: map[string][]eventSubscriber{}}
+}
+
+// Events sets the bus the mux publishes its lifecycle events to. Nested muxes publish to the bus of their parent
+// unless they set their own.
+func Events(bus *EventBus) MuxOption {
+	return func(m *Mux) {
+		m.events = bus
+	}
+}
+
+const (
+	eventRouteRegistered = "route-registered"
+	eventRouteDisabled   = "route-disabled"
+	eventRouteEnabled    = "route-enabled"
+	eventNotFound        = "not-found"
+	eventPanicRecovered  = "panic-recovered"
+	eventLimiterRejected = "limiter-rejected"
+)
+
+// OnRouteRegistered subscribes fn to RouteRegisteredEvent. It returns a function unsubscribing fn.
+func (bus *EventBus) OnRouteRegistered(fn func(RouteRegisteredEvent)) (unsubscribe func()) {
+	return bus.subscribe(eventRouteRegistered, func(event interface{}) { fn(event.(RouteRegisteredEvent)) })
+}
+
+// OnRouteDisabled subscribes fn to RouteDisabledEvent. It returns a function unsubscribing fn.
+func (bus *EventBus) OnRouteDisabled(fn func(RouteDisabledEvent)) (unsubscribe func()) {
+	return bus.subscribe(eventRouteDisabled, func(event interface{}) { fn(event.(RouteDisabledEvent)) })
+}
+
+// OnRouteEnabled subscribes fn to RouteEnabledEvent. It returns a function unsubscribing fn.
+func (bus *EventBus) OnRouteEnabled(fn func(RouteEnabledEvent)) (unsubscribe func()) {
+	return bus.subscribe(eventRouteEnabled, func(event interface{}) { fn(event.(RouteEnabledEvent)) })
+}
+
+// OnNotFound subscribes fn to NotFoundEvent. It returns a function unsubscribing fn.
+func (bus *EventBus) OnNotFound(fn func(NotFoundEvent)) (unsubscribe func()) {
+	return bus.subscribe(eventNotFound, func(event interface{}) { fn(event.(NotFoundEvent)) })
+}
+
+// OnPanicRecovered subscribes fn to PanicRecoveredEvent. It returns a function unsubscribing fn.
+func (bus *EventBus) OnPanicRecovered(fn func(PanicRecoveredEvent)) (unsubscribe func()) {
+	return bus.subscribe(eventPanicRecovered, func(event interface{}) { fn(event.(PanicRecoveredEvent)) })
+}
+
+// OnLimiterRejected subscribes fn to LimiterRejectedEvent. It returns a function unsubscribing fn.
+func (bus *EventBus) OnLimiterRejected(fn func(LimiterRejectedEvent)) (unsubscribe func()) {
+	return bus.subscribe(eventLimiterRejected, func(event interface{}) { fn(event.(LimiterRejectedEvent)) })
+}
+
+func (bus *EventBus) subscribe(kind string, fn func(event interface{})) func() {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.next++
+	id := bus.next
+	bus.subscribers[kind] = append(bus.subscribers[kind], eventSubscriber{id: id, fn: fn})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			defer bus.mu.Unlock()
+
+			subscribers := bus.subscribers[kind]
+			for i, subscriber := range subscribers {
+				if subscriber.id == id {
+					// Copy rather than remove in place: publish may be iterating over the slice.
+					bus.subscribers[kind] = append(append([]eventSubscriber{}, subscribers[:i]...), subscribers[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+// publish calls the subscribers of the kind with the event. It is a no-op on a nil bus.
+func (bus *EventBus) publish(kind string, event interface{}) {
+	if bus == nil {
+		return
+	}
+	bus.mu.RLock()
+	subscribers := bus.subscribers[kind]
+	bus.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		subscriber.fn(event)
+	}
+}
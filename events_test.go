@@ -0,0 +1,81 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	var events []string
+	record := func(event string) { events = append(events, event) }
+
+	bus := NewEventBus()
+	bus.OnRouteRegistered(func(e RouteRegisteredEvent) { record("registered " + e.Route.Pattern) })
+	bus.OnRouteDisabled(func(e RouteDisabledEvent) { record("disabled " + e.Route.Pattern) })
+	bus.OnRouteEnabled(func(e RouteEnabledEvent) { record("enabled " + e.Route.Pattern) })
+	bus.OnNotFound(func(e NotFoundEvent) { record("not found " + e.Path) })
+	bus.OnPanicRecovered(func(e PanicRecoveredEvent) { record("recovered " + e.Pattern + " " + e.Recovered.(string)) })
+	bus.OnLimiterRejected(func(e LimiterRejectedEvent) { record("rejected " + e.Pattern + " " + http.StatusText(e.Status)) })
+
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 1})
+
+	mux := New(Events(bus))
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request, c Context) { panic("boom") }, Recover(nil))
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request, c Context) {}, limiter.Middleware)
+
+	api := New()
+	api.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	mux.Handle("/api/", StripDepth(1, api))
+
+	serve := func(path string) {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	serve("/panic")
+	serve("/limited")
+	serve("/limited")
+	serve("/missing")
+	serve("/api/missing")
+
+	mux.Disable("/panic")
+	mux.Disable("/panic")
+	serve("/panic")
+	mux.Enable("/panic")
+
+	expected := []string{
+		"registered /panic",
+		"registered /limited",
+		"registered /api/",
+		"recovered /panic boom",
+		"rejected /limited Too Many Requests",
+		"not found /missing",
+		"not found /api/missing",
+		"disabled /panic",
+		"not found /panic",
+		"enabled /panic",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events:\n%q\nbut got:\n%q", expected, events)
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+
+	var first, second int
+	unsubscribe := bus.OnNotFound(func(NotFoundEvent) { first++ })
+	bus.OnNotFound(func(NotFoundEvent) { second++ })
+
+	mux := New(Events(bus))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	unsubscribe()
+	unsubscribe()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if first != 1 || second != 2 {
+		t.Errorf("expected unsubscribed subscriber to be called once and the other twice but got %d and %d", first, second)
+	}
+}
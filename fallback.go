@@ -0,0 +1,35 @@
+package muxter
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Fallback delegates the requests that match no route to h instead of answering them with 404 Not Found, such that
+// muxter can front an existing net/http ServeMux or third-party router while routes are migrated to it one at a
+// time. h receives the request with its path and query as the mux received them, even when the request reaches it
+// through a nested mux, and global middlewares are applied to it as they are to the not found handler. Requests
+// matching a route but none of its methods are still answered with 405 Method Not Allowed, unless the method
+// mismatch policy is MethodPolicyNotFound.
+//
+// Nested muxes delegate to the fallback of their parent unless they have their own.
+//
+//	mux.Fallback(legacyServeMux)
+func (m *Mux) Fallback(h http.Handler) {
+	if h == nil {
+		m.fallback = nil
+		return
+	}
+	m.fallback = HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.ogReqPath != "" && (r.URL.Path != c.ogReqPath || r.URL.RawQuery != c.ogRawQuery) {
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path, r2.URL.RawPath, r2.URL.RawQuery = c.ogReqPath, "", c.ogRawQuery
+			r2.RequestURI = r2.URL.RequestURI()
+			r = r2
+		}
+		h.ServeHTTP(w, r)
+	})
+}
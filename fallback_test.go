@@ -0,0 +1,54 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "legacy %s", r.URL.RequestURI())
+	})
+
+	mux := New()
+	mux.Fallback(legacy)
+
+	api := New()
+	api.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte("users"))
+	})
+	mux.Handle("/api/", StripDepth(1, api))
+	mux.Handle("/books/:id", MethodHandler{GET: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte("book " + c.Param("id")))
+	})})
+
+	testcases := []struct {
+		Method       string
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Method: "GET", Path: "/books/1", ExpectedCode: 200, ExpectedBody: "book 1"},
+		{Method: "GET", Path: "/authors/1?page=2", ExpectedCode: 200, ExpectedBody: "legacy /authors/1?page=2"},
+		{Method: "GET", Path: "/api/users", ExpectedCode: 200, ExpectedBody: "users"},
+		{Method: "GET", Path: "/api/teams?q=x", ExpectedCode: 200, ExpectedBody: "legacy /api/teams?q=x"},
+		{Method: "POST", Path: "/books/1", ExpectedCode: 405},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Method+" "+tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
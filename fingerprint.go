@@ -0,0 +1,175 @@
+package muxter
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FingerprintFunc computes the fingerprint of the client of a request, a value that is stable across the requests of
+// a client, such that bot detection and abuse systems can recognize clients that rotate their addresses. It returns
+// the empty string when no fingerprint can be computed.
+type FingerprintFunc func(r *http.Request) string
+
+// Fingerprints sets the function computing the fingerprint of every request served by the mux. The fingerprint is
+// computed once per request, before routing, and is exposed by Context.Fingerprint and the Fingerprint field of the
+// Logger's RespOverview. Nested muxes use the fingerprint computed by their parent unless it is empty and they set
+// their own function.
+//
+//	hellos := muxter.NewClientHelloFingerprints()
+//	mux := muxter.New(muxter.Fingerprints(hellos.Fingerprint))
+func Fingerprints(fn FingerprintFunc) MuxOption {
+	return func(m *Mux) {
+		m.fingerprint = fn
+	}
+}
+
+// Fingerprint returns the fingerprint of the client of the request, as computed by the function set with the
+// Fingerprints option, or the empty string if none was computed.
+func (c Context) Fingerprint() string {
+	return c.extra().fingerprint
+}
+
+// defaultFingerprintHeaders are the headers whose values are part of the fingerprint of HeaderFingerprint by default.
+var defaultFingerprintHeaders = []string{"User-Agent", "Accept", "Accept-Language", "Accept-Encoding"}
+
+// HeaderFingerprint returns a FingerprintFunc hashing the protocol of the request, the names of its headers and the
+// values of the given headers, which default to User-Agent, Accept, Accept-Language and Accept-Encoding. Clients
+// built with the same library and configuration send the same headers and share a fingerprint.
+//
+// The order of the headers as sent by the client is not part of the fingerprint, as net/http does not retain it.
+func HeaderFingerprint(headers ...string) FingerprintFunc {
+	if len(headers) == 0 {
+		headers = defaultFingerprintHeaders
+	}
+	canonical := make([]string, len(headers))
+	for i, header := range headers {
+		canonical[i] = http.CanonicalHeaderKey(header)
+	}
+
+	return func(r *http.Request) string {
+		names := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Proto))
+		hash.Write([]byte{0})
+		hash.Write([]byte(strings.Join(names, ",")))
+		for _, header := range canonical {
+			hash.Write([]byte{0})
+			hash.Write([]byte(strings.Join(r.Header[header], ",")))
+		}
+		return hex.EncodeToString(hash.Sum(nil)[:16])
+	}
+}
+
+// ClientHelloFingerprints records a JA3 style fingerprint of the TLS ClientHello of the connections of a server,
+// hashing the TLS versions, cipher suites, elliptic curves and point formats offered by the client. The extensions
+// of the ClientHello are not part of the fingerprint, as crypto/tls does not expose them, such that it does not
+// match the fingerprints of JA3 databases but is as stable.
+//
+// Its GetConfigForClient method records the fingerprint of new connections and its ConnState method forgets closed
+// connections; both must be set on the server.
+//
+//	hellos := muxter.NewClientHelloFingerprints()
+//	server := &http.Server{
+//		Handler:   muxter.New(muxter.Fingerprints(hellos.Fingerprint)),
+//		TLSConfig: &tls.Config{GetConfigForClient: hellos.GetConfigForClient},
+//		ConnState: hellos.ConnState,
+//	}
+type ClientHelloFingerprints struct {
+	mu    sync.Mutex
+	conns map[string]string
+}
+
+// NewClientHelloFingerprints returns a ClientHelloFingerprints without connections.
+func NewClientHelloFingerprints() *ClientHelloFingerprints {
+	return &ClientHelloFingerprints{conns: map[string]string{}}
+}
+
+// GetConfigForClient records the fingerprint of the ClientHello. It returns a nil config, such that the server's
+// config is used for the connection.
+func (f *ClientHelloFingerprints) GetConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	if hello.Conn == nil {
+		return nil, nil
+	}
+	fingerprint := clientHelloFingerprint(hello)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.conns[hello.Conn.RemoteAddr().String()] = fingerprint
+	return nil, nil
+}
+
+// ConnState forgets the fingerprint of the connections that are closed or hijacked.
+func (f *ClientHelloFingerprints) ConnState(conn net.Conn, state http.ConnState) {
+	if state != http.StateClosed && state != http.StateHijacked {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.conns, conn.RemoteAddr().String())
+}
+
+// Fingerprint returns the fingerprint of the ClientHello of the request's connection, or the empty string if the
+// request was not received over TLS.
+func (f *ClientHelloFingerprints) Fingerprint(r *http.Request) string {
+	if r.TLS == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns[r.RemoteAddr]
+}
+
+// clientHelloFingerprint is the md5 hash of the ClientHello, in the format of JA3 without the extensions:
+// versions,ciphers,curves,points, where the values of each field are joined with dashes and GREASE values are ignored.
+func clientHelloFingerprint(hello *tls.ClientHelloInfo) string {
+	curves := make([]uint16, len(hello.SupportedCurves))
+	for i, curve := range hello.SupportedCurves {
+		curves[i] = uint16(curve)
+	}
+	points := make([]uint16, len(hello.SupportedPoints))
+	for i, point := range hello.SupportedPoints {
+		points[i] = uint16(point)
+	}
+
+	fields := []string{
+		joinTLSValues(hello.SupportedVersions),
+		joinTLSValues(hello.CipherSuites),
+		joinTLSValues(curves),
+		joinTLSValues(points),
+	}
+	sum := md5.Sum([]byte(strings.Join(fields, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+func joinTLSValues(values []uint16) string {
+	var b strings.Builder
+	for _, value := range values {
+		if isGREASE(value) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(strconv.Itoa(int(value)))
+	}
+	return b.String()
+}
+
+// isGREASE reports whether the value is one of the values reserved by RFC 8701, which clients send at random to
+// prevent servers from depending on the values they know.
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
@@ -0,0 +1,135 @@
+package muxter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaderFingerprint(t *testing.T) {
+	mux := New(Fingerprints(HeaderFingerprint()))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		io.WriteString(w, c.Fingerprint())
+	})
+
+	fingerprint := func(header http.Header) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header = header
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	browser := fingerprint(http.Header{"User-Agent": {"Mozilla/5.0"}, "Accept": {"text/html"}, "Cookie": {"a=1"}})
+	if browser == "" {
+		t.Fatal("expected a fingerprint")
+	}
+
+	testcases := []struct {
+		Name   string
+		Header http.Header
+		Same   bool
+	}{
+		{
+			Name:   "other cookie value",
+			Header: http.Header{"User-Agent": {"Mozilla/5.0"}, "Accept": {"text/html"}, "Cookie": {"a=2"}},
+			Same:   true,
+		},
+		{
+			Name:   "other user agent",
+			Header: http.Header{"User-Agent": {"curl/8.0"}, "Accept": {"text/html"}, "Cookie": {"a=1"}},
+		},
+		{
+			Name:   "other header names",
+			Header: http.Header{"User-Agent": {"Mozilla/5.0"}, "Accept": {"text/html"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if same := fingerprint(tc.Header) == browser; same != tc.Same {
+				t.Errorf("expected same fingerprint to be %v but got %v", tc.Same, same)
+			}
+		})
+	}
+}
+
+func TestFingerprintInheritance(t *testing.T) {
+	calls := 0
+	parent := New(Fingerprints(func(r *http.Request) string {
+		calls++
+		return "parent"
+	}))
+	child := New(Fingerprints(func(r *http.Request) string { return "child" }))
+
+	var logs bytes.Buffer
+	parent.Use(Logger(&logs, func(overview RespOverview) string { return overview.Fingerprint }))
+
+	var got string
+	child.HandleFunc("/api/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		got = c.Fingerprint()
+	})
+	parent.Handle("/api/", child)
+
+	parent.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/books", nil))
+
+	if got != "parent" {
+		t.Errorf("expected the fingerprint of the parent but got %q", got)
+	}
+	if calls != 1 {
+		t.Errorf("expected the fingerprint to be computed once but got %d", calls)
+	}
+	if logs.String() != "parent\n" {
+		t.Errorf("expected the fingerprint to be logged but got %q", logs.String())
+	}
+}
+
+func TestClientHelloFingerprints(t *testing.T) {
+	hellos := NewClientHelloFingerprints()
+
+	mux := New(Fingerprints(hellos.Fingerprint))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		io.WriteString(w, c.Fingerprint())
+	})
+
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{GetConfigForClient: hellos.GetConfigForClient}
+	server.Config.ConnState = hellos.ConnState
+	server.StartTLS()
+	defer server.Close()
+
+	fingerprint := func(config *tls.Config) string {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		config.RootCAs = transport.TLSClientConfig.RootCAs
+		transport.TLSClientConfig = config
+		defer transport.CloseIdleConnections()
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	first := fingerprint(&tls.Config{})
+	if len(first) != 32 {
+		t.Fatalf("expected an md5 fingerprint but got %q", first)
+	}
+	if second := fingerprint(&tls.Config{}); second != first {
+		t.Errorf("expected clients with the same config to share fingerprint %q but got %q", first, second)
+	}
+	if other := fingerprint(&tls.Config{MaxVersion: tls.VersionTLS12}); other == first {
+		t.Errorf("expected clients with other configs to have another fingerprint")
+	}
+}
+
+func TestJoinTLSValues(t *testing.T) {
+	if got := joinTLSValues([]uint16{0x0a0a, 771, 0xfafa, 772}); got != "771-772" {
+		t.Errorf("expected GREASE values to be ignored but got %q", got)
+	}
+}
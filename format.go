@@ -0,0 +1,79 @@
+package muxter
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/davidmdm/muxter/internal"
+)
+
+// formatExtensions are the extensions stripped from the paths of a subtree by the FormatExtensions option.
+type formatExtensions struct {
+	subtree    string
+	extensions []string
+}
+
+// FormatExtensions is a mux option that strips a trailing extension, such as the ".json" of /api/books/1.json, from
+// the path of requests under the subtree before they are routed. The extension, without its dot, is made available
+// as the "format" param, taking precedence over route params of the same name. It supports clients of Rails-style
+// URLs without registering every route with and without an extension. The extensions default to json, xml and csv.
+// The option can be given several times to configure several subtrees.
+//
+// Like Normalize the option rewrites the path of the request in ServeHTTP, so it applies to the mux requests enter
+// through and subtree is relative to it. Context.OriginalPath keeps the extension, Context.EffectivePath does not.
+//
+//	mux := muxter.New(muxter.FormatExtensions("/api/"))
+//	mux.HandleFunc("/api/books/:id", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+//		if c.Param("format") == "xml" {
+//			// ...
+//		}
+//	})
+func FormatExtensions(subtree string, extensions ...string) MuxOption {
+	if !strings.HasPrefix(subtree, "/") {
+		panic("muxter: format extensions subtree must start with a slash but got: " + subtree)
+	}
+	if len(extensions) == 0 {
+		extensions = []string{"json", "xml", "csv"}
+	}
+	exts := make([]string, len(extensions))
+	for i, ext := range extensions {
+		exts[i] = strings.TrimPrefix(ext, ".")
+	}
+
+	return func(m *Mux) {
+		m.formats = append(m.formats, formatExtensions{subtree: subtree, extensions: exts})
+	}
+}
+
+// stripFormat strips the extension of a subtree configured with FormatExtensions from the request path and adds it
+// to the params. It reports whether the path was rewritten.
+func (m *Mux) stripFormat(r *http.Request, params *[]internal.Param) bool {
+	for _, f := range m.formats {
+		if !strings.HasPrefix(r.URL.Path, f.subtree) {
+			continue
+		}
+		ext := pathExtension(r.URL.Path)
+		if ext == "" || !containsFold(f.extensions, ext) {
+			continue
+		}
+
+		r.URL.Path = r.URL.Path[:len(r.URL.Path)-len(ext)-1]
+		if strings.HasSuffix(r.URL.RawPath, "."+ext) {
+			r.URL.RawPath = r.URL.RawPath[:len(r.URL.RawPath)-len(ext)-1]
+		}
+		*params = append(*params, internal.Param{Key: "format", Value: strings.ToLower(ext)})
+		return true
+	}
+	return false
+}
+
+// pathExtension returns the extension of the last segment of the path without its dot. A segment made only of an
+// extension, such as /.json, has none.
+func pathExtension(path string) string {
+	segment := path[strings.LastIndexByte(path, '/')+1:]
+	dot := strings.LastIndexByte(segment, '.')
+	if dot <= 0 {
+		return ""
+	}
+	return segment[dot+1:]
+}
@@ -0,0 +1,83 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFormatExtensions(t *testing.T) {
+	mux := New(FormatExtensions("/api/"), FormatExtensions("/feeds/", ".rss"))
+
+	respond := func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte(c.Pattern() + " id=" + c.Param("id") + " format=" + c.Param("format") + " path=" + r.URL.Path + " original=" + c.OriginalPath() + " effective=" + c.EffectivePath()))
+	}
+	mux.HandleFunc("/api/books/:id", respond)
+	mux.HandleFunc("/api/books", respond)
+	mux.HandleFunc("/feeds/:name", respond)
+	mux.HandleFunc("/assets/", respond)
+
+	testcases := []struct {
+		Name         string
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:         "param",
+			Path:         "/api/books/1.json",
+			ExpectedCode: 200,
+			ExpectedBody: "/api/books/:id id=1 format=json path=/api/books/1 original=/api/books/1.json effective=/api/books/1",
+		},
+		{
+			Name:         "static",
+			Path:         "/api/books.CSV",
+			ExpectedCode: 200,
+			ExpectedBody: "/api/books id= format=csv path=/api/books original=/api/books.CSV effective=/api/books",
+		},
+		{
+			Name:         "no extension",
+			Path:         "/api/books/1",
+			ExpectedCode: 200,
+			ExpectedBody: "/api/books/:id id=1 format= path=/api/books/1 original=/api/books/1 effective=/api/books/1",
+		},
+		{
+			Name:         "other extension",
+			Path:         "/api/books/1.pdf",
+			ExpectedCode: 200,
+			ExpectedBody: "/api/books/:id id=1.pdf format= path=/api/books/1.pdf original=/api/books/1.pdf effective=/api/books/1.pdf",
+		},
+		{
+			Name:         "configured extensions",
+			Path:         "/feeds/news.rss",
+			ExpectedCode: 200,
+			ExpectedBody: "/feeds/:name id= format=rss path=/feeds/news original=/feeds/news.rss effective=/feeds/news",
+		},
+		{
+			Name:         "outside of subtrees",
+			Path:         "/assets/app.json",
+			ExpectedCode: 200,
+			ExpectedBody: "/assets/ id= format= path=/assets/app.json original=/assets/app.json effective=/assets/app.json",
+		},
+		{
+			Name:         "only an extension",
+			Path:         "/api/books/.json",
+			ExpectedCode: 200,
+			ExpectedBody: "/api/books/:id id=.json format= path=/api/books/.json original=/api/books/.json effective=/api/books/.json",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package muxter
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FragmentCacheOptions configures a FragmentCache.
+type FragmentCacheOptions struct {
+	// TTL is how long fragments are cached. It defaults to one minute.
+	TTL time.Duration
+	// MaxEntries is the maximum number of fragments cached, evicting the least recently used. It defaults to 1024.
+	MaxEntries int
+	// Clock expires cached fragments. It defaults to the system clock.
+	Clock Clock
+}
+
+// FragmentCache caches the rendered fragments of server-rendered pages, such as an expensive sidebar or the partial
+// listing the reviews of a book, keyed by the fragment's name and the pattern and params of the route rendering it.
+// Fragments are tagged with surrogate keys so that they are invalidated with the cached responses built from the same
+// content.
+type FragmentCache struct {
+	opts FragmentCacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   list.List
+	keys    map[string]map[string]struct{}
+}
+
+// NewFragmentCache returns a fragment cache configured by opts.
+func NewFragmentCache(opts FragmentCacheOptions) *FragmentCache {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1024
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	return &FragmentCache{
+		opts:    opts,
+		entries: map[string]*list.Element{},
+		keys:    map[string]map[string]struct{}{},
+	}
+}
+
+type fragmentEntry struct {
+	key     string
+	pattern string
+	keys    []string
+	body    []byte
+	expires time.Time
+}
+
+// Render writes the fragment with the name to w, rendering it with render unless it is cached for the route of the
+// request. Rendered fragments are tagged with the surrogate keys, which are also added to the response with
+// Context.SurrogateKeys such that invalidating a key with Cache.InvalidateKey and FragmentCache.InvalidateKey drops
+// both the pages and the fragments built from its content. Fragments are not cached if render fails.
+//
+//	err := fragments.Render(w, c, "reviews", func(w io.Writer) error {
+//		return templates.ExecuteTemplate(w, "reviews.html", reviews)
+//	}, "book-"+c.Param("id"))
+func (fc *FragmentCache) Render(w io.Writer, c Context, name string, render func(w io.Writer) error, keys ...string) error {
+	c.SurrogateKeys(keys...)
+
+	key := fragmentKey(c, name)
+
+	fc.mu.Lock()
+	if elem, ok := fc.entries[key]; ok {
+		entry := elem.Value.(*fragmentEntry)
+		if fc.opts.Clock.Now().Before(entry.expires) {
+			fc.order.MoveToFront(elem)
+			fc.mu.Unlock()
+			_, err := w.Write(entry.body)
+			return err
+		}
+		fc.remove(elem)
+	}
+	fc.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		return err
+	}
+
+	fc.store(&fragmentEntry{
+		key:     key,
+		pattern: c.Pattern(),
+		keys:    keys,
+		body:    buf.Bytes(),
+		expires: fc.opts.Clock.Now().Add(fc.opts.TTL),
+	})
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// InvalidateKey removes the fragments tagged with any of the surrogate keys and returns their number.
+func (fc *FragmentCache) InvalidateKey(keys ...string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var n int
+	for _, key := range keys {
+		for entryKey := range fc.keys[key] {
+			fc.remove(fc.entries[entryKey])
+			n++
+		}
+	}
+	return n
+}
+
+// InvalidatePattern removes the fragments rendered by the routes registered with the pattern, such as /books/:id, and
+// returns their number.
+func (fc *FragmentCache) InvalidatePattern(pattern string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var n int
+	for elem := fc.order.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*fragmentEntry).pattern == pattern {
+			fc.remove(elem)
+			n++
+		}
+		elem = next
+	}
+	return n
+}
+
+func (fc *FragmentCache) store(entry *fragmentEntry) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if elem, ok := fc.entries[entry.key]; ok {
+		fc.remove(elem)
+	}
+	fc.entries[entry.key] = fc.order.PushFront(entry)
+	for _, key := range entry.keys {
+		if fc.keys[key] == nil {
+			fc.keys[key] = map[string]struct{}{}
+		}
+		fc.keys[key][entry.key] = struct{}{}
+	}
+
+	for fc.order.Len() > fc.opts.MaxEntries {
+		fc.remove(fc.order.Back())
+	}
+}
+
+// remove removes the element from the cache. The cache's lock must be held.
+func (fc *FragmentCache) remove(elem *list.Element) {
+	entry := fc.order.Remove(elem).(*fragmentEntry)
+	delete(fc.entries, entry.key)
+	for _, key := range entry.keys {
+		delete(fc.keys[key], entry.key)
+		if len(fc.keys[key]) == 0 {
+			delete(fc.keys, key)
+		}
+	}
+}
+
+// fragmentKey returns the key of the fragment with the name for the route of the request: its name, pattern and
+// params.
+func fragmentKey(c Context, name string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte(0)
+	b.WriteString(c.Pattern())
+	c.checkOwner()
+	if c.params != nil {
+		for _, param := range *c.params {
+			b.WriteByte(0)
+			b.WriteString(param.Key)
+			b.WriteByte('=')
+			b.WriteString(param.Value)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package muxter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFragmentCache(t *testing.T) {
+	fragments := NewFragmentCache(FragmentCacheOptions{})
+	cache := NewCache(CacheOptions{HeuristicFreshness: true})
+
+	renders := map[string]int{}
+	var fail bool
+
+	mux := New()
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		err := fragments.Render(w, c, "reviews", func(w io.Writer) error {
+			if fail {
+				return errors.New("render failed")
+			}
+			renders[c.Param("id")]++
+			_, err := fmt.Fprintf(w, "reviews of %s #%d", c.Param("id"), renders[c.Param("id")])
+			return err
+		}, "book-"+c.Param("id"))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	})
+	mux.HandleFunc("/cached/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		fragments.Render(w, c, "reviews", func(w io.Writer) error {
+			_, err := io.WriteString(w, "cached page")
+			return err
+		}, "book-"+c.Param("id"))
+	}, cache.Middleware)
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	if body := get("/books/1"); body != "reviews of 1 #1" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if body := get("/books/1"); body != "reviews of 1 #1" {
+		t.Fatalf("expected fragment to be served from the cache but got %q", body)
+	}
+	if body := get("/books/2"); body != "reviews of 2 #1" {
+		t.Fatalf("expected fragment to be keyed by params but got %q", body)
+	}
+
+	if n := fragments.InvalidateKey("book-1"); n != 1 {
+		t.Fatalf("expected 1 fragment to be invalidated but got %d", n)
+	}
+	if body := get("/books/1"); body != "reviews of 1 #2" {
+		t.Fatalf("expected fragment to be rendered again after invalidation but got %q", body)
+	}
+
+	get("/cached/books/1")
+	if n := fragments.InvalidateKey("book-1"); n != 2 {
+		t.Errorf("expected fragments of both routes to be invalidated but got %d", n)
+	}
+	if n := cache.InvalidateKey("book-1"); n != 1 {
+		t.Errorf("expected the page to be tagged with the keys of its fragments but %d responses were invalidated", n)
+	}
+
+	if n := fragments.InvalidatePattern("/books/:id"); n != 1 {
+		t.Errorf("expected fragment of book 2 to be invalidated by pattern but got %d", n)
+	}
+
+	fail = true
+	if body := get("/books/3"); body != "render failed\n" {
+		t.Fatalf("expected render error but got %q", body)
+	}
+	fail = false
+	if body := get("/books/3"); body != "reviews of 3 #1" {
+		t.Errorf("expected failed fragment not to be cached but got %q", body)
+	}
+}
+
+func TestFragmentCacheEviction(t *testing.T) {
+	fragments := NewFragmentCache(FragmentCacheOptions{MaxEntries: 2})
+
+	var renders int
+	render := func(name string) {
+		fragments.Render(io.Discard, Context{}, name, func(w io.Writer) error {
+			renders++
+			return nil
+		})
+	}
+
+	render("a")
+	render("b")
+	render("a")
+	render("c") // evicts b, the least recently used
+
+	renders = 0
+	render("a")
+	if renders != 0 {
+		t.Errorf("expected a to remain cached")
+	}
+	render("b")
+	if renders != 1 {
+		t.Errorf("expected b to be evicted")
+	}
+}
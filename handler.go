@@ -3,18 +3,70 @@ package muxter
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/davidmdm/muxter/internal"
 )
 
 type Context struct {
-	params    *[]internal.Param
-	ogReqPath string
-	pattern   string
+	params     *[]internal.Param
+	ogReqPath  string
+	ogRawQuery string
+	// effectivePath is the path after the rewrites of FormatExtensions and StripDepth. It is empty until the path is
+	// rewritten.
+	effectivePath string
+	pattern       string
+	// partialPattern is the deepest pattern prefix matched by a request no route matched.
+	partialPattern string
+	route          *RouteInfo
+	locale         string
+	jsonErrors     bool
+	fallback       Handler
+	// extras are the fields few requests set, or nil if none is set. See Context.extra.
+	extras    *contextExtras
+	lifecycle *lifecycle
+	// epoch is the epoch of the lifecycle while the request is served, or zero if ownership is not checked.
+	epoch uint64
+}
+
+// contextExtras are the fields of a Context that few requests set. They are kept behind a pointer to keep Contexts
+// small, as they are copied into every handler call, and are copied on write to keep the value semantics of the
+// Context: handlers setting them do not change the Context of the handlers wrapping them.
+type contextExtras struct {
+	catalog  Catalog
+	webhooks *WebhookDispatcher
+	events   *EventBus
+	// methodMux is the innermost mux serving the request with a method policy, if any.
+	methodMux *Mux
+	// debug is the innermost mux serving the request in debug mode, if any.
+	debug       *Mux
+	fingerprint string
+	identity    *Identity
+	// baseURL is the BaseURL of the innermost mux serving the request that has one, if any.
+	baseURL *url.URL
+}
+
+var noExtras contextExtras
+
+// extra returns the extras of the Context for reading. It never returns nil.
+func (c Context) extra() *contextExtras {
+	if c.extras == nil {
+		return &noExtras
+	}
+	return c.extras
+}
+
+// editExtras returns a copy of the extras of the Context for writing, which the Context is set to use.
+func (c *Context) editExtras() *contextExtras {
+	extras := *c.extra()
+	c.extras = &extras
+	return &extras
 }
 
 // Param returns the param value for the key. If no param exists for the key the empty string is returned.
 func (c Context) Param(key string) string {
+	c.checkOwner()
 	for _, p := range *c.params {
 		if p.Key == key {
 			return p.Value
@@ -23,8 +75,31 @@ func (c Context) Param(key string) string {
 	return ""
 }
 
+func (c Context) lookupParam(key string) (string, bool) {
+	c.checkOwner()
+	if c.params == nil {
+		return "", false
+	}
+	for _, p := range *c.params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// reversePath generates the request path from the matched pattern and params. Rooted subtree patterns
+// cannot be reversed, in which case the original request path is used.
+func (c Context) reversePath() (string, error) {
+	if c.pattern == "" || strings.HasSuffix(c.pattern, "/") {
+		return c.ogReqPath, nil
+	}
+	return expandPattern(c.pattern, c.lookupParam)
+}
+
 // Params returns a copy of the param map
 func (c Context) Params() map[string]string {
+	c.checkOwner()
 	if c.params == nil {
 		return map[string]string{}
 	}
@@ -36,11 +111,65 @@ func (c Context) Params() map[string]string {
 	return paramMap
 }
 
+// OriginalPath returns the request path as it was received by the mux. Unlike the path of the request handed to
+// a nested mux or a handler behind StripDepth it is the externally visible path, suitable for Location headers and
+// canonical links.
+func (c Context) OriginalPath() string {
+	return c.ogReqPath
+}
+
+// EffectivePath returns the request path as seen by the current handler after the rewrites of the mux: the extension
+// stripped by FormatExtensions and the prefix stripped by StripDepth. When the path has not been rewritten it is the
+// same as OriginalPath. Normalize rewrites the path before it is recorded, such that both paths are normalized.
+func (c Context) EffectivePath() string {
+	if c.effectivePath == "" {
+		return c.ogReqPath
+	}
+	return c.effectivePath
+}
+
+// OriginalURL returns the path and query of the request as it was received by the mux, before any rewrites
+// such as the path stripping done by StripDepth when nesting muxes. It is the URL to use for logging.
+func (c Context) OriginalURL() *url.URL {
+	return &url.URL{Path: c.ogReqPath, RawQuery: c.ogRawQuery}
+}
+
+// requestURL returns the path and query of the request as received by the mux, or the URL of the request if it was
+// not served by a mux.
+func (c Context) requestURL(r *http.Request) *url.URL {
+	if c.ogReqPath == "" {
+		return r.URL
+	}
+	return c.OriginalURL()
+}
+
 // Pattern returns the registered route pattern that was matched.
 func (c Context) Pattern() string {
 	return c.pattern
 }
 
+// PartialPattern returns the pattern of the deepest segments matched by a request that no route matched, ending with
+// a slash, or the empty string if the request matched a route or no segment of any pattern. Given the routes
+// /api/:version/users and /api/:version/books, a request for /api/v2/authors is not found with the partial pattern
+// /api/:version/, and the param version is available to the not found handler:
+//
+//	mux.SetNotFoundHandlerFunc(func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+//		if c.PartialPattern() == "/api/:version/" {
+//			http.Error(w, "unknown resource of api "+c.Param("version"), http.StatusNotFound)
+//			return
+//		}
+//		http.NotFound(w, r)
+//	})
+func (c Context) PartialPattern() string {
+	return c.partialPattern
+}
+
+// Route returns the metadata of the matched route, or nil if no route was matched. For nested muxes it is the route
+// of the innermost mux.
+func (c Context) Route() *RouteInfo {
+	return c.route
+}
+
 //go:generate moq -out handler_mock_test.go --stub . Handler
 type Handler interface {
 	// ServeHTTPx is the equivalent of the standard http.Handler's ServeHTTP but includes the muxter Context
@@ -77,7 +206,13 @@ func Adaptor(h http.Handler, opts ...AdaptorOption) Handler {
 
 	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 		if !options.noContext {
-			*r = *r.WithContext(context.WithValue(r.Context(), cKey, c))
+			*r = *r.WithContext(context.WithValue(r.Context(), cKey, adaptedContext{
+				params:    c.params,
+				ogReqPath: c.ogReqPath,
+				pattern:   c.pattern,
+				lifecycle: c.lifecycle,
+				epoch:     c.epoch,
+			}))
 		}
 		h.ServeHTTP(w, r)
 	})
@@ -87,13 +222,28 @@ type ctxKetType struct{}
 
 var cKey ctxKetType
 
+// adaptedContext is the part of the Context read by the functions of standard handlers, which Adaptor stores in the
+// request's context rather than the whole Context to keep the allocation of every request small.
+type adaptedContext struct {
+	params    *[]internal.Param
+	ogReqPath string
+	pattern   string
+	lifecycle *lifecycle
+	epoch     uint64
+}
+
+// requestContext returns the Context stored in the request's context by Adaptor.
+func requestContext(r *http.Request) Context {
+	ac, _ := r.Context().Value(cKey).(adaptedContext)
+	return Context{params: ac.params, ogReqPath: ac.ogReqPath, pattern: ac.pattern, lifecycle: ac.lifecycle, epoch: ac.epoch}
+}
+
 // Param reads path params from the request
 func Param(r *http.Request, key string) string {
 	if r == nil {
 		return ""
 	}
-	c, _ := r.Context().Value(cKey).(Context)
-	return c.Param(key)
+	return requestContext(r).Param(key)
 }
 
 // Params returns all path params in a map. Prefer the simple Param to avoid memory allocations.
@@ -102,8 +252,7 @@ func Params(r *http.Request) map[string]string {
 	if r == nil {
 		return nil
 	}
-	c, _ := r.Context().Value(cKey).(Context)
-	return c.Params()
+	return requestContext(r).Params()
 }
 
 // Pattern returns the matched registered route pattern.
@@ -112,6 +261,14 @@ func Pattern(r *http.Request) string {
 	if r == nil {
 		return ""
 	}
-	c, _ := r.Context().Value(cKey).(Context)
-	return c.Pattern()
+	return requestContext(r).Pattern()
+}
+
+// OriginalPath returns the request path as it was received by the mux.
+// Only works on standard handlers that have been through the Adaptor interface. Prefer using muxter.Context directly.
+func OriginalPath(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return requestContext(r).OriginalPath()
 }
@@ -39,3 +39,28 @@ func TestStdAdaptor(t *testing.T) {
 
 	mux.ServeHTTP(w, r)
 }
+
+func TestOriginalPath(t *testing.T) {
+	var fromContext, fromRequest string
+
+	child := New()
+	child.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		fromContext = c.OriginalPath()
+	})
+	child.StandardHandle("/std/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromRequest = OriginalPath(r)
+	}))
+
+	mux := New()
+	mux.Handle("/api/", StripDepth(1, child))
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/books/1", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/std/2", nil))
+
+	if fromContext != "/api/books/1" {
+		t.Errorf("expected original path from context to be %q but got %q", "/api/books/1", fromContext)
+	}
+	if fromRequest != "/api/std/2" {
+		t.Errorf("expected original path from request to be %q but got %q", "/api/std/2", fromRequest)
+	}
+}
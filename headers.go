@@ -0,0 +1,99 @@
+package muxter
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// headerWriter rewrites the header of the response before it is sent.
+type headerWriter struct {
+	http.ResponseWriter
+	rewrite func(http.Header)
+	done    bool
+}
+
+func (w *headerWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *headerWriter) rewriteHeader() {
+	if w.done {
+		return
+	}
+	w.done = true
+	w.rewrite(w.ResponseWriter.Header())
+}
+
+func (w *headerWriter) WriteHeader(code int) {
+	w.rewriteHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.rewriteHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerWriter) Flush() {
+	w.rewriteHeader()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection of the underlying writer, such that protocol upgrades like WebSockets keep working.
+// The header is not rewritten since the response is written by the caller.
+func (w *headerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("muxter: response writer does not support hijacking")
+}
+
+// rewriteResponseHeader applies the mux's response header rewrites, such as CookieDefaults and ScrubResponseHeaders.
+func (m *Mux) rewriteResponseHeader(header http.Header) {
+	for _, rewrite := range m.rewriteHeaders {
+		rewrite(header)
+	}
+}
+
+// HeaderScrubber configures the ScrubResponseHeaders option. Header names are case insensitive and a name ending
+// with "*" matches every header with the preceding prefix, such as "X-Debug-*".
+type HeaderScrubber struct {
+	// Deny are the headers removed from responses.
+	Deny []string
+	// Allow, when not empty, are the only headers responses may contain. Other headers are removed.
+	Allow []string
+}
+
+// ScrubResponseHeaders removes headers from every response served through the mux, including responses of mounted
+// third party handlers and proxies, such as X-Powered-By, Server or internal debugging headers.
+//
+//	muxter.New(muxter.ScrubResponseHeaders(muxter.HeaderScrubber{Deny: []string{"Server", "X-Powered-By", "X-Debug-*"}}))
+func ScrubResponseHeaders(scrubber HeaderScrubber) MuxOption {
+	return func(m *Mux) {
+		m.rewriteHeaders = append(m.rewriteHeaders, func(header http.Header) {
+			for key := range header {
+				if matchesHeader(key, scrubber.Deny) || (len(scrubber.Allow) > 0 && !matchesHeader(key, scrubber.Allow)) {
+					delete(header, key)
+				}
+			}
+		})
+	}
+}
+
+func matchesHeader(key string, names []string) bool {
+	for _, name := range names {
+		if prefix := strings.TrimSuffix(name, "*"); prefix != name {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestScrubResponseHeaders(t *testing.T) {
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx")
+		w.Header().Set("X-Powered-By", "PHP")
+		w.Header().Set("X-Debug-Query-Count", "12")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("ok"))
+	})
+
+	testcases := []struct {
+		Name     string
+		Scrubber HeaderScrubber
+		Expected []string
+	}{
+		{
+			Name:     "deny",
+			Scrubber: HeaderScrubber{Deny: []string{"server", "X-Powered-By", "X-Debug-*"}},
+			Expected: []string{"Cache-Control", "Content-Type"},
+		},
+		{
+			Name:     "allow",
+			Scrubber: HeaderScrubber{Allow: []string{"Content-Type", "X-Debug-*"}},
+			Expected: []string{"Content-Type", "X-Debug-Query-Count"},
+		},
+		{
+			Name:     "allow and deny",
+			Scrubber: HeaderScrubber{Allow: []string{"Content-Type", "X-Debug-*"}, Deny: []string{"x-debug-*"}},
+			Expected: []string{"Content-Type"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mux := New(ScrubResponseHeaders(tc.Scrubber))
+			mux.StandardHandle("/legacy/", upstream)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/legacy/index.php", nil))
+
+			var keys []string
+			for key := range w.Header() {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			if !reflect.DeepEqual(keys, tc.Expected) {
+				t.Errorf("expected headers %v but got %v", tc.Expected, keys)
+			}
+			if w.Body.String() != "ok" {
+				t.Errorf("expected body to be %q but got %q", "ok", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestScrubResponseHeadersWithoutWrite(t *testing.T) {
+	mux := New(ScrubResponseHeaders(HeaderScrubber{Deny: []string{"X-Powered-By"}}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("X-Powered-By", "muxter")
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if value := w.Header().Get("X-Powered-By"); value != "" {
+		t.Errorf("expected X-Powered-By to be scrubbed but got %q", value)
+	}
+}
+
+func TestScrubResponseHeadersHijack(t *testing.T) {
+	mux := New(ScrubResponseHeaders(HeaderScrubber{Deny: []string{"X-Powered-By"}}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Errorf("expected response writer to be a hijacker")
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if body, _ := io.ReadAll(resp.Body); string(body) != "hijacked" {
+		t.Errorf("expected hijacked response but got %q", body)
+	}
+}
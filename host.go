@@ -0,0 +1,46 @@
+package muxter
+
+import (
+	"net"
+	"strings"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+)
+
+// hostSegment is the first segment of the tree keys of host patterns. Host patterns are stored in the same tree as
+// path patterns by turning the labels of their host into leading path segments, such that the pattern
+// "//:tenant.example.com/api" is stored as "/\x00/:tenant/example/com/api". Request paths starting with the
+// segment are rejected so that host routes can only be matched by their host.
+const hostSegment = "/\x00"
+
+// routeKey returns the key the pattern is stored at in the tree.
+func routeKey(pattern string) string {
+	host, path, ok := syntax.SplitHost(pattern)
+	if !ok {
+		return pattern
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, ":") && !strings.HasPrefix(label, "#") {
+			labels[i] = strings.ToLower(label)
+		}
+	}
+	return hostSegment + "/" + strings.Join(labels, "/") + path
+}
+
+// hostKey returns the tree key of the request path on the host, and false if the host cannot be matched.
+func hostKey(host, path string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || strings.IndexByte(host, '/') != -1 {
+		return "", false
+	}
+	return hostSegment + "/" + strings.ReplaceAll(strings.ToLower(host), ".", "/") + path, true
+}
+
+// isHostPattern reports whether the pattern is a host pattern.
+func isHostPattern(pattern string) bool {
+	return strings.HasPrefix(pattern, "//")
+}
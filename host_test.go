@@ -0,0 +1,123 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostPatterns(t *testing.T) {
+	mux := New()
+
+	handler := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Write([]byte(name + " " + c.Pattern() + " tenant=" + c.Param("tenant") + " version=" + c.Param("version") + " rest=" + c.Param("rest")))
+		}
+	}
+
+	mux.Handle("//:tenant.example.com/api/:version/*rest", handler("tenant-api"), Name("tenant-api"))
+	mux.Handle("//admin.example.com/", handler("admin"))
+	mux.Handle("//Status.Example.com", handler("status"))
+	mux.Handle("/api/:version/*rest", handler("api"))
+	mux.Handle("/", handler("root"))
+
+	testcases := []struct {
+		Name         string
+		Host         string
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:         "tenant host",
+			Host:         "acme.example.com",
+			Path:         "/api/v1/books/1",
+			ExpectedCode: 200,
+			ExpectedBody: "tenant-api //:tenant.example.com/api/:version/*rest tenant=acme version=v1 rest=books/1",
+		},
+		{
+			Name:         "host with port and different case",
+			Host:         "ACME.Example.com:8080",
+			Path:         "/api/v2/x",
+			ExpectedCode: 200,
+			ExpectedBody: "tenant-api //:tenant.example.com/api/:version/*rest tenant=acme version=v2 rest=x",
+		},
+		{
+			Name:         "static host takes precedence over wildcard host",
+			Host:         "admin.example.com",
+			Path:         "/api/v1/users",
+			ExpectedCode: 200,
+			ExpectedBody: "admin //admin.example.com/ tenant= version= rest=",
+		},
+		{
+			Name:         "host pattern without path",
+			Host:         "status.example.com",
+			Path:         "/healthz",
+			ExpectedCode: 200,
+			ExpectedBody: "status //Status.Example.com/ tenant= version= rest=",
+		},
+		{
+			Name:         "path route for unmatched path on matched host",
+			Host:         "acme.example.com",
+			Path:         "/about",
+			ExpectedCode: 200,
+			ExpectedBody: "root / tenant= version= rest=",
+		},
+		{
+			Name:         "path route for other hosts",
+			Host:         "example.org",
+			Path:         "/api/v1/books/1",
+			ExpectedCode: 200,
+			ExpectedBody: "api /api/:version/*rest tenant= version=v1 rest=books/1",
+		},
+		{
+			Name:         "nested subdomain does not match wildcard label",
+			Host:         "a.b.example.com",
+			Path:         "/api/v1/books/1",
+			ExpectedCode: 200,
+			ExpectedBody: "api /api/:version/*rest tenant= version=v1 rest=books/1",
+		},
+		{
+			Name:         "host segment in path",
+			Host:         "example.org",
+			Path:         "/\x00/acme/example/com/api/v1/x",
+			ExpectedCode: 404,
+			ExpectedBody: "Not Found\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = tc.Host
+			r.URL.Path = tc.Path
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+
+	url, err := mux.URL("tenant-api", "tenant", "acme", "version", "v1", "rest", "books/1")
+	if err != nil {
+		t.Fatalf("unexpected error generating url: %v", err)
+	}
+	if expected := "//acme.example.com/api/v1/books/1"; url != expected {
+		t.Errorf("expected url %q but got %q", expected, url)
+	}
+}
+
+func TestHostPatternWithoutHost(t *testing.T) {
+	defer func() {
+		if recovered := recover(); recovered != "muxter: host pattern must have a host but got: ///api" {
+			t.Errorf("unexpected panic: %v", recovered)
+		}
+	}()
+	New().Handle("///api", HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {}))
+}
@@ -0,0 +1,68 @@
+package muxter
+
+import "net/http"
+
+// Identity is the authenticated user of a request, established by authentication middlewares such as OIDC.
+type Identity struct {
+	// Subject is the unique identifier of the user at the identity provider.
+	Subject string `json:"sub"`
+	// Email is the email address of the user, if known.
+	Email string `json:"email,omitempty"`
+	// Name is the display name of the user, if known.
+	Name string `json:"name,omitempty"`
+	// Groups are the groups the user belongs to, if known.
+	Groups []string `json:"groups,omitempty"`
+	// Scopes are the scopes granted to the client on behalf of the user.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Identity returns the authenticated user of the request, or nil if the request was not authenticated by a muxter
+// authentication middleware, such as anonymous requests let through by middlewares in optional mode.
+func (c Context) Identity() *Identity {
+	return c.extra().identity
+}
+
+// HasScopes reports whether all of the scopes were granted.
+func (id *Identity) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+		for _, s := range id.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireScopes is a registration option requiring the request's Identity to have been granted all of the scopes.
+// Requests without an identity are answered with 401 Unauthorized and requests missing a scope with 403 Forbidden.
+// The scopes are recorded in the route's RouteInfo so that they can be documented, and are requested by the OIDC
+// middleware when authenticating users for the route. The authentication middleware must precede it.
+//
+//	mux.HandleFunc("/books", listBooks, muxter.RequireScopes("books:read"))
+func RequireScopes(scopes ...string) Middleware {
+	return func(h Handler) Handler {
+		return routeOption{
+			Handler: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				identity := c.Identity()
+				if identity == nil {
+					writeStatus(w, c, http.StatusUnauthorized)
+					return
+				}
+				if !identity.HasScopes(scopes...) {
+					writeStatus(w, c, http.StatusForbidden)
+					return
+				}
+				h.ServeHTTPx(w, r, c)
+			}),
+			apply: func(ri *RouteInfo) {
+				ri.Scopes = appendTags(ri.Scopes, scopes...)
+			},
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRequireScopes(t *testing.T) {
+	authenticate := func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if scopes, ok := r.Header["X-Scopes"]; ok {
+				c.editExtras().identity = &Identity{Subject: "user-1", Scopes: scopes}
+			}
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+
+	mux := New()
+	mux.Use(authenticate)
+	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {}, RequireScopes("books:read", "books:write"))
+
+	if scopes := mux.Routes()[0].Scopes; !reflect.DeepEqual(scopes, []string{"books:read", "books:write"}) {
+		t.Errorf("expected the scopes to be recorded in the route but got %q", scopes)
+	}
+
+	testcases := []struct {
+		Name         string
+		Scopes       []string
+		ExpectedCode int
+	}{
+		{Name: "unauthenticated", ExpectedCode: 401},
+		{Name: "missing scope", Scopes: []string{"books:read"}, ExpectedCode: 403},
+		{Name: "granted", Scopes: []string{"openid", "books:write", "books:read"}, ExpectedCode: 200},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/books", nil)
+			if tc.Scopes != nil {
+				r.Header["X-Scopes"] = tc.Scopes
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestIdentityIsCopiedOnWrite(t *testing.T) {
+	mux := New()
+	mux.Use(func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			h.ServeHTTPx(w, r, c)
+			if c.Identity() != nil {
+				t.Error("expected the identity set by a wrapped middleware not to leak into the wrapping one")
+			}
+		})
+	})
+	mux.Use(func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			c.editExtras().identity = &Identity{Subject: "user-1"}
+			h.ServeHTTPx(w, r, c)
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if id := c.Identity(); id == nil || id.Subject != "user-1" {
+			t.Errorf("expected the identity of user-1 but got %v", id)
+		}
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
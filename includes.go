@@ -0,0 +1,121 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// IncludeOptions configures the middleware returned by Mux.Includes.
+type IncludeOptions struct {
+	// MaxIncludes is the maximum number of include directives resolved in a response. It defaults to 32.
+	MaxIncludes int
+	// MaxDepth is the maximum nesting of includes within included fragments. It defaults to 3.
+	MaxDepth int
+}
+
+var includeDirective = regexp.MustCompile(`<esi:include\s+src="([^"]*)"(\s+onerror="continue")?\s*/>`)
+
+type includeDepthKey struct{}
+
+// Includes returns a middleware that composes HTML responses from fragments, in the style of edge side includes.
+// Directives of the form <esi:include src="/fragments/header"/> in successful text/html responses are replaced by the
+// body of a GET request for the src dispatched through the mux. Fragment requests inherit the headers of the
+// request, such as its cookies. This allows fragments to be cached independently, for example with different cache
+// policies for the page and for personalized fragments.
+//
+// If a fragment cannot be served successfully the response is an internal server error, unless the directive has
+// the attribute onerror="continue" in which case it is removed. Fragments served by routes that also use the
+// middleware are composed recursively up to MaxDepth. Responses are buffered to be composed.
+func (m *Mux) Includes(opts IncludeOptions) Middleware {
+	if opts.MaxIncludes <= 0 {
+		opts.MaxIncludes = 32
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 3
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			depth, _ := r.Context().Value(includeDepthKey{}).(int)
+
+			rec := &dispatchRecorder{header: w.Header()}
+			h.ServeHTTPx(rec, r, c)
+			rec.WriteHeader(http.StatusOK)
+
+			body := rec.body.Bytes()
+
+			mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			if rec.code == http.StatusOK && mediaType == "text/html" && depth < opts.MaxDepth {
+				composed, err := m.resolveIncludes(r, body, depth, opts.MaxIncludes)
+				if err != nil {
+					writeStatus(w, c, http.StatusInternalServerError)
+					return
+				}
+				body = composed
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+
+			w.WriteHeader(rec.code)
+			w.Write(body)
+		})
+	}
+}
+
+func (m *Mux) resolveIncludes(r *http.Request, body []byte, depth, maxIncludes int) ([]byte, error) {
+	matches := includeDirective.FindAllSubmatchIndex(body, -1)
+	if len(matches) == 0 {
+		return body, nil
+	}
+	if len(matches) > maxIncludes {
+		return nil, fmt.Errorf("response has %d includes exceeding the maximum of %d", len(matches), maxIncludes)
+	}
+
+	ctx := context.WithValue(r.Context(), includeDepthKey{}, depth+1)
+
+	var composed bytes.Buffer
+	var last int
+	for _, match := range matches {
+		composed.Write(body[last:match[0]])
+		last = match[1]
+
+		// The src is an HTML attribute value, in which characters such as & may be escaped as character references.
+		src := html.UnescapeString(string(body[match[2]:match[3]]))
+		continueOnError := match[4] != -1
+
+		fragment, err := m.fragment(ctx, r, src)
+		if err != nil {
+			if continueOnError {
+				continue
+			}
+			return nil, err
+		}
+		composed.Write(fragment)
+	}
+	composed.Write(body[last:])
+
+	return composed.Bytes(), nil
+}
+
+func (m *Mux) fragment(ctx context.Context, r *http.Request, src string) ([]byte, error) {
+	fr, err := newSubRequest(ctx, r, http.MethodGet, src, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	fr.Header.Del("Accept-Encoding")
+
+	resp, err := m.dispatch(fr)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("include %s responded with status %d", src, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
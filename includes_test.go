@@ -0,0 +1,69 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	mux := New()
+	includes := mux.Includes(IncludeOptions{MaxDepth: 2})
+
+	html := func(body string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, body)
+		}
+	}
+
+	mux.GetFunc("/page", html(`<main><esi:include src="/fragments/user"/>|<esi:include src="/fragments/nav" /></main>`), includes)
+	mux.GetFunc("/optional", html(`<main><esi:include src="/fragments/missing" onerror="continue"/></main>`), includes)
+	mux.GetFunc("/broken", html(`<main><esi:include src="/fragments/missing"/></main>`), includes)
+	mux.GetFunc("/recursive", html(`[<esi:include src="/recursive"/>]`), includes)
+	mux.GetFunc("/escaped", html(`<main><esi:include src="/fragments/query?a=1&amp;b=2"/></main>`), includes)
+	mux.GetFunc("/text", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, `<esi:include src="/fragments/nav"/>`)
+	}, includes)
+
+	mux.GetFunc("/fragments/user", func(w http.ResponseWriter, r *http.Request, c Context) {
+		cookie, _ := r.Cookie("user")
+		io.WriteString(w, "hello "+cookie.Value)
+	})
+	mux.GetFunc("/fragments/nav", html(`<nav><esi:include src="/fragments/links"/></nav>`), includes)
+	mux.GetFunc("/fragments/links", html(`links`))
+	mux.GetFunc("/fragments/query", func(w http.ResponseWriter, r *http.Request, c Context) {
+		io.WriteString(w, r.URL.Query().Get("a")+","+r.URL.Query().Get("b"))
+	})
+
+	testcases := []struct {
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Path: "/page", ExpectedCode: 200, ExpectedBody: "<main>hello bob|<nav>links</nav></main>"},
+		{Path: "/optional", ExpectedCode: 200, ExpectedBody: "<main></main>"},
+		{Path: "/broken", ExpectedCode: 500, ExpectedBody: "Internal Server Error\n"},
+		{Path: "/recursive", ExpectedCode: 200, ExpectedBody: `[[[<esi:include src="/recursive"/>]]]`},
+		{Path: "/escaped", ExpectedCode: 200, ExpectedBody: "<main>1,2</main>"},
+		{Path: "/text", ExpectedCode: 200, ExpectedBody: `<esi:include src="/fragments/nav"/>`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", tc.Path, nil)
+			r.AddCookie(&http.Cookie{Name: "user", Value: "bob"})
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+		})
+	}
+}
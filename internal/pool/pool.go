@@ -4,62 +4,146 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 
 	"github.com/davidmdm/muxter/internal"
 )
 
+// counting reports whether the pools count their objects, such that requests are spared the atomic operations of the
+// counters until they are needed.
+var counting atomic.Bool
+
+// EnableCounters starts counting the objects taken from and returned to the pools.
+func EnableCounters() {
+	counting.Store(true)
+}
+
+func count(counter *atomic.Uint64) {
+	if counting.Load() {
+		counter.Add(1)
+	}
+}
+
+// Counters count the objects taken from and returned to a pool once enabled.
+type Counters struct {
+	// Gets is the number of objects taken from the pool.
+	Gets atomic.Uint64
+	// Puts is the number of objects returned to the pool.
+	Puts atomic.Uint64
+	// Allocs is the number of objects allocated because the pool was empty.
+	Allocs atomic.Uint64
+	// Oversized is the number of objects that outgrew the capacity of the pool and were not returned to it.
+	Oversized atomic.Uint64
+}
+
+// DefaultParamCapacity is the capacity of the param slices of the pool unless set otherwise.
+const DefaultParamCapacity = 12
+
+// ParamSet is the params of a request, pooled along with the state the request keeps with them, such that serving a
+// request takes a single object from the pool.
+type ParamSet struct {
+	List []internal.Param
+	// State is kept with the params when they are returned to the pool and is nil for newly allocated params.
+	State interface{}
+}
+
 type ParamPool struct {
-	pool *sync.Pool
+	pool     *sync.Pool
+	capacity *atomic.Int64
+	Counters *Counters
 }
 
-func (p ParamPool) Get() *[]internal.Param {
-	params := p.pool.Get().(*[]internal.Param)
-	*params = (*params)[:0]
+func (p ParamPool) Get() *ParamSet {
+	count(&p.Counters.Gets)
+	params := p.pool.Get().(*ParamSet)
+	params.List = params.List[:0]
 	return params
 }
 
-func (p ParamPool) Put(params *[]internal.Param) {
+// Put returns the params to the pool. Params past the capacity of the pool, reallocated by routes with more params,
+// are dropped along with their state such that the pool does not retain the slices of its largest requests.
+func (p ParamPool) Put(params *ParamSet) {
 	if params == nil {
 		return
 	}
+	if int64(cap(params.List)) > p.capacity.Load() {
+		count(&p.Counters.Oversized)
+		return
+	}
+	count(&p.Counters.Puts)
 	p.pool.Put(params)
 }
 
-var Params = ParamPool{
-	pool: &sync.Pool{New: func() interface{} {
-		p := make([]internal.Param, 12)
-		return &p
-	}},
+// SetCapacity sets the capacity of the param slices allocated by the pool.
+func (p ParamPool) SetCapacity(capacity int) {
+	p.capacity.Store(int64(capacity))
+}
+
+// Capacity returns the capacity of the param slices allocated by the pool.
+func (p ParamPool) Capacity() int {
+	return int(p.capacity.Load())
+}
+
+var Params = newParamPool()
+
+func newParamPool() ParamPool {
+	p := ParamPool{capacity: new(atomic.Int64), Counters: new(Counters)}
+	p.capacity.Store(DefaultParamCapacity)
+	p.pool = &sync.Pool{New: func() interface{} {
+		count(&p.Counters.Allocs)
+		return &ParamSet{List: make([]internal.Param, 0, p.capacity.Load())}
+	}}
+	return p
 }
 
 type RequestPool struct {
-	pool *sync.Pool
+	pool     *sync.Pool
+	Counters *Counters
 }
 
 func (pool RequestPool) Get() *http.Request {
+	count(&pool.Counters.Gets)
 	return pool.pool.Get().(*http.Request)
 }
 
 func (pool RequestPool) Put(r *http.Request) {
+	count(&pool.Counters.Puts)
 	pool.pool.Put(r)
 }
 
-var Requests = RequestPool{
-	&sync.Pool{New: func() any { return new(http.Request) }},
-}
+var Requests = func() RequestPool {
+	counters := new(Counters)
+	return RequestPool{
+		pool: &sync.Pool{New: func() any {
+			count(&counters.Allocs)
+			return new(http.Request)
+		}},
+		Counters: counters,
+	}
+}()
 
 type URLPool struct {
-	pool *sync.Pool
+	pool     *sync.Pool
+	Counters *Counters
 }
 
 func (pool URLPool) Get() *url.URL {
+	count(&pool.Counters.Gets)
 	return pool.pool.Get().(*url.URL)
 }
 
 func (pool URLPool) Put(r *url.URL) {
+	count(&pool.Counters.Puts)
 	pool.pool.Put(r)
 }
 
-var URL = URLPool{
-	&sync.Pool{New: func() any { return new(url.URL) }},
-}
+var URL = func() URLPool {
+	counters := new(Counters)
+	return URLPool{
+		pool: &sync.Pool{New: func() any {
+			count(&counters.Allocs)
+			return new(url.URL)
+		}},
+		Counters: counters,
+	}
+}()
@@ -0,0 +1,123 @@
+// Package syntax holds the grammar of muxter patterns, shared by the mux and the tools that read patterns, such as
+// the paramcheck analyzer, so that they cannot drift apart.
+package syntax
+
+import "strings"
+
+// The metacharacters of the pattern syntax, as documented by the muxter package.
+const (
+	Wildcard   = ':'
+	Expression = '#'
+	Catchall   = '*'
+	Escape     = '\\'
+)
+
+// Metacharacters is the set of the metacharacters of the pattern syntax.
+const Metacharacters = ":#*\\"
+
+// IsMeta reports whether c is a metacharacter of the pattern syntax.
+func IsMeta(c byte) bool {
+	return c == Wildcard || c == Expression || c == Catchall || c == Escape
+}
+
+// IndexMeta returns the index of the first metacharacter of the pattern that is not escaped, or -1 if there is none.
+func IndexMeta(pattern string) int {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case Escape:
+			if i+1 < len(pattern) && IsMeta(pattern[i+1]) {
+				i++
+			}
+		case Wildcard, Expression, Catchall:
+			return i
+		}
+	}
+	return -1
+}
+
+// Unescape removes the escapes of the static part of a pattern.
+func Unescape(static string) string {
+	if strings.IndexByte(static, Escape) == -1 {
+		return static
+	}
+	var b strings.Builder
+	b.Grow(len(static))
+	for i := 0; i < len(static); i++ {
+		if static[i] == Escape && i+1 < len(static) && IsMeta(static[i+1]) {
+			i++
+		}
+		b.WriteByte(static[i])
+	}
+	return b.String()
+}
+
+// SplitHost splits a host pattern of the form "//host/path" into its host and path. The path defaults to "/".
+func SplitHost(pattern string) (host, path string, ok bool) {
+	if !strings.HasPrefix(pattern, "//") {
+		return "", pattern, false
+	}
+	host, path = pattern[2:], "/"
+	if idx := strings.IndexByte(host, '/'); idx != -1 {
+		host, path = host[:idx], host[idx:]
+	}
+	return host, path, true
+}
+
+// ExpressionEnd returns the end of the regexp param the pattern starts with, that is the index of its first slash
+// that is not escaped, or the length of the pattern if there is none.
+func ExpressionEnd(pattern string) int {
+	for i := 1; i < len(pattern); i++ {
+		if pattern[i] == '/' && pattern[i-1] != Escape {
+			return i
+		}
+	}
+	return len(pattern)
+}
+
+// Params returns the keys of the params of the pattern in order, including the labels of host patterns that start
+// with a metacharacter. Regexp params without an expression are skipped.
+func Params(pattern string) []string {
+	var keys []string
+
+	if host, path, ok := SplitHost(pattern); ok {
+		for _, label := range strings.Split(host, ".") {
+			switch {
+			case strings.HasPrefix(label, string(Wildcard)):
+				keys = append(keys, label[1:])
+			case strings.HasPrefix(label, string(Expression)):
+				if key, _, ok := strings.Cut(label[1:], ":"); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+		pattern = path
+	}
+
+	for {
+		idx := IndexMeta(pattern)
+		if idx == -1 {
+			return keys
+		}
+		pattern = pattern[idx:]
+
+		switch pattern[0] {
+		case Wildcard:
+			end := strings.IndexByte(pattern, '/')
+			if end == -1 {
+				end = len(pattern)
+			}
+			keys = append(keys, pattern[1:end])
+			pattern = pattern[end:]
+
+		case Expression:
+			end := ExpressionEnd(pattern)
+			if key, _, ok := strings.Cut(pattern[1:end], ":"); ok {
+				keys = append(keys, key)
+			}
+			pattern = pattern[end:]
+
+		case Catchall:
+			return append(keys, pattern[1:])
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package muxter
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwsHeader is the protected header of a JSON Web Signature.
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the RSA or ECDSA public key described by the JWK.
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// curveBits are the sizes of the curves of the ECDSA algorithms by their hash, such that ES256 uses P-256.
+var curveBits = map[crypto.Hash]int{crypto.SHA256: 256, crypto.SHA384: 384, crypto.SHA512: 521}
+
+// verifyJWS verifies the signature of a JWS in compact serialization with the key returned for its header, and
+// returns its payload. Only asymmetric algorithms are supported, so that a token cannot be forged by signing it with
+// a public key as an HMAC secret.
+func verifyJWS(token string, key func(jwsHeader) (crypto.PublicKey, error)) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+
+	var h hash.Hash
+	var hashFunc crypto.Hash
+	switch header.Alg[min(2, len(header.Alg)):] {
+	case "256":
+		h, hashFunc = sha256.New(), crypto.SHA256
+	case "384":
+		h, hashFunc = sha512.New384(), crypto.SHA384
+	case "512":
+		h, hashFunc = sha512.New(), crypto.SHA512
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	digest := h.Sum(nil)
+
+	pub, err := key(header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch family := header.Alg[:2]; pub := pub.(type) {
+	case *rsa.PublicKey:
+		switch family {
+		case "RS":
+			err = rsa.VerifyPKCS1v15(pub, hashFunc, digest, signature)
+		case "PS":
+			err = rsa.VerifyPSS(pub, hashFunc, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		default:
+			err = fmt.Errorf("algorithm %q cannot be used with an RSA key", header.Alg)
+		}
+	case *ecdsa.PublicKey:
+		bits := pub.Curve.Params().BitSize
+		size := (bits + 7) / 8
+		if family != "ES" || len(signature) != 2*size || bits != curveBits[hashFunc] {
+			err = fmt.Errorf("invalid %s signature for an ECDSA key", header.Alg)
+			break
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			err = errors.New("invalid signature")
+		}
+	default:
+		err = fmt.Errorf("unsupported key type %T", pub)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+	return payload, nil
+}
+
+// tokenClaims are the claims of a JWT identifying a user, such as an ID token.
+type tokenClaims struct {
+	Issuer          string          `json:"iss"`
+	Subject         string          `json:"sub"`
+	Audience        audience        `json:"aud"`
+	AuthorizedParty string          `json:"azp"`
+	Expires         float64         `json:"exp"`
+	Nonce           string          `json:"nonce"`
+	Email           string          `json:"email"`
+	Name            string          `json:"name"`
+	Groups          json.RawMessage `json:"groups"`
+}
+
+// jwtClockSkew is the leeway given to the expiry of tokens to account for clock differences with their issuer.
+const jwtClockSkew = time.Minute
+
+// verify verifies the registered claims of the token: its issuer, audience, expiry and subject.
+func (claims tokenClaims) verify(issuer, aud string) error {
+	switch {
+	case claims.Issuer != issuer:
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	case !claims.Audience.contains(aud):
+		return errors.New("token is not intended for the audience")
+	case time.Now().Add(-jwtClockSkew).Unix() >= int64(claims.Expires):
+		return errors.New("token is expired")
+	case claims.Subject == "":
+		return errors.New("token has no subject")
+	}
+	return nil
+}
+
+// groups returns the groups claim. Groups are not a standard claim and some issuers use objects rather than names,
+// which are ignored.
+func (claims tokenClaims) groups() []string {
+	var groups []string
+	_ = json.Unmarshal(claims.Groups, &groups)
+	return groups
+}
+
+// audience is the aud claim, which is either a string or an array of strings.
+type audience []string
+
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(aud))
+}
+
+func (aud audience) contains(s string) bool {
+	for _, a := range aud {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
+
+// keySet is a JSON Web Key Set fetched from a URL. The set is refetched when a key is unknown, at most once a
+// minute, so that keys rotated by the issuer are picked up. Failed fetches keep the last keys fetched and are retried
+// after a backoff rather than by every request, and concurrent requests share a single fetch.
+type keySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	// failures is the number of consecutive failed fetches, err the error of the last one and retry the time before
+	// which no fetch is attempted again.
+	failures int
+	err      error
+	retry    time.Time
+	// flight is closed once the fetch in progress is done, or nil if there is none.
+	flight chan struct{}
+}
+
+// key returns the key with the ID.
+func (ks *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	for {
+		ks.mu.Lock()
+		if key, ok := ks.keys[kid]; ok {
+			ks.mu.Unlock()
+			return key, nil
+		}
+		now := time.Now()
+		if now.Sub(ks.fetched) < time.Minute {
+			ks.mu.Unlock()
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if now.Before(ks.retry) {
+			err := ks.err
+			ks.mu.Unlock()
+			return nil, fmt.Errorf("unknown key %q: %w", kid, err)
+		}
+		if flight := ks.flight; flight != nil {
+			ks.mu.Unlock()
+			select {
+			case <-flight:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		flight := make(chan struct{})
+		ks.flight = flight
+		ks.mu.Unlock()
+
+		keys, err := ks.fetch(ctx)
+
+		ks.mu.Lock()
+		ks.flight = nil
+		close(flight)
+		switch {
+		case err == nil:
+			ks.keys, ks.fetched, ks.failures, ks.err = keys, time.Now(), 0, nil
+		case ctx.Err() == nil:
+			// Fetches abandoned by their request are not failures of the issuer.
+			ks.failures++
+			ks.err = err
+			ks.retry = time.Now().Add(keySetBackoff(ks.failures))
+		}
+		key, ok := ks.keys[kid]
+		ks.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return key, nil
+	}
+}
+
+// fetch fetches the keys of the set used for signatures.
+func (ks *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := doJSON(ks.client, req, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// keySetBackoff returns the delay before fetching a key set again after the number of consecutive failed fetches,
+// doubling from one second up to a minute.
+func keySetBackoff(failures int) time.Duration {
+	delay := time.Second << (failures - 1)
+	if delay <= 0 || delay > time.Minute {
+		delay = time.Minute
+	}
+	return delay
+}
@@ -0,0 +1,199 @@
+package muxter
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyJWS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	payload := encode(`{"sub":"user-1"}`)
+
+	signES256 := func(header string, key *ecdsa.PrivateKey) string {
+		input := encode(header) + "." + payload
+		digest := sha256.Sum256([]byte(input))
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	signHS256 := func(header string) string {
+		input := encode(header) + "." + payload
+		mac := hmac.New(sha256.New, []byte("public key"))
+		mac.Write([]byte(input))
+		return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	testcases := []struct {
+		Name  string
+		Token string
+		Valid bool
+	}{
+		{Name: "valid", Token: signES256(`{"alg":"ES256","kid":"ec"}`, ecKey), Valid: true},
+		{Name: "wrong key", Token: signES256(`{"alg":"ES256","kid":"ec"}`, otherKey)},
+		{Name: "algorithm mismatch", Token: signES256(`{"alg":"ES384","kid":"ec"}`, ecKey)},
+		{Name: "unknown key", Token: signES256(`{"alg":"ES256","kid":"other"}`, ecKey)},
+		{Name: "none", Token: encode(`{"alg":"none","kid":"ec"}`) + "." + payload + "."},
+		{Name: "hmac", Token: signHS256(`{"alg":"HS256","kid":"ec"}`)},
+		{Name: "malformed", Token: "not.a-token"},
+	}
+
+	keys := map[string]crypto.PublicKey{"ec": &ecKey.PublicKey}
+	lookup := func(header jwsHeader) (crypto.PublicKey, error) {
+		if key, ok := keys[header.Kid]; ok {
+			return key, nil
+		}
+		return nil, errors.New("unknown key")
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := verifyJWS(tc.Token, lookup)
+			if !tc.Valid {
+				if err == nil {
+					t.Errorf("expected the token to be refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the token to be valid but got: %v", err)
+			}
+			if string(actual) != `{"sub":"user-1"}` {
+				t.Errorf("unexpected payload %s", actual)
+			}
+		})
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hits atomic.Int32
+	var failing atomic.Bool
+	var gate atomic.Pointer[chan struct{}]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if gate := gate.Load(); gate != nil {
+			<-*gate
+		}
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": "ec",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+			"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("failed fetches back off and keep the last keys", func(t *testing.T) {
+		hits.Store(0)
+		ks := &keySet{url: server.URL, client: server.Client()}
+
+		if _, err := ks.key(ctx, "ec"); err != nil {
+			t.Fatalf("expected the key to be fetched but got: %v", err)
+		}
+
+		failing.Store(true)
+		defer failing.Store(false)
+		ks.fetched = time.Time{}
+
+		for i := 0; i < 3; i++ {
+			if _, err := ks.key(ctx, "rotated"); err == nil {
+				t.Fatal("expected the fetch of the key set to fail")
+			}
+		}
+		if n := hits.Load(); n != 2 {
+			t.Errorf("expected a single fetch after the failure but got %d fetches", n-1)
+		}
+		if _, err := ks.key(ctx, "ec"); err != nil {
+			t.Errorf("expected the last keys to be kept but got: %v", err)
+		}
+
+		failing.Store(false)
+		ks.retry = time.Time{}
+		if _, err := ks.key(ctx, "rotated"); err == nil || err.Error() != `unknown key "rotated"` {
+			t.Errorf("expected the key set to be fetched once the backoff expired but got: %v", err)
+		}
+		if n, failures := hits.Load(), ks.failures; n != 3 || failures != 0 {
+			t.Errorf("expected 3 fetches and the failures to be reset but got %d fetches and %d failures", n, failures)
+		}
+	})
+
+	t.Run("concurrent requests share a fetch outside the lock", func(t *testing.T) {
+		hits.Store(0)
+		release := make(chan struct{})
+		gate.Store(&release)
+		defer gate.Store(nil)
+
+		ks := &keySet{url: server.URL, client: server.Client(), keys: map[string]crypto.PublicKey{"cached": &ecKey.PublicKey}}
+
+		errs := make(chan error)
+		for i := 0; i < 5; i++ {
+			go func() {
+				_, err := ks.key(ctx, "ec")
+				errs <- err
+			}()
+		}
+		for hits.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			ks.key(ctx, "cached")
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected known keys to be returned while the key set is fetched")
+		}
+
+		close(release)
+		for i := 0; i < 5; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("expected the key to be fetched but got: %v", err)
+			}
+		}
+		if n := hits.Load(); n != 1 {
+			t.Errorf("expected a single fetch but got %d", n)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package muxter
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptLanguage negotiates the locale of the request from its Accept-Language header among the supported
+// locales, falling back to the first supported locale. The negotiated locale is available from Context.Locale
+// and selects the messages of the mux's catalog for built-in responses such as not found. Register it with
+// UseGlobal for the locale to apply to not found responses as well.
+//
+//	mux.UseGlobal(muxter.AcceptLanguage("en", "fr", "es"))
+func AcceptLanguage(supported ...string) Middleware {
+	if len(supported) == 0 {
+		panic("muxter: AcceptLanguage requires at least one supported locale")
+	}
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			c.locale = negotiateLocale(r.Header.Get("Accept-Language"), supported)
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+}
+
+// Locale returns the locale negotiated by the AcceptLanguage middleware, or the empty string if it is not in use.
+func (c Context) Locale() string {
+	return c.locale
+}
+
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// negotiateLocale returns the supported locale that best matches the Accept-Language header. A range matches a
+// locale if they are equal or if one is a prefix of the other, such that "fr-CA" matches "fr" and vice versa.
+func negotiateLocale(header string, supported []string) string {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(params[len("q="):], 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, lr := range ranges {
+		if lr.tag == "*" {
+			return supported[0]
+		}
+		for _, locale := range supported {
+			if strings.EqualFold(lr.tag, locale) {
+				return locale
+			}
+		}
+		for _, locale := range supported {
+			if languagePrefix(lr.tag, locale) || languagePrefix(locale, lr.tag) {
+				return locale
+			}
+		}
+	}
+
+	return supported[0]
+}
+
+func languagePrefix(prefix, tag string) bool {
+	return len(tag) > len(prefix) && tag[len(prefix)] == '-' && strings.EqualFold(tag[:len(prefix)], prefix)
+}
@@ -0,0 +1,31 @@
+package muxter
+
+import "testing"
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"en", "fr", "pt-BR"}
+
+	testcases := []struct {
+		Header   string
+		Expected string
+	}{
+		{Header: "", Expected: "en"},
+		{Header: "fr", Expected: "fr"},
+		{Header: "FR", Expected: "fr"},
+		{Header: "fr-CA", Expected: "fr"},
+		{Header: "pt", Expected: "pt-BR"},
+		{Header: "de, fr;q=0.5", Expected: "fr"},
+		{Header: "en;q=0.2, fr;q=0.8", Expected: "fr"},
+		{Header: "fr;q=0, de", Expected: "en"},
+		{Header: "de, *;q=0.1", Expected: "en"},
+		{Header: "fr;q=bad, pt-BR", Expected: "pt-BR"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Header, func(t *testing.T) {
+			if actual := negotiateLocale(tc.Header, supported); actual != tc.Expected {
+				t.Errorf("expected locale to be %q but got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package muxter
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lazy returns a handler that initializes the real handler with init on the first request, so that expensive
+// handlers, for example ones that parse templates or load models, do not delay startup. Concurrent requests wait
+// for the initialization in progress, without holding it up if they are canceled. If init returns an error it is
+// logged and the request is served 503 Service Unavailable. Requests are served 503 without calling init again until
+// a backoff, doubling from one second up to a minute, has elapsed since the failure, so that a failing dependency is
+// not retried by every request.
+func Lazy(init func() (Handler, error)) Handler {
+	return &lazyHandler{init: init, clock: systemClock{}}
+}
+
+type lazyHandler struct {
+	init    func() (Handler, error)
+	clock   Clock
+	handler atomic.Pointer[Handler]
+
+	mu       sync.Mutex
+	failures int
+	retry    time.Time
+	// flight is closed when the initialization in progress completes, or nil if none is in progress.
+	flight chan struct{}
+}
+
+func (lh *lazyHandler) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {
+	handler, ok := lh.get(r)
+	if !ok {
+		writeStatus(w, c, http.StatusServiceUnavailable)
+		return
+	}
+	handler.ServeHTTPx(w, r, c)
+}
+
+func (lh *lazyHandler) get(r *http.Request) (Handler, bool) {
+	if h := lh.handler.Load(); h != nil {
+		return *h, true
+	}
+
+	for {
+		lh.mu.Lock()
+		if h := lh.handler.Load(); h != nil {
+			lh.mu.Unlock()
+			return *h, true
+		}
+		if lh.clock.Now().Before(lh.retry) {
+			lh.mu.Unlock()
+			return nil, false
+		}
+		if flight := lh.flight; flight != nil {
+			lh.mu.Unlock()
+			select {
+			case <-flight:
+				continue
+			case <-r.Context().Done():
+				return nil, false
+			}
+		}
+		flight := make(chan struct{})
+		lh.flight = flight
+		lh.mu.Unlock()
+
+		handler, err := lh.init()
+		if err == nil && handler == nil {
+			err = errors.New("init returned a nil handler")
+		}
+
+		lh.mu.Lock()
+		lh.flight = nil
+		close(flight)
+		if err != nil {
+			lh.failures++
+			lh.retry = lh.clock.Now().Add(keySetBackoff(lh.failures))
+			log.Printf("muxter: lazy handler initialization failed (attempt %d): %v", lh.failures, err)
+		} else {
+			lh.handler.Store(&handler)
+		}
+		lh.mu.Unlock()
+
+		return handler, err == nil
+	}
+}
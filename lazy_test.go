@@ -0,0 +1,67 @@
+package muxter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLazy(t *testing.T) {
+	var calls int
+	failures := 1
+
+	handler := Lazy(func() (Handler, error) {
+		calls++
+		if calls <= failures {
+			return nil, errors.New("not ready")
+		}
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			io.WriteString(w, "ready")
+		}), nil
+	})
+
+	if calls != 0 {
+		t.Fatalf("expected handler not to be initialized before the first request")
+	}
+
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	handler.(*lazyHandler).clock = clock
+
+	mux := New()
+	mux.Handle("/", handler)
+
+	serve := func() (int, string) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Code, w.Body.String()
+	}
+
+	if code, _ := serve(); code != 503 {
+		t.Errorf("expected failed initialization to return 503 but got %d", code)
+	}
+	if code, _ := serve(); code != 503 || calls != 1 {
+		t.Errorf("expected init not to be retried before the backoff elapsed but got %d after %d calls", code, calls)
+	}
+
+	clock.Advance(time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if code, body := serve(); code != 200 || body != "ready" {
+				t.Errorf("expected 200 ready but got %d %q", code, body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 2 {
+		t.Errorf("expected init to be called twice but got %d", calls)
+	}
+}
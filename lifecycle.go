@@ -0,0 +1,374 @@
+package muxter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/davidmdm/muxter/internal"
+	"github.com/davidmdm/muxter/internal/pool"
+)
+
+// lifecycle is the state of a request shared by all copies of its Context. It is created by Mux.ServeHTTP and pooled
+// along with the params of the request. The state that few requests use, such as the functions given to Defer, is kept
+// apart in a lifecycleExtension allocated on first use, such that other requests neither allocate nor reset it.
+type lifecycle struct {
+	params *pool.ParamSet
+	// epoch starts at one and is incremented whenever the lifecycle is released to the pool, such that Contexts
+	// recording the epoch of their request detect that it has been served. It is kept apart from the state reset on
+	// release so that late readers never race with the reset.
+	epoch atomic.Uint64
+
+	r *http.Request
+	// body is the body of the request as received by the mux, before middlewares replaced it.
+	body        io.ReadCloser
+	writer      lifecycleWriter
+	writeFailed atomic.Bool
+	// extension is the state allocated by extended, or nil if the request has not used it.
+	extension atomic.Pointer[lifecycleExtension]
+}
+
+// lifecycleExtension is the state of a request used by Defer, OnFinish, Done, SurrogateKeys, UploadProgress and
+// TimeMiddlewares.
+type lifecycleExtension struct {
+	deferred  []func(context.Context)
+	finishers []func()
+	surrogate []string
+	// layers are the middleware layers entered while serving the request, and open the indices of the layers not
+	// exited yet, innermost last. See TimeMiddlewares.
+	layers []layerTiming
+	open   []openLayer
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	upload uploadCounters
+}
+
+// extended returns the extension of the lifecycle, allocating it on first use.
+func (lc *lifecycle) extended() *lifecycleExtension {
+	if ext := lc.extension.Load(); ext != nil {
+		return ext
+	}
+	lc.extension.CompareAndSwap(nil, new(lifecycleExtension))
+	return lc.extension.Load()
+}
+
+// acquireLifecycle takes params from the pool along with the lifecycle pooled with them, allocating the lifecycle for
+// newly allocated params.
+func acquireLifecycle() *lifecycle {
+	params := pool.Params.Get()
+	lc, _ := params.State.(*lifecycle)
+	if lc == nil {
+		lc = &lifecycle{params: params}
+		lc.writer.lc = lc
+		lc.epoch.Store(1)
+		params.State = lc
+	}
+	return lc
+}
+
+// Defer schedules fn to run in the background after the response is written, instead of an ad hoc goroutine that
+// would lose track of the request. fn is called with a context that carries the values of the request's context but
+// is not canceled when the request completes; it is canceled after the timeout set with the DeferTimeout option
+// instead. Deferred functions of a request run in order, and a panic in one of them is reported to the function set
+// with the OnDeferPanic option rather than crashing the program.
+//
+// Deferred functions are not run if the handler panics without being recovered. Defer must be called before the
+// handler returns.
+func (c Context) Defer(fn func(ctx context.Context)) {
+	c.checkOwner()
+	if c.lifecycle == nil {
+		go runDeferred(detachedContext{context.Background()}, defaultDeferTimeout, defaultDeferPanicHandler, nil, []func(context.Context){fn})
+		return
+	}
+	ext := c.lifecycle.extended()
+	ext.deferred = append(ext.deferred, fn)
+}
+
+// DeferTimeout sets the timeout of functions scheduled with Context.Defer. It defaults to 30 seconds.
+func DeferTimeout(timeout time.Duration) MuxOption {
+	return func(m *Mux) {
+		m.deferTimeout = timeout
+	}
+}
+
+// OnDeferPanic sets the function called when a function scheduled with Context.Defer panics. By default the panic
+// and its stack trace are logged with the standard logger.
+func OnDeferPanic(fn func(r *http.Request, recovered interface{})) MuxOption {
+	return func(m *Mux) {
+		m.onDeferPanic = fn
+	}
+}
+
+// CheckContextOwnership sets whether Contexts detect their use after their request has been served, as when they are
+// captured by a goroutine or an OnFinish function that outlives the handler. The state of a Context, such as its
+// params, is recycled for other requests once its request is served, so late uses would silently read the data of
+// another request: with the check, they panic instead. The check is a single integer comparison and is enabled by
+// default. Handlers keep a Context beyond their request with Context.Detach.
+func CheckContextOwnership(value bool) MuxOption {
+	return func(m *Mux) {
+		m.uncheckedContexts = !value
+	}
+}
+
+// checkOwner panics if the request of the Context has been served and its state recycled.
+func (c Context) checkOwner() {
+	if c.epoch != 0 && c.lifecycle.epoch.Load() != c.epoch {
+		panic("muxter: Context used after its request was served; use Context.Detach to keep it beyond the handler")
+	}
+}
+
+// Detach returns a deep copy of the Context that is safe to use after the request has been served, such as in a
+// goroutine started by the handler or a job handed to a queue. The copy owns its params and identity, and retains
+// the pattern, route and original path of the request, but it is no longer tied to the request: functions given to
+// Defer run right away in the background, OnFinish panics and Done returns nil. Detach must be called before the
+// handler returns.
+func (c Context) Detach() Context {
+	c.checkOwner()
+	params := []internal.Param{}
+	if c.params != nil {
+		params = append(params, *c.params...)
+	}
+	c.params = &params
+	if c.extra().identity != nil {
+		extras := c.editExtras()
+		identity := *extras.identity
+		identity.Groups = append([]string(nil), identity.Groups...)
+		identity.Scopes = append([]string(nil), identity.Scopes...)
+		extras.identity = &identity
+	}
+	c.lifecycle = nil
+	c.epoch = 0
+	return c
+}
+
+const defaultDeferTimeout = 30 * time.Second
+
+func defaultDeferPanicHandler(r *http.Request, recovered interface{}) {
+	target := ""
+	if r != nil {
+		target = " for " + r.Method + " " + r.URL.Path
+	}
+	log.Printf("muxter: panic in deferred function%s: %v\n%s", target, recovered, debug.Stack())
+}
+
+// OnFinish registers fn to run once the request has been served, even if the handler panics, such that middlewares
+// and handlers can release resources like files and locks in one place. Functions run synchronously in the reverse
+// order of their registration, after the response has been written by the handler. OnFinish panics if the request
+// is not served by Mux.ServeHTTP.
+func (c Context) OnFinish(fn func()) {
+	c.checkOwner()
+	if c.lifecycle == nil {
+		panic("muxter: OnFinish called on a request not served by Mux.ServeHTTP")
+	}
+	ext := c.lifecycle.extended()
+	ext.finishers = append(ext.finishers, fn)
+}
+
+// finish counts the request if it was aborted, runs its OnFinish functions, releases the params and the lifecycle for
+// reuse, and schedules its deferred functions if the request completed without panicking, as reported by completed
+// once the handler returns. OnFinish functions run before the release, such that they can still use the Context of the
+// request.
+func (lc *lifecycle) finish(m *Mux, r *http.Request, completed *bool) {
+	ext := lc.extension.Load()
+	if ext == nil {
+		if lc.writeFailed.Load() && m.aborted != nil {
+			m.aborted.Add(1)
+		}
+		lc.release(m, r)
+		return
+	}
+
+	if lc.aborted() && m.aborted != nil {
+		m.aborted.Add(1)
+	}
+
+	deferred, finishers := ext.deferred, ext.finishers
+	if ext.cancel != nil {
+		ext.cancel()
+	}
+	if len(finishers) > 0 {
+		defer lc.release(m, r)
+		runFinishers(finishers)
+	} else {
+		lc.release(m, r)
+	}
+
+	if *completed && len(deferred) > 0 {
+		timeout, onPanic := m.deferTimeout, m.onDeferPanic
+		if timeout <= 0 {
+			timeout = defaultDeferTimeout
+		}
+		if onPanic == nil {
+			onPanic = defaultDeferPanicHandler
+		}
+		go runDeferred(detachedContext{r.Context()}, timeout, onPanic, r, deferred)
+	}
+}
+
+// release ends the epoch of the request and returns its params to the pool along with the lifecycle.
+func (lc *lifecycle) release(m *Mux, r *http.Request) {
+	params := lc.params
+	if m.debug {
+		// The lifecycle is not recycled, such that late writes panic rather than write to another response.
+		lc.writer.ResponseWriter = servedWriter{request: r.Method + " " + r.URL.Path}
+		lc.epoch.Add(1)
+		params.State = nil
+		pool.Params.Put(params)
+		return
+	}
+	// The epoch ends before the state is reset, such that late users of the Context panic rather than read the state
+	// being reset.
+	lc.epoch.Add(1)
+	lc.r, lc.body, lc.writer.ResponseWriter = nil, nil, nil
+	if lc.writeFailed.Load() {
+		lc.writeFailed.Store(false)
+	}
+	if lc.extension.Load() != nil {
+		lc.extension.Store(nil)
+	}
+	pool.Params.Put(params)
+}
+
+// runFinishers runs the functions in reverse order. Deferring each function runs all of them even if one panics.
+func runFinishers(finishers []func()) {
+	for _, fn := range finishers {
+		defer fn()
+	}
+}
+
+func runDeferred(ctx context.Context, timeout time.Duration, onPanic func(*http.Request, interface{}), r *http.Request, deferred []func(context.Context)) {
+	for _, fn := range deferred {
+		func() {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					onPanic(r, recovered)
+				}
+			}()
+			fn(ctx)
+		}()
+	}
+}
+
+// detachedContext carries the values of its parent but not its deadline or cancelation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)           { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}                 { return nil }
+func (detachedContext) Err() error                            { return nil }
+func (ctx detachedContext) Value(key interface{}) interface{} { return ctx.parent.Value(key) }
+
+// Done returns a channel that is closed when the client has gone away, either because the request's context is
+// canceled or because writing the response failed, or once the request has been served. Long running handlers can
+// use it to stop work early. Done must be called before the handler returns; it returns nil, a channel that is
+// never closed, if the request is not served by Mux.ServeHTTP.
+func (c Context) Done() <-chan struct{} {
+	c.checkOwner()
+	if c.lifecycle == nil {
+		return nil
+	}
+	return c.lifecycle.done()
+}
+
+// AbortedRequests returns the number of requests served by the mux that were abandoned by their clients, either
+// because writing the response failed or because the request's context was canceled while the handler watched Done.
+func (m *Mux) AbortedRequests() uint64 {
+	if m.aborted == nil {
+		return 0
+	}
+	return m.aborted.Load()
+}
+
+func (lc *lifecycle) done() <-chan struct{} {
+	ext := lc.extended()
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+
+	if ext.ctx == nil {
+		ext.ctx, ext.cancel = context.WithCancel(lc.r.Context())
+		if lc.writeFailed.Load() {
+			ext.cancel()
+		}
+	}
+	return ext.ctx.Done()
+}
+
+func (lc *lifecycle) writeFailure() {
+	lc.writeFailed.Store(true)
+
+	ext := lc.extension.Load()
+	if ext == nil {
+		return
+	}
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+
+	if ext.cancel != nil {
+		ext.cancel()
+	}
+}
+
+// aborted reports whether writing the response failed or, if the handler watched Done, the request's context was
+// canceled. The request's context itself is not checked, as its Err walks the whole chain of contexts derived from it
+// on every request.
+func (lc *lifecycle) aborted() bool {
+	if lc.writeFailed.Load() {
+		return true
+	}
+	ext := lc.extension.Load()
+	return ext != nil && ext.ctx != nil && ext.ctx.Err() == context.Canceled
+}
+
+// lifecycleWriter records write failures of the response to detect clients that went away.
+type lifecycleWriter struct {
+	http.ResponseWriter
+	lc *lifecycle
+}
+
+func (w *lifecycleWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *lifecycleWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	if err != nil {
+		w.lc.writeFailure()
+	}
+	return n, err
+}
+
+func (w *lifecycleWriter) ReadFrom(src io.Reader) (n int64, err error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, src)
+	}
+	if err != nil {
+		w.lc.writeFailure()
+	}
+	return n, err
+}
+
+func (w *lifecycleWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *lifecycleWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("muxter: response writer does not support hijacking")
+}
@@ -0,0 +1,342 @@
+package muxter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type lifecycleKey struct{}
+
+func TestDefer(t *testing.T) {
+	type result struct {
+		value     interface{}
+		responded bool
+		deadline  time.Duration
+	}
+
+	results := make(chan result, 2)
+	panics := make(chan interface{}, 1)
+	responded := make(chan struct{})
+
+	mux := New(
+		DeferTimeout(time.Minute),
+		OnDeferPanic(func(r *http.Request, recovered interface{}) { panics <- recovered }),
+	)
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request, c Context) {
+		c.Defer(func(ctx context.Context) { panic("boom") })
+		c.Defer(func(ctx context.Context) {
+			var res result
+			select {
+			case <-responded:
+				res.responded = true
+			case <-time.After(time.Second):
+			}
+			res.value = ctx.Value(lifecycleKey{})
+			if deadline, ok := ctx.Deadline(); ok {
+				res.deadline = time.Until(deadline)
+			}
+			results <- res
+		})
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), lifecycleKey{}, "req-1"))
+	r := httptest.NewRequest("POST", "/orders", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+	cancel()
+	close(responded)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202 but got %d", w.Code)
+	}
+
+	select {
+	case recovered := <-panics:
+		if recovered != "boom" {
+			t.Errorf("expected recovered value to be %q but got %v", "boom", recovered)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected panic of deferred function to be reported")
+	}
+
+	select {
+	case res := <-results:
+		if !res.responded {
+			t.Error("expected deferred function to run after the response")
+		}
+		if res.value != "req-1" {
+			t.Errorf("expected deferred context to carry request values but got %v", res.value)
+		}
+		if res.deadline <= 30*time.Second || res.deadline > time.Minute {
+			t.Errorf("expected deferred context to have a one minute timeout but got %v", res.deadline)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected deferred function to run")
+	}
+}
+
+func TestOnFinish(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Panic    bool
+		Expected []string
+	}{
+		{
+			Name:     "completed",
+			Expected: []string{"handler", "release lock", "close file"},
+		},
+		{
+			Name:     "panic",
+			Panic:    true,
+			Expected: []string{"release lock", "close file"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var calls []string
+
+			track := func(h Handler) Handler {
+				return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+					c.OnFinish(func() { calls = append(calls, "close file") })
+					h.ServeHTTPx(w, r, c)
+				})
+			}
+
+			mux := New()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+				c.OnFinish(func() { calls = append(calls, "release lock") })
+				if tc.Panic {
+					panic("boom")
+				}
+				calls = append(calls, "handler")
+			}, track)
+
+			func() {
+				defer func() {
+					if recovered := recover(); (recovered != nil) != tc.Panic {
+						t.Errorf("unexpected recovered value: %v", recovered)
+					}
+				}()
+				mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+			}()
+
+			if !reflect.DeepEqual(calls, tc.Expected) {
+				t.Errorf("expected calls %q but got %q", tc.Expected, calls)
+			}
+		})
+	}
+}
+
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+// errCountingContext counts the calls to its Err method.
+type errCountingContext struct {
+	context.Context
+	calls *int
+}
+
+func (ctx errCountingContext) Err() error {
+	*ctx.calls++
+	return ctx.Context.Err()
+}
+
+func TestAbortedDoesNotCheckUnwatchedContext(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	var calls int
+	ctx := errCountingContext{Context: context.Background(), calls: &calls}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+	if calls != 0 {
+		t.Errorf("expected the context of a request whose handler did not watch Done not to be checked but Err was called %d times", calls)
+	}
+}
+
+func TestLifecycleExtensionIsLazy(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte("ok"))
+		if c.lifecycle.extension.Load() != nil {
+			t.Error("expected a plain request not to extend its lifecycle")
+		}
+	})
+	mux.HandleFunc("/watched", func(w http.ResponseWriter, r *http.Request, c Context) {
+		c.Done()
+		if c.lifecycle.extension.Load() == nil {
+			t.Error("expected Done to extend the lifecycle")
+		}
+	})
+
+	for _, target := range []string{"/watched", "/plain"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+}
+
+func TestDone(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request, c Context) {
+		for i := 0; i < 3; i++ {
+			select {
+			case <-c.Done():
+				return
+			default:
+			}
+			w.Write([]byte("chunk\n"))
+		}
+	})
+
+	testcases := []struct {
+		Name            string
+		Cancel          bool
+		Fail            bool
+		ExpectedBody    string
+		ExpectedAborted uint64
+	}{
+		{
+			Name:            "completed",
+			ExpectedBody:    "chunk\nchunk\nchunk\n",
+			ExpectedAborted: 0,
+		},
+		{
+			Name:            "write failure",
+			Fail:            true,
+			ExpectedAborted: 1,
+		},
+		{
+			Name:            "canceled",
+			Cancel:          true,
+			ExpectedAborted: 2,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.Cancel {
+				cancel()
+			}
+
+			rec := httptest.NewRecorder()
+			var w http.ResponseWriter = rec
+			if tc.Fail {
+				w = failingWriter{rec}
+			}
+
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil).WithContext(ctx))
+
+			if rec.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, rec.Body.String())
+			}
+			if aborted := mux.AbortedRequests(); aborted != tc.ExpectedAborted {
+				t.Errorf("expected %d aborted requests but got %d", tc.ExpectedAborted, aborted)
+			}
+		})
+	}
+}
+
+func TestOnFinishUsesContext(t *testing.T) {
+	var id string
+	mux := New()
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		c.OnFinish(func() { id = c.Param("id") })
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books/1", nil))
+
+	if id != "1" {
+		t.Errorf("expected OnFinish function to read param id %q but got %q", "1", id)
+	}
+}
+
+func TestContextOwnership(t *testing.T) {
+	testcases := []struct {
+		Name          string
+		Options       []MuxOption
+		Detach        bool
+		ExpectedPanic bool
+	}{
+		{Name: "used after request", ExpectedPanic: true},
+		{Name: "detached", Detach: true},
+		{Name: "unchecked", Options: []MuxOption{CheckContextOwnership(false)}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var captured Context
+
+			mux := New(tc.Options...)
+			mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+				if tc.Detach {
+					c = c.Detach()
+				}
+				captured = c
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books/1", nil))
+
+			var recovered interface{}
+			var id string
+			func() {
+				defer func() { recovered = recover() }()
+				id = captured.Param("id")
+			}()
+
+			if (recovered != nil) != tc.ExpectedPanic {
+				t.Fatalf("expected panic: %v but recovered %v", tc.ExpectedPanic, recovered)
+			}
+			if tc.Detach && id != "1" {
+				t.Errorf("expected detached param to be %q but got %q", "1", id)
+			}
+		})
+	}
+}
+
+func TestDetach(t *testing.T) {
+	identity := &Identity{Subject: "alice", Scopes: []string{"books:read"}}
+
+	detached := make(chan Context, 1)
+	mux := New()
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		c.editExtras().identity = identity
+		if c.Param("id") == "1" {
+			detached <- c.Detach()
+		}
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books/1", nil))
+	// The next request reuses the pooled params and lifecycle of the first one.
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books/2", nil))
+	identity.Scopes[0] = "books:write"
+
+	c := <-detached
+	if id := c.Param("id"); id != "1" {
+		t.Errorf("expected param id to be %q but got %q", "1", id)
+	}
+	if c.Pattern() != "/books/:id" || c.OriginalPath() != "/books/1" {
+		t.Errorf("expected pattern and path of the request but got %q and %q", c.Pattern(), c.OriginalPath())
+	}
+	if scopes := c.Identity().Scopes; !reflect.DeepEqual(scopes, []string{"books:read"}) {
+		t.Errorf("expected identity to be copied but got scopes %q", scopes)
+	}
+	if c.Done() != nil {
+		t.Errorf("expected detached context not to be tied to its request")
+	}
+	if params := (Context{}).Detach().Params(); len(params) != 0 {
+		t.Errorf("expected no params but got %v", params)
+	}
+	if id := (Context{}).Detach().Param("id"); id != "" {
+		t.Errorf("expected no param but got %q", id)
+	}
+}
@@ -0,0 +1,257 @@
+package muxter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/davidmdm/muxter/internal"
+)
+
+// HeaderLimits configures the LimitHeaders middleware. Zero values disable a limit.
+type HeaderLimits struct {
+	// MaxCount is the maximum number of header fields, counting every value of repeated headers.
+	MaxCount int
+	// MaxBytes is the maximum length of a single header field, name and value included.
+	MaxBytes int
+	// OnReject is called with the reason of every rejected request, for example to record a metric.
+	OnReject func(r *http.Request, reason string)
+}
+
+// LimitHeaders rejects requests with too many or too large headers with 431 Request Header Fields Too Large.
+// The server's MaxHeaderBytes bounds the total size of headers for all routes; LimitHeaders allows tighter limits
+// per route.
+func LimitHeaders(limits HeaderLimits) Middleware {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if reason := checkHeaders(r.Header, limits); reason != "" {
+				if limits.OnReject != nil {
+					limits.OnReject(r, reason)
+				}
+				writeStatus(w, c, http.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+}
+
+func checkHeaders(header http.Header, limits HeaderLimits) string {
+	var count int
+	for key, values := range header {
+		count += len(values)
+		if limits.MaxCount > 0 && count > limits.MaxCount {
+			return fmt.Sprintf("more than %d header fields", limits.MaxCount)
+		}
+		if limits.MaxBytes <= 0 {
+			continue
+		}
+		for _, value := range values {
+			if len(key)+len(value) > limits.MaxBytes {
+				return fmt.Sprintf("header %s exceeds %d bytes", key, limits.MaxBytes)
+			}
+		}
+	}
+	return ""
+}
+
+// ParamLimit limits the values of path params. Zero values disable a limit.
+type ParamLimit struct {
+	// MaxLength is the maximum length in bytes of a param value.
+	MaxLength int
+	// Allowed reports whether a character may appear in a param value, such as unicode.IsDigit. The slashes of
+	// catchall params are always allowed.
+	Allowed func(r rune) bool
+}
+
+// ParamLimits configures the LimitParams mux option.
+type ParamLimits struct {
+	// Default limits the params without limits of their own.
+	Default ParamLimit
+	// Params are the limits of params by key.
+	Params map[string]ParamLimit
+	// Status is the status of requests whose params exceed their limits: 404 Not Found as if no route matched, the
+	// default, or 400 Bad Request.
+	Status int
+	// OnReject is called with the reason of every rejected request, for example to record a metric.
+	OnReject func(r *http.Request, reason string)
+}
+
+// LimitParams is a mux option enforcing limits on the length and characters of path param values when routes are
+// looked up, such that absurdly long or malformed segments never reach middlewares, handlers and logs.
+//
+//	mux := muxter.New(muxter.LimitParams(muxter.ParamLimits{
+//		Default: muxter.ParamLimit{MaxLength: 128},
+//		Params:  map[string]muxter.ParamLimit{"id": {MaxLength: 20, Allowed: unicode.IsDigit}},
+//	}))
+func LimitParams(limits ParamLimits) MuxOption {
+	if limits.Status == 0 {
+		limits.Status = http.StatusNotFound
+	}
+	return func(m *Mux) {
+		m.paramLimits = &limits
+	}
+}
+
+// checkParams returns why a param exceeds its limits, or the empty string if none do.
+func (limits *ParamLimits) checkParams(params []internal.Param) string {
+	for _, param := range params {
+		limit, ok := limits.Params[param.Key]
+		if !ok {
+			limit = limits.Default
+		}
+		if limit.MaxLength > 0 && len(param.Value) > limit.MaxLength {
+			return fmt.Sprintf("param %s exceeds %d bytes", param.Key, limit.MaxLength)
+		}
+		if limit.Allowed == nil {
+			continue
+		}
+		for _, r := range param.Value {
+			if r != '/' && !limit.Allowed(r) {
+				return fmt.Sprintf("param %s contains %q", param.Key, r)
+			}
+		}
+	}
+	return ""
+}
+
+// PathLimits configures the LimitPath mux option. Zero values disable a limit.
+type PathLimits struct {
+	// MaxLength is the maximum length in bytes of the request path.
+	MaxLength int
+	// MaxSegments is the maximum number of segments of the request path, such that "/a/b/" has 3 segments.
+	MaxSegments int
+	// OnReject is called with the reason of every rejected request, for example to record a metric.
+	OnReject func(r *http.Request, reason string)
+}
+
+// LimitPath is a mux option rejecting requests whose path is too long or too deep with 414 URI Too Long before
+// routes are looked up, such that pathological requests are rejected cheaply on internet facing muxes.
+//
+//	mux := muxter.New(muxter.LimitPath(muxter.PathLimits{MaxLength: 2048, MaxSegments: 32}))
+func LimitPath(limits PathLimits) MuxOption {
+	return func(m *Mux) {
+		m.pathLimits = &limits
+	}
+}
+
+// checkPath returns why the path exceeds the limits, or the empty string if it does not.
+func (limits *PathLimits) checkPath(path string) string {
+	if limits.MaxLength > 0 && len(path) > limits.MaxLength {
+		return fmt.Sprintf("path exceeds %d bytes", limits.MaxLength)
+	}
+	if limits.MaxSegments > 0 && strings.Count(path, "/") > limits.MaxSegments {
+		return fmt.Sprintf("path exceeds %d segments", limits.MaxSegments)
+	}
+	return ""
+}
+
+// ExpressionLimits configures the LimitExpressions mux option. Zero values disable a limit.
+type ExpressionLimits struct {
+	// MaxSteps is the maximum number of bytes of the path the regexp params of a lookup may scan in total, since the
+	// time of a regexp match is linear in its input.
+	MaxSteps int
+	// MaxDuration is the maximum time spent evaluating the regexp params of a lookup. It is checked before every
+	// evaluation, as a match cannot be interrupted.
+	MaxDuration time.Duration
+	// Status is the status of requests exceeding a limit: 404 Not Found as if no route matched, the default, or
+	// 400 Bad Request.
+	Status int
+	// OnReject is called with the reason of every rejected request, for example to record a metric.
+	OnReject func(r *http.Request, reason string)
+	// Stats, if not nil, counts the rejected lookups.
+	Stats *ExpressionStats
+}
+
+// ExpressionStats counts the lookups rejected by the LimitExpressions mux option.
+type ExpressionStats struct {
+	// Exceeded is the number of lookups that exceeded MaxSteps or MaxDuration.
+	Exceeded atomic.Uint64
+	// Canceled is the number of lookups abandoned because the context of their request was done.
+	Canceled atomic.Uint64
+}
+
+// LimitExpressions is a mux option bounding the work of the regexp params of a lookup, such that complex
+// expressions cannot be used to burn CPU with hostile paths. Lookups also stop evaluating expressions once the
+// context of their request is done, such as when the client went away. Rejected requests are not routed.
+//
+//	mux := muxter.New(muxter.LimitExpressions(muxter.ExpressionLimits{MaxSteps: 4096, MaxDuration: time.Millisecond}))
+func LimitExpressions(limits ExpressionLimits) MuxOption {
+	if limits.Status == 0 {
+		limits.Status = http.StatusNotFound
+	}
+	return func(m *Mux) {
+		m.expressionLimits = &limits
+	}
+}
+
+func (limits *ExpressionLimits) budget(r *http.Request) *expressionBudget {
+	return &expressionBudget{ctx: r.Context(), limits: limits}
+}
+
+func (limits *ExpressionLimits) reject(r *http.Request, reason string) {
+	if limits.Stats != nil {
+		if reason == reasonCanceled {
+			limits.Stats.Canceled.Add(1)
+		} else {
+			limits.Stats.Exceeded.Add(1)
+		}
+	}
+	if limits.OnReject != nil {
+		limits.OnReject(r, reason)
+	}
+}
+
+const reasonCanceled = "request canceled"
+
+// expressionBudget is the work left to the expressions of a lookup. A nil budget is unlimited.
+type expressionBudget struct {
+	ctx      context.Context
+	limits   *ExpressionLimits
+	steps    int
+	deadline time.Time
+	stopped  string
+}
+
+// spend charges the evaluation of an expression over input bytes to the budget, and reports whether it may run.
+func (b *expressionBudget) spend(input int) bool {
+	if b == nil {
+		return true
+	}
+	if b.stopped != "" {
+		return false
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		b.stopped = reasonCanceled
+		return false
+	}
+
+	b.steps += input
+	if max := b.limits.MaxSteps; max > 0 && b.steps > max {
+		b.stopped = fmt.Sprintf("expressions exceed %d steps", max)
+		return false
+	}
+
+	if max := b.limits.MaxDuration; max > 0 {
+		if b.deadline.IsZero() {
+			b.deadline = time.Now().Add(max)
+		} else if time.Now().After(b.deadline) {
+			b.stopped = fmt.Sprintf("expressions exceed %v", max)
+			return false
+		}
+	}
+
+	return true
+}
+
+// reason returns why the lookup was stopped, or the empty string if it was not.
+func (b *expressionBudget) reason() string {
+	if b == nil {
+		return ""
+	}
+	return b.stopped
+}
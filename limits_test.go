@@ -0,0 +1,229 @@
+package muxter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestLimitHeaders(t *testing.T) {
+	var rejections []string
+
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {}, LimitHeaders(HeaderLimits{
+		MaxCount: 3,
+		MaxBytes: 32,
+		OnReject: func(r *http.Request, reason string) { rejections = append(rejections, reason) },
+	}))
+
+	testcases := []struct {
+		Name           string
+		Header         http.Header
+		ExpectedCode   int
+		ExpectedReason string
+	}{
+		{
+			Name:         "within limits",
+			Header:       http.Header{"A": {"1"}, "B": {"2", "3"}},
+			ExpectedCode: 200,
+		},
+		{
+			Name:           "too many fields",
+			Header:         http.Header{"A": {"1", "2", "3", "4"}},
+			ExpectedCode:   431,
+			ExpectedReason: "more than 3 header fields",
+		},
+		{
+			Name:           "field too large",
+			Header:         http.Header{"Cookie": {strings.Repeat("x", 30)}},
+			ExpectedCode:   431,
+			ExpectedReason: "header Cookie exceeds 32 bytes",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rejections = nil
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+			r.Header = tc.Header
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedReason == "" && len(rejections) != 0 {
+				t.Errorf("expected no rejections but got %q", rejections)
+			}
+			if tc.ExpectedReason != "" && (len(rejections) != 1 || rejections[0] != tc.ExpectedReason) {
+				t.Errorf("expected rejection %q but got %q", tc.ExpectedReason, rejections)
+			}
+		})
+	}
+}
+
+func TestLimitParams(t *testing.T) {
+	var rejections []string
+
+	limits := ParamLimits{
+		Default:  ParamLimit{MaxLength: 16},
+		Params:   map[string]ParamLimit{"id": {MaxLength: 4, Allowed: unicode.IsDigit}},
+		OnReject: func(r *http.Request, reason string) { rejections = append(rejections, reason) },
+	}
+	handler := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New(LimitParams(limits))
+	mux.HandleFunc("/books/:id", handler)
+	mux.HandleFunc("/authors/:name", handler)
+	mux.HandleFunc("/files/*path", handler)
+
+	limits.Status = http.StatusBadRequest
+	strict := New(LimitParams(limits))
+	strict.HandleFunc("/books/:id", handler)
+
+	testcases := []struct {
+		Name           string
+		Mux            *Mux
+		Path           string
+		ExpectedCode   int
+		ExpectedReason string
+	}{
+		{Name: "within limits", Mux: mux, Path: "/books/1234", ExpectedCode: 200},
+		{Name: "too long", Mux: mux, Path: "/books/12345", ExpectedCode: 404, ExpectedReason: "param id exceeds 4 bytes"},
+		{Name: "disallowed character", Mux: mux, Path: "/books/12a", ExpectedCode: 404, ExpectedReason: `param id contains 'a'`},
+		{Name: "default limit", Mux: mux, Path: "/authors/" + strings.Repeat("x", 17), ExpectedCode: 404, ExpectedReason: "param name exceeds 16 bytes"},
+		{Name: "catchall", Mux: mux, Path: "/files/a/b/c", ExpectedCode: 200},
+		{Name: "bad request", Mux: strict, Path: "/books/abc", ExpectedCode: 400, ExpectedReason: `param id contains 'a'`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rejections = nil
+
+			w := httptest.NewRecorder()
+			tc.Mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedReason == "" && len(rejections) != 0 {
+				t.Errorf("expected no rejections but got %q", rejections)
+			}
+			if tc.ExpectedReason != "" && (len(rejections) != 1 || rejections[0] != tc.ExpectedReason) {
+				t.Errorf("expected rejection %q but got %q", tc.ExpectedReason, rejections)
+			}
+		})
+	}
+}
+
+func TestLimitPath(t *testing.T) {
+	var rejections []string
+
+	mux := New(LimitPath(PathLimits{
+		MaxLength:   32,
+		MaxSegments: 4,
+		OnReject:    func(r *http.Request, reason string) { rejections = append(rejections, reason) },
+	}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	testcases := []struct {
+		Name           string
+		Path           string
+		ExpectedCode   int
+		ExpectedReason string
+	}{
+		{Name: "within limits", Path: "/a/b/c/d", ExpectedCode: 200},
+		{Name: "too long", Path: "/" + strings.Repeat("x", 32), ExpectedCode: 414, ExpectedReason: "path exceeds 32 bytes"},
+		{Name: "too deep", Path: "/a/b/c/d/", ExpectedCode: 414, ExpectedReason: "path exceeds 4 segments"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rejections = nil
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedReason == "" && len(rejections) != 0 {
+				t.Errorf("expected no rejections but got %q", rejections)
+			}
+			if tc.ExpectedReason != "" && (len(rejections) != 1 || rejections[0] != tc.ExpectedReason) {
+				t.Errorf("expected rejection %q but got %q", tc.ExpectedReason, rejections)
+			}
+		})
+	}
+}
+
+func TestLimitExpressions(t *testing.T) {
+	var rejections []string
+	var stats ExpressionStats
+
+	limits := ExpressionLimits{
+		MaxSteps: 16,
+		OnReject: func(r *http.Request, reason string) { rejections = append(rejections, reason) },
+		Stats:    &stats,
+	}
+	handler := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New(LimitExpressions(limits))
+	mux.HandleFunc("/books/#id:\\d+", handler)
+	mux.HandleFunc("/docs/", handler)
+	mux.HandleFunc("/docs/#page:[a-z]+", handler)
+
+	limits.Status = http.StatusBadRequest
+	strict := New(LimitExpressions(limits))
+	strict.HandleFunc("/books/#id:\\d+", handler)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testcases := []struct {
+		Name           string
+		Mux            *Mux
+		Path           string
+		Context        context.Context
+		ExpectedCode   int
+		ExpectedReason string
+	}{
+		{Name: "within budget", Mux: mux, Path: "/books/1234", ExpectedCode: 200},
+		{Name: "over budget", Mux: mux, Path: "/books/" + strings.Repeat("1", 17), ExpectedCode: 404, ExpectedReason: "expressions exceed 16 steps"},
+		{Name: "no subtree fallback", Mux: mux, Path: "/docs/" + strings.Repeat("a", 17), ExpectedCode: 404, ExpectedReason: "expressions exceed 16 steps"},
+		{Name: "static routes", Mux: mux, Path: "/docs/", Context: canceled, ExpectedCode: 200},
+		{Name: "canceled", Mux: mux, Path: "/books/1", Context: canceled, ExpectedCode: 404, ExpectedReason: "request canceled"},
+		{Name: "bad request", Mux: strict, Path: "/books/" + strings.Repeat("1", 17), ExpectedCode: 400, ExpectedReason: "expressions exceed 16 steps"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rejections = nil
+
+			r := httptest.NewRequest("GET", tc.Path, nil)
+			if tc.Context != nil {
+				r = r.WithContext(tc.Context)
+			}
+			w := httptest.NewRecorder()
+			tc.Mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedReason == "" && len(rejections) != 0 {
+				t.Errorf("expected no rejections but got %q", rejections)
+			}
+			if tc.ExpectedReason != "" && (len(rejections) != 1 || rejections[0] != tc.ExpectedReason) {
+				t.Errorf("expected rejection %q but got %q", tc.ExpectedReason, rejections)
+			}
+		})
+	}
+
+	if exceeded, canceled := stats.Exceeded.Load(), stats.Canceled.Load(); exceeded != 3 || canceled != 1 {
+		t.Errorf("expected 3 exceeded and 1 canceled lookups but got %d and %d", exceeded, canceled)
+	}
+}
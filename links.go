@@ -0,0 +1,135 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+)
+
+// URL generates the path for the route registered with the given name. Params are given as alternating
+// key and value pairs and are substituted into the route's pattern. For routes registered with a host pattern the
+// result is a scheme relative URL including the host.
+//
+//	mux.URL("book", "id", "42") // "/books/42"
+//	mux.URL("tenant", "tenant", "acme") // "//acme.example.com/"
+func (m *Mux) URL(name string, params ...string) (string, error) {
+	route, ok := m.names[name]
+	if !ok {
+		return "", fmt.Errorf("muxter: no route named %q", name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("muxter: params for route %q must be key value pairs", name)
+	}
+
+	param := func(key string) (string, bool) {
+		for i := 0; i < len(params); i += 2 {
+			if params[i] == key {
+				return params[i+1], true
+			}
+		}
+		return "", false
+	}
+
+	host, pattern, isHost := syntax.SplitHost(route.Pattern)
+
+	path, err := expandPattern(pattern, param)
+	if err != nil {
+		return "", fmt.Errorf("muxter: failed to generate url for route %q: %w", name, err)
+	}
+	if !isHost {
+		return (&url.URL{Path: path}).String(), nil
+	}
+
+	host, err = expandPattern("/"+strings.ReplaceAll(host, ".", "/"), param)
+	if err != nil {
+		return "", fmt.Errorf("muxter: failed to generate url for route %q: %w", name, err)
+	}
+
+	return "//" + strings.ReplaceAll(host[1:], "/", ".") + (&url.URL{Path: path}).String(), nil
+}
+
+// AbsoluteURL generates an absolute URL for the named route as seen by the client making the request.
+// If the mux was configured with a BaseURL it is used as the origin. Otherwise the scheme and host are taken
+// from the X-Forwarded-Proto and X-Forwarded-Host headers when present, or from the request itself.
+func (m *Mux) AbsoluteURL(r *http.Request, name string, params ...string) (string, error) {
+	path, err := m.URL(name, params...)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(path, "//") {
+		return requestScheme(r) + ":" + path, nil
+	}
+	if m.baseURL != nil {
+		return m.baseURL.String() + path, nil
+	}
+	return externalOrigin(r) + path, nil
+}
+
+func externalOrigin(r *http.Request) string {
+	scheme := requestScheme(r)
+
+	host := r.Host
+	if forwarded := firstHeaderValue(r.Header.Get("X-Forwarded-Host")); forwarded != "" {
+		host = forwarded
+	}
+
+	return scheme + "://" + host
+}
+
+// firstHeaderValue returns the first element of a comma separated header value as set by chained proxies.
+func firstHeaderValue(value string) string {
+	if idx := strings.IndexByte(value, ','); idx != -1 {
+		value = value[:idx]
+	}
+	return strings.TrimSpace(value)
+}
+
+// Link is a hypermedia link in the format used by HAL style "_links" objects.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// LinkBuilder accumulates absolute links to named routes. The first error encountered is retained and
+// returned by Build.
+type LinkBuilder struct {
+	mux   *Mux
+	r     *http.Request
+	links map[string]Link
+	err   error
+}
+
+// Links returns a LinkBuilder generating absolute URLs for the mux's named routes relative to the request.
+//
+//	links, err := mux.Links(r).
+//		Add("self", "book", "id", book.ID).
+//		Add("author", "author", "id", book.AuthorID).
+//		Build()
+func (m *Mux) Links(r *http.Request) *LinkBuilder {
+	return &LinkBuilder{mux: m, r: r, links: map[string]Link{}}
+}
+
+// Add adds a link with the given relation to the named route.
+func (lb *LinkBuilder) Add(rel, name string, params ...string) *LinkBuilder {
+	if lb.err != nil {
+		return lb
+	}
+	href, err := lb.mux.AbsoluteURL(lb.r, name, params...)
+	if err != nil {
+		lb.err = err
+		return lb
+	}
+	lb.links[rel] = Link{Href: href}
+	return lb
+}
+
+// Build returns the links keyed by relation, ready to be embedded as "_links" in a JSON response.
+func (lb *LinkBuilder) Build() (map[string]Link, error) {
+	if lb.err != nil {
+		return nil, lb.err
+	}
+	return lb.links, nil
+}
@@ -0,0 +1,154 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNamedRouteURL(t *testing.T) {
+	mux := New()
+
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux.HandleFunc("/authors/:author/books/:id", noop, Name("book"))
+	mux.HandleFunc("/files/*path", noop, Name("file"))
+	mux.GetFunc("/about", noop, Name("about"))
+
+	testcases := []struct {
+		Name          string
+		Route         string
+		Params        []string
+		Expected      string
+		ExpectedError string
+	}{
+		{
+			Name:     "params",
+			Route:    "book",
+			Params:   []string{"author", "vonnegut", "id", "cats cradle"},
+			Expected: "/authors/vonnegut/books/cats%20cradle",
+		},
+		{
+			Name:     "catchall",
+			Route:    "file",
+			Params:   []string{"path", "docs/readme.md"},
+			Expected: "/files/docs/readme.md",
+		},
+		{
+			Name:     "name given with method registration",
+			Route:    "about",
+			Expected: "/about",
+		},
+		{
+			Name:          "unknown route",
+			Route:         "missing",
+			ExpectedError: `muxter: no route named "missing"`,
+		},
+		{
+			Name:          "missing param",
+			Route:         "book",
+			Params:        []string{"author", "vonnegut"},
+			ExpectedError: `muxter: failed to generate url for route "book": missing value for param "id"`,
+		},
+		{
+			Name:          "odd params",
+			Route:         "book",
+			Params:        []string{"author"},
+			ExpectedError: `muxter: params for route "book" must be key value pairs`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := mux.URL(tc.Route, tc.Params...)
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("expected %q but got %q", tc.Expected, actual)
+			}
+		})
+	}
+
+	t.Run("duplicate names", func(t *testing.T) {
+		defer func() {
+			expected := `muxter: route name "book" is already registered for /authors/:author/books/:id`
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected panic %q but got %q", expected, actual)
+			}
+		}()
+		mux.HandleFunc("/books/:id", noop, Name("book"))
+	})
+}
+
+func TestLinkBuilder(t *testing.T) {
+	mux := New()
+
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux.HandleFunc("/books/:id", noop, Name("book"))
+	mux.HandleFunc("/authors/:id", noop, Name("author"))
+
+	t.Run("request host", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "http://library.test/books/1", nil)
+
+		links, err := mux.Links(r).Add("self", "book", "id", "1").Add("author", "author", "id", "2").Build()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := map[string]Link{
+			"self":   {Href: "http://library.test/books/1"},
+			"author": {Href: "http://library.test/authors/2"},
+		}
+		if !reflect.DeepEqual(links, expected) {
+			t.Errorf("expected %+v but got %+v", expected, links)
+		}
+	})
+
+	t.Run("forwarded headers", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "http://internal:8080/books/1", nil)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "api.example.com, internal:8080")
+
+		links, err := mux.Links(r).Add("self", "book", "id", "1").Build()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if href := links["self"].Href; href != "https://api.example.com/books/1" {
+			t.Errorf("expected href %q but got %q", "https://api.example.com/books/1", href)
+		}
+	})
+
+	t.Run("base url", func(t *testing.T) {
+		mux := New(BaseURL("https://example.com/api/"))
+		mux.HandleFunc("/books/:id", noop, Name("book"))
+
+		r := httptest.NewRequest("GET", "http://internal:8080/books/1", nil)
+		r.Header.Set("X-Forwarded-Host", "spoofed.test")
+
+		links, err := mux.Links(r).Add("self", "book", "id", "1").Build()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if href := links["self"].Href; href != "https://example.com/api/books/1" {
+			t.Errorf("expected href %q but got %q", "https://example.com/api/books/1", href)
+		}
+	})
+
+	t.Run("first error is returned", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+
+		_, err := mux.Links(r).Add("self", "book").Add("other", "missing").Build()
+		if expected := `muxter: failed to generate url for route "book": missing value for param "id"`; err == nil || err.Error() != expected {
+			t.Errorf("expected error %q but got %v", expected, err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package muxter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+)
+
+type loggerOptions struct {
+	captureLimit int
+	redact       func(contentType string, body []byte) []byte
+}
+
+type LoggerOption func(*loggerOptions)
+
+// CaptureRequestBody is an option for the Logger that records up to limit bytes of the request body in the
+// RespOverview. Only the bytes the handler reads are captured, so the handler is not affected. Bodies with a gzip
+// content-encoding are decompressed, whether or not the Decompress middleware is in use, and the decompressed body
+// is subject to the same limit. It is intended for debugging and should be used together with RedactRequestBody
+// when bodies can contain secrets.
+func CaptureRequestBody(limit int) LoggerOption {
+	return func(lo *loggerOptions) {
+		lo.captureLimit = limit
+	}
+}
+
+// RedactRequestBody is an option for the Logger that applies redact to captured request bodies before they are
+// handed to the log function. Redact receives the request's content type and the decompressed body, which may be
+// truncated, and returns the body to log.
+func RedactRequestBody(redact func(contentType string, body []byte) []byte) LoggerOption {
+	return func(lo *loggerOptions) {
+		lo.redact = redact
+	}
+}
+
+// bodyCapture records the first limit bytes read from the request body.
+type bodyCapture struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (bc *bodyCapture) Read(p []byte) (int, error) {
+	n, err := bc.ReadCloser.Read(p)
+	if remaining := bc.limit - bc.buf.Len(); n > remaining {
+		bc.buf.Write(p[:remaining])
+		bc.truncated = true
+	} else {
+		bc.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// body returns the captured body in readable form. Bodies that were captured before being decompressed, that is
+// when the Logger runs outside of the Decompress middleware, are decompressed here.
+func (bc *bodyCapture) body(r *http.Request, redact func(string, []byte) []byte) (body []byte, truncated bool) {
+	body, truncated = bc.buf.Bytes(), bc.truncated
+
+	if _, decompressed := bc.ReadCloser.(*gzip.Reader); !decompressed && r.Header.Get("Content-Encoding") == "gzip" {
+		body, truncated = gunzipPrefix(body, bc.limit, truncated)
+	}
+
+	if redact != nil {
+		body = redact(r.Header.Get("Content-Type"), body)
+	}
+
+	return body, truncated
+}
+
+// gunzipPrefix decompresses up to limit bytes of a possibly incomplete gzip stream. Data that cannot be decompressed
+// is returned as is.
+func gunzipPrefix(data []byte, limit int, truncated bool) ([]byte, bool) {
+	gr := gzipReaders.Get().(*gzip.Reader)
+	defer gzipReaders.Put(gr)
+
+	if err := gr.Reset(bytes.NewReader(data)); err != nil {
+		return data, truncated
+	}
+	defer gr.Close()
+
+	var buf bytes.Buffer
+	n, _ := io.Copy(&buf, io.LimitReader(gr, int64(limit)+1))
+	if n > int64(limit) {
+		return buf.Bytes()[:limit], true
+	}
+	return buf.Bytes(), truncated
+}
@@ -0,0 +1,126 @@
+package muxter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerCaptureRequestBody(t *testing.T) {
+	gzipped := func(value string) []byte {
+		buf := new(bytes.Buffer)
+		gw := gzip.NewWriter(buf)
+		io.WriteString(gw, value)
+		gw.Close()
+		return buf.Bytes()
+	}
+
+	redact := RedactRequestBody(func(contentType string, body []byte) []byte {
+		if contentType != "application/json" {
+			return body
+		}
+		return bytes.ReplaceAll(body, []byte("hunter2"), []byte("*******"))
+	})
+
+	testcases := []struct {
+		Name              string
+		Body              []byte
+		Gzip              bool
+		Options           []LoggerOption
+		Middlewares       []Middleware
+		ExpectedBody      string
+		ExpectedTruncated bool
+	}{
+		{
+			Name:         "plain",
+			Body:         []byte(`{"user":"bob"}`),
+			Options:      []LoggerOption{CaptureRequestBody(64)},
+			ExpectedBody: `{"user":"bob"}`,
+		},
+		{
+			Name:              "truncated",
+			Body:              []byte(`{"user":"bob"}`),
+			Options:           []LoggerOption{CaptureRequestBody(5)},
+			ExpectedBody:      `{"use`,
+			ExpectedTruncated: true,
+		},
+		{
+			Name:         "gzip without decompress",
+			Body:         gzipped(`{"user":"bob"}`),
+			Gzip:         true,
+			Options:      []LoggerOption{CaptureRequestBody(64)},
+			ExpectedBody: `{"user":"bob"}`,
+		},
+		{
+			Name:         "gzip with decompress",
+			Body:         gzipped(`{"user":"bob"}`),
+			Gzip:         true,
+			Options:      []LoggerOption{CaptureRequestBody(64)},
+			Middlewares:  []Middleware{Decompress},
+			ExpectedBody: `{"user":"bob"}`,
+		},
+		{
+			Name:              "gzip truncated after decompression",
+			Body:              gzipped(strings.Repeat("a", 100)),
+			Gzip:              true,
+			Options:           []LoggerOption{CaptureRequestBody(64)},
+			ExpectedBody:      strings.Repeat("a", 64),
+			ExpectedTruncated: true,
+		},
+		{
+			Name:         "redacted",
+			Body:         []byte(`{"password":"hunter2"}`),
+			Options:      []LoggerOption{CaptureRequestBody(64), redact},
+			ExpectedBody: `{"password":"*******"}`,
+		},
+		{
+			Name:         "not captured",
+			Body:         []byte(`{"user":"bob"}`),
+			ExpectedBody: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var overview RespOverview
+
+			logger := Logger(io.Discard, func(o RespOverview) string {
+				overview = o
+				return ""
+			}, tc.Options...)
+
+			var received []byte
+
+			mux := New()
+			mux.HandleFunc(
+				"/",
+				func(w http.ResponseWriter, r *http.Request, c Context) {
+					received, _ = io.ReadAll(r.Body)
+				},
+				append([]Middleware{logger}, tc.Middlewares...)...,
+			)
+
+			r := httptest.NewRequest("POST", "/", bytes.NewReader(tc.Body))
+			r.Header.Set("Content-Type", "application/json")
+			if tc.Gzip {
+				r.Header.Set("Content-Encoding", "gzip")
+			}
+
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(tc.Middlewares) == 0 && !bytes.Equal(received, tc.Body) {
+				t.Errorf("expected handler to receive the unmodified body")
+			}
+			if actual := string(overview.RequestBody); actual != tc.ExpectedBody {
+				t.Errorf("expected captured body to be %q but got %q", tc.ExpectedBody, actual)
+			}
+			if overview.RequestBodyTruncated != tc.ExpectedTruncated {
+				t.Errorf("expected truncated to be %v but got %v", tc.ExpectedTruncated, overview.RequestBodyTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package muxter
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// LoginRedirect implements the redirect-after-login flow of server rendered apps: requests for protected routes made
+// without being authenticated are redirected to the login page, remembering the URL they were for, and once the user
+// has logged in the login handler resumes the flow by redirecting back to it.
+//
+// The URL to return to is kept in a short-lived cookie. As the cookie comes from the client it is only redirected to
+// with SafeRedirect, so that a forged cookie cannot be used as an open redirect.
+//
+//	login := muxter.LoginRedirect{LoginURL: "/login", Authenticated: isLoggedIn}
+//
+//	mux.Handle("/account/", accountMux, login.Require)
+//	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+//		// authenticate the user, then:
+//		login.Resume(w, r, "/")
+//	}, mux.Method("POST"))
+type LoginRedirect struct {
+	// LoginURL is the URL of the login page.
+	LoginURL string
+	// Authenticated reports whether the request is made by an authenticated user.
+	Authenticated func(r *http.Request) bool
+	// Cookie is the name of the cookie holding the URL to return to. It defaults to muxter_return_to.
+	Cookie string
+	// MaxAge is how long the URL to return to is remembered. It defaults to 10 minutes.
+	MaxAge time.Duration
+	// Secure marks the cookie as Secure.
+	Secure bool
+	// AllowedHosts are the hosts other than the request's host that can be returned to, as accepted by SafeRedirect.
+	AllowedHosts []string
+}
+
+// Require is a middleware redirecting requests that are not authenticated to the login page. The URL of GET and HEAD
+// requests is remembered to be returned to after logging in; other requests are not as they cannot be replayed by a
+// redirect.
+func (l LoginRedirect) Require(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if l.Authenticated(r) {
+			h.ServeHTTPx(w, r, c)
+			return
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			l.Stash(w, r, c.requestURL(r).RequestURI())
+		}
+		http.Redirect(w, r, l.LoginURL, redirectCode(r))
+	})
+}
+
+// Stash remembers target as the URL to return to after logging in, for handlers that redirect to the login page
+// themselves.
+func (l LoginRedirect) Stash(w http.ResponseWriter, r *http.Request, target string) {
+	maxAge := l.MaxAge
+	if maxAge == 0 {
+		maxAge = 10 * time.Minute
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     l.cookie(),
+		Value:    url.QueryEscape(target),
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   l.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Resume redirects the request to the URL remembered before logging in and forgets it. It redirects to fallback
+// when no URL is remembered or when the remembered URL is refused by SafeRedirect.
+func (l LoginRedirect) Resume(w http.ResponseWriter, r *http.Request, fallback string) {
+	var target string
+	if cookie, err := r.Cookie(l.cookie()); err == nil {
+		target, _ = url.QueryUnescape(cookie.Value)
+		http.SetCookie(w, &http.Cookie{
+			Name:     l.cookie(),
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   l.Secure,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	if target != "" && SafeRedirect(w, r, target, l.AllowedHosts...) == nil {
+		return
+	}
+
+	http.Redirect(w, r, fallback, redirectCode(r))
+}
+
+func (l LoginRedirect) cookie() string {
+	if l.Cookie == "" {
+		return "muxter_return_to"
+	}
+	return l.Cookie
+}
@@ -0,0 +1,98 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoginRedirect(t *testing.T) {
+	login := LoginRedirect{
+		LoginURL:      "/login",
+		Authenticated: func(r *http.Request) bool { return r.Header.Get("Authorization") != "" },
+	}
+
+	account := New()
+	account.HandleFunc("/account/settings", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte("settings"))
+	})
+
+	mux := New()
+	mux.Handle("/account/", account, login.Require)
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request, c Context) {
+		login.Resume(w, r, "/home")
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/account/settings", nil)
+		r.Header.Set("Authorization", "Bearer token")
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != 200 || w.Body.String() != "settings" {
+			t.Errorf("expected the route to be served but got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("unauthenticated post", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/account/settings", nil)
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != 303 || w.Header().Get("Location") != "/login" {
+			t.Errorf("expected a 303 redirect to /login but got %d %q", w.Code, w.Header().Get("Location"))
+		}
+		if cookies := w.Result().Cookies(); len(cookies) != 0 {
+			t.Errorf("expected no URL to be remembered but got %v", cookies)
+		}
+	})
+
+	w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/account/settings?tab=billing", nil)
+	mux.ServeHTTP(w, r)
+
+	if w.Code != 302 || w.Header().Get("Location") != "/login" {
+		t.Fatalf("expected a 302 redirect to /login but got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "muxter_return_to" || !cookies[0].HttpOnly {
+		t.Fatalf("expected the URL to be remembered in an HttpOnly cookie but got %v", cookies)
+	}
+	stashed := cookies[0]
+
+	testcases := []struct {
+		Name             string
+		Cookie           *http.Cookie
+		ExpectedLocation string
+	}{
+		{Name: "resumed", Cookie: stashed, ExpectedLocation: "/account/settings?tab=billing"},
+		{Name: "nothing remembered", ExpectedLocation: "/home"},
+		{
+			Name:             "forged cookie",
+			Cookie:           &http.Cookie{Name: "muxter_return_to", Value: "%2F%2Fevil.com"},
+			ExpectedLocation: "/home",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/login", nil)
+			if tc.Cookie != nil {
+				r.AddCookie(tc.Cookie)
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != 303 {
+				t.Errorf("expected code 303 but got %d", w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tc.ExpectedLocation {
+				t.Errorf("expected location %q but got %q", tc.ExpectedLocation, location)
+			}
+			if tc.Cookie != nil {
+				if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge != -1 {
+					t.Errorf("expected the remembered URL to be forgotten but got %v", cookies)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package muxter
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MaintenanceExempt is a registration option exempting a route from maintenance mode, such as health checks and the
+// admin API, which must remain reachable to turn maintenance mode off.
+//
+//	mux.Handle("/healthz", healthz, muxter.MaintenanceExempt())
+func MaintenanceExempt() Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.MaintenanceExempt = true
+	})
+}
+
+type maintenance struct {
+	enabled    atomic.Bool
+	retryAfter atomic.Int64
+}
+
+// SetMaintenance turns maintenance mode on or off. In maintenance mode the routes of the mux that are not
+// MaintenanceExempt are answered with 503 Service Unavailable, with a Retry-After header if retryAfter is positive.
+// Requests not matching a route are still served as not found. It is safe to call while the mux is serving
+// requests.
+func (m *Mux) SetMaintenance(enabled bool, retryAfter time.Duration) {
+	m.maintenance.retryAfter.Store(int64(retryAfter))
+	m.maintenance.enabled.Store(enabled)
+}
+
+// Maintenance reports whether the mux is in maintenance mode, and the Retry-After it advertises.
+func (m *Mux) Maintenance() (enabled bool, retryAfter time.Duration) {
+	return m.maintenance.enabled.Load(), time.Duration(m.maintenance.retryAfter.Load())
+}
+
+// applies reports whether the route is answered as unavailable.
+func (mm *maintenance) applies(route *RouteInfo) bool {
+	return mm != nil && mm.enabled.Load() && (route == nil || !route.MaintenanceExempt)
+}
+
+func (mm *maintenance) handler() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if retryAfter := time.Duration(mm.retryAfter.Load()); retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		writeStatus(w, c, http.StatusServiceUnavailable)
+	})
+}
@@ -0,0 +1,191 @@
+package muxter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MatchHeader is a registration option that makes the route match only requests with the header set to the value,
+// or set to any value if value is empty. A pattern can be registered several times as long as at most one of the
+// registrations has no matchers: requests are served by the first registration, in registration order, whose
+// matchers all match, or else by the registration without matchers. If no registration matches, the request is
+// not found.
+//
+//	mux.Handle("/orders", partnerOrders, muxter.MatchHeader("X-Api-Key-Type", "partner"))
+//	mux.Handle("/orders", orders)
+func MatchHeader(key, value string) Middleware {
+	return matcher("header "+http.CanonicalHeaderKey(key)+"="+value, headerMatcher(key, value))
+}
+
+func headerMatcher(key, value string) func(*http.Request) bool {
+	key = http.CanonicalHeaderKey(key)
+	return func(r *http.Request) bool {
+		values, ok := r.Header[key]
+		if !ok {
+			return false
+		}
+		if value == "" {
+			return true
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Query is a registration option that makes the route match only requests with the query parameter set to the
+// value, or present with any value if value is empty. Like MatchHeader it allows a pattern to be registered several
+// times, requests not matching falling through to the other registrations of the pattern.
+//
+//	mux.Handle("/reports", legacyReports, muxter.Query("mode", "legacy"))
+//	mux.Handle("/reports", reports)
+func Query(key, value string) Middleware {
+	return matcher("query "+key+"="+value, queryMatcher(key, value))
+}
+
+func queryMatcher(key, value string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		values, ok := r.URL.Query()[key]
+		if !ok {
+			return false
+		}
+		if value == "" {
+			return true
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchScheme is a registration option that makes the route match only requests with one of the schemes, "http" or
+// "https", or "ws" and "wss" for WebSocket upgrade requests. The scheme is given by the X-Forwarded-Proto header when
+// it is set, and otherwise by whether the request was received over TLS. Like MatchHeader it allows a pattern to be
+// registered several times, for example to redirect plain HTTP traffic to TLS.
+//
+//	mux.Handle("/", muxter.RedirectToHTTPS(), muxter.MatchScheme("http"))
+//	mux.Handle("/", app)
+func MatchScheme(schemes ...string) Middleware {
+	schemes = append([]string{}, schemes...)
+	for i, scheme := range schemes {
+		schemes[i] = strings.ToLower(scheme)
+	}
+	return matcher("scheme "+strings.Join(schemes, "|"), func(r *http.Request) bool {
+		scheme := matchedScheme(r)
+		for _, s := range schemes {
+			if s == scheme {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// matchedScheme returns the scheme of the request, ws or wss for WebSocket upgrade requests.
+func matchedScheme(r *http.Request) string {
+	scheme := requestScheme(r)
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return scheme
+	}
+	if scheme == "https" {
+		return "wss"
+	}
+	return "ws"
+}
+
+// RedirectToHTTPS returns a handler redirecting requests to the same URL with the https scheme. GET and HEAD requests
+// are redirected with 301 Moved Permanently and other requests with 308 Permanent Redirect so that clients repeat
+// them with the same method and body.
+func RedirectToHTTPS() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+normalizeHost(r.Host, "http")+c.requestURL(r).RequestURI(), code)
+	})
+}
+
+// HeaderMatch returns a handler dispatching requests whose header has the value to matched and other requests to
+// otherwise. It is an alternative to the MatchHeader registration option for handlers composed outside of a mux.
+//
+//	mux.Handle("/orders", muxter.HeaderMatch("X-Api-Key-Type", "partner", partnerOrders, orders))
+func HeaderMatch(key, value string, matched, otherwise Handler) Handler {
+	match := headerMatcher(key, value)
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if match(r) {
+			matched.ServeHTTPx(w, r, c)
+			return
+		}
+		otherwise.ServeHTTPx(w, r, c)
+	})
+}
+
+// matcher returns a registration option adding the match function, described by description, to the route.
+func matcher(description string, match func(*http.Request) bool) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Matchers = append(ri.Matchers, description)
+		ri.match = append(ri.match, match)
+	})
+}
+
+func matchRequest(match []func(*http.Request) bool, r *http.Request) bool {
+	for _, fn := range match {
+		if !fn(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditional reports whether the value is only matched by requests satisfying its route's matchers.
+func (v *value) conditional() bool {
+	return v.route != nil && len(v.route.match) > 0
+}
+
+// addAlternative registers alt as an alternative to v for the same pattern. It fails if both v, or one of its
+// alternatives, and alt are unconditional.
+func (v *value) addAlternative(alt *value) error {
+	if !alt.conditional() {
+		if !v.conditional() {
+			return registrationConflict{v}
+		}
+		for _, existing := range v.alternatives {
+			if !existing.conditional() {
+				return registrationConflict{existing}
+			}
+		}
+	}
+	v.alternatives = append(v.alternatives, alt)
+	return nil
+}
+
+// candidate returns the value among v and its alternatives that serves the request: the first conditional value
+// whose matchers match, or else the unconditional value. It returns nil if no value matches.
+func (v *value) candidate(r *http.Request) *value {
+	if v.alternatives == nil && !v.conditional() {
+		return v
+	}
+
+	var fallback *value
+	for i := -1; i < len(v.alternatives); i++ {
+		candidate := v
+		if i >= 0 {
+			candidate = v.alternatives[i]
+		}
+		if !candidate.conditional() {
+			fallback = candidate
+			continue
+		}
+		if matchRequest(candidate.route.match, r) {
+			return candidate
+		}
+	}
+	return fallback
+}
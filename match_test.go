@@ -0,0 +1,315 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchHeader(t *testing.T) {
+	respond := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) { w.Write([]byte(name)) }
+	}
+
+	mux := New()
+	mux.Handle("/orders/:id", respond("partner"), MatchHeader("x-api-key-type", "partner"))
+	mux.Handle("/orders/:id", respond("beta"), MatchHeader("X-Beta", ""))
+	mux.Handle("/orders/:id", respond("default"))
+	mux.Handle("/internal", respond("internal"), MatchHeader("X-Internal", "true"))
+	mux.Handle("/combined", HeaderMatch("X-Api-Key-Type", "partner", respond("partner"), respond("default")))
+
+	testcases := []struct {
+		Name         string
+		Path         string
+		Header       map[string]string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:         "matched",
+			Path:         "/orders/1",
+			Header:       map[string]string{"X-Api-Key-Type": "partner"},
+			ExpectedCode: 200,
+			ExpectedBody: "partner",
+		},
+		{
+			Name:         "presence",
+			Path:         "/orders/1",
+			Header:       map[string]string{"X-Beta": "1"},
+			ExpectedCode: 200,
+			ExpectedBody: "beta",
+		},
+		{
+			Name:         "registration order",
+			Path:         "/orders/1",
+			Header:       map[string]string{"X-Beta": "1", "X-Api-Key-Type": "partner"},
+			ExpectedCode: 200,
+			ExpectedBody: "partner",
+		},
+		{
+			Name:         "unconditional fallback",
+			Path:         "/orders/1",
+			Header:       map[string]string{"X-Api-Key-Type": "internal"},
+			ExpectedCode: 200,
+			ExpectedBody: "default",
+		},
+		{
+			Name:         "no match",
+			Path:         "/internal",
+			ExpectedCode: 404,
+			ExpectedBody: "Not Found\n",
+		},
+		{
+			Name:         "handler matched",
+			Path:         "/combined",
+			Header:       map[string]string{"X-Api-Key-Type": "partner"},
+			ExpectedCode: 200,
+			ExpectedBody: "partner",
+		},
+		{
+			Name:         "handler otherwise",
+			Path:         "/combined",
+			ExpectedCode: 200,
+			ExpectedBody: "default",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.Path, nil)
+			for key, value := range tc.Header {
+				r.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+
+	var matchers [][]string
+	for _, route := range mux.Routes() {
+		if route.Pattern == "/orders/:id" {
+			matchers = append(matchers, route.Matchers)
+		}
+	}
+	if len(matchers) != 3 {
+		t.Errorf("expected the three registrations of /orders/:id to be listed but got %v", matchers)
+	}
+}
+
+func TestMatchHeaderConflict(t *testing.T) {
+	defer func() {
+		recovered, _ := recover().(string)
+		if !strings.Contains(recovered, "multiple registrations") {
+			t.Errorf("expected multiple registrations panic but got %q", recovered)
+		}
+	}()
+
+	mux := New()
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+	mux.Handle("/orders", noop, MatchHeader("X-Beta", ""))
+	mux.Handle("/orders", noop)
+	mux.Handle("/orders", noop)
+}
+
+func TestRouteMatchersDescription(t *testing.T) {
+	mux := New()
+	mux.Handle("/orders", HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {}), MatchHeader("x-api-key-type", "partner"))
+
+	if matchers := mux.Routes()[0].Matchers; !reflect.DeepEqual(matchers, []string{"header X-Api-Key-Type=partner"}) {
+		t.Errorf("unexpected matchers: %v", matchers)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	respond := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) { w.Write([]byte(name)) }
+	}
+
+	mux := New()
+	mux.Handle("/reports/:id", respond("legacy"), Query("mode", "legacy"))
+	mux.Handle("/reports/:id", respond("preview"), Query("preview", ""), MatchHeader("X-Beta", ""))
+	mux.Handle("/reports/:id", respond("default"))
+	mux.Handle("/export", respond("csv"), Query("format", "csv"))
+
+	testcases := []struct {
+		Name         string
+		Target       string
+		Header       map[string]string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:         "matched",
+			Target:       "/reports/1?mode=legacy",
+			ExpectedCode: 200,
+			ExpectedBody: "legacy",
+		},
+		{
+			Name:         "any of the values",
+			Target:       "/reports/1?mode=new&mode=legacy",
+			ExpectedCode: 200,
+			ExpectedBody: "legacy",
+		},
+		{
+			Name:         "other value",
+			Target:       "/reports/1?mode=new",
+			ExpectedCode: 200,
+			ExpectedBody: "default",
+		},
+		{
+			Name:         "presence with header",
+			Target:       "/reports/1?preview",
+			Header:       map[string]string{"X-Beta": "1"},
+			ExpectedCode: 200,
+			ExpectedBody: "preview",
+		},
+		{
+			Name:         "all matchers must match",
+			Target:       "/reports/1?preview",
+			ExpectedCode: 200,
+			ExpectedBody: "default",
+		},
+		{
+			Name:         "matched without fallback",
+			Target:       "/export?format=csv",
+			ExpectedCode: 200,
+			ExpectedBody: "csv",
+		},
+		{
+			Name:         "no match",
+			Target:       "/export",
+			ExpectedCode: 404,
+			ExpectedBody: "Not Found\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.Target, nil)
+			for key, value := range tc.Header {
+				r.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+
+	if matchers := mux.Routes()[0].Matchers; !reflect.DeepEqual(matchers, []string{"query format=csv"}) {
+		t.Errorf("unexpected matchers: %v", matchers)
+	}
+}
+
+func TestMatchScheme(t *testing.T) {
+	respond := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) { w.Write([]byte(name)) }
+	}
+
+	mux := New()
+	mux.Handle("/socket", respond("secure socket"), MatchScheme("wss"))
+	mux.Handle("/socket", respond("socket"), MatchScheme("WS"))
+	mux.Handle("/api/", RedirectToHTTPS(), MatchScheme("http"))
+	mux.Handle("/api/", respond("api"))
+
+	testcases := []struct {
+		Name             string
+		Method           string
+		Target           string
+		Header           map[string]string
+		ExpectedCode     int
+		ExpectedBody     string
+		ExpectedLocation string
+	}{
+		{
+			Name:             "redirect get",
+			Method:           "GET",
+			Target:           "http://example.com:80/api/books?page=2",
+			ExpectedCode:     301,
+			ExpectedLocation: "https://example.com/api/books?page=2",
+		},
+		{
+			Name:             "redirect post",
+			Method:           "POST",
+			Target:           "http://example.com/api/books",
+			ExpectedCode:     308,
+			ExpectedLocation: "https://example.com/api/books",
+		},
+		{
+			Name:         "tls",
+			Method:       "GET",
+			Target:       "https://example.com/api/books",
+			ExpectedCode: 200,
+			ExpectedBody: "api",
+		},
+		{
+			Name:         "forwarded proto",
+			Method:       "GET",
+			Target:       "http://example.com/api/books",
+			Header:       map[string]string{"X-Forwarded-Proto": "https"},
+			ExpectedCode: 200,
+			ExpectedBody: "api",
+		},
+		{
+			Name:         "websocket",
+			Method:       "GET",
+			Target:       "http://example.com/socket",
+			Header:       map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"},
+			ExpectedCode: 200,
+			ExpectedBody: "socket",
+		},
+		{
+			Name:         "secure websocket",
+			Method:       "GET",
+			Target:       "https://example.com/socket",
+			Header:       map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"},
+			ExpectedCode: 200,
+			ExpectedBody: "secure socket",
+		},
+		{
+			Name:         "not an upgrade",
+			Method:       "GET",
+			Target:       "https://example.com/socket",
+			ExpectedCode: 404,
+			ExpectedBody: "Not Found\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.Method, tc.Target, nil)
+			for key, value := range tc.Header {
+				r.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+			if location := w.Header().Get("Location"); location != tc.ExpectedLocation {
+				t.Errorf("expected location %q but got %q", tc.ExpectedLocation, location)
+			}
+		})
+	}
+}
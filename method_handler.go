@@ -16,16 +16,6 @@ type MethodHandler struct {
 }
 
 func (mh MethodHandler) getHandler(method string) (handler Handler) {
-	defer func() {
-		if handler == nil {
-			if mh.MethodNotAllowedHandler == nil {
-				handler = defaultMethodNotAllowedHandler
-			} else {
-				handler = mh.MethodNotAllowedHandler
-			}
-		}
-	}()
-
 	switch strings.ToUpper(method) {
 	case "GET":
 		return mh.GET
@@ -44,6 +34,36 @@ func (mh MethodHandler) getHandler(method string) (handler Handler) {
 	}
 }
 
+// methods returns the methods for which the MethodHandler has a handler.
+func (mh MethodHandler) methods() []string {
+	var methods []string
+	for _, h := range []struct {
+		method  string
+		handler Handler
+	}{
+		{"GET", mh.GET},
+		{"HEAD", mh.HEAD},
+		{"POST", mh.POST},
+		{"PUT", mh.PUT},
+		{"PATCH", mh.PATCH},
+		{"DELETE", mh.DELETE},
+	} {
+		if h.handler != nil {
+			methods = append(methods, h.method)
+		}
+	}
+	return methods
+}
+
 func (mh MethodHandler) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {
-	mh.getHandler(r.Method).ServeHTTPx(w, r, c)
+	if handler := mh.getHandler(r.Method); handler != nil {
+		handler.ServeHTTPx(w, r, c)
+		return
+	}
+
+	notAllowed := mh.MethodNotAllowedHandler
+	if notAllowed == nil {
+		notAllowed = defaultMethodNotAllowedHandler
+	}
+	methodNotAllowed(w, r, c, notAllowed, mh.methods())
 }
@@ -0,0 +1,66 @@
+package muxter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MethodPolicy decides how requests matching the path of a route but none of its methods are answered.
+type MethodPolicy int
+
+const (
+	// MethodPolicyNotAllowed answers with the method not allowed handler of the mux, 405 Method Not Allowed by
+	// default, and an Allow header listing the methods of the route.
+	MethodPolicyNotAllowed MethodPolicy = iota + 1
+	// MethodPolicyNotFound answers as if no route matched the path, with the fallback or the not found handler of the
+	// mux, such that clients cannot tell which paths exist.
+	MethodPolicyNotFound
+)
+
+// MethodMismatch sets the policy answering the requests matching the path of a route but none of its methods, as
+// guarded by the method registration helpers or a MethodHandler. Without a policy such requests are answered by the
+// guard of the route, with the method not allowed handler of the mux it was registered on, whereas requests matching
+// no path are answered by the innermost mux: the status a client sees then depends on how muxes are nested and which
+// of them have handlers set. With a policy, nested muxes without a policy of their own report method mismatches to
+// the mux that has one, which answers them with its own handlers wherever the route was registered.
+//
+//	mux := muxter.New(muxter.MethodMismatch(muxter.MethodPolicyNotAllowed))
+//	mux.SetMethodNotAllowedHandler(problemJSON(405))
+//	mux.Handle("/api/", muxter.StripDepth(1, api)) // api routes answer mismatches with problemJSON(405)
+func MethodMismatch(policy MethodPolicy) MuxOption {
+	return func(m *Mux) {
+		m.methodPolicy = policy
+	}
+}
+
+// methodNotAllowed answers a request matching a route but none of the allowed methods, with the policy of the mux
+// of the context if it has one and with h otherwise.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, c Context, h Handler, allowed []string) {
+	m := c.extra().methodMux
+	if m == nil {
+		h.ServeHTTPx(w, r, c)
+		return
+	}
+
+	switch m.methodPolicy {
+	case MethodPolicyNotFound:
+		handler := c.fallback
+		if handler == nil {
+			handler = m.notFoundHandler
+			c.extra().events.publish(eventNotFound, NotFoundEvent{Request: r, Path: c.requestURL(r).Path})
+		}
+		if handler == nil {
+			handler = defaultNotFoundHandler
+		}
+		handler.ServeHTTPx(w, r, c)
+	default:
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+		handler := m.methodNotAllowedHandler
+		if handler == nil {
+			handler = defaultMethodNotAllowedHandler
+		}
+		handler.ServeHTTPx(w, r, c)
+	}
+}
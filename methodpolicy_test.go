@@ -0,0 +1,107 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMethodMismatch(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request, c Context) {}
+	status := func(code int, body string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.WriteHeader(code)
+			io.WriteString(w, body)
+		}
+	}
+
+	newMux := func(opts ...MuxOption) *Mux {
+		child := New()
+		child.GetFunc("/users", ok)
+		child.Handle("/books", MethodHandler{GET: HandlerFunc(ok), POST: HandlerFunc(ok)})
+		child.SetNotFoundHandlerFunc(status(404, "child not found"))
+
+		mux := New(opts...)
+		mux.SetNotFoundHandlerFunc(status(404, "parent not found"))
+		mux.SetMethodNotAllowedHandlerFunc(status(405, "parent not allowed"))
+		mux.Handle("/api/", StripDepth(1, child))
+		mux.GetFunc("/health", ok)
+		return mux
+	}
+
+	cases := []struct {
+		Name          string
+		Options       []MuxOption
+		Fallback      bool
+		Path          string
+		ExpectedCode  int
+		ExpectedBody  string
+		ExpectedAllow string
+	}{
+		{Name: "default parent route", Path: "/health", ExpectedCode: 405, ExpectedBody: "parent not allowed"},
+		{Name: "default child route", Path: "/api/users", ExpectedCode: 405, ExpectedBody: "Method Not Allowed\n"},
+		{
+			Name:          "not allowed child route",
+			Options:       []MuxOption{MethodMismatch(MethodPolicyNotAllowed)},
+			Path:          "/api/users",
+			ExpectedCode:  405,
+			ExpectedBody:  "parent not allowed",
+			ExpectedAllow: "GET",
+		},
+		{
+			Name:          "not allowed child method handler",
+			Options:       []MuxOption{MethodMismatch(MethodPolicyNotAllowed)},
+			Path:          "/api/books",
+			ExpectedCode:  405,
+			ExpectedBody:  "parent not allowed",
+			ExpectedAllow: "GET, POST",
+		},
+		{
+			Name:         "not found child route",
+			Options:      []MuxOption{MethodMismatch(MethodPolicyNotFound)},
+			Path:         "/api/users",
+			ExpectedCode: 404,
+			ExpectedBody: "parent not found",
+		},
+		{
+			Name:         "not found parent route",
+			Options:      []MuxOption{MethodMismatch(MethodPolicyNotFound)},
+			Path:         "/health",
+			ExpectedCode: 404,
+			ExpectedBody: "parent not found",
+		},
+		{
+			Name:         "not found fallback",
+			Options:      []MuxOption{MethodMismatch(MethodPolicyNotFound)},
+			Fallback:     true,
+			Path:         "/api/books",
+			ExpectedCode: 200,
+			ExpectedBody: "legacy /api/books",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mux := newMux(tc.Options...)
+			if tc.Fallback {
+				mux.Fallback(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					io.WriteString(w, "legacy "+r.URL.Path)
+				}))
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("DELETE", tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+			if allow := w.Header().Get("Allow"); allow != tc.ExpectedAllow {
+				t.Errorf("expected Allow %q but got %q", tc.ExpectedAllow, allow)
+			}
+		})
+	}
+}
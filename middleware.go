@@ -1,36 +1,254 @@
 package muxter
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"container/list"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Middleware is a function that takes a handler and modifies its behaviour by returning a new handler
 type Middleware = func(Handler) Handler
 
+// WithMiddleware returns the handler wrapped by the middlewares, the first of them being the outermost. Registration
+// options such as Name or Tags are discarded, since the handler is not registered as a route: they must be
+// passed to Handle, or to Use, to take effect.
+//
+//	handler := muxter.WithMiddleware(api, logging, auth)
 func WithMiddleware(handler Handler, middlewares ...Middleware) Handler {
+	handler, _ = withMiddleware(handler, middlewares, nil, false)
+	return handler
+}
+
+// withMiddleware composes the middlewares over the handler. Registration options found in the chain are applied
+// to route if it is not nil, and are otherwise discarded. When middlewares with the same identity are found in the
+// chain only the outermost of them is applied, and the others are not constructed. It returns the identities of the
+// applied middlewares by position in the chain, or nil if none of them have an identity. When timed is set the
+// middlewares with an identity are measured as layers, see TimeMiddlewares.
+func withMiddleware(handler Handler, middlewares []Middleware, route *RouteInfo, timed bool) (Handler, []identified) {
 	if handler == nil {
-		return nil
+		return nil, nil
+	}
+
+	var identities []*identity
+	var duplicates []bool
+	for i, middleware := range middlewares {
+		ident := identityOf(middleware)
+		if ident == nil {
+			continue
+		}
+		if identities == nil {
+			identities = make([]*identity, len(middlewares))
+		}
+		identities[i] = ident
+		for _, outer := range identities[:i] {
+			if outer != nil && outer.same(ident) {
+				if duplicates == nil {
+					duplicates = make([]bool, len(middlewares))
+				}
+				duplicates[i] = true
+				break
+			}
+		}
 	}
+
+	return compose(handler, middlewares, route, duplicates, timed)
+}
+
+// compose applies the middlewares that are not skipped over the handler. It returns the identities of the
+// middlewares by position, where registration options are marked as such, or nil if no middleware has an identity.
+func compose(handler Handler, middlewares []Middleware, route *RouteInfo, skip []bool, timed bool) (Handler, []identified) {
+	var idents []identified
+	var options []int
 	for i := len(middlewares) - 1; i >= 0; i-- {
+		if skip != nil && skip[i] {
+			continue
+		}
 		handler = middlewares[i](handler)
+		switch opt := handler.(type) {
+		case routeOption:
+			if route != nil {
+				opt.apply(route)
+			}
+			handler = opt.Handler
+			options = append(options, i)
+		case identified:
+			if idents == nil {
+				idents = make([]identified, len(middlewares))
+			}
+			idents[i] = identified{id: opt.id, constraints: opt.constraints}
+			handler = opt.Handler
+			if timed {
+				handler = timedLayer{Handler: handler, name: opt.id}
+			}
+		}
 	}
-	return handler
+	if idents != nil {
+		for _, i := range options {
+			idents[i].option = true
+		}
+	}
+	return handler, idents
+}
+
+// identified is the handler produced by middlewares with an identity. Like routeOption it is discarded once the
+// middleware chain is composed.
+type identified struct {
+	Handler
+	id          string
+	constraints []OrderConstraint
+	option      bool
+	identity    *identity
+}
+
+// identity is the identity given to a middleware by Identify.
+type identity struct {
+	id          string
+	middleware  Middleware
+	constraints []OrderConstraint
+	// distinct identities are only the same as themselves, for middlewares whose instances differ by their options.
+	distinct bool
+}
+
+// wrap constructs the middleware over the handler. Given an identityProbe, it returns the identity without
+// constructing the middleware.
+func (ident *identity) wrap(h Handler) Handler {
+	if _, ok := h.(identityProbe); ok {
+		return identified{identity: ident}
+	}
+	return identified{Handler: ident.middleware(h), id: ident.id, constraints: ident.constraints, identity: ident}
+}
+
+// same reports whether the middlewares with the identities are the same middleware.
+func (ident *identity) same(other *identity) bool {
+	if ident.distinct || other.distinct {
+		return ident == other
+	}
+	return ident.id == other.id
+}
+
+// identityProbe is the handler given to middlewares by identityOf.
+type identityProbe struct{}
+
+func (identityProbe) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {}
+
+// identityWrap is the code of the middlewares returned by Identify, which are method values of identity.wrap.
+var identityWrap = reflect.ValueOf((&identity{}).wrap).Pointer()
+
+// identityOf returns the identity of a middleware returned by Identify without constructing it, or nil if the
+// middleware has no identity.
+func identityOf(middleware Middleware) *identity {
+	if middleware == nil || reflect.ValueOf(middleware).Pointer() != identityWrap {
+		return nil
+	}
+	ident, _ := middleware(identityProbe{}).(identified)
+	return ident.identity
+}
+
+// Identify gives the middleware an identity. When a route's middlewares, including those registered with Use,
+// contain several middlewares with the same identity only the first, outermost, one is applied, and the others are
+// not constructed. It prevents middlewares that break responses when applied twice, such as Compress, from being
+// applied both globally and per route. Middlewares are only deduplicated within a single mux, not across nested
+// muxes.
+//
+// The constraints declare where the middleware belongs in a chain. They are verified when routes are registered on
+// a mux created with the StrictMiddlewareOrder option.
+func Identify(id string, middleware Middleware, constraints ...OrderConstraint) Middleware {
+	return (&identity{id: id, middleware: middleware, constraints: constraints}).wrap
+}
+
+// OrderConstraint constrains the position of an identified middleware in a middleware chain.
+type OrderConstraint struct {
+	outermost bool
+	before    string
+	after     string
+}
+
+// Outermost constrains a middleware to be the first middleware of the chain, such that it sees the effects of
+// every other middleware.
+func Outermost() OrderConstraint {
+	return OrderConstraint{outermost: true}
+}
+
+// Before constrains a middleware to run before the middleware with the identity, when that middleware is present.
+func Before(id string) OrderConstraint {
+	return OrderConstraint{before: id}
+}
+
+// After constrains a middleware to run after the middleware with the identity, when that middleware is present.
+func After(id string) OrderConstraint {
+	return OrderConstraint{after: id}
+}
+
+// checkOrder verifies the order constraints of a chain's middlewares given their identities by position.
+func checkOrder(idents []identified) error {
+	position := func(id string) int {
+		for i, ident := range idents {
+			if ident.id == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i, ident := range idents {
+		for _, constraint := range ident.constraints {
+			switch {
+			case constraint.outermost:
+				// Distinct instances of a middleware, such as Recovers with their own handler, may nest.
+				for j := 0; j < i; j++ {
+					if !idents[j].option && idents[j].id != ident.id {
+						return fmt.Errorf("middleware %s must be the outermost middleware", ident.id)
+					}
+				}
+			case constraint.before != "":
+				if j := position(constraint.before); j != -1 && j < i {
+					return fmt.Errorf("middleware %s must be applied before %s", ident.id, constraint.before)
+				}
+			case constraint.after != "":
+				if j := position(constraint.after); j != -1 && j > i {
+					return fmt.Errorf("middleware %s must be applied after %s", ident.id, constraint.after)
+				}
+			}
+		}
+	}
+	return nil
 }
 
-// Recover allows you to register a handler function should a panic occur in the stack.
+// Recover allows you to register a handler function should a panic occur in the stack. If recoverHandler is nil
+// a built-in internal server error response is written. Recover is constrained to be the outermost middleware.
+// Recover(nil) is applied once per route, while Recovers with a handler are distinct middlewares: a route can recover
+// its panics with its own handler under a global Recover.
+//
+// Panics with http.ErrAbortHandler, or with errors caused by the client going away as reported by IsClientAbort, are
+// not server errors: they are passed on to the server as http.ErrAbortHandler, which closes the connection without
+// logging, rather than answered with an internal server error.
 func Recover(recoverHandler func(recovered interface{}, w http.ResponseWriter, r *http.Request, c Context)) Middleware {
-	return func(h Handler) Handler {
+	distinct := recoverHandler != nil
+	if recoverHandler == nil {
+		recoverHandler = func(recovered interface{}, w http.ResponseWriter, r *http.Request, c Context) {
+			writeStatus(w, c, http.StatusInternalServerError)
+		}
+	}
+	ident := &identity{id: "muxter.Recover", constraints: []OrderConstraint{Outermost()}, distinct: distinct}
+	ident.middleware = func(h Handler) Handler {
 		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 			defer func() {
-				if recovered := recover(); r != nil {
+				if recovered := recover(); recovered != nil {
+					if recovered == http.ErrAbortHandler || isClientAbortPanic(recovered) {
+						panic(http.ErrAbortHandler)
+					}
+					c.extra().events.publish(eventPanicRecovered, PanicRecoveredEvent{Request: r, Pattern: c.Pattern(), Recovered: recovered})
 					recoverHandler(recovered, w, r, c)
 					return
 				}
@@ -38,6 +256,7 @@ func Recover(recoverHandler func(recovered interface{}, w http.ResponseWriter, r
 			h.ServeHTTPx(w, r, c)
 		})
 	}
+	return ident.wrap
 }
 
 // AccessControlOptions provides options for the CORS middleware.
@@ -50,16 +269,33 @@ type AccessControlOptions struct {
 	// Takes precedence over AllowOrigin.
 	AllowOriginFunc func(origin string) string
 
-	// MaxAge sets the Access-Control-Max-Age property.
+	// OriginCacheTTL memoizes the results of AllowOriginFunc by origin for the duration, for functions doing
+	// expensive work such as looking up the origins of tenants in a database. Results are not cached if it is zero.
+	OriginCacheTTL time.Duration
+
+	// OriginCacheSize is the number of origins whose results are cached. It defaults to 1000.
+	OriginCacheSize int
+
+	// OriginCacheStats, if not nil, counts the hits and misses of the origin cache.
+	OriginCacheStats *OriginCacheStats
+
+	// MaxAge sets the Access-Control-Max-Age property of preflight responses. Routes can override it with the
+	// CORSMaxAge registration option.
 	MaxAge time.Duration
 
+	// AllowCredentials allows credentialed requests. As browsers do not treat "*" as a wildcard in the CORS
+	// headers of credentialed requests, AllowHeaders and AllowMethods of "*" then allow the requested headers and
+	// method, and ExposeHeaders of "*" is not sent.
 	AllowCredentials bool
 	ExposeHeaders    []string
 	AllowHeaders     []string
 	AllowMethods     []string
 }
 
-// CORS creates a middleware for enabling CORS with browsers.
+// CORS creates a middleware for enabling CORS with browsers. Preflight requests are answered by the middleware.
+// For routes registered with method guards, such as with Mux.Get or a MethodHandler, Access-Control-Allow-Methods
+// is the set of methods the route accepts, limited to AllowMethods. Register CORS with Use, or before the method
+// guards of the route, for it to see preflight requests.
 func CORS(opts AccessControlOptions) Middleware {
 	if opts.AllowOrigin == "" {
 		opts.AllowOrigin = "*"
@@ -71,6 +307,15 @@ func CORS(opts AccessControlOptions) Middleware {
 	}
 	allowMethods := strings.Join(opts.AllowMethods, ", ")
 	allowHeaders := strings.Join(opts.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposeHeaders, ", ")
+
+	if opts.AllowCredentials && exposeHeaders == "*" {
+		exposeHeaders = ""
+	}
+
+	if opts.AllowOriginFunc != nil && opts.OriginCacheTTL > 0 {
+		opts.AllowOriginFunc = cacheOrigins(opts)
+	}
 
 	return func(h Handler) Handler {
 		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
@@ -84,78 +329,252 @@ func CORS(opts AccessControlOptions) Middleware {
 				w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
 			}
 
-			if opts.MaxAge > 0 {
-				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
-			}
-
 			if opts.AllowCredentials {
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
 
 			if strings.ToUpper(r.Method) == "OPTIONS" {
-				if allowHeaders != "" {
+				route := c.Route()
+
+				maxAge := opts.MaxAge
+				if route != nil && route.CORSMaxAge != 0 {
+					maxAge = route.CORSMaxAge
+				}
+				if maxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+				} else if maxAge < 0 {
+					w.Header().Set("Access-Control-Max-Age", "0")
+				}
+
+				if allowHeaders != "" && !(opts.AllowCredentials && allowHeaders == "*") {
 					w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
 				} else {
 					w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
 					w.Header().Add("Vary", "Access-Control-Request-Headers")
 				}
 
-				w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+				switch {
+				case route != nil && route.Methods != nil:
+					w.Header().Set("Access-Control-Allow-Methods", routeMethods(route.Methods, opts.AllowMethods))
+				case opts.AllowCredentials && allowMethods == "*":
+					w.Header().Set("Access-Control-Allow-Methods", r.Header.Get("Access-Control-Request-Method"))
+					w.Header().Add("Vary", "Access-Control-Request-Method")
+				default:
+					w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+				}
 
 				w.WriteHeader(204)
 				return
 			}
 
+			if exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+
 			h.ServeHTTPx(w, r, c)
 		})
 	}
 }
 
+// OriginCacheStats counts the hits and misses of the origin cache of the CORS middleware.
+type OriginCacheStats struct {
+	Hits   atomic.Uint64
+	Misses atomic.Uint64
+}
+
+// HitRate returns the ratio of lookups served from the cache, or 0 if there were none.
+func (s *OriginCacheStats) HitRate() float64 {
+	hits, misses := s.Hits.Load(), s.Misses.Load()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+type cachedOrigin struct {
+	origin  string
+	allow   string
+	expires time.Time
+}
+
+// originCache is a least recently used cache of the results of an AllowOriginFunc.
+type originCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// get returns the cached result for the origin, if it has not expired.
+func (cache *originCache) get(origin string, now time.Time) (string, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	elem, ok := cache.entries[origin]
+	if !ok || !now.Before(elem.Value.(cachedOrigin).expires) {
+		return "", false
+	}
+	cache.order.MoveToFront(elem)
+	return elem.Value.(cachedOrigin).allow, true
+}
+
+// set caches the result for the origin, evicting the least recently used origin when the cache is full.
+func (cache *originCache) set(entry cachedOrigin) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.entries[entry.origin]; ok {
+		elem.Value = entry
+		cache.order.MoveToFront(elem)
+		return
+	}
+
+	cache.entries[entry.origin] = cache.order.PushFront(entry)
+	if cache.order.Len() > cache.max {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(cachedOrigin).origin)
+	}
+}
+
+// cacheOrigins returns the AllowOriginFunc of opts memoized by origin. When the cache is full the least recently used
+// origin is evicted.
+func cacheOrigins(opts AccessControlOptions) func(string) string {
+	size := opts.OriginCacheSize
+	if size <= 0 {
+		size = 1000
+	}
+
+	cache := &originCache{max: size, entries: make(map[string]*list.Element, size), order: list.New()}
+
+	return func(origin string) string {
+		now := time.Now()
+
+		if allow, ok := cache.get(origin, now); ok {
+			if opts.OriginCacheStats != nil {
+				opts.OriginCacheStats.Hits.Add(1)
+			}
+			return allow
+		}
+		if opts.OriginCacheStats != nil {
+			opts.OriginCacheStats.Misses.Add(1)
+		}
+
+		allow := opts.AllowOriginFunc(origin)
+		cache.set(cachedOrigin{origin: origin, allow: allow, expires: now.Add(opts.OriginCacheTTL)})
+
+		return allow
+	}
+}
+
+// CORSMaxAge is a registration option overriding the Access-Control-Max-Age of the route's preflight responses set
+// by the CORS middleware, such that browsers cache the preflights of stable routes longer. A negative duration
+// prevents browsers from caching the route's preflights.
+//
+//	mux.Handle("/uploads/:id", uploads, muxter.CORSMaxAge(2*time.Hour))
+func CORSMaxAge(d time.Duration) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.CORSMaxAge = d
+	})
+}
+
+// routeMethods returns the methods accepted by a route that are allowed by the CORS configuration.
+func routeMethods(methods, allowed []string) string {
+	var result []string
+	for _, method := range methods {
+		for _, allow := range allowed {
+			if allow == "*" || strings.EqualFold(method, allow) {
+				result = append(result, method)
+				break
+			}
+		}
+	}
+	return strings.Join(result, ", ")
+}
+
 // DefaultCORS is a non restrictive configuration of the CORS middleware. It defaults to accepting
 // any origin for CORS requests, and accepting any set of preflight request headers. It does not
 // however default to AllowCredentials:true, therefore if making credentialed CORS requests you must
 // configure this via the standard CORS middleware function.
 var DefaultCORS = CORS(AccessControlOptions{})
 
+// gzipReaders is shared by Decompress and the Logger's request body capture.
+var gzipReaders = sync.Pool{
+	New: func() interface{} {
+		return new(gzip.Reader)
+	},
+}
+
 // Decompress modifies the request body who's content-encoding is gzip with a gzip.ReadCloser that reads from the original
 // source body. All readers are closed safely after the main handler returns.
-var Decompress Middleware = func(h Handler) Handler {
-	pool := sync.Pool{
-		New: func() interface{} {
-			return new(gzip.Reader)
-		},
-	}
-
-	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
-		if r.Header.Get("Content-Encoding") != "gzip" {
-			h.ServeHTTPx(w, r, c)
-			return
-		}
+var Decompress Middleware = DecompressWith(DecompressOptions{})
+
+// DecompressOptions configures the DecompressWith middleware.
+type DecompressOptions struct {
+	// Sniff decompresses request bodies without a content-encoding that start with the gzip magic number, for
+	// misbehaving clients that omit the header.
+	Sniff bool
+	// OnSniff is called when a request body without a gzip content-encoding is detected as gzip.
+	OnSniff func(r *http.Request)
+}
 
-		gr := pool.Get().(*gzip.Reader)
-		defer pool.Put(gr)
+// gzipMagic are the first bytes of gzip streams: the gzip magic number followed by the deflate compression method.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
 
-		if err := gr.Reset(r.Body); err != nil {
-			if errors.Is(err, io.EOF) {
+// DecompressWith returns the Decompress middleware configured by opts.
+func DecompressWith(opts DecompressOptions) Middleware {
+	return Identify("muxter.Decompress", func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if r.Header.Get("Content-Encoding") != "gzip" && !(opts.Sniff && sniffGzip(r, opts.OnSniff)) {
 				h.ServeHTTPx(w, r, c)
 				return
 			}
-			http.Error(w, fmt.Sprintf("unexpected error: %v", err), 500)
-			return
-		}
 
-		// Only close gzip reader if gr.Reset is successful otherwise decompressor is not set and close will panic.
-		defer gr.Close()
+			gr := gzipReaders.Get().(*gzip.Reader)
+			defer gzipReaders.Put(gr)
+
+			if err := gr.Reset(r.Body); err != nil {
+				if errors.Is(err, io.EOF) {
+					h.ServeHTTPx(w, r, c)
+					return
+				}
+				http.Error(w, fmt.Sprintf("unexpected error: %v", err), 500)
+				return
+			}
+
+			// Only close gzip reader if gr.Reset is successful otherwise decompressor is not set and close will panic.
+			defer gr.Close()
 
-		originalReqBody := r.Body
-		defer originalReqBody.Close()
+			originalReqBody := r.Body
+			defer originalReqBody.Close()
 
-		r.Body = gr
+			r.Body = gr
 
-		h.ServeHTTPx(w, r, c)
+			h.ServeHTTPx(w, r, c)
+		})
 	})
 }
 
+// sniffGzip reports whether the request body starts with the gzip magic number. The body is replaced by one that
+// reads the sniffed bytes again.
+func sniffGzip(r *http.Request, onSniff func(*http.Request)) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.Header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	br := bufio.NewReaderSize(r.Body, 16)
+	r.Body = readCloser{br, r.Body}
+
+	magic, _ := br.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) {
+		return false
+	}
+	if onSniff != nil {
+		onSniff(r)
+	}
+	return true
+}
+
 func Compress() Middleware {
 	hasGZIP := func(value string) bool {
 		for _, enc := range strings.Split(value, ",") {
@@ -167,7 +586,7 @@ func Compress() Middleware {
 		return false
 	}
 
-	return func(h Handler) Handler {
+	return Identify("muxter.Compress", func(h Handler) Handler {
 		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 			if !hasGZIP(r.Header.Get("Accept-Encoding")) {
 				h.ServeHTTPx(w, r, c)
@@ -187,7 +606,7 @@ func Compress() Middleware {
 				panic(err) // nothing else to do but panic and let users handle this in recovery middleware
 			}
 		})
-	}
+	})
 }
 
 type gzipResponseWriter struct {
@@ -218,11 +637,31 @@ func Skip(middleware Middleware, predicateFunc func(*http.Request) bool) Middlew
 }
 
 type RespOverview struct {
+	// Request is the request as seen by the Logger. When the Logger is used within a mux nested with StripDepth
+	// its path is relative to the mount point; the URL as received by the server is given by Context.OriginalURL.
 	Request     *http.Request
 	Response    http.ResponseWriter
 	Context     Context
 	Code        int
 	TimeElapsed time.Duration
+
+	// RequestBody is the portion of the request body read by the handler when the Logger captures request bodies.
+	// Gzip encoded bodies are decompressed and the redaction function, if any, has been applied.
+	RequestBody []byte
+	// RequestBodyTruncated reports whether RequestBody was cut short by the capture limit.
+	RequestBodyTruncated bool
+
+	// ClientAborted reports whether the client went away before the response was written, in which case Code is
+	// StatusClientClosedRequest rather than a status the client never received. Requests whose handler panics with
+	// a client abort error, as reported by IsClientAbort, are logged too.
+	ClientAborted bool
+
+	// TLS describes the TLS connection of the request, such as its version, cipher suite, SNI server name and client
+	// certificate subject. It is nil for requests not received over TLS.
+	TLS *TLSOverview
+
+	// Fingerprint is the fingerprint of the client, as computed by the function set with the Fingerprints option.
+	Fingerprint string
 }
 
 type responseProxy struct {
@@ -252,21 +691,44 @@ func (r responseProxy) Code() int {
 	return r.code
 }
 
-func Logger(dst io.Writer, fn func(overview RespOverview) string) Middleware {
+func Logger(dst io.Writer, fn func(overview RespOverview) string, opts ...LoggerOption) Middleware {
+	var options loggerOptions
+	for _, apply := range opts {
+		apply(&options)
+	}
+
 	return func(h Handler) Handler {
 		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 			proxy := responseProxy{w, 0}
 			start := time.Now()
 
-			h.ServeHTTPx(&proxy, r, c)
+			var capture *bodyCapture
+			if options.captureLimit > 0 && r.Body != nil && r.Body != http.NoBody {
+				capture = &bodyCapture{ReadCloser: r.Body, limit: options.captureLimit}
+				r.Body = capture
+				defer func() { r.Body = capture.ReadCloser }()
+			}
+
+			serveObserved(h, &proxy, r, c, func(aborted bool) {
+				overview := RespOverview{
+					Request:       r,
+					Response:      w,
+					Context:       c,
+					Code:          proxy.Code(),
+					TimeElapsed:   time.Since(start),
+					ClientAborted: aborted,
+					TLS:           newTLSOverview(r.TLS),
+					Fingerprint:   c.Fingerprint(),
+				}
+				if aborted {
+					overview.Code = StatusClientClosedRequest
+				}
+				if capture != nil {
+					overview.RequestBody, overview.RequestBodyTruncated = capture.body(r, options.redact)
+				}
 
-			fmt.Fprintln(dst, fn(RespOverview{
-				Request:     r,
-				Response:    w,
-				Context:     c,
-				Code:        proxy.Code(),
-				TimeElapsed: time.Since(start),
-			}))
+				fmt.Fprintln(dst, fn(overview))
+			})
 		})
 	}
 }
@@ -6,7 +6,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRecoverMiddleware(t *testing.T) {
@@ -43,6 +46,23 @@ func TestRecoverMiddleware(t *testing.T) {
 	}
 }
 
+func TestRecoverMiddlewareWithoutPanic(t *testing.T) {
+	mux := New()
+
+	var called bool
+	mux.Use(Recover(func(recovered interface{}, w http.ResponseWriter, r *http.Request, c Context) {
+		called = true
+	}))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/anywhere", nil))
+
+	if called {
+		t.Errorf("expected recover handler not to be called when the handler does not panic")
+	}
+}
+
 func TestMethodMiddleware(t *testing.T) {
 	t.Run("GET", func(t *testing.T) {
 		mux := New()
@@ -207,6 +227,81 @@ func TestDecompressNoContent(t *testing.T) {
 	}
 }
 
+func TestDecompressSniff(t *testing.T) {
+	var sniffed int
+
+	mux := New()
+	mux.HandleFunc(
+		"/",
+		func(w http.ResponseWriter, r *http.Request, c Context) {
+			io.Copy(w, r.Body)
+		},
+		DecompressWith(DecompressOptions{Sniff: true, OnSniff: func(r *http.Request) { sniffed++ }}),
+	)
+
+	compressed := new(bytes.Buffer)
+	gw := gzip.NewWriter(compressed)
+	io.WriteString(gw, "hello world!")
+	gw.Close()
+
+	testcases := []struct {
+		Name            string
+		Body            string
+		ContentEncoding string
+		ExpectedBody    string
+		ExpectedSniffed int
+	}{
+		{
+			Name:            "gzip without content-encoding",
+			Body:            compressed.String(),
+			ExpectedBody:    "hello world!",
+			ExpectedSniffed: 1,
+		},
+		{
+			Name:            "gzip with content-encoding",
+			Body:            compressed.String(),
+			ContentEncoding: "gzip",
+			ExpectedBody:    "hello world!",
+		},
+		{
+			Name:            "other content-encoding",
+			Body:            compressed.String(),
+			ContentEncoding: "identity",
+			ExpectedBody:    compressed.String(),
+		},
+		{
+			Name:         "plain body",
+			Body:         "hello world!",
+			ExpectedBody: "hello world!",
+		},
+		{
+			Name:         "short body",
+			Body:         "\x1f",
+			ExpectedBody: "\x1f",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			sniffed = 0
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(tc.Body))
+			if tc.ContentEncoding != "" {
+				r.Header.Set("Content-Encoding", tc.ContentEncoding)
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if actual := w.Body.String(); actual != tc.ExpectedBody {
+				t.Errorf("expected body to be %q but got %q", tc.ExpectedBody, actual)
+			}
+			if sniffed != tc.ExpectedSniffed {
+				t.Errorf("expected %d sniffed requests but got %d", tc.ExpectedSniffed, sniffed)
+			}
+		})
+	}
+}
+
 func TestCompress(t *testing.T) {
 	mux := New()
 
@@ -267,3 +362,408 @@ func TestSkipped(t *testing.T) {
 		}
 	}
 }
+
+func TestCORSPreflightRouteMethods(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux := New()
+	mux.Use(DefaultCORS)
+	mux.Get("/books", noop)
+	mux.Handle("/books/:id", MethodHandler{GET: noop, PUT: noop, DELETE: noop})
+	mux.Handle("/any", noop)
+
+	restricted := New()
+	restricted.Use(CORS(AccessControlOptions{AllowMethods: []string{"GET", "PUT"}}))
+	restricted.Handle("/books/:id", MethodHandler{GET: noop, PUT: noop, DELETE: noop})
+
+	testcases := []struct {
+		Name                 string
+		Mux                  *Mux
+		Path                 string
+		ExpectedAllowMethods string
+	}{
+		{
+			Name:                 "verb registration",
+			Mux:                  mux,
+			Path:                 "/books",
+			ExpectedAllowMethods: "GET, HEAD",
+		},
+		{
+			Name:                 "method handler",
+			Mux:                  mux,
+			Path:                 "/books/1",
+			ExpectedAllowMethods: "GET, PUT, DELETE",
+		},
+		{
+			Name:                 "any method",
+			Mux:                  mux,
+			Path:                 "/any",
+			ExpectedAllowMethods: "GET, POST, HEAD, PUT, PATCH, DELETE",
+		},
+		{
+			Name:                 "limited by configuration",
+			Mux:                  restricted,
+			Path:                 "/books/1",
+			ExpectedAllowMethods: "GET, PUT",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("OPTIONS", tc.Path, nil)
+			r.Header.Set("Origin", "https://example.com")
+			r.Header.Set("Access-Control-Request-Method", "PUT")
+
+			tc.Mux.ServeHTTP(w, r)
+
+			if w.Code != 204 {
+				t.Errorf("expected code 204 but got %d", w.Code)
+			}
+			if actual := w.Header().Get("Access-Control-Allow-Methods"); actual != tc.ExpectedAllowMethods {
+				t.Errorf("expected allowed methods to be %q but got %q", tc.ExpectedAllowMethods, actual)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux := New()
+	mux.Use(CORS(AccessControlOptions{MaxAge: 10 * time.Minute, ExposeHeaders: []string{"X-Total"}}))
+	mux.Handle("/books", noop)
+	mux.Handle("/uploads", noop, CORSMaxAge(2*time.Hour))
+	mux.Handle("/tokens", noop, CORSMaxAge(-1))
+
+	credentialed := New()
+	credentialed.Use(CORS(AccessControlOptions{
+		AllowCredentials: true,
+		AllowHeaders:     []string{"*"},
+		AllowMethods:     []string{"*"},
+		ExposeHeaders:    []string{"*"},
+	}))
+	credentialed.Handle("/books", noop)
+	credentialed.Get("/authors", noop)
+
+	testcases := []struct {
+		Name            string
+		Mux             *Mux
+		Method          string
+		Path            string
+		ExpectedHeaders map[string]string
+	}{
+		{
+			Name:   "max age",
+			Mux:    mux,
+			Method: "OPTIONS",
+			Path:   "/books",
+			ExpectedHeaders: map[string]string{
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+		{
+			Name:            "route max age",
+			Mux:             mux,
+			Method:          "OPTIONS",
+			Path:            "/uploads",
+			ExpectedHeaders: map[string]string{"Access-Control-Max-Age": "7200"},
+		},
+		{
+			Name:            "route without caching",
+			Mux:             mux,
+			Method:          "OPTIONS",
+			Path:            "/tokens",
+			ExpectedHeaders: map[string]string{"Access-Control-Max-Age": "0"},
+		},
+		{
+			Name:   "actual request",
+			Mux:    mux,
+			Method: "GET",
+			Path:   "/books",
+			ExpectedHeaders: map[string]string{
+				"Access-Control-Max-Age":        "",
+				"Access-Control-Expose-Headers": "X-Total",
+			},
+		},
+		{
+			Name:   "credentialed preflight",
+			Mux:    credentialed,
+			Method: "OPTIONS",
+			Path:   "/books",
+			ExpectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Headers":     "Authorization",
+				"Access-Control-Allow-Methods":     "PUT",
+			},
+		},
+		{
+			Name:            "credentialed preflight of route methods",
+			Mux:             credentialed,
+			Method:          "OPTIONS",
+			Path:            "/authors",
+			ExpectedHeaders: map[string]string{"Access-Control-Allow-Methods": "GET, HEAD"},
+		},
+		{
+			Name:            "credentialed request",
+			Mux:             credentialed,
+			Method:          "GET",
+			Path:            "/books",
+			ExpectedHeaders: map[string]string{"Access-Control-Expose-Headers": ""},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil)
+			r.Header.Set("Origin", "https://app.example.com")
+			if tc.Method == "OPTIONS" {
+				r.Header.Set("Access-Control-Request-Method", "PUT")
+				r.Header.Set("Access-Control-Request-Headers", "Authorization")
+			}
+
+			tc.Mux.ServeHTTP(w, r)
+
+			for key, expected := range tc.ExpectedHeaders {
+				if actual := w.Header().Get(key); actual != expected {
+					t.Errorf("expected %s to be %q but got %q", key, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSOriginCache(t *testing.T) {
+	lookups := map[string]int{}
+	var stats OriginCacheStats
+
+	mux := New()
+	mux.Use(CORS(AccessControlOptions{
+		AllowOriginFunc: func(origin string) string {
+			lookups[origin]++
+			if strings.HasSuffix(origin, ".example.com") {
+				return origin
+			}
+			return ""
+		},
+		OriginCacheTTL:   time.Minute,
+		OriginCacheSize:  2,
+		OriginCacheStats: &stats,
+	}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	request := func(origin string) string {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Origin", origin)
+		mux.ServeHTTP(w, r)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	for i := 0; i < 3; i++ {
+		if allow := request("https://a.example.com"); allow != "https://a.example.com" {
+			t.Fatalf("expected origin to be allowed but got %q", allow)
+		}
+		if allow := request("https://evil.com"); allow != "" {
+			t.Fatalf("expected origin to be refused but got %q", allow)
+		}
+	}
+
+	if lookups["https://a.example.com"] != 1 || lookups["https://evil.com"] != 1 {
+		t.Errorf("expected each origin to be looked up once but got %v", lookups)
+	}
+	if hits, misses := stats.Hits.Load(), stats.Misses.Load(); hits != 4 || misses != 2 {
+		t.Errorf("expected 4 hits and 2 misses but got %d and %d", hits, misses)
+	}
+	if rate := stats.HitRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("expected hit rate of 2/3 but got %v", rate)
+	}
+
+	// The cache is full, so caching b evicts the least recently used origin, a.
+	request("https://b.example.com")
+	request("https://evil.com")
+	request("https://a.example.com")
+	if lookups["https://a.example.com"] != 2 || lookups["https://evil.com"] != 1 {
+		t.Errorf("expected a full cache to evict the least recently used origin but got %v", lookups)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	tag := func(name string) Middleware {
+		return func(h Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				io.WriteString(w, name+">")
+				h.ServeHTTPx(w, r, c)
+			})
+		}
+	}
+
+	mux := New()
+	mux.Use(Identify("a", tag("a1")), tag("b"))
+	mux.HandleFunc(
+		"/",
+		func(w http.ResponseWriter, r *http.Request, c Context) { io.WriteString(w, "handler") },
+		Identify("a", tag("a2")),
+		Name("root"),
+		tag("c"),
+	)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if expected := "a1>b>c>handler"; w.Body.String() != expected {
+		t.Errorf("expected body %q but got %q", expected, w.Body.String())
+	}
+	if routes := mux.Routes(); len(routes) != 1 || routes[0].Name != "root" {
+		t.Errorf("expected registration options to be applied once but got %+v", routes)
+	}
+
+	t.Run("duplicates are not constructed", func(t *testing.T) {
+		constructed := map[string]int{}
+		counted := func(name string) Middleware {
+			return func(h Handler) Handler {
+				constructed[name]++
+				return h
+			}
+		}
+
+		mux := New()
+		mux.Use(Identify("a", counted("a1")), counted("b"))
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {}, Identify("a", counted("a2")), counted("c"))
+
+		if expected := map[string]int{"a1": 1, "b": 1, "c": 1}; !reflect.DeepEqual(constructed, expected) {
+			t.Errorf("expected middlewares to be constructed %v but got %v", expected, constructed)
+		}
+	})
+
+	t.Run("route recover under a global recover", func(t *testing.T) {
+		mux := New()
+		mux.Use(Recover(nil))
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+			panic("boom")
+		}, Recover(func(recovered interface{}, w http.ResponseWriter, r *http.Request, c Context) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected the recover handler of the route to be used but got status %d", w.Code)
+		}
+	})
+
+	t.Run("compress applied globally and per route", func(t *testing.T) {
+		mux := New()
+		mux.Use(Compress())
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+			io.WriteString(w, "hello world!")
+		}, Compress())
+
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		mux.ServeHTTP(w, r)
+
+		gr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, err := io.ReadAll(gr)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(body) != "hello world!" {
+			t.Errorf("expected body to be compressed once but got %q", body)
+		}
+	})
+}
+
+func TestStrictMiddlewareOrder(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+	passthrough := func(h Handler) Handler { return h }
+
+	etag := Identify("etag", passthrough)
+	compress := Identify("compress", passthrough, After("etag"))
+	auth := Identify("auth", passthrough, Before("handler-cache"))
+	cache := Identify("handler-cache", passthrough)
+
+	testcases := []struct {
+		Name          string
+		Middlewares   []Middleware
+		ExpectedPanic string
+	}{
+		{
+			Name:        "valid",
+			Middlewares: []Middleware{Name("route"), Recover(nil), etag, compress, auth, cache},
+		},
+		{
+			Name:        "nested recovers",
+			Middlewares: []Middleware{Recover(nil), Recover(func(interface{}, http.ResponseWriter, *http.Request, Context) {}), etag},
+		},
+		{
+			Name:          "recover not outermost",
+			Middlewares:   []Middleware{passthrough, Recover(nil)},
+			ExpectedPanic: "muxter: failed to register route /route - middleware muxter.Recover must be the outermost middleware",
+		},
+		{
+			Name:          "after violated",
+			Middlewares:   []Middleware{compress, etag},
+			ExpectedPanic: "muxter: failed to register route /route - middleware compress must be applied after etag",
+		},
+		{
+			Name:          "before violated",
+			Middlewares:   []Middleware{cache, auth},
+			ExpectedPanic: "muxter: failed to register route /route - middleware auth must be applied before handler-cache",
+		},
+		{
+			Name:        "constraint on absent middleware",
+			Middlewares: []Middleware{compress, auth},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			defer func() {
+				if actual, _ := recover().(string); actual != tc.ExpectedPanic {
+					t.Errorf("expected panic %q but got %q", tc.ExpectedPanic, actual)
+				}
+			}()
+			New(StrictMiddlewareOrder(true)).HandleFunc("/route", noop, tc.Middlewares...)
+		})
+	}
+
+	t.Run("not verified without option", func(t *testing.T) {
+		New().HandleFunc("/route", noop, compress, etag)
+	})
+}
+
+func TestWithMiddlewareDiscardsRegistrationOptions(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(h Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				order = append(order, name)
+				h.ServeHTTPx(w, r, c)
+			})
+		}
+	}
+
+	handler := WithMiddleware(
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) { order = append(order, "handler") }),
+		trace("outer"),
+		Name("discarded"),
+		Tags("discarded"),
+		trace("inner"),
+	)
+
+	if _, ok := handler.(routeOption); ok {
+		t.Fatalf("expected registration options to be stripped from the handler")
+	}
+
+	handler.ServeHTTPx(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), Context{})
+
+	if expected := []string{"outer", "inner", "handler"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected middlewares to run in order %v but got %v", expected, order)
+	}
+}
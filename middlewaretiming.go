@@ -0,0 +1,143 @@
+package muxter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeMiddlewares measures the time every request spends in each middleware layer of the routes of the mux, to find
+// the slow layers of deep middleware chains. The layers are the middlewares with an identity, see Identify, and the
+// handler of the route, named "handler". The time of a layer excludes the time spent in the layers it wraps, and
+// includes the time spent in the middlewares without an identity it wraps. Routes must be registered once the option
+// is set, and nested muxes measure their layers when they have the option themselves.
+//
+// The timings are available to the handlers through Context.MiddlewareTimings, are summed by route by RouteStats,
+// and are sent to clients as a Server-Timing trailer, such that browser developer tools show them:
+//
+//	Server-Timing: auth;dur=0.412, ratelimit;dur=0.051, handler;dur=12.871
+//
+// Layers calling the handler they wrap on another goroutine, once they returned, are not measured reliably.
+func TimeMiddlewares(value bool) MuxOption {
+	return func(m *Mux) {
+		m.timeMiddlewares = value
+	}
+}
+
+// MiddlewareTiming is the time spent in a middleware layer.
+type MiddlewareTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// MiddlewareTimings returns the time spent in each of the middleware layers the request went through and exited, from
+// the outermost to the innermost, when the TimeMiddlewares option is set. Layers the request went through more than
+// once, such as the layers of nested muxes, are listed as many times.
+func (c Context) MiddlewareTimings() []MiddlewareTiming {
+	c.checkOwner()
+	if c.lifecycle == nil {
+		return nil
+	}
+	ext := c.lifecycle.extension.Load()
+	if ext == nil {
+		return nil
+	}
+
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+
+	var timings []MiddlewareTiming
+	for _, layer := range ext.layers {
+		if layer.exited {
+			timings = append(timings, MiddlewareTiming{Name: layer.name, Duration: layer.duration})
+		}
+	}
+	return timings
+}
+
+type layerTiming struct {
+	name     string
+	duration time.Duration
+	exited   bool
+}
+
+type openLayer struct {
+	index int
+	// nested is the time spent in the layers wrapped by the layer.
+	nested time.Duration
+}
+
+// timedLayer measures the time spent in a middleware layer.
+type timedLayer struct {
+	Handler
+	name string
+}
+
+func (h timedLayer) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {
+	if c.lifecycle == nil {
+		h.Handler.ServeHTTPx(w, r, c)
+		return
+	}
+
+	ext := c.lifecycle.extended()
+	ext.enterLayer(h.name)
+	start := time.Now()
+	defer func() { ext.exitLayer(time.Since(start)) }()
+
+	h.Handler.ServeHTTPx(w, r, c)
+}
+
+func (ext *lifecycleExtension) enterLayer(name string) {
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	ext.layers = append(ext.layers, layerTiming{name: name})
+	ext.open = append(ext.open, openLayer{index: len(ext.layers) - 1})
+}
+
+// exitLayer records the time spent in the innermost open layer, without the time spent in the layers it wraps, and
+// counts it as nested time of the layer wrapping it.
+func (ext *lifecycleExtension) exitLayer(elapsed time.Duration) {
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	if len(ext.open) == 0 {
+		return
+	}
+	layer := ext.open[len(ext.open)-1]
+	ext.open = ext.open[:len(ext.open)-1]
+	ext.layers[layer.index].duration += elapsed - layer.nested
+	ext.layers[layer.index].exited = true
+	if len(ext.open) > 0 {
+		ext.open[len(ext.open)-1].nested += elapsed
+	}
+}
+
+// serverTiming formats the timings of the exited layers as the value of a Server-Timing header, in milliseconds.
+func (ext *lifecycleExtension) serverTiming() string {
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+
+	var b strings.Builder
+	for _, layer := range ext.layers {
+		if !layer.exited {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(timingToken(layer.name))
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(float64(layer.duration)/float64(time.Millisecond), 'f', 3, 64))
+	}
+	return b.String()
+}
+
+// timingToken replaces the characters of the name that are not allowed in the metric names of Server-Timing.
+func timingToken(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,69 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimeMiddlewares(t *testing.T) {
+	sleep := func(d time.Duration) Middleware {
+		return func(h Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				time.Sleep(d)
+				h.ServeHTTPx(w, r, c)
+			})
+		}
+	}
+
+	var timings []MiddlewareTiming
+	record := func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			h.ServeHTTPx(w, r, c)
+			timings = c.MiddlewareTimings()
+		})
+	}
+
+	stats := NewRouteStats(RouteStatsOptions{})
+	mux := New(TimeMiddlewares(true))
+	mux.Use(stats.Middleware, record, Identify("auth", sleep(10*time.Millisecond)), sleep(time.Millisecond))
+	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		time.Sleep(40 * time.Millisecond)
+	}, Identify("cache", sleep(0)))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/books", nil))
+
+	if len(timings) != 3 || timings[0].Name != "auth" || timings[1].Name != "cache" || timings[2].Name != "handler" {
+		t.Fatalf("expected timings of auth, cache and handler but got %v", timings)
+	}
+	if auth := timings[0].Duration; auth < 11*time.Millisecond || auth >= 40*time.Millisecond {
+		t.Errorf("expected auth layer to include its unidentified middleware but not the handler but got %v", auth)
+	}
+	if handler := timings[2].Duration; handler < 40*time.Millisecond {
+		t.Errorf("expected handler layer to take at least 40ms but got %v", handler)
+	}
+
+	trailer := w.Result().Trailer.Get("Server-Timing")
+	if !regexp.MustCompile(`^auth;dur=\d+\.\d{3}, cache;dur=\d+\.\d{3}, handler;dur=\d+\.\d{3}$`).MatchString(trailer) {
+		t.Errorf("unexpected Server-Timing trailer %q", trailer)
+	}
+
+	routes := stats.Routes()
+	if len(routes) != 1 || len(routes[0].Middlewares) != 3 || routes[0].Middlewares[0].Name != "auth" {
+		t.Fatalf("expected route stats to sum the layers but got %+v", routes)
+	}
+	if routes[0].Middlewares[0].Duration != timings[0].Duration {
+		t.Errorf("expected route stats to sum the time of the layer %v but got %v", timings[0].Duration, routes[0].Middlewares[0].Duration)
+	}
+
+	untimed := New()
+	untimed.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {}, Identify("cache", sleep(0)))
+	w = httptest.NewRecorder()
+	untimed.ServeHTTP(w, httptest.NewRequest("GET", "/books", nil))
+	if trailer := w.Result().Trailer.Get("Server-Timing"); trailer != "" {
+		t.Errorf("expected no Server-Timing trailer without the option but got %q", trailer)
+	}
+}
@@ -1,37 +1,86 @@
 package muxter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"runtime/pprof"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/davidmdm/muxter/internal/pool"
+	"github.com/davidmdm/muxter/internal"
+	"github.com/davidmdm/muxter/internal/syntax"
 )
 
 var _ http.Handler = &Mux{}
 
 var defaultNotFoundHandler HandlerFunc = func(w http.ResponseWriter, r *http.Request, c Context) {
-	http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	if c.extra().debug != nil && acceptsHTML(r) {
+		writeDebugPage(w, r, c, http.StatusNotFound)
+		return
+	}
+	writeStatus(w, c, http.StatusNotFound)
 }
 
-var defaultRedirectHandler HandlerFunc = func(w http.ResponseWriter, r *http.Request, c Context) {
-	w.Header().Set("Location", c.ogReqPath+"/")
-	w.WriteHeader(http.StatusMovedPermanently)
+var defaultMethodNotAllowedHandler HandlerFunc = func(w http.ResponseWriter, r *http.Request, c Context) {
+	if c.extra().debug != nil && acceptsHTML(r) {
+		writeDebugPage(w, r, c, http.StatusMethodNotAllowed)
+		return
+	}
+	writeStatus(w, c, http.StatusMethodNotAllowed)
 }
 
-var defaultMethodNotAllowedHandler HandlerFunc = func(w http.ResponseWriter, r *http.Request, c Context) {
-	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+var defaultBadRequestHandler HandlerFunc = func(w http.ResponseWriter, r *http.Request, c Context) {
+	writeStatus(w, c, http.StatusBadRequest)
+}
+
+var defaultUnavailableHandler HandlerFunc = func(w http.ResponseWriter, r *http.Request, c Context) {
+	writeStatus(w, c, http.StatusServiceUnavailable)
 }
 
 // Mux is a request multiplexer with the same routing behaviour as the standard libraries net/http ServeMux
 type Mux struct {
 	notFoundHandler         Handler
 	methodNotAllowedHandler Handler
+	methodPolicy            MethodPolicy
+	fallback                Handler
+	redirectHandler         Handler
 	root                    *node
 	matchTrailingSlash      *bool
+	stripTrailingSlash      *bool
 	middlewares             []Middleware
+	middlewareScopes        []string
 	globalwares             []Middleware
+	names                   map[string]*RouteInfo
+	baseURL                 *url.URL
+	normalize               func(*http.Request)
+	recordCallSites         bool
+	debug                   bool
+	catalog                 Catalog
+	jsonErrors              *bool
+	strictOrder             bool
+	tolerateDuplicates      bool
+	timeMiddlewares         bool
+	rewriteHeaders          []func(http.Header)
+	webhooks                *WebhookDispatcher
+	deferTimeout            time.Duration
+	onDeferPanic            func(*http.Request, interface{})
+	aborted                 *atomic.Uint64
+	hostRoutes              bool
+	formats                 []formatExtensions
+	paramLimits             *ParamLimits
+	pathLimits              *PathLimits
+	expressionLimits        *ExpressionLimits
+	profileLabels           bool
+	uncheckedContexts       bool
+	events                  *EventBus
+	fingerprint             FingerprintFunc
+	maintenance             *maintenance
 }
 
 type MuxOption func(*Mux)
@@ -44,14 +93,93 @@ func MatchTrailingSlash(value bool) MuxOption {
 	}
 }
 
+// StripTrailingSlash makes the mux route a path with a trailing slash exactly as the path without it, by stripping
+// the slash before looking up the route rather than redirecting. Unlike MatchTrailingSlash it applies whether or not
+// a rooted subtree is registered at the path: /path/ is served by the route of /path, and both /path and /path/ are
+// served by the rooted subtree /path/ when no /path route is registered, without the redirect of the subtree. It is
+// meant for APIs whose clients do not follow redirects, or lose the body of the request when they do. The request
+// path handed to the handler is not rewritten.
+func StripTrailingSlash(value bool) MuxOption {
+	return func(m *Mux) {
+		m.stripTrailingSlash = &value
+	}
+}
+
+// BaseURL sets the externally visible origin of the mux, such as "https://api.example.com". When set it is used
+// to build absolute URLs for redirects and named route links, instead of relying on the request's host and
+// forwarding headers. A path in the base URL is used as a prefix. BaseURL panics if the URL is not absolute.
+func BaseURL(rawURL string) MuxOption {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic(fmt.Sprintf("muxter: base url must be an absolute url but got: %s", rawURL))
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return func(m *Mux) {
+		m.baseURL = u
+	}
+}
+
+// RecordCallSites records the file and line of the code registering each route. Call sites are included in
+// registration panics, such as conflicting registrations of the same pattern, and in RouteInfo.
+// It is opt-in as capturing the call stack adds to the cost of registration.
+func RecordCallSites(value bool) MuxOption {
+	return func(m *Mux) {
+		m.recordCallSites = value
+	}
+}
+
+// JSONErrors makes the built-in not found, method not allowed and Recover responses JSON bodies of the form
+// {"error":"not found","status":404} instead of plain text. Nested muxes use the setting of their parent unless
+// they set their own.
+func JSONErrors(value bool) MuxOption {
+	return func(m *Mux) {
+		m.jsonErrors = &value
+	}
+}
+
+// StrictMiddlewareOrder verifies the order constraints of identified middlewares when routes are registered,
+// panicking if a route's middlewares violate them. See Identify.
+func StrictMiddlewareOrder(value bool) MuxOption {
+	return func(m *Mux) {
+		m.strictOrder = value
+	}
+}
+
+// TolerateDuplicates makes registering a pattern a second time with the same handler, or with the same route name, a
+// no-op instead of a panic. It is meant for route tables assembled from generated modules that may register the same
+// routes. The first registration is kept, with its middlewares. Handlers are the same when they are comparable and
+// equal, such as the same mux; handler funcs and adapted handlers are never the same, as functions cannot be
+// compared, and are only tolerated with the same route name. Registering the pattern with another handler still
+// panics.
+func TolerateDuplicates(value bool) MuxOption {
+	return func(m *Mux) {
+		m.tolerateDuplicates = value
+	}
+}
+
+// ProfileLabels runs handlers within pprof.Do with the labels "pattern" and "method" of their route, such that CPU
+// profiles can be sliced by route with `go tool pprof -tagfocus pattern=/books/:id`. Handlers start goroutines with
+// the labels of their request's context by calling pprof.SetGoroutineLabels with it. Nested muxes setting the option
+// label their routes with the full pattern, such as "/api/users/:id".
+func ProfileLabels(value bool) MuxOption {
+	return func(m *Mux) {
+		m.profileLabels = value
+	}
+}
+
 // New returns a pointer to a new muxter.Mux
 func New(options ...MuxOption) *Mux {
 	m := &Mux{
 		root:               &node{},
 		middlewares:        []Middleware{},
 		globalwares:        []Middleware{},
+		names:              map[string]*RouteInfo{},
+		aborted:            new(atomic.Uint64),
+		maintenance:        new(maintenance),
 		notFoundHandler:    nil,
 		matchTrailingSlash: nil,
+		debug:              debugBuild,
 	}
 	for _, apply := range options {
 		apply(m)
@@ -61,21 +189,138 @@ func New(options ...MuxOption) *Mux {
 
 // ServeHTTP implements the net/http Handler interface.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.normalize != nil {
+		m.normalize(r)
+	}
+	lc := acquireLifecycle()
+	lc.r, lc.body = r, r.Body
+	lc.writer.ResponseWriter = w
+	w = &lc.writer
+
 	c := Context{
-		ogReqPath: r.URL.Path,
-		params:    pool.Params.Get(),
+		ogReqPath:  r.URL.Path,
+		ogRawQuery: r.URL.RawQuery,
+		params:     &lc.params.List,
+		lifecycle:  lc,
+	}
+	if !m.uncheckedContexts {
+		c.epoch = lc.epoch.Load()
+	}
+	if m.formats != nil && m.stripFormat(r, c.params) {
+		c.effectivePath = r.URL.Path
+	}
+
+	completed := false
+	defer lc.finish(m, r, &completed)
+
+	if m.rewriteHeaders != nil {
+		hw := &headerWriter{ResponseWriter: w, rewrite: m.rewriteResponseHeader}
+		m.ServeHTTPx(hw, r, c)
+		hw.rewriteHeader()
+	} else {
+		m.ServeHTTPx(w, r, c)
+	}
+	if ext := lc.extension.Load(); ext != nil {
+		if timing := ext.serverTiming(); timing != "" {
+			w.Header().Set(http.TrailerPrefix+"Server-Timing", timing)
+		}
+	}
+	completed = true
+}
+
+// setExtras sets the extras of the Context the mux configures.
+func (m *Mux) setExtras(r *http.Request, c *Context) {
+	extras := c.editExtras()
+	if m.catalog != nil {
+		extras.catalog = m.catalog
+	}
+	if m.webhooks != nil {
+		extras.webhooks = m.webhooks
+	}
+	if m.events != nil {
+		extras.events = m.events
+	}
+	if m.methodPolicy != 0 {
+		extras.methodMux = m
+	}
+	if m.debug {
+		extras.debug = m
+	}
+	if m.fingerprint != nil && extras.fingerprint == "" {
+		extras.fingerprint = m.fingerprint(r)
+	}
+	if m.baseURL != nil {
+		extras.baseURL = m.baseURL
 	}
-	m.ServeHTTPx(w, r, c)
-	pool.Params.Put(c.params)
 }
 
 func (m *Mux) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {
-	value := m.root.Lookup(r.URL.Path, c.params, m.matchTrailingSlash != nil && *m.matchTrailingSlash)
+	if m.jsonErrors != nil {
+		c.jsonErrors = *m.jsonErrors
+	}
+	if m.fallback != nil {
+		c.fallback = m.fallback
+	}
+	if m.catalog != nil || m.webhooks != nil || m.events != nil || m.methodPolicy != 0 || m.debug ||
+		m.fingerprint != nil && c.extra().fingerprint == "" || m.baseURL != nil && c.extra().baseURL != m.baseURL {
+		m.setExtras(r, &c)
+	}
+
+	if m.pathLimits != nil {
+		if reason := m.pathLimits.checkPath(r.URL.Path); reason != "" {
+			if m.pathLimits.OnReject != nil {
+				m.pathLimits.OnReject(r, reason)
+			}
+			writeStatus(w, c, http.StatusRequestURITooLong)
+			return
+		}
+	}
+
+	var budget *expressionBudget
+	if m.expressionLimits != nil {
+		budget = m.expressionLimits.budget(r)
+	}
+
+	n := len(*c.params)
+	value := m.lookup(r, c.params, budget)
+
+	var rejected int
+	if reason := budget.reason(); reason != "" {
+		m.expressionLimits.reject(r, reason)
+		value, rejected = nil, m.expressionLimits.Status
+		*c.params = (*c.params)[:n]
+	}
+	if value != nil && m.paramLimits != nil {
+		if reason := m.paramLimits.checkParams((*c.params)[n:]); reason != "" {
+			if m.paramLimits.OnReject != nil {
+				m.paramLimits.OnReject(r, reason)
+			}
+			value, rejected = nil, m.paramLimits.Status
+			*c.params = (*c.params)[:n]
+		}
+	}
+
+	if value != nil {
+		value = value.candidate(r)
+	}
+
+	var disabled int32
+	if value != nil {
+		if disabled = value.disabled.Load(); disabled != 0 {
+			value = nil
+		}
+	}
 
 	var handler Handler
 	if value != nil {
 		if value.isRedirect {
-			handler = WithMiddleware(defaultRedirectHandler, m.globalwares...)
+			handler = m.redirectHandler
+			if handler == nil {
+				handler = SubtreeRedirect{BaseURL: m.baseURL}
+			}
+			handler = WithMiddleware(handler, m.globalwares...)
+		} else if m.maintenance.applies(value.route) {
+			handler = WithMiddleware(m.maintenance.handler(), m.globalwares...)
 		} else {
 			handler = value.handler
 		}
@@ -84,18 +329,85 @@ func (m *Mux) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {
 		} else {
 			c.pattern = value.pattern
 		}
+		c.route = value.route
 	} else {
-		if m.notFoundHandler != nil {
+		if rejected == http.StatusBadRequest {
+			handler = defaultBadRequestHandler
+		} else if disabled == http.StatusServiceUnavailable {
+			handler = defaultUnavailableHandler
+		} else if c.fallback != nil {
+			handler = c.fallback
+		} else if m.notFoundHandler != nil {
 			handler = m.notFoundHandler
 		} else {
 			handler = defaultNotFoundHandler
 		}
+		if rejected == 0 && disabled == 0 {
+			*c.params = (*c.params)[:n]
+			if partial := m.root.PartialMatch(r.URL.Path, c.params, budget); partial != "" {
+				if c.pattern != "" {
+					partial = c.pattern + partial[1:]
+				}
+				c.partialPattern = partial
+			}
+		}
+		if rejected != http.StatusBadRequest && disabled != http.StatusServiceUnavailable && c.fallback == nil {
+			c.extra().events.publish(eventNotFound, NotFoundEvent{Request: r, Path: c.requestURL(r).Path})
+		}
 		handler = WithMiddleware(handler, m.globalwares...)
 	}
 
+	if m.profileLabels && value != nil {
+		pprof.Do(r.Context(), pprof.Labels("pattern", c.pattern, "method", r.Method), func(ctx context.Context) {
+			handler.ServeHTTPx(w, r.WithContext(ctx), c)
+		})
+		return
+	}
+
 	handler.ServeHTTPx(w, r, c)
 }
 
+// lookup returns the value of the route matching the request. Host routes take precedence over path routes.
+func (m *Mux) lookup(r *http.Request, params *[]internal.Param, budget *expressionBudget) *value {
+	if m.stripTrailingSlash == nil || !*m.stripTrailingSlash {
+		return m.lookupPath(r, r.URL.Path, params, budget)
+	}
+
+	path := r.URL.Path
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	}
+	n := len(*params)
+	value := m.lookupPath(r, path, params, budget)
+	if value != nil && value.isRedirect {
+		// The path is the root of a rooted subtree, which is served rather than redirected to.
+		*params = (*params)[:n]
+		value = m.lookupPath(r, path+"/", params, budget)
+	}
+	return value
+}
+
+func (m *Mux) lookupPath(r *http.Request, path string, params *[]internal.Param, budget *expressionBudget) *value {
+	matchTrailingSlash := m.matchTrailingSlash != nil && *m.matchTrailingSlash
+
+	if !m.hostRoutes {
+		return m.root.Lookup(path, params, matchTrailingSlash, budget)
+	}
+	if strings.HasPrefix(path, hostSegment) {
+		return nil
+	}
+
+	if key, ok := hostKey(r.Host, path); ok {
+		n := len(*params)
+		if value := m.root.Lookup(key, params, matchTrailingSlash, budget); value != nil && isHostPattern(value.pattern) {
+			return value
+		}
+		*params = (*params)[:n]
+	}
+
+	return m.root.Lookup(path, params, matchTrailingSlash, budget)
+}
+
 func (m *Mux) SetNotFoundHandler(handler Handler) {
 	m.notFoundHandler = handler
 }
@@ -128,6 +440,45 @@ func (m *Mux) UseGlobal(middlewares ...Middleware) {
 	m.globalwares = append(m.globalwares, middlewares...)
 }
 
+// UseOn registers middlewares for the routes within the subtree rooted at prefix, such as "/app/", or for the route
+// registered with the pattern if it is not a subtree. Like Use only routes registered after the call are affected,
+// and middlewares registered with Use and UseOn run in the order of the calls. Routes are matched by their pattern,
+// so a nested mux registered outside of the subtree, such as at "/", is not affected.
+func (m *Mux) UseOn(prefix string, middlewares ...Middleware) {
+	if prefix == "" || prefix[0] != '/' {
+		panic("muxter: middleware prefix must begin with a forward-slash: '/' but got: " + prefix)
+	}
+	for len(m.middlewareScopes) < len(m.middlewares) {
+		m.middlewareScopes = append(m.middlewareScopes, "")
+	}
+	for range middlewares {
+		m.middlewareScopes = append(m.middlewareScopes, prefix)
+	}
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
+// routeMiddlewares returns the middlewares registered with Use and UseOn that apply to the pattern.
+func (m *Mux) routeMiddlewares(pattern string) []Middleware {
+	if m.middlewareScopes == nil {
+		return m.middlewares
+	}
+	middlewares := make([]Middleware, 0, len(m.middlewares))
+	for i, mw := range m.middlewares {
+		if i < len(m.middlewareScopes) && m.middlewareScopes[i] != "" && !withinSubtree(pattern, m.middlewareScopes[i]) {
+			continue
+		}
+		middlewares = append(middlewares, mw)
+	}
+	return middlewares
+}
+
+func withinSubtree(pattern, prefix string) bool {
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(pattern, prefix)
+	}
+	return pattern == prefix
+}
+
 // HandleFunc registers a net/http HandlerFunc for a given string pattern. Middlewares are applied
 // such that the first middleware will be called before passing control to the next middleware.
 // ie mux.HandleFunc(pattern, handler, m1, m2, m3) => request flow will pass through m1 then m2 then m3.
@@ -138,6 +489,10 @@ func (m *Mux) HandleFunc(pattern string, handler HandlerFunc, middlewares ...Mid
 // Handle registers a net/http HandlerFunc for a given string pattern. Middlewares are applied
 // such that the first middleware will be called before passing control to the next middleware.
 // ie mux.HandleFunc(pattern, handler, m1, m2, m3) => request flow will pass through m1 then m2 then m3.
+//
+// Patterns beginning with a double slash also match the request host, whose labels may be wildcards such as
+// "//:tenant.example.com/api/:version/*rest". Hosts are matched case insensitively and without port, and routes
+// with a host pattern take precedence over routes with a path pattern.
 func (m *Mux) Handle(pattern string, handler Handler, middlewares ...Middleware) {
 	if pattern == "" {
 		panic("muxter: cannot register empty route pattern")
@@ -145,9 +500,18 @@ func (m *Mux) Handle(pattern string, handler Handler, middlewares ...Middleware)
 	if pattern[0] != '/' {
 		panic("muxter: route pattern must begin with a forward-slash: '/' but got: " + pattern)
 	}
+	if host, _, ok := syntax.SplitHost(pattern); ok && host == "" {
+		panic("muxter: host pattern must have a host but got: " + pattern)
+	}
+	normalized, err := normalizePattern(pattern, false)
+	if err != nil {
+		panic(fmt.Sprintf("muxter: failed to register route %s - %v", pattern, err))
+	}
+	pattern = normalized
 	if handler == nil {
 		panic("muxter: handler cannot be nil")
 	}
+	registered := handler
 
 	if mh, ok := handler.(*Mux); ok {
 		cpy := *mh
@@ -157,39 +521,135 @@ func (m *Mux) Handle(pattern string, handler Handler, middlewares ...Middleware)
 		if cpy.matchTrailingSlash == nil {
 			cpy.matchTrailingSlash = m.matchTrailingSlash
 		}
+		if cpy.stripTrailingSlash == nil {
+			cpy.stripTrailingSlash = m.stripTrailingSlash
+		}
 		if cpy.methodNotAllowedHandler == nil {
 			cpy.methodNotAllowedHandler = m.methodNotAllowedHandler
 		}
+		if cpy.redirectHandler == nil {
+			cpy.redirectHandler = m.redirectHandler
+		}
+		if cpy.baseURL == nil {
+			cpy.baseURL = m.baseURL
+		}
 		cpy.globalwares = append(append([]Middleware{}, m.globalwares...), cpy.globalwares...)
 		handler = &cpy
 	}
 
-	handler = WithMiddleware(handler, append(m.middlewares, middlewares...)...)
-	if err := m.root.Insert(pattern, &value{handler: handler, pattern: pattern}); err != nil {
-		panic(fmt.Sprintf("muxter: failed to register route %s - %v", pattern, err))
+	route := &RouteInfo{Pattern: pattern}
+	if m.recordCallSites || m.debug {
+		route.CallSite = callSite()
+	}
+	switch mh := handler.(type) {
+	case MethodHandler:
+		route.Methods = mh.methods()
+	case *MethodHandler:
+		route.Methods = mh.methods()
+	}
+
+	if m.timeMiddlewares {
+		handler = timedLayer{Handler: handler, name: "handler"}
+	}
+	handler, idents := withMiddleware(handler, append(m.routeMiddlewares(pattern), middlewares...), route, m.timeMiddlewares)
+	if m.strictOrder {
+		if err := checkOrder(idents); err != nil {
+			panic(fmt.Sprintf("muxter: failed to register route %s%s - %v", pattern, at(route.CallSite), err))
+		}
+	}
+
+	if m.tolerateDuplicates && m.isDuplicate(pattern, registered, route) {
+		return
+	}
+
+	if route.Name != "" {
+		if existing, ok := m.names[route.Name]; ok {
+			panic(fmt.Sprintf("muxter: route name %q is already registered for %s%s", route.Name, existing.Pattern, at(existing.CallSite)))
+		}
+	}
+
+	v := &value{handler: handler, registered: registered, pattern: pattern, route: route}
+
+	err = m.root.Insert(routeKey(pattern), v)
+
+	var conflict registrationConflict
+	if errors.As(err, &conflict) && (v.conditional() || conflict.existing.conditional() || conflict.existing.alternatives != nil) {
+		err = conflict.existing.addAlternative(v)
 	}
+	if err != nil {
+		if errors.As(err, &conflict) && conflict.existing.route != nil {
+			if existing := conflict.existing.route; existing.CallSite != "" || existing.Description != "" {
+				err = fmt.Errorf("%w (previously registered%s%s)", err, describedAs(existing), at(existing.CallSite))
+			}
+		}
+		m.root.rebuild()
+		panic(fmt.Sprintf("muxter: failed to register route %s%s%s - %v", pattern, describedAs(route), at(route.CallSite), err))
+	}
+
+	if route.Name != "" {
+		m.names[route.Name] = route
+	}
+	if isHostPattern(pattern) {
+		m.hostRoutes = true
+	}
+
+	m.events.publish(eventRouteRegistered, RouteRegisteredEvent{Route: *route})
+}
+
+// isDuplicate reports whether the pattern is already registered with the handler or the name of the route.
+func (m *Mux) isDuplicate(pattern string, handler Handler, route *RouteInfo) bool {
+	existing := m.lookupPattern(pattern)
+	if existing == nil {
+		return false
+	}
+	for _, v := range append([]*value{existing}, existing.alternatives...) {
+		if sameHandler(v.registered, handler) || (route.Name != "" && v.route != nil && v.route.Name == route.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHandler reports whether the handlers are comparable and equal. Handler funcs are never the same: functions
+// cannot be compared, and closures built by the same function, such as the handlers of Adaptor, share their code
+// pointer.
+func sameHandler(a, b Handler) (same bool) {
+	if a == nil || b == nil {
+		return false
+	}
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || t.Kind() == reflect.Func || !t.Comparable() {
+		return false
+	}
+	// Comparable types may hold values that are not, such as handler funcs in interface fields.
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
 }
 
 func (m *Mux) StandardHandle(pattern string, handler http.Handler, middlewares ...Middleware) {
-	m.Handle(pattern, Adaptor(handler))
+	m.Handle(pattern, Adaptor(handler), middlewares...)
 }
 
 func (m *Mux) Method(method string) Middleware {
-	methodNotAllowed := m.methodNotAllowedHandler
-	if methodNotAllowed == nil {
-		methodNotAllowed = defaultMethodNotAllowedHandler
+	notAllowed := m.methodNotAllowedHandler
+	if notAllowed == nil {
+		notAllowed = defaultMethodNotAllowedHandler
 	}
 
 	method = strings.ToUpper(method)
 
 	return func(h Handler) Handler {
-		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		return allowMethods(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 			if strings.ToUpper(r.Method) != method {
-				methodNotAllowed.ServeHTTPx(w, r, c)
+				methodNotAllowed(w, r, c, notAllowed, []string{method})
 				return
 			}
 			h.ServeHTTPx(w, r, c)
-		})
+		}), method)
 	}
 }
 
@@ -245,20 +705,20 @@ func (m *Mux) get() Middleware {
 	return func(h Handler) Handler {
 		getGuard := m.Method("GET")(h)
 		headGuard := m.head()(h)
-		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		return allowMethods(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 			if strings.ToUpper(r.Method) == "HEAD" {
 				headGuard.ServeHTTPx(w, r, c)
 				return
 			}
 			getGuard.ServeHTTPx(w, r, c)
-		})
+		}), "GET", "HEAD")
 	}
 }
 
 func (m *Mux) head() Middleware {
 	return func(h Handler) Handler {
 		guard := m.Method("HEAD")(h)
-		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		return allowMethods(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 			if strings.ToUpper(r.Method) != "HEAD" {
 				guard.ServeHTTPx(w, r, c)
 				return
@@ -269,7 +729,19 @@ func (m *Mux) head() Middleware {
 			if w.Header().Get("Content-Length") == "" {
 				w.Header().Set("Content-Length", strconv.Itoa(hrw.contentLength))
 			}
-		})
+		}), "HEAD")
+	}
+}
+
+// verb returns the guard used by the method registration helpers for the given method.
+func (m *Mux) verb(method string) Middleware {
+	switch method {
+	case "GET":
+		return m.get()
+	case "HEAD":
+		return m.head()
+	default:
+		return m.Method(method)
 	}
 }
 
@@ -1,7 +1,9 @@
 package muxter
 
 import (
+	"fmt"
 	"net/http"
+	"runtime"
 	"testing"
 )
 
@@ -35,6 +37,10 @@ func TestRegistration(t *testing.T) {
 			Name:   "no errors",
 			Routes: []string{"/api", "/api/", "/api/:id", "/api/:id/other"},
 		},
+		{
+			Name:   "param names are not validated",
+			Routes: []string{"/files/:name.json", "/a/:user.id", "/x/*", "/y/:"},
+		},
 		{
 			Name:          "empty pattern",
 			Routes:        []string{""},
@@ -74,6 +80,50 @@ func TestRegistration(t *testing.T) {
 		})
 	}
 
+	t.Run("call sites are reported for conflicting registrations", func(t *testing.T) {
+		mux := New(RecordCallSites(true))
+		handler := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+		_, file, line, _ := runtime.Caller(0)
+		mux.HandleFunc("/api", handler)
+
+		defer func() {
+			expected := fmt.Sprintf(
+				"muxter: failed to register route /api at %s:%d - multiple registrations (previously registered at %s:%d)",
+				file, line+12, file, line+1,
+			)
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected error %q but got %q", expected, actual)
+			}
+		}()
+		mux.HandleFunc("/api", handler)
+	})
+
+	t.Run("descriptions are reported for conflicting registrations", func(t *testing.T) {
+		mux := New()
+		mux.HandleFunc("/api/:id", func(w http.ResponseWriter, r *http.Request, c Context) {}, Describe("Returns an api"))
+
+		defer func() {
+			expected := `muxter: failed to register route /api/:id as "Deletes an api" - multiple registrations (previously registered as "Returns an api")`
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected error %q but got %q", expected, actual)
+			}
+		}()
+		mux.HandleFunc("/api/:id", func(w http.ResponseWriter, r *http.Request, c Context) {}, Describe("Deletes an api"))
+	})
+
+	t.Run("call sites are included in route info", func(t *testing.T) {
+		mux := New(RecordCallSites(true))
+
+		_, file, line, _ := runtime.Caller(0)
+		mux.GetFunc("/api", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+		expected := fmt.Sprintf("%s:%d", file, line+1)
+		if actual := mux.Routes()[0].CallSite; actual != expected {
+			t.Errorf("expected call site %q but got %q", expected, actual)
+		}
+	})
+
 	t.Run("cannot register a nil handler", func(t *testing.T) {
 		defer func() {
 			actual, _ := recover().(string)
@@ -178,6 +178,13 @@ func TestRoutingx(t *testing.T) {
 									}
 								}
 
+								if c.pattern != "" && (ctx.route == nil || ctx.route.Pattern != c.pattern) {
+									t.Fatalf("expected ctx route to have pattern %q but got %+v", c.pattern, ctx.route)
+								}
+								ctx.route = nil
+								ctx.lifecycle = nil
+								ctx.epoch = 0
+
 								if !reflect.DeepEqual(c, ctx) {
 									t.Errorf("expected context to be equal to %v but got %v", c, ctx)
 								}
@@ -346,6 +353,46 @@ func TestSubdirRedirect(t *testing.T) {
 	}
 }
 
+func TestSubdirRedirectPreservesQuery(t *testing.T) {
+	child := New()
+	child.HandleFunc("/dir/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	parent := New()
+	parent.Handle("/nested/", StripDepth(1, child))
+
+	w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/nested/dir?page=2", nil)
+
+	parent.ServeHTTP(w, r)
+
+	if w.Code != 301 {
+		t.Errorf("expected status code to be 301 but got %d", w.Code)
+	}
+
+	if location := w.Header().Get("Location"); location != "/nested/dir/?page=2" {
+		t.Errorf("expected location to be %q but got %q", "/nested/dir/?page=2", location)
+	}
+}
+
+func TestSubdirRedirectWithBaseURL(t *testing.T) {
+	child := New()
+	child.HandleFunc("/dir/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	parent := New(BaseURL("https://example.com"))
+	parent.Handle("/", child)
+
+	w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/dir", nil)
+
+	parent.ServeHTTP(w, r)
+
+	if w.Code != 301 {
+		t.Errorf("expected status code to be 301 but got %d", w.Code)
+	}
+
+	if location := w.Header().Get("Location"); location != "https://example.com/dir/" {
+		t.Errorf("expected location to be %q but got %q", "https://example.com/dir/", location)
+	}
+}
+
 func TestMatchTrailingSlash(t *testing.T) {
 	t.Run("no params", func(t *testing.T) {
 		regular := New()
@@ -394,6 +441,50 @@ func TestMatchTrailingSlash(t *testing.T) {
 	})
 }
 
+func TestStripTrailingSlash(t *testing.T) {
+	pattern := func(w http.ResponseWriter, r *http.Request, c Context) {
+		fmt.Fprintf(w, "%s %v", c.Pattern(), c.Params())
+	}
+
+	mux := New(StripTrailingSlash(true))
+	mux.HandleFunc("/books", pattern)
+	mux.HandleFunc("/books/:id", pattern)
+	mux.HandleFunc("/docs/", pattern)
+
+	child := New()
+	child.HandleFunc("/api/users", pattern)
+	mux.Handle("/api/", child)
+
+	cases := []struct {
+		Path         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Path: "/books", ExpectedCode: 200, ExpectedBody: "/books map[]"},
+		{Path: "/books/", ExpectedCode: 200, ExpectedBody: "/books map[]"},
+		{Path: "/books/1/", ExpectedCode: 200, ExpectedBody: "/books/:id map[id:1]"},
+		{Path: "/books//", ExpectedCode: 404},
+		{Path: "/docs", ExpectedCode: 200, ExpectedBody: "/docs/ map[]"},
+		{Path: "/docs/", ExpectedCode: 200, ExpectedBody: "/docs/ map[]"},
+		{Path: "/docs/intro/", ExpectedCode: 200, ExpectedBody: "/docs/ map[]"},
+		{Path: "/api/users/", ExpectedCode: 200},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestMiddlewareCompisition(t *testing.T) {
 	var (
 		m1 Middleware = func(h Handler) Handler {
@@ -474,6 +565,53 @@ func TestUseMiddleware(t *testing.T) {
 	}
 }
 
+func TestUseOnMiddleware(t *testing.T) {
+	trace := func(name string) Middleware {
+		return func(h Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Header().Add("x-trace", name)
+				h.ServeHTTPx(w, r, c)
+			})
+		}
+	}
+
+	mux := New()
+	mux.HandleFunc("/app/before", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux.Use(trace("global"))
+	mux.UseOn("/app/", trace("app"))
+	mux.UseOn("/login", trace("login"))
+	mux.Use(trace("last"))
+
+	for _, pattern := range []string{"/app/", "/app/settings", "/application", "/login", "/login/"} {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request, c Context) {})
+	}
+
+	testcases := []struct {
+		Path          string
+		ExpectedTrace []string
+	}{
+		{Path: "/app/before", ExpectedTrace: nil},
+		{Path: "/app/", ExpectedTrace: []string{"global", "app", "last"}},
+		{Path: "/app/settings", ExpectedTrace: []string{"global", "app", "last"}},
+		{Path: "/application", ExpectedTrace: []string{"global", "last"}},
+		{Path: "/login", ExpectedTrace: []string{"global", "login", "last"}},
+		{Path: "/login/", ExpectedTrace: []string{"global", "last"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", tc.Path, nil)
+
+			mux.ServeHTTP(w, r)
+
+			if actual := w.Header().Values("x-trace"); !reflect.DeepEqual(actual, tc.ExpectedTrace) {
+				t.Errorf("expected middlewares %q but got %q", tc.ExpectedTrace, actual)
+			}
+		})
+	}
+}
+
 func TestCustomNotFoundHandler(t *testing.T) {
 	mux := New()
 
@@ -511,6 +649,50 @@ func TestCustomNotFoundHandler(t *testing.T) {
 	}
 }
 
+func TestNotFoundPartialMatch(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New()
+	mux.HandleFunc("/api/:version/users", noop)
+	mux.HandleFunc("/api/:version/books/:id", noop)
+	mux.HandleFunc(`/api/:version/#shelf:\d+/about`, noop)
+	mux.HandleFunc("/files/*path", noop)
+	notFound := func(w http.ResponseWriter, r *http.Request, c Context) {
+		fmt.Fprintf(w, "%s %v", c.PartialPattern(), c.Params())
+	}
+	mux.SetNotFoundHandlerFunc(notFound)
+
+	api := New()
+	api.HandleFunc("/:version/users", noop)
+	api.SetNotFoundHandlerFunc(notFound)
+	mux.Handle("/nested/", StripDepth(1, api))
+
+	cases := []struct {
+		Path     string
+		Expected string
+	}{
+		{Path: "/api/v2/authors", Expected: "/api/:version/ map[version:v2]"},
+		{Path: "/api/v2/us", Expected: "/api/:version/ map[version:v2]"},
+		{Path: "/api/v2/books/1/reviews", Expected: "/api/:version/books/ map[version:v2]"},
+		{Path: "/api/v2/books/", Expected: "/api/:version/books/ map[version:v2]"},
+		{Path: "/api/v2/42/contact", Expected: `/api/:version/#shelf:\d+/ map[shelf:42 version:v2]`},
+		{Path: "/api/v2", Expected: "/api/ map[]"},
+		{Path: "/authors", Expected: " map[]"},
+		{Path: "/nested/v1/books", Expected: "/nested/:version/ map[version:v1]"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+
+			if body := w.Body.String(); body != tc.Expected {
+				t.Errorf("expected %q but got %q", tc.Expected, body)
+			}
+		})
+	}
+}
+
 func TestMethodHandler(t *testing.T) {
 	t.Run("happy", func(t *testing.T) {
 		mux := New()
@@ -686,23 +868,122 @@ func TestNestedMuxes(t *testing.T) {
 	})
 }
 
+func TestTolerateDuplicates(t *testing.T) {
+	list := func(w http.ResponseWriter, r *http.Request, c Context) { io.WriteString(w, "list") }
+	show := func(w http.ResponseWriter, r *http.Request, c Context) { io.WriteString(w, "show") }
+	api := New()
+
+	cases := []struct {
+		Name     string
+		Register func(mux *Mux)
+		Panic    bool
+	}{
+		{
+			Name: "handler funcs are distinct",
+			Register: func(mux *Mux) {
+				mux.HandleFunc("/books", list)
+				mux.HandleFunc("/books", list, Name("books.list"))
+			},
+			Panic: true,
+		},
+		{
+			Name: "adapted handlers are distinct",
+			Register: func(mux *Mux) {
+				mux.StandardHandle("/books", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "list") }))
+				mux.StandardHandle("/books", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "show") }))
+			},
+			Panic: true,
+		},
+		{
+			Name: "same mux",
+			Register: func(mux *Mux) {
+				mux.Handle("/api/", api)
+				mux.Handle("/api/", api)
+			},
+		},
+		{
+			Name: "same name",
+			Register: func(mux *Mux) {
+				mux.HandleFunc("/books", list, Name("books.list"))
+				mux.HandleFunc("/books", show, Name("books.list"))
+			},
+		},
+		{
+			Name: "other handler",
+			Register: func(mux *Mux) {
+				mux.HandleFunc("/books", list)
+				mux.HandleFunc("/books", show)
+			},
+			Panic: true,
+		},
+		{
+			Name: "same name on another pattern",
+			Register: func(mux *Mux) {
+				mux.HandleFunc("/books", list, Name("books.list"))
+				mux.HandleFunc("/books/", list, Name("books.list"))
+			},
+			Panic: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mux := New(TolerateDuplicates(true))
+			func() {
+				defer func() {
+					if recovered := recover(); (recovered != nil) != tc.Panic {
+						t.Fatalf("expected panic %v but got %v", tc.Panic, recovered)
+					}
+				}()
+				tc.Register(mux)
+			}()
+			if tc.Panic {
+				return
+			}
+
+			if routes := mux.Routes(); len(routes) != 1 {
+				t.Errorf("expected a single route but got %d", len(routes))
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/books", nil))
+			if w.Code == 200 && w.Body.String() != "list" {
+				t.Errorf("expected first registration to be kept but got %q", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected duplicate registration to panic by default")
+			}
+		}()
+		mux := New()
+		mux.HandleFunc("/books", list)
+		mux.HandleFunc("/books", list)
+	})
+}
+
 func TestRegexExpressionMatching(t *testing.T) {
 	mux := New()
 
-	mock := new(HandlerMock)
+	var calls int
+	var actualParams map[string]string
 
-	mux.Handle(`/assets/#dir:folder-\d+/:name`, mock)
+	mux.HandleFunc(`/assets/#dir:folder-\d+/:name`, func(w http.ResponseWriter, r *http.Request, c Context) {
+		calls++
+		actualParams = c.Params()
+	})
 
 	r := httptest.NewRequest("GET", "/assets/folder-123/readme.txt", nil)
 	w := httptest.NewRecorder()
 
 	mux.ServeHTTP(w, r)
 
-	if calls := len(mock.calls.ServeHTTPx); calls != 1 {
+	if calls != 1 {
 		t.Fatalf("expected 1 call but got %d", calls)
 	}
 
-	actualParams := mock.calls.ServeHTTPx[0].C.Params()
 	expectedParams := map[string]string{
 		"dir":  "folder-123",
 		"name": "readme.txt",
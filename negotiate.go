@@ -0,0 +1,146 @@
+package muxter
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentEncodings is a registration option declaring the content encodings, such as gzip, the route accepts for
+// request bodies. Requests with another Content-Encoding are rejected with 415 Unsupported Media Type. Requests
+// without a Content-Encoding, or with the identity encoding, are always accepted. The encodings are recorded in the
+// route's RouteInfo.
+//
+//	mux.HandleFunc("/events", ingest, muxter.ContentEncodings("gzip"), muxter.Decompress)
+func ContentEncodings(encodings ...string) Middleware {
+	return func(h Handler) Handler {
+		return routeOption{
+			Handler: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				for _, value := range r.Header.Values("Content-Encoding") {
+					for _, encoding := range strings.Split(value, ",") {
+						if encoding = strings.TrimSpace(encoding); encoding != "" && !strings.EqualFold(encoding, "identity") && !containsFold(encodings, encoding) {
+							w.Header().Set("Accept-Encoding", strings.Join(encodings, ", "))
+							writeStatus(w, c, http.StatusUnsupportedMediaType)
+							return
+						}
+					}
+				}
+				h.ServeHTTPx(w, r, c)
+			}),
+			apply: func(ri *RouteInfo) {
+				ri.ContentEncodings = append(ri.ContentEncodings, encodings...)
+			},
+		}
+	}
+}
+
+// Produces is a registration option declaring the media types of the route's responses. Requests whose Accept
+// header does not accept any of them are rejected with 406 Not Acceptable, and requests without an Accept header
+// are always accepted. The media types are recorded in the route's RouteInfo, and handlers can pick the media type
+// of their response with NegotiateContentType.
+//
+//	mux.HandleFunc("/reports/:id", report, muxter.Produces("application/json", "text/csv"))
+func Produces(mediaTypes ...string) Middleware {
+	return func(h Handler) Handler {
+		return routeOption{
+			Handler: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				if r.Header.Get("Accept") != "" && NegotiateContentType(r, mediaTypes...) == "" {
+					writeStatus(w, c, http.StatusNotAcceptable)
+					return
+				}
+				h.ServeHTTPx(w, r, c)
+			}),
+			apply: func(ri *RouteInfo) {
+				ri.Produces = append(ri.Produces, mediaTypes...)
+			},
+		}
+	}
+}
+
+// NegotiateContentType returns the offered media type the request's Accept header prefers, or the empty string if
+// it accepts none of them. Ties are broken by the more specific media range and then by the order of the offers.
+// Without an Accept header the first offer is returned.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		mediaType, _, err := mime.ParseMediaType(offer)
+		if err != nil {
+			continue
+		}
+
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+
+			s := mediaRangeSpecificity(rangeType, mediaType)
+			if s < 0 || s < specificity {
+				continue
+			}
+
+			rangeQ := 1.0
+			if value, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					rangeQ = parsed
+				}
+			}
+			q, specificity = rangeQ, s
+		}
+
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
+// mediaRangeSpecificity returns how specifically the media range matches the media type: 2 for an exact match,
+// 1 for a type/* range, 0 for */* and -1 if it does not match.
+func mediaRangeSpecificity(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, mediaRange[:len(mediaRange)-1]):
+		return 1
+	default:
+		return -1
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header lists the encoding without a zero q-value.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			q, err := strconv.ParseFloat(params[len("q="):], 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
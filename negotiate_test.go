@@ -0,0 +1,95 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestContentEncodingsAndProduces(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte(NegotiateContentType(r, "application/json", "text/csv")))
+	}, ContentEncodings("gzip"), Produces("application/json", "text/csv"))
+
+	testcases := []struct {
+		Name            string
+		Header          map[string]string
+		ExpectedCode    int
+		ExpectedBody    string
+		ExpectedHeaders map[string]string
+	}{
+		{
+			Name:         "no headers",
+			ExpectedCode: 200,
+			ExpectedBody: "application/json",
+		},
+		{
+			Name:         "supported encoding",
+			Header:       map[string]string{"Content-Encoding": "gzip", "Accept": "text/csv"},
+			ExpectedCode: 200,
+			ExpectedBody: "text/csv",
+		},
+		{
+			Name:         "identity encoding",
+			Header:       map[string]string{"Content-Encoding": "identity"},
+			ExpectedCode: 200,
+			ExpectedBody: "application/json",
+		},
+		{
+			Name:            "unsupported encoding",
+			Header:          map[string]string{"Content-Encoding": "br"},
+			ExpectedCode:    415,
+			ExpectedBody:    "Unsupported Media Type\n",
+			ExpectedHeaders: map[string]string{"Accept-Encoding": "gzip"},
+		},
+		{
+			Name:         "wildcard accept with preferences",
+			Header:       map[string]string{"Accept": "application/json;q=0.5, text/*;q=0.8, */*;q=0.1"},
+			ExpectedCode: 200,
+			ExpectedBody: "text/csv",
+		},
+		{
+			Name:         "excluded media type",
+			Header:       map[string]string{"Accept": "text/csv;q=0, */*"},
+			ExpectedCode: 200,
+			ExpectedBody: "application/json",
+		},
+		{
+			Name:         "not acceptable",
+			Header:       map[string]string{"Accept": "text/html, application/xml;q=0.9"},
+			ExpectedCode: 406,
+			ExpectedBody: "Not Acceptable\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/reports", nil)
+			for key, value := range tc.Header {
+				r.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+			for key, value := range tc.ExpectedHeaders {
+				if actual := w.Header().Get(key); actual != value {
+					t.Errorf("expected header %s to be %q but got %q", key, value, actual)
+				}
+			}
+		})
+	}
+
+	route := mux.Routes()[0]
+	if !reflect.DeepEqual(route.ContentEncodings, []string{"gzip"}) || !reflect.DeepEqual(route.Produces, []string{"application/json", "text/csv"}) {
+		t.Errorf("expected route to record its encodings and media types but got %+v", route)
+	}
+}
@@ -0,0 +1,80 @@
+package muxter
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Normalize normalizes requests before they are routed: the Host is lowercased and stripped of the default port
+// for the request's scheme, duplicate slashes in the path are collapsed and dot-segments are resolved. A trailing
+// slash is preserved. Normalization happens in ServeHTTP and therefore applies to the mux requests enter through.
+//
+// If report is not nil it is called with the normalized request and its original host and path whenever
+// normalization modified the request, which is useful for finding misbehaving clients.
+func Normalize(report func(r *http.Request, host, path string)) MuxOption {
+	return func(m *Mux) {
+		m.normalize = func(r *http.Request) {
+			host, path := r.Host, r.URL.Path
+			if !normalizeRequest(r) || report == nil {
+				return
+			}
+			report(r, host, path)
+		}
+	}
+}
+
+func normalizeRequest(r *http.Request) (modified bool) {
+	if host := normalizeHost(r.Host, requestScheme(r)); host != r.Host {
+		r.Host = host
+		modified = true
+	}
+
+	if p := cleanPath(r.URL.Path); p != r.URL.Path {
+		r.URL.Path = p
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = cleanPath(r.URL.RawPath)
+		}
+		modified = true
+	}
+
+	return modified
+}
+
+func requestScheme(r *http.Request) string {
+	if proto := firstHeaderValue(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+		return strings.ToLower(proto)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func normalizeHost(host, scheme string) string {
+	host = strings.ToLower(host)
+	switch {
+	case scheme == "http" && strings.HasSuffix(host, ":80"):
+		return host[:len(host)-len(":80")]
+	case scheme == "https" && strings.HasSuffix(host, ":443"):
+		return host[:len(host)-len(":443")]
+	default:
+		return host
+	}
+}
+
+// cleanPath returns the canonical path for p, eliminating duplicate slashes and dot-segments
+// while preserving a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
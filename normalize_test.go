@@ -0,0 +1,120 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	type report struct {
+		host string
+		path string
+	}
+
+	var reports []report
+
+	mux := New(Normalize(func(r *http.Request, host, path string) {
+		reports = append(reports, report{host, path})
+	}))
+
+	var (
+		host    string
+		pattern string
+	)
+	handler := func(w http.ResponseWriter, r *http.Request, c Context) {
+		host, pattern = r.Host, c.Pattern()
+	}
+
+	mux.HandleFunc("/api/books", handler)
+	mux.HandleFunc("/api/books/", handler)
+
+	testcases := []struct {
+		Name            string
+		Host            string
+		Path            string
+		ExpectedHost    string
+		ExpectedPattern string
+		Reported        bool
+	}{
+		{
+			Name:            "already normal",
+			Host:            "example.com",
+			Path:            "/api/books",
+			ExpectedHost:    "example.com",
+			ExpectedPattern: "/api/books",
+		},
+		{
+			Name:            "default port",
+			Host:            "Example.com:80",
+			Path:            "/api/books",
+			ExpectedHost:    "example.com",
+			ExpectedPattern: "/api/books",
+			Reported:        true,
+		},
+		{
+			Name:            "non default port",
+			Host:            "example.com:8080",
+			Path:            "/api/books",
+			ExpectedHost:    "example.com:8080",
+			ExpectedPattern: "/api/books",
+		},
+		{
+			Name:            "duplicate slashes",
+			Host:            "example.com",
+			Path:            "//api///books",
+			ExpectedHost:    "example.com",
+			ExpectedPattern: "/api/books",
+			Reported:        true,
+		},
+		{
+			Name:            "dot segments with trailing slash",
+			Host:            "example.com",
+			Path:            "/api/./authors/../books/",
+			ExpectedHost:    "example.com",
+			ExpectedPattern: "/api/books/",
+			Reported:        true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			reports = nil
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = tc.Host
+			r.URL.Path = tc.Path
+
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if host != tc.ExpectedHost {
+				t.Errorf("expected host %q but got %q", tc.ExpectedHost, host)
+			}
+			if pattern != tc.ExpectedPattern {
+				t.Errorf("expected pattern %q but got %q", tc.ExpectedPattern, pattern)
+			}
+
+			if !tc.Reported {
+				if len(reports) != 0 {
+					t.Errorf("expected no reports but got %+v", reports)
+				}
+				return
+			}
+			if len(reports) != 1 || reports[0] != (report{tc.Host, tc.Path}) {
+				t.Errorf("expected original host and path to be reported but got %+v", reports)
+			}
+		})
+	}
+
+	t.Run("https default port", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/api/books", nil)
+		r.Host = "example.com:443"
+		r.Header.Set("X-Forwarded-Proto", "https")
+
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		if host != "example.com" {
+			t.Errorf("expected host %q but got %q", "example.com", host)
+		}
+	})
+}
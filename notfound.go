@@ -0,0 +1,295 @@
+package muxter
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+)
+
+// NotFoundStatsOptions configures NotFoundStats.
+type NotFoundStatsOptions struct {
+	// MaxBuckets is the number of buckets kept. Not found requests falling in new buckets once it is reached are only
+	// counted as dropped. It defaults to 1000.
+	MaxBuckets int
+	// Samples is the number of distinct paths kept as examples of every bucket. It defaults to 3.
+	Samples int
+	// Clock records when buckets were last seen. It defaults to the system clock.
+	Clock Clock
+}
+
+// NotFoundStats aggregates the requests served as not found by a mux, bucketed by the registered pattern nearest to
+// their path and by their referrer, such that the broken links of a page and the clients using outdated paths stand
+// out after a deploy. It records the NotFoundEvents of an EventBus:
+//
+//	notFound := muxter.NewNotFoundStats(mux, muxter.NotFoundStatsOptions{})
+//	events.OnNotFound(notFound.Record)
+//
+// The nearest pattern is the pattern of the mux's path routes whose segments are the fewest edits away from the
+// segments of the path, where a segment differing by a typo counts as half an edit. Requests whose path matches none
+// of the static segments of any pattern have no nearest pattern. Routes of nested muxes are not considered.
+type NotFoundStats struct {
+	mux  *Mux
+	opts NotFoundStatsOptions
+
+	mu      sync.Mutex
+	buckets map[notFoundKey]*NotFoundBucket
+	dropped uint64
+}
+
+type notFoundKey struct {
+	nearest  string
+	referrer string
+}
+
+// NotFoundBucket counts the not found requests sharing a nearest pattern and referrer.
+type NotFoundBucket struct {
+	// Nearest is the registered pattern nearest to the paths of the requests, or empty if none is close.
+	Nearest string `json:"nearest"`
+	// Referrer is the Referer of the requests without its query and fragment, or empty if they had none.
+	Referrer string `json:"referrer"`
+	Count    uint64 `json:"count"`
+	// Paths are examples of the paths of the requests, up to the configured number of samples.
+	Paths    []string  `json:"paths"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// NewNotFoundStats returns NotFoundStats for the requests served as not found by the mux, configured by opts.
+func NewNotFoundStats(mux *Mux, opts NotFoundStatsOptions) *NotFoundStats {
+	if opts.MaxBuckets <= 0 {
+		opts.MaxBuckets = 1000
+	}
+	if opts.Samples <= 0 {
+		opts.Samples = 3
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	return &NotFoundStats{mux: mux, opts: opts, buckets: map[notFoundKey]*NotFoundBucket{}}
+}
+
+// Record counts the not found request of the event.
+func (s *NotFoundStats) Record(event NotFoundEvent) {
+	key := notFoundKey{
+		nearest:  s.mux.nearestPattern(event.Path),
+		referrer: normalizeReferrer(event.Request.Referer()),
+	}
+	now := s.opts.Clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= s.opts.MaxBuckets {
+			s.dropped++
+			return
+		}
+		bucket = &NotFoundBucket{Nearest: key.nearest, Referrer: key.referrer}
+		s.buckets[key] = bucket
+	}
+	bucket.Count++
+	bucket.LastSeen = now
+	if len(bucket.Paths) >= s.opts.Samples {
+		return
+	}
+	for _, path := range bucket.Paths {
+		if path == event.Path {
+			return
+		}
+	}
+	bucket.Paths = append(bucket.Paths, event.Path)
+}
+
+// Buckets returns the buckets sorted by decreasing count, then by nearest pattern and referrer.
+func (s *NotFoundStats) Buckets() []NotFoundBucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets := make([]NotFoundBucket, 0, len(s.buckets))
+	for _, bucket := range s.buckets {
+		copied := *bucket
+		copied.Paths = append([]string(nil), bucket.Paths...)
+		buckets = append(buckets, copied)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		a, b := buckets[i], buckets[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Nearest != b.Nearest {
+			return a.Nearest < b.Nearest
+		}
+		return a.Referrer < b.Referrer
+	})
+	return buckets
+}
+
+// Dropped returns the number of not found requests that were not bucketed because MaxBuckets was reached.
+func (s *NotFoundStats) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Reset forgets the buckets and dropped requests, for example to start over after a deploy.
+func (s *NotFoundStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets = map[notFoundKey]*NotFoundBucket{}
+	s.dropped = 0
+}
+
+// Handler returns a handler serving the buckets and dropped requests as JSON, as {"buckets":[...],"dropped":0}.
+func (s *NotFoundStats) Handler() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		writeAdminJSON(w, struct {
+			Buckets []NotFoundBucket `json:"buckets"`
+			Dropped uint64           `json:"dropped"`
+		}{s.Buckets(), s.Dropped()})
+	})
+}
+
+// normalizeReferrer strips the query and fragment of the referrer, which would otherwise split the requests of a
+// page into as many buckets as its query strings.
+func normalizeReferrer(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return "invalid"
+	}
+	u.RawQuery, u.Fragment, u.RawFragment, u.User = "", "", "", nil
+	return u.String()
+}
+
+// nearestPattern returns the pattern of the path routes of the mux nearest to the path, or the empty string if no
+// pattern is close. A pattern is close when it is fewer edits away than it has static segments, such that at least
+// one of them matches. Ties are broken in favor of the pattern sorting first.
+func (m *Mux) nearestPattern(path string) string {
+	segments := splitSegments(path)
+
+	var (
+		nearest string
+		best    float64
+	)
+	m.root.walk(func(v *value) {
+		if v.route == nil || !strings.HasPrefix(v.route.Pattern, "/") {
+			return
+		}
+		pattern := v.route.Pattern
+		patternSegments := splitSegments(pattern)
+		distance := patternDistance(patternSegments, strings.HasSuffix(pattern, "/"), segments)
+		if distance >= float64(staticSegments(patternSegments)) {
+			return
+		}
+		if nearest == "" || distance < best || (distance == best && pattern < nearest) {
+			nearest, best = pattern, distance
+		}
+	})
+	return nearest
+}
+
+func splitSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func staticSegments(segments []string) int {
+	n := 0
+	for _, segment := range segments {
+		if !isParamSegment(segment) && !strings.HasPrefix(segment, "*") {
+			n++
+		}
+	}
+	return n
+}
+
+func isParamSegment(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "#")
+}
+
+// patternDistance is the edit distance between the segments of a pattern and those of a path, where params match any
+// segment and a trailing catchall, or the subtree of a pattern ending with a slash, matches any remaining segments. A
+// param missing from the path counts as half an edit.
+func patternDistance(pattern []string, subtree bool, path []string) float64 {
+	rest := subtree
+	if n := len(pattern); n > 0 && strings.HasPrefix(pattern[n-1], "*") {
+		pattern, rest = pattern[:n-1], true
+	}
+
+	// row[j] is the distance between the pattern segments seen so far and the first j segments of the path.
+	row := make([]float64, len(path)+1)
+	for j := range row {
+		row[j] = float64(j)
+	}
+	next := make([]float64, len(path)+1)
+	for _, p := range pattern {
+		missing := 1.0
+		if isParamSegment(p) {
+			missing = 0.5
+		}
+		next[0] = row[0] + missing
+		for j, segment := range path {
+			next[j+1] = minFloat(row[j]+segmentDistance(p, segment), minFloat(row[j+1]+missing, next[j]+1))
+		}
+		row, next = next, row
+	}
+
+	if !rest {
+		return row[len(path)]
+	}
+	distance := row[0]
+	for _, d := range row[1:] {
+		distance = minFloat(distance, d)
+	}
+	return distance
+}
+
+// segmentDistance is the cost of substituting the segment of a path for the segment of a pattern: none if it is a
+// param or the same segment, half an edit for a typo, and a full edit otherwise.
+func segmentDistance(pattern, segment string) float64 {
+	switch {
+	case isParamSegment(pattern) || syntax.Unescape(pattern) == segment:
+		return 0
+	case len(pattern) > 3 && editDistance(pattern, segment) <= 2:
+		return 0.5
+	default:
+		return 1
+	}
+}
+
+// editDistance is the Levenshtein distance between two strings, counted in bytes.
+func editDistance(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 0; i < len(a); i++ {
+		prev := row[0]
+		row[0] = i + 1
+		for j := 0; j < len(b); j++ {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			prev, row[j+1] = row[j+1], min(prev+cost, min(row[j+1], row[j])+1)
+		}
+	}
+	return row[len(b)]
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
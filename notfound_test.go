@@ -0,0 +1,127 @@
+package muxter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNearestPattern(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New()
+	mux.HandleFunc("/books/:id", handler)
+	mux.HandleFunc("/books/:id/reviews", handler)
+	mux.HandleFunc("/authors/:name", handler)
+	mux.HandleFunc("/static/", handler)
+	mux.HandleFunc("/files/*path", handler)
+
+	testcases := []struct {
+		Path     string
+		Expected string
+	}{
+		{Path: "/bokos/42", Expected: "/books/:id"},
+		{Path: "/books/42/review", Expected: "/books/:id/reviews"},
+		{Path: "/books/42/reviews/7", Expected: "/books/:id/reviews"},
+		{Path: "/books", Expected: "/books/:id"},
+		{Path: "/author/ursula", Expected: "/authors/:name"},
+		{Path: "/statics/app.js", Expected: "/static/"},
+		{Path: "/file/a/b/c", Expected: "/files/*path"},
+		{Path: "/wp-admin/setup.php", Expected: ""},
+		{Path: "/", Expected: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Path, func(t *testing.T) {
+			if nearest := mux.nearestPattern(tc.Path); nearest != tc.Expected {
+				t.Errorf("expected nearest pattern %q but got %q", tc.Expected, nearest)
+			}
+		})
+	}
+}
+
+func TestNotFoundStats(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := NewEventBus()
+
+	mux := New(Events(events))
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	stats := NewNotFoundStats(mux, NotFoundStatsOptions{MaxBuckets: 3, Samples: 2, Clock: clock})
+	events.OnNotFound(stats.Record)
+
+	for _, req := range []struct{ Path, Referrer string }{
+		{"/bokos/1", "https://blog.example.com/post?utm_source=feed"},
+		{"/bokos/2", "https://blog.example.com/post?utm_source=mail"},
+		{"/bokos/3", "https://blog.example.com/post"},
+		{"/bokos/1", ""},
+		{"/admin.php", ""},
+		{"/.env", "https://scanner.example"},
+		{"/.git/config", "https://other.example"},
+	} {
+		r := httptest.NewRequest("GET", req.Path, nil)
+		if req.Referrer != "" {
+			r.Header.Set("Referer", req.Referrer)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	expected := []NotFoundBucket{
+		{Nearest: "/books/:id", Referrer: "https://blog.example.com/post", Count: 3, Paths: []string{"/bokos/1", "/bokos/2"}, LastSeen: clock.Now()},
+		{Nearest: "", Referrer: "", Count: 1, Paths: []string{"/admin.php"}, LastSeen: clock.Now()},
+		{Nearest: "/books/:id", Referrer: "", Count: 1, Paths: []string{"/bokos/1"}, LastSeen: clock.Now()},
+	}
+	if buckets := stats.Buckets(); !reflect.DeepEqual(buckets, expected) {
+		t.Errorf("expected buckets %+v but got %+v", expected, buckets)
+	}
+	if dropped := stats.Dropped(); dropped != 2 {
+		t.Errorf("expected 2 dropped requests but got %d", dropped)
+	}
+
+	stats.Reset()
+	if buckets, dropped := stats.Buckets(), stats.Dropped(); len(buckets) != 0 || dropped != 0 {
+		t.Errorf("expected reset stats but got %+v and %d dropped", buckets, dropped)
+	}
+}
+
+func TestAdminNotFound(t *testing.T) {
+	events := NewEventBus()
+	mux := New(Events(events))
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	stats := NewNotFoundStats(mux, NotFoundStatsOptions{})
+	events.OnNotFound(stats.Record)
+
+	mux.Handle("/admin/", StripDepth(1, mux.AdminHandler(AdminOptions{
+		Authorize: func(r *http.Request, c Context) bool { return true },
+		NotFound:  stats,
+	})))
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bookz/1", nil))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/admin/notfound", nil))
+
+	var body struct {
+		Buckets []NotFoundBucket
+		Dropped uint64
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Buckets) != 1 || body.Buckets[0].Nearest != "/books/:id" || body.Buckets[0].Count != 1 {
+		t.Errorf("unexpected buckets: %+v", body.Buckets)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/admin/notfound", nil))
+	if w.Code != 204 {
+		t.Errorf("expected 204 but got %d", w.Code)
+	}
+	if buckets := stats.Buckets(); len(buckets) != 0 {
+		t.Errorf("expected no buckets after reset but got %+v", buckets)
+	}
+}
@@ -0,0 +1,499 @@
+package muxter
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig configures the OIDC middleware.
+type OIDCConfig struct {
+	// Issuer is the URL of the OpenID provider, such as "https://accounts.example.com". The endpoints of the provider
+	// are discovered from its /.well-known/openid-configuration document when the middleware is first used.
+	Issuer string
+	// ClientID is the identifier of the client registered with the provider.
+	ClientID string
+	// ClientSecret is the secret of confidential clients. Public clients leave it empty and rely on PKCE alone.
+	ClientSecret string
+	// RedirectURL is the absolute URL of the callback registered with the provider, such as
+	// "https://app.example.com/auth/callback". Its path is registered on the mux.
+	RedirectURL string
+	// Scopes are the scopes requested in addition to openid. They default to profile and email.
+	Scopes []string
+	// SessionKey is the secret of at least 32 bytes the session cookies are signed with.
+	SessionKey []byte
+	// SessionTTL is how long a session lasts before the user is authenticated again. It defaults to 8 hours.
+	SessionTTL time.Duration
+	// CookieName is the name of the session cookie. It defaults to muxter_oidc. The cookie holding the state of a
+	// login in progress has the _flow suffix.
+	CookieName string
+	// Insecure issues cookies without the Secure attribute, for development over plain HTTP.
+	Insecure bool
+	// LoginPath, if set, is registered on the mux to start the login, returning to the URL of its next query
+	// parameter if it is safe to redirect to, or to "/". It is how users log in when the middleware is Optional.
+	LoginPath string
+	// LogoutPath, if set, is registered on the mux to end the session and redirect to "/".
+	LogoutPath string
+	// Optional lets requests without a session through anonymously instead of starting the login, for routes that
+	// are public but personalized for authenticated users. Handlers tell anonymous requests apart by their nil
+	// Identity.
+	Optional bool
+	// Client is the client used to make requests to the provider. It defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// OIDC returns a middleware authenticating users with the OpenID Connect authorization code flow, and registers the
+// callback of the flow on the mux. Requests without a session are redirected to the provider, or answered with 401
+// Unauthorized if they are not GET or HEAD requests, and the user's Identity is available to handlers from the
+// Context once authenticated. The scopes required by a route with RequireScopes are requested in addition to the
+// configured scopes when authenticating users for the route.
+//
+// The flow is protected with state, nonce and PKCE, and ID tokens are verified against the provider's JSON Web Key
+// Set. Sessions are kept in a cookie signed with the SessionKey, such that they hold no server side state. OIDC
+// panics if the configuration is invalid.
+//
+//	oidc := muxter.OIDC(mux, muxter.OIDCConfig{
+//		Issuer:      "https://accounts.example.com",
+//		ClientID:    "app",
+//		RedirectURL: "https://app.example.com/auth/callback",
+//		SessionKey:  sessionKey,
+//	})
+//	mux.UseOn("/app/", oidc)
+func OIDC(mux *Mux, config OIDCConfig) Middleware {
+	redirect, err := url.Parse(config.RedirectURL)
+	if err != nil || redirect.Scheme == "" || redirect.Host == "" || redirect.Path == "" {
+		panic(fmt.Sprintf("muxter: oidc redirect url must be an absolute url with a path but got: %s", config.RedirectURL))
+	}
+	if config.Issuer == "" || config.ClientID == "" {
+		panic("muxter: oidc issuer and client id are required")
+	}
+	if len(config.SessionKey) < 32 {
+		panic("muxter: oidc session key must be at least 32 bytes")
+	}
+	if config.Scopes == nil {
+		config.Scopes = []string{"profile", "email"}
+	}
+	if config.SessionTTL == 0 {
+		config.SessionTTL = 8 * time.Hour
+	}
+	if config.CookieName == "" {
+		config.CookieName = "muxter_oidc"
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	o := &oidcClient{config: config, redirect: redirect}
+
+	mux.HandleFunc(redirect.Path, o.callback, mux.Method(http.MethodGet))
+	if config.LoginPath != "" {
+		mux.HandleFunc(config.LoginPath, func(w http.ResponseWriter, r *http.Request, c Context) {
+			next := r.URL.Query().Get("next")
+			if !safeRedirectTarget(r, next, nil) {
+				next = "/"
+			}
+			o.login(w, r, c, next)
+		}, mux.Method(http.MethodGet))
+	}
+	if config.LogoutPath != "" {
+		mux.HandleFunc(config.LogoutPath, o.logout)
+	}
+
+	return o.middleware
+}
+
+type oidcClient struct {
+	config   OIDCConfig
+	redirect *url.URL
+
+	mu       sync.Mutex
+	provider *oidcProvider
+	keys     *keySet
+	// failures is the number of consecutive failed discoveries, err the error of the last one and retry the time
+	// before which no discovery is attempted again.
+	failures int
+	err      error
+	retry    time.Time
+	// flight is closed once the discovery in progress is done, or nil if there is none.
+	flight chan struct{}
+}
+
+// oidcProvider are the metadata of the provider from its discovery document.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcFlow is the state of a login in progress, kept in a signed cookie until the callback.
+type oidcFlow struct {
+	State    string   `json:"state"`
+	Nonce    string   `json:"nonce"`
+	Verifier string   `json:"verifier"`
+	ReturnTo string   `json:"returnTo"`
+	Scopes   []string `json:"scopes"`
+	Expires  int64    `json:"exp"`
+}
+
+type oidcSession struct {
+	Identity
+	Expires int64 `json:"exp"`
+}
+
+const oidcFlowTTL = 10 * time.Minute
+
+func (o *oidcClient) middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		var session oidcSession
+		if o.open(r, o.config.CookieName, &session) && time.Now().Unix() < session.Expires {
+			c.editExtras().identity = &session.Identity
+			h.ServeHTTPx(w, r, c)
+			return
+		}
+
+		if o.config.Optional {
+			h.ServeHTTPx(w, r, c)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeStatus(w, c, http.StatusUnauthorized)
+			return
+		}
+		o.login(w, r, c, c.requestURL(r).RequestURI())
+	})
+}
+
+// login starts the authorization code flow, redirecting to the provider, to return to returnTo once logged in.
+func (o *oidcClient) login(w http.ResponseWriter, r *http.Request, c Context, returnTo string) {
+	provider, err := o.discover(r.Context())
+	if err != nil {
+		writeStatus(w, c, http.StatusBadGateway)
+		return
+	}
+
+	flow := oidcFlow{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: randomToken(),
+		ReturnTo: returnTo,
+		Scopes:   append([]string{"openid"}, o.config.Scopes...),
+		Expires:  time.Now().Add(oidcFlowTTL).Unix(),
+	}
+	if route := c.Route(); route != nil {
+		flow.Scopes = appendTags(flow.Scopes, route.Scopes...)
+	}
+	o.setCookie(w, o.config.CookieName+"_flow", flow, o.redirect.Path, oidcFlowTTL)
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+
+	authorize, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		writeStatus(w, c, http.StatusBadGateway)
+		return
+	}
+	query := authorize.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", o.config.ClientID)
+	query.Set("redirect_uri", o.config.RedirectURL)
+	query.Set("scope", strings.Join(flow.Scopes, " "))
+	query.Set("state", flow.State)
+	query.Set("nonce", flow.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authorize.RawQuery = query.Encode()
+
+	http.Redirect(w, r, authorize.String(), http.StatusFound)
+}
+
+// callback completes the authorization code flow, establishing the session and returning to the URL the login was
+// started from.
+func (o *oidcClient) callback(w http.ResponseWriter, r *http.Request, c Context) {
+	var flow oidcFlow
+	if !o.open(r, o.config.CookieName+"_flow", &flow) || time.Now().Unix() >= flow.Expires {
+		writeStatus(w, c, http.StatusBadRequest)
+		return
+	}
+	o.clearCookie(w, o.config.CookieName+"_flow", o.redirect.Path)
+
+	query := r.URL.Query()
+	if !hmac.Equal([]byte(query.Get("state")), []byte(flow.State)) {
+		writeStatus(w, c, http.StatusBadRequest)
+		return
+	}
+	if query.Get("error") != "" {
+		writeStatus(w, c, http.StatusUnauthorized)
+		return
+	}
+	if query.Get("code") == "" {
+		writeStatus(w, c, http.StatusBadRequest)
+		return
+	}
+
+	provider, err := o.discover(r.Context())
+	if err != nil {
+		writeStatus(w, c, http.StatusBadGateway)
+		return
+	}
+
+	token, err := o.exchange(r.Context(), provider, query.Get("code"), flow.Verifier)
+	if err != nil {
+		writeStatus(w, c, http.StatusBadGateway)
+		return
+	}
+
+	claims, err := o.verify(r.Context(), provider, token.IDToken, flow.Nonce)
+	if err != nil {
+		writeStatus(w, c, http.StatusUnauthorized)
+		return
+	}
+
+	session := oidcSession{
+		Identity: Identity{
+			Subject: claims.Subject,
+			Email:   claims.Email,
+			Name:    claims.Name,
+			Groups:  claims.groups(),
+			Scopes:  strings.Fields(token.Scope),
+		},
+		Expires: time.Now().Add(o.config.SessionTTL).Unix(),
+	}
+	if session.Scopes == nil {
+		session.Scopes = flow.Scopes
+	}
+
+	o.setCookie(w, o.config.CookieName, session, "/", o.config.SessionTTL)
+
+	// The flow is signed, but its return URL comes from the request that started the login, which may be crafted.
+	returnTo := flow.ReturnTo
+	if !safeRedirectTarget(r, returnTo, nil) {
+		returnTo = "/"
+	}
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+func (o *oidcClient) logout(w http.ResponseWriter, r *http.Request, c Context) {
+	o.clearCookie(w, o.config.CookieName, "/")
+	http.Redirect(w, r, "/", redirectCode(r))
+}
+
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+	Scope   string `json:"scope"`
+}
+
+// exchange redeems the authorization code at the token endpoint.
+func (o *oidcClient) exchange(ctx context.Context, provider *oidcProvider, code, verifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if o.config.ClientSecret == "" {
+		form.Set("client_id", o.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+
+	var token tokenResponse
+	if err := doJSON(o.config.Client, req, &token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id token")
+	}
+	return &token, nil
+}
+
+// verify verifies the signature and claims of the ID token.
+func (o *oidcClient) verify(ctx context.Context, provider *oidcProvider, idToken, nonce string) (*tokenClaims, error) {
+	payload, err := verifyJWS(idToken, func(header jwsHeader) (crypto.PublicKey, error) {
+		return o.keys.key(ctx, header.Kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	if err := claims.verify(provider.Issuer, o.config.ClientID); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != o.config.ClientID:
+		return nil, errors.New("token is not authorized for the client")
+	case !hmac.Equal([]byte(claims.Nonce), []byte(nonce)):
+		return nil, errors.New("nonce mismatch")
+	}
+	return &claims, nil
+}
+
+// discover returns the provider's metadata, fetching its discovery document on first use.
+func (o *oidcClient) discover(ctx context.Context) (*oidcProvider, error) {
+	for {
+		o.mu.Lock()
+		if o.provider != nil {
+			provider := o.provider
+			o.mu.Unlock()
+			return provider, nil
+		}
+		if time.Now().Before(o.retry) {
+			err := o.err
+			o.mu.Unlock()
+			return nil, err
+		}
+		if flight := o.flight; flight != nil {
+			o.mu.Unlock()
+			select {
+			case <-flight:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		flight := make(chan struct{})
+		o.flight = flight
+		o.mu.Unlock()
+
+		provider, err := o.fetchProvider(ctx)
+
+		o.mu.Lock()
+		o.flight = nil
+		close(flight)
+		switch {
+		case err == nil:
+			o.provider, o.failures, o.err = provider, 0, nil
+			o.keys = &keySet{url: provider.JWKSURI, client: o.config.Client}
+		case ctx.Err() == nil:
+			// Discoveries abandoned by their request are not failures of the issuer.
+			o.failures++
+			o.err = err
+			o.retry = time.Now().Add(keySetBackoff(o.failures))
+		}
+		o.mu.Unlock()
+
+		return provider, err
+	}
+}
+
+// fetchProvider fetches the discovery document of the issuer.
+func (o *oidcClient) fetchProvider(ctx context.Context) (*oidcProvider, error) {
+	issuer := strings.TrimSuffix(o.config.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider oidcProvider
+	if err := doJSON(o.config.Client, req, &provider); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovered issuer %q does not match %q", provider.Issuer, o.config.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("incomplete discovery document")
+	}
+	return &provider, nil
+}
+
+// doJSON makes the request and decodes its JSON response.
+func doJSON(client *http.Client, req *http.Request, dst interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status %d", req.Method, req.URL, resp.StatusCode)
+	}
+	return json.Unmarshal(body, dst)
+}
+
+// setCookie sets a cookie holding v, signed such that it cannot be forged by the client.
+func (o *oidcClient) setCookie(w http.ResponseWriter, name string, v interface{}, path string, maxAge time.Duration) {
+	payload, _ := json.Marshal(v)
+	value := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value + "." + o.sign(name, value),
+		Path:     path,
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   !o.config.Insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (o *oidcClient) clearCookie(w http.ResponseWriter, name, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     path,
+		MaxAge:   -1,
+		Secure:   !o.config.Insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// open decodes the signed cookie into v. It returns false if the cookie is missing or its signature is invalid.
+func (o *oidcClient) open(r *http.Request, name string, v interface{}) bool {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+	value, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(o.sign(name, value))) {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(payload, v) == nil
+}
+
+// sign returns the signature of the cookie value, which covers the cookie name so that the value of one cookie
+// cannot be used as another.
+func (o *oidcClient) sign(name, value string) string {
+	mac := hmac.New(sha256.New, o.config.SessionKey)
+	mac.Write([]byte(name + "=" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("muxter: failed to generate random token: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,436 @@
+package muxter
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider is an OpenID provider issuing ID tokens signed with an RSA key for the codes it is told about.
+type testProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]interface{}
+	// challenge is the PKCE challenge of the last authorization request.
+	challenge string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{key: key}
+
+	mux := New()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request, c Context) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request, c Context) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request, c Context) {
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("code") != "code-1" || base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if id, secret, _ := r.BasicAuth(); id != "app" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(t, p.claims), "scope": "openid email"})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *testProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "key-1"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDC(t *testing.T) {
+	provider := newTestProvider(t)
+
+	mux := New()
+	oidc := OIDC(mux, OIDCConfig{
+		Issuer:       provider.URL,
+		ClientID:     "app",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/auth/callback",
+		SessionKey:   []byte(strings.Repeat("k", 32)),
+		LogoutPath:   "/auth/logout",
+	})
+	mux.UseOn("/app/", oidc)
+	mux.HandleFunc("/app/profile", func(w http.ResponseWriter, r *http.Request, c Context) {
+		id := c.Identity()
+		json.NewEncoder(w).Encode(id)
+	})
+	mux.HandleFunc("/app/books", func(w http.ResponseWriter, r *http.Request, c Context) {}, RequireScopes("books:read"))
+	mux.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.Identity() != nil {
+			t.Error("expected no identity outside of the protected subtree")
+		}
+	})
+
+	// login starts a flow for /app/profile and returns the flow cookie and the parameters of the authorization request.
+	login := func(t *testing.T) (*http.Cookie, url.Values) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "https://app.example.com/app/profile?tab=1", nil)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != 302 {
+			t.Fatalf("expected a redirect to the provider but got %d", w.Code)
+		}
+		location, _ := url.Parse(w.Header().Get("Location"))
+		if !strings.HasPrefix(location.String(), provider.URL+"/authorize?") {
+			t.Fatalf("expected a redirect to the authorization endpoint but got %s", location)
+		}
+		query := location.Query()
+		for key, expected := range map[string]string{
+			"response_type":         "code",
+			"client_id":             "app",
+			"redirect_uri":          "https://app.example.com/auth/callback",
+			"scope":                 "openid profile email",
+			"code_challenge_method": "S256",
+		} {
+			if actual := query.Get(key); actual != expected {
+				t.Errorf("expected %s to be %q but got %q", key, expected, actual)
+			}
+		}
+		provider.challenge = query.Get("code_challenge")
+
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Path != "/auth/callback" || !cookies[0].Secure || !cookies[0].HttpOnly {
+			t.Fatalf("expected a secure flow cookie scoped to the callback but got %v", cookies)
+		}
+		return cookies[0], query
+	}
+
+	validClaims := func(nonce string) map[string]interface{} {
+		return map[string]interface{}{
+			"iss":    provider.URL,
+			"sub":    "user-1",
+			"aud":    "app",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"nonce":  nonce,
+			"email":  "user@example.com",
+			"groups": []string{"admins"},
+		}
+	}
+
+	callback := func(flow *http.Cookie, query string) *httptest.ResponseRecorder {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "https://app.example.com/auth/callback?"+query, nil)
+		if flow != nil {
+			r.AddCookie(flow)
+		}
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("non GET requests are unauthorized", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/app/profile", nil)
+		mux.ServeHTTP(w, r)
+		if w.Code != 401 {
+			t.Errorf("expected code 401 but got %d", w.Code)
+		}
+	})
+
+	t.Run("required scopes are requested", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/app/books", nil)
+		mux.ServeHTTP(w, r)
+
+		location, _ := url.Parse(w.Header().Get("Location"))
+		if scope := location.Query().Get("scope"); scope != "openid profile email books:read" {
+			t.Errorf("expected the route's scopes to be requested but got %q", scope)
+		}
+	})
+
+	t.Run("public routes are not protected", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/public", nil)
+		mux.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Errorf("expected code 200 but got %d", w.Code)
+		}
+	})
+
+	t.Run("login", func(t *testing.T) {
+		flow, query := login(t)
+		provider.claims = validClaims(query.Get("nonce"))
+
+		w := callback(flow, "code=code-1&state="+query.Get("state"))
+		if w.Code != 302 || w.Header().Get("Location") != "/app/profile?tab=1" {
+			t.Fatalf("expected a redirect back to the protected route but got %d %q", w.Code, w.Header().Get("Location"))
+		}
+
+		var session *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "muxter_oidc" {
+				session = cookie
+			}
+		}
+		if session == nil {
+			t.Fatal("expected a session cookie")
+		}
+
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/app/profile", nil)
+		r.AddCookie(session)
+		mux.ServeHTTP(w, r)
+
+		expected := `{"sub":"user-1","email":"user@example.com","groups":["admins"],"scopes":["openid","email"]}` + "\n"
+		if w.Code != 200 || w.Body.String() != expected {
+			t.Errorf("expected identity %s but got %d %s", expected, w.Code, w.Body.String())
+		}
+
+		t.Run("tampered session", func(t *testing.T) {
+			value, signature, _ := strings.Cut(session.Value, ".")
+			payload, _ := base64.RawURLEncoding.DecodeString(value)
+			forged := strings.Replace(string(payload), "user-1", "user-2", 1)
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/app/profile", nil)
+			r.AddCookie(&http.Cookie{Name: "muxter_oidc", Value: base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + signature})
+			mux.ServeHTTP(w, r)
+
+			if w.Code != 302 {
+				t.Errorf("expected a forged session to be redirected to the provider but got %d", w.Code)
+			}
+		})
+
+		t.Run("logout", func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/auth/logout", nil)
+			r.AddCookie(session)
+			mux.ServeHTTP(w, r)
+
+			if w.Code != 303 {
+				t.Errorf("expected code 303 but got %d", w.Code)
+			}
+			if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "muxter_oidc" || cookies[0].MaxAge != -1 {
+				t.Errorf("expected the session cookie to be cleared but got %v", cookies)
+			}
+		})
+	})
+
+	t.Run("unsafe return", func(t *testing.T) {
+		flow, query := login(t)
+		provider.claims = validClaims(query.Get("nonce"))
+
+		// The flow is re-signed with the key of the mux, as if its return URL had been crafted by the request that
+		// started the login.
+		var state oidcFlow
+		value, _, _ := strings.Cut(flow.Value, ".")
+		payload, _ := base64.RawURLEncoding.DecodeString(value)
+		json.Unmarshal(payload, &state)
+		state.ReturnTo = "//evil.com/app"
+
+		signer := &oidcClient{config: OIDCConfig{SessionKey: []byte(strings.Repeat("k", 32))}}
+		recorder := httptest.NewRecorder()
+		signer.setCookie(recorder, flow.Name, state, flow.Path, time.Minute)
+
+		w := callback(recorder.Result().Cookies()[0], "code=code-1&state="+query.Get("state"))
+		if w.Code != 302 || w.Header().Get("Location") != "/" {
+			t.Errorf("expected a redirect to the root but got %d %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	testcases := []struct {
+		Name         string
+		Claims       func(claims map[string]interface{})
+		Query        func(state string) string
+		NoFlow       bool
+		ExpectedCode int
+	}{
+		{
+			Name:         "missing flow",
+			NoFlow:       true,
+			ExpectedCode: 400,
+		},
+		{
+			Name:         "state mismatch",
+			Query:        func(string) string { return "code=code-1&state=forged" },
+			ExpectedCode: 400,
+		},
+		{
+			Name:         "provider error",
+			Query:        func(state string) string { return "error=access_denied&state=" + state },
+			ExpectedCode: 401,
+		},
+		{
+			Name:         "invalid code",
+			Query:        func(state string) string { return "code=code-2&state=" + state },
+			ExpectedCode: 502,
+		},
+		{
+			Name:         "nonce mismatch",
+			Claims:       func(claims map[string]interface{}) { claims["nonce"] = "replayed" },
+			ExpectedCode: 401,
+		},
+		{
+			Name:         "wrong audience",
+			Claims:       func(claims map[string]interface{}) { claims["aud"] = "other-app" },
+			ExpectedCode: 401,
+		},
+		{
+			Name:         "unauthorized party",
+			Claims:       func(claims map[string]interface{}) { claims["aud"] = []string{"app", "other-app"} },
+			ExpectedCode: 401,
+		},
+		{
+			Name:         "wrong issuer",
+			Claims:       func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+			ExpectedCode: 401,
+		},
+		{
+			Name:         "expired",
+			Claims:       func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+			ExpectedCode: 401,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			flow, query := login(t)
+			provider.claims = validClaims(query.Get("nonce"))
+			if tc.Claims != nil {
+				tc.Claims(provider.claims)
+			}
+
+			callbackQuery := "code=code-1&state=" + query.Get("state")
+			if tc.Query != nil {
+				callbackQuery = tc.Query(query.Get("state"))
+			}
+			if tc.NoFlow {
+				flow = nil
+			}
+
+			w := callback(flow, callbackQuery)
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == "muxter_oidc" {
+					t.Errorf("expected no session to be established")
+				}
+			}
+		})
+	}
+}
+
+func TestOIDCOptional(t *testing.T) {
+	provider := newTestProvider(t)
+
+	mux := New()
+	mux.Use(OIDC(mux, OIDCConfig{
+		Issuer:      provider.URL,
+		ClientID:    "app",
+		RedirectURL: "https://app.example.com/auth/callback",
+		SessionKey:  []byte(strings.Repeat("k", 32)),
+		LoginPath:   "/auth/login",
+		Optional:    true,
+	}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.Identity() != nil {
+			t.Error("expected the request to be anonymous")
+		}
+		w.Write([]byte("welcome"))
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != 200 || w.Body.String() != "welcome" {
+			t.Errorf("expected the anonymous request to be served but got %d %q", w.Code, w.Body.String())
+		}
+	})
+
+	testcases := []struct {
+		Name             string
+		Next             string
+		ExpectedReturnTo string
+	}{
+		{Name: "next", Next: "/books?page=2", ExpectedReturnTo: "/books?page=2"},
+		{Name: "unsafe next", Next: "//evil.com", ExpectedReturnTo: "/"},
+		{Name: "no next", ExpectedReturnTo: "/"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/auth/login?next="+url.QueryEscape(tc.Next), nil)
+			mux.ServeHTTP(w, r)
+
+			if w.Code != 302 || !strings.HasPrefix(w.Header().Get("Location"), provider.URL+"/authorize?") {
+				t.Fatalf("expected a redirect to the provider but got %d %q", w.Code, w.Header().Get("Location"))
+			}
+
+			cookie := w.Result().Cookies()[0]
+			value, _, _ := strings.Cut(cookie.Value, ".")
+			payload, _ := base64.RawURLEncoding.DecodeString(value)
+
+			var flow oidcFlow
+			json.Unmarshal(payload, &flow)
+			if flow.ReturnTo != tc.ExpectedReturnTo {
+				t.Errorf("expected to return to %q but got %q", tc.ExpectedReturnTo, flow.ReturnTo)
+			}
+		})
+	}
+}
+
+func TestOIDCDiscoveryBackoff(t *testing.T) {
+	var discoveries int
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		discoveries++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer issuer.Close()
+
+	mux := New()
+	mux.Use(OIDC(mux, OIDCConfig{
+		Issuer:      issuer.URL,
+		ClientID:    "app",
+		RedirectURL: "https://app.example.com/auth/callback",
+		SessionKey:  []byte(strings.Repeat("k", 32)),
+	}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	for i := 0; i < 3; i++ {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+		mux.ServeHTTP(w, r)
+		if w.Code != 502 {
+			t.Errorf("expected code 502 but got %d", w.Code)
+		}
+	}
+	if discoveries != 1 {
+		t.Errorf("expected the failed discovery not to be retried immediately but got %d discoveries", discoveries)
+	}
+}
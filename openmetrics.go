@@ -0,0 +1,98 @@
+package muxter
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsHandler returns a handler serving the statistics in the OpenMetrics text format, which Prometheus
+// scrapes, without depending on the Prometheus client. Routes are identified by the route label:
+//
+//	muxter_requests_total{route="/books/:id"} 3
+//	muxter_errors_total{route="/books/:id"} 1
+//	muxter_request_duration_seconds_bucket{route="/books/:id",le="0.005"} 2
+//
+//	mux.Handle("/metrics", stats.OpenMetricsHandler())
+func (s *RouteStats) OpenMetricsHandler() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		routes := s.Routes()
+		bw := bufio.NewWriter(w)
+
+		bw.WriteString("# TYPE muxter_requests counter\n# HELP muxter_requests Requests served by the route.\n")
+		for _, route := range routes {
+			writeSample(bw, "muxter_requests_total", route.Pattern, "", formatUint(route.Requests))
+		}
+
+		bw.WriteString("# TYPE muxter_errors counter\n# HELP muxter_errors Requests answered with a 5xx status.\n")
+		for _, route := range routes {
+			writeSample(bw, "muxter_errors_total", route.Pattern, "", formatUint(route.Errors))
+		}
+
+		bw.WriteString("# TYPE muxter_aborted_requests counter\n# HELP muxter_aborted_requests Requests abandoned by their clients.\n")
+		for _, route := range routes {
+			writeSample(bw, "muxter_aborted_requests_total", route.Pattern, "", formatUint(route.Aborted))
+		}
+
+		bw.WriteString("# TYPE muxter_request_duration_seconds histogram\n")
+		bw.WriteString("# UNIT muxter_request_duration_seconds seconds\n")
+		bw.WriteString("# HELP muxter_request_duration_seconds Latency of the requests served by the route.\n")
+		for _, route := range routes {
+			for _, bucket := range route.Latency {
+				writeSample(bw, "muxter_request_duration_seconds_bucket", route.Pattern, formatFloat(bucket.UpperBound.Seconds()), formatUint(bucket.Count))
+			}
+			writeSample(bw, "muxter_request_duration_seconds_bucket", route.Pattern, "+Inf", formatUint(route.Requests))
+			writeSample(bw, "muxter_request_duration_seconds_sum", route.Pattern, "", formatFloat(route.LatencySum.Seconds()))
+			writeSample(bw, "muxter_request_duration_seconds_count", route.Pattern, "", formatUint(route.Requests))
+		}
+
+		bw.WriteString("# TYPE muxter_response_write_seconds counter\n")
+		bw.WriteString("# UNIT muxter_response_write_seconds seconds\n")
+		bw.WriteString("# HELP muxter_response_write_seconds Time spent writing responses to clients.\n")
+		for _, route := range routes {
+			writeSample(bw, "muxter_response_write_seconds_total", route.Pattern, "", formatFloat(route.WriteTime.Seconds()))
+		}
+
+		bw.WriteString("# TYPE muxter_stalled_writes counter\n")
+		bw.WriteString("# HELP muxter_stalled_writes Writes of responses stalled by slow clients.\n")
+		for _, route := range routes {
+			writeSample(bw, "muxter_stalled_writes_total", route.Pattern, "", formatUint(route.StalledWrites))
+		}
+
+		bw.WriteString("# EOF\n")
+		bw.Flush()
+	})
+}
+
+func writeSample(w *bufio.Writer, name, route, le, value string) {
+	w.WriteString(name)
+	w.WriteString(`{route="`)
+	w.WriteString(escapeLabelValue(route))
+	if le != "" {
+		w.WriteString(`",le="`)
+		w.WriteString(le)
+	}
+	w.WriteString(`"} `)
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,66 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenMetricsHandler(t *testing.T) {
+	stats := NewRouteStats(RouteStatsOptions{Buckets: []time.Duration{5 * time.Millisecond, time.Second}})
+	stats.record("/books/:id", 200, time.Millisecond)
+	stats.record("/books/:id", 500, 2*time.Second)
+	stats.record(`/odd/"quoted"`, 200, 500*time.Millisecond)
+	stats.record(`/odd/"quoted"`, StatusClientClosedRequest, 2*time.Second)
+
+	mux := New()
+	mux.Handle("/metrics", stats.OpenMetricsHandler())
+
+	w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil)
+	mux.ServeHTTP(w, r)
+
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/openmetrics-text; version=1.0.0; charset=utf-8" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+
+	expected := `# TYPE muxter_requests counter
+# HELP muxter_requests Requests served by the route.
+muxter_requests_total{route="/books/:id"} 2
+muxter_requests_total{route="/odd/\"quoted\""} 2
+# TYPE muxter_errors counter
+# HELP muxter_errors Requests answered with a 5xx status.
+muxter_errors_total{route="/books/:id"} 1
+muxter_errors_total{route="/odd/\"quoted\""} 0
+# TYPE muxter_aborted_requests counter
+# HELP muxter_aborted_requests Requests abandoned by their clients.
+muxter_aborted_requests_total{route="/books/:id"} 0
+muxter_aborted_requests_total{route="/odd/\"quoted\""} 1
+# TYPE muxter_request_duration_seconds histogram
+# UNIT muxter_request_duration_seconds seconds
+# HELP muxter_request_duration_seconds Latency of the requests served by the route.
+muxter_request_duration_seconds_bucket{route="/books/:id",le="0.005"} 1
+muxter_request_duration_seconds_bucket{route="/books/:id",le="1"} 1
+muxter_request_duration_seconds_bucket{route="/books/:id",le="+Inf"} 2
+muxter_request_duration_seconds_sum{route="/books/:id"} 2.001
+muxter_request_duration_seconds_count{route="/books/:id"} 2
+muxter_request_duration_seconds_bucket{route="/odd/\"quoted\"",le="0.005"} 0
+muxter_request_duration_seconds_bucket{route="/odd/\"quoted\"",le="1"} 1
+muxter_request_duration_seconds_bucket{route="/odd/\"quoted\"",le="+Inf"} 2
+muxter_request_duration_seconds_sum{route="/odd/\"quoted\""} 2.5
+muxter_request_duration_seconds_count{route="/odd/\"quoted\""} 2
+# TYPE muxter_response_write_seconds counter
+# UNIT muxter_response_write_seconds seconds
+# HELP muxter_response_write_seconds Time spent writing responses to clients.
+muxter_response_write_seconds_total{route="/books/:id"} 0
+muxter_response_write_seconds_total{route="/odd/\"quoted\""} 0
+# TYPE muxter_stalled_writes counter
+# HELP muxter_stalled_writes Writes of responses stalled by slow clients.
+muxter_stalled_writes_total{route="/books/:id"} 0
+muxter_stalled_writes_total{route="/odd/\"quoted\""} 0
+# EOF
+`
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Errorf("expected:\n%s\nbut got %d:\n%s", expected, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package muxter
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// OrderViolation is a path routed differently by the mux and by the same routes registered in another order.
+type OrderViolation struct {
+	// Order is the registration order of the patterns that routes the path differently.
+	Order []string `json:"order"`
+	// RoutingDifference compares the route of the path in the mux, A, with its route in the reordered mux, B.
+	RoutingDifference
+	// Problem is set instead when the routes could not all be registered in the order.
+	Problem string `json:"problem,omitempty"`
+}
+
+func (v OrderViolation) String() string {
+	if v.Problem != "" {
+		return fmt.Sprintf("registering %s: %s", strings.Join(v.Order, ", "), v.Problem)
+	}
+	return fmt.Sprintf("%s registered as %s", v.RoutingDifference, strings.Join(v.Order, ", "))
+}
+
+// VerifyRegistrationOrder registers the routes of the mux in other orders, reversed and shuffled, and reports the
+// paths that they route differently. Matching must only depend on the set of routes and never on the order they
+// were registered in; a violation is a bug in the route tree. Registrations of the same pattern with matchers, such
+// as MatchHeader, are kept in their order since it is their documented precedence.
+//
+// Paths are the sample paths, which may be absolute URLs for host routes, and paths derived from the patterns of
+// the routes: the patterns with their params filled in, with and without a trailing slash, and their parent paths.
+// Routes with host patterns or regexp params that do not match "x" are only checked with the sample paths. It is
+// meant to be run as a test:
+//
+//	func TestRegistrationOrder(t *testing.T) {
+//		for _, violation := range newMux().VerifyRegistrationOrder(nil) {
+//			t.Error(violation)
+//		}
+//	}
+func (m *Mux) VerifyRegistrationOrder(samplePaths []string) []OrderViolation {
+	registrations := m.root.registrations()
+
+	paths := append(derivedPaths(registrations), samplePaths...)
+
+	orders := [][]*value{reversed(registrations)}
+	for seed := int64(1); seed <= 8 && len(registrations) > 2; seed++ {
+		order := append([]*value{}, registrations...)
+		rand.New(rand.NewSource(seed)).Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		orders = append(orders, order)
+	}
+
+	var violations []OrderViolation
+reorder:
+	for _, order := range orders {
+		reordered := *m
+		reordered.root = &node{}
+
+		patterns := make([]string, len(order))
+		for i, v := range order {
+			patterns[i] = v.pattern
+			if err := reordered.root.Insert(routeKey(v.pattern), v); err != nil {
+				violations = append(violations, OrderViolation{Order: patterns[:i+1], Problem: err.Error()})
+				continue reorder
+			}
+		}
+
+		for _, diff := range CompareRouting(m, &reordered, paths) {
+			violations = append(violations, OrderViolation{Order: patterns, RoutingDifference: diff})
+		}
+	}
+
+	return violations
+}
+
+// derivedPaths returns sample paths for the patterns of the values.
+func derivedPaths(values []*value) []string {
+	seen := map[string]bool{}
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, v := range values {
+		if isHostPattern(v.pattern) {
+			continue
+		}
+		path, err := expandPattern(v.pattern, func(key string) (string, bool) { return "x", true })
+		if err != nil {
+			continue
+		}
+
+		add(path)
+		if strings.HasSuffix(path, "/") {
+			add(strings.TrimSuffix(path, "/"))
+		} else {
+			add(path + "/")
+		}
+		for i := 1; i < len(path); i++ {
+			if path[i] == '/' {
+				add(path[:i])
+				add(path[:i+1])
+			}
+		}
+	}
+
+	return paths
+}
+
+func reversed(values []*value) []*value {
+	result := make([]*value, len(values))
+	for i, v := range values {
+		result[len(values)-1-i] = v
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package muxter
+
+import (
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRegistrationOrder(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New()
+	mux.HandleFunc("/books/:id", noop)
+	mux.HandleFunc("/books/latest", noop)
+	mux.HandleFunc("/books/:id/#format:json|xml", noop)
+	mux.HandleFunc("/docs/", noop)
+	mux.HandleFunc("/docsify", noop)
+	mux.HandleFunc("/files/*path", noop)
+	mux.HandleFunc("/orders", noop, MatchHeader("X-Partner", ""))
+	mux.HandleFunc("/orders", noop)
+	mux.HandleFunc("//:tenant.example.com/", noop)
+
+	if violations := mux.VerifyRegistrationOrder([]string{"http://acme.example.com/books/1", "/books/1/json"}); len(violations) != 0 {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+
+	// Corrupt the tree such that it no longer matches the registered pattern.
+	mux.root.Children[0].Children[0].Wildcard.Key = "key"
+
+	violations := mux.VerifyRegistrationOrder(nil)
+	if len(violations) == 0 {
+		t.Fatalf("expected violations of corrupted tree")
+	}
+	if violation := violations[0]; violation.Path != "/books/x" || violation.A.Params["key"] != "x" || violation.B.Params["id"] != "x" {
+		t.Errorf("unexpected violation: %+v", violation)
+	}
+	if !strings.HasPrefix(violations[0].String(), "/books/x: /books/:id != /books/:id registered as ") {
+		t.Errorf("unexpected violation string: %s", violations[0])
+	}
+}
+
+// TestRegistrationOrderIndependence registers random route sets and verifies that matching does not depend on
+// registration order.
+func TestRegistrationOrderIndependence(t *testing.T) {
+	segments := []string{"a", "ab", "abc", "b", "a.json", "", ":id", "*rest", "#n:[0-9]+", "a:id", "ab:id", "a*rest", "ab#n:[0-9]+", "b#n:[a-z]+"}
+	words := []string{"", "a", "ab", "abc", "abd", "b", "1", "x", "a.json"}
+
+	var samples []string
+	for _, first := range words {
+		for _, second := range words {
+			samples = append(samples, "/"+first+"/"+second, "/"+first+"/"+second+"/", "/"+first+second)
+		}
+	}
+
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	for seed := int64(0); seed < 300; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+
+		mux := New()
+		for i := 2 + rnd.Intn(6); i > 0; i-- {
+			var pattern string
+			for depth := 1 + rnd.Intn(3); depth > 0; depth-- {
+				pattern += "/" + segments[rnd.Intn(len(segments))]
+			}
+			if rnd.Intn(3) == 0 && !strings.HasSuffix(pattern, "/") {
+				pattern += "/"
+			}
+			func() {
+				// Conflicting patterns are skipped.
+				defer func() { recover() }()
+				mux.HandleFunc(pattern, noop)
+			}()
+		}
+
+		for _, violation := range mux.VerifyRegistrationOrder(samples) {
+			t.Errorf("seed %d: %s", seed, violation)
+		}
+	}
+}
+
+func TestFailedRegistrationDoesNotChangeMatching(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux := New()
+	mux.HandleFunc("/", noop)
+	mux.HandleFunc("/:id/x", noop)
+
+	before, stats := mux.Match("/abc/"), mux.TreeStats()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected registration of segments after a catchall to panic")
+			}
+		}()
+		mux.HandleFunc("/abc/:key/*rest/", noop)
+	}()
+	func() {
+		defer func() { recover() }()
+		mux.HandleFunc("/:key/y", noop)
+	}()
+
+	if after := mux.Match("/abc/"); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected failed registrations not to change matching but got %v rather than %v", after, before)
+	}
+	if after := mux.TreeStats(); !reflect.DeepEqual(after, stats) {
+		t.Errorf("expected failed registrations to leave no nodes behind but got %+v rather than %+v", after, stats)
+	}
+}
@@ -0,0 +1,134 @@
+package muxter
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page holds the pagination parameters parsed from a request by Paginate.
+type Page struct {
+	// Number is the 1-based page number read from the "page" query parameter.
+	Number int
+	// Limit is the page size read from the "limit" query parameter, bounded by the configured maximum.
+	Limit int
+	// Cursor is the opaque value of the "cursor" query parameter, if any.
+	Cursor string
+
+	query url.Values
+}
+
+// Offset returns the number of items preceding the page.
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.Limit
+}
+
+type paginateOptions struct {
+	defaultLimit int
+	maxLimit     int
+}
+
+type PaginateOption func(*paginateOptions)
+
+// PageLimits sets the page size used when the request does not specify a limit, and the maximum page size a
+// request may ask for. Defaults are 20 and 100 respectively.
+func PageLimits(defaultLimit, maxLimit int) PaginateOption {
+	return func(po *paginateOptions) {
+		po.defaultLimit = defaultLimit
+		po.maxLimit = maxLimit
+	}
+}
+
+// Paginate parses the page, limit and cursor query parameters of the request. Missing or invalid values fall back
+// to page 1 and the default limit, and limits are clamped between 1 and the maximum limit.
+func Paginate(r *http.Request, opts ...PaginateOption) Page {
+	options := paginateOptions{defaultLimit: 20, maxLimit: 100}
+	for _, apply := range opts {
+		apply(&options)
+	}
+
+	query := r.URL.Query()
+
+	page := Page{
+		Number: 1,
+		Limit:  options.defaultLimit,
+		Cursor: query.Get("cursor"),
+		query:  query,
+	}
+
+	if n, err := strconv.Atoi(query.Get("page")); err == nil && n > 0 {
+		page.Number = n
+	}
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil {
+		page.Limit = n
+	}
+	if page.Limit < 1 {
+		page.Limit = 1
+	}
+	if page.Limit > options.maxLimit {
+		page.Limit = options.maxLimit
+	}
+
+	return page
+}
+
+// WriteLinkHeaders sets RFC 8288 Link headers with first, prev, next and last relations for page given the total
+// number of items. Links are generated from the matched route pattern and params, preserving the request's other
+// query parameters, and are absolute when the mux was configured with a BaseURL. If total is negative it is
+// considered unknown and the last relation is omitted. A page number or limit below 1 is treated as 1, as done by
+// Paginate.
+func WriteLinkHeaders(w http.ResponseWriter, c Context, page Page, total int) error {
+	path, err := c.reversePath()
+	if err != nil {
+		return err
+	}
+
+	if page.Number < 1 {
+		page.Number = 1
+	}
+	if page.Limit < 1 {
+		page.Limit = 1
+	}
+
+	var origin string
+	if base := c.extra().baseURL; base != nil {
+		origin = base.String()
+	}
+
+	last := -1
+	if total >= 0 {
+		last = (total + page.Limit - 1) / page.Limit
+		if last < 1 {
+			last = 1
+		}
+	}
+
+	link := func(number int, rel string) string {
+		query := url.Values{}
+		for key, values := range page.query {
+			query[key] = values
+		}
+		query.Set("page", strconv.Itoa(number))
+		query.Set("limit", strconv.Itoa(page.Limit))
+		query.Del("cursor")
+
+		u := url.URL{Path: path, RawQuery: query.Encode()}
+		return "<" + origin + u.String() + `>; rel="` + rel + `"`
+	}
+
+	links := []string{link(1, "first")}
+	if page.Number > 1 {
+		links = append(links, link(page.Number-1, "prev"))
+	}
+	if last == -1 || page.Number < last {
+		links = append(links, link(page.Number+1, "next"))
+	}
+	if last != -1 {
+		links = append(links, link(last, "last"))
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		URL      string
+		Options  []PaginateOption
+		Expected Page
+	}{
+		{
+			Name:     "defaults",
+			URL:      "/books",
+			Expected: Page{Number: 1, Limit: 20},
+		},
+		{
+			Name:     "explicit values",
+			URL:      "/books?page=3&limit=50&cursor=abc",
+			Expected: Page{Number: 3, Limit: 50, Cursor: "abc"},
+		},
+		{
+			Name:     "invalid values",
+			URL:      "/books?page=-2&limit=zero",
+			Expected: Page{Number: 1, Limit: 20},
+		},
+		{
+			Name:     "limit bounds",
+			URL:      "/books?limit=1000",
+			Options:  []PaginateOption{PageLimits(10, 25)},
+			Expected: Page{Number: 1, Limit: 25},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			page := Paginate(httptest.NewRequest("GET", tc.URL, nil), tc.Options...)
+			if page.Number != tc.Expected.Number || page.Limit != tc.Expected.Limit || page.Cursor != tc.Expected.Cursor {
+				t.Errorf("expected page %+v but got %+v", tc.Expected, page)
+			}
+		})
+	}
+
+	if offset := (Page{Number: 3, Limit: 10}).Offset(); offset != 20 {
+		t.Errorf("expected offset to be 20 but got %d", offset)
+	}
+}
+
+func TestWriteLinkHeaders(t *testing.T) {
+	mux := New()
+
+	var total int
+	mux.HandleFunc("/authors/:author/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if err := WriteLinkHeaders(w, c, Paginate(r), total); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	testcases := []struct {
+		Name     string
+		URL      string
+		Total    int
+		Expected string
+	}{
+		{
+			Name:  "middle page",
+			URL:   "/authors/vonnegut/books?page=2&limit=10&sort=title",
+			Total: 35,
+			Expected: `</authors/vonnegut/books?limit=10&page=1&sort=title>; rel="first", ` +
+				`</authors/vonnegut/books?limit=10&page=1&sort=title>; rel="prev", ` +
+				`</authors/vonnegut/books?limit=10&page=3&sort=title>; rel="next", ` +
+				`</authors/vonnegut/books?limit=10&page=4&sort=title>; rel="last"`,
+		},
+		{
+			Name:  "last page",
+			URL:   "/authors/vonnegut/books?page=4&limit=10",
+			Total: 35,
+			Expected: `</authors/vonnegut/books?limit=10&page=1>; rel="first", ` +
+				`</authors/vonnegut/books?limit=10&page=3>; rel="prev", ` +
+				`</authors/vonnegut/books?limit=10&page=4>; rel="last"`,
+		},
+		{
+			Name:  "unknown total",
+			URL:   "/authors/vonnegut/books?limit=10",
+			Total: -1,
+			Expected: `</authors/vonnegut/books?limit=10&page=1>; rel="first", ` +
+				`</authors/vonnegut/books?limit=10&page=2>; rel="next"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			total = tc.Total
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.URL, nil))
+
+			if link := w.Header().Get("Link"); link != tc.Expected {
+				t.Errorf("expected link header:\n%s\nbut got:\n%s", tc.Expected, link)
+			}
+		})
+	}
+}
+
+func TestWriteLinkHeadersBaseURL(t *testing.T) {
+	mux := New(BaseURL("https://api.example.com/v1"))
+	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {
+		// A zero page must not divide by a zero limit.
+		if err := WriteLinkHeaders(w, c, Page{}, 2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/books", nil))
+
+	expected := `<https://api.example.com/v1/books?limit=1&page=1>; rel="first", ` +
+		`<https://api.example.com/v1/books?limit=1&page=2>; rel="next", ` +
+		`<https://api.example.com/v1/books?limit=1&page=2>; rel="last"`
+	if link := w.Header().Get("Link"); link != expected {
+		t.Errorf("expected link header:\n%s\nbut got:\n%s", expected, link)
+	}
+}
@@ -0,0 +1,13 @@
+// Command paramcheck runs the paramcheck analyzer.
+//
+//	go run github.com/davidmdm/muxter/paramcheck/cmd/paramcheck ./...
+package main
+
+import (
+	"github.com/davidmdm/muxter/paramcheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(paramcheck.Analyzer)
+}
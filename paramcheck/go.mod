@@ -0,0 +1,16 @@
+module github.com/davidmdm/muxter/paramcheck
+
+go 1.22.0
+
+require (
+	github.com/davidmdm/muxter v0.0.0-00010101000000-000000000000
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+)
+
+// The analyzer shares the pattern grammar of the mux, such that it parses patterns exactly as the mux does.
+replace github.com/davidmdm/muxter => ../
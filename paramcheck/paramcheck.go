@@ -0,0 +1,205 @@
+// Package paramcheck defines an analyzer that reports muxter param lookups whose keys do not appear in the
+// pattern of the route the handler is registered on.
+//
+// Given:
+//
+//	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+//		id := c.Param("idd")
+//	})
+//
+// paramcheck reports that param "idd" is not part of pattern "/users/:id", since the lookup silently returns the
+// empty string. Handlers are checked when they are function literals or functions declared in the same package.
+// Routes of nested muxes only see their own pattern, so params captured by a parent mux's pattern are reported;
+// such lookups can be suppressed with a "//paramcheck:ignore" comment on the same line.
+package paramcheck
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const muxterPath = "github.com/davidmdm/muxter"
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "paramcheck",
+	Doc:      "report muxter param lookups with keys that are not part of the route pattern",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var registrationMethods = map[string]bool{
+	"Handle":         true,
+	"HandleFunc":     true,
+	"StandardHandle": true,
+	"Get":            true,
+	"GetFunc":        true,
+	"Head":           true,
+	"HeadFunc":       true,
+	"Post":           true,
+	"PostFunc":       true,
+	"Put":            true,
+	"PutFunc":        true,
+	"Patch":          true,
+	"PatchFunc":      true,
+	"Delete":         true,
+	"DeleteFunc":     true,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	decls := map[*types.Func]*ast.FuncDecl{}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				if obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func); ok {
+					decls[obj] = fn
+				}
+			}
+		}
+	}
+
+	ignored := ignoredLines(pass)
+
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !registrationMethods[sel.Sel.Name] || len(call.Args) < 2 {
+			return
+		}
+		if !isMuxterType(pass.TypesInfo.TypeOf(sel.X), "Mux") {
+			return
+		}
+
+		pattern, ok := constantString(pass, call.Args[0])
+		if !ok {
+			return
+		}
+
+		handler := ast.Unparen(call.Args[1])
+
+		// Unwrap conversions such as http.HandlerFunc(fn) or muxter.HandlerFunc(fn).
+		for {
+			conversion, ok := handler.(*ast.CallExpr)
+			if !ok || len(conversion.Args) != 1 || !pass.TypesInfo.Types[conversion.Fun].IsType() {
+				break
+			}
+			handler = ast.Unparen(conversion.Args[0])
+		}
+
+		var body *ast.BlockStmt
+		switch handler := handler.(type) {
+		case *ast.FuncLit:
+			body = handler.Body
+		case *ast.Ident:
+			if fn, ok := pass.TypesInfo.Uses[handler].(*types.Func); ok && decls[fn] != nil {
+				body = decls[fn].Body
+			}
+		}
+		if body == nil {
+			return
+		}
+
+		keys := patternParams(pattern)
+
+		ast.Inspect(body, func(n ast.Node) bool {
+			lookup, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			key, arg, ok := paramLookup(pass, lookup)
+			if !ok || keys[key] {
+				return true
+			}
+			position := pass.Fset.Position(arg.Pos())
+			if ignored[position.Filename][position.Line] {
+				return true
+			}
+			pass.Reportf(arg.Pos(), "param %q is not part of pattern %q", key, pattern)
+			return true
+		})
+	})
+
+	return nil, nil
+}
+
+// paramLookup reports whether call is c.Param(key) on a muxter.Context or muxter.Param(r, key), returning the key.
+func paramLookup(pass *analysis.Pass, call *ast.CallExpr) (string, ast.Expr, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Param" {
+		return "", nil, false
+	}
+
+	if isMuxterType(pass.TypesInfo.TypeOf(sel.X), "Context") && len(call.Args) == 1 {
+		key, ok := constantString(pass, call.Args[0])
+		return key, call.Args[0], ok
+	}
+
+	if fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == muxterPath && len(call.Args) == 2 {
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() == nil {
+			key, ok := constantString(pass, call.Args[1])
+			return key, call.Args[1], ok
+		}
+	}
+
+	return "", nil, false
+}
+
+func isMuxterType(t types.Type, name string) bool {
+	if t == nil {
+		return false
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == muxterPath && obj.Name() == name
+}
+
+func constantString(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// patternParams returns the set of param keys captured by a muxter pattern.
+func patternParams(pattern string) map[string]bool {
+	keys := map[string]bool{}
+	for _, key := range syntax.Params(pattern) {
+		keys[key] = true
+	}
+	return keys
+}
+
+func ignoredLines(pass *analysis.Pass) map[string]map[int]bool {
+	lines := map[string]map[int]bool{}
+	for _, file := range pass.Files {
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if !strings.HasPrefix(comment.Text, "//paramcheck:ignore") {
+					continue
+				}
+				position := pass.Fset.Position(comment.Pos())
+				if lines[position.Filename] == nil {
+					lines[position.Filename] = map[int]bool{}
+				}
+				lines[position.Filename][position.Line] = true
+			}
+		}
+	}
+	return lines
+}
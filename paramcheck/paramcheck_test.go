@@ -0,0 +1,12 @@
+package paramcheck_test
+
+import (
+	"testing"
+
+	"github.com/davidmdm/muxter/paramcheck"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), paramcheck.Analyzer, "a")
+}
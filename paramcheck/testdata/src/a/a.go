@@ -0,0 +1,56 @@
+package a
+
+import (
+	"net/http"
+
+	"github.com/davidmdm/muxter"
+)
+
+const bookPattern = "/books/:id"
+
+func routes() {
+	mux := muxter.New()
+
+	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		_ = c.Param("id")
+		_ = c.Param("idd") // want `param "idd" is not part of pattern "/users/:id"`
+	})
+
+	mux.GetFunc(bookPattern, getBook)
+
+	mux.HandleFunc(`/assets/#dir:folder-\d+/*file`, func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		_ = c.Param("dir")
+		_ = c.Param("file")
+		_ = c.Param("folder") // want `param "folder" is not part of pattern "/assets/#dir:folder-\\\\d\+/\*file"`
+	})
+
+	mux.StandardHandle("/std/:name", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = muxter.Param(r, "name")
+		_ = muxter.Param(r, "nam") // want `param "nam" is not part of pattern "/std/:name"`
+	}))
+
+	mux.HandleFunc("/nested/:id", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		_ = c.Param("tenant") //paramcheck:ignore
+	})
+
+	mux.HandleFunc("//:tenant.#region:eu|us.example.com/users/:id", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		_ = c.Param("tenant")
+		_ = c.Param("region")
+		_ = c.Param("id")
+		_ = c.Param("example") // want `param "example" is not part of pattern "//:tenant\.#region:eu\|us\.example\.com/users/:id"`
+	})
+
+	mux.HandleFunc(`/escaped/\:literal/:id`, func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		_ = c.Param("id")
+		_ = c.Param("literal") // want `param "literal" is not part of pattern "/escaped/\\\\:literal/:id"`
+	})
+
+	key := "dynamic"
+	mux.HandleFunc("/dynamic/:id", func(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+		_ = c.Param(key)
+	})
+}
+
+func getBook(w http.ResponseWriter, r *http.Request, c muxter.Context) {
+	_ = c.Param("book") // want `param "book" is not part of pattern "/books/:id"`
+}
@@ -0,0 +1,26 @@
+// Package muxter is a minimal stub of the muxter API used by the paramcheck tests.
+package muxter
+
+import "net/http"
+
+type Context struct{}
+
+func (c Context) Param(key string) string { return "" }
+
+type Handler interface {
+	ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context)
+}
+
+type HandlerFunc func(w http.ResponseWriter, r *http.Request, c Context)
+
+func (fn HandlerFunc) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) { fn(w, r, c) }
+
+type Mux struct{}
+
+func New() *Mux { return &Mux{} }
+
+func (m *Mux) Handle(pattern string, h Handler)              {}
+func (m *Mux) HandleFunc(pattern string, fn HandlerFunc)     {}
+func (m *Mux) GetFunc(pattern string, fn HandlerFunc)        {}
+func (m *Mux) StandardHandle(pattern string, h http.Handler) {}
+func Param(r *http.Request, key string) string               { return "" }
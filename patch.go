@@ -0,0 +1,629 @@
+package muxter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the media type of RFC 7386 JSON Merge Patch documents.
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type of RFC 6902 JSON Patch documents.
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+// PatchError is returned when a patch document cannot be parsed or applied. Status is the HTTP status code
+// the error should be reported with: 415 for unsupported patch formats, 400 for malformed patch documents,
+// 422 for patches that are well formed but cannot be applied to the target or fail validation and 500 for
+// targets that cannot be patched at all.
+type PatchError struct {
+	Status int
+	Op     string
+	Path   string
+	Err    error
+}
+
+func (err *PatchError) Error() string {
+	if err.Op == "" {
+		return fmt.Sprintf("patch: %v", err.Err)
+	}
+	return fmt.Sprintf("patch: %s %q: %v", err.Op, err.Path, err.Err)
+}
+
+func (err *PatchError) Unwrap() error { return err.Err }
+
+// ApplyPatch reads the patch document from the request body and applies it to dst, which must be a pointer.
+// The format of the patch is chosen from the request Content-Type: application/merge-patch+json or
+// application/json-patch+json. The patched document is decoded into dst rejecting unknown fields, and if dst
+// implements interface{ Validate() error } it is validated. Fields that are not part of the JSON document, such as
+// unexported fields and fields tagged json:"-", keep their value. Any failure is reported as a *PatchError.
+func ApplyPatch(r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &PatchError{
+			Status: http.StatusInternalServerError,
+			Err:    fmt.Errorf("muxter: ApplyPatch requires a non-nil pointer but got %T", dst),
+		}
+	}
+
+	mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediatype {
+	case MergePatchContentType:
+		apply = MergePatch
+	case JSONPatchContentType:
+		apply = JSONPatch
+	default:
+		return &PatchError{
+			Status: http.StatusUnsupportedMediaType,
+			Err:    fmt.Errorf("unsupported patch format %q", mediatype),
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		return &PatchError{Status: http.StatusBadRequest, Err: err}
+	}
+
+	doc, err := json.Marshal(dst)
+	if err != nil {
+		return &PatchError{Status: http.StatusInternalServerError, Err: fmt.Errorf("invalid target: %w", err)}
+	}
+
+	patched, err := apply(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	// Decoding leaves the members missing from the patched document untouched, so the members the patch removed are
+	// reset first.
+	resetJSONFields(rv.Elem())
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return &PatchError{Status: http.StatusUnprocessableEntity, Err: err}
+	}
+
+	if validator, ok := dst.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return &PatchError{Status: http.StatusUnprocessableEntity, Err: err}
+		}
+	}
+
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// resetJSONFields zeroes the parts of v that are decoded from JSON, leaving unexported fields and fields tagged
+// json:"-" as they are. Values decoding themselves are zeroed as a whole.
+func resetJSONFields(v reflect.Value) {
+	zero := func() {
+		if v.CanSet() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+	if ptr := reflect.PointerTo(v.Type()); ptr.Implements(jsonUnmarshalerType) || ptr.Implements(textUnmarshalerType) {
+		zero()
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			resetJSONFields(v.Elem())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			// The exported fields of unexported embedded structs are promoted and decoded all the same.
+			promoted := field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct
+			if !field.IsExported() && !promoted {
+				continue
+			}
+			resetJSONFields(v.Field(i))
+		}
+	default:
+		zero()
+	}
+}
+
+// MergePatch applies an RFC 7386 JSON Merge Patch to doc and returns the resulting document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := unmarshalJSON(doc, &target); err != nil {
+			return nil, &PatchError{Status: http.StatusUnprocessableEntity, Err: fmt.Errorf("invalid target document: %w", err)}
+		}
+	}
+
+	var p interface{}
+	if err := unmarshalJSON(patch, &p); err != nil {
+		return nil, &PatchError{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid merge patch: %w", err)}
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch applies an RFC 6902 JSON Patch to doc and returns the resulting document.
+// Operations are applied in order and the patch is atomic: if any operation fails an error is returned.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, &PatchError{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid json patch: %w", err)}
+	}
+
+	var target interface{}
+	if err := unmarshalJSON(doc, &target); err != nil {
+		return nil, &PatchError{Status: http.StatusUnprocessableEntity, Err: fmt.Errorf("invalid target document: %w", err)}
+	}
+
+	for _, op := range ops {
+		var err error
+		target, err = op.apply(target)
+		if err != nil {
+			path := ""
+			if op.Path != nil {
+				path = *op.Path
+			}
+			var perr *PatchError
+			if errors.As(err, &perr) {
+				perr.Op, perr.Path = op.Op, path
+				return nil, perr
+			}
+			return nil, &PatchError{Status: http.StatusUnprocessableEntity, Op: op.Op, Path: path, Err: err}
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// JSONDiff returns an RFC 6902 JSON Patch transforming the document from into the document to, such that applying
+// it with JSONPatch to from yields to. Objects are diffed member by member and arrays element by element when their
+// lengths allow it, otherwise values are replaced.
+func JSONDiff(from, to []byte) ([]byte, error) {
+	var a, b interface{}
+	if err := unmarshalJSON(from, &a); err != nil {
+		return nil, fmt.Errorf("invalid source document: %w", err)
+	}
+	if err := unmarshalJSON(to, &b); err != nil {
+		return nil, fmt.Errorf("invalid target document: %w", err)
+	}
+
+	ops := []diffOperation{}
+	if err := diffJSON(&ops, "", a, b); err != nil {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
+
+type diffOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+func diffJSON(ops *[]diffOperation, path string, a, b interface{}) error {
+	if jsonEqual(a, b) {
+		return nil
+	}
+
+	add := func(op, path string, value interface{}) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		raw := json.RawMessage(data)
+		*ops = append(*ops, diffOperation{Op: op, Path: path, Value: &raw})
+		return nil
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for key := range av {
+			keys = append(keys, key)
+		}
+		for key := range bv {
+			if _, ok := av[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			member := path + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+			from, inA := av[key]
+			to, inB := bv[key]
+			switch {
+			case !inB:
+				*ops = append(*ops, diffOperation{Op: "remove", Path: member})
+			case !inA:
+				if err := add("add", member, to); err != nil {
+					return err
+				}
+			default:
+				if err := diffJSON(ops, member, from, to); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(bv) < len(av) {
+			break
+		}
+		for i := range av {
+			if err := diffJSON(ops, path+"/"+strconv.Itoa(i), av[i], bv[i]); err != nil {
+				return err
+			}
+		}
+		for _, elem := range bv[len(av):] {
+			if err := add("add", path+"/-", elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return add("replace", path, b)
+}
+
+func (op jsonPatchOperation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, &PatchError{Status: http.StatusBadRequest, Err: errors.New(`missing "path" member`)}
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, &PatchError{Status: http.StatusBadRequest, Err: err}
+	}
+
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, &PatchError{Status: http.StatusBadRequest, Err: errors.New(`missing "value" member`)}
+		}
+		var v interface{}
+		if err := unmarshalJSON(op.Value, &v); err != nil {
+			return nil, &PatchError{Status: http.StatusBadRequest, Err: err}
+		}
+		return v, nil
+	}
+
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, &PatchError{Status: http.StatusBadRequest, Err: errors.New(`missing "from" member`)}
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, &PatchError{Status: http.StatusBadRequest, Err: err}
+		}
+		return from, nil
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "remove":
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "move":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(src) && reflect.DeepEqual(src, path[:len(src)]) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, v, err := pointerRemove(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "copy":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopyJSON(v))
+	case "test":
+		expected, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(expected, actual) {
+			return nil, errors.New("test failed: value does not match")
+		}
+		return doc, nil
+	default:
+		return nil, &PatchError{Status: http.StatusBadRequest, Err: fmt.Errorf("unknown operation %q", op.Op)}
+	}
+}
+
+func unmarshalJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after top-level value")
+	}
+	return nil
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid json pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if idx > max {
+		return 0, fmt.Errorf("array index %d out of bounds", idx)
+	}
+	return idx, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			doc = value
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("cannot traverse into %q", token)
+		}
+	}
+	return doc, nil
+}
+
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[idx+1:], node[idx:])
+		node[idx] = value
+		return pointerSet(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("cannot add member %q to a scalar value", last)
+	}
+}
+
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q not found", last)
+		}
+		delete(node, last)
+		return doc, value, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[idx]
+		node = append(node[:idx:idx], node[idx+1:]...)
+		doc, err = pointerSet(doc, path[:len(path)-1], node)
+		return doc, value, err
+	default:
+		return nil, nil, fmt.Errorf("cannot remove member %q from a scalar value", last)
+	}
+}
+
+// pointerSet replaces the value at path. Used to write back arrays whose backing slices may have changed.
+func pointerSet(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = value
+	}
+	return doc, nil
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		cpy := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			cpy[key] = deepCopyJSON(elem)
+		}
+		return cpy
+	case []interface{}:
+		cpy := make([]interface{}, len(v))
+		for i, elem := range v {
+			cpy[i] = deepCopyJSON(elem)
+		}
+		return cpy
+	default:
+		return v
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	if an, ok := a.(json.Number); ok {
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		if aerr == nil && berr == nil {
+			return af == bf
+		}
+		return an == bn
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
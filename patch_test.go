@@ -0,0 +1,358 @@
+package muxter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Doc      string
+		Patch    string
+		Expected string
+	}{
+		{
+			Name:     "replace member",
+			Doc:      `{"a":"b"}`,
+			Patch:    `{"a":"c"}`,
+			Expected: `{"a":"c"}`,
+		},
+		{
+			Name:     "remove member",
+			Doc:      `{"a":"b","b":"c"}`,
+			Patch:    `{"a":null}`,
+			Expected: `{"b":"c"}`,
+		},
+		{
+			Name:     "nested merge",
+			Doc:      `{"a":{"b":"c","d":"e"}}`,
+			Patch:    `{"a":{"d":null,"f":"g"}}`,
+			Expected: `{"a":{"b":"c","f":"g"}}`,
+		},
+		{
+			Name:     "arrays are replaced",
+			Doc:      `{"a":[1,2]}`,
+			Patch:    `{"a":[3]}`,
+			Expected: `{"a":[3]}`,
+		},
+		{
+			Name:     "non object patch replaces document",
+			Doc:      `{"a":"b"}`,
+			Patch:    `["c"]`,
+			Expected: `["c"]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := MergePatch([]byte(tc.Doc), []byte(tc.Patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !jsonBytesEqual(t, actual, []byte(tc.Expected)) {
+				t.Errorf("expected %s but got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	testcases := []struct {
+		Name          string
+		Doc           string
+		Patch         string
+		Expected      string
+		ExpectedError string
+	}{
+		{
+			Name:     "add member",
+			Doc:      `{"foo":"bar"}`,
+			Patch:    `[{"op":"add","path":"/baz","value":"qux"}]`,
+			Expected: `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			Name:     "add null member",
+			Doc:      `{"foo":"bar"}`,
+			Patch:    `[{"op":"add","path":"/baz","value":null}]`,
+			Expected: `{"foo":"bar","baz":null}`,
+		},
+		{
+			Name:     "add array element",
+			Doc:      `{"foo":["bar","baz"]}`,
+			Patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			Expected: `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			Name:     "append array element",
+			Doc:      `{"foo":["bar"]}`,
+			Patch:    `[{"op":"add","path":"/foo/-","value":"qux"}]`,
+			Expected: `{"foo":["bar","qux"]}`,
+		},
+		{
+			Name:     "remove array element",
+			Doc:      `{"foo":["bar","qux","baz"]}`,
+			Patch:    `[{"op":"remove","path":"/foo/1"}]`,
+			Expected: `{"foo":["bar","baz"]}`,
+		},
+		{
+			Name:     "replace member",
+			Doc:      `{"baz":"qux","foo":"bar"}`,
+			Patch:    `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			Expected: `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			Name:     "move member",
+			Doc:      `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			Patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			Expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			Name:     "copy member",
+			Doc:      `{"foo":{"bar":1}}`,
+			Patch:    `[{"op":"copy","from":"/foo","path":"/baz"}]`,
+			Expected: `{"foo":{"bar":1},"baz":{"bar":1}}`,
+		},
+		{
+			Name:     "escaped pointer",
+			Doc:      `{"a/b":1,"m~n":2}`,
+			Patch:    `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/m~0n"}]`,
+			Expected: `{}`,
+		},
+		{
+			Name:     "passing test",
+			Doc:      `{"baz":"qux","foo":["a",2,"c"]}`,
+			Patch:    `[{"op":"test","path":"/foo","value":["a",2.0,"c"]}]`,
+			Expected: `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			Name:          "failing test",
+			Doc:           `{"baz":"qux"}`,
+			Patch:         `[{"op":"test","path":"/baz","value":"bar"}]`,
+			ExpectedError: `patch: test "/baz": test failed: value does not match`,
+		},
+		{
+			Name:          "remove missing member",
+			Doc:           `{"baz":"qux"}`,
+			Patch:         `[{"op":"remove","path":"/foo"}]`,
+			ExpectedError: `patch: remove "/foo": member "foo" not found`,
+		},
+		{
+			Name:          "add out of bounds",
+			Doc:           `{"foo":["bar"]}`,
+			Patch:         `[{"op":"add","path":"/foo/5","value":"qux"}]`,
+			ExpectedError: `patch: add "/foo/5": array index 5 out of bounds`,
+		},
+		{
+			Name:          "unknown operation",
+			Doc:           `{}`,
+			Patch:         `[{"op":"frobnicate","path":"/foo"}]`,
+			ExpectedError: `patch: frobnicate "/foo": unknown operation "frobnicate"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := JSONPatch([]byte(tc.Doc), []byte(tc.Patch))
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !jsonBytesEqual(t, actual, []byte(tc.Expected)) {
+				t.Errorf("expected %s but got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestJSONDiff(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		From     string
+		To       string
+		Expected string
+	}{
+		{
+			Name:     "equal",
+			From:     `{"foo":[1,2]}`,
+			To:       `{"foo":[1,2.0]}`,
+			Expected: `[]`,
+		},
+		{
+			Name:     "members",
+			From:     `{"a":1,"b":{"c":"d","e/f":true},"g":null}`,
+			To:       `{"a":2,"b":{"c":"d","e/f":false},"h":null}`,
+			Expected: `[{"op":"replace","path":"/a","value":2},{"op":"replace","path":"/b/e~1f","value":false},{"op":"remove","path":"/g"},{"op":"add","path":"/h","value":null}]`,
+		},
+		{
+			Name:     "appended elements",
+			From:     `{"items":[{"id":1,"done":false}]}`,
+			To:       `{"items":[{"id":1,"done":true},{"id":2,"done":false}]}`,
+			Expected: `[{"op":"replace","path":"/items/0/done","value":true},{"op":"add","path":"/items/-","value":{"done":false,"id":2}}]`,
+		},
+		{
+			Name:     "removed elements",
+			From:     `{"items":[1,2,3]}`,
+			To:       `{"items":[1,3]}`,
+			Expected: `[{"op":"replace","path":"/items","value":[1,3]}]`,
+		},
+		{
+			Name:     "type change",
+			From:     `{"a":{"b":1}}`,
+			To:       `{"a":[1]}`,
+			Expected: `[{"op":"replace","path":"/a","value":[1]}]`,
+		},
+		{
+			Name:     "root",
+			From:     `"foo"`,
+			To:       `"bar"`,
+			Expected: `[{"op":"replace","path":"","value":"bar"}]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			patch, err := JSONDiff([]byte(tc.From), []byte(tc.To))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(patch) != tc.Expected {
+				t.Errorf("expected patch %s but got %s", tc.Expected, patch)
+			}
+
+			patched, err := JSONPatch([]byte(tc.From), patch)
+			if err != nil {
+				t.Fatalf("failed to apply patch: %v", err)
+			}
+			if !jsonBytesEqual(t, patched, []byte(tc.To)) {
+				t.Errorf("expected patched document %s but got %s", tc.To, patched)
+			}
+		})
+	}
+}
+
+type patchTarget struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+	patchMeta
+	Version int `json:"-"`
+	etag    string
+}
+
+type patchMeta struct {
+	Owner string `json:"owner,omitempty"`
+}
+
+func (target patchTarget) Validate() error {
+	if target.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestApplyPatch(t *testing.T) {
+	testcases := []struct {
+		Name           string
+		ContentType    string
+		Body           string
+		Expected       patchTarget
+		ExpectedStatus int
+	}{
+		{
+			Name:        "merge patch",
+			ContentType: MergePatchContentType,
+			Body:        `{"tags":["new"]}`,
+			Expected:    patchTarget{Name: "book", Tags: []string{"new"}, patchMeta: patchMeta{Owner: "me"}, Version: 3, etag: "v3"},
+		},
+		{
+			Name:        "json patch",
+			ContentType: JSONPatchContentType,
+			Body:        `[{"op":"remove","path":"/tags"},{"op":"remove","path":"/owner"}]`,
+			Expected:    patchTarget{Name: "book", Version: 3, etag: "v3"},
+		},
+		{
+			Name:           "unsupported media type",
+			ContentType:    "application/json",
+			Body:           `{}`,
+			ExpectedStatus: 415,
+		},
+		{
+			Name:           "malformed patch",
+			ContentType:    JSONPatchContentType,
+			Body:           `{`,
+			ExpectedStatus: 400,
+		},
+		{
+			Name:           "unknown field",
+			ContentType:    MergePatchContentType,
+			Body:           `{"author":"you"}`,
+			ExpectedStatus: 422,
+		},
+		{
+			Name:           "validation failure",
+			ContentType:    MergePatchContentType,
+			Body:           `{"name":null}`,
+			ExpectedStatus: 422,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/book", strings.NewReader(tc.Body))
+			r.Header.Set("Content-Type", tc.ContentType)
+
+			target := patchTarget{Name: "book", Tags: []string{"old"}, patchMeta: patchMeta{Owner: "me"}, Version: 3, etag: "v3"}
+
+			err := ApplyPatch(r, &target)
+			if tc.ExpectedStatus != 0 {
+				var perr *PatchError
+				if !errors.As(err, &perr) {
+					t.Fatalf("expected a patch error but got %v", err)
+				}
+				if perr.Status != tc.ExpectedStatus {
+					t.Fatalf("expected status %d but got %d", tc.ExpectedStatus, perr.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(target, tc.Expected) {
+				t.Errorf("expected %+v but got %+v", tc.Expected, target)
+			}
+		})
+	}
+}
+
+func TestApplyPatchInvalidTarget(t *testing.T) {
+	for _, dst := range []interface{}{patchTarget{}, &map[string]interface{}{"fn": func() {}}} {
+		r := httptest.NewRequest("PATCH", "/book", strings.NewReader(`{}`))
+		r.Header.Set("Content-Type", MergePatchContentType)
+
+		var perr *PatchError
+		if err := ApplyPatch(r, dst); !errors.As(err, &perr) || perr.Status != 500 {
+			t.Errorf("expected a patch error with status 500 for %T but got %v", dst, err)
+		}
+	}
+}
+
+func jsonBytesEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y interface{}
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatalf("invalid json %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatalf("invalid json %s: %v", b, err)
+	}
+	return reflect.DeepEqual(x, y)
+}
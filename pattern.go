@@ -0,0 +1,286 @@
+package muxter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+)
+
+// The metacharacters of the pattern syntax. They start params wherever they appear in the static parts of a pattern,
+// unless they are escaped with EscapeChar, as done by EscapeSegment.
+const (
+	// WildcardPrefix starts a param matching a segment, as in "/users/:id".
+	WildcardPrefix = ':'
+	// ExpressionPrefix starts a param matching a segment against a regular expression, as in "/posts/#id:\\d+".
+	ExpressionPrefix = '#'
+	// CatchallPrefix starts a param matching the rest of the path, as in "/files/*path".
+	CatchallPrefix = '*'
+	// EscapeChar escapes the metacharacter or EscapeChar following it in the static parts of a pattern, such that it
+	// is matched literally, as in "/meetings/12\\:30". Before other characters it is matched literally itself.
+	EscapeChar = '\\'
+)
+
+// EscapeSegment escapes the metacharacters of s, such that it is matched literally when embedded in the static part
+// of a pattern. Slashes are not escaped and still separate segments.
+//
+//	mux.HandleFunc("/meetings/"+muxter.EscapeSegment("12:30"), standup) // matches /meetings/12:30
+func EscapeSegment(s string) string {
+	if strings.IndexAny(s, syntax.Metacharacters) == -1 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	for i := 0; i < len(s); i++ {
+		if syntax.IsMeta(s[i]) {
+			b.WriteByte(EscapeChar)
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// expandPattern generates a path from a route pattern by substituting its wildcard, expression and catchall
+// segments with the values returned by param. It is the inverse of matching a path against the pattern.
+func expandPattern(pattern string, param func(key string) (string, bool)) (string, error) {
+	var b strings.Builder
+	b.Grow(len(pattern))
+
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ':':
+			end := strings.IndexByte(pattern[i:], '/')
+			if end == -1 {
+				end = len(pattern)
+			} else {
+				end += i
+			}
+			key := pattern[i+1 : end]
+			value, ok := param(key)
+			if !ok || value == "" {
+				return "", fmt.Errorf("missing value for param %q", key)
+			}
+			if strings.IndexByte(value, '/') != -1 {
+				return "", fmt.Errorf("value for param %q cannot contain '/'", key)
+			}
+			b.WriteString(value)
+			i = end
+
+		case '*':
+			key := pattern[i+1:]
+			value, ok := param(key)
+			if !ok {
+				return "", fmt.Errorf("missing value for param %q", key)
+			}
+			b.WriteString(value)
+			i = len(pattern)
+
+		case '#':
+			colon := strings.IndexByte(pattern[i:], ':')
+			if colon == -1 {
+				return "", fmt.Errorf("invalid regexp param: %s", pattern[i:])
+			}
+			colon += i
+
+			end := colon + syntax.ExpressionEnd(pattern[colon:])
+
+			key := pattern[i+1 : colon]
+			value, ok := param(key)
+			if !ok || value == "" {
+				return "", fmt.Errorf("missing value for param %q", key)
+			}
+
+			exp, err := regexp.Compile(fmt.Sprintf("^(%s)$", pattern[colon+1:end]))
+			if err != nil {
+				return "", err
+			}
+			if !exp.MatchString(value) {
+				return "", fmt.Errorf("value %q for param %q does not match expression %s", value, key, pattern[colon+1:end])
+			}
+
+			b.WriteString(value)
+			i = end
+
+		case EscapeChar:
+			if i+1 < len(pattern) && syntax.IsMeta(pattern[i+1]) {
+				i++
+			}
+			b.WriteByte(pattern[i])
+			i++
+
+		default:
+			b.WriteByte(pattern[i])
+			i++
+		}
+	}
+
+	return b.String(), nil
+}
+
+// patternParams returns the set of param keys captured by the pattern.
+func patternParams(pattern string) map[string]bool {
+	params := map[string]bool{}
+	for _, key := range syntax.Params(pattern) {
+		params[key] = true
+	}
+	return params
+}
+
+// NormalizePattern validates the pattern and returns its canonical form, as registered by Handle: duplicate slashes
+// are collapsed, the trailing dot of hosts is removed, and so are the anchors of regexp params since they are
+// implied. Hosts keep their case as they are matched case insensitively. Param names must be made of letters,
+// digits, underscores and dashes, and be unique within the pattern. Handle does not enforce the rules of param
+// names, such that patterns like /files/:name.json keep registering. It lets tools that read patterns, such as route
+// manifests, agree with the mux on them.
+//
+//	muxter.NormalizePattern("/users//:id/#format:^(json|xml)$") // "/users/:id/#format:(json|xml)"
+func NormalizePattern(pattern string) (string, error) {
+	normalized, err := normalizePattern(pattern, true)
+	if err != nil {
+		return "", fmt.Errorf("muxter: invalid pattern %q: %w", pattern, err)
+	}
+	return normalized, nil
+}
+
+// normalizePattern returns the canonical form of the pattern. Strict validates the names of its params, which Handle
+// does not.
+func normalizePattern(pattern string, strict bool) (string, error) {
+	if pattern == "" {
+		return "", errors.New("pattern is empty")
+	}
+	if pattern[0] != '/' {
+		return "", errors.New("pattern must begin with a forward-slash")
+	}
+
+	var b strings.Builder
+	b.Grow(len(pattern))
+	params := map[string]bool{}
+
+	addParam := func(name string) error {
+		if !strict {
+			return nil
+		}
+		if name == "" {
+			return errors.New("param name is empty")
+		}
+		for _, c := range name {
+			if !(c == '_' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+				return fmt.Errorf("invalid param name %q", name)
+			}
+		}
+		if params[name] {
+			return fmt.Errorf("param %q appears more than once", name)
+		}
+		params[name] = true
+		return nil
+	}
+
+	if host, path, ok := syntax.SplitHost(pattern); ok {
+		if host == "" {
+			return "", errors.New("host pattern must have a host")
+		}
+		labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+		for i, label := range labels {
+			switch {
+			case label == "":
+				return "", fmt.Errorf("host %q has an empty label", host)
+			case label[0] == ':':
+				if err := addParam(label[1:]); err != nil {
+					return "", err
+				}
+			case label[0] == '#':
+				normalized, err := normalizeExpression(label, addParam)
+				if err != nil {
+					return "", err
+				}
+				labels[i] = normalized
+			}
+		}
+		b.WriteString("//" + strings.Join(labels, "."))
+		pattern = path
+	}
+
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ':':
+			end := strings.IndexByte(pattern[i:], '/')
+			if end == -1 {
+				end = len(pattern)
+			} else {
+				end += i
+			}
+			if err := addParam(pattern[i+1 : end]); err != nil {
+				return "", err
+			}
+			b.WriteString(pattern[i:end])
+			i = end
+
+		case '*':
+			if end := strings.IndexByte(pattern[i:], '/'); end != -1 {
+				return "", fmt.Errorf("cannot register segments after a catchall expression %q", pattern[i:i+end])
+			}
+			if err := addParam(pattern[i+1:]); err != nil {
+				return "", err
+			}
+			b.WriteString(pattern[i:])
+			i = len(pattern)
+
+		case '#':
+			end := i + syntax.ExpressionEnd(pattern[i:])
+			normalized, err := normalizeExpression(pattern[i:end], addParam)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(normalized)
+			i = end
+
+		case '/':
+			b.WriteByte('/')
+			for i < len(pattern) && pattern[i] == '/' {
+				i++
+			}
+
+		case EscapeChar:
+			if i+1 < len(pattern) && syntax.IsMeta(pattern[i+1]) {
+				b.WriteByte(pattern[i])
+				i++
+			}
+			b.WriteByte(pattern[i])
+			i++
+
+		default:
+			b.WriteByte(pattern[i])
+			i++
+		}
+	}
+
+	return b.String(), nil
+}
+
+// normalizeExpression validates the regexp param segment, of the form "#name:expression", and removes the anchors of
+// its expression.
+func normalizeExpression(segment string, addParam func(string) error) (string, error) {
+	name, expression, ok := strings.Cut(segment[1:], ":")
+	if !ok {
+		return "", fmt.Errorf("invalid regexp param: %s", segment)
+	}
+	if err := addParam(name); err != nil {
+		return "", err
+	}
+
+	expression = strings.TrimPrefix(expression, "^")
+	if strings.HasSuffix(expression, "$") && !strings.HasSuffix(expression, `\$`) {
+		expression = strings.TrimSuffix(expression, "$")
+	}
+	if expression == "" {
+		return "", fmt.Errorf("regexp param %q has an empty expression", name)
+	}
+	if _, err := regexp.Compile(expression); err != nil {
+		return "", err
+	}
+
+	return "#" + name + ":" + expression, nil
+}
@@ -0,0 +1,224 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+)
+
+func TestExpandPattern(t *testing.T) {
+	params := map[string]string{
+		"id":   "42",
+		"dir":  "folder-123",
+		"rest": "a/b/c",
+		"bad":  "a/b",
+	}
+
+	lookup := func(key string) (string, bool) {
+		value, ok := params[key]
+		return value, ok
+	}
+
+	testcases := []struct {
+		Pattern       string
+		Expected      string
+		ExpectedError string
+	}{
+		{Pattern: "/static/path", Expected: "/static/path"},
+		{Pattern: "/users/:id/posts", Expected: "/users/42/posts"},
+		{Pattern: `/assets/#dir:folder-\d+/:id`, Expected: "/assets/folder-123/42"},
+		{Pattern: "/files/*rest", Expected: "/files/a/b/c"},
+		{Pattern: `/meetings/12\:30/:id`, Expected: "/meetings/12:30/42"},
+		{Pattern: `/glob/\*/\\\#/a\b`, Expected: `/glob/*/\#/a\b`},
+		{Pattern: "/users/:missing", ExpectedError: `missing value for param "missing"`},
+		{Pattern: "/users/:bad", ExpectedError: `value for param "bad" cannot contain '/'`},
+		{Pattern: `/assets/#id:folder-\d+`, ExpectedError: `value "42" for param "id" does not match expression folder-\d+`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Pattern, func(t *testing.T) {
+			actual, err := expandPattern(tc.Pattern, lookup)
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("expected %q but got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestNormalizePattern(t *testing.T) {
+	testcases := []struct {
+		Pattern       string
+		Expected      string
+		ExpectedError string
+	}{
+		{Pattern: "/users/:id", Expected: "/users/:id"},
+		{Pattern: "/users//:id///posts", Expected: "/users/:id/posts"},
+		{Pattern: "/users/#format:^(json|xml)$", Expected: "/users/#format:(json|xml)"},
+		{Pattern: `/price/#amount:\d+\$`, Expected: `/price/#amount:\d+\$`},
+		{Pattern: `/files/#dir:a\/b//*rest`, Expected: `/files/#dir:a\/b/*rest`},
+		{Pattern: "//:tenant.Example.com./api//v1", Expected: "//:tenant.Example.com/api/v1"},
+		{Pattern: "//example.com", Expected: "//example.com/"},
+		{Pattern: "/user_:user-id", Expected: "/user_:user-id"},
+		{Pattern: `/meetings//12\:30/\#1/:id`, Expected: `/meetings/12\:30/\#1/:id`},
+		{Pattern: "", ExpectedError: `muxter: invalid pattern "": pattern is empty`},
+		{Pattern: "users", ExpectedError: `muxter: invalid pattern "users": pattern must begin with a forward-slash`},
+		{Pattern: "///api", ExpectedError: `muxter: invalid pattern "///api": host pattern must have a host`},
+		{Pattern: "//a..com/", ExpectedError: `muxter: invalid pattern "//a..com/": host "a..com" has an empty label`},
+		{Pattern: "/users/:", ExpectedError: `muxter: invalid pattern "/users/:": param name is empty`},
+		{Pattern: "/users/:id.json", ExpectedError: `muxter: invalid pattern "/users/:id.json": invalid param name "id.json"`},
+		{Pattern: "/users/:id/posts/:id", ExpectedError: `muxter: invalid pattern "/users/:id/posts/:id": param "id" appears more than once`},
+		{Pattern: "/files/*rest/x", ExpectedError: `muxter: invalid pattern "/files/*rest/x": cannot register segments after a catchall expression "*rest"`},
+		{Pattern: "/users/#id", ExpectedError: `muxter: invalid pattern "/users/#id": invalid regexp param: #id`},
+		{Pattern: "/users/#id:^$", ExpectedError: `muxter: invalid pattern "/users/#id:^$": regexp param "id" has an empty expression`},
+		{Pattern: "/users/#id:[a-z", ExpectedError: "muxter: invalid pattern \"/users/#id:[a-z\": error parsing regexp: missing closing ]: `[a-z`"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Pattern, func(t *testing.T) {
+			actual, err := NormalizePattern(tc.Pattern)
+			if tc.ExpectedError != "" {
+				if err == nil || err.Error() != tc.ExpectedError {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("expected %q but got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestHandleNormalizesPattern(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/users//:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte(c.Pattern() + " " + c.Param("id")))
+	}, Name("user"))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+	if body := w.Body.String(); body != "/users/:id 1" {
+		t.Errorf("expected normalized route to serve the request but got %q", body)
+	}
+
+	// Disable panics for unregistered patterns.
+	mux.Disable("/users//:id")
+	if url, err := mux.URL("user", "id", "2"); err != nil || url != "/users/2" {
+		t.Errorf("expected url of normalized pattern but got %q (%v)", url, err)
+	}
+}
+
+func TestEscapeSegment(t *testing.T) {
+	testcases := []struct {
+		Segment  string
+		Expected string
+	}{
+		{Segment: "plain", Expected: "plain"},
+		{Segment: "12:30", Expected: `12\:30`},
+		{Segment: "#1*", Expected: `\#1\*`},
+		{Segment: `a\b`, Expected: `a\\b`},
+		{Segment: "a/b:c", Expected: `a/b\:c`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Segment, func(t *testing.T) {
+			escaped := EscapeSegment(tc.Segment)
+			if escaped != tc.Expected {
+				t.Fatalf("expected %q but got %q", tc.Expected, escaped)
+			}
+			if syntax.IndexMeta(escaped) != -1 {
+				t.Errorf("expected no metacharacters in %q", escaped)
+			}
+			if unescaped := syntax.Unescape(escaped); unescaped != tc.Segment {
+				t.Errorf("expected escaped segment to unescape to %q but got %q", tc.Segment, unescaped)
+			}
+		})
+	}
+}
+
+func TestEscapedPatterns(t *testing.T) {
+	mux := New()
+	for _, pattern := range []string{
+		"/meetings/:time",
+		"/meetings/" + EscapeSegment("12:30"),
+		"/meetings/" + EscapeSegment("12:30") + "/:room",
+		"/tags/" + EscapeSegment("#go") + "/*rest",
+		"/glob/" + EscapeSegment("*.txt"),
+		"/glob/" + EscapeSegment(`a\b`),
+	} {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Write([]byte(c.Pattern() + " " + c.Param("time") + c.Param("room") + c.Param("rest")))
+		})
+	}
+
+	testcases := []struct {
+		Path     string
+		Expected string
+	}{
+		{Path: "/meetings/12:30", Expected: `/meetings/12\:30 `},
+		{Path: "/meetings/12:31", Expected: "/meetings/:time 12:31"},
+		{Path: "/meetings/12:30/blue", Expected: `/meetings/12\:30/:room blue`},
+		{Path: "/tags/%23go/a/b", Expected: `/tags/\#go/*rest a/b`},
+		{Path: "/glob/*.txt", Expected: `/glob/\*.txt `},
+		{Path: `/glob/a\b`, Expected: `/glob/a\\b `},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Path, nil))
+			if body := w.Body.String(); body != tc.Expected {
+				t.Errorf("expected %q but got %q", tc.Expected, body)
+			}
+		})
+	}
+
+	// Disable panics for unregistered patterns.
+	mux.Disable("/meetings/" + EscapeSegment("12:30"))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/meetings/12:30", nil))
+	if w.Code != 404 {
+		t.Errorf("expected disabled escaped route to be served as not found but got %d", w.Code)
+	}
+}
+
+func TestPatternParams(t *testing.T) {
+	testcases := []struct {
+		Pattern  string
+		Expected []string
+	}{
+		{Pattern: "/users/:id", Expected: []string{"id"}},
+		{Pattern: "/files/v:version/*path", Expected: []string{"version", "path"}},
+		{Pattern: `/meetings/12\:30/:room`, Expected: []string{"room"}},
+		{Pattern: `/dates/#date:\d+\/\d+/:id`, Expected: []string{"date", "id"}},
+		{Pattern: "//:tenant.#region:eu|us.example.com/users/:id", Expected: []string{"tenant", "region", "id"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Pattern, func(t *testing.T) {
+			params := patternParams(tc.Pattern)
+			if len(params) != len(tc.Expected) {
+				t.Fatalf("expected params %v but got %v", tc.Expected, params)
+			}
+			for _, key := range tc.Expected {
+				if !params[key] {
+					t.Errorf("expected param %q in %v", key, params)
+				}
+			}
+		})
+	}
+}
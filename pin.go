@@ -0,0 +1,140 @@
+package muxter
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PrimaryPinOptions configures a PrimaryPin.
+type PrimaryPinOptions struct {
+	// Primary serves the mutating requests and the reads of pinned clients, typically a reverse proxy to the primary
+	// upstream. Without a Primary every request is served by the handler, which can route its own queries with
+	// Pinned.
+	Primary Handler
+	// TTL is how long clients stay pinned after a successful mutating request, which should exceed the replication
+	// lag of the replicas. It defaults to 5 seconds.
+	TTL time.Duration
+	// Cookie is the cookie pinning browsers. It defaults to muxter_primary_pin.
+	Cookie string
+	// Header is the response header pinning API clients, which send it back with their next requests. It defaults to
+	// X-Primary-Pin.
+	Header string
+	// Secure marks the cookie as Secure.
+	Secure bool
+}
+
+// PrimaryPin gives clients of replicated backends read-your-writes consistency: after a successful mutating request
+// a client is pinned to the primary for a short while, such that its reads are not served by a replica that has not
+// caught up with its write yet. A pin is the time it expires at, set as a cookie and a response header. Clients can
+// pin themselves at will, which costs the primary load but nothing else.
+type PrimaryPin struct {
+	opts PrimaryPinOptions
+}
+
+// NewPrimaryPin returns a PrimaryPin configured by opts. Its Middleware method pins the clients of the routes it is
+// used on.
+//
+//	pin := muxter.NewPrimaryPin(muxter.PrimaryPinOptions{Primary: muxter.Adaptor(primaryProxy)})
+//	mux.Handle("/orders/", replicaProxy, pin.Middleware)
+func NewPrimaryPin(opts PrimaryPinOptions) *PrimaryPin {
+	if opts.TTL <= 0 {
+		opts.TTL = 5 * time.Second
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = "muxter_primary_pin"
+	}
+	if opts.Header == "" {
+		opts.Header = "X-Primary-Pin"
+	}
+	return &PrimaryPin{opts: opts}
+}
+
+// Middleware is a middleware pinning the clients of successful mutating requests, and serving the mutating requests
+// and the reads of pinned clients with the Primary handler when there is one.
+func (p *PrimaryPin) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		next := h
+		if p.opts.Primary != nil && (isMutating(r.Method) || p.Pinned(r)) {
+			next = p.opts.Primary
+		}
+		if !isMutating(r.Method) {
+			next.ServeHTTPx(w, r, c)
+			return
+		}
+
+		pw := &pinWriter{ResponseWriter: w, pin: p}
+		next.ServeHTTPx(pw, r, c)
+
+		// Handlers that write nothing respond 200 OK once they return.
+		if !pw.started {
+			pw.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// Pinned reports whether the client of the request is pinned to the primary.
+func (p *PrimaryPin) Pinned(r *http.Request) bool {
+	value := r.Header.Get(p.opts.Header)
+	if value == "" {
+		cookie, err := r.Cookie(p.opts.Cookie)
+		if err != nil {
+			return false
+		}
+		value = cookie.Value
+	}
+	expires, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && time.Now().Before(time.UnixMilli(expires))
+}
+
+func (p *PrimaryPin) pin(w http.ResponseWriter) {
+	expires := time.Now().Add(p.opts.TTL)
+	value := strconv.FormatInt(expires.UnixMilli(), 10)
+
+	w.Header().Set(p.opts.Header, value)
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.opts.Cookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(p.opts.TTL.Round(time.Second) / time.Second),
+		Secure:   p.opts.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+// pinWriter pins the client when a successful response is written.
+type pinWriter struct {
+	http.ResponseWriter
+	pin     *PrimaryPin
+	started bool
+}
+
+func (w *pinWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *pinWriter) WriteHeader(code int) {
+	if !w.started && code >= 200 {
+		w.started = true
+		if code < 400 {
+			w.pin.pin(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *pinWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
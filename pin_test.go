@@ -0,0 +1,97 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPrimaryPin(t *testing.T) {
+	pin := NewPrimaryPin(PrimaryPinOptions{
+		Primary: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if r.URL.Query().Get("fail") != "" {
+				w.WriteHeader(http.StatusConflict)
+			}
+			io.WriteString(w, "primary")
+		}),
+	})
+
+	mux := New()
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request, c Context) {
+		io.WriteString(w, "replica")
+	}, pin.Middleware)
+
+	pinned := strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).UnixMilli(), 10)
+
+	testcases := []struct {
+		Name         string
+		Method       string
+		Target       string
+		Header       string
+		Cookie       string
+		ExpectedBody string
+		ExpectedPin  bool
+	}{
+		{Name: "read", Method: "GET", Target: "/orders", ExpectedBody: "replica"},
+		{Name: "write", Method: "POST", Target: "/orders", ExpectedBody: "primary", ExpectedPin: true},
+		{Name: "failed write", Method: "POST", Target: "/orders?fail=1", ExpectedBody: "primary"},
+		{Name: "read pinned by header", Method: "GET", Target: "/orders", Header: pinned, ExpectedBody: "primary"},
+		{Name: "read pinned by cookie", Method: "GET", Target: "/orders", Cookie: pinned, ExpectedBody: "primary"},
+		{Name: "expired pin", Method: "GET", Target: "/orders", Cookie: expired, ExpectedBody: "replica"},
+		{Name: "invalid pin", Method: "GET", Target: "/orders", Header: "forever", ExpectedBody: "replica"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Target, nil)
+			if tc.Header != "" {
+				r.Header.Set("X-Primary-Pin", tc.Header)
+			}
+			if tc.Cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "muxter_primary_pin", Value: tc.Cookie})
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+
+			header := w.Header().Get("X-Primary-Pin")
+			if !tc.ExpectedPin {
+				if header != "" || len(w.Result().Cookies()) != 0 {
+					t.Errorf("expected no pin but got header %q and cookies %v", header, w.Result().Cookies())
+				}
+				return
+			}
+
+			r = httptest.NewRequest("GET", "/orders", nil)
+			r.Header.Set("X-Primary-Pin", header)
+			if !pin.Pinned(r) {
+				t.Errorf("expected pin header %q to pin the client", header)
+			}
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != "muxter_primary_pin" || cookies[0].Value != header {
+				t.Errorf("expected pin cookie %q but got %v", header, cookies)
+			}
+		})
+	}
+}
+
+func TestPrimaryPinWithoutPrimary(t *testing.T) {
+	pin := NewPrimaryPin(PrimaryPinOptions{})
+
+	mux := New()
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request, c Context) {}, pin.Middleware)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/orders", nil))
+
+	if w.Header().Get("X-Primary-Pin") == "" {
+		t.Errorf("expected a write without response body to pin the client")
+	}
+}
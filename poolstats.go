@@ -0,0 +1,63 @@
+package muxter
+
+import (
+	"github.com/davidmdm/muxter/internal/pool"
+)
+
+// PoolMetrics are the counters of a pool of objects reused across requests. Gets minus Allocs is the number of
+// objects reused, and Gets minus Puts and Oversized the number of objects in use or never returned, such as the
+// requests of StripDepth poisoned in Debug mode.
+type PoolMetrics struct {
+	// Gets is the number of objects taken from the pool.
+	Gets uint64 `json:"gets"`
+	// Puts is the number of objects returned to the pool.
+	Puts uint64 `json:"puts"`
+	// Allocs is the number of objects allocated because the pool was empty.
+	Allocs uint64 `json:"allocs"`
+	// Oversized is the number of param slices past the capacity of the pool, reallocated by routes with more params or
+	// allocated before the capacity was lowered, which are dropped rather than returned to the pool.
+	Oversized uint64 `json:"oversized,omitempty"`
+}
+
+// PoolStats are the counters of the pools muxter reuses objects from, which are shared by every mux of the process. The
+// pools count their objects from the creation of the first RouteStats of the process, sparing the requests of programs
+// without RouteStats the cost of counting.
+type PoolStats struct {
+	// Params is the pool of the params of matched routes.
+	Params PoolMetrics `json:"params"`
+	// ParamCapacity is the capacity of the param slices of the pool.
+	ParamCapacity int `json:"paramCapacity"`
+	// Requests and URLs are the pools of the requests and URLs StripDepth hands to its handler.
+	Requests PoolMetrics `json:"requests"`
+	URLs     PoolMetrics `json:"urls"`
+}
+
+// Pools returns the counters of the object pools, to verify that pooling is effective under a workload. Many Allocs
+// show objects not being reused and many Oversized routes with more params than the ParamCapacity.
+func (s *RouteStats) Pools() PoolStats {
+	return PoolStats{
+		Params:        poolMetrics(pool.Params.Counters),
+		ParamCapacity: pool.Params.Capacity(),
+		Requests:      poolMetrics(pool.Requests.Counters),
+		URLs:          poolMetrics(pool.URL.Counters),
+	}
+}
+
+// SetParamCapacity sets the capacity of the param slices of the pool shared by every mux of the process, such that
+// requests to routes with up to capacity params, including the format param of FormatExtensions, are matched without
+// allocating. It defaults to 12 and can be tuned with the Oversized counter of RouteStats.Pools.
+func SetParamCapacity(capacity int) {
+	if capacity < 1 {
+		panic("muxter: param capacity must be positive")
+	}
+	pool.Params.SetCapacity(capacity)
+}
+
+func poolMetrics(counters *pool.Counters) PoolMetrics {
+	return PoolMetrics{
+		Gets:      counters.Gets.Load(),
+		Puts:      counters.Puts.Load(),
+		Allocs:    counters.Allocs.Load(),
+		Oversized: counters.Oversized.Load(),
+	}
+}
@@ -0,0 +1,65 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPoolStats(t *testing.T) {
+	stats := NewRouteStats(RouteStatsOptions{})
+
+	api := New()
+	api.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	api.HandleFunc("/*path", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux := New()
+	mux.Handle("/api/", StripDepth(1, api))
+	mux.HandleFunc("/:a/:b/:c/:d/:e/:f/:g/:h/:i/:j/:k/:l/:m", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	before := stats.Pools()
+	for i := 0; i < 10; i++ {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/books/1", nil))
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/"+strings.Repeat("x/", 12)+"x", nil))
+	after := stats.Pools()
+
+	if gets := after.Params.Gets - before.Params.Gets; gets != 11 {
+		t.Errorf("expected 11 param gets but got %d", gets)
+	}
+	puts, oversized := after.Params.Puts-before.Params.Puts, after.Params.Oversized-before.Params.Oversized
+	if puts+oversized != 11 || oversized < 1 {
+		t.Errorf("expected params to be returned unless oversized, as for the route with 13 params, but got %d puts and %d oversized", puts, oversized)
+	}
+	if gets, puts := after.Requests.Gets-before.Requests.Gets, after.Requests.Puts-before.Requests.Puts; gets != 10 || puts != 10 {
+		t.Errorf("expected 10 request gets and puts but got %d and %d", gets, puts)
+	}
+	if gets, puts := after.URLs.Gets-before.URLs.Gets, after.URLs.Puts-before.URLs.Puts; gets != 10 || puts != 10 {
+		t.Errorf("expected 10 url gets and puts but got %d and %d", gets, puts)
+	}
+
+	t.Run("param capacity", func(t *testing.T) {
+		SetParamCapacity(16)
+		defer SetParamCapacity(12)
+
+		if capacity := stats.Pools().ParamCapacity; capacity != 16 {
+			t.Fatalf("expected param capacity 16 but got %d", capacity)
+		}
+
+		var served int
+		mux.HandleFunc("/capacity/:a/:b/:c/:d/:e/:f/:g/:h/:i/:j/:k/:l/:m/:n", func(w http.ResponseWriter, r *http.Request, c Context) {
+			served++
+		})
+
+		// The pool may still hold params allocated with the previous capacity, which are dropped once outgrown.
+		for i := 0; i < 100; i++ {
+			before := stats.Pools()
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/capacity/"+strings.Repeat("x/", 13)+"n", nil))
+			if stats.Pools().Params.Oversized == before.Params.Oversized {
+				return
+			}
+		}
+		t.Errorf("expected params allocated with the new capacity not to be oversized but %d requests were", served)
+	})
+}
@@ -0,0 +1,66 @@
+package muxter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PreloadAsset is an asset a route's responses depend on, announced to clients with a Link rel=preload header.
+type PreloadAsset struct {
+	// URL is the URL of the asset.
+	URL string `json:"url"`
+	// As is the destination of the asset such as "style", "script", "font" or "image".
+	As string `json:"as,omitempty"`
+	// Type is the MIME type of the asset.
+	Type string `json:"type,omitempty"`
+	// CrossOrigin marks the asset as fetched with CORS, which fonts require.
+	CrossOrigin bool `json:"crossOrigin,omitempty"`
+}
+
+// String formats the asset as the value of a Link header.
+func (a PreloadAsset) String() string {
+	var b strings.Builder
+	b.WriteString("<" + a.URL + ">; rel=preload")
+	if a.As != "" {
+		b.WriteString("; as=" + a.As)
+	}
+	if a.Type != "" {
+		b.WriteString(`; type="` + a.Type + `"`)
+	}
+	if a.CrossOrigin {
+		b.WriteString("; crossorigin")
+	}
+	return b.String()
+}
+
+// Preload is a registration option that declares the assets a route's responses depend on. The assets are recorded
+// in the route's RouteInfo and announced by the PreloadHints middleware.
+//
+//	mux.HandleFunc("/", home, muxter.Preload(muxter.PreloadAsset{URL: "/static/app.css", As: "style"}))
+func Preload(assets ...PreloadAsset) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Preload = append(ri.Preload, assets...)
+	})
+}
+
+// PreloadHints returns a middleware adding a Link rel=preload header for each asset declared with the Preload
+// option on the matched route. When earlyHints is true the headers are also sent ahead of the response in a
+// 103 Early Hints response to HTTP/1.1 and later clients, so that they can start fetching the assets while the
+// handler is running.
+//
+//	mux.Use(muxter.PreloadHints(true))
+func PreloadHints(earlyHints bool) Middleware {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if route := c.Route(); route != nil && len(route.Preload) > 0 {
+				for _, asset := range route.Preload {
+					w.Header().Add("Link", asset.String())
+				}
+				if earlyHints && r.ProtoAtLeast(1, 1) {
+					w.WriteHeader(http.StatusEarlyHints)
+				}
+			}
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+func TestPreloadHints(t *testing.T) {
+	testcases := []struct {
+		Name       string
+		EarlyHints bool
+		Path       string
+		Links      []string
+		Hints      []string
+	}{
+		{
+			Name: "no assets",
+			Path: "/plain",
+		},
+		{
+			Name:  "link headers",
+			Path:  "/",
+			Links: []string{`</static/app.css>; rel=preload; as=style`, `</static/font.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`},
+		},
+		{
+			Name:       "early hints",
+			EarlyHints: true,
+			Path:       "/",
+			Links:      []string{`</static/app.css>; rel=preload; as=style`, `</static/font.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`},
+			Hints:      []string{`</static/app.css>; rel=preload; as=style`, `</static/font.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mux := New()
+			mux.Use(PreloadHints(tc.EarlyHints))
+
+			mux.HandleFunc(
+				"/",
+				func(w http.ResponseWriter, r *http.Request, c Context) { w.Write([]byte("home")) },
+				Preload(
+					PreloadAsset{URL: "/static/app.css", As: "style"},
+					PreloadAsset{URL: "/static/font.woff2", As: "font", Type: "font/woff2", CrossOrigin: true},
+				),
+			)
+			mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			var hints []string
+			trace := &httptrace.ClientTrace{
+				Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+					if code == http.StatusEarlyHints {
+						hints = append(hints, header["Link"]...)
+					}
+					return nil
+				},
+			}
+
+			r, err := http.NewRequest("GET", server.URL+tc.Path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r = r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != 200 {
+				t.Errorf("expected status 200 but got %d", resp.StatusCode)
+			}
+			if links := resp.Header["Link"]; !reflect.DeepEqual(links, tc.Links) {
+				t.Errorf("expected links %q but got %q", tc.Links, links)
+			}
+			if !reflect.DeepEqual(hints, tc.Hints) {
+				t.Errorf("expected early hints %q but got %q", tc.Hints, hints)
+			}
+		})
+	}
+}
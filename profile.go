@@ -0,0 +1,117 @@
+package muxter
+
+import (
+	"bytes"
+	"net/http"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync/atomic"
+	"time"
+)
+
+// ProfileKind is the kind of profile captured by ProfileRequests.
+type ProfileKind string
+
+const (
+	// CPUProfile is a CPU profile in the pprof format, read with `go tool pprof`.
+	CPUProfile ProfileKind = "cpu"
+	// ExecutionTrace is an execution trace, read with `go tool trace`.
+	ExecutionTrace ProfileKind = "trace"
+)
+
+// ProfileOptions configures the ProfileRequests middleware.
+type ProfileOptions struct {
+	// SampleRate is the ratio of the requests of each route that are profiled. It defaults to 0.001.
+	SampleRate float64
+	// Patterns are the sample rates of routes by pattern, overriding SampleRate, such that a single hot route can be
+	// profiled. A rate of 0 disables profiling of the route.
+	Patterns map[string]float64
+	// Kind is the kind of profile captured. It defaults to CPUProfile.
+	Kind ProfileKind
+	// OnProfile is called with the profiles once the handler returns. It is required.
+	OnProfile func(profile RequestProfile)
+	// Rand draws the sampled requests. It defaults to the global source of math/rand.
+	Rand Rand
+}
+
+// RequestProfile is the profile of the execution of a handler.
+type RequestProfile struct {
+	Request  *http.Request
+	Pattern  string
+	Kind     ProfileKind
+	Start    time.Time
+	Duration time.Duration
+	// Data is the profile in the format of its kind.
+	Data []byte
+}
+
+// profiling is set while a request is profiled, since the CPU profiler and the execution tracer are process-wide.
+var profiling atomic.Bool
+
+// ProfileRequests is a middleware capturing a CPU profile or an execution trace around the execution of a sample of
+// the requests of each route, to profile hot routes in production. Only one request is profiled at a time across the
+// process: requests sampled while another is profiled, or while the profiler is used elsewhere such as by
+// net/http/pprof, are served without profile. CPU profiles sample the stacks 100 times per second, so that they tell
+// little of handlers that return within a few milliseconds. It panics if OnProfile is nil.
+//
+//	mux.Use(muxter.ProfileRequests(muxter.ProfileOptions{
+//		Patterns:  map[string]float64{"/search": 0.01},
+//		OnProfile: func(p muxter.RequestProfile) { uploadProfile(p.Pattern, p.Kind, p.Data) },
+//	}))
+func ProfileRequests(opts ProfileOptions) Middleware {
+	if opts.OnProfile == nil {
+		panic("muxter: ProfileRequests requires an OnProfile callback")
+	}
+	if opts.SampleRate == 0 {
+		opts.SampleRate = 0.001
+	}
+	if opts.Kind == "" {
+		opts.Kind = CPUProfile
+	}
+	if opts.Rand == nil {
+		opts.Rand = systemRand{}
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			rate, ok := opts.Patterns[c.Pattern()]
+			if !ok {
+				rate = opts.SampleRate
+			}
+			if opts.Rand.Float64() >= rate || !profiling.CompareAndSwap(false, true) {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+			defer profiling.Store(false)
+
+			var buf bytes.Buffer
+			stop, err := startProfile(opts.Kind, &buf)
+			if err != nil {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			start := time.Now()
+			defer func() {
+				stop()
+				opts.OnProfile(RequestProfile{
+					Request:  r,
+					Pattern:  c.Pattern(),
+					Kind:     opts.Kind,
+					Start:    start,
+					Duration: time.Since(start),
+					Data:     buf.Bytes(),
+				})
+			}()
+
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+}
+
+func startProfile(kind ProfileKind, buf *bytes.Buffer) (stop func(), err error) {
+	if kind == ExecutionTrace {
+		return trace.Stop, trace.Start(buf)
+	}
+	return pprof.StopCPUProfile, pprof.StartCPUProfile(buf)
+}
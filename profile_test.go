@@ -0,0 +1,111 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+func TestProfileRequests(t *testing.T) {
+	testcases := []struct {
+		Name            string
+		Kind            ProfileKind
+		Path            string
+		ExpectedProfile bool
+		ExpectedPrefix  []byte
+	}{
+		{Name: "cpu profile", Path: "/search", ExpectedProfile: true, ExpectedPrefix: []byte{0x1f, 0x8b}},
+		{Name: "execution trace", Kind: ExecutionTrace, Path: "/search", ExpectedProfile: true, ExpectedPrefix: []byte("go 1.")},
+		{Name: "disabled route", Path: "/health"},
+		{Name: "default rate", Path: "/books"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var profiles []RequestProfile
+
+			mux := New()
+			mux.Use(ProfileRequests(ProfileOptions{
+				Patterns:  map[string]float64{"/search": 1, "/health": 0},
+				Kind:      tc.Kind,
+				OnProfile: func(profile RequestProfile) { profiles = append(profiles, profile) },
+				Rand:      fixedRand(0.5),
+			}))
+			handler := func(w http.ResponseWriter, r *http.Request, c Context) {
+				for start := time.Now(); time.Since(start) < 20*time.Millisecond; {
+				}
+			}
+			mux.HandleFunc("/search", handler)
+			mux.HandleFunc("/health", handler)
+			mux.HandleFunc("/books", handler)
+
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tc.Path, nil))
+
+			if !tc.ExpectedProfile {
+				if len(profiles) != 0 {
+					t.Fatalf("expected no profile but got %d", len(profiles))
+				}
+				return
+			}
+			if len(profiles) != 1 {
+				t.Fatalf("expected a profile but got %d", len(profiles))
+			}
+
+			profile := profiles[0]
+			if profile.Pattern != tc.Path || profile.Duration < 20*time.Millisecond {
+				t.Errorf("expected a profile of %s lasting at least 20ms but got %s lasting %v", tc.Path, profile.Pattern, profile.Duration)
+			}
+			if !bytes.HasPrefix(profile.Data, tc.ExpectedPrefix) {
+				t.Errorf("expected profile data starting with %q but got %q", tc.ExpectedPrefix, profile.Data[:min(len(profile.Data), 8)])
+			}
+		})
+	}
+}
+
+func TestProfileLabels(t *testing.T) {
+	label := func(ctx context.Context, key string) string {
+		value, _ := pprof.Label(ctx, key)
+		return value
+	}
+
+	var labels []string
+	handler := func(w http.ResponseWriter, r *http.Request, c Context) {
+		labels = append(labels, label(r.Context(), "pattern")+" "+label(r.Context(), "method"))
+	}
+
+	api := New(ProfileLabels(true))
+	api.HandleFunc("/users/:id", handler)
+
+	mux := New(ProfileLabels(true))
+	mux.HandleFunc("/books/:id", handler)
+	mux.Handle("/api/", StripDepth(1, api))
+
+	unlabeled := New()
+	unlabeled.HandleFunc("/books/:id", handler)
+
+	testcases := []struct {
+		Name          string
+		Mux           *Mux
+		Method        string
+		Path          string
+		ExpectedLabel string
+	}{
+		{Name: "route", Mux: mux, Method: "GET", Path: "/books/1", ExpectedLabel: "/books/:id GET"},
+		{Name: "nested mux", Mux: mux, Method: "DELETE", Path: "/api/users/1", ExpectedLabel: "/api/users/:id DELETE"},
+		{Name: "disabled", Mux: unlabeled, Method: "GET", Path: "/books/1", ExpectedLabel: " "},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			labels = nil
+			tc.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil))
+			if len(labels) != 1 || labels[0] != tc.ExpectedLabel {
+				t.Errorf("expected labels %q but got %q", tc.ExpectedLabel, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package muxter
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProxyAuthOptions configures the ProxyAuth middleware.
+type ProxyAuthOptions struct {
+	// TrustedProxies are the IP addresses or CIDR ranges, such as "10.0.0.0/8", of the authenticating proxies.
+	// Identity headers are only trusted on requests coming from them.
+	TrustedProxies []string
+	// UserHeader is the header holding the user's identifier. It defaults to X-Forwarded-User.
+	UserHeader string
+	// EmailHeader is the header holding the user's email address. It defaults to X-Forwarded-Email.
+	EmailHeader string
+	// GroupsHeader is the header holding the comma separated groups of the user. It defaults to X-Forwarded-Groups.
+	GroupsHeader string
+	// AssertionHeader is the header holding a JWT signed by the proxy asserting the user's identity, such as
+	// X-Goog-IAP-JWT-Assertion or Cf-Access-Jwt-Assertion. When set the identity is taken from the verified
+	// assertion instead of the plain identity headers, and the assertion is verified with the keys at JWKSURL, its
+	// issuer must be Issuer and its audience must include Audience.
+	AssertionHeader string
+	JWKSURL         string
+	Issuer          string
+	Audience        string
+	// Optional lets requests without identity headers, or without an assertion, through anonymously instead of
+	// answering them with 401 Unauthorized. Handlers tell anonymous requests apart by their nil Identity. Spoofed
+	// identity headers and invalid assertions are still refused.
+	Optional bool
+	// OnSpoof is called with requests carrying identity headers that do not come from a trusted proxy, for example
+	// to log or alert on attempts to bypass the proxy.
+	OnSpoof func(r *http.Request)
+	// Client is the client used to fetch the keys of assertions. It defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// ProxyAuth returns a middleware for deployments behind an authenticating proxy, such as oauth2-proxy or
+// Identity-Aware Proxy, establishing the Identity of requests from the identity headers set by the proxy.
+//
+// Requests that do not come from a trusted proxy are answered with 403 Forbidden if they carry identity headers, as
+// they may be attempting to impersonate a user by bypassing the proxy, and with 401 Unauthorized otherwise, as are
+// requests without a valid identity. When assertions are verified the trusted proxies may be omitted, as the
+// assertions cannot be forged. ProxyAuth panics if the options are invalid.
+//
+//	mux.Use(muxter.ProxyAuth(muxter.ProxyAuthOptions{TrustedProxies: []string{"10.0.0.0/8"}}))
+func ProxyAuth(opts ProxyAuthOptions) Middleware {
+	if opts.UserHeader == "" {
+		opts.UserHeader = "X-Forwarded-User"
+	}
+	if opts.EmailHeader == "" {
+		opts.EmailHeader = "X-Forwarded-Email"
+	}
+	if opts.GroupsHeader == "" {
+		opts.GroupsHeader = "X-Forwarded-Groups"
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	proxies := make([]*net.IPNet, len(opts.TrustedProxies))
+	for i, proxy := range opts.TrustedProxies {
+		proxies[i] = parseProxy(proxy)
+	}
+
+	var keys *keySet
+	if opts.AssertionHeader != "" {
+		if opts.JWKSURL == "" || opts.Issuer == "" || opts.Audience == "" {
+			panic("muxter: proxy assertions require a jwks url, an issuer and an audience")
+		}
+		keys = &keySet{url: opts.JWKSURL, client: opts.Client}
+	} else if len(proxies) == 0 {
+		panic("muxter: proxy auth requires trusted proxies or signed assertions")
+	}
+
+	identityHeaders := []string{opts.UserHeader, opts.EmailHeader, opts.GroupsHeader, opts.AssertionHeader}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			if len(proxies) > 0 && !fromProxy(r, proxies) {
+				for _, header := range identityHeaders {
+					if header != "" && r.Header.Get(header) != "" {
+						if opts.OnSpoof != nil {
+							opts.OnSpoof(r)
+						}
+						writeStatus(w, c, http.StatusForbidden)
+						return
+					}
+				}
+				if opts.Optional {
+					h.ServeHTTPx(w, r, c)
+					return
+				}
+				writeStatus(w, c, http.StatusUnauthorized)
+				return
+			}
+
+			credentials := opts.UserHeader
+			if keys != nil {
+				credentials = opts.AssertionHeader
+			}
+			if opts.Optional && r.Header.Get(credentials) == "" {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			var identity *Identity
+			if keys != nil {
+				identity = verifyAssertion(r, keys, opts)
+			} else if user := r.Header.Get(opts.UserHeader); user != "" {
+				identity = &Identity{
+					Subject: user,
+					Email:   r.Header.Get(opts.EmailHeader),
+					Groups:  splitList(r.Header.Get(opts.GroupsHeader)),
+				}
+			}
+			if identity == nil {
+				writeStatus(w, c, http.StatusUnauthorized)
+				return
+			}
+
+			c.editExtras().identity = identity
+			h.ServeHTTPx(w, r, c)
+		})
+	}
+}
+
+// verifyAssertion returns the identity asserted by the request's assertion, or nil if it is missing or invalid.
+func verifyAssertion(r *http.Request, keys *keySet, opts ProxyAuthOptions) *Identity {
+	assertion := r.Header.Get(opts.AssertionHeader)
+	if assertion == "" {
+		return nil
+	}
+
+	payload, err := verifyJWS(assertion, func(header jwsHeader) (crypto.PublicKey, error) {
+		return keys.key(r.Context(), header.Kid)
+	})
+	if err != nil {
+		return nil
+	}
+
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.verify(opts.Issuer, opts.Audience) != nil {
+		return nil
+	}
+	return &Identity{Subject: claims.Subject, Email: claims.Email, Name: claims.Name, Groups: claims.groups()}
+}
+
+func parseProxy(proxy string) *net.IPNet {
+	if !strings.Contains(proxy, "/") {
+		ip := net.ParseIP(proxy)
+		if ip == nil {
+			panic(fmt.Sprintf("muxter: invalid trusted proxy: %s", proxy))
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	_, network, err := net.ParseCIDR(proxy)
+	if err != nil {
+		panic(fmt.Sprintf("muxter: invalid trusted proxy: %s", proxy))
+	}
+	return network
+}
+
+// fromProxy reports whether the request's remote address is one of the proxies.
+func fromProxy(r *http.Request, proxies []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
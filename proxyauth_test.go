@@ -0,0 +1,200 @@
+package muxter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProxyAuth(t *testing.T) {
+	var spoofed int
+
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		json.NewEncoder(w).Encode(c.Identity())
+	}, ProxyAuth(ProxyAuthOptions{
+		TrustedProxies: []string{"10.0.0.0/8", "::1"},
+		OnSpoof:        func(r *http.Request) { spoofed++ },
+	}))
+
+	testcases := []struct {
+		Name            string
+		RemoteAddr      string
+		Headers         map[string]string
+		ExpectedCode    int
+		ExpectedBody    string
+		ExpectedSpoofed int
+	}{
+		{
+			Name:       "trusted proxy",
+			RemoteAddr: "10.1.2.3:4567",
+			Headers: map[string]string{
+				"X-Forwarded-User":   "user-1",
+				"X-Forwarded-Email":  "user@example.com",
+				"X-Forwarded-Groups": "admins, ops",
+			},
+			ExpectedCode: 200,
+			ExpectedBody: `{"sub":"user-1","email":"user@example.com","groups":["admins","ops"]}` + "\n",
+		},
+		{
+			Name:         "trusted ipv6 proxy",
+			RemoteAddr:   "[::1]:4567",
+			Headers:      map[string]string{"X-Forwarded-User": "user-1"},
+			ExpectedCode: 200,
+			ExpectedBody: `{"sub":"user-1"}` + "\n",
+		},
+		{
+			Name:         "no user",
+			RemoteAddr:   "10.1.2.3:4567",
+			Headers:      map[string]string{"X-Forwarded-Email": "user@example.com"},
+			ExpectedCode: 401,
+		},
+		{
+			Name:            "spoofed headers",
+			RemoteAddr:      "192.0.2.1:4567",
+			Headers:         map[string]string{"X-Forwarded-User": "admin"},
+			ExpectedCode:    403,
+			ExpectedSpoofed: 1,
+		},
+		{
+			Name:         "bypassed proxy",
+			RemoteAddr:   "192.0.2.1:4567",
+			ExpectedCode: 401,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			spoofed = 0
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.RemoteAddr
+			for key, value := range tc.Headers {
+				r.Header.Set(key, value)
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %s but got %s", tc.ExpectedBody, w.Body.String())
+			}
+			if spoofed != tc.ExpectedSpoofed {
+				t.Errorf("expected %d spoofing attempts but got %d", tc.ExpectedSpoofed, spoofed)
+			}
+		})
+	}
+}
+
+func TestProxyAuthAssertion(t *testing.T) {
+	provider := newTestProvider(t)
+
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte(c.Identity().Email))
+	}, ProxyAuth(ProxyAuthOptions{
+		AssertionHeader: "X-Goog-IAP-JWT-Assertion",
+		JWKSURL:         provider.URL + "/jwks",
+		Issuer:          "https://cloud.google.com/iap",
+		Audience:        "/projects/1/global/backendServices/2",
+	}))
+
+	claims := func(modify func(map[string]interface{})) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss":   "https://cloud.google.com/iap",
+			"aud":   "/projects/1/global/backendServices/2",
+			"sub":   "accounts.google.com:1234",
+			"email": "user@example.com",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}
+		if modify != nil {
+			modify(claims)
+		}
+		return claims
+	}
+
+	testcases := []struct {
+		Name         string
+		Assertion    string
+		ExpectedCode int
+	}{
+		{Name: "valid", Assertion: provider.sign(t, claims(nil)), ExpectedCode: 200},
+		{Name: "missing", ExpectedCode: 401},
+		{
+			Name:         "wrong audience",
+			Assertion:    provider.sign(t, claims(func(c map[string]interface{}) { c["aud"] = "/projects/other" })),
+			ExpectedCode: 401,
+		},
+		{
+			Name:         "expired",
+			Assertion:    provider.sign(t, claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			ExpectedCode: 401,
+		},
+		{Name: "forged", Assertion: provider.sign(t, claims(nil)) + "x", ExpectedCode: 401},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+			if tc.Assertion != "" {
+				r.Header.Set("X-Goog-IAP-JWT-Assertion", tc.Assertion)
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedCode == 200 && w.Body.String() != "user@example.com" {
+				t.Errorf("expected the asserted email but got %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestProxyAuthOptional(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if identity := c.Identity(); identity != nil {
+			w.Write([]byte("hello " + identity.Subject))
+			return
+		}
+		w.Write([]byte("hello stranger"))
+	}, ProxyAuth(ProxyAuthOptions{TrustedProxies: []string{"10.0.0.0/8"}, Optional: true}))
+
+	testcases := []struct {
+		Name         string
+		RemoteAddr   string
+		User         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Name: "authenticated", RemoteAddr: "10.1.2.3:4567", User: "user-1", ExpectedCode: 200, ExpectedBody: "hello user-1"},
+		{Name: "anonymous", RemoteAddr: "10.1.2.3:4567", ExpectedCode: 200, ExpectedBody: "hello stranger"},
+		{Name: "anonymous bypassing proxy", RemoteAddr: "192.0.2.1:4567", ExpectedCode: 200, ExpectedBody: "hello stranger"},
+		{Name: "spoofed", RemoteAddr: "192.0.2.1:4567", User: "admin", ExpectedCode: 403},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.RemoteAddr
+			if tc.User != "" {
+				r.Header.Set("X-Forwarded-User", tc.User)
+			}
+
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package muxter
+
+import (
+	"container/list"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TokenBucket is the budget of a client of a RateLimiter: the tokens left at the time it was last updated.
+type TokenBucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// LimiterStore holds the token buckets of a RateLimiter by client key. Implementations backed by a key-value store
+// such as Redis keep the budgets of clients across restarts and share them between instances. Updates of the bucket
+// of a client are serialized within a RateLimiter but not across instances sharing a store, where concurrent
+// requests of a client may be allowed a few tokens beyond its budget.
+type LimiterStore interface {
+	// Get returns the bucket of the key, or false if the key has no bucket.
+	Get(key string) (TokenBucket, bool, error)
+	// Set stores the bucket of the key. Buckets that are full again after ttl can be expired by the store.
+	Set(key string, bucket TokenBucket, ttl time.Duration) error
+}
+
+// RateLimitOptions configures a RateLimiter.
+type RateLimitOptions struct {
+	// Rate is the number of requests per second clients are allowed on average.
+	Rate float64
+	// Burst is the number of requests clients can make at once. It defaults to the Rate rounded up.
+	Burst int
+	// Key returns the client of the request. It defaults to the IP address of the request's remote address.
+	Key func(r *http.Request, c Context) string
+	// Store holds the buckets of clients. It defaults to a MemoryLimiterStore of 10000 clients.
+	Store LimiterStore
+	// OnStoreError is called with the errors of the store. Requests are allowed when the store fails.
+	OnStoreError func(err error)
+	// Clock refills the buckets. It defaults to the system clock, and is also the clock of the default store.
+	Clock Clock
+}
+
+// RateLimiter limits the rate of requests of clients with token buckets: every client has a bucket of Burst tokens
+// refilled at Rate tokens per second, and each request takes a token from the bucket of its client.
+type RateLimiter struct {
+	opts    RateLimitOptions
+	stripes [64]sync.Mutex
+
+	// limitMu guards the Rate and Burst of opts, which can be changed with SetLimit.
+	limitMu sync.RWMutex
+}
+
+// NewRateLimiter returns a rate limiter configured by opts. Its Middleware method limits the routes it is used on.
+// It panics if the rate is not positive.
+//
+//	limiter := muxter.NewRateLimiter(muxter.RateLimitOptions{Rate: 10, Burst: 20})
+//	mux.Use(limiter.Middleware)
+func NewRateLimiter(opts RateLimitOptions) *RateLimiter {
+	if opts.Rate <= 0 {
+		panic("muxter: rate limit must be positive")
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = int(math.Ceil(opts.Rate))
+	}
+	if opts.Key == nil {
+		opts.Key = remoteIP
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	if opts.Store == nil {
+		store := NewMemoryLimiterStore(10000)
+		store.clock = opts.Clock
+		opts.Store = store
+	}
+	return &RateLimiter{opts: opts}
+}
+
+// Middleware is a middleware answering the requests of clients that exceeded their budget with 429 Too Many
+// Requests and a Retry-After header.
+func (l *RateLimiter) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if wait := l.Take(l.opts.Key(r, c)); wait > 0 {
+			c.extra().events.publish(eventLimiterRejected, LimiterRejectedEvent{
+				Request:    r,
+				Pattern:    c.Pattern(),
+				Status:     http.StatusTooManyRequests,
+				RetryAfter: wait,
+			})
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeStatus(w, c, http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTPx(w, r, c)
+	})
+}
+
+// Take takes a token from the bucket of the key. It returns 0 if a token was taken, or how long until a token is
+// available otherwise.
+func (l *RateLimiter) Take(key string) time.Duration {
+	stripe := fnv.New32a()
+	stripe.Write([]byte(key))
+	mu := &l.stripes[stripe.Sum32()%uint32(len(l.stripes))]
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := l.opts.Clock.Now()
+	rate, maxBurst := l.Limit()
+	burst := float64(maxBurst)
+
+	bucket, ok, err := l.opts.Store.Get(key)
+	if err != nil {
+		l.storeError(err)
+		return 0
+	}
+	if !ok {
+		bucket = TokenBucket{Tokens: burst, Updated: now}
+	}
+
+	if elapsed := now.Sub(bucket.Updated); elapsed > 0 {
+		bucket.Tokens = math.Min(burst, bucket.Tokens+elapsed.Seconds()*rate)
+	}
+	bucket.Updated = now
+
+	var wait time.Duration
+	if bucket.Tokens >= 1 {
+		bucket.Tokens--
+	} else {
+		wait = time.Duration((1 - bucket.Tokens) / rate * float64(time.Second))
+	}
+
+	ttl := time.Duration((burst - bucket.Tokens) / rate * float64(time.Second))
+	if err := l.opts.Store.Set(key, bucket, ttl); err != nil {
+		l.storeError(err)
+	}
+	return wait
+}
+
+// Limit returns the rate and burst of the limiter.
+func (l *RateLimiter) Limit() (rate float64, burst int) {
+	l.limitMu.RLock()
+	defer l.limitMu.RUnlock()
+	return l.opts.Rate, l.opts.Burst
+}
+
+// SetLimit changes the rate and burst of the limiter while it is limiting requests. The burst defaults to the rate
+// rounded up if it is not positive. Buckets keep their tokens, capped to the new burst as they are refilled. It
+// panics if the rate is not positive.
+func (l *RateLimiter) SetLimit(rate float64, burst int) {
+	if rate <= 0 {
+		panic("muxter: rate limit must be positive")
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	l.limitMu.Lock()
+	defer l.limitMu.Unlock()
+	l.opts.Rate, l.opts.Burst = rate, burst
+}
+
+func (l *RateLimiter) storeError(err error) {
+	if l.opts.OnStoreError != nil {
+		l.opts.OnStoreError(err)
+	}
+}
+
+func remoteIP(r *http.Request, c Context) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// MemoryLimiterStore is an in-memory LimiterStore holding the buckets of a bounded number of clients, evicting the
+// least recently used. Its buckets can be snapshotted before a restart and restored after, such that restarts do not
+// reset the budgets of abusive clients.
+type MemoryLimiterStore struct {
+	max   int
+	clock Clock
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	order   *list.List
+}
+
+type storedBucket struct {
+	Key     string      `json:"key"`
+	Bucket  TokenBucket `json:"bucket"`
+	Expires time.Time   `json:"expires"`
+}
+
+// NewMemoryLimiterStore returns a store holding the buckets of at most max clients.
+func NewMemoryLimiterStore(max int) *MemoryLimiterStore {
+	return &MemoryLimiterStore{max: max, clock: systemClock{}, buckets: map[string]*list.Element{}, order: list.New()}
+}
+
+func (s *MemoryLimiterStore) Get(key string) (TokenBucket, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.buckets[key]
+	if !ok {
+		return TokenBucket{}, false, nil
+	}
+	stored := elem.Value.(*storedBucket)
+	if s.clock.Now().After(stored.Expires) {
+		s.remove(elem)
+		return TokenBucket{}, false, nil
+	}
+	s.order.MoveToFront(elem)
+	return stored.Bucket, true, nil
+}
+
+func (s *MemoryLimiterStore) Set(key string, bucket TokenBucket, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(&storedBucket{Key: key, Bucket: bucket, Expires: s.clock.Now().Add(ttl)})
+	return nil
+}
+
+func (s *MemoryLimiterStore) set(stored *storedBucket) {
+	if elem, ok := s.buckets[stored.Key]; ok {
+		elem.Value = stored
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.buckets[stored.Key] = s.order.PushFront(stored)
+	for s.order.Len() > s.max {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *MemoryLimiterStore) remove(elem *list.Element) {
+	delete(s.buckets, elem.Value.(*storedBucket).Key)
+	s.order.Remove(elem)
+}
+
+// Snapshot writes the buckets that have not expired to w as JSON.
+func (s *MemoryLimiterStore) Snapshot(w io.Writer) error {
+	s.mu.Lock()
+	now := s.clock.Now()
+	buckets := make([]storedBucket, 0, s.order.Len())
+	for elem := s.order.Back(); elem != nil; elem = elem.Prev() {
+		if stored := elem.Value.(*storedBucket); stored.Expires.After(now) {
+			buckets = append(buckets, *stored)
+		}
+	}
+	s.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(buckets)
+}
+
+// Restore adds the buckets of a snapshot read from r to the store, skipping those that have expired since.
+func (s *MemoryLimiterStore) Restore(r io.Reader) error {
+	var buckets []storedBucket
+	if err := json.NewDecoder(r).Decode(&buckets); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	for i := range buckets {
+		if buckets[i].Expires.After(now) {
+			s.set(&buckets[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package muxter
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 1, Burst: 2})
+
+	mux := New()
+	mux.Use(limiter.Middleware)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	for i, expected := range []int{200, 200, 429} {
+		if w := request("192.0.2.1:1234"); w.Code != expected {
+			t.Errorf("request %d: expected code %d but got %d", i, expected, w.Code)
+		} else if expected == 429 && w.Header().Get("Retry-After") != "1" {
+			t.Errorf("expected to be told to retry after a second but got %q", w.Header().Get("Retry-After"))
+		}
+	}
+
+	if w := request("192.0.2.2:1234"); w.Code != 200 {
+		t.Errorf("expected clients to have separate budgets but got %d", w.Code)
+	}
+}
+
+func TestMemoryLimiterStoreSnapshot(t *testing.T) {
+	store := NewMemoryLimiterStore(2)
+	limiter := NewRateLimiter(RateLimitOptions{Rate: 0.001, Burst: 1, Store: store})
+
+	limiter.Take("abusive")
+	if wait := limiter.Take("abusive"); wait == 0 {
+		t.Fatal("expected the client to exceed its budget")
+	}
+
+	var snapshot bytes.Buffer
+	if err := store.Snapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewMemoryLimiterStore(2)
+	if err := restored.Restore(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter = NewRateLimiter(RateLimitOptions{Rate: 0.001, Burst: 1, Store: restored})
+	if wait := limiter.Take("abusive"); wait == 0 {
+		t.Error("expected the budget of the client to survive the restart")
+	}
+	if wait := limiter.Take("other"); wait != 0 {
+		t.Errorf("expected other clients to be allowed but got to wait %s", wait)
+	}
+
+	t.Run("eviction", func(t *testing.T) {
+		limiter.Take("newest")
+		if _, ok, _ := restored.Get("abusive"); ok {
+			t.Error("expected the least recently used bucket to be evicted")
+		}
+	})
+}
+
+type failingStore struct{}
+
+func (failingStore) Get(string) (TokenBucket, bool, error) {
+	return TokenBucket{}, false, errors.New("unavailable")
+}
+
+func (failingStore) Set(string, TokenBucket, time.Duration) error { return errors.New("unavailable") }
+
+func TestRateLimiterStoreError(t *testing.T) {
+	var errs int
+	limiter := NewRateLimiter(RateLimitOptions{
+		Rate:         0.001,
+		Burst:        1,
+		Store:        failingStore{},
+		OnStoreError: func(error) { errs++ },
+	})
+
+	for i := 0; i < 3; i++ {
+		if wait := limiter.Take("client"); wait != 0 {
+			t.Fatalf("expected requests to be allowed when the store fails but got to wait %s", wait)
+		}
+	}
+	if errs != 3 {
+		t.Errorf("expected 3 store errors but got %d", errs)
+	}
+}
@@ -0,0 +1,148 @@
+package muxter
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsafeRedirect is returned by SafeRedirect when the target is refused.
+var ErrUnsafeRedirect = errors.New("muxter: unsafe redirect target")
+
+// SubtreeRedirect is the handler redirecting requests for a rooted subtree made without its trailing slash, such as
+// /images for the subtree /images/, to the subtree. It is the default redirect handler of muxes, which can be
+// replaced with Mux.SetRedirectHandler.
+//
+// The redirect is based on the original request URL so that it is correct for nested muxes. The path is sanitized
+// as it comes from the client: requests whose path or query have control characters, which could be used to inject
+// headers, are rejected with 400 Bad Request, and leading slashes and backslashes are collapsed such that the
+// location cannot be read by browsers as a protocol-relative URL pointing to another host.
+type SubtreeRedirect struct {
+	// BaseURL is the externally visible origin of the mux prefixed to the location, such as one set with the BaseURL
+	// option. The location is relative when it is nil.
+	BaseURL *url.URL
+	// Code is the status of the redirect. It defaults to 301 Moved Permanently.
+	Code int
+}
+
+func (h SubtreeRedirect) ServeHTTPx(w http.ResponseWriter, r *http.Request, c Context) {
+	target := c.requestURL(r)
+
+	path, ok := redirectPath(target.Path)
+	if !ok || hasControlCharacters(target.RawQuery) {
+		writeStatus(w, c, http.StatusBadRequest)
+		return
+	}
+
+	location := (&url.URL{Path: path + "/", RawQuery: target.RawQuery}).String()
+	if h.BaseURL != nil {
+		location = h.BaseURL.String() + location
+	}
+
+	code := h.Code
+	if code == 0 {
+		code = http.StatusMovedPermanently
+	}
+
+	w.Header().Set("Location", location)
+	w.WriteHeader(code)
+}
+
+// redirectPath returns the path made safe to redirect to: leading slashes and backslashes, which browsers read as
+// the start of a host, are collapsed into a single slash. It returns false if the path has control characters.
+func redirectPath(path string) (string, bool) {
+	if hasControlCharacters(path) {
+		return "", false
+	}
+	return "/" + strings.TrimLeft(path, `/\`), true
+}
+
+func hasControlCharacters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRedirectHandler sets the handler of requests for a rooted subtree made without its trailing slash. It defaults
+// to a SubtreeRedirect using the mux's BaseURL.
+func (m *Mux) SetRedirectHandler(handler Handler) {
+	m.redirectHandler = handler
+}
+
+func (m *Mux) SetRedirectHandlerFunc(handler HandlerFunc) {
+	m.SetRedirectHandler(handler)
+}
+
+// SafeRedirect redirects the request to target if it stays on the request's host, or goes to one of the allowed
+// hosts, and returns ErrUnsafeRedirect without writing a response otherwise. It is meant for targets taken from the
+// request, such as the URL to return to after logging in, which would otherwise make an open redirect.
+//
+// Relative targets such as /account are safe. Absolute and protocol-relative targets such as //example.com/account
+// are refused unless their host is the request's host or an allowed host, and their scheme is http or https. Allowed
+// hosts are host names, matched case insensitively, or patterns such as *.example.com matching subdomains. Targets
+// with credentials, backslashes or control characters are refused. GET and HEAD requests are redirected with 302
+// Found and other requests, such as the submission of a login form, with 303 See Other.
+//
+//	if err := muxter.SafeRedirect(w, r, r.FormValue("next")); err != nil {
+//		http.Redirect(w, r, "/", http.StatusSeeOther)
+//	}
+func SafeRedirect(w http.ResponseWriter, r *http.Request, target string, allowedHosts ...string) error {
+	if !safeRedirectTarget(r, target, allowedHosts) {
+		return ErrUnsafeRedirect
+	}
+
+	http.Redirect(w, r, target, redirectCode(r))
+	return nil
+}
+
+// redirectCode returns 302 Found for GET and HEAD requests and 303 See Other for other requests, such that the
+// redirect is followed with a GET request.
+func redirectCode(r *http.Request) int {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return http.StatusFound
+	}
+	return http.StatusSeeOther
+}
+
+func safeRedirectTarget(r *http.Request, target string, allowedHosts []string) bool {
+	if target == "" || strings.ContainsRune(target, '\\') || hasControlCharacters(target) {
+		return false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil || u.Opaque != "" {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		// Browsers read targets beginning with several slashes, such as ///example.com, as protocol-relative.
+		return !strings.HasPrefix(target, "//")
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if requestHost := (&url.URL{Host: r.Host}).Hostname(); strings.EqualFold(host, requestHost) {
+		return true
+	}
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix := strings.TrimPrefix(allowed, "*"); suffix != allowed {
+			if strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
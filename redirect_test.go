@@ -0,0 +1,218 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSubtreeRedirect(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux := New()
+	mux.Handle("/images/", noop)
+	mux.Handle("/my docs?/", noop)
+
+	// The nested mux redirects based on the original path, whose segments captured by the parent are controlled by
+	// the client and can be empty.
+	child := New()
+	child.Handle("/docs/", noop)
+
+	tenants := New()
+	tenants.Handle("/:tenant/:project/", StripDepth(2, child))
+
+	testcases := []struct {
+		Name             string
+		Mux              *Mux
+		Path             string
+		RawQuery         string
+		ExpectedCode     int
+		ExpectedLocation string
+	}{
+		{
+			Name:             "subtree",
+			Mux:              mux,
+			Path:             "/images",
+			RawQuery:         "size=large",
+			ExpectedCode:     301,
+			ExpectedLocation: "/images/?size=large",
+		},
+		{
+			Name:             "escaped",
+			Mux:              mux,
+			Path:             "/my docs?",
+			ExpectedCode:     301,
+			ExpectedLocation: "/my%20docs%3F/",
+		},
+		{
+			Name:             "nested",
+			Mux:              tenants,
+			Path:             "/acme/site/docs",
+			ExpectedCode:     301,
+			ExpectedLocation: "/acme/site/docs/",
+		},
+		{
+			Name:             "protocol relative",
+			Mux:              tenants,
+			Path:             "//evil.com/docs",
+			ExpectedCode:     301,
+			ExpectedLocation: "/evil.com/docs/",
+		},
+		{
+			Name:             "backslash",
+			Mux:              tenants,
+			Path:             `/\evil.com/x/docs`,
+			ExpectedCode:     301,
+			ExpectedLocation: "/evil.com/x/docs/",
+		},
+		{
+			Name:         "header injection",
+			Mux:          tenants,
+			Path:         "/acme/x\r\nSet-Cookie: session=evil/docs",
+			ExpectedCode: 400,
+		},
+		{
+			Name:         "control characters in query",
+			Mux:          mux,
+			Path:         "/images",
+			RawQuery:     "a=\x00",
+			ExpectedCode: 400,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.URL = &url.URL{Path: tc.Path, RawQuery: tc.RawQuery}
+
+			w := httptest.NewRecorder()
+			tc.Mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tc.ExpectedLocation {
+				t.Errorf("expected location %q but got %q", tc.ExpectedLocation, location)
+			}
+		})
+	}
+}
+
+func TestSetRedirectHandler(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+	base, _ := url.Parse("https://example.com")
+
+	child := New()
+	child.Handle("/api/docs/", noop)
+
+	mux := New()
+	mux.SetRedirectHandler(SubtreeRedirect{BaseURL: base, Code: http.StatusPermanentRedirect})
+	mux.Handle("/images/", noop)
+	mux.Handle("/api/", child)
+
+	testcases := []struct {
+		Path             string
+		ExpectedLocation string
+	}{
+		{Path: "/images", ExpectedLocation: "https://example.com/images/"},
+		{Path: "/api/docs", ExpectedLocation: "https://example.com/api/docs/"},
+	}
+
+	for _, tc := range testcases {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", tc.Path, nil))
+
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: expected status 308 but got %d", tc.Path, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != tc.ExpectedLocation {
+			t.Errorf("%s: expected location %q but got %q", tc.Path, tc.ExpectedLocation, location)
+		}
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	allowed := []string{"accounts.example.com", "*.example.org"}
+
+	testcases := []struct {
+		Name             string
+		Method           string
+		Target           string
+		ExpectedCode     int
+		ExpectedLocation string
+		ExpectedErr      error
+	}{
+		{
+			Name:             "relative",
+			Method:           "GET",
+			Target:           "/account?tab=billing",
+			ExpectedCode:     302,
+			ExpectedLocation: "/account?tab=billing",
+		},
+		{
+			Name:             "after form submission",
+			Method:           "POST",
+			Target:           "/account",
+			ExpectedCode:     303,
+			ExpectedLocation: "/account",
+		},
+		{
+			Name:             "same host",
+			Method:           "GET",
+			Target:           "https://App.example.com/account",
+			ExpectedCode:     302,
+			ExpectedLocation: "https://App.example.com/account",
+		},
+		{
+			Name:             "allowed host",
+			Method:           "GET",
+			Target:           "https://accounts.example.com:8443/login",
+			ExpectedCode:     302,
+			ExpectedLocation: "https://accounts.example.com:8443/login",
+		},
+		{
+			Name:             "allowed subdomain",
+			Method:           "GET",
+			Target:           "//docs.example.org/guide",
+			ExpectedCode:     302,
+			ExpectedLocation: "//docs.example.org/guide",
+		},
+		{Name: "off host", Method: "GET", Target: "https://evil.com/account", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "protocol relative", Method: "GET", Target: "//evil.com/account", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "triple slash", Method: "GET", Target: "///evil.com", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "backslash", Method: "GET", Target: `/\evil.com`, ExpectedErr: ErrUnsafeRedirect},
+		{Name: "credentials", Method: "GET", Target: "https://app.example.com@evil.com/", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "javascript", Method: "GET", Target: "javascript:alert(1)", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "scheme without host", Method: "GET", Target: "https:/evil.com", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "control characters", Method: "GET", Target: "/account\r\nSet-Cookie: a=b", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "tab", Method: "GET", Target: "/\t/evil.com", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "apex of allowed subdomains", Method: "GET", Target: "https://example.org/", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "suffix of allowed host", Method: "GET", Target: "https://evilaccounts.example.com/", ExpectedErr: ErrUnsafeRedirect},
+		{Name: "empty", Method: "GET", Target: "", ExpectedErr: ErrUnsafeRedirect},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.Method, "http://app.example.com:8080/login", nil)
+			w := httptest.NewRecorder()
+
+			if err := SafeRedirect(w, r, tc.Target, allowed...); err != tc.ExpectedErr {
+				t.Fatalf("expected error %v but got %v", tc.ExpectedErr, err)
+			}
+			if tc.ExpectedErr != nil {
+				if w.Code != 200 || w.Body.Len() != 0 || len(w.Header()) != 0 {
+					t.Errorf("expected no response to be written but got status %d", w.Code)
+				}
+				return
+			}
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tc.ExpectedLocation {
+				t.Errorf("expected location %q but got %q", tc.ExpectedLocation, location)
+			}
+		})
+	}
+}
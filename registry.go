@@ -0,0 +1,367 @@
+package muxter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option types of an OptionSchema.
+const (
+	OptionString   = "string"
+	OptionBool     = "boolean"
+	OptionNumber   = "number"
+	OptionInteger  = "integer"
+	OptionDuration = "duration"
+	OptionStrings  = "strings"
+	OptionObject   = "object"
+)
+
+// OptionSchema describes an option of a registered middleware.
+type OptionSchema struct {
+	Name string `json:"name"`
+	// Type is one of the option types: string, boolean, number, integer, duration (a string such as "1m30s"),
+	// strings (a list of strings) or object.
+	Type        string      `json:"type"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// MiddlewareConstructor builds a middleware from options validated against its schema, with the defaults of the
+// schema applied.
+type MiddlewareConstructor func(options MiddlewareOptions) (Middleware, error)
+
+// MiddlewareRegistry holds middleware constructors by name with the schemas of their options, such that middleware
+// chains described as data, as in a Config or through an admin API, are validated before any middleware is built.
+// It is a part of a Registry used with FromConfig.
+type MiddlewareRegistry struct {
+	mu      sync.RWMutex
+	entries map[string]registeredMiddleware
+}
+
+type registeredMiddleware struct {
+	schema      []OptionSchema
+	constructor MiddlewareConstructor
+}
+
+// NewMiddlewareRegistry returns a registry of the built-in middlewares, registered by their Identify ids:
+//
+//	muxter.Recover     no options
+//	muxter.Compress    no options
+//	muxter.Decompress  sniff (boolean)
+//	muxter.RateLimit   rate (number, required), burst (integer)
+//	muxter.CORS        allowOrigin (string), allowCredentials (boolean), allowMethods, allowHeaders and
+//	                   exposeHeaders (strings), maxAge (duration)
+func NewMiddlewareRegistry() *MiddlewareRegistry {
+	reg := &MiddlewareRegistry{entries: map[string]registeredMiddleware{}}
+
+	reg.Register("muxter.Recover", nil, func(MiddlewareOptions) (Middleware, error) {
+		return Recover(nil), nil
+	})
+	reg.Register("muxter.Compress", nil, func(MiddlewareOptions) (Middleware, error) {
+		return Compress(), nil
+	})
+	reg.Register("muxter.Decompress", []OptionSchema{
+		{Name: "sniff", Type: OptionBool, Description: "decompress gzip bodies sent without a Content-Encoding"},
+	}, func(options MiddlewareOptions) (Middleware, error) {
+		return DecompressWith(DecompressOptions{Sniff: options.Bool("sniff")}), nil
+	})
+	reg.Register("muxter.RateLimit", []OptionSchema{
+		{Name: "rate", Type: OptionNumber, Required: true, Description: "requests per second allowed per client"},
+		{Name: "burst", Type: OptionInteger, Description: "requests allowed at once per client"},
+	}, func(options MiddlewareOptions) (Middleware, error) {
+		if options.Number("rate") <= 0 {
+			return nil, fmt.Errorf("rate must be positive")
+		}
+		limiter := NewRateLimiter(RateLimitOptions{Rate: options.Number("rate"), Burst: options.Int("burst")})
+		return limiter.Middleware, nil
+	})
+	reg.Register("muxter.CORS", []OptionSchema{
+		{Name: "allowOrigin", Type: OptionString, Default: "*"},
+		{Name: "allowCredentials", Type: OptionBool},
+		{Name: "allowMethods", Type: OptionStrings},
+		{Name: "allowHeaders", Type: OptionStrings},
+		{Name: "exposeHeaders", Type: OptionStrings},
+		{Name: "maxAge", Type: OptionDuration},
+	}, func(options MiddlewareOptions) (Middleware, error) {
+		return CORS(AccessControlOptions{
+			AllowOrigin:      options.String("allowOrigin"),
+			AllowCredentials: options.Bool("allowCredentials"),
+			AllowMethods:     options.Strings("allowMethods"),
+			AllowHeaders:     options.Strings("allowHeaders"),
+			ExposeHeaders:    options.Strings("exposeHeaders"),
+			MaxAge:           options.Duration("maxAge"),
+		}), nil
+	})
+
+	return reg
+}
+
+// Register registers the constructor of a middleware with the schema of its options. It panics if the name is
+// already registered or if the schema is invalid.
+func (reg *MiddlewareRegistry) Register(name string, schema []OptionSchema, constructor MiddlewareConstructor) {
+	if name == "" || constructor == nil {
+		panic("muxter: middleware must be registered with a name and a constructor")
+	}
+	for _, option := range schema {
+		if !validOptionType(option.Type) {
+			panic(fmt.Sprintf("muxter: option %s of middleware %s has invalid type %q", option.Name, name, option.Type))
+		}
+		if option.Default != nil {
+			if err := checkOption(option, option.Default); err != nil {
+				panic(fmt.Sprintf("muxter: invalid default of option %s of middleware %s: %v", option.Name, name, err))
+			}
+		}
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.entries[name]; ok {
+		panic(fmt.Sprintf("muxter: middleware %s is already registered", name))
+	}
+	reg.entries[name] = registeredMiddleware{schema: schema, constructor: constructor}
+}
+
+// Names returns the names of the registered middlewares, sorted.
+func (reg *MiddlewareRegistry) Names() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	names := make([]string, 0, len(reg.entries))
+	for name := range reg.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schema returns the schema of the options of the middleware with the name, and false if it is not registered.
+func (reg *MiddlewareRegistry) Schema(name string) ([]OptionSchema, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	entry, ok := reg.entries[name]
+	return entry.schema, ok
+}
+
+// ValidateMiddleware validates the options of the middleware with the name against its schema: required options
+// must be set, options must have the type of their schema and unknown options are an error.
+func (reg *MiddlewareRegistry) ValidateMiddleware(name string, options MiddlewareOptions) error {
+	_, err := reg.resolve(name, options)
+	return err
+}
+
+// ValidateChain validates every middleware of the chain, returning a *ChainError listing all the problems found.
+func (reg *MiddlewareRegistry) ValidateChain(chain []MiddlewareConfig) error {
+	var problems []string
+	for _, config := range chain {
+		if err := reg.ValidateMiddleware(config.Name, config.Options); err != nil {
+			problems = append(problems, fmt.Sprintf("middleware %s: %v", config.Name, err))
+		}
+	}
+	if problems != nil {
+		return &ChainError{Problems: problems}
+	}
+	return nil
+}
+
+// Middleware validates the options and builds the middleware with the name.
+func (reg *MiddlewareRegistry) Middleware(name string, options MiddlewareOptions) (Middleware, error) {
+	entry, err := reg.resolve(name, options)
+	if err != nil {
+		return nil, err
+	}
+	return entry.constructor(withDefaults(entry.schema, options))
+}
+
+// Chain validates the whole chain and then builds its middlewares, in order.
+func (reg *MiddlewareRegistry) Chain(chain []MiddlewareConfig) ([]Middleware, error) {
+	if err := reg.ValidateChain(chain); err != nil {
+		return nil, err
+	}
+	middlewares := make([]Middleware, 0, len(chain))
+	for _, config := range chain {
+		middleware, err := reg.Middleware(config.Name, config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", config.Name, err)
+		}
+		middlewares = append(middlewares, middleware)
+	}
+	return middlewares, nil
+}
+
+func (reg *MiddlewareRegistry) resolve(name string, options MiddlewareOptions) (registeredMiddleware, error) {
+	reg.mu.RLock()
+	entry, ok := reg.entries[name]
+	reg.mu.RUnlock()
+
+	if !ok {
+		return registeredMiddleware{}, fmt.Errorf("unknown middleware %q", name)
+	}
+
+	var problems []string
+	for _, option := range entry.schema {
+		value, ok := options[option.Name]
+		if !ok || value == nil {
+			if option.Required {
+				problems = append(problems, fmt.Sprintf("option %s is required", option.Name))
+			}
+			continue
+		}
+		if err := checkOption(option, value); err != nil {
+			problems = append(problems, fmt.Sprintf("option %s %v", option.Name, err))
+		}
+	}
+	for key := range options {
+		if !hasOption(entry.schema, key) {
+			problems = append(problems, fmt.Sprintf("unknown option %s", key))
+		}
+	}
+	if problems != nil {
+		sort.Strings(problems)
+		return registeredMiddleware{}, fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return entry, nil
+}
+
+// ChainError lists the problems of a middleware chain found by MiddlewareRegistry.ValidateChain.
+type ChainError struct {
+	Problems []string
+}
+
+func (err *ChainError) Error() string {
+	return "invalid middleware chain: " + strings.Join(err.Problems, "; ")
+}
+
+func validOptionType(typ string) bool {
+	switch typ {
+	case OptionString, OptionBool, OptionNumber, OptionInteger, OptionDuration, OptionStrings, OptionObject:
+		return true
+	}
+	return false
+}
+
+func hasOption(schema []OptionSchema, name string) bool {
+	for _, option := range schema {
+		if option.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOption checks that the value, as decoded from JSON or YAML, has the type of the option.
+func checkOption(option OptionSchema, value interface{}) error {
+	ok := false
+	switch option.Type {
+	case OptionString:
+		_, ok = value.(string)
+	case OptionBool:
+		_, ok = value.(bool)
+	case OptionNumber:
+		_, ok = toFloat(value)
+	case OptionInteger:
+		f, isNumber := toFloat(value)
+		ok = isNumber && f == math.Trunc(f)
+	case OptionDuration:
+		if s, isString := value.(string); isString {
+			_, err := time.ParseDuration(s)
+			ok = err == nil
+		}
+	case OptionStrings:
+		_, ok = toStrings(value)
+	case OptionObject:
+		_, ok = value.(map[string]interface{})
+	}
+	if !ok {
+		return fmt.Errorf("must be of type %s but got %v", option.Type, value)
+	}
+	return nil
+}
+
+func withDefaults(schema []OptionSchema, options MiddlewareOptions) MiddlewareOptions {
+	result := make(MiddlewareOptions, len(options))
+	for key, value := range options {
+		result[key] = value
+	}
+	for _, option := range schema {
+		if _, ok := result[option.Name]; !ok && option.Default != nil {
+			result[option.Name] = option.Default
+		}
+	}
+	return result
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toStrings(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			values[i] = s
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// String returns the string option with the name, or the empty string.
+func (o MiddlewareOptions) String(name string) string {
+	s, _ := o[name].(string)
+	return s
+}
+
+// Bool returns the boolean option with the name, or false.
+func (o MiddlewareOptions) Bool(name string) bool {
+	b, _ := o[name].(bool)
+	return b
+}
+
+// Number returns the number option with the name, or zero.
+func (o MiddlewareOptions) Number(name string) float64 {
+	f, _ := toFloat(o[name])
+	return f
+}
+
+// Int returns the integer option with the name, or zero.
+func (o MiddlewareOptions) Int(name string) int {
+	return int(o.Number(name))
+}
+
+// Strings returns the strings option with the name, or nil.
+func (o MiddlewareOptions) Strings(name string) []string {
+	values, _ := toStrings(o[name])
+	return values
+}
+
+// Duration returns the duration option with the name, or zero.
+func (o MiddlewareOptions) Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(o.String(name))
+	return d
+}
@@ -0,0 +1,188 @@
+package muxter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareRegistry(t *testing.T) {
+	reg := NewMiddlewareRegistry()
+
+	var received MiddlewareOptions
+	reg.Register("header", []OptionSchema{
+		{Name: "name", Type: OptionString, Required: true},
+		{Name: "value", Type: OptionString, Default: "1"},
+		{Name: "ttl", Type: OptionDuration},
+		{Name: "methods", Type: OptionStrings},
+		{Name: "weight", Type: OptionInteger},
+	}, func(options MiddlewareOptions) (Middleware, error) {
+		received = options
+		return func(h Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.Header().Set(options.String("name"), options.String("value"))
+				h.ServeHTTPx(w, r, c)
+			})
+		}, nil
+	})
+
+	cases := []struct {
+		Name          string
+		Options       MiddlewareOptions
+		ExpectedError string
+	}{
+		{
+			Name:    "valid",
+			Options: MiddlewareOptions{"name": "X-Test", "ttl": "1m", "methods": []interface{}{"GET"}, "weight": float64(2)},
+		},
+		{
+			Name:          "missing required",
+			Options:       MiddlewareOptions{},
+			ExpectedError: "option name is required",
+		},
+		{
+			Name:          "wrong types",
+			Options:       MiddlewareOptions{"name": 1, "ttl": "soon", "weight": 1.5},
+			ExpectedError: "option name must be of type string but got 1, option ttl must be of type duration but got soon, option weight must be of type integer but got 1.5",
+		},
+		{
+			Name:          "unknown option",
+			Options:       MiddlewareOptions{"name": "X-Test", "colour": "blue"},
+			ExpectedError: "unknown option colour",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := reg.ValidateMiddleware("header", tc.Options)
+			if tc.ExpectedError == "" {
+				if err != nil {
+					t.Fatalf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.ExpectedError {
+				t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+			}
+		})
+	}
+
+	middlewares, err := reg.Chain([]MiddlewareConfig{
+		{Name: "muxter.Recover"},
+		{Name: "header", Options: MiddlewareOptions{"name": "X-Test", "ttl": "1m"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.String("value") != "1" || received.Duration("ttl") != time.Minute {
+		t.Errorf("expected defaults and typed options but got %v", received)
+	}
+
+	handler := WithMiddleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) { panic("boom") }), middlewares...)
+	w := httptest.NewRecorder()
+	handler.ServeHTTPx(w, httptest.NewRequest("GET", "/", nil), Context{})
+	if w.Code != 500 || w.Header().Get("X-Test") != "1" {
+		t.Errorf("expected recovered response with header but got %d and %q", w.Code, w.Header().Get("X-Test"))
+	}
+
+	_, err = reg.Chain([]MiddlewareConfig{
+		{Name: "muxter.RateLimit"},
+		{Name: "missing"},
+		{Name: "muxter.Recover"},
+	})
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) {
+		t.Fatalf("expected a chain error but got %v", err)
+	}
+	expected := []string{
+		"middleware muxter.RateLimit: option rate is required",
+		`middleware missing: unknown middleware "missing"`,
+	}
+	if !reflect.DeepEqual(chainErr.Problems, expected) {
+		t.Errorf("expected problems %q but got %q", expected, chainErr.Problems)
+	}
+
+	if schema, ok := reg.Schema("muxter.RateLimit"); !ok || len(schema) != 2 {
+		t.Errorf("expected schema of built-in rate limiter but got %v", schema)
+	}
+}
+
+func TestMiddlewareRegistryRegister(t *testing.T) {
+	constructor := func(MiddlewareOptions) (Middleware, error) { return nil, nil }
+
+	cases := []struct {
+		Name     string
+		Register func(reg *MiddlewareRegistry)
+	}{
+		{
+			Name:     "duplicate",
+			Register: func(reg *MiddlewareRegistry) { reg.Register("muxter.Recover", nil, constructor) },
+		},
+		{
+			Name: "invalid type",
+			Register: func(reg *MiddlewareRegistry) {
+				reg.Register("custom", []OptionSchema{{Name: "x", Type: "float"}}, constructor)
+			},
+		},
+		{
+			Name: "invalid default",
+			Register: func(reg *MiddlewareRegistry) {
+				reg.Register("custom", []OptionSchema{{Name: "x", Type: OptionBool, Default: "yes"}}, constructor)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registration to panic")
+				}
+			}()
+			tc.Register(NewMiddlewareRegistry())
+		})
+	}
+}
+
+func TestFromConfigMiddlewareRegistry(t *testing.T) {
+	registry := Registry{
+		Handlers: map[string]Handler{
+			"ok": HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {}),
+		},
+		MiddlewareRegistry: NewMiddlewareRegistry(),
+	}
+
+	_, err := FromConfig(Config{
+		Middlewares: []MiddlewareConfig{{Name: "muxter.CORS", Options: MiddlewareOptions{"maxAge": 10}}},
+		Routes: []RouteConfig{{
+			Pattern:     "/",
+			Handler:     "ok",
+			Middlewares: []MiddlewareConfig{{Name: "muxter.RateLimit", Options: MiddlewareOptions{"rate": "fast"}}},
+		}},
+	}, registry)
+
+	expected := "muxter: invalid middleware chain: middleware muxter.CORS: option maxAge must be of type duration but got 10; " +
+		"route /: middleware muxter.RateLimit: option rate must be of type number but got fast"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected error %q but got %v", expected, err)
+	}
+
+	mux, err := FromConfig(Config{
+		Middlewares: []MiddlewareConfig{{Name: "muxter.CORS", Options: MiddlewareOptions{"allowOrigin": "https://example.com"}}},
+		Routes:      []RouteConfig{{Pattern: "/", Handler: "ok"}},
+	}, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "https://example.com" {
+		t.Errorf("expected CORS middleware from the registry but got allow origin %q", origin)
+	}
+}
@@ -0,0 +1,280 @@
+package muxter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RecordedRequest is a request served by a mux, recorded by the RecordRequests middleware to be replayed with
+// Mux.Replay.
+type RecordedRequest struct {
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	Target string      `json:"target"`
+	Host   string      `json:"host,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	// BodyTruncated reports that the body was larger than the recorder's limit and was not recorded.
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+	// Pattern is the pattern of the route that served the request.
+	Pattern string        `json:"pattern,omitempty"`
+	Status  int           `json:"status"`
+	Latency time.Duration `json:"latency"`
+}
+
+// RecordOptions configures RecordRequests.
+type RecordOptions struct {
+	// MaxBodySize is the size of the largest request body recorded. It defaults to 64KiB.
+	MaxBodySize int
+	// Redact is called with the recorded requests before they are written, to remove secrets and personal data.
+	// By default the Authorization, Cookie and Proxy-Authorization headers are removed.
+	Redact func(rec *RecordedRequest)
+}
+
+// RecordRequests is a middleware recording the requests it serves, with their status and latency, as JSON lines
+// to dst, such that they can be replayed against a new version of the mux with Mux.Replay. Request bodies are read
+// up to the limit before the handler is called.
+//
+//	mux.Use(muxter.RecordRequests(file, muxter.RecordOptions{}))
+func RecordRequests(dst io.Writer, opts RecordOptions) Middleware {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	if opts.Redact == nil {
+		opts.Redact = func(rec *RecordedRequest) {
+			for _, key := range []string{"Authorization", "Cookie", "Proxy-Authorization"} {
+				rec.Header.Del(key)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(dst)
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			rec := RecordedRequest{
+				Time:   time.Now(),
+				Method: r.Method,
+				Target: c.requestURL(r).RequestURI(),
+				Host:   r.Host,
+				Header: r.Header.Clone(),
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				body, err := io.ReadAll(io.LimitReader(r.Body, int64(opts.MaxBodySize)+1))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+				if err != nil || len(body) > opts.MaxBodySize {
+					rec.BodyTruncated = true
+				} else {
+					rec.Body = body
+				}
+			}
+
+			proxy := &responseProxy{ResponseWriter: w}
+			h.ServeHTTPx(proxy, r, c)
+
+			rec.Latency = time.Since(rec.Time)
+			rec.Status = proxy.Code()
+			rec.Pattern = c.Pattern()
+			opts.Redact(&rec)
+
+			mu.Lock()
+			defer mu.Unlock()
+			encoder.Encode(rec)
+		})
+	}
+}
+
+// ReadRecordedRequests reads the JSON lines written by RecordRequests.
+func ReadRecordedRequests(src io.Reader) ([]RecordedRequest, error) {
+	var requests []RecordedRequest
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec RecordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("muxter: invalid recorded request on line %d: %w", line, err)
+		}
+		requests = append(requests, rec)
+	}
+	return requests, scanner.Err()
+}
+
+// ReplayOptions configures Mux.Replay.
+type ReplayOptions struct {
+	// Speed scales the time between the recorded requests: 1 replays them at the pace they were recorded, 10 ten
+	// times faster. Requests are replayed as fast as possible if it is zero.
+	Speed float64
+	// Concurrency is the maximum number of requests in flight. It defaults to 16.
+	Concurrency int
+	// Prepare is called with the requests before they are replayed, for example to add credentials removed from
+	// the recording.
+	Prepare func(r *http.Request)
+}
+
+// ReplayReport compares the replayed responses with the recorded ones, by the pattern of the routes that served
+// the recorded requests.
+type ReplayReport struct {
+	Patterns []PatternReplay `json:"patterns"`
+}
+
+// PatternReplay compares the responses of the requests of a route.
+type PatternReplay struct {
+	Pattern  string `json:"pattern"`
+	Requests int    `json:"requests"`
+	// StatusChanges counts the responses whose status differs from the recorded status, such as {"200 -> 500": 3}.
+	StatusChanges map[string]int `json:"statusChanges,omitempty"`
+	// Errors is the number of requests that could not be replayed, such as requests whose handler panicked.
+	Errors int `json:"errors,omitempty"`
+	// RecordedLatency and ReplayedLatency are the mean latencies of the recorded and replayed requests.
+	RecordedLatency time.Duration `json:"recordedLatency"`
+	ReplayedLatency time.Duration `json:"replayedLatency"`
+}
+
+// Changed reports whether any response of the route changed status or could not be replayed.
+func (p PatternReplay) Changed() bool {
+	return len(p.StatusChanges) > 0 || p.Errors > 0
+}
+
+// Replay serves the recorded requests through the mux in-process, at the pace they were recorded scaled by the
+// speed option, and reports the status and latency differences with the recording per pattern. It is meant for load
+// tests and for regression comparisons of a new version of the mux against traffic recorded in production. Replay
+// stops early and returns the error of the context if it is done.
+//
+//	requests, err := muxter.ReadRecordedRequests(file)
+//	...
+//	report, err := mux.Replay(ctx, requests, muxter.ReplayOptions{Speed: 10})
+func (m *Mux) Replay(ctx context.Context, requests []RecordedRequest, opts ReplayOptions) (ReplayReport, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 16
+	}
+
+	type result struct {
+		recorded *RecordedRequest
+		status   int
+		latency  time.Duration
+		err      error
+	}
+
+	results := make([]result, len(requests))
+	slots := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	var err error
+
+replay:
+	for i := range requests {
+		rec := &requests[i]
+
+		if opts.Speed > 0 && i > 0 {
+			offset := time.Duration(float64(rec.Time.Sub(requests[0].Time)) / opts.Speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					err = ctx.Err()
+					break replay
+				}
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break replay
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-slots; wg.Done() }()
+
+			rec := &requests[i]
+			results[i].recorded = rec
+
+			r, err := http.NewRequestWithContext(ctx, rec.Method, rec.Target, bytes.NewReader(rec.Body))
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			if rec.Header != nil {
+				r.Header = rec.Header.Clone()
+			}
+			if rec.Host != "" {
+				r.Host = rec.Host
+			}
+			r.RequestURI = r.URL.RequestURI()
+			if opts.Prepare != nil {
+				opts.Prepare(r)
+			}
+
+			began := time.Now()
+			resp, err := m.dispatch(r)
+			results[i].latency = time.Since(began)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			results[i].status = resp.StatusCode
+		}(i)
+	}
+
+	wg.Wait()
+
+	type totals struct {
+		replay           PatternReplay
+		recorded, served time.Duration
+	}
+	byPattern := map[string]*totals{}
+
+	for _, res := range results {
+		if res.recorded == nil {
+			continue
+		}
+		t, ok := byPattern[res.recorded.Pattern]
+		if !ok {
+			t = &totals{replay: PatternReplay{Pattern: res.recorded.Pattern}}
+			byPattern[res.recorded.Pattern] = t
+		}
+		t.replay.Requests++
+		t.recorded += res.recorded.Latency
+		t.served += res.latency
+
+		if res.err != nil {
+			t.replay.Errors++
+			continue
+		}
+		if res.status != res.recorded.Status {
+			if t.replay.StatusChanges == nil {
+				t.replay.StatusChanges = map[string]int{}
+			}
+			t.replay.StatusChanges[fmt.Sprintf("%d -> %d", res.recorded.Status, res.status)]++
+		}
+	}
+
+	var report ReplayReport
+	for _, t := range byPattern {
+		n := time.Duration(t.replay.Requests)
+		t.replay.RecordedLatency = t.recorded / n
+		t.replay.ReplayedLatency = t.served / n
+		report.Patterns = append(report.Patterns, t.replay)
+	}
+	sort.Slice(report.Patterns, func(i, j int) bool { return report.Patterns[i].Pattern < report.Patterns[j].Pattern })
+
+	return report, err
+}
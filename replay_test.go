@@ -0,0 +1,127 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var recording bytes.Buffer
+
+	v1 := New()
+	v1.Use(RecordRequests(&recording, RecordOptions{MaxBodySize: 16}))
+	v1.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {})
+	v1.HandleFunc("/authors", func(w http.ResponseWriter, r *http.Request, c Context) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "name=ursula" && len(body) < 16 {
+			t.Errorf("expected handler to read the recorded body but got %q", body)
+		}
+		w.WriteHeader(201)
+	})
+
+	serve := func(method, target, body string) {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Authorization", "secret")
+		v1.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	serve("GET", "/books/1", "")
+	serve("GET", "/books/2?full=1", "")
+	serve("POST", "/authors", "name=ursula")
+	serve("POST", "/authors", "name=a very long name")
+
+	requests, err := ReadRecordedRequests(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("expected 4 recorded requests but got %d", len(requests))
+	}
+	if rec := requests[1]; rec.Target != "/books/2?full=1" || rec.Pattern != "/books/:id" || rec.Status != 200 {
+		t.Errorf("unexpected recorded request: %+v", rec)
+	}
+	if rec := requests[2]; string(rec.Body) != "name=ursula" || rec.Status != 201 {
+		t.Errorf("expected recorded body and status but got %+v", rec)
+	}
+	if !requests[3].BodyTruncated || requests[3].Body != nil {
+		t.Errorf("expected body over the limit not to be recorded")
+	}
+	for _, rec := range requests {
+		if rec.Header.Get("Authorization") != "" {
+			t.Errorf("expected authorization header to be redacted")
+		}
+	}
+
+	var replayedBodies []string
+	v2 := New()
+	v2.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.Param("id") == "2" {
+			w.WriteHeader(500)
+		}
+	})
+	v2.HandleFunc("/authors", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if r.Header.Get("Authorization") != "replay" {
+			t.Errorf("expected prepared request")
+		}
+		body, _ := io.ReadAll(r.Body)
+		replayedBodies = append(replayedBodies, string(body))
+		w.WriteHeader(201)
+	})
+
+	report, err := v2.Replay(context.Background(), requests, ReplayOptions{
+		Concurrency: 1,
+		Prepare:     func(r *http.Request) { r.Header.Set("Authorization", "replay") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Patterns) != 2 {
+		t.Fatalf("expected 2 patterns but got %+v", report.Patterns)
+	}
+	authors, books := report.Patterns[0], report.Patterns[1]
+	if authors.Pattern != "/authors" || authors.Requests != 2 || authors.Changed() {
+		t.Errorf("unexpected authors replay: %+v", authors)
+	}
+	if books.Pattern != "/books/:id" || books.Requests != 2 || !reflect.DeepEqual(books.StatusChanges, map[string]int{"200 -> 500": 1}) {
+		t.Errorf("unexpected books replay: %+v", books)
+	}
+	if !reflect.DeepEqual(replayedBodies, []string{"name=ursula", ""}) {
+		t.Errorf("unexpected replayed bodies: %q", replayedBodies)
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	now := time.Now()
+	requests := []RecordedRequest{
+		{Time: now, Method: "GET", Target: "/", Pattern: "/", Status: 200},
+		{Time: now.Add(200 * time.Millisecond), Method: "GET", Target: "/", Pattern: "/", Status: 200},
+	}
+
+	start := time.Now()
+	if _, err := mux.Replay(context.Background(), requests, ReplayOptions{Speed: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed >= 200*time.Millisecond {
+		t.Errorf("expected replay at 4x speed to take about 50ms rather than the recorded 200ms but took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report, err := mux.Replay(ctx, requests, ReplayOptions{Speed: 1})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected replay to stop with the context but got %v", err)
+	}
+	if len(report.Patterns) != 1 || report.Patterns[0].Requests != 1 {
+		t.Errorf("expected report of the requests replayed before the deadline but got %+v", report.Patterns)
+	}
+}
@@ -0,0 +1,330 @@
+package muxter
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonNumberType    = reflect.TypeOf(json.Number(""))
+)
+
+// Returns is a registration option that declares the type of the JSON documents the route responds with, such that
+// the shape of its responses is recorded in its RouteInfo for documentation and validated by ValidateResponses.
+// The shape follows the encoding rules of encoding/json. Maps accept any field, which is written as .* in the shape,
+// and the values of interfaces and of types implementing json.Marshaler have the type any.
+//
+//	mux.GetFunc("/books/:id", getBook, muxter.Returns(Book{}))
+func Returns(v interface{}) Middleware {
+	rt := reflect.TypeOf(v)
+	if rt == nil {
+		panic("muxter: Returns requires a value but got nil")
+	}
+	shape := Shape{}
+	shape.addGoType("$", rt, nil)
+
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Response = shape
+	})
+}
+
+// addGoType adds the paths and types of the JSON encoding of values of the type at the path. Parents are the struct
+// types being added, whose recursive fields have the type any.
+func (shape Shape) addGoType(path string, t reflect.Type, parents []reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		shape.addType(path, "null")
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		shape.addType(path, "string")
+		return
+	case t == jsonNumberType:
+		shape.addType(path, "number")
+		return
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		shape[path] = "any"
+		return
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		shape.addType(path, "string")
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		shape.addType(path, "boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		shape.addType(path, "number")
+	case reflect.String:
+		shape.addType(path, "string")
+	case reflect.Slice:
+		shape.addType(path, "null")
+		if t.Elem().Kind() == reflect.Uint8 {
+			shape.addType(path, "string")
+			return
+		}
+		shape.addType(path, "array")
+		shape.addGoType(path+"[]", t.Elem(), parents)
+	case reflect.Array:
+		shape.addType(path, "array")
+		shape.addGoType(path+"[]", t.Elem(), parents)
+	case reflect.Map:
+		shape.addType(path, "null")
+		shape.addType(path, "object")
+		shape.addGoType(path+".*", t.Elem(), parents)
+	case reflect.Struct:
+		for _, parent := range parents {
+			if parent == t {
+				shape[path] = "any"
+				return
+			}
+		}
+		shape.addType(path, "object")
+		shape.addFields(path, t, append(parents, t))
+	default:
+		shape[path] = "any"
+	}
+}
+
+// addFields adds the fields of the struct type at the path, including the fields of embedded structs.
+func (shape Shape) addFields(path string, t reflect.Type, parents []reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				shape.addFields(path, ft, parents)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if options == "string" || strings.Contains(options, ",string") {
+			shape.addType(path+"."+name, "string")
+			continue
+		}
+		shape.addGoType(path+"."+name, field.Type, parents)
+	}
+}
+
+// validate reports the values of the JSON document decoded with encoding/json that do not match the shape: fields
+// that are not part of the shape and values of other types than the shape's at their path.
+func (shape Shape) validate(path string, v interface{}, report func(path, expected, actual string)) {
+	expected := shape[path]
+	if expected == "any" {
+		return
+	}
+	actual := jsonType(v)
+	if !hasType(expected, actual) {
+		report(path, expected, actual)
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := path + "." + key
+			if _, ok := shape[field]; !ok {
+				if _, ok := shape[path+".*"]; ok {
+					field = path + ".*"
+				}
+			}
+			shape.validate(field, v[key], report)
+		}
+	case []interface{}:
+		for _, value := range v {
+			shape.validate(path+"[]", value, report)
+		}
+	}
+}
+
+// jsonType returns the type of the JSON value decoded with encoding/json, as written in shapes.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// ResponseValidationOptions configures the ValidateResponses middleware.
+type ResponseValidationOptions struct {
+	// Schemas are the schemas of routes by method and pattern, such as "GET /books/:id", typically recorded by a
+	// SchemaRecorder. They are used for the routes that do not declare their responses with Returns.
+	Schemas map[string]RouteSchema
+	// MaxBodySize is the size in bytes of the largest response validated. It defaults to 1MiB.
+	MaxBodySize int
+	// Fail answers responses that do not match their schema with 500 Internal Server Error instead of sending them.
+	// Responses are then buffered until they are validated or exceed MaxBodySize.
+	Fail bool
+	// OnViolation is called for every path of a response that does not match its schema. It defaults to logging
+	// the violation with the standard logger.
+	OnViolation func(r *http.Request, violation SchemaDrift)
+}
+
+// ValidateResponses validates the successful JSON responses of routes against the shape of their responses, as
+// declared with Returns or given in the options, and reports the fields that are not part of the shape and the
+// values of other types. It catches contract violations before clients do, and is meant for tests and staging
+// environments as decoding every response is costly. Responses of routes without a shape are not validated.
+//
+//	mux.Use(muxter.ValidateResponses(muxter.ResponseValidationOptions{Fail: true}))
+//	mux.GetFunc("/books/:id", getBook, muxter.Returns(Book{}))
+func ValidateResponses(opts ResponseValidationOptions) Middleware {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 1 << 20
+	}
+	onViolation := opts.OnViolation
+	if onViolation == nil {
+		onViolation = func(r *http.Request, violation SchemaDrift) {
+			expected := violation.Expected
+			if expected == "" {
+				expected = "no value"
+			}
+			log.Printf("muxter: %s: response %s is %s but the schema expects %s", violation.Route, violation.Path, violation.Actual, expected)
+		}
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			route := r.Method + " " + c.Pattern()
+			var shape Shape
+			if c.route != nil {
+				shape = c.route.Response
+			}
+			if shape == nil {
+				shape = opts.Schemas[route].Response
+			}
+			if shape == nil || c.Pattern() == "" || r.Method == http.MethodHead {
+				h.ServeHTTPx(w, r, c)
+				return
+			}
+
+			vw := &validationWriter{ResponseWriter: w, limit: opts.MaxBodySize, buffer: opts.Fail}
+			h.ServeHTTPx(vw, r, c)
+
+			var violations []SchemaDrift
+			if code := vw.code; (code == 0 || code >= 200 && code < 300) && !vw.overflow && vw.body.Len() > 0 && isJSON(w.Header().Get("Content-Type")) {
+				var doc interface{}
+				if err := json.Unmarshal(vw.body.Bytes(), &doc); err != nil {
+					violations = append(violations, SchemaDrift{Route: route, Direction: "response", Path: "$", Expected: shape["$"], Actual: "invalid JSON"})
+				} else {
+					seen := map[string]bool{}
+					shape.validate("$", doc, func(path, expected, actual string) {
+						if !seen[path+" "+actual] {
+							seen[path+" "+actual] = true
+							violations = append(violations, SchemaDrift{Route: route, Direction: "response", Path: path, Expected: expected, Actual: actual})
+						}
+					})
+				}
+			}
+
+			for _, violation := range violations {
+				onViolation(r, violation)
+			}
+			if !vw.buffer || vw.overflow {
+				return
+			}
+			if len(violations) > 0 {
+				writeStatus(w, c, http.StatusInternalServerError)
+				return
+			}
+			vw.flush()
+		})
+	}
+}
+
+// validationWriter captures the body of the response up to a limit. When buffering, the response is held back until
+// it is flushed, which happens on its own once the body exceeds the limit.
+type validationWriter struct {
+	http.ResponseWriter
+	code     int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+	buffer   bool
+}
+
+func (w *validationWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *validationWriter) WriteHeader(code int) {
+	if code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+	if !w.buffer {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *validationWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.overflow {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.body.Len()+len(p) > w.limit {
+		if w.buffer {
+			w.flush()
+		}
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return w.ResponseWriter.Write(p)
+	}
+	w.body.Write(p)
+	if w.buffer {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// flush writes the buffered response.
+func (w *validationWriter) flush() {
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+	w.ResponseWriter.Write(w.body.Bytes())
+}
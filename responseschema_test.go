@@ -0,0 +1,184 @@
+package muxter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReturns(t *testing.T) {
+	type Author struct {
+		Name string `json:"name"`
+	}
+	type Node struct {
+		Children []Node `json:"children"`
+	}
+	type Book struct {
+		Author
+		ID        int             `json:"id,string"`
+		Title     string          `json:"title"`
+		Published *time.Time      `json:"published,omitempty"`
+		Tags      []string        `json:"tags"`
+		Cover     []byte          `json:"cover"`
+		Meta      map[string]int  `json:"meta"`
+		Extra     interface{}     `json:"extra"`
+		Raw       json.RawMessage `json:"raw"`
+		Tree      Node            `json:"tree"`
+		Ignored   string          `json:"-"`
+		internal  string
+		Fields    map[string]Author `json:"fields"`
+	}
+
+	mux := New()
+	mux.GetFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {}, Returns(&Book{}))
+
+	expected := Shape{
+		"$":                 "null|object",
+		"$.name":            "string",
+		"$.id":              "string",
+		"$.title":           "string",
+		"$.published":       "null|string",
+		"$.tags":            "array|null",
+		"$.tags[]":          "string",
+		"$.cover":           "null|string",
+		"$.meta":            "null|object",
+		"$.meta.*":          "number",
+		"$.extra":           "any",
+		"$.raw":             "any",
+		"$.tree":            "object",
+		"$.tree.children":   "array|null",
+		"$.tree.children[]": "any",
+		"$.fields":          "null|object",
+		"$.fields.*":        "object",
+		"$.fields.*.name":   "string",
+	}
+	if shape := mux.Routes()[0].Response; !reflect.DeepEqual(shape, expected) {
+		t.Errorf("expected shape %v but got %v", expected, shape)
+	}
+}
+
+func TestValidateResponses(t *testing.T) {
+	type Book struct {
+		ID    int               `json:"id"`
+		Title string            `json:"title"`
+		Tags  []string          `json:"tags"`
+		Meta  map[string]string `json:"meta"`
+	}
+
+	respond := func(body string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, body)
+		}
+	}
+
+	cases := []struct {
+		Name               string
+		Body               string
+		Fail               bool
+		ExpectedViolations []SchemaDrift
+		ExpectedCode       int
+	}{
+		{
+			Name:         "valid",
+			Body:         `{"id":1,"title":"Dune","tags":["scifi"],"meta":{"isbn":"0441013597"}}`,
+			ExpectedCode: 200,
+		},
+		{
+			Name: "violations",
+			Body: `{"id":"1","title":"Dune","tags":["scifi",2,3],"year":1965}`,
+			ExpectedViolations: []SchemaDrift{
+				{Route: "GET /books/:id", Direction: "response", Path: "$.id", Expected: "number", Actual: "string"},
+				{Route: "GET /books/:id", Direction: "response", Path: "$.tags[]", Expected: "string", Actual: "number"},
+				{Route: "GET /books/:id", Direction: "response", Path: "$.year", Actual: "number"},
+			},
+			ExpectedCode: 200,
+		},
+		{
+			Name: "fail",
+			Body: `{"id":1,"meta":{"pages":412}}`,
+			Fail: true,
+			ExpectedViolations: []SchemaDrift{
+				{Route: "GET /books/:id", Direction: "response", Path: "$.meta.*", Expected: "string", Actual: "number"},
+			},
+			ExpectedCode: 500,
+		},
+		{
+			Name:         "fail valid",
+			Body:         `{"id":1}`,
+			Fail:         true,
+			ExpectedCode: 200,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var violations []SchemaDrift
+			mux := New()
+			mux.Use(ValidateResponses(ResponseValidationOptions{
+				Fail:        tc.Fail,
+				OnViolation: func(r *http.Request, violation SchemaDrift) { violations = append(violations, violation) },
+			}))
+			mux.Get("/books/:id", respond(tc.Body), Returns(Book{}))
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/books/1", nil))
+
+			if !reflect.DeepEqual(violations, tc.ExpectedViolations) {
+				t.Errorf("expected violations %+v but got %+v", tc.ExpectedViolations, violations)
+			}
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedCode == 200 && w.Body.String() != tc.Body {
+				t.Errorf("expected body %s but got %s", tc.Body, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("schemas", func(t *testing.T) {
+		var violations []SchemaDrift
+		mux := New()
+		mux.Use(ValidateResponses(ResponseValidationOptions{
+			Schemas:     map[string]RouteSchema{"GET /authors": {Response: Shape{"$": "array", "$[]": "string"}}},
+			OnViolation: func(r *http.Request, violation SchemaDrift) { violations = append(violations, violation) },
+		}))
+		mux.Get("/authors", respond(`["Herbert",{}]`))
+		mux.Get("/untyped", respond(`{"anything":true}`))
+
+		for _, target := range []string{"/authors", "/untyped"} {
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+		}
+
+		expected := []SchemaDrift{{Route: "GET /authors", Direction: "response", Path: "$[]", Expected: "string", Actual: "object"}}
+		if !reflect.DeepEqual(violations, expected) {
+			t.Errorf("expected violations %+v but got %+v", expected, violations)
+		}
+	})
+
+	t.Run("oversized responses are sent unvalidated", func(t *testing.T) {
+		mux := New()
+		mux.Use(ValidateResponses(ResponseValidationOptions{
+			Fail:        true,
+			MaxBodySize: 8,
+			OnViolation: func(r *http.Request, violation SchemaDrift) { t.Errorf("unexpected violation %+v", violation) },
+		}))
+		mux.GetFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"id":`)
+			io.WriteString(w, `"not a number"}`)
+		}, Returns(Book{}))
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/books/1", nil))
+		if w.Code != http.StatusCreated || !strings.HasSuffix(w.Body.String(), `"not a number"}`) {
+			t.Errorf("expected the response to be sent but got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
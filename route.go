@@ -0,0 +1,176 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	// Pattern is the pattern the route was registered with.
+	Pattern string `json:"pattern"`
+	// Name is the name given to the route with the Name registration option.
+	Name string `json:"name,omitempty"`
+	// Description documents what the route does, given with the Describe registration option.
+	Description string `json:"description,omitempty"`
+	// CallSite is the file:line of the code that registered the route when the mux records call sites.
+	CallSite string `json:"callSite,omitempty"`
+	// Methods are the HTTP methods accepted by the route when it is registered with method guards, such as with
+	// Mux.Get or a MethodHandler. It is nil if the route accepts any method.
+	Methods []string `json:"methods,omitempty"`
+	// Tags are the tags of the route given with the Tags registration option or Mux.Tag.
+	Tags []string `json:"tags,omitempty"`
+	// Warmup are the targets of the synthetic requests made for the route by Mux.Warmup.
+	Warmup []string `json:"warmup,omitempty"`
+	// Preload are the assets declared with the Preload registration option.
+	Preload []PreloadAsset `json:"preload,omitempty"`
+	// ContentEncodings are the request content encodings accepted by the route, declared with the ContentEncodings
+	// registration option.
+	ContentEncodings []string `json:"contentEncodings,omitempty"`
+	// Produces are the media types of the route's responses, declared with the Produces registration option.
+	Produces []string `json:"produces,omitempty"`
+	// Parameters are the request values bound by the route, declared with the Binds registration option.
+	Parameters []ParameterInfo `json:"parameters,omitempty"`
+	// Response is the shape of the JSON documents the route responds with, declared with the Returns registration
+	// option.
+	Response Shape `json:"response,omitempty"`
+	// StrictQuery reports whether the route rejects query parameters missing from its Parameters, declared with the
+	// StrictQuery registration option.
+	StrictQuery bool `json:"strictQuery,omitempty"`
+	// Scopes are the scopes required to access the route, declared with the RequireScopes registration option.
+	Scopes []string `json:"scopes,omitempty"`
+	// CORSMaxAge overrides the Access-Control-Max-Age of the route's preflight responses, declared with the
+	// CORSMaxAge registration option.
+	CORSMaxAge time.Duration `json:"corsMaxAge,omitempty"`
+	// Cost is the relative cost of serving the route budgeted by the LoadShedder, declared with the Cost
+	// registration option.
+	Cost int `json:"cost,omitempty"`
+	// Deprecation describes the deprecation of the route, declared with the Deprecated registration option.
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+	// Matchers describe the conditions requests must satisfy to match the route, declared with registration options
+	// such as MatchHeader.
+	Matchers []string `json:"matchers,omitempty"`
+	// MaintenanceExempt reports whether the route is served in maintenance mode, declared with the
+	// MaintenanceExempt registration option.
+	MaintenanceExempt bool `json:"maintenanceExempt,omitempty"`
+	// Canonical is the canonical URL policy of the route, declared with the Canonical registration option.
+	Canonical *CanonicalPolicy `json:"canonical,omitempty"`
+	// Disabled reports whether the route was disabled at the time the RouteInfo was returned by Mux.Routes.
+	Disabled bool `json:"disabled,omitempty"`
+
+	match []func(*http.Request) bool
+}
+
+// Routes returns the routes registered on the mux sorted by pattern. Routes of nested muxes are not included.
+// Encoded as JSON the result can be used as a route manifest by the muxter-gen tool.
+func (m *Mux) Routes() []RouteInfo {
+	return m.routes(func(*value) bool { return true })
+}
+
+// routes returns the routes whose values satisfy match sorted by pattern.
+func (m *Mux) routes(match func(*value) bool) []RouteInfo {
+	var routes []RouteInfo
+	m.root.walk(func(v *value) {
+		if v.route != nil && match(v) {
+			route := *v.route
+			route.Disabled = v.disabled.Load() != 0
+			routes = append(routes, route)
+		}
+	})
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
+
+// routeOption is the handler produced by registration options. It is never served: when the mux composes the
+// middlewares of a route it applies the option to the route's RouteInfo and discards the wrapper.
+type routeOption struct {
+	Handler
+	apply func(*RouteInfo)
+}
+
+func registrationOption(apply func(*RouteInfo)) Middleware {
+	return func(h Handler) Handler {
+		return routeOption{Handler: h, apply: apply}
+	}
+}
+
+// Name is a registration option that names a route so that URLs can be generated for it using Mux.URL and
+// Mux.Links. Names are scoped to the mux the route is registered on and must be unique within it.
+//
+//	mux.HandleFunc("/books/:id", getBook, muxter.Name("book"))
+func Name(name string) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Name = name
+	})
+}
+
+// Describe is a registration option that documents what the route does. The description is part of the route's
+// RouteInfo, listed by Mux.Routes and the admin handler for documentation and OpenAPI generation, of the not found
+// page of the Debug mode, and of the panics of conflicting registrations, such that the route table describes itself.
+//
+//	mux.GetFunc("/books/:id", getBook, muxter.Describe("Returns a book by id"))
+func Describe(description string) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Description = description
+	})
+}
+
+// describedAs formats the description of the route for inclusion in error messages.
+func describedAs(route *RouteInfo) string {
+	if route == nil || route.Description == "" {
+		return ""
+	}
+	return fmt.Sprintf(" as %q", route.Description)
+}
+
+// allowMethods marks h as accepting only the methods. When it is composed as a route's middleware the methods are
+// recorded in the route's RouteInfo, intersected with the methods of other guards on the route.
+func allowMethods(h Handler, methods ...string) Handler {
+	return routeOption{
+		Handler: h,
+		apply: func(ri *RouteInfo) {
+			if ri.Methods == nil {
+				ri.Methods = methods
+				return
+			}
+			var intersection []string
+			for _, method := range ri.Methods {
+				for _, allowed := range methods {
+					if method == allowed {
+						intersection = append(intersection, method)
+					}
+				}
+			}
+			ri.Methods = append([]string{}, intersection...)
+		},
+	}
+}
+
+// callSite returns the file:line of the first caller outside of the muxter package.
+func callSite() string {
+	pc := make([]uintptr, 16)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/davidmdm/muxter.") || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// at formats a call site for inclusion in error messages.
+func at(callSite string) string {
+	if callSite == "" {
+		return ""
+	}
+	return " at " + callSite
+}
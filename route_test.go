@@ -0,0 +1,35 @@
+package muxter
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	mux := New()
+
+	noop := func(w http.ResponseWriter, r *http.Request, c Context) {}
+
+	mux.HandleFunc("/users/:id", noop, Name("user"))
+	mux.HandleFunc("/", noop)
+	mux.HandleFunc("/users", noop, Describe("Lists users"))
+	mux.HandleFunc(`/assets/#dir:\d+/*file`, noop)
+	mux.GetFunc("/posts", noop)
+	mux.Handle("/posts/:id", MethodHandler{GET: HandlerFunc(noop), DELETE: HandlerFunc(noop)})
+	mux.HandleFunc("/drafts", noop, mux.Method("POST"))
+
+	expected := []RouteInfo{
+		{Pattern: "/"},
+		{Pattern: `/assets/#dir:\d+/*file`},
+		{Pattern: "/drafts", Methods: []string{"POST"}},
+		{Pattern: "/posts", Methods: []string{"GET", "HEAD"}},
+		{Pattern: "/posts/:id", Methods: []string{"GET", "DELETE"}},
+		{Pattern: "/users", Description: "Lists users"},
+		{Pattern: "/users/:id", Name: "user"},
+	}
+
+	if routes := mux.Routes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("expected routes %+v but got %+v", expected, routes)
+	}
+}
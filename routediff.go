@@ -0,0 +1,222 @@
+package muxter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RouteDiff is the difference between two route tables, as returned by DiffRoutes. Routes are identified by their
+// pattern, such that a route whose pattern changes is removed and added.
+type RouteDiff struct {
+	Added   []RouteInfo   `json:"added,omitempty"`
+	Removed []RouteInfo   `json:"removed,omitempty"`
+	Changed []RouteChange `json:"changed,omitempty"`
+}
+
+// RouteChange is a route whose contract changed between two route tables.
+type RouteChange struct {
+	Pattern string        `json:"pattern"`
+	Old     RouteInfo     `json:"old"`
+	New     RouteInfo     `json:"new"`
+	Fields  []FieldChange `json:"fields"`
+}
+
+// FieldChange is the change of a field of a route, formatted as text.
+type FieldChange struct {
+	// Field is the JSON name of the RouteInfo field, such as "methods" or "scopes".
+	Field string `json:"field"`
+	// Removed are the values of the field that are no longer present.
+	Removed []string `json:"removed,omitempty"`
+	// Added are the values of the field that are new.
+	Added []string `json:"added,omitempty"`
+}
+
+// DiffRoutes returns the routes added, removed and changed from the old to the new route table, such as the results
+// of Mux.Routes of two releases. Changes of the name, methods, content encodings, produced media types, parameters,
+// scopes and matchers of routes are reported; call sites, tags, warmup targets, preloads and whether routes are
+// disabled are not part of the HTTP surface and are ignored.
+//
+//	diff := muxter.DiffRoutes(previous, mux.Routes())
+//	if diff.Breaking() {
+//		log.Fatalf("breaking API changes:\n%s", diff)
+//	}
+func DiffRoutes(old, new []RouteInfo) RouteDiff {
+	oldRoutes := make(map[string]RouteInfo, len(old))
+	for _, route := range old {
+		oldRoutes[route.Pattern] = route
+	}
+	newRoutes := make(map[string]RouteInfo, len(new))
+	for _, route := range new {
+		newRoutes[route.Pattern] = route
+	}
+
+	var diff RouteDiff
+	for _, route := range new {
+		previous, ok := oldRoutes[route.Pattern]
+		if !ok {
+			diff.Added = append(diff.Added, route)
+			continue
+		}
+		if fields := diffRoute(previous, route); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, RouteChange{Pattern: route.Pattern, Old: previous, New: route, Fields: fields})
+		}
+	}
+	for _, route := range old {
+		if _, ok := newRoutes[route.Pattern]; !ok {
+			diff.Removed = append(diff.Removed, route)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Pattern < diff.Added[j].Pattern })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Pattern < diff.Removed[j].Pattern })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Pattern < diff.Changed[j].Pattern })
+
+	return diff
+}
+
+func diffRoute(old, new RouteInfo) []FieldChange {
+	fields := []struct {
+		name     string
+		old, new []string
+	}{
+		{"name", nonEmpty(old.Name), nonEmpty(new.Name)},
+		{"methods", methodsOf(old), methodsOf(new)},
+		{"contentEncodings", old.ContentEncodings, new.ContentEncodings},
+		{"produces", old.Produces, new.Produces},
+		{"parameters", parameterStrings(old.Parameters), parameterStrings(new.Parameters)},
+		{"scopes", old.Scopes, new.Scopes},
+		{"matchers", old.Matchers, new.Matchers},
+	}
+
+	var changes []FieldChange
+	for _, field := range fields {
+		removed, added := difference(field.old, field.new), difference(field.new, field.old)
+		if len(removed) > 0 || len(added) > 0 {
+			changes = append(changes, FieldChange{Field: field.name, Removed: removed, Added: added})
+		}
+	}
+	return changes
+}
+
+// Empty reports whether the route tables are the same.
+func (d RouteDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Breaking reports whether the changes can break existing clients: routes or methods were removed, or parameters,
+// scopes, matchers or content encodings changed in ways that can reject requests that used to be accepted.
+func (d RouteDiff) Breaking() bool {
+	if len(d.Removed) > 0 {
+		return true
+	}
+	for _, change := range d.Changed {
+		for _, field := range change.Fields {
+			switch field.Field {
+			case "name", "produces":
+			case "methods", "contentEncodings":
+				if len(field.Removed) > 0 {
+					return true
+				}
+			case "parameters":
+				for _, param := range field.Added {
+					if strings.HasSuffix(param, " required") {
+						return true
+					}
+				}
+				if len(field.Removed) > 0 && len(field.Added) > 0 {
+					return true
+				}
+			default:
+				if len(field.Added) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// String formats the diff as a changelog of the HTTP surface, suitable for release notes.
+func (d RouteDiff) String() string {
+	var b strings.Builder
+	if len(d.Added) > 0 {
+		b.WriteString("Added:\n")
+		for _, route := range d.Added {
+			fmt.Fprintf(&b, "  %s\n", routeTitle(route))
+		}
+	}
+	if len(d.Removed) > 0 {
+		b.WriteString("Removed:\n")
+		for _, route := range d.Removed {
+			fmt.Fprintf(&b, "  %s\n", routeTitle(route))
+		}
+	}
+	if len(d.Changed) > 0 {
+		b.WriteString("Changed:\n")
+		for _, change := range d.Changed {
+			fmt.Fprintf(&b, "  %s\n", change.Pattern)
+			for _, field := range change.Fields {
+				for _, value := range field.Removed {
+					fmt.Fprintf(&b, "    - %s: %s\n", field.Field, value)
+				}
+				for _, value := range field.Added {
+					fmt.Fprintf(&b, "    + %s: %s\n", field.Field, value)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+func routeTitle(route RouteInfo) string {
+	if len(route.Methods) == 0 {
+		return route.Pattern
+	}
+	return strings.Join(route.Methods, ", ") + " " + route.Pattern
+}
+
+// methodsOf returns the methods of the route, where routes accepting any method accept "*".
+func methodsOf(route RouteInfo) []string {
+	if route.Methods == nil {
+		return []string{"*"}
+	}
+	return route.Methods
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+func parameterStrings(params []ParameterInfo) []string {
+	values := make([]string, len(params))
+	for i, param := range params {
+		values[i] = param.In + " " + param.Name + " " + param.Type
+		if param.Default != "" {
+			values[i] += " default=" + param.Default
+		}
+		if param.Required {
+			values[i] += " required"
+		}
+	}
+	return values
+}
+
+// difference returns the values of a that are not in b, sorted.
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, value := range b {
+		in[value] = true
+	}
+	var result []string
+	for _, value := range a {
+		if !in[value] {
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
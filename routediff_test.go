@@ -0,0 +1,123 @@
+package muxter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRoutes(t *testing.T) {
+	old := []RouteInfo{
+		{Pattern: "/books", Methods: []string{"GET", "POST"}},
+		{Pattern: "/books/:id", Methods: []string{"GET"}, Name: "book"},
+		{Pattern: "/authors", CallSite: "main.go:10"},
+		{Pattern: "/legacy"},
+	}
+	new := []RouteInfo{
+		{Pattern: "/books", Methods: []string{"GET"}},
+		{Pattern: "/books/:id", Methods: []string{"GET"}, Name: "book", Scopes: []string{"books:read"}},
+		{Pattern: "/authors", CallSite: "main.go:12", Tags: []string{"authors"}},
+		{Pattern: "/v2/books", Methods: []string{"GET"}},
+	}
+
+	diff := DiffRoutes(old, new)
+
+	if !reflect.DeepEqual(diff.Added, new[3:]) {
+		t.Errorf("expected added %v but got %v", new[3:], diff.Added)
+	}
+	if !reflect.DeepEqual(diff.Removed, old[3:]) {
+		t.Errorf("expected removed %v but got %v", old[3:], diff.Removed)
+	}
+
+	expectedChanges := map[string][]FieldChange{
+		"/books":     {{Field: "methods", Removed: []string{"POST"}}},
+		"/books/:id": {{Field: "scopes", Added: []string{"books:read"}}},
+	}
+	if len(diff.Changed) != len(expectedChanges) {
+		t.Fatalf("expected %d changed routes but got %v", len(expectedChanges), diff.Changed)
+	}
+	for _, change := range diff.Changed {
+		if !reflect.DeepEqual(change.Fields, expectedChanges[change.Pattern]) {
+			t.Errorf("expected changes of %s to be %v but got %v", change.Pattern, expectedChanges[change.Pattern], change.Fields)
+		}
+	}
+
+	expected := `Added:
+  GET /v2/books
+Removed:
+  /legacy
+Changed:
+  /books
+    - methods: POST
+  /books/:id
+    + scopes: books:read
+`
+	if actual := diff.String(); actual != expected {
+		t.Errorf("expected changelog:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestRouteDiffBreaking(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Old      RouteInfo
+		New      RouteInfo
+		Breaking bool
+	}{
+		{
+			Name: "unchanged",
+			Old:  RouteInfo{Pattern: "/", Methods: []string{"GET"}},
+			New:  RouteInfo{Pattern: "/", Methods: []string{"GET"}},
+		},
+		{
+			Name: "added method",
+			Old:  RouteInfo{Pattern: "/", Methods: []string{"GET"}},
+			New:  RouteInfo{Pattern: "/", Methods: []string{"GET", "PUT"}},
+		},
+		{
+			Name:     "restricted to methods",
+			Old:      RouteInfo{Pattern: "/"},
+			New:      RouteInfo{Pattern: "/", Methods: []string{"GET"}},
+			Breaking: true,
+		},
+		{
+			Name: "added optional parameter",
+			Old:  RouteInfo{Pattern: "/"},
+			New:  RouteInfo{Pattern: "/", Parameters: []ParameterInfo{{Name: "page", In: "query", Type: "integer"}}},
+		},
+		{
+			Name:     "added required parameter",
+			Old:      RouteInfo{Pattern: "/"},
+			New:      RouteInfo{Pattern: "/", Parameters: []ParameterInfo{{Name: "page", In: "query", Type: "integer", Required: true}}},
+			Breaking: true,
+		},
+		{
+			Name:     "changed parameter type",
+			Old:      RouteInfo{Pattern: "/", Parameters: []ParameterInfo{{Name: "page", In: "query", Type: "string"}}},
+			New:      RouteInfo{Pattern: "/", Parameters: []ParameterInfo{{Name: "page", In: "query", Type: "integer"}}},
+			Breaking: true,
+		},
+		{
+			Name:     "added matcher",
+			Old:      RouteInfo{Pattern: "/"},
+			New:      RouteInfo{Pattern: "/", Matchers: []string{"header X-Version=2"}},
+			Breaking: true,
+		},
+		{
+			Name: "renamed",
+			Old:  RouteInfo{Pattern: "/", Name: "home"},
+			New:  RouteInfo{Pattern: "/", Name: "index"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if breaking := DiffRoutes([]RouteInfo{tc.Old}, []RouteInfo{tc.New}).Breaking(); breaking != tc.Breaking {
+				t.Errorf("expected breaking to be %v but got %v", tc.Breaking, breaking)
+			}
+		})
+	}
+
+	if !DiffRoutes([]RouteInfo{{Pattern: "/"}}, nil).Breaking() {
+		t.Errorf("expected removing a route to be breaking")
+	}
+}
@@ -0,0 +1,277 @@
+package muxter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Shape is the shape of JSON documents: the JSON types observed at each path of the documents, where paths are
+// written as $ for the document, $.field for the field of an object and $[] for the elements of an array. Types are
+// object, array, string, number, boolean and null, joined with | when several were observed at a path. Shapes declared
+// with Returns may also have the type any, which matches every value.
+type Shape map[string]string
+
+// RouteSchema is the shape of the JSON requests and successful responses of a route.
+type RouteSchema struct {
+	Request  Shape `json:"request,omitempty"`
+	Response Shape `json:"response,omitempty"`
+}
+
+// SchemaDrift is a difference between the observed shape of a route's documents and its baseline.
+type SchemaDrift struct {
+	// Route is the method and pattern of the route, such as "GET /books/:id".
+	Route string
+	// Direction is either "request" or "response".
+	Direction string
+	// Path is the path of the value that drifted.
+	Path string
+	// Expected are the types of the baseline at the path, empty if the path is not in the baseline.
+	Expected string
+	// Actual is the type observed at the path.
+	Actual string
+}
+
+// SchemaOptions configures a SchemaRecorder.
+type SchemaOptions struct {
+	// SampleRate is the ratio of requests whose bodies are recorded. It defaults to 0.01.
+	SampleRate float64
+	// MaxBodySize is the size in bytes of the largest body recorded. It defaults to 64KiB.
+	MaxBodySize int
+	// Baseline are the schemas of the routes by method and pattern, such as "GET /books/:id", typically the
+	// schemas recorded by a previous release.
+	Baseline map[string]RouteSchema
+	// OnDrift is called the first time a path or type not in the baseline is observed.
+	OnDrift func(drift SchemaDrift)
+	// Rand draws the sampled requests. It defaults to the global source of math/rand.
+	Rand Rand
+}
+
+// SchemaRecorder records the shapes of the JSON bodies of a sample of the requests and successful responses of the
+// routes it is used on, and reports their drift from a baseline: new fields and type changes. It helps API owners
+// detect accidental changes to the contract of their routes.
+type SchemaRecorder struct {
+	opts SchemaOptions
+
+	mu      sync.Mutex
+	schemas map[string]RouteSchema
+}
+
+// NewSchemaRecorder returns a SchemaRecorder configured by opts.
+//
+//	recorder := muxter.NewSchemaRecorder(muxter.SchemaOptions{Baseline: baseline, OnDrift: report})
+//	mux.Use(recorder.Middleware)
+func NewSchemaRecorder(opts SchemaOptions) *SchemaRecorder {
+	if opts.SampleRate == 0 {
+		opts.SampleRate = 0.01
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	if opts.Rand == nil {
+		opts.Rand = systemRand{}
+	}
+	return &SchemaRecorder{opts: opts, schemas: map[string]RouteSchema{}}
+}
+
+// Middleware is a middleware recording the shapes of the JSON bodies of a sample of the handler's requests and
+// responses.
+func (s *SchemaRecorder) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.Pattern() == "" || s.opts.Rand.Float64() >= s.opts.SampleRate {
+			h.ServeHTTPx(w, r, c)
+			return
+		}
+		route := r.Method + " " + c.Pattern()
+
+		if r.Body != nil && r.Body != http.NoBody && isJSON(r.Header.Get("Content-Type")) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, int64(s.opts.MaxBodySize)+1))
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			if err == nil && len(body) <= s.opts.MaxBodySize {
+				s.observe(route, "request", body)
+			}
+		}
+
+		sw := &schemaWriter{ResponseWriter: w, limit: s.opts.MaxBodySize}
+		h.ServeHTTPx(sw, r, c)
+
+		if code := sw.code; (code == 0 || code >= 200 && code < 300) && !sw.overflow && isJSON(w.Header().Get("Content-Type")) {
+			s.observe(route, "response", sw.body.Bytes())
+		}
+	})
+}
+
+// Schemas returns the schemas observed for every route by method and pattern. They can be saved as the baseline of
+// a later release.
+func (s *SchemaRecorder) Schemas() map[string]RouteSchema {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schemas := make(map[string]RouteSchema, len(s.schemas))
+	for route, schema := range s.schemas {
+		schemas[route] = RouteSchema{Request: copyShape(schema.Request), Response: copyShape(schema.Response)}
+	}
+	return schemas
+}
+
+func (s *SchemaRecorder) observe(route, direction string, body []byte) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return
+	}
+	observed := Shape{}
+	observed.add("$", doc)
+
+	baseline := s.opts.Baseline[route]
+	expectedShape := baseline.Request
+	if direction == "response" {
+		expectedShape = baseline.Response
+	}
+
+	var drifts []SchemaDrift
+
+	s.mu.Lock()
+	schema := s.schemas[route]
+	shape := schema.Request
+	if direction == "response" {
+		shape = schema.Response
+	}
+	if shape == nil {
+		shape = Shape{}
+	}
+
+	for path, typ := range observed {
+		if hasType(shape[path], typ) {
+			continue
+		}
+		shape.addType(path, typ)
+
+		if s.opts.Baseline != nil && !hasType(expectedShape[path], typ) {
+			drifts = append(drifts, SchemaDrift{
+				Route:     route,
+				Direction: direction,
+				Path:      path,
+				Expected:  expectedShape[path],
+				Actual:    typ,
+			})
+		}
+	}
+
+	if direction == "response" {
+		schema.Response = shape
+	} else {
+		schema.Request = shape
+	}
+	s.schemas[route] = schema
+	s.mu.Unlock()
+
+	if s.opts.OnDrift != nil {
+		sort.Slice(drifts, func(i, j int) bool { return drifts[i].Path < drifts[j].Path })
+		for _, drift := range drifts {
+			s.opts.OnDrift(drift)
+		}
+	}
+}
+
+// add adds the paths and types of the JSON value decoded with encoding/json at the path.
+func (shape Shape) add(path string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		shape.addType(path, "object")
+		for key, value := range v {
+			shape.add(path+"."+key, value)
+		}
+	case []interface{}:
+		shape.addType(path, "array")
+		for _, value := range v {
+			shape.add(path+"[]", value)
+		}
+	case string:
+		shape.addType(path, "string")
+	case float64:
+		shape.addType(path, "number")
+	case bool:
+		shape.addType(path, "boolean")
+	case nil:
+		shape.addType(path, "null")
+	}
+}
+
+func (shape Shape) addType(path, typ string) {
+	existing := shape[path]
+	if existing == "" {
+		shape[path] = typ
+		return
+	}
+	if hasType(existing, typ) {
+		return
+	}
+	types := append(strings.Split(existing, "|"), typ)
+	sort.Strings(types)
+	shape[path] = strings.Join(types, "|")
+}
+
+func hasType(types, typ string) bool {
+	for _, t := range strings.Split(types, "|") {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func copyShape(shape Shape) Shape {
+	if shape == nil {
+		return nil
+	}
+	cpy := make(Shape, len(shape))
+	for path, typ := range shape {
+		cpy[path] = typ
+	}
+	return cpy
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// schemaWriter captures the body of the response up to a limit.
+type schemaWriter struct {
+	http.ResponseWriter
+	code     int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *schemaWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *schemaWriter) WriteHeader(code int) {
+	if w.code == 0 && code >= 200 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *schemaWriter) Write(p []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(p) > w.limit {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
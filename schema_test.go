@@ -0,0 +1,97 @@
+package muxter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaRecorder(t *testing.T) {
+	var drifts []SchemaDrift
+
+	recorder := NewSchemaRecorder(SchemaOptions{
+		SampleRate: 1,
+		Baseline: map[string]RouteSchema{
+			"POST /books/:id": {
+				Request:  Shape{"$": "object", "$.title": "string"},
+				Response: Shape{"$": "object", "$.id": "number", "$.title": "string", "$.tags": "array", "$.tags[]": "string"},
+			},
+		},
+		OnDrift: func(drift SchemaDrift) { drifts = append(drifts, drift) },
+	})
+
+	mux := New()
+	mux.Use(recorder.Middleware)
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		body, _ := io.ReadAll(r.Body)
+		var book map[string]interface{}
+		json.Unmarshal(body, &book)
+
+		if _, ok := book["title"]; !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"missing title"}`))
+			return
+		}
+
+		book["id"] = c.Param("id")
+		book["tags"] = []string{"fiction"}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(book)
+	}, mux.Method("POST"))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("POST", "/books/1", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post(`{"title":"Dune","year":1965}`); !strings.Contains(w.Body.String(), `"year":1965`) {
+		t.Fatalf("expected the handler to read the whole request body but got %s", w.Body.String())
+	}
+	post(`{"title":"Dune","year":1965}`)
+	post(`{"year":1965}`)
+
+	expected := []SchemaDrift{
+		{Route: "POST /books/:id", Direction: "request", Path: "$.year", Actual: "number"},
+		{Route: "POST /books/:id", Direction: "response", Path: "$.id", Expected: "number", Actual: "string"},
+		{Route: "POST /books/:id", Direction: "response", Path: "$.year", Actual: "number"},
+	}
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("expected drifts %+v but got %+v", expected, drifts)
+	}
+
+	expectedSchema := RouteSchema{
+		Request: Shape{"$": "object", "$.title": "string", "$.year": "number"},
+		Response: Shape{
+			"$":        "object",
+			"$.id":     "string",
+			"$.title":  "string",
+			"$.year":   "number",
+			"$.tags":   "array",
+			"$.tags[]": "string",
+		},
+	}
+	if schema := recorder.Schemas()["POST /books/:id"]; !reflect.DeepEqual(schema, expectedSchema) {
+		t.Errorf("expected schema %+v but got %+v", expectedSchema, schema)
+	}
+}
+
+func TestShape(t *testing.T) {
+	shape := Shape{}
+	for _, doc := range []string{`{"a":[1,"x"],"b":null}`, `{"b":{"c":true}}`} {
+		var v interface{}
+		json.Unmarshal([]byte(doc), &v)
+		shape.add("$", v)
+	}
+
+	expected := Shape{"$": "object", "$.a": "array", "$.a[]": "number|string", "$.b": "null|object", "$.b.c": "boolean"}
+	if !reflect.DeepEqual(shape, expected) {
+		t.Errorf("expected shape %v but got %v", expected, shape)
+	}
+}
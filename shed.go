@@ -0,0 +1,260 @@
+package muxter
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cost is a registration option declaring the relative cost of serving a route, such that the LoadShedder budgets
+// expensive routes differently from cheap ones. Routes without a cost cost 1.
+//
+//	mux.HandleFunc("/reports/:id", renderReport, muxter.Cost(10))
+func Cost(cost int) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Cost = cost
+	})
+}
+
+// LoadShedderOptions configures a LoadShedder.
+type LoadShedderOptions struct {
+	// Capacity is the total cost of the requests served concurrently. It defaults to 32 per CPU available to the
+	// program, as reported by runtime.GOMAXPROCS.
+	Capacity int
+	// TagShares limits the routes with a tag to a share of the capacity, such as 0.25 for a quarter of it, such that
+	// a class of routes cannot starve the others. Routes with several limited tags are bounded by each of them.
+	TagShares map[string]float64
+	// MaxWait is how long requests wait in line for capacity before being shed. Requests are shed immediately if it
+	// is zero.
+	MaxWait time.Duration
+	// OnShed is called with the requests that are shed, for example to record a metric.
+	OnShed func(r *http.Request, c Context)
+}
+
+// LoadShedder limits the total cost of the requests served concurrently, where each request costs the Cost of its
+// route. Requests exceeding the capacity wait in line, first come first served, and are answered with 503 Service
+// Unavailable and a Retry-After header once they waited MaxWait.
+type LoadShedder struct {
+	opts LoadShedderOptions
+	// tagLimits are the capacities of the tags limited to a share of the capacity.
+	tagLimits map[string]int
+
+	mu      sync.Mutex
+	used    int
+	tagUsed map[string]int
+	waiters list.List
+}
+
+type shedWaiter struct {
+	cost  int
+	tags  []string
+	ready chan struct{}
+}
+
+// defaultCapacityPerCPU is the capacity of a LoadShedder per CPU when it is not configured.
+const defaultCapacityPerCPU = 32
+
+// NewLoadShedder returns a LoadShedder configured by opts. Its Middleware method limits the routes it is used on. It
+// panics if the capacity is negative or if a share is not within (0, 1].
+//
+//	shedder := muxter.NewLoadShedder(muxter.LoadShedderOptions{
+//		MaxWait:   time.Second,
+//		TagShares: map[string]float64{"reports": 0.25},
+//	})
+//	mux.Use(shedder.Middleware)
+func NewLoadShedder(opts LoadShedderOptions) *LoadShedder {
+	if opts.Capacity < 0 {
+		panic("muxter: load shedder capacity must not be negative")
+	}
+	if opts.Capacity == 0 {
+		opts.Capacity = defaultCapacityPerCPU * runtime.GOMAXPROCS(0)
+	}
+
+	s := &LoadShedder{opts: opts, tagLimits: map[string]int{}, tagUsed: map[string]int{}}
+	for tag, share := range opts.TagShares {
+		if !(share > 0 && share <= 1) {
+			panic(fmt.Sprintf("muxter: load shedder share of tag %s must be within (0, 1] but got %v", tag, share))
+		}
+		s.tagLimits[tag] = int(math.Max(1, math.Floor(share*float64(opts.Capacity))))
+	}
+	return s
+}
+
+// Middleware is a middleware serving requests within the capacity of the shedder.
+func (s *LoadShedder) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		cost := 1
+		if route := c.Route(); route != nil && route.Cost > 0 {
+			cost = route.Cost
+		}
+		if cost > s.opts.Capacity {
+			cost = s.opts.Capacity
+		}
+
+		var tags []string
+		if route := c.Route(); route != nil {
+			for _, tag := range route.Tags {
+				if limit, ok := s.tagLimits[tag]; ok {
+					tags = append(tags, tag)
+					if cost > limit {
+						cost = limit
+					}
+				}
+			}
+		}
+		waiter := &shedWaiter{cost: cost, tags: tags}
+
+		if !s.acquire(r.Context(), waiter) {
+			if s.opts.OnShed != nil {
+				s.opts.OnShed(r, c)
+			}
+			retryAfter := int(math.Ceil(math.Max(s.opts.MaxWait.Seconds(), 1)))
+			c.extra().events.publish(eventLimiterRejected, LimiterRejectedEvent{
+				Request:    r,
+				Pattern:    c.Pattern(),
+				Status:     http.StatusServiceUnavailable,
+				RetryAfter: time.Duration(retryAfter) * time.Second,
+			})
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeStatus(w, c, http.StatusServiceUnavailable)
+			return
+		}
+		defer s.release(waiter)
+
+		h.ServeHTTPx(w, r, c)
+	})
+}
+
+// InUse returns the total cost of the requests being served.
+func (s *LoadShedder) InUse() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used
+}
+
+// TagInUse returns the total cost of the requests being served for routes with the tag, if it is limited to a share
+// of the capacity.
+func (s *LoadShedder) TagInUse(tag string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tagUsed[tag]
+}
+
+// Capacity returns the capacity of the shedder.
+func (s *LoadShedder) Capacity() int {
+	return s.opts.Capacity
+}
+
+func (s *LoadShedder) acquire(ctx context.Context, waiter *shedWaiter) bool {
+	s.mu.Lock()
+	if s.waiters.Len() == 0 && s.fits(waiter) == fitted {
+		s.grant(waiter)
+		s.mu.Unlock()
+		return true
+	}
+
+	// Waiters held back by the share of their tags do not hold back the others, so the request may fit anyway.
+	waiter.ready = make(chan struct{})
+	elem := s.waiters.PushBack(waiter)
+	s.notify()
+	select {
+	case <-waiter.ready:
+		s.mu.Unlock()
+		return true
+	default:
+	}
+	if s.opts.MaxWait <= 0 {
+		s.waiters.Remove(elem)
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Unlock()
+
+	timer := time.NewTimer(s.opts.MaxWait)
+	defer timer.Stop()
+
+	select {
+	case <-waiter.ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		// Capacity was granted while giving up: give it back.
+		s.revoke(waiter)
+		s.notify()
+	default:
+		s.waiters.Remove(elem)
+		s.notify()
+	}
+	return false
+}
+
+func (s *LoadShedder) release(waiter *shedWaiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(waiter)
+	s.notify()
+}
+
+const (
+	fitted = iota
+	overCapacity
+	overShare
+)
+
+// fits reports whether the waiter fits in the capacity and in the shares of its tags.
+func (s *LoadShedder) fits(waiter *shedWaiter) int {
+	if s.used+waiter.cost > s.opts.Capacity {
+		return overCapacity
+	}
+	for _, tag := range waiter.tags {
+		if s.tagUsed[tag]+waiter.cost > s.tagLimits[tag] {
+			return overShare
+		}
+	}
+	return fitted
+}
+
+func (s *LoadShedder) grant(waiter *shedWaiter) {
+	s.used += waiter.cost
+	for _, tag := range waiter.tags {
+		s.tagUsed[tag] += waiter.cost
+	}
+}
+
+func (s *LoadShedder) revoke(waiter *shedWaiter) {
+	s.used -= waiter.cost
+	for _, tag := range waiter.tags {
+		s.tagUsed[tag] -= waiter.cost
+	}
+}
+
+// notify grants capacity to the waiters that fit in it, in order. Waiters held back by the share of their tags are
+// skipped, but a waiter held back by the capacity holds back those behind it.
+func (s *LoadShedder) notify() {
+	for elem := s.waiters.Front(); elem != nil; {
+		waiter, next := elem.Value.(*shedWaiter), elem.Next()
+		switch s.fits(waiter) {
+		case overCapacity:
+			return
+		case fitted:
+			s.grant(waiter)
+			s.waiters.Remove(elem)
+			close(waiter.ready)
+		}
+		elem = next
+	}
+}
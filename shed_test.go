@@ -0,0 +1,167 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLoadShedder(t *testing.T) {
+	var shed int
+	shedder := NewLoadShedder(LoadShedderOptions{
+		Capacity: 10,
+		MaxWait:  time.Second,
+		OnShed:   func(r *http.Request, c Context) { shed++ },
+	})
+
+	started, release := make(chan struct{}), make(chan struct{})
+
+	mux := New()
+	mux.Use(shedder.Middleware)
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request, c Context) {
+		started <- struct{}{}
+		<-release
+	}, Cost(10))
+	mux.HandleFunc("/cheap", func(w http.ResponseWriter, r *http.Request, c Context) {}, Cost(0))
+
+	serve := func(path string) <-chan int {
+		done := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			done <- w.Code
+		}()
+		return done
+	}
+
+	report := serve("/reports")
+	<-started
+	if used := shedder.InUse(); used != 10 {
+		t.Fatalf("expected report to use the whole capacity but used %d", used)
+	}
+
+	cheap := serve("/cheap")
+	select {
+	case code := <-cheap:
+		t.Fatalf("expected cheap request to wait for capacity but it was answered with %d", code)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	if code := <-report; code != 200 {
+		t.Errorf("expected report to be served but got %d", code)
+	}
+	if code := <-cheap; code != 200 {
+		t.Errorf("expected waiting cheap request to be served but got %d", code)
+	}
+	if used := shedder.InUse(); used != 0 {
+		t.Errorf("expected capacity to be released but %d is in use", used)
+	}
+
+	shedder = NewLoadShedder(LoadShedderOptions{
+		Capacity: 10,
+		OnShed:   func(r *http.Request, c Context) { shed++ },
+	})
+
+	mux = New()
+	mux.Use(shedder.Middleware)
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request, c Context) {
+		started <- struct{}{}
+		<-release
+	}, Cost(5))
+	mux.HandleFunc("/cheap", func(w http.ResponseWriter, r *http.Request, c Context) {}, Cost(5))
+	mux.HandleFunc("/expensive", func(w http.ResponseWriter, r *http.Request, c Context) {}, Cost(8))
+
+	report = serve("/reports")
+	<-started
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/expensive", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected expensive request to be shed but got %d with Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if shed != 1 {
+		t.Errorf("expected OnShed to be called once but got %d calls", shed)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/cheap", nil))
+	if w.Code != 200 {
+		t.Errorf("expected cheap request within capacity to be served but got %d", w.Code)
+	}
+
+	release <- struct{}{}
+	<-report
+}
+
+func TestLoadShedderTagShares(t *testing.T) {
+	shedder := NewLoadShedder(LoadShedderOptions{
+		Capacity:  8,
+		MaxWait:   time.Second,
+		TagShares: map[string]float64{"reports": 0.25},
+	})
+
+	started, release := make(chan string), make(chan struct{})
+
+	mux := New()
+	mux.Use(shedder.Middleware)
+	mux.HandleFunc("/reports/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		started <- c.Param("id")
+		<-release
+	}, Tags("reports"))
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request, c Context) {
+		started <- "users"
+		<-release
+	})
+
+	serve := func(path string) <-chan int {
+		done := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			done <- w.Code
+		}()
+		return done
+	}
+
+	first, second := serve("/reports/1"), serve("/reports/2")
+	<-started
+	<-started
+	if used := shedder.TagInUse("reports"); used != 2 {
+		t.Fatalf("expected reports to use their share of 2 but used %d", used)
+	}
+
+	third := serve("/reports/3")
+	time.Sleep(20 * time.Millisecond)
+
+	// The report waiting for the share of its tag does not hold back other routes.
+	users := serve("/users")
+	if id := <-started; id != "users" {
+		t.Fatalf("expected users to be served before report 3 but %s was", id)
+	}
+
+	release <- struct{}{}
+	if id := <-started; id != "3" {
+		t.Fatalf("expected report 3 to be served once a report finished but %s was", id)
+	}
+	release <- struct{}{}
+	release <- struct{}{}
+	release <- struct{}{}
+	for _, done := range []<-chan int{first, second, third, users} {
+		if code := <-done; code != 200 {
+			t.Errorf("expected request to be served but got %d", code)
+		}
+	}
+	if used := shedder.InUse(); used != 0 {
+		t.Errorf("expected capacity to be released but %d is in use", used)
+	}
+}
+
+func TestLoadShedderDefaultCapacity(t *testing.T) {
+	shedder := NewLoadShedder(LoadShedderOptions{})
+	if expected := defaultCapacityPerCPU * runtime.GOMAXPROCS(0); shedder.Capacity() != expected {
+		t.Errorf("expected capacity %d but got %d", expected, shedder.Capacity())
+	}
+}
@@ -0,0 +1,155 @@
+package muxter
+
+import (
+	"sync"
+	"time"
+)
+
+// SLO is a service level objective of a route, tracked by RouteStats. An SLO has an availability objective, a
+// latency objective, or both.
+type SLO struct {
+	// Pattern is the pattern of the route.
+	Pattern string
+	// Availability is the target ratio of requests not answered with a 5xx status, such as 0.999.
+	Availability float64
+	// Latency is the latency within which the LatencyTarget ratio of requests, such as 0.99, must be served.
+	Latency       time.Duration
+	LatencyTarget float64
+	// Window is the period over which burn rates are measured. It defaults to one hour.
+	Window time.Duration
+	// BurnRate is the rate at which the error budget burns above which an alert is raised, where a rate of 1 spends
+	// exactly the budget. It defaults to 14.4, which spends 2% of a 30 day budget in an hour.
+	BurnRate float64
+	// MinRequests is the number of requests in the window below which no alert is raised, so that a few failures
+	// on a quiet route do not raise alerts. It defaults to 100.
+	MinRequests uint64
+}
+
+// BurnAlert is raised when the error budget of an SLO burns faster than its threshold.
+type BurnAlert struct {
+	SLO SLO
+	// Objective is the objective burning its budget: "availability" or "latency".
+	Objective string
+	// BurnRate is the rate at which the budget burns over the window.
+	BurnRate float64
+	// Requests is the number of requests in the window.
+	Requests uint64
+	// Bad is the number of requests in the window that missed the objective.
+	Bad uint64
+}
+
+// sloSlots is the number of slots the window of an SLO is divided in.
+const sloSlots = 60
+
+// sloTracker counts the requests of an SLO over a sliding window made of slots.
+type sloTracker struct {
+	slo  SLO
+	slot time.Duration
+
+	mu       sync.Mutex
+	slots    [sloSlots]sloCounts
+	current  int64
+	total    sloCounts
+	alerting [2]bool
+}
+
+type sloCounts struct {
+	requests uint64
+	errors   uint64
+	slow     uint64
+}
+
+func newSLOTracker(slo SLO) *sloTracker {
+	if slo.Window <= 0 {
+		slo.Window = time.Hour
+	}
+	if slo.BurnRate <= 0 {
+		slo.BurnRate = 14.4
+	}
+	if slo.MinRequests == 0 {
+		slo.MinRequests = 100
+	}
+	tracker := &sloTracker{slo: slo, slot: slo.Window / sloSlots}
+	if tracker.slot <= 0 {
+		tracker.slot = 1
+	}
+	return tracker
+}
+
+// record counts the request and returns an alert if it makes an objective burn its budget faster than the
+// threshold. An objective alerts once when crossing the threshold and again only after recovering.
+func (t *sloTracker) record(code int, elapsed time.Duration) (BurnAlert, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.advance(time.Now())
+
+	counts := &t.slots[t.current%sloSlots]
+	counts.requests++
+	t.total.requests++
+	if code >= 500 {
+		counts.errors++
+		t.total.errors++
+	}
+	if t.slo.Latency > 0 && elapsed > t.slo.Latency {
+		counts.slow++
+		t.total.slow++
+	}
+
+	objectives := [2]struct {
+		name   string
+		target float64
+		bad    uint64
+	}{
+		{"availability", t.slo.Availability, t.total.errors},
+		{"latency", t.slo.LatencyTarget, t.total.slow},
+	}
+
+	var alert BurnAlert
+	var raised bool
+	for i, objective := range objectives {
+		if objective.target <= 0 || objective.target >= 1 {
+			continue
+		}
+		burnRate := float64(objective.bad) / float64(t.total.requests) / (1 - objective.target)
+		burning := t.total.requests >= t.slo.MinRequests && burnRate >= t.slo.BurnRate
+		if !burning {
+			t.alerting[i] = false
+			continue
+		}
+		if t.alerting[i] || raised {
+			continue
+		}
+		t.alerting[i] = true
+		alert = BurnAlert{
+			SLO:       t.slo,
+			Objective: objective.name,
+			BurnRate:  burnRate,
+			Requests:  t.total.requests,
+			Bad:       objective.bad,
+		}
+		raised = true
+	}
+	return alert, raised
+}
+
+// advance moves the window to now, dropping the counts of the slots that fell out of it.
+func (t *sloTracker) advance(now time.Time) {
+	slot := now.UnixNano() / int64(t.slot)
+	if slot <= t.current {
+		return
+	}
+	if slot-t.current >= sloSlots {
+		t.slots = [sloSlots]sloCounts{}
+		t.total = sloCounts{}
+	} else {
+		for s := t.current + 1; s <= slot; s++ {
+			expired := &t.slots[s%sloSlots]
+			t.total.requests -= expired.requests
+			t.total.errors -= expired.errors
+			t.total.slow -= expired.slow
+			*expired = sloCounts{}
+		}
+	}
+	t.current = slot
+}
@@ -0,0 +1,80 @@
+package muxter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLOBurnRate(t *testing.T) {
+	var alerts []BurnAlert
+
+	stats := NewRouteStats(RouteStatsOptions{
+		SLOs: []SLO{{
+			Pattern:       "/checkout",
+			Availability:  0.99,
+			Latency:       100 * time.Millisecond,
+			LatencyTarget: 0.9,
+			BurnRate:      9.5,
+			MinRequests:   10,
+		}},
+		OnBurn: func(alert BurnAlert) { alerts = append(alerts, alert) },
+	})
+
+	// 9 successful requests: too few requests to alert.
+	for i := 0; i < 9; i++ {
+		stats.record("/checkout", 200, time.Millisecond)
+	}
+	stats.record("/other", 500, time.Millisecond)
+	if len(alerts) != 0 {
+		t.Fatalf("expected no alerts but got %+v", alerts)
+	}
+
+	// An error in 10 requests burns a 1% budget 10 times faster than it should.
+	stats.record("/checkout", 500, time.Millisecond)
+	if len(alerts) != 1 {
+		t.Fatalf("expected an alert but got %+v", alerts)
+	}
+	if alert := alerts[0]; alert.Objective != "availability" || alert.BurnRate < 9.99 || alert.Requests != 10 || alert.Bad != 1 {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+
+	// The availability objective does not alert again while it keeps burning.
+	stats.record("/checkout", 500, time.Millisecond)
+	if len(alerts) != 1 {
+		t.Fatalf("expected a single alert but got %+v", alerts)
+	}
+
+	// Slow requests burn the latency budget: 10 slow requests in 21 make a burn rate of 4.76, and 11 in 22 of 5.
+	for i := 0; i < 10; i++ {
+		stats.record("/checkout", 200, time.Second)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("expected the latency objective not to alert below its threshold but got %+v", alerts)
+	}
+
+	tracker := stats.slos["/checkout"][0]
+	tracker.slo.BurnRate = 5
+	stats.record("/checkout", 200, time.Second)
+	if len(alerts) != 2 || alerts[1].Objective != "latency" {
+		t.Fatalf("expected a latency alert but got %+v", alerts)
+	}
+}
+
+func TestSLOWindow(t *testing.T) {
+	tracker := newSLOTracker(SLO{Pattern: "/", Availability: 0.99, Window: time.Minute, MinRequests: 1})
+
+	now := time.Now()
+	tracker.advance(now)
+	tracker.slots[tracker.current%sloSlots] = sloCounts{requests: 10, errors: 10}
+	tracker.total = sloCounts{requests: 10, errors: 10}
+
+	tracker.advance(now.Add(30 * time.Second))
+	if tracker.total.requests != 10 {
+		t.Errorf("expected the requests to remain in the window but got %d", tracker.total.requests)
+	}
+
+	tracker.advance(now.Add(61 * time.Second))
+	if tracker.total != (sloCounts{}) {
+		t.Errorf("expected the requests to fall out of the window but got %+v", tracker.total)
+	}
+}
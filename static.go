@@ -0,0 +1,280 @@
+package muxter
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOptions configures Static.
+type StaticOptions struct {
+	// Param is the name of the catch-all param holding the path of the file, such as "file" for the pattern
+	// "/assets/*file". The effective path of the request is used if it is empty.
+	Param string
+	// CacheSize is the total size in bytes of the files kept in memory, the most recently served first. Files are
+	// read from the file system on every request if it is zero.
+	CacheSize int64
+	// MaxFileSize is the size of the largest file kept in memory, 64KiB if zero. Larger files are always read from
+	// the file system.
+	MaxFileSize int64
+	// Revalidate is how long files are served from memory before their size and modification time are compared with
+	// the file system again, 1s if zero.
+	Revalidate time.Duration
+}
+
+// staticEncodings are the precompressed variants served by Static in order of preference.
+var staticEncodings = []struct{ encoding, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Static returns a handler serving the files of fsys to GET and HEAD requests. When the client accepts it, a
+// precompressed variant of the file, such as app.js.br or app.js.gz next to app.js, is served in its place with the
+// corresponding Content-Encoding. Directories are not listed.
+//
+// With a CacheSize the hot small files and their variants are kept in memory and evicted least recently used first,
+// such that busy asset routes neither read nor stat the file system until the cached files must be revalidated.
+//
+//	mux.Handle("/assets/*file", muxter.Static(os.DirFS("public"), muxter.StaticOptions{
+//		Param:     "file",
+//		CacheSize: 32 << 20,
+//	}))
+func Static(fsys fs.FS, opts StaticOptions) Handler {
+	if opts.MaxFileSize == 0 {
+		opts.MaxFileSize = 64 << 10
+	}
+	if opts.Revalidate == 0 {
+		opts.Revalidate = time.Second
+	}
+	files := &staticFiles{fsys: fsys, opts: opts, entries: map[string]*list.Element{}}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if c.extra().methodMux == nil {
+				w.Header().Set("Allow", "GET, HEAD")
+			}
+			methodNotAllowed(w, r, c, defaultMethodNotAllowedHandler, []string{http.MethodGet, http.MethodHead})
+			return
+		}
+
+		name := c.EffectivePath()
+		if opts.Param != "" {
+			name = c.Param(opts.Param)
+		}
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		if name == "" || !fs.ValidPath(name) {
+			writeStatus(w, c, http.StatusNotFound)
+			return
+		}
+
+		file, err := files.open(name, false)
+		if err != nil {
+			writeStatus(w, c, staticErrorStatus(err))
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Accept-Encoding")
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		accept := r.Header.Get("Accept-Encoding")
+		for _, variant := range staticEncodings {
+			if !acceptsEncoding(accept, variant.encoding) {
+				continue
+			}
+			encoded, err := files.open(name+variant.ext, true)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file = encoded
+			header.Set("Content-Encoding", variant.encoding)
+			if contentType == "" {
+				// Prevent ServeContent from sniffing the type of the encoded content.
+				contentType = "application/octet-stream"
+			}
+			break
+		}
+		defer file.Close()
+
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		http.ServeContent(w, r, name, file.modTime, file.content)
+	})
+}
+
+func staticErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type staticFile struct {
+	modTime time.Time
+	content io.ReadSeeker
+	closer  io.Closer
+}
+
+func (f staticFile) Close() {
+	if f.closer != nil {
+		f.closer.Close()
+	}
+}
+
+// staticFiles reads the files of a Static handler through its in-memory cache.
+type staticFiles struct {
+	fsys fs.FS
+	opts StaticOptions
+
+	mu      sync.Mutex
+	used    int64
+	entries map[string]*list.Element
+	lru     list.List
+}
+
+// staticEntry is a cached file, or the absence of a precompressed variant.
+type staticEntry struct {
+	name    string
+	data    []byte
+	missing bool
+	modTime time.Time
+	checked time.Time
+}
+
+func (e *staticEntry) cost() int64 {
+	return int64(len(e.name) + len(e.data))
+}
+
+func (e *staticEntry) file() (staticFile, error) {
+	if e.missing {
+		return staticFile{}, fs.ErrNotExist
+	}
+	return staticFile{modTime: e.modTime, content: bytes.NewReader(e.data)}, nil
+}
+
+// open returns the file with the name. The absence of variants is cached as well, such that files without
+// precompressed variants do not cost a stat per encoding.
+func (s *staticFiles) open(name string, variant bool) (staticFile, error) {
+	if s.opts.CacheSize <= 0 {
+		return s.read(name)
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	var cached *staticEntry
+	if elem, ok := s.entries[name]; ok {
+		s.lru.MoveToFront(elem)
+		cached = elem.Value.(*staticEntry)
+		if now.Sub(cached.checked) < s.opts.Revalidate {
+			defer s.mu.Unlock()
+			return cached.file()
+		}
+	}
+	s.mu.Unlock()
+
+	info, err := fs.Stat(s.fsys, name)
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		if variant && errors.Is(err, fs.ErrNotExist) {
+			s.store(&staticEntry{name: name, missing: true, checked: now})
+		} else {
+			s.remove(name)
+		}
+		return staticFile{}, err
+	}
+
+	if cached != nil && !cached.missing && info.ModTime().Equal(cached.modTime) && info.Size() == int64(len(cached.data)) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		cached.checked = now
+		return cached.file()
+	}
+
+	if info.Size() > s.opts.MaxFileSize {
+		s.remove(name)
+		return s.read(name)
+	}
+
+	data, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		s.remove(name)
+		return staticFile{}, err
+	}
+	entry := &staticEntry{name: name, data: data, modTime: info.ModTime(), checked: now}
+	s.store(entry)
+	return entry.file()
+}
+
+// read opens the file from the file system.
+func (s *staticFiles) read(name string) (staticFile, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return staticFile{}, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return staticFile{}, err
+	}
+	if content, ok := f.(io.ReadSeeker); ok {
+		return staticFile{modTime: info.ModTime(), content: content, closer: f}, nil
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return staticFile{}, err
+	}
+	return staticFile{modTime: info.ModTime(), content: bytes.NewReader(data)}, nil
+}
+
+func (s *staticFiles) store(entry *staticEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(entry.name)
+	if entry.cost() > s.opts.CacheSize {
+		return
+	}
+	s.entries[entry.name] = s.lru.PushFront(entry)
+	s.used += entry.cost()
+
+	for s.used > s.opts.CacheSize {
+		s.removeLocked(s.lru.Back().Value.(*staticEntry).name)
+	}
+}
+
+func (s *staticFiles) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(name)
+}
+
+func (s *staticFiles) removeLocked(name string) {
+	elem, ok := s.entries[name]
+	if !ok {
+		return
+	}
+	s.lru.Remove(elem)
+	delete(s.entries, name)
+	s.used -= elem.Value.(*staticEntry).cost()
+}
@@ -0,0 +1,238 @@
+package muxter
+
+import (
+	"io/fs"
+	"mime"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// countingFS counts the accesses to the file system.
+type countingFS struct {
+	fstest.MapFS
+	accesses atomic.Int64
+}
+
+func (fsys *countingFS) Open(name string) (fs.File, error) {
+	fsys.accesses.Add(1)
+	return fsys.MapFS.Open(name)
+}
+
+func (fsys *countingFS) Stat(name string) (fs.FileInfo, error) {
+	fsys.accesses.Add(1)
+	return fsys.MapFS.Stat(name)
+}
+
+func TestStatic(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":          {Data: []byte("console.log('app')")},
+		"app.js.br":       {Data: []byte("brotli")},
+		"app.js.gz":       {Data: []byte("gzip")},
+		"style.css":       {Data: []byte("body{}")},
+		"style.css.gz":    {Data: []byte("gzipped style")},
+		"img/logo.svg":    {Data: []byte("<svg/>")},
+		"data.unknown":    {Data: []byte("raw")},
+		"data.unknown.br": {Data: []byte("encoded raw")},
+	}
+
+	jsType := mime.TypeByExtension(".js")
+
+	mux := New()
+	mux.Handle("/assets/*file", Static(fsys, StaticOptions{Param: "file"}))
+
+	cases := []struct {
+		Name             string
+		Method           string
+		Path             string
+		AcceptEncoding   string
+		ExpectedCode     int
+		ExpectedBody     string
+		ExpectedType     string
+		ExpectedEncoding string
+	}{
+		{
+			Name:         "identity",
+			Path:         "/assets/app.js",
+			ExpectedCode: 200,
+			ExpectedBody: "console.log('app')",
+			ExpectedType: jsType,
+		},
+		{
+			Name:             "prefers brotli",
+			Path:             "/assets/app.js",
+			AcceptEncoding:   "gzip, br",
+			ExpectedCode:     200,
+			ExpectedBody:     "brotli",
+			ExpectedType:     jsType,
+			ExpectedEncoding: "br",
+		},
+		{
+			Name:             "refused brotli",
+			Path:             "/assets/app.js",
+			AcceptEncoding:   "gzip, br;q=0",
+			ExpectedCode:     200,
+			ExpectedBody:     "gzip",
+			ExpectedType:     jsType,
+			ExpectedEncoding: "gzip",
+		},
+		{
+			Name:             "missing variant",
+			Path:             "/assets/style.css",
+			AcceptEncoding:   "br, gzip",
+			ExpectedCode:     200,
+			ExpectedBody:     "gzipped style",
+			ExpectedType:     "text/css; charset=utf-8",
+			ExpectedEncoding: "gzip",
+		},
+		{
+			Name:             "unknown type",
+			Path:             "/assets/data.unknown",
+			AcceptEncoding:   "br",
+			ExpectedCode:     200,
+			ExpectedBody:     "encoded raw",
+			ExpectedType:     "application/octet-stream",
+			ExpectedEncoding: "br",
+		},
+		{
+			Name:         "nested",
+			Path:         "/assets/img/logo.svg",
+			ExpectedCode: 200,
+			ExpectedBody: "<svg/>",
+			ExpectedType: "image/svg+xml",
+		},
+		{
+			Name:         "directory",
+			Path:         "/assets/img",
+			ExpectedCode: 404,
+		},
+		{
+			Name:         "missing",
+			Path:         "/assets/missing.js",
+			ExpectedCode: 404,
+		},
+		{
+			Name:         "method",
+			Method:       "POST",
+			Path:         "/assets/app.js",
+			ExpectedCode: 405,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			method := tc.Method
+			if method == "" {
+				method = "GET"
+			}
+			r := httptest.NewRequest(method, tc.Path, nil)
+			if tc.AcceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.AcceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedCode != 200 {
+				return
+			}
+			if body := w.Body.String(); body != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.ExpectedType {
+				t.Errorf("expected content type %q but got %q", tc.ExpectedType, contentType)
+			}
+			if encoding := w.Header().Get("Content-Encoding"); encoding != tc.ExpectedEncoding {
+				t.Errorf("expected content encoding %q but got %q", tc.ExpectedEncoding, encoding)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("expected Vary to be Accept-Encoding but got %q", vary)
+			}
+		})
+	}
+}
+
+func TestStaticCache(t *testing.T) {
+	fsys := &countingFS{MapFS: fstest.MapFS{
+		"app.js":    {Data: []byte("version 1"), ModTime: time.Unix(1, 0)},
+		"app.js.br": {Data: []byte("brotli 1"), ModTime: time.Unix(1, 0)},
+		"large.js":  {Data: []byte("0123456789abcdef")},
+	}}
+
+	handler := Static(fsys, StaticOptions{CacheSize: 1 << 10, MaxFileSize: 10, Revalidate: 20 * time.Millisecond})
+
+	serve := func(path, acceptEncoding string) string {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		handler.ServeHTTPx(w, r, Context{ogReqPath: path})
+		return w.Body.String()
+	}
+
+	for _, acceptEncoding := range []string{"br", "gzip", ""} {
+		serve("/app.js", acceptEncoding)
+	}
+
+	accesses := fsys.accesses.Load()
+	if body := serve("/app.js", "gzip, br"); body != "brotli 1" {
+		t.Fatalf("expected cached brotli variant but got %q", body)
+	}
+	if body := serve("/app.js", "gzip"); body != "version 1" {
+		t.Fatalf("expected cached file but got %q", body)
+	}
+	if n := fsys.accesses.Load() - accesses; n != 0 {
+		t.Errorf("expected hot files to be served from memory but the file system was accessed %d times", n)
+	}
+
+	serve("/large.js", "")
+	accesses = fsys.accesses.Load()
+	if body := serve("/large.js", ""); body != "0123456789abcdef" {
+		t.Fatalf("expected large file but got %q", body)
+	}
+	if fsys.accesses.Load() == accesses {
+		t.Errorf("expected large file to be read from the file system")
+	}
+
+	fsys.MapFS["app.js"] = &fstest.MapFile{Data: []byte("version 2"), ModTime: time.Unix(2, 0)}
+	if body := serve("/app.js", ""); body != "version 1" {
+		t.Fatalf("expected cached file until revalidation but got %q", body)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if body := serve("/app.js", ""); body != "version 2" {
+		t.Errorf("expected modified file to be served after revalidation but got %q", body)
+	}
+}
+
+func TestStaticCacheEviction(t *testing.T) {
+	fsys := &countingFS{MapFS: fstest.MapFS{
+		"a": {Data: []byte("aaaaaaaa")},
+		"b": {Data: []byte("bbbbbbbb")},
+		"c": {Data: []byte("cccccccc")},
+	}}
+
+	// Room for two entries of one byte of name and eight bytes of data.
+	handler := Static(fsys, StaticOptions{CacheSize: 18, Revalidate: time.Hour})
+
+	serve := func(path string) int64 {
+		accesses := fsys.accesses.Load()
+		r := httptest.NewRequest("GET", path, nil)
+		handler.ServeHTTPx(httptest.NewRecorder(), r, Context{ogReqPath: path})
+		return fsys.accesses.Load() - accesses
+	}
+
+	serve("/a")
+	serve("/b")
+	serve("/a")
+	serve("/c") // evicts b, the least recently used
+
+	if n := serve("/a"); n != 0 {
+		t.Errorf("expected a to remain cached but the file system was accessed %d times", n)
+	}
+	if n := serve("/b"); n == 0 {
+		t.Errorf("expected b to be evicted")
+	}
+}
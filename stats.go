@@ -0,0 +1,258 @@
+package muxter
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/davidmdm/muxter/internal/pool"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms of RouteStats when none are given.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// RouteStatsOptions configures RouteStats.
+type RouteStatsOptions struct {
+	// Buckets are the upper bounds of the latency histograms in increasing order. They default to
+	// DefaultLatencyBuckets.
+	Buckets []time.Duration
+	// SLOs are the service level objectives of routes whose burn rates are tracked.
+	SLOs []SLO
+	// OnBurn is called when the error budget of an SLO burns faster than its threshold. It is called on the
+	// goroutine of the request crossing the threshold and must not block.
+	OnBurn func(alert BurnAlert)
+	// StallThreshold is the duration of a single write or flush of the response above which it counts as stalled by
+	// a slow client. It defaults to 50ms.
+	StallThreshold time.Duration
+}
+
+// RouteStats collects the number of requests, server errors and a latency histogram of every route. Requests are
+// attributed to the pattern of the route they matched, and requests not matching a route are not counted.
+type RouteStats struct {
+	opts RouteStatsOptions
+	slos map[string][]*sloTracker
+
+	mu     sync.RWMutex
+	routes map[string]*routeCounters
+}
+
+type routeCounters struct {
+	requests atomic.Uint64
+	errors   atomic.Uint64
+	aborted  atomic.Uint64
+	sum      atomic.Int64
+	counts   []atomic.Uint64
+	writing  atomic.Int64
+	stalls   atomic.Uint64
+
+	mu     sync.Mutex
+	layers map[string]time.Duration
+}
+
+// RouteMetrics are the statistics of a route.
+type RouteMetrics struct {
+	Pattern string `json:"pattern"`
+	// Requests is the number of requests served by the route.
+	Requests uint64 `json:"requests"`
+	// Errors is the number of requests answered with a 5xx status.
+	Errors uint64 `json:"errors"`
+	// Aborted is the number of requests abandoned by their clients, which are not counted as errors whatever their
+	// status.
+	Aborted uint64 `json:"aborted"`
+	// LatencySum is the sum of the latencies of the requests.
+	LatencySum time.Duration `json:"latencySum"`
+	// Latency are the cumulative counts of requests by latency, as in Prometheus histograms: each bucket counts the
+	// requests served within its upper bound. Requests slower than the last bucket are only counted in Requests.
+	Latency []LatencyBucket `json:"latency"`
+	// WriteTime is the time spent writing and flushing responses to clients, which is part of the latency. A route
+	// whose latency is mostly write time is slowed down by its clients rather than by its handler.
+	WriteTime time.Duration `json:"writeTime"`
+	// StalledWrites is the number of writes and flushes that took longer than the StallThreshold.
+	StalledWrites uint64 `json:"stalledWrites"`
+	// Middlewares are the total time spent in each middleware layer wrapped by the stats middleware, sorted by name,
+	// when the TimeMiddlewares option is set.
+	Middlewares []MiddlewareTiming `json:"middlewares,omitempty"`
+}
+
+// LatencyBucket is a bucket of a latency histogram.
+type LatencyBucket struct {
+	UpperBound time.Duration `json:"upperBound"`
+	Count      uint64        `json:"count"`
+}
+
+// NewRouteStats returns RouteStats configured by opts. Its Middleware method collects the statistics of the routes it
+// is used on.
+//
+//	stats := muxter.NewRouteStats(muxter.RouteStatsOptions{})
+//	mux.Use(stats.Middleware)
+func NewRouteStats(opts RouteStatsOptions) *RouteStats {
+	if opts.Buckets == nil {
+		opts.Buckets = DefaultLatencyBuckets
+	}
+	if opts.StallThreshold <= 0 {
+		opts.StallThreshold = 50 * time.Millisecond
+	}
+	pool.EnableCounters()
+	stats := &RouteStats{opts: opts, slos: map[string][]*sloTracker{}, routes: map[string]*routeCounters{}}
+	for _, slo := range opts.SLOs {
+		stats.slos[slo.Pattern] = append(stats.slos[slo.Pattern], newSLOTracker(slo))
+	}
+	return stats
+}
+
+// Middleware is a middleware collecting the statistics of the handler.
+func (s *RouteStats) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		sw := &statsWriter{responseProxy: responseProxy{w, 0}, threshold: s.opts.StallThreshold}
+		start := time.Now()
+
+		serveObserved(h, sw, r, c, func(aborted bool) {
+			if c.Pattern() == "" {
+				return
+			}
+			code := sw.Code()
+			if aborted {
+				code = StatusClientClosedRequest
+			}
+			counters := s.record(c.Pattern(), code, time.Since(start))
+			counters.writing.Add(int64(sw.writing))
+			counters.stalls.Add(sw.stalls)
+			if timings := c.MiddlewareTimings(); len(timings) > 0 {
+				counters.addLayers(timings)
+			}
+		})
+	})
+}
+
+func (s *RouteStats) record(pattern string, code int, elapsed time.Duration) *routeCounters {
+	s.mu.RLock()
+	counters, ok := s.routes[pattern]
+	s.mu.RUnlock()
+
+	if !ok {
+		s.mu.Lock()
+		if counters, ok = s.routes[pattern]; !ok {
+			counters = &routeCounters{counts: make([]atomic.Uint64, len(s.opts.Buckets))}
+			s.routes[pattern] = counters
+		}
+		s.mu.Unlock()
+	}
+
+	counters.requests.Add(1)
+	if code == StatusClientClosedRequest {
+		counters.aborted.Add(1)
+	} else if code >= 500 {
+		counters.errors.Add(1)
+	}
+	counters.sum.Add(int64(elapsed))
+	for i, bound := range s.opts.Buckets {
+		if elapsed <= bound {
+			counters.counts[i].Add(1)
+			break
+		}
+	}
+
+	for _, slo := range s.slos[pattern] {
+		if alert, ok := slo.record(code, elapsed); ok && s.opts.OnBurn != nil {
+			s.opts.OnBurn(alert)
+		}
+	}
+	return counters
+}
+
+// Routes returns the statistics of the routes that served requests sorted by pattern.
+func (s *RouteStats) Routes() []RouteMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routes := make([]RouteMetrics, 0, len(s.routes))
+	for pattern, counters := range s.routes {
+		metrics := RouteMetrics{
+			Pattern:       pattern,
+			Requests:      counters.requests.Load(),
+			Errors:        counters.errors.Load(),
+			Aborted:       counters.aborted.Load(),
+			LatencySum:    time.Duration(counters.sum.Load()),
+			Latency:       make([]LatencyBucket, len(s.opts.Buckets)),
+			WriteTime:     time.Duration(counters.writing.Load()),
+			StalledWrites: counters.stalls.Load(),
+		}
+		var cumulative uint64
+		for i, bound := range s.opts.Buckets {
+			cumulative += counters.counts[i].Load()
+			metrics.Latency[i] = LatencyBucket{UpperBound: bound, Count: cumulative}
+		}
+		metrics.Middlewares = counters.middlewares()
+		routes = append(routes, metrics)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
+
+func (c *routeCounters) addLayers(timings []MiddlewareTiming) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.layers == nil {
+		c.layers = map[string]time.Duration{}
+	}
+	for _, timing := range timings {
+		c.layers[timing.Name] += timing.Duration
+	}
+}
+
+func (c *routeCounters) middlewares() []MiddlewareTiming {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.layers) == 0 {
+		return nil
+	}
+	timings := make([]MiddlewareTiming, 0, len(c.layers))
+	for name, duration := range c.layers {
+		timings = append(timings, MiddlewareTiming{Name: name, Duration: duration})
+	}
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Name < timings[j].Name })
+	return timings
+}
+
+// statsWriter measures the time spent writing and flushing the response, which blocks when clients do not read it
+// fast enough.
+type statsWriter struct {
+	responseProxy
+	threshold time.Duration
+	writing   time.Duration
+	stalls    uint64
+}
+
+func (w *statsWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := w.ResponseWriter.Write(p)
+	w.measure(time.Since(start))
+	return n, err
+}
+
+func (w *statsWriter) Flush() {
+	start := time.Now()
+	w.responseProxy.Flush()
+	w.measure(time.Since(start))
+}
+
+func (w *statsWriter) measure(elapsed time.Duration) {
+	w.writing += elapsed
+	if elapsed > w.threshold {
+		w.stalls++
+	}
+}
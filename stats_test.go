@@ -0,0 +1,92 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRouteStats(t *testing.T) {
+	stats := NewRouteStats(RouteStatsOptions{Buckets: []time.Duration{time.Hour, 2 * time.Hour}})
+
+	mux := New()
+	mux.UseGlobal(stats.Middleware)
+	mux.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.Param("id") == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	for _, path := range []string{"/books/1", "/books/2", "/books/broken", "/unknown"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	routes := stats.Routes()
+	if len(routes) != 1 {
+		t.Fatalf("expected statistics for a single route but got %+v", routes)
+	}
+
+	route := routes[0]
+	if route.Pattern != "/books/:id" || route.Requests != 3 || route.Errors != 1 || route.LatencySum <= 0 {
+		t.Errorf("unexpected statistics %+v", route)
+	}
+
+	expected := []LatencyBucket{{UpperBound: time.Hour, Count: 3}, {UpperBound: 2 * time.Hour, Count: 3}}
+	if !reflect.DeepEqual(route.Latency, expected) {
+		t.Errorf("expected latency histogram %+v but got %+v", expected, route.Latency)
+	}
+}
+
+func TestRouteStatsHistogram(t *testing.T) {
+	stats := NewRouteStats(RouteStatsOptions{Buckets: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond}})
+
+	for _, elapsed := range []time.Duration{time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond, time.Second} {
+		stats.record("/", 200, elapsed)
+	}
+
+	expected := []LatencyBucket{{UpperBound: 10 * time.Millisecond, Count: 2}, {UpperBound: 100 * time.Millisecond, Count: 3}}
+	if actual := stats.Routes()[0].Latency; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected cumulative buckets %+v but got %+v", expected, actual)
+	}
+}
+
+// slowClientWriter is a response writer whose writes and flushes block as if the client were slow to read.
+type slowClientWriter struct {
+	*httptest.ResponseRecorder
+	delay time.Duration
+}
+
+func (w slowClientWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.ResponseRecorder.Write(p)
+}
+
+func (w slowClientWriter) Flush() {
+	time.Sleep(w.delay)
+	w.ResponseRecorder.Flush()
+}
+
+func TestRouteStatsWriteStalls(t *testing.T) {
+	stats := NewRouteStats(RouteStatsOptions{StallThreshold: 5 * time.Millisecond})
+
+	mux := New()
+	mux.Use(stats.Middleware)
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request, c Context) {
+		w.Write([]byte("a"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("b"))
+	})
+
+	mux.ServeHTTP(slowClientWriter{httptest.NewRecorder(), 10 * time.Millisecond}, httptest.NewRequest("GET", "/stream", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stream", nil))
+
+	route := stats.Routes()[0]
+	if route.StalledWrites != 3 {
+		t.Errorf("expected 3 stalled writes but got %d", route.StalledWrites)
+	}
+	if route.WriteTime < 30*time.Millisecond || route.WriteTime > route.LatencySum {
+		t.Errorf("expected write time of at least 30ms within the latency %v but got %v", route.LatencySum, route.WriteTime)
+	}
+}
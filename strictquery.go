@@ -0,0 +1,77 @@
+package muxter
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+)
+
+// StrictQuery is a registration option rejecting requests with query parameters the route does not declare with 400
+// Bad Request, catching typos such as ?pgae=2 rather than silently ignoring them. The query parameters of the struct
+// given to the Binds option are declared, as are the allowed names, which are recorded among the route's Parameters
+// for the parameters read without binding. The response names the first unknown parameter.
+//
+//	mux.GetFunc("/books", listBooks, muxter.Binds(ListBooksRequest{}), muxter.StrictQuery("callback"))
+func StrictQuery(allowed ...string) Middleware {
+	return func(h Handler) Handler {
+		return routeOption{
+			Handler: HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				if unknown := unknownQueryParameter(r, c.route, allowed); unknown != "" {
+					err := &BindError{Status: http.StatusBadRequest, Source: "query", Key: unknown, Err: errUnknownParameter}
+					writeMessage(w, c, err.Status, err.Error())
+					return
+				}
+				h.ServeHTTPx(w, r, c)
+			}),
+			apply: func(ri *RouteInfo) {
+				ri.StrictQuery = true
+				for _, name := range allowed {
+					if !declaresQuery(ri, name) {
+						ri.Parameters = append(ri.Parameters, ParameterInfo{Name: name, In: "query", Type: "string"})
+					}
+				}
+			},
+		}
+	}
+}
+
+var errUnknownParameter = errors.New("is not a parameter of the route")
+
+// unknownQueryParameter returns the first query parameter of the request in sorted order that is neither allowed nor
+// declared by the route, or the empty string if there is none.
+func unknownQueryParameter(r *http.Request, route *RouteInfo, allowed []string) string {
+	var unknown []string
+	for key := range r.URL.Query() {
+		if !declaresQuery(route, key) && !isAllowed(allowed, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return ""
+	}
+	sort.Strings(unknown)
+	return unknown[0]
+}
+
+// declaresQuery reports whether the route declares the query parameter.
+func declaresQuery(route *RouteInfo, name string) bool {
+	if route == nil {
+		return false
+	}
+	for _, param := range route.Parameters {
+		if param.In == "query" && param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowed reports whether the name is one of the allowed names.
+func isAllowed(allowed []string, name string) bool {
+	for _, a := range allowed {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
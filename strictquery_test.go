@@ -0,0 +1,57 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStrictQuery(t *testing.T) {
+	type ListBooksRequest struct {
+		Page   int    `query:"page"`
+		Author string `query:"author"`
+	}
+
+	mux := New()
+	mux.GetFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) {}, StrictQuery("callback"), Binds(ListBooksRequest{}))
+	mux.GetFunc("/authors", func(w http.ResponseWriter, r *http.Request, c Context) {}, Binds(ListBooksRequest{}))
+
+	jsonMux := New(JSONErrors(true))
+	jsonMux.Handle("/", StripDepth(0, mux))
+
+	cases := []struct {
+		Name         string
+		Mux          *Mux
+		Target       string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Name: "declared parameters", Mux: mux, Target: "/books?page=2&author=le+guin", ExpectedCode: 200},
+		{Name: "allowed parameter", Mux: mux, Target: "/books?callback=cb", ExpectedCode: 200},
+		{Name: "typo", Mux: mux, Target: "/books?pgae=2", ExpectedCode: 400, ExpectedBody: "query \"pgae\": is not a parameter of the route\n"},
+		{Name: "first unknown parameter", Mux: mux, Target: "/books?page=2&zoom&debug=1", ExpectedCode: 400, ExpectedBody: "query \"debug\": is not a parameter of the route\n"},
+		{Name: "not strict", Mux: mux, Target: "/authors?pgae=2", ExpectedCode: 200},
+		{Name: "json errors", Mux: jsonMux, Target: "/books?pgae=2", ExpectedCode: 400, ExpectedBody: `{"error":"query \"pgae\": is not a parameter of the route","status":400}` + "\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.Mux.ServeHTTP(w, httptest.NewRequest("GET", tc.Target, nil))
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected status %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("route info", func(t *testing.T) {
+		route := mux.Routes()[1]
+		if !route.StrictQuery || len(route.Parameters) != 3 || route.Parameters[2] != (ParameterInfo{Name: "callback", In: "query", Type: "string"}) {
+			t.Errorf("expected strict route declaring callback but got %+v", route)
+		}
+	})
+}
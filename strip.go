@@ -2,23 +2,88 @@ package muxter
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/davidmdm/muxter/internal/pool"
 )
 
-func StripDepth(depth int, handler Handler) Handler {
+type stripOptions struct {
+	noPool bool
+	poison bool
+}
+
+type StripOption func(*stripOptions)
+
+// NoPool is an option for StripDepth. By default the request and URL handed to the stripped handler are taken
+// from a pool and recycled once the handler returns, which breaks handlers that retain the request past their
+// lifetime, for example in a goroutine. With NoPool a fresh request and URL are allocated for every request.
+var NoPool StripOption = func(so *stripOptions) {
+	so.noPool = true
+}
+
+// PoisonAfterUse is a development option for StripDepth that detects use of the request after the handler
+// returned. Once the handler returns the pooled request is overwritten with a poisoned value whose method is
+// "MUXTER_POISONED" and whose path describes the misuse, and it is not returned to the pool so that the
+// poisoned value remains visible to any code that retained it.
+var PoisonAfterUse StripOption = func(so *stripOptions) {
+	so.poison = true
+}
+
+const poisonedPath = "/muxter:request-used-after-handler-returned"
+
+func poisonRequest(r *http.Request) {
+	*r.URL = url.URL{Path: poisonedPath}
+	*r = http.Request{
+		Method: "MUXTER_POISONED",
+		URL:    r.URL,
+		Header: http.Header{},
+		Body:   http.NoBody,
+	}
+}
+
+// StripDepth returns a handler that serves requests to handler after removing depth segments from the
+// request path. It is used to mount muxes, whose patterns are relative to the mount point, under a parent mux.
+// The stripped path is reported by Context.EffectivePath while Context.OriginalPath keeps the path as received.
+func StripDepth(depth int, handler Handler, opts ...StripOption) Handler {
+	var options stripOptions
+	for _, apply := range opts {
+		apply(&options)
+	}
+
+	if options.noPool {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			r2 := new(http.Request)
+			*r2 = *r
+
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+
+			r2.URL.Path = stripDepth(r.URL.Path, depth)
+			c.effectivePath = r2.URL.Path
+
+			handler.ServeHTTPx(w, r2, c)
+		})
+	}
+
 	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
 		r2 := pool.Requests.Get()
-		defer pool.Requests.Put(r2)
-
 		*r2 = *r
 
-		r2.URL = pool.URL.Get()
-		defer pool.URL.Put(r2.URL)
+		u := pool.URL.Get()
+		*u = *r.URL
+		r2.URL = u
 
-		*r2.URL = *r.URL
+		defer func() {
+			if options.poison || c.extra().debug != nil {
+				poisonRequest(r2)
+				return
+			}
+			pool.URL.Put(u)
+			pool.Requests.Put(r2)
+		}()
 
 		r2.URL.Path = stripDepth(r.URL.Path, depth)
+		c.effectivePath = r2.URL.Path
 
 		handler.ServeHTTPx(w, r2, c)
 	})
@@ -1,6 +1,11 @@
 package muxter
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
 
 func TestStripPathDepth(t *testing.T) {
 	testcases := []struct {
@@ -38,3 +43,104 @@ func TestStripPathDepth(t *testing.T) {
 		})
 	}
 }
+
+func TestStripDepthOptions(t *testing.T) {
+	testcases := []struct {
+		Name           string
+		Options        []StripOption
+		ExpectedMethod string
+		ExpectedPath   string
+	}{
+		{
+			Name:           "no pool",
+			Options:        []StripOption{NoPool},
+			ExpectedMethod: "GET",
+			ExpectedPath:   "/resource",
+		},
+		{
+			Name:           "poison after use",
+			Options:        []StripOption{PoisonAfterUse},
+			ExpectedMethod: "MUXTER_POISONED",
+			ExpectedPath:   "/muxter:request-used-after-handler-returned",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var retained *http.Request
+
+			handler := StripDepth(1, HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				if r.URL.Path != "/resource" {
+					t.Errorf("expected stripped path to be %q but got %q", "/resource", r.URL.Path)
+				}
+				retained = r
+			}), tc.Options...)
+
+			mux := New()
+			mux.Handle("/api/", handler)
+
+			r := httptest.NewRequest("GET", "/api/resource", nil)
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if retained.Method != tc.ExpectedMethod {
+				t.Errorf("expected retained request method to be %q but got %q", tc.ExpectedMethod, retained.Method)
+			}
+			if retained.URL.Path != tc.ExpectedPath {
+				t.Errorf("expected retained request path to be %q but got %q", tc.ExpectedPath, retained.URL.Path)
+			}
+			if r.URL.Path != "/api/resource" {
+				t.Errorf("expected original request to be untouched but got path %q", r.URL.Path)
+			}
+		})
+	}
+}
+
+func TestStripDepthPreservesOriginalURL(t *testing.T) {
+	var stripped, original string
+
+	child := New()
+	child.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		stripped, original = r.URL.String(), c.OriginalURL().String()
+	})
+
+	parent := New()
+	parent.Handle("/api/v1/", StripDepth(2, child))
+
+	parent.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/books/1?fields=title", nil))
+
+	if stripped != "/books/1?fields=title" {
+		t.Errorf("expected stripped url to be %q but got %q", "/books/1?fields=title", stripped)
+	}
+	if original != "/api/v1/books/1?fields=title" {
+		t.Errorf("expected original url to be %q but got %q", "/api/v1/books/1?fields=title", original)
+	}
+}
+
+func TestStripDepthEffectivePath(t *testing.T) {
+	var observed []string
+
+	observe := func(name string) Middleware {
+		return func(h Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+				observed = append(observed, name+" "+c.OriginalPath()+" "+c.EffectivePath())
+				h.ServeHTTPx(w, r, c)
+			})
+		}
+	}
+
+	child := New()
+	child.HandleFunc("/books/:id", func(w http.ResponseWriter, r *http.Request, c Context) {}, observe("child"))
+
+	parent := New()
+	parent.Handle("/api/v1/", StripDepth(2, child), observe("parent"))
+
+	parent.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/books/1", nil))
+
+	expected := []string{
+		"parent /api/v1/books/1 /api/v1/books/1",
+		"child /api/v1/books/1 /books/1",
+	}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("expected observed paths to be %q but got %q", expected, observed)
+	}
+}
@@ -0,0 +1,79 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// RouteSwitchOptions configures a RouteSwitch.
+type RouteSwitchOptions struct {
+	// Tables are the route tables by name, such as "blue" and "green".
+	Tables map[string]*Mux
+	// Active is the name of the table serving requests that do not select one.
+	Active string
+	// Header is the request header selecting a table by name. It defaults to X-Route-Table.
+	Header string
+	// Cookie is the cookie selecting a table by name when the header is absent. It defaults to muxter_route_table.
+	Cookie string
+}
+
+// RouteSwitch serves requests with one of several route tables: the table named by the request's header or cookie,
+// or else the active table. It lets a restructured API be tested internally on production traffic before it is
+// activated for everyone, and the active table be switched back and forth at runtime.
+type RouteSwitch struct {
+	opts   RouteSwitchOptions
+	active atomic.Value
+}
+
+// NewRouteSwitch returns a RouteSwitch configured by opts. It panics if the active table is not one of the tables.
+//
+//	routes := muxter.NewRouteSwitch(muxter.RouteSwitchOptions{
+//		Tables: map[string]*muxter.Mux{"blue": blue, "green": green},
+//		Active: "blue",
+//	})
+//	http.ListenAndServe(":8080", routes)
+func NewRouteSwitch(opts RouteSwitchOptions) *RouteSwitch {
+	if opts.Header == "" {
+		opts.Header = "X-Route-Table"
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = "muxter_route_table"
+	}
+	s := &RouteSwitch{opts: opts}
+	s.Activate(opts.Active)
+	return s
+}
+
+// Activate makes the named table serve the requests that do not select one. It is safe to call while serving
+// requests. It panics if the table does not exist.
+func (s *RouteSwitch) Activate(name string) {
+	if _, ok := s.opts.Tables[name]; !ok {
+		panic(fmt.Sprintf("muxter: cannot activate unknown route table %q", name))
+	}
+	s.active.Store(name)
+}
+
+// Active returns the name of the active table.
+func (s *RouteSwitch) Active() string {
+	return s.active.Load().(string)
+}
+
+// Table returns the name of the table serving the request.
+func (s *RouteSwitch) Table(r *http.Request) string {
+	name := r.Header.Get(s.opts.Header)
+	if name == "" {
+		if cookie, err := r.Cookie(s.opts.Cookie); err == nil {
+			name = cookie.Value
+		}
+	}
+	if _, ok := s.opts.Tables[name]; ok {
+		return name
+	}
+	return s.Active()
+}
+
+func (s *RouteSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", s.opts.Header)
+	s.opts.Tables[s.Table(r)].ServeHTTP(w, r)
+}
@@ -0,0 +1,73 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteSwitch(t *testing.T) {
+	blue, green := New(), New()
+	blue.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request, c Context) { io.WriteString(w, "blue") })
+	green.HandleFunc("/v2/books", func(w http.ResponseWriter, r *http.Request, c Context) { io.WriteString(w, "green") })
+
+	routes := NewRouteSwitch(RouteSwitchOptions{
+		Tables: map[string]*Mux{"blue": blue, "green": green},
+		Active: "blue",
+	})
+
+	testcases := []struct {
+		Name         string
+		Path         string
+		Header       string
+		Cookie       string
+		Active       string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{Name: "active table", Path: "/books", ExpectedCode: 200, ExpectedBody: "blue"},
+		{Name: "header", Path: "/v2/books", Header: "green", ExpectedCode: 200, ExpectedBody: "green"},
+		{Name: "cookie", Path: "/v2/books", Cookie: "green", ExpectedCode: 200, ExpectedBody: "green"},
+		{Name: "header over cookie", Path: "/books", Header: "blue", Cookie: "green", ExpectedCode: 200, ExpectedBody: "blue"},
+		{Name: "unknown table", Path: "/books", Header: "red", ExpectedCode: 200, ExpectedBody: "blue"},
+		{Name: "not in active table", Path: "/v2/books", ExpectedCode: 404},
+		{Name: "switched", Path: "/v2/books", Active: "green", ExpectedCode: 200, ExpectedBody: "green"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if tc.Active != "" {
+				routes.Activate(tc.Active)
+				defer routes.Activate("blue")
+			}
+
+			w, r := httptest.NewRecorder(), httptest.NewRequest("GET", tc.Path, nil)
+			if tc.Header != "" {
+				r.Header.Set("X-Route-Table", tc.Header)
+			}
+			if tc.Cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "muxter_route_table", Value: tc.Cookie})
+			}
+
+			routes.ServeHTTP(w, r)
+
+			if w.Code != tc.ExpectedCode {
+				t.Errorf("expected code %d but got %d", tc.ExpectedCode, w.Code)
+			}
+			if tc.ExpectedBody != "" && w.Body.String() != tc.ExpectedBody {
+				t.Errorf("expected body %q but got %q", tc.ExpectedBody, w.Body.String())
+			}
+			if vary := w.Header().Get("Vary"); vary != "X-Route-Table" {
+				t.Errorf("expected Vary X-Route-Table but got %q", vary)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected activating an unknown table to panic")
+		}
+	}()
+	routes.Activate("red")
+}
@@ -0,0 +1,98 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Tags is a registration option that tags a route. Tags group routes by feature area so that they can be operated
+// on together with UseTag, DisableTag, EnableTag and RoutesTagged.
+//
+//	mux.HandleFunc("/admin/users", listUsers, muxter.Tags("admin"))
+func Tags(tags ...string) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		ri.Tags = appendTags(ri.Tags, tags...)
+	})
+}
+
+// Tag tags the routes registered with the patterns. It panics if a pattern is not registered on the mux.
+func (m *Mux) Tag(tag string, patterns ...string) {
+	for _, pattern := range patterns {
+		v := m.lookupPattern(pattern)
+		if v == nil {
+			panic(fmt.Sprintf("muxter: cannot tag unregistered route %s", pattern))
+		}
+		v.route.Tags = appendTags(v.route.Tags, tag)
+	}
+}
+
+// UseTag applies the middlewares to the routes with the tag. Like Use it only affects routes registered, and tagged,
+// before the call, and it must not be called while the mux is serving requests. The middlewares run after those
+// the routes were registered with.
+func (m *Mux) UseTag(tag string, middlewares ...Middleware) {
+	m.eachTagged(tag, func(v *value) {
+		v.handler = WithMiddleware(v.handler, middlewares...)
+	})
+}
+
+// DisableTag disables the routes with the tag such that they are served as not found. Unlike UseTag it is safe to
+// call while the mux is serving requests.
+func (m *Mux) DisableTag(tag string) {
+	m.eachTagged(tag, func(v *value) { m.toggle(v, http.StatusNotFound) })
+}
+
+// EnableTag enables the routes with the tag that were disabled.
+func (m *Mux) EnableTag(tag string) {
+	m.eachTagged(tag, func(v *value) { m.toggle(v, 0) })
+}
+
+// RoutesTagged returns the routes with the tag sorted by pattern.
+func (m *Mux) RoutesTagged(tag string) []RouteInfo {
+	return m.routes(func(v *value) bool { return hasTag(v.route, tag) })
+}
+
+func (m *Mux) eachTagged(tag string, fn func(*value)) {
+	m.root.walk(func(v *value) {
+		if hasTag(v.route, tag) {
+			fn(v)
+		}
+	})
+}
+
+// lookupPattern returns the value registered with the pattern, or nil.
+func (m *Mux) lookupPattern(pattern string) (result *value) {
+	if normalized, err := normalizePattern(pattern, false); err == nil {
+		pattern = normalized
+	}
+	m.root.walk(func(v *value) {
+		if v.route != nil && v.pattern == pattern {
+			result = v
+		}
+	})
+	return result
+}
+
+func hasTag(route *RouteInfo, tag string) bool {
+	if route == nil {
+		return false
+	}
+	for _, t := range route.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func appendTags(tags []string, add ...string) []string {
+Add:
+	for _, tag := range add {
+		for _, existing := range tags {
+			if existing == tag {
+				continue Add
+			}
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,93 @@
+package muxter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	mux := New()
+
+	handler := func(name string) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, c Context) {
+			io.WriteString(w, name)
+		}
+	}
+
+	mux.HandleFunc("/admin/users", handler("users"), Tags("admin"))
+	mux.HandleFunc("/admin/audit", handler("audit"), Tags("admin", "audit"))
+	mux.HandleFunc("/reports", handler("reports"))
+	mux.HandleFunc("/public", handler("public"))
+
+	mux.Tag("admin", "/reports")
+
+	mux.UseTag("admin", func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			io.WriteString(w, "admin:")
+			h.ServeHTTPx(w, r, c)
+		})
+	})
+
+	serve := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	t.Run("middleware applied to tagged routes", func(t *testing.T) {
+		for path, expected := range map[string]string{
+			"/admin/users": "admin:users",
+			"/admin/audit": "admin:audit",
+			"/reports":     "admin:reports",
+			"/public":      "public",
+		} {
+			if _, body := serve(path); body != expected {
+				t.Errorf("expected body for %s to be %q but got %q", path, expected, body)
+			}
+		}
+	})
+
+	t.Run("routes tagged", func(t *testing.T) {
+		expected := []RouteInfo{
+			{Pattern: "/admin/audit", Tags: []string{"admin", "audit"}},
+			{Pattern: "/admin/users", Tags: []string{"admin"}},
+			{Pattern: "/reports", Tags: []string{"admin"}},
+		}
+		if routes := mux.RoutesTagged("admin"); !reflect.DeepEqual(routes, expected) {
+			t.Errorf("expected routes %+v but got %+v", expected, routes)
+		}
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		mux.DisableTag("audit")
+
+		if code, _ := serve("/admin/audit"); code != 404 {
+			t.Errorf("expected disabled route to return 404 but got %d", code)
+		}
+		if code, _ := serve("/admin/users"); code != 200 {
+			t.Errorf("expected route to return 200 but got %d", code)
+		}
+		if routes := mux.RoutesTagged("audit"); len(routes) != 1 || !routes[0].Disabled {
+			t.Errorf("expected audit route to be reported as disabled but got %+v", routes)
+		}
+
+		mux.EnableTag("audit")
+
+		if code, _ := serve("/admin/audit"); code != 200 {
+			t.Errorf("expected enabled route to return 200 but got %d", code)
+		}
+	})
+
+	t.Run("tag unregistered route", func(t *testing.T) {
+		defer func() {
+			expected := "muxter: cannot tag unregistered route /missing"
+			if actual, _ := recover().(string); actual != expected {
+				t.Errorf("expected panic %q but got %q", expected, actual)
+			}
+		}()
+		mux.Tag("admin", "/missing")
+	})
+}
@@ -0,0 +1,83 @@
+package muxter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// TLSOverview describes the TLS connection of a request, for access logs required to record how clients connect.
+type TLSOverview struct {
+	// Version is the negotiated TLS version, such as "TLS 1.3".
+	Version string `json:"version"`
+	// CipherSuite is the name of the negotiated cipher suite, such as "TLS_AES_128_GCM_SHA256".
+	CipherSuite string `json:"cipherSuite"`
+	// ServerName is the server name sent by the client with SNI, if any.
+	ServerName string `json:"serverName,omitempty"`
+	// NegotiatedProtocol is the application protocol negotiated with ALPN, such as "h2", if any.
+	NegotiatedProtocol string `json:"negotiatedProtocol,omitempty"`
+	// ClientSubject is the subject of the certificate presented by the client, if any, as an RFC 2253 distinguished
+	// name.
+	ClientSubject string `json:"clientSubject,omitempty"`
+	// Resumed reports whether the session was resumed from a previous connection.
+	Resumed bool `json:"resumed,omitempty"`
+}
+
+// newTLSOverview returns the overview of the connection state, or nil for requests not received over TLS.
+func newTLSOverview(state *tls.ConnectionState) *TLSOverview {
+	if state == nil {
+		return nil
+	}
+	overview := &TLSOverview{
+		Version:            tlsVersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Resumed:            state.DidResume,
+	}
+	if len(state.PeerCertificates) > 0 {
+		overview.ClientSubject = state.PeerCertificates[0].Subject.String()
+	}
+	return overview
+}
+
+// String formats the overview as space separated key=value pairs for access logs, such as
+// `tls="TLS 1.3" cipher=TLS_AES_128_GCM_SHA256 sni=api.example.com`. Values containing spaces are quoted.
+func (o *TLSOverview) String() string {
+	if o == nil {
+		return "tls=none"
+	}
+	var b strings.Builder
+	field := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		if strings.ContainsAny(value, " \"") {
+			value = fmt.Sprintf("%q", value)
+		}
+		b.WriteString(key + "=" + value)
+	}
+	field("tls", o.Version)
+	field("cipher", o.CipherSuite)
+	field("sni", o.ServerName)
+	field("alpn", o.NegotiatedProtocol)
+	field("client", o.ClientSubject)
+	return b.String()
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
@@ -0,0 +1,69 @@
+package muxter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoggerTLS(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Target   string
+		State    *tls.ConnectionState
+		Expected string
+	}{
+		{
+			Name:     "plaintext",
+			Target:   "http://example.com/",
+			Expected: "tls=none\n",
+		},
+		{
+			Name:   "server authenticated",
+			Target: "https://api.example.com/",
+			State: &tls.ConnectionState{
+				Version:            tls.VersionTLS13,
+				CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+				ServerName:         "api.example.com",
+				NegotiatedProtocol: "h2",
+			},
+			Expected: `tls="TLS 1.3" cipher=TLS_AES_128_GCM_SHA256 sni=api.example.com alpn=h2` + "\n",
+		},
+		{
+			Name:   "mutual",
+			Target: "https://api.example.com/",
+			State: &tls.ConnectionState{
+				Version:     tls.VersionTLS12,
+				CipherSuite: tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				PeerCertificates: []*x509.Certificate{
+					{Subject: pkix.Name{CommonName: "billing service", Organization: []string{"Example"}}},
+				},
+			},
+			Expected: `tls="TLS 1.2" cipher=TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 client="CN=billing service,O=Example"` + "\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var logs bytes.Buffer
+
+			mux := New()
+			mux.Use(Logger(&logs, func(overview RespOverview) string { return overview.TLS.String() }))
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+			r := httptest.NewRequest("GET", tc.Target, nil)
+			if tc.State != nil {
+				r.TLS = tc.State
+			}
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if logs.String() != tc.Expected {
+				t.Errorf("expected log %q but got %q", tc.Expected, logs.String())
+			}
+		})
+	}
+}
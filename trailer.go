@@ -0,0 +1,43 @@
+package muxter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrTrailerUnavailable is returned by Context.Trailer when the trailers of the request cannot be read, because more
+// of its body is left than allowed or because it was not served by a mux.
+var ErrTrailerUnavailable = errors.New("muxter: request trailers are unavailable")
+
+// Trailer returns the trailers of the request, or nil if the request declared none. Trailers are sent after the body,
+// so net/http only sets them once the body has been read to its end. Middlewares that replace the body, such as
+// Decompress or http.MaxBytesReader, may stop reading before the end of the original body, so Trailer reads the
+// original body that is left rather than the body of the request. It discards up to max bytes of it, and fails with
+// ErrTrailerUnavailable if more is left. It is meant to be called once the handler read the body:
+//
+//	sum := sha256.New()
+//	io.Copy(sum, r.Body)
+//	trailer, err := c.Trailer(0)
+//	if err == nil && trailer.Get("Digest") != hex.EncodeToString(sum.Sum(nil)) { ... }
+func (c Context) Trailer(max int64) (http.Header, error) {
+	c.checkOwner()
+	lc := c.lifecycle
+	if lc == nil {
+		return nil, ErrTrailerUnavailable
+	}
+	if lc.r.Trailer == nil {
+		return nil, nil
+	}
+
+	if lc.body != nil && lc.body != http.NoBody {
+		_, err := io.CopyN(io.Discard, lc.body, max+1)
+		if err == nil {
+			return nil, ErrTrailerUnavailable
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+	}
+	return lc.r.Trailer, nil
+}
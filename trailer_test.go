@@ -0,0 +1,70 @@
+package muxter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrailer(t *testing.T) {
+	mux := New()
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request, c Context) {
+		body, _ := io.ReadAll(r.Body)
+		trailer, err := c.Trailer(0)
+		fmt.Fprintf(w, "%s %q %v", body, trailer.Get("Checksum"), err)
+	}, Decompress)
+	mux.HandleFunc("/unread", func(w http.ResponseWriter, r *http.Request, c Context) {
+		_, err := c.Trailer(2)
+		fmt.Fprint(w, errors.Is(err, ErrTrailerUnavailable))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	io.WriteString(gw, "payload")
+	gw.Close()
+
+	cases := []struct {
+		Name     string
+		Path     string
+		Body     []byte
+		Encoding string
+		Trailer  http.Header
+		Expected string
+	}{
+		{Name: "plain", Path: "/upload", Body: []byte("payload"), Trailer: http.Header{"Checksum": {"abc"}}, Expected: `payload "abc" <nil>`},
+		{Name: "decompressed", Path: "/upload", Body: compressed.Bytes(), Encoding: "gzip", Trailer: http.Header{"Checksum": {"abc"}}, Expected: `payload "abc" <nil>`},
+		{Name: "no trailers", Path: "/upload", Body: []byte("payload"), Expected: `payload "" <nil>`},
+		{Name: "unread body", Path: "/unread", Body: []byte("payload"), Trailer: http.Header{"Checksum": {"abc"}}, Expected: "true"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// A body of unknown length is sent chunked, followed by the trailers.
+			req, _ := http.NewRequest("POST", server.URL+tc.Path, io.NopCloser(bytes.NewReader(tc.Body)))
+			req.Trailer = tc.Trailer
+			if tc.Encoding != "" {
+				req.Header.Set("Content-Encoding", tc.Encoding)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if actual := strings.TrimSpace(string(body)); actual != tc.Expected {
+				t.Errorf("expected %s but got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync/atomic"
 
 	"github.com/davidmdm/muxter/internal"
+	"github.com/davidmdm/muxter/internal/syntax"
 )
 
 const (
@@ -18,10 +21,26 @@ const (
 
 var errMultipleRegistrations = errors.New("multiple registrations")
 
+// registrationConflict is returned when a value is inserted at a key that already holds a value.
+type registrationConflict struct {
+	existing *value
+}
+
+func (registrationConflict) Error() string { return errMultipleRegistrations.Error() }
+
+func (registrationConflict) Unwrap() error { return errMultipleRegistrations }
+
 type value struct {
-	handler    Handler
+	handler Handler
+	// registered is the handler as it was registered, before its middlewares.
+	registered Handler
 	pattern    string
+	route      *RouteInfo
 	isRedirect bool
+	// alternatives are the values registered for the same pattern with matchers, such as MatchHeader.
+	alternatives []*value
+	// disabled is the status served instead of the route when it is disabled, or 0 when it is enabled.
+	disabled atomic.Int32
 }
 
 type node struct {
@@ -37,15 +56,15 @@ type node struct {
 }
 
 func (n *node) Insert(key string, value *value) error {
-	idx := strings.IndexAny(key, "#:*")
+	idx := syntax.IndexMeta(key)
 	if idx == -1 {
-		_, err := n.insert(key, value)
+		_, err := n.insertStatic(syntax.Unescape(key), value)
 		return err
 	}
 
-	pre := key[:idx]
+	pre := syntax.Unescape(key[:idx])
 
-	n, err := n.insert(pre, nil)
+	n, err := n.insertStatic(pre, nil)
 	if err != nil {
 		return err
 	}
@@ -55,11 +74,10 @@ func (n *node) Insert(key string, value *value) error {
 		if post[0] != '#' {
 			return strings.IndexByte(post, '/')
 		}
-		i := regexp.MustCompile(`[^\\]/`).FindStringIndex(post)
-		if i == nil {
-			return -1
+		if end := syntax.ExpressionEnd(post); end < len(post) {
+			return end
 		}
-		return i[1] - 1
+		return -1
 	}()
 
 	if slashIdx == -1 {
@@ -103,7 +121,7 @@ func (n *node) insert(key string, value *value) (*node, error) {
 			}
 			if value != nil {
 				if n.Expression.Value != nil {
-					return nil, errMultipleRegistrations
+					return nil, registrationConflict{n.Expression.Value}
 				}
 				n.Expression.Value = value
 			}
@@ -126,7 +144,7 @@ func (n *node) insert(key string, value *value) (*node, error) {
 			}
 			if value != nil {
 				if n.Wildcard.Value != nil {
-					return nil, errMultipleRegistrations
+					return nil, registrationConflict{n.Wildcard.Value}
 				}
 				n.Wildcard.Value = value
 			}
@@ -146,7 +164,7 @@ func (n *node) insert(key string, value *value) (*node, error) {
 			if n.Catchall.Key != key[1:] {
 				return nil, fmt.Errorf("mismatched wild cards *%s and %s", n.Catchall.Key, key)
 			}
-			return nil, errMultipleRegistrations
+			return nil, registrationConflict{n.Catchall.Value}
 		}
 		n.Catchall = &node{
 			Key:   key[1:],
@@ -156,11 +174,16 @@ func (n *node) insert(key string, value *value) (*node, error) {
 		return n.Catchall, nil
 	}
 
+	return n.insertStatic(key, value)
+}
+
+// insertStatic inserts the value at the static key, whose metacharacters are matched literally.
+func (n *node) insertStatic(key string, value *value) (*node, error) {
 	for i, childNode := range n.Children {
 		if key == childNode.Key {
 			if value != nil {
 				if childNode.Value != nil {
-					return nil, errMultipleRegistrations
+					return nil, registrationConflict{childNode.Value}
 				}
 				childNode.Value = value
 			}
@@ -173,7 +196,7 @@ func (n *node) insert(key string, value *value) (*node, error) {
 		}
 
 		if cp == len(childNode.Key) {
-			return childNode.insert(key[cp:], value)
+			return childNode.insertStatic(key[cp:], value)
 		}
 
 		childNode.Key = childNode.Key[cp:]
@@ -215,10 +238,123 @@ func (n *node) insert(key string, value *value) (*node, error) {
 	return targetNode, nil
 }
 
-func (n *node) Lookup(path string, params *[]internal.Param, matchTrailingSlash bool) (result *value) {
+// Delete removes the value stored at the key and returns it, or nil if there is none. Nodes left without values are
+// removed and static nodes left with a single static child are merged with it, such that the tree is the same as if
+// the value had never been inserted.
+func (n *node) Delete(key string) *value {
+	path := []*node{n}
+
+	for key != "" {
+		end := len(key)
+
+		switch key[0] {
+		case '#':
+			end = syntax.ExpressionEnd(key)
+			colon := strings.IndexByte(key[:end], ':')
+			if colon == -1 || n.Expression == nil || n.Expression.Key != key[1:colon] || n.Expression.expression.String() != "^("+key[colon+1:end]+")" {
+				return nil
+			}
+			n = n.Expression
+
+		case ':':
+			if idx := strings.IndexByte(key, '/'); idx != -1 {
+				end = idx
+			}
+			if n.Wildcard == nil || n.Wildcard.Key != key[1:end] {
+				return nil
+			}
+			n = n.Wildcard
+
+		case '*':
+			if n.Catchall == nil || n.Catchall.Key != key[1:] {
+				return nil
+			}
+			n = n.Catchall
+
+		default:
+			if end = syntax.IndexMeta(key); end == -1 {
+				end = len(key)
+			}
+			for static := syntax.Unescape(key[:end]); static != ""; static = static[len(n.Key):] {
+				child := n.child(static[0])
+				if child == nil || !strings.HasPrefix(static, child.Key) {
+					return nil
+				}
+				n = child
+				path = append(path, n)
+			}
+			key = key[end:]
+			continue
+		}
+
+		path = append(path, n)
+		key = key[end:]
+	}
+
+	removed := n.Value
+	if removed == nil {
+		return nil
+	}
+	n.Value = nil
+
+	for i := len(path) - 1; i > 0; i-- {
+		n, parent := path[i], path[i-1]
+		if n.Value != nil || n.Wildcard != nil || n.Catchall != nil || n.Expression != nil {
+			break
+		}
+		switch {
+		case len(n.Children) == 0:
+			parent.remove(n)
+		case len(n.Children) == 1 && n.Type == static:
+			child := n.Children[0]
+			child.Key = n.Key + child.Key
+			parent.Children[parent.index(n.Key[0])] = child
+		}
+	}
+
+	return removed
+}
+
+// child returns the static child whose key starts with c, or nil if there is none.
+func (n *node) child(c byte) *node {
+	if i := n.index(c); i != -1 {
+		return n.Children[i]
+	}
+	return nil
+}
+
+// index returns the index of the static child whose key starts with c, or -1 if there is none.
+func (n *node) index(c byte) int {
+	for i, index := range n.Indices {
+		if index == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// remove removes the child from n.
+func (n *node) remove(child *node) {
+	switch child {
+	case n.Wildcard:
+		n.Wildcard = nil
+	case n.Catchall:
+		n.Catchall = nil
+	case n.Expression:
+		n.Expression = nil
+	default:
+		i := n.index(child.Key[0])
+		n.Children = append(n.Children[:i], n.Children[i+1:]...)
+		n.Indices = append(n.Indices[:i], n.Indices[i+1:]...)
+	}
+}
+
+// Lookup returns the value matching the path. The expressions it evaluates are charged to the budget, and it returns
+// nil once the budget is exceeded. A nil budget is unlimited.
+func (n *node) Lookup(path string, params *[]internal.Param, matchTrailingSlash bool, budget *expressionBudget) (result *value) {
 	var fallback *value
 	defer func() {
-		if result == nil {
+		if result == nil && budget.reason() == "" {
 			result = fallback
 		}
 	}()
@@ -267,6 +403,9 @@ Walk:
 			})
 			return n.Value
 		case expression:
+			if !budget.spend(len(path)) {
+				return nil
+			}
 			i := n.expression.FindStringIndex(path)
 			if i == nil {
 				return nil
@@ -314,6 +453,232 @@ Walk:
 	}
 }
 
+// PartialMatch walks the path as far as the registered patterns match it, when no value matches it entirely. It
+// returns the pattern of the deepest segments matched, ending with the slash following them, and appends the params
+// captured in them. Static segments are preferred over wildcards and expressions without backtracking, as in Lookup.
+// It returns the empty string when no segment matched.
+func (n *node) PartialMatch(path string, params *[]internal.Param, budget *expressionBudget) string {
+	var (
+		pattern  strings.Builder
+		matched  int
+		captured = len(*params)
+	)
+	checkpoint := func() {
+		matched, captured = pattern.Len(), len(*params)
+	}
+
+Walk:
+	for path != "" {
+		switch n.Type {
+		case static:
+			l := commonPrefixLength(path, n.Key)
+			for i := 0; i < l; i++ {
+				if syntax.IsMeta(path[i]) {
+					pattern.WriteByte(EscapeChar)
+				}
+				pattern.WriteByte(path[i])
+				if path[i] == '/' {
+					checkpoint()
+				}
+			}
+			if l < len(n.Key) {
+				break Walk
+			}
+			path = path[l:]
+		case wildcard:
+			idx := strings.IndexByte(path, '/')
+			if idx == -1 {
+				break Walk
+			}
+			*params = append(*params, internal.Param{Key: n.Key, Value: path[:idx]})
+			pattern.WriteString(string(WildcardPrefix) + n.Key)
+			path = path[idx:]
+		case expression:
+			if !budget.spend(len(path)) {
+				break Walk
+			}
+			i := n.expression.FindStringIndex(path)
+			if i == nil {
+				break Walk
+			}
+			*params = append(*params, internal.Param{Key: n.Key, Value: path[:i[1]]})
+			source := n.expression.String()
+			pattern.WriteString(string(ExpressionPrefix) + n.Key + ":" + source[2:len(source)-1])
+			path = path[i[1]:]
+		default:
+			break Walk
+		}
+		if path == "" {
+			break
+		}
+
+		for i, c := range n.Indices {
+			if c == path[0] {
+				n = n.Children[i]
+				continue Walk
+			}
+		}
+		switch {
+		case n.Wildcard != nil:
+			n = n.Wildcard
+		case n.Expression != nil:
+			n = n.Expression
+		default:
+			break Walk
+		}
+	}
+
+	*params = (*params)[:captured]
+	if matched <= 1 {
+		return ""
+	}
+	return pattern.String()[:matched]
+}
+
+// Candidates calls fn with the values matching the path, and the params they match, in precedence order until fn
+// returns false. The first candidate is the value returned by Lookup, if any. It is followed by the other values
+// matching the whole path, preferring static segments over expressions, expressions over wildcards and wildcards
+// over catchalls, and then by the values of the rooted subtrees containing the path, the deepest first.
+func (n *node) Candidates(path string, matchTrailingSlash bool, fn func(v *value, params []internal.Param) bool) {
+	var winner []internal.Param
+	first := n.Lookup(path, &winner, matchTrailingSlash, nil)
+	if first != nil && !first.isRedirect {
+		if !fn(first, winner) {
+			return
+		}
+	}
+
+	var exact, subtrees []candidate
+	n.collect(path, nil, 0, matchTrailingSlash, &exact, &subtrees)
+	sort.SliceStable(subtrees, func(i, j int) bool { return subtrees[i].depth > subtrees[j].depth })
+
+	seen := map[*value]bool{first: true}
+	for _, c := range append(exact, subtrees...) {
+		if seen[c.value] {
+			continue
+		}
+		seen[c.value] = true
+		if !fn(c.value, c.params) {
+			return
+		}
+	}
+}
+
+type candidate struct {
+	value  *value
+	params []internal.Param
+	// depth is the length of the path matched by the subtree of the value.
+	depth int
+}
+
+// collect appends the values below n matching the whole path to exact, and the values of the rooted subtrees
+// containing the path to subtrees, searching every branch of the tree.
+func (n *node) collect(path string, params []internal.Param, depth int, matchTrailingSlash bool, exact, subtrees *[]candidate) {
+	// Params are appended to a full slice, such that the branches do not share their params.
+	params = params[:len(params):len(params)]
+
+	switch n.Type {
+	case static:
+		if !strings.HasPrefix(path, n.Key) {
+			return
+		}
+		path, depth = path[len(n.Key):], depth+len(n.Key)
+	case wildcard:
+		idx := strings.IndexByte(path, '/')
+		if idx == -1 {
+			idx = len(path)
+		}
+		if idx == 0 {
+			return
+		}
+		params = append(params, internal.Param{Key: n.Key, Value: path[:idx]})
+		path, depth = path[idx:], depth+idx
+	case catchall:
+		if path != "" && n.Value != nil {
+			*exact = append(*exact, candidate{value: n.Value, params: append(params, internal.Param{Key: n.Key, Value: path})})
+		}
+		return
+	case expression:
+		i := n.expression.FindStringIndex(path)
+		if i == nil {
+			return
+		}
+		params = append(params, internal.Param{Key: n.Key, Value: path[:i[1]]})
+		path, depth = path[i[1]:], depth+i[1]
+	}
+
+	if path == "" {
+		if n.Value != nil {
+			*exact = append(*exact, candidate{value: n.Value, params: params})
+		}
+		return
+	}
+	if n.IsSubdirNode() || (matchTrailingSlash && path == "/" && n.Value != nil) {
+		*subtrees = append(*subtrees, candidate{value: n.Value, params: params, depth: depth})
+	}
+
+	if child := n.child(path[0]); child != nil {
+		child.collect(path, params, depth, matchTrailingSlash, exact, subtrees)
+	}
+	for _, child := range []*node{n.Expression, n.Wildcard, n.Catchall} {
+		if child != nil {
+			child.collect(path, params, depth, matchTrailingSlash, exact, subtrees)
+		}
+	}
+}
+
+// registrations returns the values stored in the tree, without the alternatives registered for their patterns.
+func (n *node) registrations() []*value {
+	alternatives := map[*value]bool{}
+	var values []*value
+	n.walk(func(v *value) {
+		for _, alt := range v.alternatives {
+			alternatives[alt] = true
+		}
+		values = append(values, v)
+	})
+
+	registrations := values[:0]
+	for _, v := range values {
+		if !alternatives[v] {
+			registrations = append(registrations, v)
+		}
+	}
+	return registrations
+}
+
+// rebuild replaces the tree with a new tree of its values. Insert can fail after adding or splitting nodes on the
+// way to the key, and the nodes it leaves behind change how paths are matched, such that the tree must be rebuilt
+// for matching to only depend on the values it holds.
+func (n *node) rebuild() {
+	rebuilt := &node{}
+	for _, v := range n.registrations() {
+		if err := rebuilt.Insert(routeKey(v.pattern), v); err != nil {
+			panic(fmt.Sprintf("muxter: failed to rebuild route tree at %s - %v", v.pattern, err))
+		}
+	}
+	*n = *rebuilt
+}
+
+// walk calls fn for every value stored in the tree.
+func (n *node) walk(fn func(*value)) {
+	if n == nil {
+		return
+	}
+	if n.Value != nil {
+		fn(n.Value)
+		for _, alt := range n.Value.alternatives {
+			fn(alt)
+		}
+	}
+	for _, child := range n.Children {
+		child.walk(fn)
+	}
+	n.Expression.walk(fn)
+	n.Wildcard.walk(fn)
+	n.Catchall.walk(fn)
+}
+
 func (node *node) IsSubdirNode() bool {
 	return node != nil && node.Value != nil && strings.HasSuffix(node.Key, "/")
 }
@@ -0,0 +1,408 @@
+// Package tree implements a generic radix tree router matching keys made of segments, such as URL paths, message
+// topics or file paths, against patterns with params. It is the routing algorithm of muxter without HTTP:
+//
+//	var topics tree.Router[Handler]
+//	topics.Separator = '.'
+//	topics.Insert("orders.:id.created", onOrderCreated)
+//	topics.Insert("orders.*event", onOrderEvent)
+//
+//	handler, params, ok := topics.Lookup("orders.42.created") // onOrderCreated, [{id 42}], true
+//
+// Patterns are made of static text, params of the form ":name" matching a non-empty segment up to the next
+// separator, and a catchall of the form "*name" at the end of the pattern matching the non-empty rest of the key.
+// A backslash escapes the colon, asterisk or backslash following it, which is then matched literally, as in `12\:30`.
+// Static text takes precedence over params, and params over catchalls, whatever the order patterns were inserted
+// in. Lookups backtrack, such that a key matches a less specific pattern when the more specific branches fail.
+//
+// A Router is safe for concurrent lookups but not for lookups concurrent with insertions or deletions.
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrConflict is the error wrapped by the errors of Insert for patterns that conflict with inserted patterns.
+var ErrConflict = errors.New("tree: conflicting pattern")
+
+// Param is a param matched by a lookup.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the params matched by a lookup, in the order of the pattern.
+type Params []Param
+
+// Get returns the value of the param, or the empty string if the pattern has no such param.
+func (ps Params) Get(key string) string {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Router is a radix tree of values by pattern. The zero value is an empty router with '/' as separator.
+type Router[T any] struct {
+	// Separator is the byte separating the segments of keys. It defaults to '/' and must be set before the first
+	// insertion.
+	Separator byte
+
+	root node[T]
+	size int
+}
+
+// New returns an empty router with '/' as separator.
+func New[T any]() *Router[T] {
+	return &Router[T]{}
+}
+
+type kind uint8
+
+const (
+	static kind = iota
+	param
+	catchall
+)
+
+type node[T any] struct {
+	kind kind
+	// prefix is the static text of static nodes, and name the name of param and catchall nodes.
+	prefix string
+	name   string
+	// children are the static children, sorted by the first byte of their prefix which is unique among them.
+	children []*node[T]
+	param    *node[T]
+	catchall *node[T]
+
+	pattern  string
+	value    T
+	hasValue bool
+}
+
+type token struct {
+	kind kind
+	text string
+}
+
+// parse splits the pattern into static text, params and catchall.
+func (r *Router[T]) parse(pattern string) ([]token, error) {
+	sep := r.separator()
+
+	var tokens []token
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; c {
+		case ':', '*':
+			end := strings.IndexByte(pattern[i:], sep)
+			if end == -1 {
+				end = len(pattern)
+			} else {
+				end += i
+			}
+			name := pattern[i+1 : end]
+			if name == "" {
+				return nil, fmt.Errorf("tree: %q has a param without name", pattern)
+			}
+			if j := strings.IndexAny(name, ":*"); j != -1 {
+				return nil, fmt.Errorf("tree: %q has a param followed by %q rather than a separator", pattern, name[j:])
+			}
+			if c == '*' {
+				if end != len(pattern) {
+					return nil, fmt.Errorf("tree: %q has segments after the catchall *%s", pattern, name)
+				}
+				tokens = append(tokens, token{catchall, name})
+			} else {
+				tokens = append(tokens, token{param, name})
+			}
+			i = end
+
+		default:
+			var text strings.Builder
+			for ; i < len(pattern) && pattern[i] != ':' && pattern[i] != '*'; i++ {
+				if pattern[i] == '\\' && i+1 < len(pattern) && strings.IndexByte(`:*\`, pattern[i+1]) != -1 {
+					i++
+				}
+				text.WriteByte(pattern[i])
+			}
+			tokens = append(tokens, token{static, text.String()})
+		}
+	}
+
+	return tokens, nil
+}
+
+func (r *Router[T]) separator() byte {
+	if r.Separator == 0 {
+		return '/'
+	}
+	return r.Separator
+}
+
+// Insert inserts the value at the pattern. It fails without changing the router if the pattern is invalid, if
+// a value is already inserted at the pattern, or if its params are named differently than the params of the
+// inserted patterns at the same position.
+func (r *Router[T]) Insert(pattern string, value T) error {
+	tokens, err := r.parse(pattern)
+	if err != nil {
+		return err
+	}
+
+	// The tokens are followed once to check for conflicts and once to create the nodes, such that a failed
+	// insertion leaves no node behind.
+	if _, err := r.root.follow(tokens, pattern, false); err != nil {
+		return err
+	}
+	n, _ := r.root.follow(tokens, pattern, true)
+
+	n.pattern, n.value, n.hasValue = pattern, value, true
+	r.size++
+	return nil
+}
+
+// follow returns the node of the tokens, creating it if create is true. Otherwise it returns nil once the tokens
+// lead past the existing nodes.
+func (n *node[T]) follow(tokens []token, pattern string, create bool) (*node[T], error) {
+	for _, tok := range tokens {
+		switch tok.kind {
+		case static:
+			n = n.static(tok.text, create)
+
+		case param, catchall:
+			child := &n.param
+			if tok.kind == catchall {
+				child = &n.catchall
+			}
+			if *child == nil {
+				if !create {
+					return nil, nil
+				}
+				*child = &node[T]{kind: tok.kind, name: tok.text}
+			}
+			if (*child).name != tok.text {
+				return nil, fmt.Errorf("%w: %q names %s %q rather than %q", ErrConflict, pattern, tok.kind, tok.text, (*child).name)
+			}
+			n = *child
+		}
+		if n == nil {
+			return nil, nil
+		}
+	}
+
+	if n.hasValue {
+		return nil, fmt.Errorf("%w: %q is already inserted as %q", ErrConflict, pattern, n.pattern)
+	}
+	return n, nil
+}
+
+// static returns the node at the end of the static text below n, splitting and creating nodes if create is true.
+// Otherwise it returns nil if there is no such node.
+func (n *node[T]) static(text string, create bool) *node[T] {
+	for text != "" {
+		i, child := n.child(text[0])
+		if child == nil {
+			if !create {
+				return nil
+			}
+			child = &node[T]{prefix: text}
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = child
+			return child
+		}
+
+		cp := commonPrefixLength(child.prefix, text)
+		if cp < len(child.prefix) {
+			if !create {
+				return nil
+			}
+			split := &node[T]{prefix: child.prefix[:cp], children: []*node[T]{child}}
+			child.prefix = child.prefix[cp:]
+			n.children[i] = split
+			child = split
+		}
+
+		n, text = child, text[cp:]
+	}
+	return n
+}
+
+// child returns the static child whose prefix starts with c, or the index it would be inserted at.
+func (n *node[T]) child(c byte) (int, *node[T]) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].prefix[0] >= c })
+	if i < len(n.children) && n.children[i].prefix[0] == c {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+// Lookup returns the value of the pattern matching the key and the params it matched.
+func (r *Router[T]) Lookup(key string) (value T, params Params, ok bool) {
+	n := r.root.lookup(key, r.separator(), &params)
+	if n == nil {
+		return value, nil, false
+	}
+	return n.value, params, true
+}
+
+// lookup returns the node matching the rest of the key, below n.
+func (n *node[T]) lookup(key string, sep byte, params *Params) *node[T] {
+	if key == "" {
+		if n.hasValue {
+			return n
+		}
+		return nil
+	}
+
+	if _, child := n.child(key[0]); child != nil && strings.HasPrefix(key, child.prefix) {
+		if match := child.lookup(key[len(child.prefix):], sep, params); match != nil {
+			return match
+		}
+	}
+
+	if n.param != nil {
+		end := strings.IndexByte(key, sep)
+		if end == -1 {
+			end = len(key)
+		}
+		if end > 0 {
+			*params = append(*params, Param{Key: n.param.name, Value: key[:end]})
+			if match := n.param.lookup(key[end:], sep, params); match != nil {
+				return match
+			}
+			*params = (*params)[:len(*params)-1]
+		}
+	}
+
+	if n.catchall != nil {
+		*params = append(*params, Param{Key: n.catchall.name, Value: key})
+		return n.catchall
+	}
+
+	return nil
+}
+
+// Delete removes the value at the pattern, and reports whether there was one. Nodes left without values are
+// removed and static nodes left with a single child are merged with it, such that the router is the same as if the
+// pattern had never been inserted.
+func (r *Router[T]) Delete(pattern string) bool {
+	tokens, err := r.parse(pattern)
+	if err != nil {
+		return false
+	}
+
+	path := []*node[T]{&r.root}
+	n := &r.root
+	for _, tok := range tokens {
+		switch tok.kind {
+		case static:
+			for text := tok.text; text != "" && n != nil; {
+				_, child := n.child(text[0])
+				if child == nil || !strings.HasPrefix(text, child.prefix) {
+					return false
+				}
+				n, text = child, text[len(child.prefix):]
+				path = append(path, n)
+			}
+		case param:
+			n = n.param
+			path = append(path, n)
+		case catchall:
+			n = n.catchall
+			path = append(path, n)
+		}
+		if n == nil || (tok.kind != static && n.name != tok.text) {
+			return false
+		}
+	}
+	if !n.hasValue || n.pattern != pattern {
+		return false
+	}
+
+	var zero T
+	n.pattern, n.value, n.hasValue = "", zero, false
+	r.size--
+
+	for i := len(path) - 1; i > 0; i-- {
+		n, parent := path[i], path[i-1]
+		switch {
+		case !n.hasValue && len(n.children) == 0 && n.param == nil && n.catchall == nil:
+			parent.remove(n)
+		case n.kind == static && !n.hasValue && len(n.children) == 1 && n.param == nil && n.catchall == nil:
+			child := n.children[0]
+			child.prefix = n.prefix + child.prefix
+			i, _ := parent.child(n.prefix[0])
+			parent.children[i] = child
+		}
+	}
+
+	return true
+}
+
+// remove removes the child from n.
+func (n *node[T]) remove(child *node[T]) {
+	switch child.kind {
+	case param:
+		n.param = nil
+	case catchall:
+		n.catchall = nil
+	default:
+		i, _ := n.child(child.prefix[0])
+		n.children = append(n.children[:i], n.children[i+1:]...)
+		if len(n.children) == 0 {
+			n.children = nil
+		}
+	}
+}
+
+// Walk calls fn with the patterns and values of the router, static text before params and params before
+// catchalls, until fn returns false.
+func (r *Router[T]) Walk(fn func(pattern string, value T) bool) {
+	r.root.walk(fn)
+}
+
+func (n *node[T]) walk(fn func(pattern string, value T) bool) bool {
+	if n.hasValue && !fn(n.pattern, n.value) {
+		return false
+	}
+	for _, child := range n.children {
+		if !child.walk(fn) {
+			return false
+		}
+	}
+	if n.param != nil && !n.param.walk(fn) {
+		return false
+	}
+	if n.catchall != nil && !n.catchall.walk(fn) {
+		return false
+	}
+	return true
+}
+
+// Len returns the number of values in the router.
+func (r *Router[T]) Len() int {
+	return r.size
+}
+
+func (k kind) String() string {
+	switch k {
+	case param:
+		return "param"
+	case catchall:
+		return "catchall"
+	default:
+		return "static"
+	}
+}
+
+func commonPrefixLength(a, b string) (i int) {
+	for ; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,229 @@
+package tree
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	patterns := []string{
+		"/",
+		"/books",
+		"/books/:id",
+		"/books/latest",
+		"/books/:id/authors",
+		"/books/:id/*rest",
+		"/files/*path",
+		"/users/u:id",
+		"/users/u:id/posts/:post",
+	}
+
+	var router Router[string]
+	for _, pattern := range patterns {
+		if err := router.Insert(pattern, pattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Key             string
+		ExpectedPattern string
+		ExpectedParams  Params
+	}{
+		{Key: "/", ExpectedPattern: "/"},
+		{Key: "/books", ExpectedPattern: "/books"},
+		{Key: "/books/1", ExpectedPattern: "/books/:id", ExpectedParams: Params{{"id", "1"}}},
+		{Key: "/books/latest", ExpectedPattern: "/books/latest"},
+		{Key: "/books/lat", ExpectedPattern: "/books/:id", ExpectedParams: Params{{"id", "lat"}}},
+		{Key: "/books/latest/authors", ExpectedPattern: "/books/:id/authors", ExpectedParams: Params{{"id", "latest"}}},
+		{Key: "/books/1/authors", ExpectedPattern: "/books/:id/authors", ExpectedParams: Params{{"id", "1"}}},
+		{Key: "/books/1/reviews/2", ExpectedPattern: "/books/:id/*rest", ExpectedParams: Params{{"id", "1"}, {"rest", "reviews/2"}}},
+		{Key: "/books/1/", ExpectedPattern: ""},
+		{Key: "/books/", ExpectedPattern: ""},
+		{Key: "/files/a/b.txt", ExpectedPattern: "/files/*path", ExpectedParams: Params{{"path", "a/b.txt"}}},
+		{Key: "/files/", ExpectedPattern: ""},
+		{Key: "/users/u42/posts/7", ExpectedPattern: "/users/u:id/posts/:post", ExpectedParams: Params{{"id", "42"}, {"post", "7"}}},
+		{Key: "/users/x42", ExpectedPattern: ""},
+		{Key: "/authors", ExpectedPattern: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Key, func(t *testing.T) {
+			value, params, ok := router.Lookup(tc.Key)
+			if ok != (tc.ExpectedPattern != "") || value != tc.ExpectedPattern {
+				t.Fatalf("expected %q but got %q (%v)", tc.ExpectedPattern, value, ok)
+			}
+			if len(params) != 0 || len(tc.ExpectedParams) != 0 {
+				if !reflect.DeepEqual(params, tc.ExpectedParams) {
+					t.Errorf("expected params %v but got %v", tc.ExpectedParams, params)
+				}
+			}
+		})
+	}
+
+	if id := (Params{{"id", "1"}}).Get("id"); id != "1" {
+		t.Errorf("expected param id to be 1 but got %q", id)
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	topics := Router[int]{Separator: '.'}
+	topics.Insert("orders.:id.created", 1)
+	topics.Insert("orders.*event", 2)
+
+	if value, params, _ := topics.Lookup("orders.42.created"); value != 1 || params.Get("id") != "42" {
+		t.Errorf("expected created topic with id 42 but got %d %v", value, params)
+	}
+	if value, params, _ := topics.Lookup("orders.42.shipped"); value != 2 || params.Get("event") != "42.shipped" {
+		t.Errorf("expected catchall topic but got %d %v", value, params)
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	var router Router[int]
+	router.Insert("/meetings/:time", 1)
+	router.Insert(`/meetings/12\:30`, 2)
+	router.Insert(`/glob/\*.txt/\\/*rest`, 3)
+
+	if value, _, _ := router.Lookup("/meetings/12:30"); value != 2 {
+		t.Errorf("expected escaped colon to be matched literally but got %d", value)
+	}
+	if value, params, _ := router.Lookup("/meetings/12:31"); value != 1 || params.Get("time") != "12:31" {
+		t.Errorf("expected param route but got %d %v", value, params)
+	}
+	if value, params, _ := router.Lookup(`/glob/*.txt/\/a/b`); value != 3 || params.Get("rest") != "a/b" {
+		t.Errorf("expected escaped asterisk and backslash to be matched literally but got %d %v", value, params)
+	}
+	if !router.Delete(`/meetings/12\:30`) {
+		t.Errorf("expected escaped pattern to be deleted")
+	}
+}
+
+func TestInsertErrors(t *testing.T) {
+	cases := []struct {
+		Pattern  string
+		Conflict bool
+	}{
+		{Pattern: "/books/:id", Conflict: true},
+		{Pattern: "/books/:book", Conflict: true},
+		{Pattern: "/books/:book/authors", Conflict: true},
+		{Pattern: "/files/*rest", Conflict: true},
+		{Pattern: "/files/*path/x"},
+		{Pattern: "/books/:"},
+		{Pattern: "/books/:id:format"},
+	}
+
+	router := New[int]()
+	router.Insert("/books/:id", 1)
+	router.Insert("/files/*path", 2)
+
+	for _, tc := range cases {
+		t.Run(tc.Pattern, func(t *testing.T) {
+			before := *router
+
+			err := router.Insert(tc.Pattern, 3)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if errors.Is(err, ErrConflict) != tc.Conflict {
+				t.Errorf("expected conflict %v but got %v", tc.Conflict, err)
+			}
+			if !reflect.DeepEqual(*router, before) {
+				t.Errorf("expected failed insertion not to change the router")
+			}
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	var router Router[int]
+	for i, pattern := range []string{"/b/*rest", "/b/:id", "/a", "/b/c", "/"} {
+		router.Insert(pattern, i)
+	}
+
+	var patterns []string
+	router.Walk(func(pattern string, value int) bool {
+		patterns = append(patterns, pattern)
+		return true
+	})
+	if expected := []string{"/", "/a", "/b/c", "/b/:id", "/b/*rest"}; !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("expected walk order %v but got %v", expected, patterns)
+	}
+
+	var count int
+	router.Walk(func(string, int) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Errorf("expected walk to stop after 2 patterns but got %d", count)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	patterns := []string{"/", "/books", "/books/:id", "/books/:id/authors", "/books/latest", "/bookshelf", "/files/*path", "/b"}
+
+	for _, deleted := range patterns {
+		t.Run(deleted, func(t *testing.T) {
+			var router, expected Router[string]
+			for _, pattern := range patterns {
+				router.Insert(pattern, pattern)
+				if pattern != deleted {
+					expected.Insert(pattern, pattern)
+				}
+			}
+
+			if !router.Delete(deleted) {
+				t.Fatalf("expected %s to be deleted", deleted)
+			}
+			if router.Delete(deleted) {
+				t.Errorf("expected second deletion to report no value")
+			}
+			if !reflect.DeepEqual(router, expected) {
+				t.Errorf("expected router to be the same as if %s had never been inserted", deleted)
+			}
+		})
+	}
+
+	var router Router[int]
+	router.Insert("/books/:id", 1)
+	if router.Delete("/books/:book") || router.Delete("/books") || router.Len() != 1 {
+		t.Errorf("expected deletion of patterns that were not inserted to fail")
+	}
+}
+
+// TestInsertionOrder verifies that the router does not depend on the order patterns were inserted in.
+func TestInsertionOrder(t *testing.T) {
+	segments := []string{"a", "ab", "abc", "b", ":id", "*rest", "a:id", "ab:id", "a*rest", ""}
+
+	for seed := int64(0); seed < 200; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+
+		var patterns []string
+		var reference Router[string]
+		for i := 2 + rnd.Intn(6); i > 0; i-- {
+			var pattern string
+			for depth := 1 + rnd.Intn(3); depth > 0; depth-- {
+				pattern += "/" + segments[rnd.Intn(len(segments))]
+			}
+			if reference.Insert(pattern, pattern) == nil {
+				patterns = append(patterns, pattern)
+			}
+		}
+
+		rnd.Shuffle(len(patterns), func(i, j int) { patterns[i], patterns[j] = patterns[j], patterns[i] })
+
+		var shuffled Router[string]
+		for _, pattern := range patterns {
+			if err := shuffled.Insert(pattern, pattern); err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+		}
+
+		if !reflect.DeepEqual(reference, shuffled) {
+			t.Errorf("seed %d: expected the same router for patterns %q inserted in another order", seed, patterns)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package muxter
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTreeDelete(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	patterns := []string{
+		"/",
+		"/users",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/latest",
+		"/usersettings",
+		"/files/*path",
+		`/orders/#id:\d+`,
+		`/orders/#id:\d+/items/`,
+		"/docs/",
+		"//:tenant.example.com/",
+		`/users/\:me`,
+		`/users/\:me/\*/:id`,
+	}
+
+	samples := []string{"/users/latest/", "/docs", "/orders/1/items", "http://acme.example.com/users", "/users/:me/*/1"}
+	for _, pattern := range patterns {
+		if path, err := expandPattern(pattern, func(string) (string, bool) { return "1", true }); err == nil {
+			samples = append(samples, path)
+		}
+	}
+
+	for _, deleted := range patterns {
+		t.Run(deleted, func(t *testing.T) {
+			mux, expected := New(), New()
+			for _, pattern := range patterns {
+				mux.Handle(pattern, noop)
+				if pattern != deleted {
+					expected.Handle(pattern, noop)
+				}
+			}
+
+			if removed := mux.root.Delete(routeKey(deleted)); removed == nil || removed.pattern != deleted {
+				t.Fatalf("expected value of %s to be deleted but got %v", deleted, removed)
+			}
+			if removed := mux.root.Delete(routeKey(deleted)); removed != nil {
+				t.Errorf("expected second deletion to find no value")
+			}
+
+			stats, expectedStats := mux.TreeStats(), expected.TreeStats()
+			stats.HeapBytes, expectedStats.HeapBytes = 0, 0
+			if !reflect.DeepEqual(stats, expectedStats) {
+				t.Errorf("expected tree to be the same as if %s had never been inserted:\n%+v\n%+v", deleted, stats, expectedStats)
+			}
+			if diff := CompareRouting(mux, expected, samples); len(diff) != 0 {
+				t.Errorf("unexpected routing differences: %v", diff)
+			}
+		})
+	}
+
+	mux := New()
+	mux.Handle("/users/:id", noop)
+	mux.Handle(`/orders/#id:\d+`, noop)
+	for _, pattern := range []string{"/users/:user", "/users", "/users/:id/posts", `/orders/#id:\w+`, "/files/*path"} {
+		if mux.root.Delete(routeKey(pattern)) != nil {
+			t.Errorf("expected deletion of %s, which was not inserted, to find no value", pattern)
+		}
+	}
+	if len(mux.Routes()) != 2 {
+		t.Errorf("expected failed deletions to keep the routes")
+	}
+}
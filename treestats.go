@@ -0,0 +1,74 @@
+package muxter
+
+import (
+	"unsafe"
+)
+
+// TreeStats describes the shape and memory footprint of the route tree of a mux.
+type TreeStats struct {
+	// Nodes is the number of nodes of the tree, the sum of the nodes of each type.
+	Nodes           int `json:"nodes"`
+	StaticNodes     int `json:"staticNodes"`
+	WildcardNodes   int `json:"wildcardNodes"`
+	ExpressionNodes int `json:"expressionNodes"`
+	CatchallNodes   int `json:"catchallNodes"`
+	// Values is the number of routes held by the tree, including the alternatives of routes with matchers.
+	Values int `json:"values"`
+	// Depths is the depth distribution of the nodes: Depths[d] is the number of nodes at depth d, the root being
+	// at depth 0. The length of Depths is one more than the depth of the tree.
+	Depths []int `json:"depths"`
+	// KeyBytes is the total length of the keys of the nodes.
+	KeyBytes int `json:"keyBytes"`
+	// HeapBytes is an estimate of the memory used by the tree: its nodes, keys, indices and values. It does not
+	// include handlers, route metadata and compiled regular expressions.
+	HeapBytes int `json:"heapBytes"`
+}
+
+// TreeStats returns statistics about the route tree of the mux, such as its number of nodes and an estimate of its
+// memory usage, for capacity planning of very large route tables. Trees of nested muxes are not included.
+func (m *Mux) TreeStats() TreeStats {
+	var stats TreeStats
+	m.root.stats(&stats, 0)
+	return stats
+}
+
+func (n *node) stats(stats *TreeStats, depth int) {
+	if n == nil {
+		return
+	}
+
+	stats.Nodes++
+	switch n.Type {
+	case static:
+		stats.StaticNodes++
+	case wildcard:
+		stats.WildcardNodes++
+	case expression:
+		stats.ExpressionNodes++
+	case catchall:
+		stats.CatchallNodes++
+	}
+
+	if depth == len(stats.Depths) {
+		stats.Depths = append(stats.Depths, 0)
+	}
+	stats.Depths[depth]++
+
+	stats.KeyBytes += len(n.Key)
+	stats.HeapBytes += int(unsafe.Sizeof(*n)) + len(n.Key) + cap(n.Children)*int(unsafe.Sizeof(n)) + cap(n.Indices)
+
+	if n.Value != nil {
+		for _, v := range append([]*value{n.Value}, n.Value.alternatives...) {
+			stats.Values++
+			stats.HeapBytes += int(unsafe.Sizeof(*v)) + len(v.pattern)
+		}
+		stats.HeapBytes += cap(n.Value.alternatives) * int(unsafe.Sizeof(n.Value))
+	}
+
+	for _, child := range n.Children {
+		child.stats(stats, depth+1)
+	}
+	n.Expression.stats(stats, depth+1)
+	n.Wildcard.stats(stats, depth+1)
+	n.Catchall.stats(stats, depth+1)
+}
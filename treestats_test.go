@@ -0,0 +1,55 @@
+package muxter
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTreeStats(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	mux := New()
+	mux.Handle("/users", noop)
+	mux.Handle("/users/:id", noop)
+	mux.Handle("/users/:id/posts", noop)
+	mux.Handle("/files/*path", noop)
+	mux.Handle(`/orders/#id:\d+`, noop)
+	mux.Handle("/orders/latest", noop, MatchHeader("X-Beta", ""))
+	mux.Handle("/orders/latest", noop)
+
+	stats := mux.TreeStats()
+	stats.HeapBytes = 0
+
+	expected := TreeStats{
+		Nodes:           11,
+		StaticNodes:     8,
+		WildcardNodes:   1,
+		ExpressionNodes: 1,
+		CatchallNodes:   1,
+		Values:          7,
+		Depths:          []int{1, 1, 3, 4, 1, 1},
+		KeyBytes:        len("/") + len("users") + len("/") + len("id") + len("/posts") + len("files/") + len("path") + len("orders/") + len("id") + len("latest"),
+	}
+
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected stats %+v but got %+v", expected, stats)
+	}
+}
+
+func TestTreeStatsHeapBytes(t *testing.T) {
+	noop := HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {})
+
+	small, large := New(), New()
+	for i := 0; i < 10; i++ {
+		small.Handle("/items/"+strconv.Itoa(i), noop)
+	}
+	for i := 0; i < 1000; i++ {
+		large.Handle("/items/"+strconv.Itoa(i), noop)
+	}
+
+	if s, l := small.TreeStats().HeapBytes, large.TreeStats().HeapBytes; s <= 0 || l < 50*s {
+		t.Errorf("expected heap estimate to grow with the route table but got %d and %d", s, l)
+	}
+}
@@ -0,0 +1,108 @@
+package muxter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/davidmdm/muxter/internal/syntax"
+)
+
+// Warm is a registration option that opts a route into Mux.Warmup. Each target is the path of a synthetic request,
+// optionally preceded by its method such as "POST /search". Without targets a GET request for the pattern is used,
+// in which case the pattern must not contain params.
+//
+//	mux.HandleFunc("/templates/:name", render, muxter.Warm("/templates/home", "/templates/about"))
+func Warm(targets ...string) Middleware {
+	return registrationOption(func(ri *RouteInfo) {
+		if len(targets) > 0 {
+			ri.Warmup = append(ri.Warmup, targets...)
+			return
+		}
+		if syntax.IndexMeta(ri.Pattern) != -1 {
+			panic(fmt.Sprintf("muxter: route %s has params and requires explicit warmup targets", ri.Pattern))
+		}
+		ri.Warmup = append(ri.Warmup, syntax.Unescape(ri.Pattern))
+	})
+}
+
+type warmupKey struct{}
+
+// IsWarmup reports whether the request is a synthetic request made by Mux.Warmup, allowing handlers to skip side
+// effects for such requests.
+func IsWarmup(r *http.Request) bool {
+	warmup, _ := r.Context().Value(warmupKey{}).(bool)
+	return warmup
+}
+
+// Warmup serves synthetic requests for the targets of the routes registered with the Warm option, to prime caches
+// and lazily initialized handlers before the service reports itself as ready. Requests are served in pattern order
+// through the mux, including its middlewares, and their responses are discarded. Warmup returns an error describing
+// the requests that failed with a server error or a panic, or the context's error if it is done.
+func (m *Mux) Warmup(ctx context.Context) error {
+	ctx = context.WithValue(ctx, warmupKey{}, true)
+
+	var failures []string
+	for _, route := range m.Routes() {
+		for _, target := range route.Warmup {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			method, path := "GET", target
+			if idx := strings.IndexByte(target, ' '); idx != -1 {
+				method, path = strings.ToUpper(target[:idx]), strings.TrimSpace(target[idx+1:])
+			}
+
+			if err := m.warm(ctx, method, path); err != nil {
+				failures = append(failures, fmt.Sprintf("%s %s: %v", method, path, err))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("muxter: warmup failed for %d request(s): %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (m *Mux) warm(ctx context.Context, method, path string) (err error) {
+	r, err := http.NewRequestWithContext(ctx, method, path, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	w := &warmupResponseWriter{header: http.Header{}}
+	m.ServeHTTP(w, r)
+
+	if w.code >= 500 {
+		return fmt.Errorf("status %d", w.code)
+	}
+	return nil
+}
+
+// warmupResponseWriter discards the response of warmup requests, only keeping its status code.
+type warmupResponseWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *warmupResponseWriter) Header() http.Header { return w.header }
+
+func (w *warmupResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *warmupResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
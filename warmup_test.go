@@ -0,0 +1,68 @@
+package muxter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWarmup(t *testing.T) {
+	var warmed []string
+
+	record := func(w http.ResponseWriter, r *http.Request, c Context) {
+		if !IsWarmup(r) {
+			t.Errorf("expected request to be a warmup request")
+		}
+		warmed = append(warmed, r.Method+" "+r.URL.Path)
+	}
+
+	mux := New()
+	mux.HandleFunc("/home", record, Warm())
+	mux.HandleFunc("/templates/:name", record, Warm("/templates/a", "POST /templates/b"))
+	mux.HandleFunc("/cold", record)
+
+	if err := mux.Warmup(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"GET /home", "GET /templates/a", "POST /templates/b"}
+	if !reflect.DeepEqual(warmed, expected) {
+		t.Errorf("expected warmed requests %q but got %q", expected, warmed)
+	}
+
+	t.Run("failures", func(t *testing.T) {
+		mux := New()
+		mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request, c Context) { w.WriteHeader(500) }, Warm())
+		mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request, c Context) { panic("boom") }, Warm())
+		mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request, c Context) {}, Warm())
+
+		err := mux.Warmup(context.Background())
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		expected := "muxter: warmup failed for 2 request(s): GET /error: status 500; GET /panic: panic: boom"
+		if err.Error() != expected {
+			t.Errorf("expected error %q but got %q", expected, err.Error())
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := mux.Warmup(ctx); err != context.Canceled {
+			t.Errorf("expected context canceled but got %v", err)
+		}
+	})
+
+	t.Run("params without targets", func(t *testing.T) {
+		defer func() {
+			actual, _ := recover().(string)
+			if !strings.Contains(actual, "route /users/:id has params and requires explicit warmup targets") {
+				t.Errorf("unexpected panic %q", actual)
+			}
+		}()
+		New().HandleFunc("/users/:id", record, Warm())
+	})
+}
@@ -0,0 +1,272 @@
+package muxter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWebhookQueueFull is returned when enqueuing a webhook to a dispatcher whose queue is full.
+	ErrWebhookQueueFull = errors.New("muxter: webhook queue is full")
+	// ErrWebhookDispatcherClosed is returned when enqueuing a webhook to a closed dispatcher.
+	ErrWebhookDispatcherClosed = errors.New("muxter: webhook dispatcher is closed")
+	// ErrNoWebhookDispatcher is returned by Context.EnqueueWebhook when the mux has no webhook dispatcher.
+	ErrNoWebhookDispatcher = errors.New("muxter: no webhook dispatcher")
+)
+
+// Webhook is an outbound webhook delivery.
+type Webhook struct {
+	// URL is the endpoint the webhook is posted to.
+	URL string
+	// Body is the payload of the webhook.
+	Body []byte
+	// Header are additional headers of the request. The Content-Type defaults to application/json.
+	Header http.Header
+	// ID is the webhook-id of the deliveries, which receivers use to discard the retries of a webhook they already
+	// processed. It is generated by Enqueue when empty, and kept by the webhooks given to DeadLetter such that they
+	// can be replayed under the same ID.
+	ID string
+}
+
+// WebhookOptions configures a WebhookDispatcher.
+type WebhookOptions struct {
+	// Secret is the key the payloads are signed with. Without a secret webhooks are not signed.
+	Secret []byte
+	// Client is the client webhooks are delivered with. It defaults to a client with a 10 second timeout.
+	Client *http.Client
+	// MaxAttempts is the number of delivery attempts before a webhook is dead-lettered. It defaults to 5.
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt after the given number of failed attempts. It defaults to
+	// an exponential backoff with jitter starting at one second and capped at one minute.
+	Backoff func(attempt int) time.Duration
+	// QueueSize is the number of webhooks that can be pending delivery. It defaults to 1024.
+	QueueSize int
+	// Workers is the number of concurrent deliveries. It defaults to 4.
+	Workers int
+	// DeadLetter is called with webhooks that could not be delivered and the last error, such that they can be
+	// persisted and replayed.
+	DeadLetter func(hook Webhook, err error)
+}
+
+// WebhookDispatcher delivers outbound webhooks in the background, signing their payloads and retrying failed
+// deliveries with backoff. Deliveries are retried on network errors and on 408, 429 and 5xx responses.
+//
+// Payloads are signed following the Standard Webhooks specification: requests have webhook-id and webhook-timestamp
+// headers and a webhook-signature header of the form "v1,<signature>", where the signature is the base64 encoded
+// HMAC-SHA256 of "<id>.<timestamp>.<body>" keyed with the secret.
+type WebhookDispatcher struct {
+	opts   WebhookOptions
+	queue  chan Webhook
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookDispatcher returns a WebhookDispatcher and starts its workers. The dispatcher must be closed with Close.
+func NewWebhookDispatcher(opts WebhookOptions) *WebhookDispatcher {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = defaultWebhookBackoff
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &WebhookDispatcher{
+		opts:   opts,
+		queue:  make(chan Webhook, opts.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	d.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer d.wg.Done()
+			for hook := range d.queue {
+				d.deliver(hook)
+			}
+		}()
+	}
+
+	return d
+}
+
+// Webhooks sets the dispatcher handlers enqueue webhooks to with Context.EnqueueWebhook. Nested muxes use the
+// dispatcher of their parent unless they set their own.
+func Webhooks(d *WebhookDispatcher) MuxOption {
+	return func(m *Mux) {
+		m.webhooks = d
+	}
+}
+
+// EnqueueWebhook enqueues the webhook for delivery by the mux's dispatcher set with the Webhooks option. It does not
+// wait for the delivery. It returns ErrNoWebhookDispatcher if the mux has no dispatcher.
+func (c Context) EnqueueWebhook(hook Webhook) error {
+	if c.extra().webhooks == nil {
+		return ErrNoWebhookDispatcher
+	}
+	return c.extra().webhooks.Enqueue(hook)
+}
+
+// Enqueue enqueues the webhook for delivery. It returns ErrWebhookQueueFull if the queue is full and
+// ErrWebhookDispatcherClosed if the dispatcher is closed.
+func (d *WebhookDispatcher) Enqueue(hook Webhook) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return ErrWebhookDispatcherClosed
+	}
+
+	if hook.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		hook.ID = "msg_" + hex.EncodeToString(id)
+	}
+
+	select {
+	case d.queue <- hook:
+		return nil
+	default:
+		return ErrWebhookQueueFull
+	}
+}
+
+// Close stops accepting webhooks and waits for pending webhooks to be delivered. If the context is done first,
+// pending deliveries are abandoned and dead-lettered and the context's error is returned.
+func (d *WebhookDispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *WebhookDispatcher) deliver(hook Webhook) {
+	for attempt := 1; ; attempt++ {
+		retry, err := d.send(hook)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.opts.MaxAttempts {
+			d.deadLetter(hook, fmt.Errorf("muxter: webhook delivery to %s failed after %d attempt(s): %w", hook.URL, attempt, err))
+			return
+		}
+
+		timer := time.NewTimer(d.opts.Backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			d.deadLetter(hook, fmt.Errorf("muxter: webhook delivery to %s abandoned after %d attempt(s): %w", hook.URL, attempt, d.ctx.Err()))
+			return
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deadLetter(hook Webhook, err error) {
+	if d.opts.DeadLetter != nil {
+		d.opts.DeadLetter(hook, err)
+	}
+}
+
+// send makes a delivery attempt and reports whether a failed attempt should be retried.
+func (d *WebhookDispatcher) send(hook Webhook) (retry bool, err error) {
+	r, err := http.NewRequestWithContext(d.ctx, "POST", hook.URL, bytes.NewReader(hook.Body))
+	if err != nil {
+		return false, err
+	}
+
+	for key, values := range hook.Header {
+		r.Header[key] = append([]string(nil), values...)
+	}
+	if r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	if d.opts.Secret != nil {
+		signWebhook(r.Header, d.opts.Secret, hook.ID, time.Now(), hook.Body)
+	}
+
+	resp, err := d.opts.Client.Do(r)
+	if err != nil {
+		return true, err
+	}
+	// The body is drained such that the connection is reused for the next delivery.
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+		return false, nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500:
+		return true, fmt.Errorf("unexpected status %d", code)
+	default:
+		return false, fmt.Errorf("unexpected status %d", code)
+	}
+}
+
+func signWebhook(header http.Header, secret []byte, id string, timestamp time.Time, body []byte) {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id + "." + ts + "."))
+	mac.Write(body)
+
+	header.Set("webhook-id", id)
+	header.Set("webhook-timestamp", ts)
+	header.Set("webhook-signature", "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func defaultWebhookBackoff(attempt int) time.Duration {
+	delay := time.Second << (attempt - 1)
+	if delay <= 0 || delay > time.Minute {
+		delay = time.Minute
+	}
+	return delay/2 + time.Duration(mathrand.Int63n(int64(delay/2)+1))
+}
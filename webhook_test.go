@@ -0,0 +1,104 @@
+package muxter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	secret := []byte("whsec")
+
+	var mu sync.Mutex
+	var attempts int
+	var signatureErr string
+	ids := map[string]bool{}
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		attempts++
+		ids[r.Header.Get("webhook-id")] = true
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(r.Header.Get("webhook-id") + "." + r.Header.Get("webhook-timestamp") + "." + string(body)))
+		expected := "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+		if signature := r.Header.Get("webhook-signature"); signature != expected {
+			signatureErr = "expected signature " + expected + " but got " + signature
+		}
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			signatureErr = "unexpected content type " + contentType
+		}
+	}))
+	defer receiver.Close()
+
+	var deadLetters []string
+	dispatcher := NewWebhookDispatcher(WebhookOptions{
+		Secret:  secret,
+		Backoff: func(int) time.Duration { return time.Millisecond },
+		DeadLetter: func(hook Webhook, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			deadLetters = append(deadLetters, err.Error())
+		},
+	})
+
+	mux := New(Webhooks(dispatcher))
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if err := c.EnqueueWebhook(Webhook{URL: receiver.URL, Body: []byte(`{"type":"order.created"}`)}); err != nil {
+			t.Errorf("unexpected error enqueuing webhook: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/orders", nil))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 but got %d", w.Code)
+	}
+
+	if err := dispatcher.Enqueue(Webhook{URL: receiver.URL + "/gone", Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("unexpected error enqueuing webhook: %v", err)
+	}
+
+	if err := dispatcher.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error closing dispatcher: %v", err)
+	}
+
+	if err := dispatcher.Enqueue(Webhook{URL: receiver.URL}); err != ErrWebhookDispatcherClosed {
+		t.Errorf("expected enqueuing to a closed dispatcher to fail with %v but got %v", ErrWebhookDispatcherClosed, err)
+	}
+
+	if signatureErr != "" {
+		t.Error(signatureErr)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 delivery attempts but got %d", attempts)
+	}
+	if len(ids) != 1 {
+		t.Errorf("expected the retries of the webhook to keep its id but got ids %v", ids)
+	}
+	if len(deadLetters) != 1 || !strings.Contains(deadLetters[0], "/gone failed after 1 attempt(s): unexpected status 410") {
+		t.Errorf("expected webhook to /gone to be dead-lettered without retries but got %q", deadLetters)
+	}
+}
@@ -0,0 +1,215 @@
+package muxter
+
+import (
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WorkerPoolOptions configures a WorkerPool.
+type WorkerPoolOptions struct {
+	// Workers is the number of requests served concurrently by the pool. It defaults to the number of CPUs available
+	// to the program, as reported by runtime.GOMAXPROCS, which suits CPU bound handlers.
+	Workers int
+	// QueueSize is the number of requests waiting for a worker. Requests arriving when the queue is full are shed.
+	// Zero means requests wait for a worker without a queue, bounded only by MaxWait.
+	QueueSize int
+	// MaxWait is how long requests wait in the queue for a worker before being shed. Zero means requests wait until
+	// a worker is free or their client goes away.
+	MaxWait time.Duration
+	// OnShed is called with the requests that are shed, for example to record a metric.
+	OnShed func(r *http.Request, c Context)
+}
+
+// WorkerPool serves the requests of heavy routes on a fixed set of worker goroutines, such that a burst of requests
+// to one endpoint and the memory their handlers use are bounded by the number of workers rather than spread over
+// the goroutines of the server. Requests wait for a worker in a bounded queue and are answered with 503 Service
+// Unavailable and a Retry-After header if the queue is full or they waited MaxWait, as the LoadShedder does.
+//
+// A panic in a handler served by the pool does not take its worker down: it is recovered on the worker and raised
+// again on the goroutine of the request, where the Recover middleware and net/http handle it as usual.
+type WorkerPool struct {
+	opts WorkerPoolOptions
+	jobs chan *workerJob
+	quit chan struct{}
+	once sync.Once
+	busy atomic.Int64
+}
+
+// workerJob is a request handed to a worker. Its state is claimed by the worker when it starts serving it, or by the
+// request when it gives up waiting, whichever comes first.
+type workerJob struct {
+	serve     func()
+	state     atomic.Int32
+	started   chan struct{}
+	done      chan struct{}
+	recovered interface{}
+	panicked  bool
+}
+
+const (
+	jobQueued int32 = iota
+	jobRunning
+	jobAbandoned
+)
+
+// NewWorkerPool starts a WorkerPool configured by opts. Its Middleware method serves the routes it is used on with
+// the workers until the pool is closed. It panics if the number of workers is negative.
+//
+//	reports := muxter.NewWorkerPool(muxter.WorkerPoolOptions{Workers: 4, QueueSize: 16, MaxWait: 5 * time.Second})
+//	mux.HandleFunc("/reports/:id", renderReport, reports.Middleware)
+func NewWorkerPool(opts WorkerPoolOptions) *WorkerPool {
+	if opts.Workers < 0 {
+		panic("muxter: worker pool must not have a negative number of workers")
+	}
+	if opts.Workers == 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.QueueSize < 0 {
+		opts.QueueSize = 0
+	}
+
+	p := &WorkerPool{
+		opts: opts,
+		jobs: make(chan *workerJob, opts.QueueSize),
+		quit: make(chan struct{}),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Middleware is a middleware serving requests on the workers of the pool.
+func (p *WorkerPool) Middleware(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+		job := &workerJob{started: make(chan struct{}), done: make(chan struct{})}
+		job.serve = func() { h.ServeHTTPx(w, r, c) }
+
+		if !p.submit(job, r) {
+			p.shed(w, r, c)
+			return
+		}
+
+		<-job.done
+		if job.panicked {
+			panic(job.recovered)
+		}
+	})
+}
+
+// Busy returns the number of requests being served by the workers.
+func (p *WorkerPool) Busy() int {
+	return int(p.busy.Load())
+}
+
+// Close stops the workers once they served the requests they started. Requests still waiting in the queue and
+// requests arriving after Close are shed.
+func (p *WorkerPool) Close() {
+	p.once.Do(func() { close(p.quit) })
+}
+
+// submit queues the job and waits for a worker to start serving it. It reports false if the job was shed.
+func (p *WorkerPool) submit(job *workerJob, r *http.Request) bool {
+	select {
+	case <-p.quit:
+		return false
+	default:
+	}
+
+	select {
+	case p.jobs <- job:
+	default:
+		if p.opts.QueueSize > 0 || !p.handOff(job, r) {
+			return false
+		}
+	}
+
+	var timeout <-chan time.Time
+	if p.opts.MaxWait > 0 {
+		timer := time.NewTimer(p.opts.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-job.started:
+		return true
+	case <-timeout:
+	case <-r.Context().Done():
+	case <-p.quit:
+	}
+
+	// The job stays in the queue, where workers skip it once it is abandoned, unless a worker claimed it meanwhile.
+	if job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+		return false
+	}
+	<-job.started
+	return true
+}
+
+// handOff waits for a free worker to take the job, for pools without a queue.
+func (p *WorkerPool) handOff(job *workerJob, r *http.Request) bool {
+	var timeout <-chan time.Time
+	if p.opts.MaxWait > 0 {
+		timer := time.NewTimer(p.opts.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.jobs <- job:
+		return true
+	case <-timeout:
+	case <-r.Context().Done():
+	case <-p.quit:
+	}
+	return false
+}
+
+func (p *WorkerPool) shed(w http.ResponseWriter, r *http.Request, c Context) {
+	if p.opts.OnShed != nil {
+		p.opts.OnShed(r, c)
+	}
+	retryAfter := int(math.Ceil(math.Max(p.opts.MaxWait.Seconds(), 1)))
+	c.extra().events.publish(eventLimiterRejected, LimiterRejectedEvent{
+		Request:    r,
+		Pattern:    c.Pattern(),
+		Status:     http.StatusServiceUnavailable,
+		RetryAfter: time.Duration(retryAfter) * time.Second,
+	})
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeStatus(w, c, http.StatusServiceUnavailable)
+}
+
+func (p *WorkerPool) work() {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case job := <-p.jobs:
+			if job.state.CompareAndSwap(jobQueued, jobRunning) {
+				p.run(job)
+			}
+		}
+	}
+}
+
+// run serves the job, recovering its panic such that the worker survives it.
+func (p *WorkerPool) run(job *workerJob) {
+	p.busy.Add(1)
+	defer close(job.done)
+	defer p.busy.Add(-1)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			job.recovered, job.panicked = recovered, true
+		}
+	}()
+
+	close(job.started)
+	job.serve()
+}
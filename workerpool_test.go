@@ -0,0 +1,117 @@
+package muxter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	var shed int
+	pool := NewWorkerPool(WorkerPoolOptions{
+		Workers:   1,
+		QueueSize: 1,
+		OnShed:    func(r *http.Request, c Context) { shed++ },
+	})
+	defer pool.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+
+	mux := New()
+	mux.HandleFunc("/reports/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte(c.Param("id")))
+	}, pool.Middleware)
+
+	serve := func(path string) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			done <- w
+		}()
+		return done
+	}
+
+	first := serve("/reports/1")
+	<-started
+	if busy := pool.Busy(); busy != 1 {
+		t.Fatalf("expected 1 busy worker but got %d", busy)
+	}
+
+	queued := serve("/reports/2")
+	time.Sleep(20 * time.Millisecond)
+
+	w := <-serve("/reports/3")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" || shed != 1 {
+		t.Errorf("expected request to be shed with a full queue but got %d with Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	release <- struct{}{}
+	if w := <-first; w.Code != 200 || w.Body.String() != "1" {
+		t.Errorf("expected first report to be served but got %d %q", w.Code, w.Body.String())
+	}
+	<-started
+	release <- struct{}{}
+	if w := <-queued; w.Code != 200 || w.Body.String() != "2" {
+		t.Errorf("expected queued report to be served but got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestWorkerPoolMaxWait(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolOptions{Workers: 1, MaxWait: 20 * time.Millisecond})
+	defer pool.Close()
+
+	release := make(chan struct{})
+	handler := pool.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) { <-release }))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTPx(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), Context{})
+		close(done)
+	}()
+	for pool.Busy() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTPx(w, httptest.NewRequest("GET", "/", nil), Context{})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected request to be shed after waiting but got %d", w.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestWorkerPoolPanic(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolOptions{Workers: 1})
+	defer pool.Close()
+
+	var recovered interface{}
+	mux := New()
+	mux.Use(Recover(func(v interface{}, w http.ResponseWriter, r *http.Request, c Context) {
+		recovered = v
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	mux.HandleFunc("/reports/:id", func(w http.ResponseWriter, r *http.Request, c Context) {
+		if c.Param("id") == "boom" {
+			panic("boom")
+		}
+		w.Write([]byte("ok"))
+	}, pool.Middleware)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/reports/boom", nil))
+	if w.Code != 500 || recovered != "boom" {
+		t.Errorf("expected panic to be recovered on the request but got %d and %v", w.Code, recovered)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/reports/1", nil))
+	if w.Code != 200 || w.Body.String() != "ok" {
+		t.Errorf("expected the worker to survive the panic but got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,111 @@
+package muxter
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// WriteHeaderOptions configures the VerifyWriteHeader middleware.
+type WriteHeaderOptions struct {
+	// OnConflict is called with the reason of every WriteHeader call made once the headers of the response were
+	// written. It defaults to panicking, such that the stack of the panic points at the second call.
+	OnConflict func(r *http.Request, reason string)
+}
+
+// VerifyWriteHeader records where the headers of responses are written, by an explicit WriteHeader or by the first
+// Write, and reports the WriteHeader calls that come after, naming the function and the line of both calls. Unlike
+// the superfluous WriteHeader warning of net/http, which points at the response writer wrapped closest to the
+// server, it attributes the conflict to the handler or middleware of the chain that made each call. It is meant for
+// development, as recording call sites is costly. Informational 1xx responses are not conflicts.
+//
+//	mux.Use(muxter.VerifyWriteHeader(muxter.WriteHeaderOptions{}))
+//	// panic: muxter: GET /books: WriteHeader(500) by main.recoverer.func1 (api/main.go:42) after the headers were
+//	// written with status 200 by main.listBooks (api/books.go:17)
+func VerifyWriteHeader(opts WriteHeaderOptions) Middleware {
+	onConflict := opts.OnConflict
+	if onConflict == nil {
+		onConflict = func(r *http.Request, reason string) {
+			panic(fmt.Sprintf("muxter: %s %s: %s", r.Method, r.URL.Path, reason))
+		}
+	}
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			h.ServeHTTPx(&writeHeaderWriter{ResponseWriter: w, r: r, onConflict: onConflict}, r, c)
+		})
+	}
+}
+
+// writeHeaderWriter records the call site of the call writing the headers of the response.
+type writeHeaderWriter struct {
+	http.ResponseWriter
+	r          *http.Request
+	onConflict func(r *http.Request, reason string)
+	code       int
+	// writer is the call site that wrote the headers, or empty if they were not written.
+	writer string
+}
+
+func (w *writeHeaderWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *writeHeaderWriter) WriteHeader(code int) {
+	switch {
+	case w.writer != "":
+		w.onConflict(w.r, fmt.Sprintf("WriteHeader(%d) by %s after the headers were written with status %d by %s", code, writeCallSite(), w.code, w.writer))
+		return
+	case code >= 200:
+		w.code, w.writer = code, writeCallSite()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writeHeaderWriter) Write(p []byte) (int, error) {
+	if w.writer == "" {
+		w.code, w.writer = http.StatusOK, writeCallSite()
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// writeCallSite returns the function and the line of the code writing to the response. The Write and WriteHeader
+// methods of the response writers wrapping it, the functions of the standard library such as fmt.Fprintf and
+// http.Error and the helpers of the muxter package are skipped, such that the call is attributed to the handler or
+// middleware making it.
+func writeCallSite() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		name := frame.Function[strings.LastIndexByte(frame.Function, '/')+1:]
+		wrapper := strings.HasSuffix(name, ".Write") || strings.HasSuffix(name, ".WriteHeader") || strings.HasSuffix(name, ".WriteString")
+		helper := strings.HasPrefix(frame.Function, "github.com/davidmdm/muxter.") && !strings.Contains(name, ".func")
+		if !more || !wrapper && !helper && !isStdlib(frame.Function) {
+			return name + " (" + shortFile(frame.File) + ":" + strconv.Itoa(frame.Line) + ")"
+		}
+	}
+}
+
+// isStdlib reports whether the function belongs to the standard library, whose import paths have no dot in their
+// first element.
+func isStdlib(function string) bool {
+	slash := strings.LastIndexByte(function, '/')
+	pkg := function
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot != -1 {
+		pkg = function[:slash+1+dot]
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	return pkg != "main" && !strings.Contains(first, ".")
+}
+
+// shortFile returns the file name with its directory, which is enough to locate it.
+func shortFile(file string) string {
+	if i := strings.LastIndexByte(file, '/'); i != -1 {
+		if j := strings.LastIndexByte(file[:i], '/'); j != -1 {
+			return file[j+1:]
+		}
+	}
+	return file
+}
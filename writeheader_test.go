@@ -0,0 +1,83 @@
+package muxter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestVerifyWriteHeader(t *testing.T) {
+	overwrite := func(h Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request, c Context) {
+			h.ServeHTTPx(w, r, c)
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+	}
+
+	cases := []struct {
+		Name     string
+		Handler  HandlerFunc
+		Expected string
+	}{
+		{
+			Name: "write then middleware",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				io.WriteString(w, "ok")
+			},
+			Expected: `^WriteHeader\(500\) by muxter\.TestVerifyWriteHeader\.func1\.1 \([^ ]*writeheader_test\.go:\d+\) after the headers were written with status 200 by muxter\.TestVerifyWriteHeader\.func\d+ \([^ ]*writeheader_test\.go:\d+\)$`,
+		},
+		{
+			Name: "standard library helper",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				http.Error(w, "gone", http.StatusGone)
+			},
+			Expected: `^WriteHeader\(500\) by .* after the headers were written with status 410 by muxter\.TestVerifyWriteHeader\.func\d+ \([^ ]*writeheader_test\.go:\d+\)$`,
+		},
+		{
+			Name: "informational",
+			Handler: func(w http.ResponseWriter, r *http.Request, c Context) {
+				w.WriteHeader(http.StatusEarlyHints)
+				fmt.Fprint(w, "ok")
+			},
+			Expected: `status 200 by muxter\.TestVerifyWriteHeader\.func\d+ \([^ ]*writeheader_test\.go:\d+\)$`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var reasons []string
+			verify := VerifyWriteHeader(WriteHeaderOptions{
+				OnConflict: func(r *http.Request, reason string) { reasons = append(reasons, reason) },
+			})
+
+			mux := New()
+			mux.Handle("/", tc.Handler, verify, overwrite)
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			if len(reasons) != 1 {
+				t.Fatalf("expected a single conflict but got %q", reasons)
+			}
+			if !regexp.MustCompile(tc.Expected).MatchString(reasons[0]) {
+				t.Errorf("expected reason to match %s but got %q", tc.Expected, reasons[0])
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		mux := New()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request, c Context) {
+			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(http.StatusOK)
+		}, VerifyWriteHeader(WriteHeaderOptions{}))
+
+		defer func() {
+			if recovered, _ := recover().(string); !regexp.MustCompile(`^muxter: GET /: WriteHeader\(200\) by `).MatchString(recovered) {
+				t.Errorf("expected conflict to panic but got %q", recovered)
+			}
+		}()
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}